@@ -16,12 +16,14 @@
 #   .\build-windows.ps1                    # Build everything
 #   .\build-windows.ps1 -SkipDownloads     # Skip downloading dependencies
 #   .\build-windows.ps1 -Version "1.0.1"   # Build with specific version
+#   .\build-windows.ps1 -PublicKey "RWQ..." # Require updates signed with this minisign key
 # =============================================================================
 
 param(
     [string]$Version = "1.0.0",
     [string]$GitHubOwner = "muhammadsuryono",
     [string]$GitHubRepo = "mekari-esign-go",
+    [string]$PublicKey = "",
     [switch]$SkipDownloads,
     [switch]$SkipInstaller,
     [switch]$Clean
@@ -146,6 +148,7 @@ $ldflags = @(
     "-X mekari-esign/updater.Version=$Version",
     "-X mekari-esign/updater.DefaultConfig.Owner=$GitHubOwner",
     "-X mekari-esign/updater.DefaultConfig.Repo=$GitHubRepo",
+    "-X mekari-esign/updater.PublicKey=$PublicKey",
     "-s",
     "-w"
 ) -join " "
@@ -1,35 +1,148 @@
 package main
 
 import (
+	"flag"
+	"os"
+	_ "time/tzdata" // app.timezone zones on Windows servers without a zone database
+
 	"go.uber.org/fx"
 
+	"mekari-esign/internal/backup"
+	"mekari-esign/internal/benchcmd"
 	"mekari-esign/internal/config"
+	"mekari-esign/internal/configcheck"
+	"mekari-esign/internal/contractcmd"
 	deliveryhttp "mekari-esign/internal/delivery/http"
 	"mekari-esign/internal/infrastructure/database"
 	"mekari-esign/internal/infrastructure/document"
 	"mekari-esign/internal/infrastructure/httpclient"
+	"mekari-esign/internal/infrastructure/i18n"
 	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/infrastructure/mekarimock"
+	"mekari-esign/internal/infrastructure/metrics"
 	"mekari-esign/internal/infrastructure/nav"
+	"mekari-esign/internal/infrastructure/notifier"
 	"mekari-esign/internal/infrastructure/oauth2"
 	"mekari-esign/internal/infrastructure/redis"
+	"mekari-esign/internal/infrastructure/reload"
 	"mekari-esign/internal/infrastructure/repository"
+	"mekari-esign/internal/infrastructure/scheduler"
+	"mekari-esign/internal/infrastructure/shutdown"
+	"mekari-esign/internal/navcmd"
 	"mekari-esign/internal/server"
+	"mekari-esign/internal/statuscmd"
+	"mekari-esign/internal/tokencmd"
 	"mekari-esign/internal/usecase"
+	"mekari-esign/internal/worker"
 )
 
+// @title Mekari eSign Integration API
+// @version 1.0
+// @description Integration service between Microsoft Dynamics NAV and Mekari eSign.
+// @BasePath /
+//
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Login token from /auth/login, sent as "Bearer <token>"
+//
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name X-Admin-Token
 func main() {
+	defer logger.RecordCrash()
+
+	validateConfig := flag.Bool("validate-config", false, "Validate the configuration and exit")
+	checkDB := flag.Bool("check-db", false, "With -validate-config, test the database connection")
+	checkRedis := flag.Bool("check-redis", false, "With -validate-config, test the Redis connection")
+	checkNAV := flag.Bool("check-nav", false, "With -validate-config, test the NAV connection")
+	initMasterKey := flag.Bool("init-master-key", false, "Create the master key file for ENC(...) config values")
+	encryptValue := flag.Bool("encrypt-value", false, "Encrypt a value read from stdin as ENC(...) for the config file")
+	backupFile := flag.String("backup", "", "Back up the service tables to a new zip archive at this path and exit")
+	restoreFile := flag.String("restore", "", "Restore the service tables from a zip archive made with -backup and exit")
+	withRedis := flag.Bool("with-redis", false, "With -backup or -restore, include the Redis keys")
+	flag.Parse()
+
+	if flag.Arg(0) == "nav" {
+		if !navcmd.Run(os.Stdout, flag.Args()[1:]) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if flag.Arg(0) == "token" {
+		if !tokencmd.Run(os.Stdout, flag.Args()[1:]) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if flag.Arg(0) == "contract" {
+		if !contractcmd.Run(os.Stdout, flag.Args()[1:]) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if flag.Arg(0) == "bench" {
+		if !benchcmd.Run(os.Stdout, flag.Args()[1:]) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if flag.Arg(0) == "status" {
+		if !statuscmd.Run(os.Stdout) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if *validateConfig {
+		if !configcheck.Run(os.Stdout, configcheck.Options{Database: *checkDB, Redis: *checkRedis, NAV: *checkNAV}) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if *initMasterKey {
+		if !configcheck.InitMasterKey(os.Stdout) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if *encryptValue {
+		if !configcheck.EncryptValue(os.Stdin, os.Stdout) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if *backupFile != "" {
+		if !backup.Backup(os.Stdout, *backupFile, backup.Options{Redis: *withRedis}) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if *restoreFile != "" {
+		if !backup.Restore(os.Stdin, os.Stdout, *restoreFile, backup.Options{Redis: *withRedis}) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	fx.New(
 		// Configuration
 		config.Module,
 
 		// Infrastructure
 		logger.Module,
+		metrics.Module,
 		database.Module,
 		redis.Module,
+		shutdown.Module,
+		scheduler.Module,
 		oauth2.Module,
 		document.Module,
+		reload.Module,
 		httpclient.Module,
+		mekarimock.Module,
 		nav.Module,
+		i18n.Module,
+		notifier.Module,
 		repository.Module,
 
 		// Business Logic
@@ -38,7 +151,13 @@ func main() {
 		// Delivery
 		deliveryhttp.Module,
 
+		// Background workers
+		worker.Module,
+
 		// Server
 		server.Module,
+
+		// Drain deadline is app.shutdown_timeout, enforced by the shutdown coordinator
+		fx.StopTimeout(shutdown.StopTimeout),
 	).Run()
 }
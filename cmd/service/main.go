@@ -7,19 +7,43 @@ import (
 	"os"
 	"path/filepath"
 
+	"mekari-esign/internal/backup"
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/configcheck"
+	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/navcmd"
 	"mekari-esign/internal/service"
+	"mekari-esign/internal/statuscmd"
+	"mekari-esign/internal/tokencmd"
 	"mekari-esign/updater"
 )
 
 func main() {
+	defer logger.RecordCrash()
+
 	// Define command line flags
 	install := flag.Bool("install", false, "Install Windows service")
+	user := flag.String("user", "", "With -install, run the service as this account (DOMAIN\\name) instead of LocalSystem")
+	password := flag.String("password", "", "With -install -user, the password of the account")
 	uninstall := flag.Bool("uninstall", false, "Uninstall Windows service")
 	start := flag.Bool("start", false, "Start the service")
 	stop := flag.Bool("stop", false, "Stop the service")
 	debug := flag.Bool("debug", false, "Run in debug/console mode")
 	update := flag.Bool("update", false, "Check and apply updates from GitHub")
+	installPending := flag.Bool("install-pending", false, "Install the update staged by the background updater and restart the service")
+	rollback := flag.Bool("rollback", false, "Reinstall the version replaced by the last update and restart the service")
 	version := flag.Bool("version", false, "Show version information")
+	validateConfig := flag.Bool("validate-config", false, "Validate the configuration and exit")
+	checkDB := flag.Bool("check-db", false, "With -validate-config, test the database connection")
+	checkRedis := flag.Bool("check-redis", false, "With -validate-config, test the Redis connection")
+	checkNAV := flag.Bool("check-nav", false, "With -validate-config, test the NAV connection")
+	initMasterKey := flag.Bool("init-master-key", false, "Create the master key file for ENC(...) config values")
+	encryptValue := flag.Bool("encrypt-value", false, "Encrypt a value read from stdin as ENC(...) for the config file")
+	backupFile := flag.String("backup", "", "Back up the service tables to a new zip archive at this path and exit")
+	restoreFile := flag.String("restore", "", "Restore the service tables from a zip archive made with -backup and exit")
+	withRedis := flag.Bool("with-redis", false, "With -backup or -restore, include the Redis keys")
+	noProvision := flag.Bool("no-provision", false, "With -install, only install the service: no config file, folders, migrations or connection tests")
+	provisionOpts := configcheck.ProvisionFlags(flag.CommandLine)
 	flag.Parse()
 
 	// Show version
@@ -29,13 +53,13 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Handle update
-	if *update {
-		if err := updater.CheckAndUpdate(); err != nil {
-			log.Printf("Update failed: %v", err)
-			os.Exit(1)
+	// Archive paths are relative to the working directory, not the executable directory
+	for _, path := range []*string{backupFile, restoreFile} {
+		if *path != "" {
+			if abs, err := filepath.Abs(*path); err == nil {
+				*path = abs
+			}
 		}
-		os.Exit(0)
 	}
 
 	// Get executable path
@@ -50,8 +74,89 @@ func main() {
 	}
 
 	switch {
+	case *update:
+		if err := updater.CheckAndUpdate(updateConfig(), service.Manager{}); err != nil {
+			log.Printf("Update failed: %v", err)
+			os.Exit(1)
+		}
+
+	case *installPending:
+		if err := updater.InstallPending(updateConfig(), service.Manager{}); err != nil {
+			log.Printf("Update failed: %v", err)
+			os.Exit(1)
+		}
+
+	case *rollback:
+		if err := updater.Rollback(configFile(), service.Manager{}); err != nil {
+			log.Printf("Rollback failed: %v", err)
+			os.Exit(1)
+		}
+
+	case *validateConfig:
+		// Reads the config next to the executable, like the service
+		if !configcheck.Run(os.Stdout, configcheck.Options{Database: *checkDB, Redis: *checkRedis, NAV: *checkNAV}) {
+			os.Exit(1)
+		}
+
+	case *initMasterKey:
+		// The key file goes next to the config of the executable directory
+		if !configcheck.InitMasterKey(os.Stdout) {
+			os.Exit(1)
+		}
+
+	case *encryptValue:
+		if !configcheck.EncryptValue(os.Stdin, os.Stdout) {
+			os.Exit(1)
+		}
+
+	case flag.Arg(0) == "nav":
+		// Reads the config next to the executable, like the service
+		if !navcmd.Run(os.Stdout, flag.Args()[1:]) {
+			os.Exit(1)
+		}
+
+	case flag.Arg(0) == "token":
+		if !tokencmd.Run(os.Stdout, flag.Args()[1:]) {
+			os.Exit(1)
+		}
+
+	case flag.Arg(0) == "status":
+		if !statuscmd.Run(os.Stdout) {
+			os.Exit(1)
+		}
+
+	case *backupFile != "":
+		// Reads the config next to the executable, like the service
+		if !backup.Backup(os.Stdout, *backupFile, backup.Options{Redis: *withRedis}) {
+			os.Exit(1)
+		}
+
+	case *restoreFile != "":
+		if !backup.Restore(os.Stdin, os.Stdout, *restoreFile, backup.Options{Redis: *withRedis}) {
+			os.Exit(1)
+		}
+
 	case *install:
-		err = service.InstallService(exePath)
+		// A new site gets its config file, folders and tables; the connections are tested first
+		if !*noProvision && !configcheck.Provision(os.Stdin, os.Stdout, provisionOpts) {
+			log.Fatalf("Failed to install service: fix the problems above and run -install again, or use -no-provision")
+		}
+
+		if *user != "" {
+			if *password == "" {
+				// Managed service accounts have no password and cannot be logged on to here
+				fmt.Printf("Warning: no -password given, access of %s to the document folders is not checked\n", *user)
+			} else if err := checkFolderAccess(*user, *password); err != nil {
+				log.Fatalf("Failed to install service: %v", err)
+			}
+		}
+		if *user != "" {
+			if err := grantMasterKey(*user); err != nil {
+				log.Fatalf("Failed to install service: %v", err)
+			}
+		}
+
+		err = service.InstallService(exePath, *user, *password)
 		if err != nil {
 			log.Fatalf("Failed to install service: %v", err)
 		}
@@ -112,16 +217,87 @@ func main() {
 			fmt.Println("Running in console mode. Press Ctrl+C to stop.")
 			fmt.Println()
 			fmt.Println("Available commands:")
-			fmt.Println("  -install    Install as Windows service")
+			fmt.Println("  -install    Provision the site and install as Windows service [-user DOMAIN\\name -password ...]")
+			fmt.Println("              Asks for the settings of a new config.yml; -no-provision to skip")
 			fmt.Println("  -uninstall  Uninstall Windows service")
 			fmt.Println("  -start      Start the service")
 			fmt.Println("  -stop       Stop the service")
 			fmt.Println("  -debug      Run in debug mode")
 			fmt.Println("  -update     Check for updates")
+			fmt.Println("  -rollback   Reinstall the version replaced by the last update")
 			fmt.Println("  -version    Show version")
+			fmt.Println("  status      Show the service, document folders and webhook queue")
+			fmt.Println("  -validate-config [-check-db] [-check-redis] [-check-nav]")
+			fmt.Println("              Validate the configuration and exit")
+			fmt.Println("  nav resend -entry-no <n>")
+			fmt.Println("              Send the NAV log entry of a document again")
+			fmt.Println("  token list | token show|refresh|invalidate -email <email>")
+			fmt.Println("              Inspect and manage the OAuth tokens")
 			fmt.Println()
 
-			app.Run()
+			if err := app.Run(); err != nil {
+				log.Fatalf("Application failed: %v", err)
+			}
+		}
+	}
+}
+
+// checkFolderAccess verifies that the service account can use the document folders of the config
+// next to the executable
+func checkFolderAccess(user, password string) error {
+	cfg, err := config.NewConfig()
+	if err != nil {
+		return fmt.Errorf("cannot load config to check the document folders: %w", err)
+	}
+
+	doc := cfg.Document
+	folders := []string{
+		filepath.Join(doc.BasePath, doc.ReadyFolder),
+		filepath.Join(doc.BasePath, doc.ProgressFolder),
+		filepath.Join(doc.BasePath, doc.FinishFolder),
+	}
+	if err := service.CheckFolderAccess(user, password, folders); err != nil {
+		return err
+	}
+	fmt.Printf("%s can use the document folders\n", user)
+	return nil
+}
+
+// grantMasterKey lets the service account read the master key file, which only SYSTEM and
+// Administrators can read otherwise; without it ENC(...) values stop the service from starting
+func grantMasterKey(user string) error {
+	path, err := config.GrantMasterKey(user)
+	if err != nil {
+		if path == "" {
+			return fmt.Errorf("cannot find the master key file: %w", err)
 		}
+		return fmt.Errorf("%s could not be given read access to %s: %w; grant it in the file's security settings, "+
+			"or set CONFIG_MASTER_KEY for the service instead", user, path, err)
+	}
+	if path != "" {
+		fmt.Printf("%s can read the master key %s\n", user, path)
+	}
+	return nil
+}
+
+// updateConfig returns the updater settings of the config next to the executable. A config that
+// cannot be loaded must not block updates, so the stable channel is used then.
+func updateConfig() updater.GitHubConfig {
+	appConfig, err := config.NewConfig()
+	if err != nil {
+		log.Printf("Warning: could not load config, using the stable channel: %v", err)
+		cfg := updater.DefaultConfig
+		cfg.ConfigFile = config.FileUsed()
+		return cfg
+	}
+	return appConfig.Updater.GitHub()
+}
+
+// configFile returns the path of the config file next to the executable, which a rollback
+// restores along with the executable. An invalid config is still restored, so errors only warn.
+func configFile() string {
+	if _, err := config.NewConfig(); err != nil {
+		log.Printf("Warning: could not load config: %v", err)
 	}
+	return config.FileUsed()
 }
@@ -0,0 +1,16 @@
+// Package docs embeds the OpenAPI spec generated by `make swagger`.
+package docs
+
+import "embed"
+
+// SwaggerJSON is the generated swagger.json served at /docs/swagger.json
+//
+//go:embed swagger.json
+var SwaggerJSON []byte
+
+// SwaggerUI holds the swagger-ui-dist 5.18.2 assets (Apache-2.0, see swagger-ui/LICENSE) served
+// under /docs/assets, so the page also works on hosts without internet access. `make swagger-ui`
+// refreshes them with their license files.
+//
+//go:embed swagger-ui
+var SwaggerUI embed.FS
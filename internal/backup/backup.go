@@ -0,0 +1,265 @@
+// Package backup implements the -backup and -restore command line modes: it copies the tables of
+// the service schema, and optionally its Redis keys, to a single zip archive and restores them from
+// one, for disaster recovery of a host.
+//
+// The archive holds manifest.json, one tables/<name>.jsonl file per table with a JSON object per
+// row, and redis.jsonl with the Redis keys without their key prefix.
+package backup
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/infrastructure/database"
+	"mekari-esign/internal/infrastructure/redis"
+	"mekari-esign/updater"
+)
+
+const (
+	manifestFile = "manifest.json"
+	redisFile    = "redis.jsonl"
+	// redisScanCount is the SCAN batch size used when copying the Redis keys
+	redisScanCount = 500
+)
+
+// Options selects what is copied besides the database tables
+type Options struct {
+	Redis bool
+}
+
+// manifest describes the contents of an archive
+type manifest struct {
+	Version   string      `json:"version"`
+	CreatedAt time.Time   `json:"created_at"`
+	Driver    string      `json:"driver"`
+	Tables    []tableDump `json:"tables"`
+	Redis     bool        `json:"redis"`
+	KeyPrefix string      `json:"key_prefix,omitempty"` // Prefix the keys were stored under
+	RedisKeys int         `json:"redis_keys"`
+}
+
+type tableDump struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Rows    int      `json:"rows"`
+}
+
+// redisEntry is a string key of redis.jsonl; TTL is 0 for keys without expiry
+type redisEntry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	TTLMs int64  `json:"ttl_ms,omitempty"`
+}
+
+func tableFile(name string) string {
+	return "tables/" + name + ".jsonl"
+}
+
+// Backup writes the archive to path, which must not exist, and reports progress to out. It returns
+// false on failure, removing the incomplete archive.
+func Backup(out io.Writer, path string, opts Options) bool {
+	cfg, err := config.NewConfig()
+	if err != nil {
+		fmt.Fprintf(out, "Failed to load configuration: %v\n", err)
+		return false
+	}
+	cfg.Startup.Wait = false // report an unavailable database at once
+
+	// Copying a large table takes longer than database.query_timeout
+	if err := backup(database.WithoutQueryTimeout(context.Background()), out, cfg, path, opts); err != nil {
+		fmt.Fprintf(out, "Backup FAILED: %v\n", err)
+		return false
+	}
+	return true
+}
+
+func backup(ctx context.Context, out io.Writer, cfg *config.Config, path string, opts Options) (err error) {
+	start := time.Now()
+
+	db, err := database.NewDatabase(cfg, zap.NewNop())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var client *redis.RedisClient
+	if opts.Redis {
+		if client, err = redis.NewRedisClient(cfg, zap.NewNop()); err != nil {
+			return err
+		}
+		defer client.Close()
+	}
+
+	// An existing file is never overwritten
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		file.Close()
+		if err != nil {
+			os.Remove(path)
+		}
+	}()
+	archive := zip.NewWriter(file)
+
+	m := manifest{
+		Version:   updater.Version,
+		CreatedAt: time.Now().UTC(),
+		Driver:    cfg.Database.Driver,
+		Redis:     opts.Redis,
+	}
+
+	fmt.Fprintf(out, "Backing up to %s\n", path)
+	for _, table := range database.Tables {
+		dump, err := dumpTable(ctx, db, archive, table.Name)
+		if err != nil {
+			return fmt.Errorf("table %s: %w", table.Name, err)
+		}
+		m.Tables = append(m.Tables, *dump)
+		fmt.Fprintf(out, "  %-26s %d rows\n", table.Name, dump.Rows)
+	}
+
+	if client != nil {
+		m.KeyPrefix = cfg.Redis.KeyPrefix
+		if m.RedisKeys, err = dumpRedis(ctx, client, archive); err != nil {
+			return fmt.Errorf("redis: %w", err)
+		}
+		fmt.Fprintf(out, "  %-26s %d keys\n", "redis", m.RedisKeys)
+	}
+
+	if err := writeJSON(archive, manifestFile, m); err != nil {
+		return err
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Backup completed in %s\n", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// dumpTable writes every row of a table as a JSON object. Text comes back as []byte from some
+// drivers and is stored as a string.
+func dumpTable(ctx context.Context, db *database.Database, archive *zip.Writer, table string) (*tableDump, error) {
+	rows, err := db.QueryContext(ctx, "SELECT * FROM "+table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := archive.Create(tableFile(table))
+	if err != nil {
+		return nil, err
+	}
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+
+	dump := &tableDump{Name: table, Columns: columns}
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			if b, ok := values[i].([]byte); ok {
+				row[column] = string(b)
+			} else {
+				row[column] = values[i]
+			}
+		}
+		if err := enc.Encode(row); err != nil {
+			return nil, err
+		}
+		dump.Rows++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return dump, buf.Flush()
+}
+
+// dumpRedis writes the string keys under the key prefix with their remaining expiry
+func dumpRedis(ctx context.Context, client *redis.RedisClient, archive *zip.Writer) (int, error) {
+	w, err := archive.Create(redisFile)
+	if err != nil {
+		return 0, err
+	}
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+
+	count := 0
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, "*", redisScanCount)
+		if err != nil {
+			return count, err
+		}
+
+		values, err := client.MGet(ctx, keys...)
+		if err != nil {
+			return count, fmt.Errorf("failed to get keys: %w", err)
+		}
+		ttls, err := client.TTLs(ctx, keys...)
+		if err != nil {
+			return count, fmt.Errorf("failed to get ttls: %w", err)
+		}
+
+		for i, key := range keys {
+			value, ok := values[key]
+			if !ok {
+				continue // Expired meanwhile, or not a string
+			}
+
+			entry := redisEntry{Key: key, Value: value}
+			if ttls[i] > 0 {
+				entry.TTLMs = ttls[i].Milliseconds()
+			}
+			if err := enc.Encode(entry); err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+
+	return count, buf.Flush()
+}
+
+func writeJSON(archive *zip.Writer, name string, v interface{}) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
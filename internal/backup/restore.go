@@ -0,0 +1,269 @@
+package backup
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/infrastructure/database"
+	"mekari-esign/internal/infrastructure/redis"
+)
+
+// maxLineSize bounds one row of a table file; API log bodies are the largest values
+const maxLineSize = 64 << 20
+
+// Restore replaces the tables of the configured database with the rows of the archive at path,
+// and writes its Redis keys when opts.Redis is set. It asks for confirmation on in and reports
+// progress to out, returning false on failure.
+func Restore(in io.Reader, out io.Writer, path string, opts Options) bool {
+	cfg, err := config.NewConfig()
+	if err != nil {
+		fmt.Fprintf(out, "Failed to load configuration: %v\n", err)
+		return false
+	}
+	cfg.Startup.Wait = false // report an unavailable database at once
+
+	// Replacing a large table takes longer than database.query_timeout
+	if err := restore(database.WithoutQueryTimeout(context.Background()), in, out, cfg, path, opts); err != nil {
+		fmt.Fprintf(out, "Restore FAILED: %v\n", err)
+		return false
+	}
+	return true
+}
+
+func restore(ctx context.Context, in io.Reader, out io.Writer, cfg *config.Config, path string, opts Options) error {
+	start := time.Now()
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	var m manifest
+	if err := readJSON(&archive.Reader, manifestFile, &m); err != nil {
+		return fmt.Errorf("%s is not a backup archive: %w", path, err)
+	}
+	if m.Driver != cfg.Database.Driver {
+		return fmt.Errorf("archive is of a %s database, database.driver is %s", m.Driver, cfg.Database.Driver)
+	}
+	if opts.Redis && !m.Redis {
+		return errors.New("archive holds no Redis keys; back up with -with-redis")
+	}
+
+	fmt.Fprintf(out, "Archive %s: version %s, created %s\n", path, m.Version, m.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(out, "This REPLACES every row of %d tables in %s/%s", len(m.Tables), cfg.Database.Host, cfg.Database.DBName)
+	if opts.Redis {
+		fmt.Fprintf(out, " and writes %d Redis keys under %q", m.RedisKeys, cfg.Redis.KeyPrefix)
+	}
+	fmt.Fprint(out, ". Stop the service first.\nType yes to continue: ")
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	if strings.TrimSpace(answer) != "yes" {
+		return errors.New("cancelled")
+	}
+
+	db, err := database.NewDatabase(cfg, zap.NewNop())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var client *redis.RedisClient
+	if opts.Redis {
+		if client, err = redis.NewRedisClient(cfg, zap.NewNop()); err != nil {
+			return err
+		}
+		defer client.Close()
+	}
+
+	identity := make(map[string]bool, len(database.Tables))
+	for _, table := range database.Tables {
+		identity[table.Name] = table.Identity
+	}
+
+	for _, dump := range m.Tables {
+		if _, ok := identity[dump.Name]; !ok {
+			return fmt.Errorf("archive holds unknown table %s", dump.Name)
+		}
+		if err := restoreTable(ctx, db, &archive.Reader, dump, identity[dump.Name]); err != nil {
+			return fmt.Errorf("table %s: %w", dump.Name, err)
+		}
+		fmt.Fprintf(out, "  %-26s %d rows\n", dump.Name, dump.Rows)
+	}
+
+	if client != nil {
+		count, err := restoreRedis(ctx, client, &archive.Reader)
+		if err != nil {
+			return fmt.Errorf("redis: %w", err)
+		}
+		fmt.Fprintf(out, "  %-26s %d keys\n", "redis", count)
+	}
+
+	fmt.Fprintf(out, "Restore completed in %s\n", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// restoreTable deletes the rows of a table and inserts the archived ones in one transaction
+func restoreTable(ctx context.Context, db *database.Database, archive *zip.Reader, dump tableDump, identity bool) error {
+	file, err := archive.Open(tableFile(dump.Name))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	timeColumns, err := timestampColumns(ctx, db, dump)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", dump.Name, strings.Join(dump.Columns, ", "), database.Placeholders(1, len(dump.Columns)))
+
+	return db.WithTx(ctx, func(tx *database.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+dump.Name); err != nil {
+			return err
+		}
+
+		var before, after string
+		if identity {
+			before, after = db.Dialect.IdentityInsert(dump.Name)
+		}
+		if before != "" {
+			if _, err := tx.ExecContext(ctx, before); err != nil {
+				return err
+			}
+		}
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+		restored := 0
+		for scanner.Scan() {
+			args, err := rowArgs(scanner.Bytes(), dump.Columns, timeColumns)
+			if err != nil {
+				return fmt.Errorf("row %d: %w", restored+1, err)
+			}
+			if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+				return fmt.Errorf("row %d: %w", restored+1, err)
+			}
+			restored++
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+		if restored != dump.Rows {
+			return fmt.Errorf("archive holds %d rows, manifest lists %d", restored, dump.Rows)
+		}
+
+		if after != "" {
+			if _, err := tx.ExecContext(ctx, after); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// timestampColumns returns the archived columns the database stores as dates or times; JSON holds
+// them as RFC 3339 strings, which not every driver converts back on its own
+func timestampColumns(ctx context.Context, db *database.Database, dump tableDump) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE 1 = 0", strings.Join(dump.Columns, ", "), dump.Name))
+	if err != nil {
+		return nil, fmt.Errorf("archive does not match the schema: %w", err)
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]bool)
+	for _, t := range types {
+		name := strings.ToUpper(t.DatabaseTypeName())
+		if strings.Contains(name, "TIME") || strings.Contains(name, "DATE") {
+			columns[t.Name()] = true
+		}
+	}
+	return columns, nil
+}
+
+// rowArgs converts an archived row to the insert arguments in column order
+func rowArgs(line []byte, columns []string, timeColumns map[string]bool) ([]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+
+	var row map[string]interface{}
+	if err := dec.Decode(&row); err != nil {
+		return nil, err
+	}
+
+	args := make([]interface{}, len(columns))
+	for i, column := range columns {
+		switch value := row[column].(type) {
+		case json.Number:
+			if n, err := value.Int64(); err == nil {
+				args[i] = n
+			} else if f, err := value.Float64(); err == nil {
+				args[i] = f
+			} else {
+				return nil, fmt.Errorf("column %s: %w", column, err)
+			}
+		case string:
+			if timeColumns[column] {
+				t, err := time.Parse(time.RFC3339Nano, value)
+				if err != nil {
+					return nil, fmt.Errorf("column %s: %w", column, err)
+				}
+				args[i] = t
+			} else {
+				args[i] = value
+			}
+		default:
+			args[i] = value
+		}
+	}
+	return args, nil
+}
+
+// restoreRedis writes the archived keys under the current key prefix, keeping their remaining expiry
+func restoreRedis(ctx context.Context, client *redis.RedisClient, archive *zip.Reader) (int, error) {
+	file, err := archive.Open(redisFile)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		var entry redisEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return count, err
+		}
+		ttl := time.Duration(entry.TTLMs) * time.Millisecond
+		if err := client.Set(ctx, entry.Key, entry.Value, ttl); err != nil {
+			return count, fmt.Errorf("failed to set %s: %w", entry.Key, err)
+		}
+		count++
+	}
+	return count, scanner.Err()
+}
+
+func readJSON(archive *zip.Reader, name string, v interface{}) error {
+	file, err := archive.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return json.NewDecoder(file).Decode(v)
+}
@@ -0,0 +1,584 @@
+// Package benchcmd implements the "bench" command line mode, which measures the webhook pipeline
+// under load. It starts the service in-process with Mekari and NAV replaced by local stubs, then
+// sends every generated document through the full flow, as Mekari would: a signing completed
+// webhook that downloads the signed PDF and requests e-meterai stamping, and a stamping success
+// webhook that saves the final PDF and audit trail to the finish folder and updates NAV. The
+// callbacks go through POST /webhook/mekari, the webhook_events queue and the webhook worker,
+// and the report shows throughput, latency percentiles and memory use.
+//
+// The database and Redis of the configuration are used, so run it against a QA setup: the queue
+// must be empty, the mappings and Redis keys of the run are deleted afterwards, and its webhook
+// events and document events are left in the database.
+package benchcmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/fx"
+
+	"mekari-esign/internal/config"
+	deliveryhttp "mekari-esign/internal/delivery/http"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/database"
+	"mekari-esign/internal/infrastructure/document"
+	"mekari-esign/internal/infrastructure/httpclient"
+	"mekari-esign/internal/infrastructure/i18n"
+	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/infrastructure/metrics"
+	"mekari-esign/internal/infrastructure/nav"
+	"mekari-esign/internal/infrastructure/notifier"
+	"mekari-esign/internal/infrastructure/oauth2"
+	"mekari-esign/internal/infrastructure/redis"
+	"mekari-esign/internal/infrastructure/reload"
+	"mekari-esign/internal/infrastructure/repository"
+	"mekari-esign/internal/infrastructure/scheduler"
+	"mekari-esign/internal/infrastructure/shutdown"
+	"mekari-esign/internal/server"
+	"mekari-esign/internal/usecase"
+	"mekari-esign/internal/worker"
+)
+
+const (
+	benchEmail = "bench@example.com"
+
+	// entryNoBase keeps the entry numbers of a run clear of real NAV entries
+	entryNoBase = 1_900_000_000
+
+	// Folders of the run, also reported by the NAV stub setup
+	readyFolder    = "ready"
+	progressFolder = "progress"
+	finishFolder   = "finish"
+
+	// Redis keys the pipeline leaves for a document, see the webhook usecase
+	navSetupKeyPrefix     = "nav_setup:"
+	documentInfoKeyPrefix = "document_info:"
+)
+
+// options are the flags of the bench command
+type options struct {
+	documents   int
+	concurrency int
+	pages       int
+	sizeKB      int
+	timeout     time.Duration
+	logLevel    string
+}
+
+// deps are the components the benchmark prepares and cleans up the documents with
+type deps struct {
+	fx.In
+
+	Config   *config.Config
+	Mappings repository.MappingRepository
+	Events   repository.WebhookEventRepository
+	Cache    redis.Cache
+}
+
+// webhook is one callback of a document and its timings
+type webhook struct {
+	id       string // Document ID of the callback
+	sentAt   time.Time
+	accepted time.Duration // Until POST /webhook/mekari answered
+	started  time.Time     // Start of the successful processing attempt
+	finished time.Time
+	failures int // Processing attempts that returned an error
+	lastErr  error
+	done     chan struct{}
+}
+
+// benchDoc is one generated document sent through the pipeline
+type benchDoc struct {
+	invoice  string
+	filename string
+	entryNo  int
+	signed   *webhook // Signing completed, requests stamping
+	stamped  *webhook // Stamping success on the stamp document
+	stampReq chan struct{}
+	start    time.Time
+	end      time.Time
+	err      error
+}
+
+type bench struct {
+	opts    options
+	runID   string
+	dir     string
+	pdf     []byte
+	port    int
+	docs    []*benchDoc
+	byFile  map[string]*benchDoc // By filename, for stamp requests
+	stubs   *stubs
+	client  *http.Client
+	memory  *memorySampler
+	started time.Time
+	elapsed time.Duration
+
+	mu       sync.Mutex
+	webhooks map[string]*webhook // By document ID
+}
+
+// Run runs the benchmark with the flags in args and reports to out. It returns false when a
+// document did not make it through the pipeline.
+func Run(out io.Writer, args []string) bool {
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	flags.SetOutput(out)
+	var opts options
+	flags.IntVar(&opts.documents, "n", 200, "Number of documents to send through the pipeline")
+	flags.IntVar(&opts.concurrency, "concurrency", 10, "Documents in flight at once")
+	flags.IntVar(&opts.pages, "pages", 1, "Pages of each generated PDF")
+	flags.IntVar(&opts.sizeKB, "size", 0, "KB of padding added to each generated PDF")
+	flags.DurationVar(&opts.timeout, "timeout", 10*time.Minute, "Give up on the documents not finished by then")
+	flags.StringVar(&opts.logLevel, "log-level", "warn", "logging.level of the service during the run")
+	if err := flags.Parse(args); err != nil {
+		return false
+	}
+	if opts.documents < 1 || opts.concurrency < 1 || opts.pages < 1 || opts.sizeKB < 0 || opts.timeout <= 0 {
+		fmt.Fprintln(out, "-n, -concurrency and -pages must be at least 1, -size at least 0 and -timeout positive")
+		return false
+	}
+
+	cfg, err := config.NewConfig()
+	if err != nil {
+		fmt.Fprintf(out, "Failed to load configuration: %v\n", err)
+		return false
+	}
+	if cfg.IsProduction() {
+		fmt.Fprintln(out, "The benchmark writes to the configured database and Redis; it cannot run when app.env is production")
+		return false
+	}
+
+	fmt.Fprintln(out, "Webhook pipeline benchmark")
+
+	b, err := newBench(opts)
+	if err != nil {
+		fmt.Fprintf(out, "Failed to prepare: %v\n", err)
+		return false
+	}
+	defer b.close()
+
+	fmt.Fprintf(out, "  Documents: %d of %d page(s), %s each, %d in flight\n", opts.documents, opts.pages, formatBytes(uint64(len(b.pdf))), opts.concurrency)
+	fmt.Fprintf(out, "  Service:   http://127.0.0.1:%d (Mekari stub %s, NAV stub %s)\n", b.port, b.stubs.mekari.URL, b.stubs.nav.URL)
+	fmt.Fprintf(out, "  Folders:   %s\n\n", b.dir)
+
+	var d deps
+	app := fx.New(
+		fx.NopLogger,
+		config.Module,
+		fx.Decorate(b.configure),
+		logger.Module,
+		metrics.Module,
+		database.Module,
+		redis.Module,
+		shutdown.Module,
+		scheduler.Module,
+		oauth2.Module,
+		document.Module,
+		reload.Module,
+		httpclient.Module,
+		nav.Module,
+		i18n.Module,
+		notifier.Module,
+		repository.Module,
+		usecase.Module,
+		fx.Decorate(b.track),
+		deliveryhttp.Module,
+		fx.Invoke(checkQueueEmpty),
+		fx.Invoke(worker.NewWebhookWorker),
+		server.Module,
+		fx.Populate(&d),
+	)
+
+	ctx := context.Background()
+	if err := app.Start(ctx); err != nil {
+		fmt.Fprintf(out, "Failed to start: %v\n", err)
+		return false
+	}
+
+	if err := b.prepare(ctx, d); err != nil {
+		fmt.Fprintf(out, "Failed to prepare documents: %v\n", err)
+		b.cleanup(ctx, d)
+		app.Stop(ctx)
+		return false
+	}
+
+	b.run(ctx)
+	b.cleanup(ctx, d)
+	app.Stop(ctx)
+
+	return b.report(out)
+}
+
+func newBench(opts options) (*bench, error) {
+	b := &bench{
+		opts:     opts,
+		runID:    newRunID(),
+		byFile:   make(map[string]*benchDoc),
+		webhooks: make(map[string]*webhook),
+		client:   &http.Client{Timeout: time.Minute},
+	}
+
+	port, err := freePort()
+	if err != nil {
+		return nil, fmt.Errorf("no free port for the service: %w", err)
+	}
+	b.port = port
+
+	b.dir, err = os.MkdirTemp("", "mekari-esign-bench-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the document folders: %w", err)
+	}
+
+	b.pdf = generatePDF("Mekari eSign benchmark "+b.runID, opts.pages, opts.sizeKB)
+
+	for i := 1; i <= opts.documents; i++ {
+		id := fmt.Sprintf("bench-%s-%05d", b.runID, i)
+		doc := &benchDoc{
+			invoice:  fmt.Sprintf("BENCH-%s-%05d", b.runID, i),
+			entryNo:  entryNoBase + i,
+			signed:   &webhook{id: id, done: make(chan struct{})},
+			stamped:  &webhook{id: id + "-stamp", done: make(chan struct{})},
+			stampReq: make(chan struct{}),
+		}
+		doc.filename = doc.invoice + ".pdf"
+		b.docs = append(b.docs, doc)
+		b.byFile[doc.filename] = doc
+		b.webhooks[doc.signed.id] = doc.signed
+		b.webhooks[doc.stamped.id] = doc.stamped
+	}
+
+	b.stubs = newStubs(b)
+	return b, nil
+}
+
+func (b *bench) close() {
+	if b.stubs != nil {
+		b.stubs.close()
+	}
+	if b.dir != "" {
+		os.RemoveAll(b.dir)
+	}
+}
+
+// configure points the service at the stubs and the temporary folders and leaves out what
+// would reach beyond this machine
+func (b *bench) configure(cfg *config.Config) *config.Config {
+	cfg.App.Host = "127.0.0.1"
+	cfg.App.Port = b.port
+	cfg.App.Listen = nil
+	cfg.App.BaseURL = b.serviceURL()
+	cfg.App.WatchConfig = false
+
+	cfg.Mekari.Mock = false
+	cfg.Mekari.AuthType = config.AuthTypeHMAC
+	cfg.Mekari.HMAC = config.HMACCredentials{ClientID: "bench", ClientSecret: "bench"}
+	cfg.Mekari.BaseURL = b.stubs.mekari.URL
+	cfg.Mekari.SsoBaseURL = b.stubs.mekari.URL
+	cfg.Mekari.AuthURL = b.stubs.mekari.URL
+
+	cfg.NAV = config.NAVConfig{
+		Enabled:  true,
+		BaseURL:  b.stubs.nav.URL,
+		Company:  "BENCH",
+		Username: "bench",
+		Password: "bench",
+		Timeout:  30,
+	}
+
+	cfg.Document.BasePath = b.dir
+	cfg.Document.ReadyFolder = readyFolder
+	cfg.Document.ProgressFolder = progressFolder
+	cfg.Document.FinishFolder = finishFolder
+	cfg.RateLimit.Enabled = false
+	cfg.Logging.Level = b.opts.logLevel
+	cfg.Logging.Levels = nil
+	cfg.Notifier = config.NotifierConfig{}
+	cfg.Sentry.DSN = ""
+	cfg.Startup.Wait = false
+	return cfg
+}
+
+// track wraps the webhook usecase to time every processing attempt
+func (b *bench) track(u usecase.WebhookUsecase) usecase.WebhookUsecase {
+	return &trackedUsecase{WebhookUsecase: u, bench: b}
+}
+
+// checkQueueEmpty refuses to start the worker while the queue holds events of other documents,
+// which it would process against the stubs
+func checkQueueEmpty(lc fx.Lifecycle, cfg *config.Config, events repository.WebhookEventRepository) {
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			counts, err := events.CountByStatus(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to check the webhook queue: %w", err)
+			}
+			if n := counts[entity.WebhookEventPending] + counts[entity.WebhookEventProcessing]; n > 0 {
+				return fmt.Errorf("the webhook queue has %d unprocessed event(s); run the benchmark against a QA database", n)
+			}
+
+			failed, err := events.FindAll(ctx, entity.WebhookEventFailed, 1000)
+			if err != nil {
+				return fmt.Errorf("failed to check the webhook queue: %w", err)
+			}
+			for _, event := range failed {
+				if event.Attempts < cfg.Webhook.MaxAttempts {
+					return errors.New("the webhook queue has failed events still to be retried; run the benchmark against a QA database")
+				}
+			}
+			return nil
+		},
+	})
+}
+
+// prepare puts every document in progress with the mapping of a sent sign request
+func (b *bench) prepare(ctx context.Context, d deps) error {
+	progress := b.folder(progressFolder)
+	for _, folder := range []string{readyFolder, progressFolder, finishFolder} {
+		if err := os.MkdirAll(b.folder(folder), 0755); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	for _, doc := range b.docs {
+		if err := os.WriteFile(filepath.Join(progress, doc.filename), b.pdf, 0644); err != nil {
+			return err
+		}
+
+		mapping := &entity.DocumentMapping{
+			DocumentID:     doc.signed.id,
+			Email:          benchEmail,
+			InvoiceNumber:  doc.invoice,
+			Filename:       doc.filename,
+			StampPositions: &entity.StampPosition{X: 400, Y: 650, Width: 100, Height: 100, Page: 1},
+			EntryNo:        doc.entryNo,
+			Signing:        true,
+			Stamping:       true,
+			StampPlacement: config.StampPlacementFixed,
+			SubmittedAt:    &now,
+			SigningStatus:  "in_progress",
+		}
+		if err := d.Mappings.SaveByDocumentID(ctx, doc.signed.id, mapping); err != nil {
+			return err
+		}
+		if err := d.Mappings.SaveByEntryNo(ctx, doc.entryNo, mapping); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run sends the documents through the pipeline, opts.concurrency at a time, until all are
+// finished or opts.timeout passes
+func (b *bench) run(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, b.opts.timeout)
+	defer cancel()
+
+	b.memory = startMemorySampler()
+	b.started = time.Now()
+
+	sem := make(chan struct{}, b.opts.concurrency)
+	var wg sync.WaitGroup
+	for _, doc := range b.docs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(doc *benchDoc) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			doc.err = b.runDocument(ctx, doc)
+		}(doc)
+	}
+	wg.Wait()
+
+	b.elapsed = time.Since(b.started)
+	b.memory.stop()
+}
+
+// runDocument sends the two webhooks of a document and waits for each to be processed
+func (b *bench) runDocument(ctx context.Context, doc *benchDoc) error {
+	doc.start = time.Now()
+
+	if err := b.send(ctx, doc.signed, doc, "none"); err != nil {
+		return err
+	}
+	if err := b.wait(ctx, doc.signed); err != nil {
+		return err
+	}
+
+	// A failed stamp request is only logged by the pipeline; the document would wait forever
+	select {
+	case <-doc.stampReq:
+	default:
+		return errors.New("no stamp request was sent, see the service logs")
+	}
+
+	if err := b.send(ctx, doc.stamped, doc, "success"); err != nil {
+		return err
+	}
+	if err := b.wait(ctx, doc.stamped); err != nil {
+		return err
+	}
+
+	doc.end = time.Now()
+	return nil
+}
+
+// send posts a webhook of a document to the service like Mekari does
+func (b *bench) send(ctx context.Context, w *webhook, doc *benchDoc, stampingStatus string) error {
+	now := time.Now()
+	signedAt := now.Format(time.RFC3339)
+	payload := entity.WebhookPayload{
+		Data: entity.WebhookData{
+			ID:   w.id,
+			Type: "document",
+			Attributes: entity.WebhookAttributes{
+				Filename:       doc.filename,
+				Category:       "global",
+				DocURL:         "/documents/" + w.id + "/download",
+				SigningStatus:  entity.SigningStatusCompleted,
+				StampingStatus: stampingStatus,
+				Signers: []entity.WebhookSigner{{
+					Name:     "Bench Signer",
+					Email:    benchEmail,
+					Order:    1,
+					Status:   "completed",
+					SignedAt: &signedAt,
+				}},
+				CreatedAt: doc.start,
+				UpdatedAt: now,
+			},
+		},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.serviceURL()+"/webhook/mekari", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	b.mu.Lock()
+	w.sentAt = time.Now()
+	b.mu.Unlock()
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook %s not delivered: %w", w.id, err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	b.mu.Lock()
+	w.accepted = time.Since(w.sentAt)
+	b.mu.Unlock()
+
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("webhook %s answered with status %d", w.id, resp.StatusCode)
+	}
+	return nil
+}
+
+// wait waits until a webhook is processed successfully
+func (b *bench) wait(ctx context.Context, w *webhook) error {
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if w.lastErr != nil {
+			return fmt.Errorf("webhook %s not processed after %d attempt(s): %w", w.id, w.failures, w.lastErr)
+		}
+		return fmt.Errorf("webhook %s not processed within %s", w.id, b.opts.timeout)
+	}
+}
+
+// processed records a processing attempt of a webhook
+func (b *bench) processed(id string, started time.Time, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	w, ok := b.webhooks[id]
+	if !ok {
+		return
+	}
+	if err != nil {
+		w.failures++
+		w.lastErr = err
+		return
+	}
+	if w.finished.IsZero() {
+		w.started = started
+		w.finished = time.Now()
+		close(w.done)
+	}
+}
+
+// cleanup deletes the mappings and Redis keys of the run
+func (b *bench) cleanup(ctx context.Context, d deps) {
+	var ids, keys []string
+	for _, doc := range b.docs {
+		ids = append(ids, doc.signed.id, doc.stamped.id)
+		keys = append(keys,
+			navSetupKeyPrefix+strconv.Itoa(doc.entryNo),
+			documentInfoKeyPrefix+doc.signed.id,
+			documentInfoKeyPrefix+doc.stamped.id,
+		)
+		d.Mappings.DeleteByEntryNo(ctx, doc.entryNo)
+	}
+	d.Mappings.DeleteByDocumentID(ctx, ids...)
+	d.Cache.Del(ctx, keys...)
+}
+
+func (b *bench) folder(name string) string {
+	return filepath.Join(b.dir, name)
+}
+
+func (b *bench) serviceURL() string {
+	return fmt.Sprintf("http://127.0.0.1:%d", b.port)
+}
+
+// trackedUsecase is the webhook usecase of the service, timing every processing attempt
+type trackedUsecase struct {
+	usecase.WebhookUsecase
+	bench *bench
+}
+
+func (t *trackedUsecase) ProcessWebhook(ctx context.Context, payload *entity.WebhookPayload) error {
+	started := time.Now()
+	err := t.WebhookUsecase.ProcessWebhook(ctx, payload)
+	t.bench.processed(payload.Data.ID, started, err)
+	return err
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+func newRunID() string {
+	b := make([]byte, 3)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,50 @@
+package benchcmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// generatePDF returns a PDF of pages pages showing label, padded with sizeKB KB of comment lines
+// in the first page's content so the documents can be sized like real invoices
+func generatePDF(label string, pages, sizeKB int) []byte {
+	var buf bytes.Buffer
+	var offsets []int
+
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// 1 catalog, 2 pages, 3 font, then a page and its content per page
+	kids := make([]string, pages)
+	for i := range kids {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pages))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+
+	padding := strings.Repeat("% "+strings.Repeat("x", 62)+"\n", sizeKB*1024/65)
+	for i := 0; i < pages; i++ {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", 5+2*i))
+
+		content := fmt.Sprintf("BT /F1 18 Tf 72 760 Td (%s - page %d of %d) Tj ET\n", label, i+1, pages)
+		if i == 0 {
+			content += padding
+		}
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.Bytes()
+}
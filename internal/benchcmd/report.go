@@ -0,0 +1,248 @@
+package benchcmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Check statuses, as printed by -validate-config
+const (
+	statusOK   = "OK"
+	statusWarn = "WARN"
+	statusFail = "FAIL"
+)
+
+// memorySampleInterval is how often the memory of the process is sampled during a run
+const memorySampleInterval = 100 * time.Millisecond
+
+type result struct {
+	status string
+	name   string
+	detail string
+}
+
+// memorySampler records the memory and goroutine peaks of the process during a run
+type memorySampler struct {
+	before         runtime.MemStats
+	after          runtime.MemStats
+	peakHeap       uint64
+	peakGoroutines int
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func startMemorySampler() *memorySampler {
+	m := &memorySampler{done: make(chan struct{})}
+	runtime.ReadMemStats(&m.before)
+	m.sample()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(memorySampleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.done:
+				return
+			case <-ticker.C:
+				m.sample()
+			}
+		}
+	}()
+	return m
+}
+
+func (m *memorySampler) sample() {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	if stats.HeapAlloc > m.peakHeap {
+		m.peakHeap = stats.HeapAlloc
+	}
+	if n := runtime.NumGoroutine(); n > m.peakGoroutines {
+		m.peakGoroutines = n
+	}
+}
+
+func (m *memorySampler) stop() {
+	close(m.done)
+	m.wg.Wait()
+	m.sample()
+	runtime.ReadMemStats(&m.after)
+}
+
+// report prints the checks, throughput, latencies and memory of the run and returns false when
+// a document did not finish
+func (b *bench) report(out io.Writer) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var accept, queue, processing, endToEnd []time.Duration
+	finished, failures, webhooks := 0, 0, 0
+	var firstErr, lastFailure error
+	for _, doc := range b.docs {
+		for _, w := range []*webhook{doc.signed, doc.stamped} {
+			failures += w.failures
+			if w.lastErr != nil {
+				lastFailure = w.lastErr
+			}
+			if !w.sentAt.IsZero() && w.accepted > 0 {
+				accept = append(accept, w.accepted)
+			}
+			if !w.finished.IsZero() {
+				webhooks++
+				queue = append(queue, w.started.Sub(w.sentAt))
+				processing = append(processing, w.finished.Sub(w.started))
+			}
+		}
+		if doc.err != nil {
+			if firstErr == nil {
+				firstErr = doc.err
+			}
+			continue
+		}
+		finished++
+		endToEnd = append(endToEnd, doc.end.Sub(doc.start))
+	}
+
+	var results []result
+	if finished == len(b.docs) {
+		results = append(results, result{statusOK, "Documents finished", fmt.Sprintf("%d/%d", finished, len(b.docs))})
+	} else {
+		results = append(results, result{statusFail, "Documents finished", fmt.Sprintf("%d/%d, first error: %v", finished, len(b.docs), firstErr)})
+	}
+
+	saved, err := b.countFinished()
+	switch {
+	case err != nil:
+		results = append(results, result{statusFail, "Final documents in finish", err.Error()})
+	case saved != finished:
+		results = append(results, result{statusFail, "Final documents in finish", fmt.Sprintf("%d, expected %d", saved, finished)})
+	default:
+		results = append(results, result{statusOK, "Final documents in finish", fmt.Sprintf("%d", saved)})
+	}
+
+	if failures > 0 {
+		results = append(results, result{statusWarn, "Failed processing attempts", fmt.Sprintf("%d, last error: %v", failures, lastFailure)})
+	}
+
+	failed := 0
+	for _, r := range results {
+		line := fmt.Sprintf("  [%-4s] %s", r.status, r.name)
+		if r.detail != "" {
+			line += ": " + r.detail
+		}
+		fmt.Fprintln(out, line)
+		if r.status == statusFail {
+			failed++
+		}
+	}
+
+	stamps, navUpdates := b.stubs.counts()
+	seconds := b.elapsed.Seconds()
+	fmt.Fprintln(out, "\nThroughput")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  Wall time\t%s\n", b.elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "  Documents/s\t%.2f\n", float64(finished)/seconds)
+	fmt.Fprintf(w, "  Webhooks/s\t%.2f\n", float64(webhooks)/seconds)
+	fmt.Fprintf(w, "  Stamp requests\t%d\n", stamps)
+	fmt.Fprintf(w, "  NAV updates\t%d\n", navUpdates)
+	w.Flush()
+
+	fmt.Fprintln(out, "\nLatency")
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "\tcount\tp50\tp90\tp99\tmax\t")
+	latencyRow(w, "Webhook accepted", accept)
+	latencyRow(w, "Queue wait", queue)
+	latencyRow(w, "Processing", processing)
+	latencyRow(w, "Document end to end", endToEnd)
+	w.Flush()
+
+	m := b.memory
+	fmt.Fprintln(out, "\nMemory")
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  Peak heap\t%s\n", formatBytes(m.peakHeap))
+	fmt.Fprintf(w, "  Heap after run\t%s\n", formatBytes(m.after.HeapAlloc))
+	fmt.Fprintf(w, "  Allocated\t%s (%s per document)\n", formatBytes(m.after.TotalAlloc-m.before.TotalAlloc), formatBytes((m.after.TotalAlloc-m.before.TotalAlloc)/uint64(len(b.docs))))
+	fmt.Fprintf(w, "  GC cycles\t%d\n", m.after.NumGC-m.before.NumGC)
+	fmt.Fprintf(w, "  Peak goroutines\t%d\n", m.peakGoroutines)
+	w.Flush()
+
+	fmt.Fprintln(out)
+	if failed > 0 {
+		fmt.Fprintf(out, "Benchmark FAILED: %d check(s) failed\n", failed)
+		return false
+	}
+	fmt.Fprintln(out, "Benchmark completed")
+	return true
+}
+
+// countFinished counts the final documents of the run saved to the finish folder
+func (b *bench) countFinished() (int, error) {
+	entries, err := os.ReadDir(b.folder(finishFolder))
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, entry := range entries {
+		if _, ok := b.byFile[entry.Name()]; ok {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func latencyRow(w io.Writer, name string, durations []time.Duration) {
+	if len(durations) == 0 {
+		fmt.Fprintf(w, "  %s\t0\t-\t-\t-\t-\t\n", name)
+		return
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	fmt.Fprintf(w, "  %s\t%d\t%s\t%s\t%s\t%s\t\n", name, len(durations),
+		formatDuration(percentile(durations, 50)),
+		formatDuration(percentile(durations, 90)),
+		formatDuration(percentile(durations, 99)),
+		formatDuration(durations[len(durations)-1]),
+	)
+}
+
+// percentile returns the nearest-rank percentile p of sorted durations
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(100 * time.Microsecond).String()
+	default:
+		return d.Round(time.Microsecond).String()
+	}
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %s", float64(n)/float64(div), strings.Split("KB MB GB TB", " ")[exp])
+}
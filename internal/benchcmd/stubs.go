@@ -0,0 +1,142 @@
+package benchcmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"mekari-esign/internal/domain/entity"
+)
+
+// stubBalance is the e-meterai balance of the Mekari stub, enough for any run
+const stubBalance = 1_000_000
+
+// stubs are the local Mekari and NAV APIs of a run. Mekari serves the generated PDF as every
+// signed, stamped and audit trail document and accepts stamp requests of the run's documents;
+// NAV reports the run's folders as its setup and accepts every log entry.
+type stubs struct {
+	bench  *bench
+	mekari *httptest.Server
+	nav    *httptest.Server
+
+	mu         sync.Mutex
+	stamps     int
+	navUpdates int
+}
+
+func newStubs(b *bench) *stubs {
+	s := &stubs{bench: b}
+
+	mekari := http.NewServeMux()
+	mekari.HandleFunc("GET /profile", s.profile)
+	mekari.HandleFunc("POST /documents/stamp", s.stamp)
+	mekari.HandleFunc("GET /documents/{id}/download", s.download)
+	mekari.HandleFunc("GET /documents/{id}/audit_trail", s.download)
+	s.mekari = httptest.NewServer(mekari)
+
+	s.nav = httptest.NewServer(http.HandlerFunc(s.navAPI))
+	return s
+}
+
+func (s *stubs) close() {
+	s.mekari.Close()
+	s.nav.Close()
+}
+
+func (s *stubs) profile(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, entity.ProfileResponse{
+		Data: &entity.Profile{
+			ID:   "bench-profile",
+			Type: "user",
+			Attributes: entity.ProfileAttributes{
+				ID:     "bench-profile",
+				Email:  benchEmail,
+				Name:   "Mekari Stub",
+				Status: "active",
+				Quota:  &entity.Quota{RemainingEmeterai: stubBalance},
+			},
+		},
+		Message: "OK",
+		Status:  http.StatusOK,
+	})
+}
+
+// stamp accepts the stamp request of a run's document and answers with its stamp document
+func (s *stubs) stamp(w http.ResponseWriter, r *http.Request) {
+	var req entity.StampRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "invalid request body"})
+		return
+	}
+	doc, ok := s.bench.byFile[req.Filename]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "not a document of this run"})
+		return
+	}
+
+	s.mu.Lock()
+	s.stamps++
+	select {
+	case <-doc.stampReq:
+	default:
+		close(doc.stampReq)
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusCreated, entity.StampResponse{
+		Data: &entity.StampData{
+			ID:   doc.stamped.id,
+			Type: "document",
+			Attributes: entity.StampAttributes{
+				DocID:          doc.stamped.id,
+				Filename:       doc.filename,
+				Status:         entity.SigningStatusCompleted,
+				StampingStatus: "in_progress",
+				DocURL:         "/documents/" + doc.stamped.id + "/download",
+				CreatedAt:      time.Now().Format(time.RFC3339),
+			},
+		},
+	})
+}
+
+func (s *stubs) download(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Write(s.bench.pdf)
+}
+
+// navAPI answers the setup with the run's folders and accepts everything else
+func (s *stubs) navAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/Api_MekariSetup") {
+		writeJSON(w, http.StatusOK, entity.NAVSetupResponse{
+			Value: []entity.NAVSetup{{
+				FileLocationOut:     s.bench.folder(readyFolder),
+				FileLocationProcess: s.bench.folder(progressFolder),
+				FileLocationIn:      s.bench.folder(finishFolder),
+			}},
+		})
+		return
+	}
+
+	if r.Method == http.MethodPatch {
+		s.mu.Lock()
+		s.navUpdates++
+		s.mu.Unlock()
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// counts returns the stamp requests and NAV log entry updates received
+func (s *stubs) counts() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stamps, s.navUpdates
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -1,10 +1,26 @@
 package config
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/mitchellh/mapstructure"
+	"github.com/robfig/cron/v3"
 	"github.com/spf13/viper"
+
+	"mekari-esign/internal/config/secrets"
+	"mekari-esign/updater"
 )
 
 // AuthType constants
@@ -13,22 +29,85 @@ const (
 	AuthTypeHMAC   = "hmac"
 )
 
+// Document mapping stores
+const (
+	MappingStoreRedis    = "redis"    // Redis keys with an expiry
+	MappingStoreDatabase = "database" // document_mappings table
+	MappingStoreLayered  = "layered"  // Database, cached in Redis
+)
+
+// Log viewer authentication modes
+const (
+	LogViewerAuthNone  = "none"  // Same rules as other read-only routes
+	LogViewerAuthBasic = "basic" // HTTP basic auth with the configured credentials
+	LogViewerAuthLogin = "login" // User login (JWT) always required
+)
+
 type Config struct {
-	App      AppConfig      `mapstructure:"app"`
-	Mekari   MekariConfig   `mapstructure:"mekari"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	OAuth    OAuthConfig    `mapstructure:"oauth"`
-	Document DocumentConfig `mapstructure:"document"`
-	Logging  LoggingConfig  `mapstructure:"logging"`
-	NAV      NAVConfig      `mapstructure:"nav"`
+	App         AppConfig         `mapstructure:"app"`
+	Mekari      MekariConfig      `mapstructure:"mekari"`
+	Database    DatabaseConfig    `mapstructure:"database"`
+	Redis       RedisConfig       `mapstructure:"redis"`
+	OAuth       OAuthConfig       `mapstructure:"oauth"`
+	Document    DocumentConfig    `mapstructure:"document"`
+	Logging     LoggingConfig     `mapstructure:"logging"`
+	NAV         NAVConfig         `mapstructure:"nav"`
+	Webhook     WebhookConfig     `mapstructure:"webhook"`
+	Docs        DocsConfig        `mapstructure:"docs"`
+	Security    SecurityConfig    `mapstructure:"security"`
+	Auth        AuthConfig        `mapstructure:"auth"`
+	RateLimit   RateLimitConfig   `mapstructure:"rate_limit"`
+	Retention   RetentionConfig   `mapstructure:"retention"`
+	Mapping     MappingConfig     `mapstructure:"mapping"`
+	LogViewer   LogViewerConfig   `mapstructure:"log_viewer"`
+	Dashboard   DashboardConfig   `mapstructure:"dashboard"`
+	Quota       QuotaConfig       `mapstructure:"quota"`
+	Stamping    StampingConfig    `mapstructure:"stamping"`
+	Deadline    DeadlineConfig    `mapstructure:"deadline"`
+	Notifier    NotifierConfig    `mapstructure:"notifier"`
+	Alerts      AlertsConfig      `mapstructure:"alerts"`
+	Metrics     MetricsConfig     `mapstructure:"metrics"`
+	Diagnostics DiagnosticsConfig `mapstructure:"diagnostics"`
+	Sentry      SentryConfig      `mapstructure:"sentry"`
+	Secrets     SecretsConfig     `mapstructure:"secrets"`
+	Updater     UpdaterConfig     `mapstructure:"updater"`
+	Startup     StartupConfig     `mapstructure:"startup"`
+	Scheduler   SchedulerConfig   `mapstructure:"scheduler"`
 }
 
 type AppConfig struct {
-	Name    string `mapstructure:"name"`
-	Port    int    `mapstructure:"port"`
-	Env     string `mapstructure:"env"`
-	BaseURL string `mapstructure:"base_url"`
+	Name              string     `mapstructure:"name"`
+	Host              string     `mapstructure:"host"` // Address the server binds, e.g. 127.0.0.1 (default: all interfaces)
+	Port              int        `mapstructure:"port"`
+	Listen            []string   `mapstructure:"listen"` // host:port addresses to listen on instead of host and port, e.g. ["127.0.0.1:8080", "10.0.0.5:8080"]
+	Env               string     `mapstructure:"env"`
+	BaseURL           string     `mapstructure:"base_url"`
+	ShutdownTimeout   int        `mapstructure:"shutdown_timeout"` // Seconds to drain in-flight requests and background work on stop (default: 30)
+	CORS              CORSConfig `mapstructure:"cors"`
+	BodyLimit         int        `mapstructure:"body_limit"`          // Max request body in MB for most routes (default: 4)
+	SignBodyLimit     int        `mapstructure:"sign_body_limit"`     // Max body in MB for sign requests with inline documents (default: 50)
+	WebhookBodyLimit  int        `mapstructure:"webhook_body_limit"`  // Max body in MB for Mekari webhook callbacks (default: 10)
+	StreamRequestBody bool       `mapstructure:"stream_request_body"` // Stream large bodies instead of buffering them before the handler runs
+	Compress          bool       `mapstructure:"compress"`            // gzip/brotli responses for clients that accept it (default: true)
+	WatchConfig       bool       `mapstructure:"watch_config"`        // Apply reloadable settings when the config file changes (default: true)
+	Timezone          string     `mapstructure:"timezone"`            // IANA zone of dates people type and read, e.g. "Asia/Jakarta" (default: Local, the server's zone)
+	Language          string     `mapstructure:"language"`            // en or id: language of notifications and of API messages and pages without a matching Accept-Language (default: en)
+
+	// Name of this instance in logs, leases and claimed webhook events; set a different one on each
+	// node when two run side by side, and keep it across restarts (default: the host name)
+	InstanceID string `mapstructure:"instance_id"`
+	// Only this instance uses the database and Redis: leases and periodic job runs are granted
+	// while Redis is unreachable instead of refused (default: false)
+	SingleInstance bool `mapstructure:"single_instance"`
+}
+
+type CORSConfig struct {
+	AllowOrigins     []string `mapstructure:"allow_origins"`     // Allowed origins (default: "*" outside production, app.base_url in production)
+	AllowMethods     []string `mapstructure:"allow_methods"`     // Allowed methods (default: GET, POST, PUT, PATCH, DELETE, OPTIONS)
+	AllowHeaders     []string `mapstructure:"allow_headers"`     // Allowed request headers (default: the headers the API reads)
+	ExposeHeaders    []string `mapstructure:"expose_headers"`    // Response headers readable by browsers (default: X-Request-ID)
+	AllowCredentials bool     `mapstructure:"allow_credentials"` // Allow cookies/credentials (not allowed with "*" origins)
+	MaxAge           int      `mapstructure:"max_age"`           // Seconds browsers may cache preflight results (0 = not cached)
 }
 
 type MekariConfig struct {
@@ -39,6 +118,40 @@ type MekariConfig struct {
 	Timeout    time.Duration     `mapstructure:"timeout"`
 	OAuth2     OAuth2Credentials `mapstructure:"oauth2"` // OAuth2 credentials
 	HMAC       HMACCredentials   `mapstructure:"hmac"`   // HMAC credentials
+	Signers    SignerPolicy      `mapstructure:"signers"`
+
+	// Mock replaces Mekari with an in-process fake API for offline testing; never use it in production
+	Mock             bool `mapstructure:"mock"`
+	MockPort         int  `mapstructure:"mock_port"`          // Port of the fake API on 127.0.0.1 (default: 18089)
+	MockWebhookDelay int  `mapstructure:"mock_webhook_delay"` // Seconds between the fake webhooks of a document (default: 3)
+}
+
+// MockURL is the address of the fake Mekari API of mekari.mock
+func (m *MekariConfig) MockURL() string {
+	return fmt.Sprintf("http://127.0.0.1:%d", m.MockPort)
+}
+
+// SignerPolicy is applied to the signers of every sign request before it is sent to Mekari
+type SignerPolicy struct {
+	DefaultCountryCode string   `mapstructure:"default_country_code"` // Country calling code of phone numbers without one (default: 62)
+	RequireOTPExternal bool     `mapstructure:"require_otp_external"` // Require OTP for signers outside internal_domains, whatever the request says
+	InternalDomains    []string `mapstructure:"internal_domains"`     // Email domains of the company's own signers, e.g. ["example.co.id"]
+}
+
+// IsInternal reports whether email belongs to one of the internal domains or their subdomains
+func (p *SignerPolicy) IsInternal(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, internal := range p.InternalDomains {
+		internal = strings.ToLower(strings.TrimPrefix(internal, "@"))
+		if domain == internal || strings.HasSuffix(domain, "."+internal) {
+			return true
+		}
+	}
+	return false
 }
 
 // OAuth2Credentials stores OAuth2 client credentials
@@ -80,24 +193,71 @@ func (m *MekariConfig) IsHMAC() bool {
 }
 
 type DatabaseConfig struct {
-	Driver   string `mapstructure:"driver"`
-	Host     string `mapstructure:"host"`
-	Port     int    `mapstructure:"port"`
-	User     string `mapstructure:"user"`
-	Password string `mapstructure:"password"`
-	DBName   string `mapstructure:"dbname"`
-	SSLMode  string `mapstructure:"sslmode"`
+	Driver          string `mapstructure:"driver"`
+	Host            string `mapstructure:"host"`
+	Port            int    `mapstructure:"port"`
+	User            string `mapstructure:"user"`
+	Password        string `mapstructure:"password"`
+	DBName          string `mapstructure:"dbname"`
+	SSLMode         string `mapstructure:"sslmode"`
+	MaxOpenConns    int    `mapstructure:"max_open_conns"`    // Max open connections; requests wait for a free one beyond it (default: 25)
+	MaxIdleConns    int    `mapstructure:"max_idle_conns"`    // Idle connections kept for reuse, at most max_open_conns (default: 10)
+	ConnMaxLifetime int    `mapstructure:"conn_max_lifetime"` // Seconds before a connection is closed and replaced (default: 300)
+	HealthInterval  int    `mapstructure:"health_interval"`   // Seconds between health pings that detect outages and reconnects (default: 10)
+	LogBuffer       int    `mapstructure:"log_buffer"`        // API logs kept in memory while the database is down, oldest dropped first (default: 1000)
+	LogQueueSize    int    `mapstructure:"log_queue_size"`    // API logs waiting for the batch writer; new logs are dropped when it is full (default: 10000)
+	LogBatchSize    int    `mapstructure:"log_batch_size"`    // API logs written per INSERT (default: 100)
+	LogFlushMs      int    `mapstructure:"log_flush_ms"`      // Milliseconds a partial batch of API logs waits before it is written (default: 500)
+	ReplicaDSN      string `mapstructure:"replica_dsn"`       // Driver-specific DSN of a read-only replica for log and report queries (empty = primary only)
+	QueryTimeout    int    `mapstructure:"query_timeout"`     // Seconds a query may run before it is cancelled (default: 30)
+	SlowQueryMs     int    `mapstructure:"slow_query_ms"`     // Queries slower than this many milliseconds are logged with redacted parameters (default: 1000)
+	LegacyOffset    string `mapstructure:"legacy_offset"`     // UTC offset, e.g. "+07:00", of timestamps written before they were stored in UTC; converted once on start (empty = none)
 }
 
 type RedisConfig struct {
-	Host     string `mapstructure:"host"`
-	Port     int    `mapstructure:"port"`
-	Password string `mapstructure:"password"`
-	DB       int    `mapstructure:"db"`
+	Host     string         `mapstructure:"host"`
+	Port     int            `mapstructure:"port"`
+	Username string         `mapstructure:"username"` // ACL user of Redis 6+ (empty = default user)
+	Password string         `mapstructure:"password"`
+	DB       int            `mapstructure:"db"`
+	TLS      RedisTLSConfig `mapstructure:"tls"`
+
+	KeyPrefix    string `mapstructure:"key_prefix"`    // Prepended to every key; {env} and {company} become app.env and nav.company (default: mekari:)
+	FallbackSize int    `mapstructure:"fallback_size"` // Keys kept in memory to serve tokens, NAV setups and mappings while Redis is down (default: 10000)
+}
+
+type RedisTLSConfig struct {
+	Enabled            bool   `mapstructure:"enabled"`              // Connect over TLS
+	CAFile             string `mapstructure:"ca_file"`              // PEM CA bundle to verify the server (empty = system roots)
+	CertFile           string `mapstructure:"cert_file"`            // PEM client certificate, with key_file, for servers requiring mutual TLS
+	KeyFile            string `mapstructure:"key_file"`             // PEM private key of cert_file
+	ServerName         string `mapstructure:"server_name"`          // Name expected in the server certificate (default: host)
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"` // Skip server certificate verification (testing only)
 }
 
 type OAuthConfig struct {
-	RefreshTokenAgeDays int `mapstructure:"refresh_token_age_days"`
+	RefreshTokenAgeDays  int `mapstructure:"refresh_token_age_days"`
+	DeletedRetentionDays int `mapstructure:"deleted_retention_days"` // Days deleted token records are kept before they are purged (default: 30)
+	PurgeInterval        int `mapstructure:"purge_interval"`         // Hours between purges of deleted token records (default: 24)
+
+	// EncryptionKey is a base64 AES-256 key encrypting the stored OAuth codes and tokens, usually a
+	// secrets manager reference (empty = stored in plain text)
+	EncryptionKey string `mapstructure:"encryption_key"`
+}
+
+// EncryptionKeyBytes returns the decoded oauth.encryption_key, or nil when it is not set
+func (o *OAuthConfig) EncryptionKeyBytes() ([]byte, error) {
+	if o.EncryptionKey == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(o.EncryptionKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid oauth.encryption_key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid oauth.encryption_key: must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
 }
 
 type DocumentConfig struct {
@@ -105,13 +265,33 @@ type DocumentConfig struct {
 	ReadyFolder    string `mapstructure:"ready_folder"`    // Folder for documents ready to send
 	ProgressFolder string `mapstructure:"progress_folder"` // Folder for documents in progress
 	FinishFolder   string `mapstructure:"finish_folder"`   // Folder for completed documents
+	FailedFolder   string `mapstructure:"failed_folder"`   // Folder for documents that expired unsigned (default: failed)
+	OnVoid         string `mapstructure:"on_void"`         // ready or failed: where the file of a voided document goes (default: ready)
+	AttachmentMode string `mapstructure:"attachment_mode"` // merge or separate: how sign request attachments are sent (default: merge)
+	AuditTrail     bool   `mapstructure:"audit_trail"`     // Save the Mekari audit trail next to stamped documents in finish and report it to NAV (default: true)
+	TrustedRoots   string `mapstructure:"trusted_roots"`   // PEM file of CA certificates trusted when verifying signed PDFs, besides the system roots
 	FilePrefix     string `mapstructure:"file_prefix"`     // Optional prefix for files
 	FileExtension  string `mapstructure:"file_extension"`  // File extension (default: .pdf)
 }
 
 type LoggingConfig struct {
-	Level  string `mapstructure:"level"`
-	Format string `mapstructure:"format"`
+	Level    string `mapstructure:"level"`
+	Format   string `mapstructure:"format"`
+	EventLog bool   `mapstructure:"event_log"` // Forward Warn/Error entries to the Windows Event Log in service mode (default: true)
+
+	// Levels overriding level for one module, e.g. httpclient: debug. Modules: httpclient, oauth2,
+	// esign, webhook, nav, document. Environment: LOGGING_LEVELS="httpclient=debug,nav=warn".
+	Levels map[string]string `mapstructure:"levels"`
+
+	// Masked in logs and api_logs. Field patterns match JSON keys, form fields and query parameters
+	// case-insensitively and support * wildcards; setting a list replaces the defaults (tokens,
+	// OAuth codes, secrets, passwords, phone numbers).
+	RedactFields  []string `mapstructure:"redact_fields"`
+	RedactHeaders []string `mapstructure:"redact_headers"` // Header names (default: Authorization, Cookie, API and admin tokens)
+
+	// Save requests received on /api/v1 and /api/v2 to api_logs with source "api" (default: false)
+	Inbound        bool     `mapstructure:"inbound"`
+	InboundMethods []string `mapstructure:"inbound_methods"` // Methods saved (default: POST, PUT, PATCH, DELETE)
 }
 
 type NAVConfig struct {
@@ -123,22 +303,460 @@ type NAVConfig struct {
 	Timeout  int    `mapstructure:"timeout"`
 }
 
+type WebhookConfig struct {
+	PollInterval int `mapstructure:"poll_interval"` // Seconds between queue polls when idle (default: 2)
+	MaxAttempts  int `mapstructure:"max_attempts"`  // Max processing attempts per event (default: 5)
+	ClaimTimeout int `mapstructure:"claim_timeout"` // Seconds before an event claimed by an instance that stopped responding is processed by another (default: 600)
+	RetryBackoff int `mapstructure:"retry_backoff"` // Seconds before the first retry of a failed event, doubled per attempt up to an hour (default: 30)
+}
+
+type DocsConfig struct {
+	Enabled bool `mapstructure:"enabled"` // Serve Swagger UI at /docs
+}
+
+type SecurityConfig struct {
+	APIKeyEnabled    bool     `mapstructure:"api_key_enabled"`    // Require X-API-Key on /api/v1 routes
+	AdminToken       string   `mapstructure:"admin_token"`        // Token for /admin endpoints (empty = disabled)
+	DefaultRateLimit int      `mapstructure:"default_rate_limit"` // Requests per minute per key when the key has none (0 = unlimited)
+	ExemptPaths      []string `mapstructure:"exempt_paths"`       // Paths that skip API key authentication
+}
+
+type AuthConfig struct {
+	Enabled       bool   `mapstructure:"enabled"`        // Require a user login for logs and admin endpoints
+	JWTSecret     string `mapstructure:"jwt_secret"`     // Secret used to sign login tokens (random per start if empty)
+	TokenTTL      int    `mapstructure:"token_ttl"`      // Login token lifetime in hours (default: 12)
+	AdminUsername string `mapstructure:"admin_username"` // Bootstrap admin created when no users exist
+	AdminPassword string `mapstructure:"admin_password"` // Bootstrap admin password
+}
+
+type RateLimitConfig struct {
+	Enabled     bool     `mapstructure:"enabled"`      // Enable inbound rate limiting (counters are kept in Redis)
+	PerIP       int      `mapstructure:"per_ip"`       // Requests per window per client IP (0 = unlimited)
+	Window      int      `mapstructure:"window"`       // Window length in seconds (default: 60)
+	ExemptPaths []string `mapstructure:"exempt_paths"` // Paths that skip per-IP limiting
+}
+
+type RetentionConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`     // Periodically purge old API logs
+	Days       int    `mapstructure:"days"`        // Keep API logs for this many days (default: 90)
+	Interval   int    `mapstructure:"interval"`    // Hours between purge runs (default: 24)
+	BatchSize  int    `mapstructure:"batch_size"`  // Rows deleted per transaction (default: 1000)
+	ArchiveDir string `mapstructure:"archive_dir"` // Write purged rows as gzipped JSON lines here first (empty = no archive)
+}
+
+// MappingConfig controls where document mappings are stored and when they expire
+type MappingConfig struct {
+	Store           string `mapstructure:"store"`            // redis, database or layered (default: redis)
+	TTLDays         int    `mapstructure:"ttl_days"`         // Mappings expire after this many days without activity (default: 90)
+	CleanupInterval int    `mapstructure:"cleanup_interval"` // Hours between cleanup runs (default: 24)
+	Archive         bool   `mapstructure:"archive"`          // Copy expiring mappings to document_mapping_archive before deleting them (default: true)
+}
+
+type LogViewerConfig struct {
+	Enabled       bool   `mapstructure:"enabled"`        // Serve /logs and /api/v1/logs (default: true)
+	Auth          string `mapstructure:"auth"`           // none, basic or login (default: none)
+	BasicUsername string `mapstructure:"basic_username"` // Credentials for basic auth mode
+	BasicPassword string `mapstructure:"basic_password"`
+}
+
+type DashboardConfig struct {
+	Enabled    bool `mapstructure:"enabled"`     // Serve /dashboard and /admin/dashboard endpoints (default: true)
+	StuckAfter int  `mapstructure:"stuck_after"` // Hours a document may stay in progress before it is reported as stuck (default: 24)
+}
+
+// E-meterai placements
+const (
+	StampPlacementFixed  = "fixed"  // Positions sent with the sign request
+	StampPlacementAuto   = "auto"   // Next to the first signature of the signed PDF, else at the anchor
+	StampPlacementAnchor = "anchor" // Always at the anchor
+)
+
+type StampingConfig struct {
+	Placement string      `mapstructure:"placement"` // fixed, auto or anchor; requests may choose with stamp_placement (default: fixed)
+	Width     float64     `mapstructure:"width"`     // E-meterai width in points (default: 80)
+	Height    float64     `mapstructure:"height"`    // E-meterai height in points (default: 80)
+	Margin    float64     `mapstructure:"margin"`    // Points kept clear to signatures, other stamps and the page edge (default: 10)
+	Anchor    StampAnchor `mapstructure:"anchor"`
+}
+
+// StampAnchor is where the e-meterai goes with the anchor placement, or with auto when no
+// signature is found or there is no room beside it
+type StampAnchor struct {
+	Page int     `mapstructure:"page"` // 1-based page (default: 0, the last page)
+	X    float64 `mapstructure:"x"`    // Points from the left of the page (default with y: bottom right corner)
+	Y    float64 `mapstructure:"y"`    // Points from the top of the page
+}
+
+// Where the files of documents that end unsigned go (deadline.on_expiry, document.on_void)
+const (
+	MoveToFailed = "failed" // document.failed_folder
+	MoveToReady  = "ready"  // Ready folder, to be sent again
+)
+
+// How the attachments of a sign request are sent (document.attachment_mode)
+const (
+	AttachmentModeMerge    = "merge"    // Appended to the document, signed as one PDF
+	AttachmentModeSeparate = "separate" // Each sent as its own document, linked to the main one
+)
+
+// DeadlineConfig configures the tracking of signing deadlines (document_deadline.signing_deadline
+// of the sign request, or the expiry date Mekari returns)
+type DeadlineConfig struct {
+	Enabled       bool   `mapstructure:"enabled"`        // Send reminders and resubmit expired documents (default: false)
+	CheckInterval int    `mapstructure:"check_interval"` // Minutes between deadline checks (default: 15)
+	Reminders     []int  `mapstructure:"reminders"`      // Hours before the deadline to remind, most urgent last (default: [72, 24, 4])
+	OnExpiry      string `mapstructure:"on_expiry"`      // failed or ready: where the file of an expired document goes (default: failed)
+	AutoResubmit  bool   `mapstructure:"auto_resubmit"`  // Send expired documents for signing again to the same signers (default: false)
+	MaxResubmits  int    `mapstructure:"max_resubmits"`  // Resubmits per document before giving up (default: 1)
+}
+
+type QuotaConfig struct {
+	CacheTTL       int `mapstructure:"cache_ttl"`       // Seconds an e-meterai balance is cached (default: 300)
+	AlertThreshold int `mapstructure:"alert_threshold"` // Notify when the remaining e-meterai balance falls below this (0 = no alerts)
+	AlertInterval  int `mapstructure:"alert_interval"`  // Hours before the same account is alerted again (default: 24)
+	CheckInterval  int `mapstructure:"check_interval"`  // Minutes between background balance checks (0 = only when requested)
+
+	CheckBeforeStamping bool `mapstructure:"check_before_stamping"` // Refuse stamp requests the balance cannot cover (default: true)
+}
+
+// AlertsConfig sets the thresholds of the operational alerts; a rule is checked when its threshold
+// is above 0. Failure counts are over the last hour.
+type AlertsConfig struct {
+	CheckInterval        int `mapstructure:"check_interval"`         // Minutes between checks (default: 5)
+	RepeatInterval       int `mapstructure:"repeat_interval"`        // Hours before a still firing alert is sent again (default: 6)
+	WebhookFailures      int `mapstructure:"webhook_failures"`       // Failed webhook processing attempts per hour
+	NAVFailures          int `mapstructure:"nav_failures"`           // NAV calls per hour without a response or with a 5xx status
+	TokenRefreshFailures int `mapstructure:"token_refresh_failures"` // Failed OAuth2 token refreshes per hour
+	StuckDocuments       int `mapstructure:"stuck_documents"`        // Documents in progress for longer than dashboard.stuck_after
+}
+
+// IsEnabled reports whether any alert rule has a threshold
+func (a *AlertsConfig) IsEnabled() bool {
+	return a.WebhookFailures > 0 || a.NAVFailures > 0 || a.TokenRefreshFailures > 0 || a.StuckDocuments > 0
+}
+
+// NotifierConfig selects the channels notifications are sent to besides the log. A channel is
+// used when it is configured; each can be limited to some events (e.g. quota_low, document_declined).
+type NotifierConfig struct {
+	WebhookURL string   `mapstructure:"webhook_url"` // POST notifications as JSON to this URL (empty = off)
+	Events     []string `mapstructure:"events"`      // Events POSTed to webhook_url (empty = all)
+
+	Email    NotifierEmailConfig    `mapstructure:"email"`
+	Slack    NotifierSlackConfig    `mapstructure:"slack"`
+	Teams    NotifierTeamsConfig    `mapstructure:"teams"`
+	Telegram NotifierTelegramConfig `mapstructure:"telegram"`
+}
+
+// NotifierEmailConfig sends notifications as plain text mails over SMTP
+type NotifierEmailConfig struct {
+	Host     string   `mapstructure:"host"`     // SMTP server (empty = off)
+	Port     int      `mapstructure:"port"`     // 465 uses TLS, other ports STARTTLS when offered (default: 587)
+	Username string   `mapstructure:"username"` // Login, also the sender when from is empty (empty = no login)
+	Password string   `mapstructure:"password"`
+	From     string   `mapstructure:"from"` // Sender address (default: username)
+	To       []string `mapstructure:"to"`   // Recipient addresses
+	Events   []string `mapstructure:"events"`
+}
+
+// NotifierSlackConfig posts notifications to a Slack incoming webhook
+type NotifierSlackConfig struct {
+	WebhookURL string   `mapstructure:"webhook_url"` // https://hooks.slack.com/services/... (empty = off)
+	Events     []string `mapstructure:"events"`
+}
+
+// NotifierTeamsConfig posts notifications as adaptive cards to a Microsoft Teams workflow or
+// incoming webhook
+type NotifierTeamsConfig struct {
+	WebhookURL string   `mapstructure:"webhook_url"` // Webhook URL of the channel (empty = off)
+	Events     []string `mapstructure:"events"`
+}
+
+// NotifierTelegramConfig sends notifications as messages of a Telegram bot
+type NotifierTelegramConfig struct {
+	BotToken string   `mapstructure:"bot_token"` // Token from @BotFather (empty = off)
+	ChatID   string   `mapstructure:"chat_id"`   // User, group or channel (e.g. -1001234567890 or @channel)
+	Events   []string `mapstructure:"events"`
+}
+
+type MetricsConfig struct {
+	Enabled bool   `mapstructure:"enabled"` // Serve Prometheus metrics (default: false)
+	Path    string `mapstructure:"path"`    // Metrics route (default: /metrics)
+	Token   string `mapstructure:"token"`   // Bearer token for scrapers (empty = operator login or admin token)
+
+	PoolLogInterval int `mapstructure:"pool_log_interval"` // Seconds between database and Redis pool statistics log lines (default: 60)
+}
+
+type DiagnosticsConfig struct {
+	Pprof bool `mapstructure:"pprof"` // Serve net/http/pprof profiles under /debug/pprof to admins (default: false)
+}
+
+// SentryConfig reports panics and Error logs to Sentry or a compatible service (e.g. GlitchTip)
+type SentryConfig struct {
+	DSN         string  `mapstructure:"dsn"`         // Project DSN (empty = error reporting disabled)
+	Environment string  `mapstructure:"environment"` // Environment tag (default: app.env)
+	SampleRate  float64 `mapstructure:"sample_rate"` // Share of events sent, 0-1 (default: 1)
+}
+
+// SchedulerConfig overrides the recurring jobs of the scheduler, keyed by job name (see
+// GET /api/v1/admin/scheduler/jobs), e.g. log_retention: {schedule: "0 2 * * *"}
+type SchedulerConfig struct {
+	Jobs map[string]SchedulerJobConfig `mapstructure:"jobs"`
+}
+
+type SchedulerJobConfig struct {
+	Enabled  *bool  `mapstructure:"enabled"`  // Run the job (default: on when its feature is enabled, e.g. retention.enabled)
+	Schedule string `mapstructure:"schedule"` // Cron expression in app.timezone, e.g. "0 2 * * *" or "@every 6h" (default: the job's interval setting, first run at startup)
+}
+
+// StartupConfig sets how long startup waits for the database, Redis and the document folders,
+// which may not be available yet when the service starts on boot
+type StartupConfig struct {
+	Wait          bool `mapstructure:"wait"`           // Retry unavailable dependencies instead of failing at once (default: true)
+	WaitTimeout   int  `mapstructure:"wait_timeout"`   // Seconds to wait for each dependency (default: 120)
+	RetryInterval int  `mapstructure:"retry_interval"` // Seconds between attempts (default: 5)
+}
+
+// UpdaterConfig selects the releases installed by -update and by the service's background updater
+type UpdaterConfig struct {
+	Channel           string `mapstructure:"channel"`            // stable (releases) or beta (also pre-releases) (default: stable)
+	PinVersion        string `mapstructure:"pin_version"`        // Install exactly this version, e.g. "1.4.2", also to roll back (empty = newest of the channel)
+	Background        bool   `mapstructure:"background"`         // Check for and download updates in the service, installing them in the maintenance window (default: false)
+	CheckInterval     int    `mapstructure:"check_interval"`     // Hours between background update checks (default: 6)
+	MaintenanceWindow string `mapstructure:"maintenance_window"` // When background updates are installed, in app.timezone, e.g. "Sun 02:00-04:00" (empty = as soon as downloaded)
+	Token             string `mapstructure:"token"`              // GitHub token with read access to the releases of a private repository (empty = anonymous)
+	APIBase           string `mapstructure:"api_base"`           // GitHub API URL, e.g. https://github.example.com/api/v3 for GitHub Enterprise (default: https://api.github.com)
+	Proxy             string `mapstructure:"proxy"`              // HTTP(S) proxy for update checks and downloads, e.g. http://proxy:3128 (empty = HTTPS_PROXY/HTTP_PROXY environment)
+}
+
+// GitHub returns the updater settings for the release repository
+func (u *UpdaterConfig) GitHub() updater.GitHubConfig {
+	cfg := updater.DefaultConfig
+	cfg.Channel = u.Channel
+	cfg.PinVersion = u.PinVersion
+	cfg.Token = u.Token
+	cfg.APIBase = u.APIBase
+	cfg.Proxy = u.Proxy
+	cfg.ConfigFile = FileUsed()
+	return cfg
+}
+
+// Window returns the parsed maintenance window and whether one is set
+func (u *UpdaterConfig) Window() (MaintenanceWindow, bool) {
+	if u.MaintenanceWindow == "" {
+		return MaintenanceWindow{}, false
+	}
+	w, _ := ParseMaintenanceWindow(u.MaintenanceWindow) // Validated on load
+	return w, true
+}
+
+// ConfigFileEnv names an explicit config file path; the file must then exist
+const ConfigFileEnv = "CONFIG_FILE"
+
+// envDefaults are used when a setting is in neither the config file nor the environment, so
+// the service can run from environment variables alone
+var envDefaults = map[string]interface{}{
+	"app.name":                     "mekari-esign",
+	"app.port":                     8080,
+	"mekari.timeout":               30,
+	"database.driver":              "postgres",
+	"database.host":                "localhost",
+	"database.port":                5432,
+	"database.sslmode":             "disable",
+	"redis.host":                   "localhost",
+	"redis.port":                   6379,
+	"oauth.refresh_token_age_days": 30,
+	"document.base_path":           "./documents",
+	"document.ready_folder":        "ready",
+	"document.progress_folder":     "progress",
+	"document.finish_folder":       "finish",
+	"document.file_extension":      ".pdf",
+	"logging.level":                "info",
+	"logging.format":               "json",
+	"nav.timeout":                  30,
+}
+
+// SecretsConfig configures the secrets managers referenced by vault://, awssm:// and azurekv://
+// values, and the master key of ENC(...) values. Empty settings fall back to the provider's
+// standard environment variables.
+type SecretsConfig struct {
+	Timeout       int                `mapstructure:"timeout"`         // Seconds per secrets manager request (default: 10)
+	MasterKeyFile string             `mapstructure:"master_key_file"` // Key of ENC(...) values, unless CONFIG_MASTER_KEY is set (default: master.key next to the config file)
+	Vault         VaultSecretsConfig `mapstructure:"vault"`
+	AWS           AWSSecretsConfig   `mapstructure:"aws"`
+	Azure         AzureSecretsConfig `mapstructure:"azure"`
+}
+
+type VaultSecretsConfig struct {
+	Address   string `mapstructure:"address"`   // Vault URL (default: VAULT_ADDR)
+	Token     string `mapstructure:"token"`     // Vault token (default: VAULT_TOKEN)
+	Namespace string `mapstructure:"namespace"` // Enterprise namespace (default: VAULT_NAMESPACE)
+}
+
+type AWSSecretsConfig struct {
+	Region          string `mapstructure:"region"`            // Default: AWS_REGION
+	AccessKeyID     string `mapstructure:"access_key_id"`     // Default: AWS_ACCESS_KEY_ID
+	SecretAccessKey string `mapstructure:"secret_access_key"` // Default: AWS_SECRET_ACCESS_KEY
+	SessionToken    string `mapstructure:"session_token"`     // Default: AWS_SESSION_TOKEN
+}
+
+type AzureSecretsConfig struct {
+	TenantID     string `mapstructure:"tenant_id"`     // Default: AZURE_TENANT_ID
+	ClientID     string `mapstructure:"client_id"`     // Default: AZURE_CLIENT_ID
+	ClientSecret string `mapstructure:"client_secret"` // Empty uses the managed identity (default: AZURE_CLIENT_SECRET)
+}
+
 func NewConfig() (*Config, error) {
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath(".")
-	viper.AddConfigPath("./config")
+	if err := setup(); err != nil {
+		return nil, err
+	}
+
+	return load()
+}
+
+// setup registers the config file, environment bindings and defaults, and reads the file
+func setup() error {
+	if path := os.Getenv(ConfigFileEnv); path != "" {
+		viper.SetConfigFile(path)
+	} else {
+		viper.SetConfigName("config")
+		viper.SetConfigType("yaml")
+		viper.AddConfigPath(".")
+		viper.AddConfigPath("./config")
+	}
 
-	// Enable environment variable override
+	// Every setting can be overridden from the environment, e.g. app.port -> APP_PORT and
+	// mekari.oauth2.client_id -> MEKARI_OAUTH2_CLIENT_ID. Keys are bound explicitly so settings
+	// missing from the config file (or without one) are still read.
 	viper.AutomaticEnv()
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	for key := range settings(reflect.ValueOf(Config{}), "") {
+		if err := viper.BindEnv(key); err != nil {
+			return err
+		}
+	}
+
+	for key, value := range envDefaults {
+		viper.SetDefault(key, value)
+	}
+
+	// Defaults for settings that are enabled unless explicitly turned off
+	viper.SetDefault("log_viewer.enabled", true)
+	viper.SetDefault("dashboard.enabled", true)
+	viper.SetDefault("app.compress", true)
+	viper.SetDefault("app.watch_config", true)
+	viper.SetDefault("logging.event_log", true)
+	viper.SetDefault("mapping.archive", true)
+	viper.SetDefault("startup.wait", true)
+	viper.SetDefault("quota.check_before_stamping", true)
+	viper.SetDefault("document.audit_trail", true)
 
-	if err := viper.ReadInConfig(); err != nil {
+	return readConfigFile()
+}
+
+// Reload re-reads the config file and its environment overlay and returns the resulting
+// configuration. The running configuration is not changed; see Apply.
+func Reload() (*Config, error) {
+	if err := readConfigFile(); err != nil {
 		return nil, err
 	}
 
+	return load()
+}
+
+// FileUsed returns the path of the config file in use, or "" when running from the environment only
+func FileUsed() string {
+	return viper.ConfigFileUsed()
+}
+
+// OverlayUsed returns the path of the environment overlay merged over the config file, or "" when none
+func OverlayUsed() string {
+	return overlayFile
+}
+
+// overlayFile is the environment overlay found by the last read
+var overlayFile string
+
+// overlayExts are tried for the overlay of a YAML config file, or when there is no config file
+var overlayExts = []string{".yaml", ".yml"}
+
+// readConfigFile reads config.yml when present, then merges the overlay of app.env over it.
+// Without a config file the configuration comes from defaults and environment variables; an
+// explicit CONFIG_FILE must exist.
+func readConfigFile() error {
+	err := viper.ReadInConfig()
+	var notFound viper.ConfigFileNotFoundError
+	if err != nil && !errors.As(err, &notFound) {
+		return err
+	}
+
+	return mergeOverlay()
+}
+
+// mergeOverlay merges config.{env}.yaml (or .yml) over the settings read so far, env being
+// app.env (APP_ENV). Settings in the overlay replace those of the base file; environment
+// variables still take precedence over both.
+func mergeOverlay() error {
+	overlayFile = ""
+
+	env := viper.GetString("app.env")
+	if env == "" || strings.ContainsAny(env, `/\.`) {
+		return nil
+	}
+
+	path := findOverlay(env)
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := viper.MergeConfig(f); err != nil {
+		return fmt.Errorf("failed to read config overlay %s: %w", path, err)
+	}
+	overlayFile = path
+	return nil
+}
+
+// findOverlay returns the overlay of env next to the config file (config.yml ->
+// config.production.yml), or in . and ./config when there is no config file
+func findOverlay(env string) string {
+	dirs := []string{".", "./config"}
+	name := "config"
+	exts := overlayExts
+
+	if base := viper.ConfigFileUsed(); base != "" {
+		ext := filepath.Ext(base)
+		dirs = []string{filepath.Dir(base)}
+		name = strings.TrimSuffix(filepath.Base(base), ext)
+		if ext != ".yaml" && ext != ".yml" {
+			exts = []string{ext}
+		}
+	}
+
+	for _, dir := range dirs {
+		for _, ext := range exts {
+			path := filepath.Join(dir, name+"."+env+ext)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// load unmarshals the settings read by viper and applies defaults
+func load() (*Config, error) {
 	var cfg Config
-	if err := viper.Unmarshal(&cfg); err != nil {
+	decodeHook := mapstructure.ComposeDecodeHookFunc(secondsHook, keyValueHook, mapstructure.StringToSliceHookFunc(","))
+	if err := viper.Unmarshal(&cfg, viper.DecodeHook(decodeHook)); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.resolveSecrets(); err != nil {
 		return nil, err
 	}
 
@@ -150,9 +768,646 @@ func NewConfig() (*Config, error) {
 		cfg.Mekari.AuthType = AuthTypeOAuth2
 	}
 
+	// Phone numbers without a country code are taken as Indonesian unless configured otherwise
+	cfg.Mekari.Signers.DefaultCountryCode = strings.TrimPrefix(cfg.Mekari.Signers.DefaultCountryCode, "+")
+	if cfg.Mekari.Signers.DefaultCountryCode == "" {
+		cfg.Mekari.Signers.DefaultCountryCode = "62"
+	}
+	if n, err := strconv.Atoi(cfg.Mekari.Signers.DefaultCountryCode); err != nil || n < 1 || n > 999 {
+		return nil, fmt.Errorf("invalid mekari.signers.default_country_code %q (expected 1-999, e.g. 62)", cfg.Mekari.Signers.DefaultCountryCode)
+	}
+
+	// The mock API takes the place of every Mekari host, so clients built from them need no changes
+	if cfg.Mekari.Mock {
+		if cfg.IsProduction() {
+			return nil, errors.New("mekari.mock cannot be enabled when app.env is production")
+		}
+		if cfg.Mekari.MockPort <= 0 {
+			cfg.Mekari.MockPort = 18089
+		}
+		if cfg.Mekari.MockWebhookDelay <= 0 {
+			cfg.Mekari.MockWebhookDelay = 3
+		}
+		cfg.Mekari.BaseURL = cfg.Mekari.MockURL()
+		cfg.Mekari.SsoBaseURL = cfg.Mekari.MockURL()
+		cfg.Mekari.AuthURL = cfg.Mekari.MockURL()
+	}
+
+	// Default shutdown drain timeout
+	if cfg.App.ShutdownTimeout <= 0 {
+		cfg.App.ShutdownTimeout = 30
+	}
+
+	// Default request body limits
+	if cfg.App.BodyLimit <= 0 {
+		cfg.App.BodyLimit = 4
+	}
+	if cfg.App.SignBodyLimit <= 0 {
+		cfg.App.SignBodyLimit = 50
+	}
+	if cfg.App.WebhookBodyLimit <= 0 {
+		cfg.App.WebhookBodyLimit = 10
+	}
+
+	if err := cfg.App.CORS.applyDefaults(cfg.IsProduction(), cfg.App.BaseURL); err != nil {
+		return nil, err
+	}
+
+	for _, addr := range cfg.App.ListenAddresses() {
+		if err := validateListenAddress(addr); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.App.InstanceID == "" {
+		hostname, err := os.Hostname()
+		if err != nil || hostname == "" {
+			hostname = "localhost"
+		}
+		cfg.App.InstanceID = hostname
+	}
+
+	// Timestamps are stored and sent in UTC; app.timezone only applies to dates people use
+	if cfg.App.Timezone == "" {
+		cfg.App.Timezone = "Local"
+	}
+	if _, err := time.LoadLocation(cfg.App.Timezone); err != nil {
+		return nil, fmt.Errorf("invalid app.timezone %q: %w", cfg.App.Timezone, err)
+	}
+	switch cfg.App.Language {
+	case "":
+		cfg.App.Language = "en"
+	case "en", "id":
+	default:
+		return nil, fmt.Errorf("invalid app.language %q (expected en or id)", cfg.App.Language)
+	}
+
+	// Validate the database driver ("mssql" is accepted for sqlserver)
+	switch strings.ToLower(cfg.Database.Driver) {
+	case "", "postgres", "postgresql":
+		cfg.Database.Driver = "postgres"
+	case "mysql":
+		cfg.Database.Driver = "mysql"
+	case "sqlserver", "mssql":
+		cfg.Database.Driver = "sqlserver"
+	default:
+		return nil, fmt.Errorf("invalid database.driver %q (expected postgres, mysql or sqlserver)", cfg.Database.Driver)
+	}
+
+	// Instances sharing a Redis server need distinct prefixes
+	if cfg.Redis.KeyPrefix == "" {
+		cfg.Redis.KeyPrefix = "mekari:"
+	}
+	cfg.Redis.KeyPrefix = strings.NewReplacer("{env}", cfg.App.Env, "{company}", cfg.NAV.Company).Replace(cfg.Redis.KeyPrefix)
+	if !strings.HasSuffix(cfg.Redis.KeyPrefix, ":") {
+		cfg.Redis.KeyPrefix += ":"
+	}
+	if cfg.Redis.FallbackSize <= 0 {
+		cfg.Redis.FallbackSize = 10000
+	}
+	if (cfg.Redis.TLS.CertFile == "") != (cfg.Redis.TLS.KeyFile == "") {
+		return nil, fmt.Errorf("redis.tls.cert_file and redis.tls.key_file must be set together")
+	}
+
+	// Default connection pool limits; database/sql opens unlimited connections otherwise
+	if cfg.Database.MaxOpenConns <= 0 {
+		cfg.Database.MaxOpenConns = 25
+	}
+	if cfg.Database.MaxIdleConns <= 0 {
+		cfg.Database.MaxIdleConns = 10
+	}
+	if cfg.Database.MaxIdleConns > cfg.Database.MaxOpenConns {
+		cfg.Database.MaxIdleConns = cfg.Database.MaxOpenConns
+	}
+	if cfg.Database.ConnMaxLifetime <= 0 {
+		cfg.Database.ConnMaxLifetime = 300
+	}
+	if cfg.Database.HealthInterval <= 0 {
+		cfg.Database.HealthInterval = 10
+	}
+	if cfg.Database.LogBuffer <= 0 {
+		cfg.Database.LogBuffer = 1000
+	}
+	if cfg.Database.LogQueueSize <= 0 {
+		cfg.Database.LogQueueSize = 10000
+	}
+	if cfg.Database.LogBatchSize <= 0 {
+		cfg.Database.LogBatchSize = 100
+	}
+	if cfg.Database.LogFlushMs <= 0 {
+		cfg.Database.LogFlushMs = 500
+	}
+	if cfg.Database.QueryTimeout <= 0 {
+		cfg.Database.QueryTimeout = 30
+	}
+	if cfg.Database.SlowQueryMs <= 0 {
+		cfg.Database.SlowQueryMs = 1000
+	}
+	if cfg.Database.LegacyOffset != "" {
+		if _, err := time.Parse(utcOffsetLayout, cfg.Database.LegacyOffset); err != nil {
+			return nil, fmt.Errorf("invalid database.legacy_offset %q (expected an offset such as +07:00)", cfg.Database.LegacyOffset)
+		}
+	}
+	if cfg.Metrics.PoolLogInterval <= 0 {
+		cfg.Metrics.PoolLogInterval = 60
+	}
+
+	// Default webhook processing settings
+	if cfg.Webhook.PollInterval <= 0 {
+		cfg.Webhook.PollInterval = 2
+	}
+	if cfg.Webhook.MaxAttempts <= 0 {
+		cfg.Webhook.MaxAttempts = 5
+	}
+	if cfg.Webhook.ClaimTimeout <= 0 {
+		cfg.Webhook.ClaimTimeout = 600
+	}
+	if cfg.Webhook.RetryBackoff <= 0 {
+		cfg.Webhook.RetryBackoff = 30
+	}
+
+	// Default rate limit window
+	if cfg.RateLimit.Window <= 0 {
+		cfg.RateLimit.Window = 60
+	}
+
+	// Default API log retention settings
+	if cfg.Retention.Days <= 0 {
+		cfg.Retention.Days = 90
+	}
+	if cfg.Retention.Interval <= 0 {
+		cfg.Retention.Interval = 24
+	}
+	if cfg.Retention.BatchSize <= 0 {
+		cfg.Retention.BatchSize = 1000
+	}
+
+	// Validate document mapping store and default expiry settings
+	switch cfg.Mapping.Store {
+	case "":
+		cfg.Mapping.Store = MappingStoreRedis
+	case MappingStoreRedis, MappingStoreDatabase, MappingStoreLayered:
+	default:
+		return nil, fmt.Errorf("invalid mapping.store %q (expected redis, database or layered)", cfg.Mapping.Store)
+	}
+	switch cfg.Updater.Channel {
+	case "":
+		cfg.Updater.Channel = "stable"
+	case "stable", "beta":
+	default:
+		return nil, fmt.Errorf("invalid updater.channel %q (expected stable or beta)", cfg.Updater.Channel)
+	}
+	if cfg.Updater.CheckInterval <= 0 {
+		cfg.Updater.CheckInterval = 6
+	}
+	cfg.Updater.APIBase = strings.TrimRight(cfg.Updater.APIBase, "/")
+	if cfg.Updater.APIBase == "" {
+		cfg.Updater.APIBase = updater.DefaultAPIBase
+	}
+	if u, err := url.Parse(cfg.Updater.APIBase); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid updater.api_base %q (expected an http(s) URL)", cfg.Updater.APIBase)
+	}
+	if cfg.Updater.Proxy != "" {
+		u, err := url.Parse(cfg.Updater.Proxy)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") || u.Host == "" {
+			return nil, fmt.Errorf("invalid updater.proxy (expected an http, https or socks5 URL)")
+		}
+	}
+	if cfg.Updater.MaintenanceWindow != "" {
+		if _, err := ParseMaintenanceWindow(cfg.Updater.MaintenanceWindow); err != nil {
+			return nil, fmt.Errorf("invalid updater.maintenance_window %q: %w", cfg.Updater.MaintenanceWindow, err)
+		}
+	}
+	for name, job := range cfg.Scheduler.Jobs {
+		if job.Schedule == "" {
+			continue
+		}
+		if _, err := cron.ParseStandard(job.Schedule); err != nil {
+			return nil, fmt.Errorf("invalid scheduler.jobs.%s.schedule %q: %w", name, job.Schedule, err)
+		}
+	}
+	if cfg.Startup.WaitTimeout <= 0 {
+		cfg.Startup.WaitTimeout = 120
+	}
+	if cfg.Startup.RetryInterval <= 0 {
+		cfg.Startup.RetryInterval = 5
+	}
+	if cfg.OAuth.DeletedRetentionDays <= 0 {
+		cfg.OAuth.DeletedRetentionDays = 30
+	}
+	if cfg.OAuth.PurgeInterval <= 0 {
+		cfg.OAuth.PurgeInterval = 24
+	}
+	if _, err := cfg.OAuth.EncryptionKeyBytes(); err != nil {
+		return nil, err
+	}
+
+	if cfg.Mapping.TTLDays <= 0 {
+		cfg.Mapping.TTLDays = 90
+	}
+	if cfg.Mapping.CleanupInterval <= 0 {
+		cfg.Mapping.CleanupInterval = 24
+	}
+
+	// Validate log viewer authentication
+	switch cfg.LogViewer.Auth {
+	case "":
+		cfg.LogViewer.Auth = LogViewerAuthNone
+	case LogViewerAuthNone, LogViewerAuthLogin:
+	case LogViewerAuthBasic:
+		if cfg.LogViewer.BasicUsername == "" || cfg.LogViewer.BasicPassword == "" {
+			return nil, fmt.Errorf("log_viewer.basic_username and log_viewer.basic_password are required for basic auth")
+		}
+	default:
+		return nil, fmt.Errorf("invalid log_viewer.auth %q (expected none, basic or login)", cfg.LogViewer.Auth)
+	}
+
+	// Default operations dashboard settings
+	if cfg.Dashboard.StuckAfter <= 0 {
+		cfg.Dashboard.StuckAfter = 24
+	}
+
+	// Default e-meterai quota monitoring settings
+	if cfg.Quota.CacheTTL <= 0 {
+		cfg.Quota.CacheTTL = 300
+	}
+	if cfg.Quota.AlertInterval <= 0 {
+		cfg.Quota.AlertInterval = 24
+	}
+
+	// Default alert check settings
+	if cfg.Alerts.CheckInterval <= 0 {
+		cfg.Alerts.CheckInterval = 5
+	}
+	if cfg.Alerts.RepeatInterval <= 0 {
+		cfg.Alerts.RepeatInterval = 6
+	}
+
+	// Default notifier settings
+	if cfg.Notifier.Email.Host != "" {
+		if cfg.Notifier.Email.Port <= 0 {
+			cfg.Notifier.Email.Port = 587
+		}
+		if cfg.Notifier.Email.From == "" {
+			cfg.Notifier.Email.From = cfg.Notifier.Email.Username
+		}
+		if cfg.Notifier.Email.From == "" || len(cfg.Notifier.Email.To) == 0 {
+			return nil, fmt.Errorf("notifier.email needs from (or username) and to when host is set")
+		}
+	}
+	if cfg.Notifier.Telegram.BotToken != "" && cfg.Notifier.Telegram.ChatID == "" {
+		return nil, fmt.Errorf("notifier.telegram.chat_id is required when bot_token is set")
+	}
+
+	// Default inbound request logging methods
+	if len(cfg.Logging.InboundMethods) == 0 {
+		cfg.Logging.InboundMethods = []string{"POST", "PUT", "PATCH", "DELETE"}
+	}
+	for i, method := range cfg.Logging.InboundMethods {
+		cfg.Logging.InboundMethods[i] = strings.ToUpper(strings.TrimSpace(method))
+	}
+
+	// Default e-meterai placement; 80 points was the size used before it was configurable
+	switch cfg.Stamping.Placement {
+	case "":
+		cfg.Stamping.Placement = StampPlacementFixed
+	case StampPlacementFixed, StampPlacementAuto, StampPlacementAnchor:
+	default:
+		return nil, fmt.Errorf("invalid stamping.placement %q (expected fixed, auto or anchor)", cfg.Stamping.Placement)
+	}
+	if cfg.Stamping.Width <= 0 {
+		cfg.Stamping.Width = 80
+	}
+	if cfg.Stamping.Height <= 0 {
+		cfg.Stamping.Height = 80
+	}
+	if cfg.Stamping.Margin <= 0 {
+		cfg.Stamping.Margin = 10
+	}
+
+	// Default deadline tracking
+	if cfg.Document.FailedFolder == "" {
+		cfg.Document.FailedFolder = "failed"
+	}
+	switch cfg.Document.OnVoid {
+	case "":
+		cfg.Document.OnVoid = MoveToReady
+	case MoveToFailed, MoveToReady:
+	default:
+		return nil, fmt.Errorf("invalid document.on_void %q (expected ready or failed)", cfg.Document.OnVoid)
+	}
+	switch cfg.Document.AttachmentMode {
+	case "":
+		cfg.Document.AttachmentMode = AttachmentModeMerge
+	case AttachmentModeMerge, AttachmentModeSeparate:
+	default:
+		return nil, fmt.Errorf("invalid document.attachment_mode %q (expected merge or separate)", cfg.Document.AttachmentMode)
+	}
+	if cfg.Deadline.CheckInterval <= 0 {
+		cfg.Deadline.CheckInterval = 15
+	}
+	if len(cfg.Deadline.Reminders) == 0 {
+		cfg.Deadline.Reminders = []int{72, 24, 4}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(cfg.Deadline.Reminders)))
+	switch cfg.Deadline.OnExpiry {
+	case "":
+		cfg.Deadline.OnExpiry = MoveToFailed
+	case MoveToFailed, MoveToReady:
+	default:
+		return nil, fmt.Errorf("invalid deadline.on_expiry %q (expected failed or ready)", cfg.Deadline.OnExpiry)
+	}
+	if cfg.Deadline.MaxResubmits <= 0 {
+		cfg.Deadline.MaxResubmits = 1
+	}
+
+	// Default metrics route
+	if cfg.Metrics.Path == "" {
+		cfg.Metrics.Path = "/metrics"
+	}
+
+	// Default error reporting settings
+	if cfg.Sentry.Environment == "" {
+		cfg.Sentry.Environment = cfg.App.Env
+	}
+	if cfg.Sentry.SampleRate <= 0 || cfg.Sentry.SampleRate > 1 {
+		cfg.Sentry.SampleRate = 1
+	}
+
+	// Default login token lifetime
+	if cfg.Auth.TokenTTL <= 0 {
+		cfg.Auth.TokenTTL = 12
+	}
+
 	return &cfg, nil
 }
 
+// resolveSecrets decrypts ENC(...) values and replaces secret references (e.g.
+// "vault://secret/data/mekari#client_secret") in any string setting with the secret value. The
+// secrets.* settings may be encrypted but are not resolved.
+func (c *Config) resolveSecrets() error {
+	if err := c.decryptValues(); err != nil {
+		return err
+	}
+
+	timeout := time.Duration(c.Secrets.Timeout) * time.Second
+	resolver := secrets.NewResolver(secrets.Options{
+		Timeout: timeout,
+		Vault: secrets.VaultOptions{
+			Address:   c.Secrets.Vault.Address,
+			Token:     c.Secrets.Vault.Token,
+			Namespace: c.Secrets.Vault.Namespace,
+		},
+		AWS: secrets.AWSOptions{
+			Region:          c.Secrets.AWS.Region,
+			AccessKeyID:     c.Secrets.AWS.AccessKeyID,
+			SecretAccessKey: c.Secrets.AWS.SecretAccessKey,
+			SessionToken:    c.Secrets.AWS.SessionToken,
+		},
+		Azure: secrets.AzureOptions{
+			TenantID:     c.Secrets.Azure.TenantID,
+			ClientID:     c.Secrets.Azure.ClientID,
+			ClientSecret: c.Secrets.Azure.ClientSecret,
+		},
+	})
+
+	for key, field := range settings(reflect.ValueOf(c).Elem(), "") {
+		if field.Kind() != reflect.String || strings.HasPrefix(key, "secrets.") || !resolver.IsReference(field.String()) {
+			continue
+		}
+		value, err := resolver.Resolve(context.Background(), field.String())
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		field.SetString(value)
+	}
+
+	return nil
+}
+
+// decryptValues replaces ENC(...) values with their plain text. The master key is only loaded
+// when the configuration holds an encrypted value.
+func (c *Config) decryptValues() error {
+	var decrypter *secrets.Cipher
+	for key, field := range settings(reflect.ValueOf(c).Elem(), "") {
+		if field.Kind() != reflect.String || !secrets.IsEncrypted(field.String()) {
+			continue
+		}
+		if decrypter == nil {
+			var err error
+			if decrypter, err = masterCipher(c.Secrets.MasterKeyFile); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+		value, err := decrypter.Decrypt(field.String())
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		field.SetString(value)
+	}
+	return nil
+}
+
+// masterKeyPath returns secrets.master_key_file, or master.key next to the config file
+func masterKeyPath(setting string) string {
+	if setting != "" {
+		return setting
+	}
+	dir := "."
+	if file := FileUsed(); file != "" {
+		dir = filepath.Dir(file)
+	}
+	return filepath.Join(dir, secrets.MasterKeyFileName)
+}
+
+func masterCipher(keyFile string) (*secrets.Cipher, error) {
+	key, err := secrets.LoadMasterKey(masterKeyPath(keyFile))
+	if err != nil {
+		return nil, err
+	}
+	return secrets.NewCipher(key)
+}
+
+// InitMasterKey creates the key file of ENC(...) values at secrets.master_key_file (default
+// master.key next to the config file) and returns its path. An existing key file is kept.
+func InitMasterKey() (string, error) {
+	if err := setup(); err != nil {
+		return "", err
+	}
+
+	path := masterKeyPath(viper.GetString("secrets.master_key_file"))
+	if err := secrets.CreateMasterKey(path); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return path, fmt.Errorf("%s already exists; values encrypted with it would no longer decrypt if it was replaced", path)
+		}
+		return path, err
+	}
+	return path, nil
+}
+
+// GrantMasterKey lets account read the key file of ENC(...) values, for a service that does not
+// run as SYSTEM, and returns its path. It returns "" when the service needs no key file: none
+// exists or the key is set in CONFIG_MASTER_KEY.
+func GrantMasterKey(account string) (string, error) {
+	if err := setup(); err != nil {
+		return "", err
+	}
+	if os.Getenv(secrets.MasterKeyEnv) != "" {
+		return "", nil
+	}
+
+	path := masterKeyPath(viper.GetString("secrets.master_key_file"))
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	return path, secrets.GrantKeyFileRead(path, account)
+}
+
+// EncryptValue returns plain as an ENC(...) value for the config file, encrypted with the
+// master key
+func EncryptValue(plain string) (string, error) {
+	if err := setup(); err != nil {
+		return "", err
+	}
+
+	encrypter, err := masterCipher(viper.GetString("secrets.master_key_file"))
+	if err != nil {
+		return "", err
+	}
+	return encrypter.Encrypt(plain)
+}
+
+// secondsHook decodes string durations the way numeric ones are read: as seconds. Environment
+// values are strings, so MEKARI_TIMEOUT=30 means 30 seconds like "timeout: 30" in the file.
+func secondsHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String || to != reflect.TypeOf(time.Duration(0)) {
+		return data, nil
+	}
+
+	value := strings.TrimSpace(data.(string))
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds), nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return nil, err
+	}
+	return d / time.Second, nil
+}
+
+// keyValueHook decodes "key=value,key=value" strings into maps, so map settings such as
+// logging.levels can be set from the environment
+func keyValueHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String || to.Kind() != reflect.Map || to.Key().Kind() != reflect.String {
+		return data, nil
+	}
+
+	out := make(map[string]string)
+	for _, pair := range strings.Split(data.(string), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid key=value pair %q", pair)
+		}
+		out[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+	return out, nil
+}
+
+// Default CORS methods and headers (everything the API reads)
+var (
+	defaultCORSMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	defaultCORSHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key", "X-Admin-Token", "X-Request-ID"}
+)
+
+// applyDefaults fills unset CORS settings. Production only allows the service's own origin
+// unless origins are configured explicitly.
+func (c *CORSConfig) applyDefaults(production bool, baseURL string) error {
+	if len(c.AllowOrigins) == 0 {
+		if production {
+			if baseURL == "" {
+				return fmt.Errorf("app.cors.allow_origins (or app.base_url) is required in production")
+			}
+			c.AllowOrigins = []string{strings.TrimRight(baseURL, "/")}
+		} else {
+			c.AllowOrigins = []string{"*"}
+		}
+	}
+	if len(c.AllowMethods) == 0 {
+		c.AllowMethods = defaultCORSMethods
+	}
+	if len(c.AllowHeaders) == 0 {
+		c.AllowHeaders = defaultCORSHeaders
+	}
+	if len(c.ExposeHeaders) == 0 {
+		c.ExposeHeaders = []string{"X-Request-ID"}
+	}
+
+	for _, origin := range c.AllowOrigins {
+		if origin == "*" && c.AllowCredentials {
+			return fmt.Errorf("app.cors.allow_credentials cannot be used with the \"*\" origin")
+		}
+	}
+
+	return nil
+}
+
+// MaxBodyLimit returns the largest configured body limit in MB, used as the server-wide cap
+func (a *AppConfig) MaxBodyLimit() int {
+	limit := a.BodyLimit
+	if a.SignBodyLimit > limit {
+		limit = a.SignBodyLimit
+	}
+	if a.WebhookBodyLimit > limit {
+		limit = a.WebhookBodyLimit
+	}
+	return limit
+}
+
+// ListenAddresses returns the addresses the server listens on: app.listen, or app.host with
+// app.port
+func (a *AppConfig) ListenAddresses() []string {
+	if len(a.Listen) > 0 {
+		return a.Listen
+	}
+	return []string{net.JoinHostPort(a.Host, strconv.Itoa(a.Port))}
+}
+
+func validateListenAddress(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid app.listen address %q (expected host:port)", addr)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid port in app listen address %q (expected 1-65535)", addr)
+	}
+	return nil
+}
+
+// utcOffsetLayout parses UTC offsets such as +07:00
+const utcOffsetLayout = "-07:00"
+
+// LegacyOffsetSeconds returns database.legacy_offset in seconds east of UTC, 0 when unset
+func (d *DatabaseConfig) LegacyOffsetSeconds() int {
+	t, err := time.Parse(utcOffsetLayout, d.LegacyOffset)
+	if err != nil {
+		return 0 // Unset; validated on load
+	}
+	_, offset := t.Zone()
+	return offset
+}
+
+// Location returns the zone of app.timezone, used for the dates people type and read
+func (a *AppConfig) Location() *time.Location {
+	loc, err := time.LoadLocation(a.Timezone)
+	if err != nil {
+		return time.Local // Validated on load
+	}
+	return loc
+}
+
 func (c *Config) IsDevelopment() bool {
 	return c.App.Env == "development"
 }
@@ -160,3 +1415,47 @@ func (c *Config) IsDevelopment() bool {
 func (c *Config) IsProduction() bool {
 	return c.App.Env == "production"
 }
+
+// redactedValue replaces secrets in Redacted
+const redactedValue = "********"
+
+// Redacted returns a copy of the config with secrets masked, safe to expose over the API
+func (c *Config) Redacted() Config {
+	reloadMu.RLock()
+	redacted := *c
+	reloadMu.RUnlock()
+
+	mask := func(s *string) {
+		if *s != "" {
+			*s = redactedValue
+		}
+	}
+	mask(&redacted.Mekari.OAuth2.ClientSecret)
+	mask(&redacted.Mekari.HMAC.ClientSecret)
+	mask(&redacted.Database.Password)
+	mask(&redacted.Database.ReplicaDSN) // The DSN embeds the replica credentials
+	mask(&redacted.Redis.Password)
+	mask(&redacted.NAV.Password)
+	mask(&redacted.Security.AdminToken)
+	mask(&redacted.Auth.JWTSecret)
+	mask(&redacted.Auth.AdminPassword)
+	mask(&redacted.OAuth.EncryptionKey)
+	mask(&redacted.LogViewer.BasicPassword)
+	mask(&redacted.Notifier.WebhookURL) // Webhook URLs embed their credentials
+	mask(&redacted.Notifier.Email.Password)
+	mask(&redacted.Notifier.Slack.WebhookURL)
+	mask(&redacted.Notifier.Teams.WebhookURL)
+	mask(&redacted.Notifier.Telegram.BotToken)
+	mask(&redacted.Metrics.Token)
+	mask(&redacted.Sentry.DSN) // The DSN embeds the project key
+	mask(&redacted.Secrets.Vault.Token)
+	mask(&redacted.Secrets.AWS.SecretAccessKey)
+	mask(&redacted.Secrets.AWS.SessionToken)
+	mask(&redacted.Secrets.Azure.ClientSecret)
+	mask(&redacted.Updater.Token)
+	if u, err := url.Parse(c.Updater.Proxy); err == nil && u.User != nil {
+		redacted.Updater.Proxy = u.Redacted() // Proxy credentials
+	}
+
+	return redacted
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaintenanceWindow is a weekly time range such as "Sun 02:00-04:00", in app.timezone. A range
+// ending before it starts runs past midnight into the next day.
+type MaintenanceWindow struct {
+	days     map[time.Weekday]bool // Days the window starts on, nil for every day
+	start    time.Duration         // Offset of the start from midnight
+	duration time.Duration
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseMaintenanceWindow parses "[days] HH:MM-HH:MM", where days is a comma-separated list of
+// weekday abbreviations, e.g. "Sat,Sun 01:00-05:00"; without days the window is daily
+func ParseMaintenanceWindow(s string) (MaintenanceWindow, error) {
+	var w MaintenanceWindow
+	fields := strings.Fields(s)
+	switch len(fields) {
+	case 1:
+	case 2:
+		w.days = make(map[time.Weekday]bool)
+		for _, name := range strings.Split(fields[0], ",") {
+			day, ok := weekdays[strings.ToLower(name)]
+			if !ok {
+				return w, fmt.Errorf("unknown day %q (expected Sun, Mon, Tue, Wed, Thu, Fri or Sat)", name)
+			}
+			w.days[day] = true
+		}
+	default:
+		return w, fmt.Errorf("expected \"[days] HH:MM-HH:MM\"")
+	}
+
+	from, to, ok := strings.Cut(fields[len(fields)-1], "-")
+	if !ok {
+		return w, fmt.Errorf("expected a time range such as 02:00-04:00")
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return w, err
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return w, err
+	}
+	w.start = start
+	w.duration = end - start
+	if w.duration <= 0 {
+		w.duration += 24 * time.Hour
+	}
+	return w, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (expected HH:MM)", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains reports whether t is in the window
+func (w MaintenanceWindow) Contains(t time.Time) bool {
+	// A window that started yesterday may still be open
+	for offset := -1; offset <= 0; offset++ {
+		start := w.startOn(t, offset)
+		if w.startsOn(start.Weekday()) && !t.Before(start) && t.Before(start.Add(w.duration)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns t when it is in the window, otherwise when the window opens next
+func (w MaintenanceWindow) Next(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+	for offset := 0; offset <= 7; offset++ {
+		start := w.startOn(t, offset)
+		if w.startsOn(start.Weekday()) && start.After(t) {
+			return start
+		}
+	}
+	return t // Not reached: every window opens within a week
+}
+
+// startOn returns the start of the window on the day offset days from t, in the zone of t
+func (w MaintenanceWindow) startOn(t time.Time, offset int) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d+offset, 0, 0, 0, 0, t.Location()).Add(w.start)
+}
+
+func (w MaintenanceWindow) startsOn(day time.Weekday) bool {
+	return w.days == nil || w.days[day]
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// reloadable lists the settings applied without a restart, as exact keys or prefixes ending in "."
+var reloadable = []string{
+	"document.",      // Folder paths and file naming
+	"nav.",           // NAV URL, company, credentials and timeout
+	"mekari.timeout", // Mekari API request timeout
+	"logging.level",
+	"logging.levels",
+	"logging.inbound",
+	"logging.inbound_methods",
+}
+
+// reloadMu guards the reloadable settings: Apply writes them while requests read them, so they
+// are read through the accessors below rather than the fields
+var reloadMu sync.RWMutex
+
+// DocumentSettings returns the document settings in effect
+func (c *Config) DocumentSettings() DocumentConfig {
+	reloadMu.RLock()
+	defer reloadMu.RUnlock()
+	return c.Document
+}
+
+// NAVSettings returns the NAV settings in effect
+func (c *Config) NAVSettings() NAVConfig {
+	reloadMu.RLock()
+	defer reloadMu.RUnlock()
+	return c.NAV
+}
+
+// LoggingSettings returns the logging settings in effect
+func (c *Config) LoggingSettings() LoggingConfig {
+	reloadMu.RLock()
+	defer reloadMu.RUnlock()
+	return c.Logging
+}
+
+// MekariTimeout returns the Mekari API request timeout in effect
+func (c *Config) MekariTimeout() time.Duration {
+	reloadMu.RLock()
+	defer reloadMu.RUnlock()
+	return c.Mekari.Timeout
+}
+
+// Change is a setting that differs between the running and the reloaded configuration
+type Change struct {
+	Key     string `json:"key"`
+	Old     string `json:"old"`
+	New     string `json:"new"`
+	Applied bool   `json:"applied"` // False when the setting only takes effect after a restart
+}
+
+// IsReloadable reports whether a setting (e.g. "nav.password") is applied without a restart
+func IsReloadable(key string) bool {
+	for _, r := range reloadable {
+		if key == r || (strings.HasSuffix(r, ".") && strings.HasPrefix(key, r)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply copies the reloadable settings of next into c and returns every setting that changed,
+// sorted by key. Secrets are masked in the returned values. Only one Apply may run at a time.
+func (c *Config) Apply(next *Config) []Change {
+	current := settings(reflect.ValueOf(c).Elem(), "")
+	updated := settings(reflect.ValueOf(next).Elem(), "")
+	currentShown := settings(reflect.ValueOf(c.Redacted()), "")
+	updatedShown := settings(reflect.ValueOf(next.Redacted()), "")
+
+	keys := make([]string, 0, len(current))
+	for key := range current {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var changes []Change
+	var applied []string
+	for _, key := range keys {
+		if reflect.DeepEqual(current[key].Interface(), updated[key].Interface()) {
+			continue
+		}
+
+		change := Change{
+			Key:     key,
+			Old:     fmt.Sprint(currentShown[key].Interface()),
+			New:     fmt.Sprint(updatedShown[key].Interface()),
+			Applied: IsReloadable(key),
+		}
+		if change.Applied {
+			applied = append(applied, key)
+		}
+		changes = append(changes, change)
+	}
+
+	// All at once, so that readers never see half of a reload, e.g. a new base path with old folders
+	reloadMu.Lock()
+	for _, key := range applied {
+		current[key].Set(updated[key])
+	}
+	reloadMu.Unlock()
+
+	return changes
+}
+
+// settings maps the leaf fields of a config struct by their dotted mapstructure key
+func settings(v reflect.Value, prefix string) map[string]reflect.Value {
+	out := make(map[string]reflect.Value)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := prefix + t.Field(i).Tag.Get("mapstructure")
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			for k, f := range settings(field, key+".") {
+				out[k] = f
+			}
+			continue
+		}
+		out[key] = field
+	}
+	return out
+}
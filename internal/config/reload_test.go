@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestApplyWhileReading applies reloads while other goroutines read the reloadable settings the
+// way requests do; run with -race to catch unguarded access
+func TestApplyWhileReading(t *testing.T) {
+	cfg := &Config{}
+	cfg.Document.BasePath = "/documents/0"
+	cfg.NAV.BaseURL = "http://nav-0"
+	cfg.Mekari.Timeout = time.Second
+	cfg.Logging.Level = "info"
+
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				_ = cfg.DocumentSettings().BasePath
+				_ = cfg.NAVSettings().BaseURL
+				_ = cfg.MekariTimeout()
+				_ = cfg.LoggingSettings().Level
+				_ = cfg.Redacted()
+			}
+		}()
+	}
+
+	for i := 1; i <= 100; i++ {
+		next := &Config{}
+		next.Document.BasePath = fmt.Sprintf("/documents/%d", i)
+		next.NAV.BaseURL = fmt.Sprintf("http://nav-%d", i)
+		next.Mekari.Timeout = time.Duration(i) * time.Second
+		next.Logging.Level = "debug"
+		next.Database.Host = "ignored" // Not reloadable
+		cfg.Apply(next)
+	}
+	close(stop)
+	readers.Wait()
+
+	if got := cfg.DocumentSettings().BasePath; got != "/documents/100" {
+		t.Errorf("document.base_path is %q after the last reload, expected /documents/100", got)
+	}
+	if got := cfg.MekariTimeout(); got != 100*time.Second {
+		t.Errorf("mekari.timeout is %v after the last reload, expected 100s", got)
+	}
+	if cfg.Database.Host != "" {
+		t.Errorf("database.host was applied without a restart")
+	}
+}
+
+// TestApplyChanges checks the changes Apply reports, and that only reloadable ones are applied
+func TestApplyChanges(t *testing.T) {
+	cfg := &Config{}
+	cfg.NAV.Password = "old-secret"
+	cfg.Redis.Host = "localhost"
+
+	next := &Config{}
+	next.NAV.Password = "new-secret"
+	next.Redis.Host = "redis"
+
+	changes := cfg.Apply(next)
+	if len(changes) != 2 {
+		t.Fatalf("Apply reported %d changes (%+v), expected 2", len(changes), changes)
+	}
+	nav, redis := changes[0], changes[1]
+	if nav.Key != "nav.password" || !nav.Applied || nav.Old != redactedValue || nav.New != redactedValue {
+		t.Errorf("nav.password change is %+v, expected applied and masked", nav)
+	}
+	if redis.Key != "redis.host" || redis.Applied {
+		t.Errorf("redis.host change is %+v, expected not applied", redis)
+	}
+	if cfg.NAVSettings().Password != "new-secret" || cfg.Redis.Host != "localhost" {
+		t.Errorf("Apply set nav.password %q and redis.host %q, expected only nav.password to change",
+			cfg.NAVSettings().Password, cfg.Redis.Host)
+	}
+}
@@ -0,0 +1,152 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SchemeAWS references AWS Secrets Manager: awssm://<secret name or ARN>#<key>
+const SchemeAWS = "awssm"
+
+type AWSOptions struct {
+	Region          string // Default: AWS_REGION, then AWS_DEFAULT_REGION
+	AccessKeyID     string // Default: AWS_ACCESS_KEY_ID
+	SecretAccessKey string // Default: AWS_SECRET_ACCESS_KEY
+	SessionToken    string // Default: AWS_SESSION_TOKEN
+}
+
+type awsProvider struct {
+	opts   AWSOptions
+	client *http.Client
+}
+
+func newAWSProvider(opts AWSOptions, client *http.Client) *awsProvider {
+	opts.Region = firstNonEmpty(opts.Region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"))
+	opts.AccessKeyID = firstNonEmpty(opts.AccessKeyID, os.Getenv("AWS_ACCESS_KEY_ID"))
+	opts.SecretAccessKey = firstNonEmpty(opts.SecretAccessKey, os.Getenv("AWS_SECRET_ACCESS_KEY"))
+	opts.SessionToken = firstNonEmpty(opts.SessionToken, os.Getenv("AWS_SESSION_TOKEN"))
+	return &awsProvider{opts: opts, client: client}
+}
+
+// Fetch returns the SecretString of the current version of a secret
+func (p *awsProvider) Fetch(ctx context.Context, secretID string) (string, error) {
+	if p.opts.Region == "" || p.opts.AccessKeyID == "" || p.opts.SecretAccessKey == "" {
+		return "", fmt.Errorf("aws region and access keys are required (secrets.aws or AWS_REGION/AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY)")
+	}
+
+	body, err := json.Marshal(map[string]string{"SecretId": secretID})
+	if err != nil {
+		return "", err
+	}
+
+	host := "secretsmanager." + p.opts.Region + ".amazonaws.com"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://"+host+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	p.sign(req, host, body, time.Now().UTC())
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("aws secrets manager returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		SecretString *string `json:"SecretString"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return "", fmt.Errorf("invalid aws secrets manager response: %w", err)
+	}
+	if result.SecretString == nil {
+		return "", fmt.Errorf("secret %s has no string value (binary secrets are not supported)", secretID)
+	}
+	return *result.SecretString, nil
+}
+
+// sign adds AWS Signature Version 4 headers for the secretsmanager service
+func (p *awsProvider) sign(req *http.Request, host string, body []byte, now time.Time) {
+	const service = "secretsmanager"
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("Host", host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if p.opts.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", p.opts.SessionToken)
+	}
+
+	signed := []string{"content-type", "host", "x-amz-date", "x-amz-target"}
+	if p.opts.SessionToken != "" {
+		signed = append(signed, "x-amz-security-token")
+	}
+	sort.Strings(signed) // Canonical headers are sorted by name
+
+	var canonicalHeaders strings.Builder
+	for _, name := range signed {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	signedHeaders := strings.Join(signed, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		"/",
+		"",
+		canonicalHeaders.String(),
+		signedHeaders,
+		hexSHA256(body),
+	}, "\n")
+
+	scope := date + "/" + p.opts.Region + "/" + service + "/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hexSHA256([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+p.opts.SecretAccessKey), date)
+	key = hmacSHA256(key, p.opts.Region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		p.opts.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
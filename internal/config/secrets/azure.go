@@ -0,0 +1,134 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// SchemeAzure references Azure Key Vault: azurekv://<vault name or host>/<secret name>[/<version>]#<key>
+const SchemeAzure = "azurekv"
+
+const (
+	azureVaultScope   = "https://vault.azure.net"
+	azureAPIVersion   = "7.4"
+	azureIMDSEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+)
+
+// AzureOptions authenticates with a service principal when ClientSecret is set, otherwise
+// with the managed identity of the host
+type AzureOptions struct {
+	TenantID     string // Default: AZURE_TENANT_ID
+	ClientID     string // Default: AZURE_CLIENT_ID (also selects a user-assigned managed identity)
+	ClientSecret string // Default: AZURE_CLIENT_SECRET
+}
+
+type azureProvider struct {
+	opts   AzureOptions
+	client *http.Client
+	token  string
+}
+
+func newAzureProvider(opts AzureOptions, client *http.Client) *azureProvider {
+	opts.TenantID = firstNonEmpty(opts.TenantID, os.Getenv("AZURE_TENANT_ID"))
+	opts.ClientID = firstNonEmpty(opts.ClientID, os.Getenv("AZURE_CLIENT_ID"))
+	opts.ClientSecret = firstNonEmpty(opts.ClientSecret, os.Getenv("AZURE_CLIENT_SECRET"))
+	return &azureProvider{opts: opts, client: client}
+}
+
+// Fetch returns the current (or the given) version of a Key Vault secret
+func (p *azureProvider) Fetch(ctx context.Context, path string) (string, error) {
+	vault, secret, ok := strings.Cut(path, "/")
+	if !ok || secret == "" {
+		return "", fmt.Errorf("azure key vault reference must be <vault>/<secret>")
+	}
+	if !strings.Contains(vault, ".") {
+		vault += ".vault.azure.net"
+	}
+
+	if p.token == "" {
+		token, err := p.accessToken(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to get azure access token: %w", err)
+		}
+		p.token = token
+	}
+
+	secretURL := fmt.Sprintf("https://%s/secrets/%s?api-version=%s", vault, secret, azureAPIVersion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, secretURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.token)
+
+	var result struct {
+		Value string `json:"value"`
+	}
+	if err := p.doJSON(req, &result); err != nil {
+		return "", err
+	}
+	return result.Value, nil
+}
+
+func (p *azureProvider) accessToken(ctx context.Context) (string, error) {
+	var req *http.Request
+	var err error
+
+	if p.opts.ClientSecret != "" {
+		if p.opts.TenantID == "" || p.opts.ClientID == "" {
+			return "", fmt.Errorf("azure tenant_id and client_id are required with a client secret")
+		}
+		form := url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {p.opts.ClientID},
+			"client_secret": {p.opts.ClientSecret},
+			"scope":         {azureVaultScope + "/.default"},
+		}
+		tokenURL := "https://login.microsoftonline.com/" + url.PathEscape(p.opts.TenantID) + "/oauth2/v2.0/token"
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		query := url.Values{"api-version": {"2018-02-01"}, "resource": {azureVaultScope}}
+		if p.opts.ClientID != "" {
+			query.Set("client_id", p.opts.ClientID)
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, azureIMDSEndpoint+"?"+query.Encode(), nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Metadata", "true")
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := p.doJSON(req, &result); err != nil {
+		return "", err
+	}
+	return result.AccessToken, nil
+}
+
+func (p *azureProvider) doJSON(req *http.Request, result interface{}) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", req.URL.Host, resp.StatusCode)
+	}
+	return json.Unmarshal(body, result)
+}
@@ -0,0 +1,140 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// MasterKeyEnv holds the base64 master key, taking precedence over the key file
+	MasterKeyEnv = "CONFIG_MASTER_KEY"
+
+	// MasterKeyFileName is the default key file, next to the config file
+	MasterKeyFileName = "master.key"
+
+	masterKeySize = 32 // AES-256
+
+	encPrefix = "ENC("
+	encSuffix = ")"
+)
+
+// ErrNoMasterKey is returned when an ENC(...) value is found but no master key is configured
+var ErrNoMasterKey = errors.New("no master key: set " + MasterKeyEnv + " or create the key file with -init-master-key")
+
+// IsEncrypted reports whether value is an ENC(...) value
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encPrefix) && strings.HasSuffix(value, encSuffix)
+}
+
+// Cipher encrypts and decrypts config values with AES-256-GCM. Encrypted values are written as
+// ENC(<base64 nonce and ciphertext>).
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != masterKeySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", masterKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt returns plain as an ENC(...) value
+func (c *Cipher) Encrypt(plain string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plain), nil)
+	return encPrefix + base64.StdEncoding.EncodeToString(sealed) + encSuffix, nil
+}
+
+// Decrypt returns the plain text of an ENC(...) value
+func (c *Cipher) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", fmt.Errorf("not an ENC(...) value")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(value[len(encPrefix) : len(value)-len(encSuffix)])
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", fmt.Errorf("malformed ENC(...) value")
+	}
+
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot decrypt ENC(...) value, wrong master key?")
+	}
+	return string(plain), nil
+}
+
+// LoadMasterKey returns the master key of CONFIG_MASTER_KEY, or else of the key file at path.
+// On Windows the key file is protected with DPAPI and only readable on the machine that
+// created it.
+func LoadMasterKey(path string) ([]byte, error) {
+	if encoded := strings.TrimSpace(os.Getenv(MasterKeyEnv)); encoded != "" {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", MasterKeyEnv, err)
+		}
+		return key, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoMasterKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read master key file: %w", err)
+	}
+
+	protected, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid master key file %s: %w", path, err)
+	}
+	key, err := unprotectKey(protected)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unprotect master key file %s: %w", path, err)
+	}
+	return key, nil
+}
+
+// CreateMasterKey generates a master key and writes it to a new key file at path, readable by
+// administrators and the service account only
+func CreateMasterKey(path string) error {
+	key := make([]byte, masterKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+
+	protected, err := protectKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to protect master key: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(base64.StdEncoding.EncodeToString(protected) + "\n"); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return restrictKeyFile(path)
+}
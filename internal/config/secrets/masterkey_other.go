@@ -0,0 +1,23 @@
+//go:build !windows
+// +build !windows
+
+package secrets
+
+// protectKey is a no-op outside Windows; the key file is only protected by its permissions
+func protectKey(key []byte) ([]byte, error) {
+	return key, nil
+}
+
+func unprotectKey(protected []byte) ([]byte, error) {
+	return protected, nil
+}
+
+// restrictKeyFile is a no-op outside Windows; the file is created with mode 0600
+func restrictKeyFile(path string) error {
+	return nil
+}
+
+// GrantKeyFileRead is a no-op outside Windows, where the service runs as the owner of the key file
+func GrantKeyFileRead(path, account string) error {
+	return nil
+}
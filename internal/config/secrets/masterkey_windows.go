@@ -0,0 +1,75 @@
+//go:build windows
+// +build windows
+
+package secrets
+
+import (
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// keyFileSDDL grants full access to SYSTEM (the service account) and Administrators only
+const keyFileSDDL = "D:P(A;;FA;;;SY)(A;;FA;;;BA)"
+
+// protectKey encrypts the key with DPAPI for the local machine, so a copied key file cannot be
+// used on another server
+func protectKey(key []byte) ([]byte, error) {
+	var out windows.DataBlob
+	in := windows.DataBlob{Size: uint32(len(key)), Data: &key[0]}
+	flags := uint32(windows.CRYPTPROTECT_LOCAL_MACHINE | windows.CRYPTPROTECT_UI_FORBIDDEN)
+	if err := windows.CryptProtectData(&in, nil, nil, 0, nil, flags, &out); err != nil {
+		return nil, err
+	}
+	return blobBytes(&out), nil
+}
+
+func unprotectKey(protected []byte) ([]byte, error) {
+	if len(protected) == 0 {
+		return nil, windows.ERROR_INVALID_DATA
+	}
+	var out windows.DataBlob
+	in := windows.DataBlob{Size: uint32(len(protected)), Data: &protected[0]}
+	if err := windows.CryptUnprotectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return nil, err
+	}
+	return blobBytes(&out), nil
+}
+
+// blobBytes copies the data of a blob allocated by DPAPI and frees it
+func blobBytes(blob *windows.DataBlob) []byte {
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(blob.Data)))
+	return append([]byte(nil), unsafe.Slice(blob.Data, blob.Size)...)
+}
+
+// restrictKeyFile replaces the inherited permissions of the key file with keyFileSDDL
+func restrictKeyFile(path string) error {
+	return setKeyFileDACL(path, keyFileSDDL)
+}
+
+// GrantKeyFileRead lets account, e.g. CORP\svc-esign, read the key file besides SYSTEM and
+// Administrators, for a service that does not run as SYSTEM
+func GrantKeyFileRead(path, account string) error {
+	// Local accounts may be given as .\name, which account lookups do not understand
+	sid, _, _, err := windows.LookupSID("", strings.TrimPrefix(account, `.\`))
+	if err != nil {
+		return fmt.Errorf("cannot find account %s: %w", account, err)
+	}
+	return setKeyFileDACL(path, keyFileSDDL+"(A;;FR;;;"+sid.String()+")")
+}
+
+// setKeyFileDACL replaces the permissions of the key file with those of sddl, without inheriting any
+func setKeyFileDACL(path, sddl string) error {
+	sd, err := windows.SecurityDescriptorFromString(sddl)
+	if err != nil {
+		return err
+	}
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return err
+	}
+	return windows.SetNamedSecurityInfo(path, windows.SE_FILE_OBJECT,
+		windows.DACL_SECURITY_INFORMATION|windows.PROTECTED_DACL_SECURITY_INFORMATION, nil, nil, dacl, nil)
+}
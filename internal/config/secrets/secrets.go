@@ -0,0 +1,121 @@
+// Package secrets resolves secret references in configuration values, such as
+// "vault://secret/data/mekari#client_secret", against an external secrets manager.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Provider fetches a secret by the part of a reference after "scheme://" and before "#"
+type Provider interface {
+	Fetch(ctx context.Context, path string) (string, error)
+}
+
+// Options configures the providers. Empty settings fall back to each provider's standard
+// environment variables (VAULT_ADDR, AWS_REGION, AZURE_TENANT_ID, ...).
+type Options struct {
+	Timeout time.Duration
+	Vault   VaultOptions
+	AWS     AWSOptions
+	Azure   AzureOptions
+}
+
+// Resolver resolves references for the registered schemes. Each secret is fetched once per
+// Resolver, so several keys of one secret cost a single request.
+type Resolver struct {
+	providers map[string]Provider
+	timeout   time.Duration
+	cache     map[string]string
+}
+
+func NewResolver(opts Options) *Resolver {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	client := &http.Client{}
+
+	return &Resolver{
+		providers: map[string]Provider{
+			SchemeVault: newVaultProvider(opts.Vault, client),
+			SchemeAWS:   newAWSProvider(opts.AWS, client),
+			SchemeAzure: newAzureProvider(opts.Azure, client),
+		},
+		timeout: opts.Timeout,
+		cache:   make(map[string]string),
+	}
+}
+
+// IsReference reports whether value is a reference to a supported secrets manager
+func (r *Resolver) IsReference(value string) bool {
+	scheme, _, ok := strings.Cut(value, "://")
+	if !ok {
+		return false
+	}
+	_, known := r.providers[scheme]
+	return known
+}
+
+// Resolve returns the secret a reference points to. With a "#key" suffix the secret must be
+// a JSON object (or a Vault key/value secret) and the value of key is returned.
+func (r *Resolver) Resolve(ctx context.Context, ref string) (string, error) {
+	scheme, rest, ok := strings.Cut(ref, "://")
+	provider, known := r.providers[scheme]
+	if !ok || !known {
+		return "", fmt.Errorf("unsupported secret reference %q", ref)
+	}
+	path, key, _ := strings.Cut(rest, "#")
+	if path == "" {
+		return "", fmt.Errorf("secret reference %q has no path", ref)
+	}
+
+	cacheKey := scheme + "://" + path
+	secret, cached := r.cache[cacheKey]
+	if !cached {
+		ctx, cancel := context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+
+		var err error
+		secret, err = provider.Fetch(ctx, path)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve %s: %w", cacheKey, err)
+		}
+		r.cache[cacheKey] = secret
+	}
+
+	if key == "" {
+		return secret, nil
+	}
+	return field(secret, key, cacheKey)
+}
+
+// field returns a key of a secret stored as a JSON object
+func field(secret, key, name string) (string, error) {
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(secret), &values); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object, cannot read key %q", name, key)
+	}
+
+	value, ok := values[key]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no key %q", name, key)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+// firstNonEmpty returns the first non-empty value, used for environment fallbacks
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
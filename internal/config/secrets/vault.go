@@ -0,0 +1,82 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// SchemeVault references HashiCorp Vault: vault://<mount>/<path>#<key>, e.g. for KV v2
+// vault://secret/data/mekari#client_secret
+const SchemeVault = "vault"
+
+type VaultOptions struct {
+	Address   string // Default: VAULT_ADDR
+	Token     string // Default: VAULT_TOKEN
+	Namespace string // Enterprise namespace (default: VAULT_NAMESPACE)
+}
+
+type vaultProvider struct {
+	opts   VaultOptions
+	client *http.Client
+}
+
+func newVaultProvider(opts VaultOptions, client *http.Client) *vaultProvider {
+	opts.Address = strings.TrimRight(firstNonEmpty(opts.Address, os.Getenv("VAULT_ADDR")), "/")
+	opts.Token = firstNonEmpty(opts.Token, os.Getenv("VAULT_TOKEN"))
+	opts.Namespace = firstNonEmpty(opts.Namespace, os.Getenv("VAULT_NAMESPACE"))
+	return &vaultProvider{opts: opts, client: client}
+}
+
+// Fetch reads a KV secret and returns its key/value data as a JSON object
+func (p *vaultProvider) Fetch(ctx context.Context, path string) (string, error) {
+	if p.opts.Address == "" || p.opts.Token == "" {
+		return "", fmt.Errorf("vault address and token are required (secrets.vault or VAULT_ADDR/VAULT_TOKEN)")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.opts.Address+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", p.opts.Token)
+	if p.opts.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.opts.Namespace)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("invalid vault response: %w", err)
+	}
+
+	// KV v2 nests the values under data.data (next to data.metadata), KV v1 does not
+	if nested, ok := result.Data["data"]; ok {
+		if _, hasMetadata := result.Data["metadata"]; hasMetadata {
+			return string(nested), nil
+		}
+	}
+	data, err := json.Marshal(result.Data)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
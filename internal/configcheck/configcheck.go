@@ -0,0 +1,226 @@
+// Package configcheck implements the -validate-config command line mode: it loads the
+// configuration, checks the required settings and optionally tests the connections to the
+// database, Redis and NAV, so a deployment can be verified before the service is restarted.
+// It also implements -init-master-key and -encrypt-value for ENC(...) config values, and the
+// provisioning of a new site by -install.
+package configcheck
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/infrastructure/database"
+	"mekari-esign/internal/infrastructure/metrics"
+	"mekari-esign/internal/infrastructure/nav"
+	"mekari-esign/internal/infrastructure/redis"
+)
+
+// connectTimeout bounds each connection test
+const connectTimeout = 10 * time.Second
+
+// Check results
+const (
+	statusOK   = "OK"
+	statusWarn = "WARN"
+	statusFail = "FAIL"
+	statusSkip = "SKIP"
+)
+
+// Options selects the connection tests run after the configuration is loaded
+type Options struct {
+	Database bool
+	Redis    bool
+	NAV      bool
+}
+
+type result struct {
+	status string
+	name   string
+	detail string
+}
+
+// Run loads and validates the configuration, runs the selected connection tests and writes a
+// report to out. It returns false when any check failed.
+func Run(out io.Writer, opts Options) bool {
+	fmt.Fprintln(out, "Configuration check")
+
+	cfg, err := config.NewConfig()
+	if err != nil {
+		report(out, []result{{statusFail, "Load configuration", err.Error()}})
+		return false
+	}
+
+	file := config.FileUsed()
+	if file == "" {
+		file = "(none, environment variables only)"
+	}
+	fmt.Fprintf(out, "  Config file: %s\n", file)
+	if overlay := config.OverlayUsed(); overlay != "" {
+		fmt.Fprintf(out, "  Overlay:     %s\n", overlay)
+	}
+	fmt.Fprintf(out, "  Environment: %s\n\n", cfg.App.Env)
+
+	results := []result{{status: statusOK, name: "Load configuration"}}
+	results = append(results, checkSettings(cfg)...)
+
+	ctx := context.Background()
+	if opts.Database {
+		results = append(results, timed("Database", func() (string, error) { return checkDatabase(ctx, cfg) }))
+	}
+	if opts.Redis {
+		results = append(results, timed("Redis", func() (string, error) { return checkRedis(ctx, cfg) }))
+	}
+	if opts.NAV {
+		if cfg.NAV.Enabled {
+			results = append(results, timed("NAV", func() (string, error) { return checkNAV(ctx, cfg) }))
+		} else {
+			results = append(results, result{statusSkip, "NAV", "nav.enabled is false"})
+		}
+	}
+
+	return report(out, results)
+}
+
+// checkSettings reports settings the service cannot work without, and likely mistakes
+func checkSettings(cfg *config.Config) []result {
+	var results []result
+	fail := func(name, detail string) { results = append(results, result{statusFail, name, detail}) }
+	warn := func(name, detail string) { results = append(results, result{statusWarn, name, detail}) }
+
+	if cfg.Mekari.BaseURL == "" {
+		fail("mekari.base_url", "is required")
+	} else if u, err := url.Parse(cfg.Mekari.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		fail("mekari.base_url", fmt.Sprintf("%q is not an absolute URL", cfg.Mekari.BaseURL))
+	}
+
+	switch cfg.Mekari.AuthType {
+	case config.AuthTypeOAuth2:
+		if cfg.Mekari.OAuth2.ClientID == "" || cfg.Mekari.OAuth2.ClientSecret == "" {
+			fail("mekari.oauth2", "client_id and client_secret are required for oauth2")
+		}
+	case config.AuthTypeHMAC:
+		if cfg.Mekari.HMAC.ClientID == "" || cfg.Mekari.HMAC.ClientSecret == "" {
+			fail("mekari.hmac", "client_id and client_secret are required for hmac")
+		}
+	default:
+		fail("mekari.auth_type", fmt.Sprintf("%q (expected oauth2 or hmac)", cfg.Mekari.AuthType))
+	}
+
+	if cfg.Database.DBName == "" || cfg.Database.User == "" {
+		fail("database", "dbname and user are required")
+	}
+
+	if cfg.NAV.Enabled && (cfg.NAV.BaseURL == "" || cfg.NAV.Company == "") {
+		fail("nav", "base_url and company are required when nav.enabled is true")
+	}
+
+	if cfg.App.BaseURL == "" {
+		warn("app.base_url", "is empty; Mekari cannot deliver stamping callbacks")
+	}
+
+	if cfg.Mekari.Mock {
+		warn("mekari.mock", fmt.Sprintf("is enabled; Mekari requests go to the fake API at %s", cfg.Mekari.MockURL()))
+	}
+
+	if len(results) == 0 {
+		results = append(results, result{status: statusOK, name: "Required settings"})
+	}
+	return results
+}
+
+func checkDatabase(ctx context.Context, cfg *config.Config) (string, error) {
+	dialect, err := database.NewDialect(cfg.Database.Driver)
+	if err != nil {
+		return "", err
+	}
+	db, err := sql.Open(dialect.Name(), dialect.DSN(cfg))
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+
+	target := fmt.Sprintf("%s:%d/%s", cfg.Database.Host, cfg.Database.Port, cfg.Database.DBName)
+	return target, db.PingContext(ctx)
+}
+
+func checkRedis(ctx context.Context, cfg *config.Config) (string, error) {
+	// The error is reported once below instead of per dial attempt
+	goredis.SetLogger(quietLogger{})
+
+	options, err := redis.Options(cfg)
+	if err != nil {
+		return "", err
+	}
+	addr := options.Addr
+	client := goredis.NewClient(options)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+
+	target := fmt.Sprintf("%s db %d", addr, cfg.Redis.DB)
+	if cfg.Redis.TLS.Enabled {
+		target += " (TLS)"
+	}
+	return target, client.Ping(ctx).Err()
+}
+
+func checkNAV(ctx context.Context, cfg *config.Config) (string, error) {
+	client := nav.NewClient(cfg, metrics.NewMetrics(), nil, zap.NewNop())
+	return fmt.Sprintf("%s, company %s", cfg.NAV.BaseURL, cfg.NAV.Company), client.Ping(ctx)
+}
+
+// quietLogger drops go-redis internal logging
+type quietLogger struct{}
+
+func (quietLogger) Printf(ctx context.Context, format string, v ...interface{}) {}
+
+// timed runs a connection test and reports its target and duration
+func timed(name string, test func() (string, error)) result {
+	start := time.Now()
+	target, err := test()
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	if err != nil {
+		return result{statusFail, name, fmt.Sprintf("%s: %v", target, err)}
+	}
+	return result{statusOK, name, fmt.Sprintf("%s (%s)", target, elapsed)}
+}
+
+// report writes one line per result and a summary, and returns false when any check failed
+func report(out io.Writer, results []result) bool {
+	failed, warnings := 0, 0
+	for _, r := range results {
+		line := fmt.Sprintf("  [%-4s] %s", r.status, r.name)
+		if r.detail != "" {
+			line += ": " + r.detail
+		}
+		fmt.Fprintln(out, line)
+
+		switch r.status {
+		case statusFail:
+			failed++
+		case statusWarn:
+			warnings++
+		}
+	}
+
+	fmt.Fprintln(out)
+	if failed > 0 {
+		fmt.Fprintf(out, "Configuration is INVALID: %d failed, %d warning(s)\n", failed, warnings)
+		return false
+	}
+	fmt.Fprintf(out, "Configuration is valid (%d warning(s))\n", warnings)
+	return true
+}
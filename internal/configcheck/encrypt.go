@@ -0,0 +1,47 @@
+package configcheck
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"mekari-esign/internal/config"
+)
+
+// InitMasterKey implements -init-master-key: it creates the key file of ENC(...) values
+func InitMasterKey(out io.Writer) bool {
+	path, err := config.InitMasterKey()
+	if err != nil {
+		fmt.Fprintf(out, "Failed to create the master key: %v\n", err)
+		return false
+	}
+	fmt.Fprintf(out, "Master key created: %s\n", path)
+	fmt.Fprintln(out, "Back it up somewhere safe: encrypted values cannot be recovered without it.")
+	return true
+}
+
+// EncryptValue implements -encrypt-value: it reads a value from in and writes it as an
+// ENC(...) value to paste into the config file. Reading the value from in rather than a flag
+// keeps it out of the shell history.
+func EncryptValue(in io.Reader, out io.Writer) bool {
+	fmt.Fprint(out, "Value to encrypt: ")
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		fmt.Fprintf(out, "\nFailed to read the value: %v\n", err)
+		return false
+	}
+	value := strings.TrimRight(line, "\r\n")
+	if value == "" {
+		fmt.Fprintln(out, "\nNo value given")
+		return false
+	}
+
+	encrypted, err := config.EncryptValue(value)
+	if err != nil {
+		fmt.Fprintf(out, "\nFailed to encrypt: %v\n", err)
+		return false
+	}
+	fmt.Fprintf(out, "\n%s\n", encrypted)
+	return true
+}
@@ -0,0 +1,296 @@
+package configcheck
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/infrastructure/database"
+)
+
+// setting is a value of a new config file, given by its flag or asked for
+type setting struct {
+	name     string // Key in the config template and name of the flag
+	prompt   string
+	def      func(values map[string]string) string
+	required bool
+	secret   bool                                // Its default is not shown
+	ask      func(values map[string]string) bool // Asked only when true (default: always)
+	value    *string
+}
+
+func fixed(s string) func(map[string]string) string {
+	return func(map[string]string) string { return s }
+}
+
+// defaultDBPorts are the standard ports of the database drivers
+var defaultDBPorts = map[string]string{
+	database.DriverPostgres:  "5432",
+	database.DriverMySQL:     "3306",
+	database.DriverSQLServer: "1433",
+}
+
+// ProvisionOptions are the settings of a new config file. They are set from flags registered by
+// ProvisionFlags; those left empty are asked for.
+type ProvisionOptions struct {
+	settings []*setting
+}
+
+// ProvisionFlags registers a flag on fs for every setting of a new config file, e.g.
+// -mekari-client-id and -db-password
+func ProvisionFlags(fs *flag.FlagSet) *ProvisionOptions {
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "localhost"
+	}
+	navEnabled := func(v map[string]string) bool { return v["nav-url"] != "" }
+
+	opts := &ProvisionOptions{settings: []*setting{
+		{name: "base-url", prompt: "Public URL of this service, for Mekari callbacks", def: fixed("http://" + hostname + ":8080")},
+		{name: "mekari-auth-type", prompt: "Mekari authentication (oauth2 or hmac)", def: fixed(config.AuthTypeOAuth2)},
+		{name: "mekari-url", prompt: "Mekari API URL", def: fixed("https://sandbox-api.mekari.com")},
+		{name: "mekari-sso-url", prompt: "Mekari SSO URL", def: fixed("https://sandbox-sso.mekari.com")},
+		{name: "mekari-auth-url", prompt: "Mekari account URL", def: fixed("https://sandbox-account.mekari.com")},
+		{name: "mekari-client-id", prompt: "Mekari client ID", required: true},
+		{name: "mekari-client-secret", prompt: "Mekari client secret", required: true, secret: true},
+		{name: "db-driver", prompt: "Database driver (postgres, mysql or sqlserver)", def: fixed(database.DriverPostgres)},
+		{name: "db-host", prompt: "Database host", def: fixed("localhost")},
+		{name: "db-port", prompt: "Database port", def: func(v map[string]string) string { return defaultDBPorts[v["db-driver"]] }},
+		{name: "db-user", prompt: "Database user", required: true},
+		{name: "db-password", prompt: "Database password", secret: true},
+		{name: "db-name", prompt: "Database name", def: fixed("mekari_esign")},
+		{name: "redis-host", prompt: "Redis host", def: fixed("localhost")},
+		{name: "redis-port", prompt: "Redis port", def: fixed("6379")},
+		{name: "redis-password", prompt: "Redis password", secret: true},
+		{name: "documents", prompt: "Document folder, with the ready, progress and finish folders", def: fixed("./documents")},
+		{name: "nav-url", prompt: "NAV OData URL (empty = NAV integration disabled)"},
+		{name: "nav-company", prompt: "NAV company", required: true, ask: navEnabled},
+		{name: "nav-user", prompt: "NAV user", ask: navEnabled},
+		{name: "nav-password", prompt: "NAV password", secret: true, ask: navEnabled},
+	}}
+	for _, s := range opts.settings {
+		s.value = fs.String(s.name, "", "With -install and no config file: "+strings.ToLower(s.prompt[:1])+s.prompt[1:])
+	}
+	return opts
+}
+
+// Provision prepares a new site for the service: it writes config.yml next to the executable
+// when there is none, asking on in for the settings not given by flags, creates the document
+// folders, runs the database migrations and tests the connections to Redis and NAV. It reports
+// to out and returns false when any step failed.
+func Provision(in io.Reader, out io.Writer, opts *ProvisionOptions) bool {
+	fmt.Fprintln(out, "Provisioning")
+
+	path, exists := configPath()
+	if exists {
+		fmt.Fprintf(out, "  Config file: %s (kept)\n", path)
+	} else {
+		if err := writeConfig(in, out, path, opts); err != nil {
+			fmt.Fprintf(out, "Failed to write %s: %v\n", path, err)
+			return false
+		}
+		fmt.Fprintf(out, "  Config file: %s (written)\n", path)
+	}
+
+	cfg, err := config.NewConfig()
+	if err != nil {
+		report(out, []result{{statusFail, "Load configuration", err.Error()}})
+		return false
+	}
+	fmt.Fprintln(out)
+
+	results := []result{{status: statusOK, name: "Load configuration"}}
+	results = append(results, checkSettings(cfg)...)
+	results = append(results, timed("Document folders", func() (string, error) { return createFolders(cfg) }))
+	results = append(results, timed("Database migrations", func() (string, error) { return migrate(cfg) }))
+
+	ctx := context.Background()
+	results = append(results, timed("Redis", func() (string, error) { return checkRedis(ctx, cfg) }))
+	if cfg.NAV.Enabled {
+		results = append(results, timed("NAV", func() (string, error) { return checkNAV(ctx, cfg) }))
+	} else {
+		results = append(results, result{statusSkip, "NAV", "nav.enabled is false"})
+	}
+
+	return report(out, results)
+}
+
+// configPath returns the config file the service reads and whether it exists; config.yml in
+// the working directory when there is none
+func configPath() (string, bool) {
+	if path := os.Getenv(config.ConfigFileEnv); path != "" {
+		_, err := os.Stat(path)
+		return path, err == nil
+	}
+	for _, dir := range []string{".", "config"} {
+		for _, name := range []string{"config.yml", "config.yaml"} {
+			path := filepath.Join(dir, name)
+			if _, err := os.Stat(path); err == nil {
+				return path, true
+			}
+		}
+	}
+	return "config.yml", false
+}
+
+// writeConfig writes a config file with the settings of opts, asking on in for those not given
+func writeConfig(in io.Reader, out io.Writer, path string, opts *ProvisionOptions) error {
+	fmt.Fprintf(out, "  No config file, writing %s. Press Enter to accept the [default].\n", path)
+	reader := bufio.NewReader(in)
+	values := make(map[string]string)
+	for _, s := range opts.settings {
+		value, err := resolve(reader, out, s, values)
+		if err != nil {
+			return err
+		}
+		values[s.name] = value
+	}
+
+	if _, err := database.NewDialect(values["db-driver"]); err != nil {
+		return err
+	}
+	switch values["mekari-auth-type"] {
+	case config.AuthTypeOAuth2, config.AuthTypeHMAC:
+	default:
+		return fmt.Errorf("invalid Mekari authentication %q (expected oauth2 or hmac)", values["mekari-auth-type"])
+	}
+	for _, name := range []string{"db-port", "redis-port"} {
+		if n, err := strconv.Atoi(values[name]); err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("invalid %s %q (expected 1-65535)", name, values[name])
+		}
+	}
+
+	var b strings.Builder
+	if err := configTemplate.Execute(&b, values); err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	// Holds credentials; keep it from other users where the file system supports it
+	return os.WriteFile(path, []byte(b.String()), 0600)
+}
+
+// resolve returns the flag value of s, or asks for it. Without more input (e.g. a redirected
+// empty stdin) the defaults are used.
+func resolve(reader *bufio.Reader, out io.Writer, s *setting, values map[string]string) (string, error) {
+	if *s.value != "" {
+		return *s.value, nil
+	}
+	if s.ask != nil && !s.ask(values) {
+		return "", nil
+	}
+	def := ""
+	if s.def != nil {
+		def = s.def(values)
+	}
+
+	prompt := "  " + s.prompt
+	if def != "" && !s.secret {
+		prompt += " [" + def + "]"
+	}
+	fmt.Fprint(out, prompt+": ")
+	line, err := reader.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	if errors.Is(err, io.EOF) {
+		fmt.Fprintln(out)
+	}
+
+	value := strings.TrimSpace(line)
+	if value == "" {
+		value = def
+	}
+	if value == "" && s.required {
+		return "", fmt.Errorf("%s is required (-%s)", strings.ToLower(s.prompt[:1])+s.prompt[1:], s.name)
+	}
+	return value, nil
+}
+
+func createFolders(cfg *config.Config) (string, error) {
+	doc := cfg.Document
+	for _, folder := range []string{doc.ReadyFolder, doc.ProgressFolder, doc.FinishFolder} {
+		if err := os.MkdirAll(filepath.Join(doc.BasePath, folder), 0755); err != nil {
+			return doc.BasePath, err
+		}
+	}
+	return doc.BasePath, nil
+}
+
+// migrate connects to the database like the service, which creates and migrates the tables
+func migrate(cfg *config.Config) (string, error) {
+	target := fmt.Sprintf("%s:%d/%s", cfg.Database.Host, cfg.Database.Port, cfg.Database.DBName)
+	cfg.Startup.Wait = false
+	db, err := database.NewDatabase(cfg, zap.NewNop())
+	if err != nil {
+		return target, err
+	}
+	return target, db.DB.Close()
+}
+
+// configTemplate is the config file written by Provision; config.example.yml lists every setting
+var configTemplate = template.Must(template.New("config").Funcs(template.FuncMap{"q": strconv.Quote}).Parse(`# Written by -install; config.example.yml lists every setting
+app:
+  port: 8080
+  env: "production"
+  base_url: {{q (index . "base-url")}}
+
+mekari:
+  auth_type: {{q (index . "mekari-auth-type")}}
+  base_url: {{q (index . "mekari-url")}}
+  sso_base_url: {{q (index . "mekari-sso-url")}}
+  auth_url: {{q (index . "mekari-auth-url")}}
+  timeout: 30
+  {{index . "mekari-auth-type"}}:
+    client_id: {{q (index . "mekari-client-id")}}
+    client_secret: {{q (index . "mekari-client-secret")}}
+
+database:
+  driver: {{q (index . "db-driver")}}
+  host: {{q (index . "db-host")}}
+  port: {{index . "db-port"}}
+  user: {{q (index . "db-user")}}
+  password: {{q (index . "db-password")}}
+  dbname: {{q (index . "db-name")}}
+  sslmode: "disable"
+
+redis:
+  host: {{q (index . "redis-host")}}
+  port: {{index . "redis-port"}}
+  password: {{q (index . "redis-password")}}
+  db: 0
+
+document:
+  base_path: {{q (index . "documents")}}
+  ready_folder: "ready"
+  progress_folder: "progress"
+  finish_folder: "finish"
+  file_extension: ".pdf"
+
+logging:
+  level: "info"
+  format: "json"
+  event_log: true
+
+nav:
+  enabled: {{if index . "nav-url"}}true{{else}}false{{end}}
+  base_url: {{q (index . "nav-url")}}
+  company: {{q (index . "nav-company")}}
+  username: {{q (index . "nav-user")}}
+  password: {{q (index . "nav-password")}}
+  timeout: 30
+`))
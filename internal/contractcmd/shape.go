@@ -0,0 +1,195 @@
+package contractcmd
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// JSON kinds of a shape; kindAny accepts any value and everything below it
+const (
+	kindObject = "object"
+	kindArray  = "array"
+	kindString = "string"
+	kindNumber = "number"
+	kindBool   = "bool"
+	kindNull   = "null"
+	kindAny    = "any"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// field is a JSON path read by one of our types
+type field struct {
+	kind     string
+	optional bool // omitempty, Mekari may leave it out
+}
+
+// jsonShape flattens a decoded JSON value into the kind of each path, e.g.
+// "data.attributes.signers[].email": "string". Array elements share the path of the array with [].
+func jsonShape(v interface{}, path string, out map[string]string) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		out[path] = kindObject
+		for key, value := range t {
+			jsonShape(value, joinPath(path, key), out)
+		}
+	case []interface{}:
+		out[path] = kindArray
+		for _, value := range t {
+			jsonShape(value, path+"[]", out)
+		}
+	case string:
+		out[path] = kindString
+	case float64:
+		out[path] = kindNumber
+	case bool:
+		out[path] = kindBool
+	default:
+		out[path] = kindNull
+	}
+}
+
+// typeShape flattens the JSON paths a type is decoded from, the way encoding/json reads them
+func typeShape(t reflect.Type, path string, optional bool, out map[string]field) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		out[path] = field{kindString, optional}
+	case t.Kind() == reflect.Struct:
+		out[path] = field{kindObject, optional}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if f.Anonymous && name == "" {
+				typeShape(f.Type, path, optional, out)
+				out[path] = field{kindObject, optional}
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			typeShape(f.Type, joinPath(path, name), strings.Contains(opts, "omitempty"), out)
+		}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		out[path] = field{kindString, optional}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		out[path] = field{kindArray, optional}
+		typeShape(t.Elem(), path+"[]", false, out)
+	case t.Kind() == reflect.String:
+		out[path] = field{kindString, optional}
+	case t.Kind() == reflect.Bool:
+		out[path] = field{kindBool, optional}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Float64:
+		out[path] = field{kindNumber, optional}
+	default:
+		out[path] = field{kindAny, optional}
+	}
+}
+
+// compareRequest reports how the request we sent differs from the recorded one. Fields Mekari
+// has not seen fail; recorded fields we no longer send only warn, they may be optional.
+func compareRequest(recorded, sent map[string]string) []result {
+	var results []result
+	for _, path := range sortedKeys(sent) {
+		want, ok := recorded[path]
+		switch {
+		case !ok:
+			if _, parent := recorded[parentPath(path)]; parent || parentPath(path) == "" {
+				results = append(results, result{statusFail, "", fmt.Sprintf("sends %s, which the recorded request does not have", displayPath(path))})
+			}
+		case want != sent[path] && want != kindNull && sent[path] != kindNull:
+			results = append(results, result{statusFail, "", fmt.Sprintf("sends %s as %s, recorded as %s", displayPath(path), sent[path], want)})
+		}
+	}
+	for _, path := range sortedKeys(recorded) {
+		if _, ok := sent[path]; !ok {
+			if _, parent := sent[parentPath(path)]; parent {
+				results = append(results, result{statusWarn, "", fmt.Sprintf("does not send %s of the recorded request", displayPath(path))})
+			}
+		}
+	}
+	return results
+}
+
+// compareResponse reports how a recorded response differs from the type we decode it into.
+// Fields we read that are gone or changed type fail; new fields only warn.
+func compareResponse(expected map[string]field, recorded map[string]string) []result {
+	var results []result
+	for _, path := range sortedKeys(expected) {
+		want := expected[path]
+		got, ok := recorded[path]
+		switch {
+		case !ok:
+			if _, parent := recorded[parentPath(path)]; !want.optional && (parent || path == "") && !underAny(path, expected) {
+				results = append(results, result{statusFail, "", fmt.Sprintf("%s is missing from the response", displayPath(path))})
+			}
+		case want.kind != kindAny && got != kindNull && got != want.kind:
+			results = append(results, result{statusFail, "", fmt.Sprintf("%s is %s in the response, we read %s", displayPath(path), got, want.kind)})
+		}
+	}
+	for _, path := range sortedKeys(recorded) {
+		if _, ok := expected[path]; !ok && !underAny(path, expected) {
+			if _, parent := expected[parentPath(path)]; parent {
+				results = append(results, result{statusWarn, "", fmt.Sprintf("new field %s in the response", displayPath(path))})
+			}
+		}
+	}
+	return results
+}
+
+// underAny reports whether a path is below a field of any type, such as a map or interface{}
+func underAny(path string, expected map[string]field) bool {
+	for path != "" {
+		path = parentPath(path)
+		if f, ok := expected[path]; ok && f.kind == kindAny {
+			return true
+		}
+	}
+	return false
+}
+
+// parentPath returns the path of the object or array holding path
+func parentPath(path string) string {
+	if strings.HasSuffix(path, "[]") {
+		return strings.TrimSuffix(path, "[]")
+	}
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		return path[:i]
+	}
+	return ""
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "the body"
+	}
+	return path
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
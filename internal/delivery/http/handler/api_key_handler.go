@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/delivery/http/validation"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/usecase"
+)
+
+type APIKeyHandler struct {
+	usecase usecase.APIKeyUsecase
+	logger  *zap.Logger
+}
+
+func NewAPIKeyHandler(usecase usecase.APIKeyUsecase, logger *zap.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		usecase: usecase,
+		logger:  logger,
+	}
+}
+
+// CreateAPIKey godoc
+// @Summary Create API key
+// @Description Create a new API key. The plaintext key is only returned in this response.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Param request body entity.CreateAPIKeyRequest true "Create API key request"
+// @Success 201 {object} entity.APIResponse
+// @Failure 400 {object} entity.APIResponse
+// @Failure 422 {object} entity.APIResponse "Field validation errors"
+// @Failure 500 {object} entity.APIResponse
+// @Router /admin/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	var req entity.CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		h.logger.Error("Failed to parse request body", zap.Error(err))
+		return c.Status(fiber.StatusBadRequest).JSON(
+			entity.NewErrorResponse("BAD_REQUEST", "Invalid request body"),
+		)
+	}
+
+	if fields := validation.Validate(&req); fields != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(entity.NewValidationErrorResponse(fields))
+	}
+
+	result, err := h.usecase.CreateKey(ctx, &req)
+	if err != nil {
+		h.logger.Error("Failed to create API key", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(
+			entity.NewErrorResponse("INTERNAL_ERROR", err.Error()),
+		)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(
+		entity.NewSuccessResponse(result, "API key created. Store the key now, it will not be shown again."),
+	)
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description List all API keys with usage information (secrets are never returned)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Success 200 {object} entity.APIResponse
+// @Failure 500 {object} entity.APIResponse
+// @Router /admin/api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys(c *fiber.Ctx) error {
+	keys, err := h.usecase.ListKeys(c.UserContext())
+	if err != nil {
+		h.logger.Error("Failed to list API keys", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(
+			entity.NewErrorResponse("INTERNAL_ERROR", err.Error()),
+		)
+	}
+
+	return c.JSON(entity.NewSuccessResponse(keys, "API keys retrieved successfully"))
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke API key
+// @Description Revoke an API key so it can no longer be used
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Param id path int true "API key ID"
+// @Success 200 {object} entity.APIResponse
+// @Failure 400 {object} entity.APIResponse
+// @Failure 404 {object} entity.APIResponse
+// @Router /admin/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(
+			entity.NewErrorResponse("BAD_REQUEST", "Invalid API key ID"),
+		)
+	}
+
+	if err := h.usecase.RevokeKey(c.UserContext(), id); err != nil {
+		h.logger.Warn("Failed to revoke API key", zap.Int64("id", id), zap.Error(err))
+		return c.Status(fiber.StatusNotFound).JSON(
+			entity.NewErrorResponse("NOT_FOUND", err.Error()),
+		)
+	}
+
+	return c.JSON(entity.NewSuccessResponse(map[string]int64{"id": id}, "API key revoked successfully"))
+}
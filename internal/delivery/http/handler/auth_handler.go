@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/delivery/http/middleware"
+	"mekari-esign/internal/delivery/http/validation"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/reload"
+	"mekari-esign/internal/usecase"
+)
+
+type AuthHandler struct {
+	config   *config.Config
+	usecase  usecase.AuthUsecase
+	reloader *reload.Reloader
+	logger   *zap.Logger
+}
+
+func NewAuthHandler(cfg *config.Config, usecase usecase.AuthUsecase, reloader *reload.Reloader, logger *zap.Logger) *AuthHandler {
+	return &AuthHandler{
+		config:   cfg,
+		usecase:  usecase,
+		reloader: reloader,
+		logger:   logger,
+	}
+}
+
+// Login godoc
+// @Summary Login
+// @Description Authenticate with username and password and receive a bearer token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body entity.LoginRequest true "Login request"
+// @Success 200 {object} entity.APIResponse
+// @Failure 400 {object} entity.APIResponse
+// @Failure 401 {object} entity.APIResponse
+// @Failure 422 {object} entity.APIResponse "Field validation errors"
+// @Router /auth/login [post]
+func (h *AuthHandler) Login(c *fiber.Ctx) error {
+	var req entity.LoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(
+			entity.NewErrorResponse("BAD_REQUEST", "Invalid request body"),
+		)
+	}
+
+	if fields := validation.Validate(&req); fields != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(entity.NewValidationErrorResponse(fields))
+	}
+
+	result, err := h.usecase.Login(c.UserContext(), &req)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidCredentials) {
+			h.logger.Warn("Failed login attempt", zap.String("username", req.Username), zap.String("ip", c.IP()))
+			return c.Status(fiber.StatusUnauthorized).JSON(
+				entity.NewErrorResponse("UNAUTHORIZED", err.Error()),
+			)
+		}
+		h.logger.Error("Failed to login", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(
+			entity.NewErrorResponse("INTERNAL_ERROR", "Failed to login"),
+		)
+	}
+
+	return c.JSON(entity.NewSuccessResponse(result, "Login successful"))
+}
+
+// Me godoc
+// @Summary Current user
+// @Description Get the user of the bearer token
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} entity.APIResponse
+// @Failure 401 {object} entity.APIResponse
+// @Router /auth/me [get]
+func (h *AuthHandler) Me(c *fiber.Ctx) error {
+	user := middleware.CurrentUser(c)
+	if user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(
+			entity.NewErrorResponse("UNAUTHORIZED", "Authentication is required"),
+		)
+	}
+
+	return c.JSON(entity.NewSuccessResponse(user, "User retrieved successfully"))
+}
+
+// CreateUser godoc
+// @Summary Create user
+// @Description Create a user with role admin, operator or read_only
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Param request body entity.CreateUserRequest true "Create user request"
+// @Success 201 {object} entity.APIResponse
+// @Failure 400 {object} entity.APIResponse
+// @Failure 422 {object} entity.APIResponse "Field validation errors"
+// @Router /admin/users [post]
+func (h *AuthHandler) CreateUser(c *fiber.Ctx) error {
+	var req entity.CreateUserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(
+			entity.NewErrorResponse("BAD_REQUEST", "Invalid request body"),
+		)
+	}
+
+	if fields := validation.Validate(&req); fields != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(entity.NewValidationErrorResponse(fields))
+	}
+
+	user, err := h.usecase.CreateUser(c.UserContext(), &req)
+	if err != nil {
+		h.logger.Warn("Failed to create user", zap.String("username", req.Username), zap.Error(err))
+		return c.Status(fiber.StatusBadRequest).JSON(
+			entity.NewErrorResponse("BAD_REQUEST", err.Error()),
+		)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(entity.NewSuccessResponse(user, "User created successfully"))
+}
+
+// ListUsers godoc
+// @Summary List users
+// @Description List all users and their roles
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Success 200 {object} entity.APIResponse
+// @Failure 500 {object} entity.APIResponse
+// @Router /admin/users [get]
+func (h *AuthHandler) ListUsers(c *fiber.Ctx) error {
+	users, err := h.usecase.ListUsers(c.UserContext())
+	if err != nil {
+		h.logger.Error("Failed to list users", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(
+			entity.NewErrorResponse("INTERNAL_ERROR", err.Error()),
+		)
+	}
+
+	return c.JSON(entity.NewSuccessResponse(users, "Users retrieved successfully"))
+}
+
+// GetConfig godoc
+// @Summary Get configuration
+// @Description Get the running configuration with secrets masked
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Success 200 {object} entity.APIResponse
+// @Router /admin/config [get]
+func (h *AuthHandler) GetConfig(c *fiber.Ctx) error {
+	return c.JSON(entity.NewSuccessResponse(h.config.Redacted(), "Configuration retrieved successfully"))
+}
+
+// ReloadConfig godoc
+// @Summary Reload configuration
+// @Description Re-read the config file and apply folder paths, NAV settings, timeouts and the log level
+// @Description without a restart. Returns every changed setting (secrets masked); settings with
+// @Description applied=false only take effect after a restart.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Success 200 {object} entity.APIResponse
+// @Failure 422 {object} entity.APIResponse "Invalid config file"
+// @Router /api/v1/admin/config/reload [post]
+func (h *AuthHandler) ReloadConfig(c *fiber.Ctx) error {
+	changes, err := h.reloader.Reload(requestActor(c))
+	if err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(
+			entity.NewErrorResponse("INVALID_CONFIG", err.Error()),
+		)
+	}
+	if changes == nil {
+		changes = []config.Change{}
+	}
+
+	return c.JSON(entity.NewSuccessResponse(changes, "Configuration reloaded successfully"))
+}
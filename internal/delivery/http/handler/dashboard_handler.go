@@ -0,0 +1,291 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/delivery/http/middleware"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/i18n"
+	"mekari-esign/internal/usecase"
+)
+
+type DashboardHandler struct {
+	usecase    usecase.DashboardUsecase
+	translator i18n.Translator
+	logger     *zap.Logger
+}
+
+func NewDashboardHandler(usecase usecase.DashboardUsecase, translator i18n.Translator, logger *zap.Logger) *DashboardHandler {
+	return &DashboardHandler{
+		usecase:    usecase,
+		translator: translator,
+		logger:     logger,
+	}
+}
+
+// GetSummary godoc
+// @Summary Dashboard summary
+// @Description Document counts per folder (ready/progress/finish), stuck documents, webhook queue and recent failures
+// @Tags dashboard
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Success 200 {object} entity.APIResponse
+// @Failure 401 {object} entity.APIResponse
+// @Failure 500 {object} entity.APIResponse
+// @Router /admin/dashboard/summary [get]
+func (h *DashboardHandler) GetSummary(c *fiber.Ctx) error {
+	summary, err := h.usecase.GetSummary(c.UserContext())
+	if err != nil {
+		h.logger.Error("Failed to build dashboard summary", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(
+			entity.NewErrorResponse("INTERNAL_ERROR", err.Error()),
+		)
+	}
+
+	return c.JSON(entity.NewSuccessResponse(summary, "Dashboard summary retrieved successfully"))
+}
+
+// GetQuota godoc
+// @Summary E-meterai quota
+// @Description Remaining e-meterai balance per connected account (cached for quota.cache_ttl seconds)
+// @Tags dashboard
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Success 200 {object} entity.APIResponse
+// @Failure 401 {object} entity.APIResponse
+// @Failure 500 {object} entity.APIResponse
+// @Router /admin/dashboard/quota [get]
+func (h *DashboardHandler) GetQuota(c *fiber.Ctx) error {
+	quotas, err := h.usecase.GetQuota(c.UserContext())
+	if err != nil {
+		h.logger.Error("Failed to get e-meterai quota", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(
+			entity.NewErrorResponse("INTERNAL_ERROR", err.Error()),
+		)
+	}
+
+	return c.JSON(entity.NewSuccessResponse(quotas, "E-meterai quota retrieved successfully"))
+}
+
+// GetTokenExpiry godoc
+// @Summary OAuth token expiry
+// @Description OAuth access/refresh token expiry per email (no token values are returned)
+// @Tags dashboard
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Success 200 {object} entity.APIResponse
+// @Failure 401 {object} entity.APIResponse
+// @Failure 500 {object} entity.APIResponse
+// @Router /admin/dashboard/tokens [get]
+func (h *DashboardHandler) GetTokenExpiry(c *fiber.Ctx) error {
+	tokens, err := h.usecase.GetTokenExpiry(c.UserContext())
+	if err != nil {
+		h.logger.Error("Failed to get token expiry", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(
+			entity.NewErrorResponse("INTERNAL_ERROR", err.Error()),
+		)
+	}
+
+	return c.JSON(entity.NewSuccessResponse(tokens, "Token expiry retrieved successfully"))
+}
+
+// Dashboard serves the HTML operations dashboard in the language of the request. The page itself
+// is public; its data endpoints require the operator role, so the page asks for a login when needed.
+func (h *DashboardHandler) Dashboard(c *fiber.Ctx) error {
+	html := `<!DOCTYPE html>
+<html lang="{{LANG}}">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{i18n:PageDashboardTitle}}</title>
+    <style>
+        * { box-sizing: border-box; margin: 0; padding: 0; }
+        body { font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif; background: #1a1a2e; color: #eee; padding: 20px; }
+        h1 { color: #00d4ff; margin-bottom: 20px; }
+        h2 { color: #00d4ff; font-size: 18px; margin: 25px 0 10px; }
+        .toolbar { margin-bottom: 20px; display: flex; gap: 10px; align-items: center; color: #888; }
+        button { padding: 10px 20px; font-size: 14px; background: #00d4ff; color: #000; border: none; border-radius: 8px; cursor: pointer; font-weight: bold; }
+        button:hover { background: #00ff88; }
+        .stats { display: flex; gap: 15px; flex-wrap: wrap; }
+        .stat-item { background: #0f3460; padding: 15px 25px; border-radius: 8px; text-align: center; min-width: 140px; }
+        .stat-value { font-size: 28px; font-weight: bold; color: #00d4ff; }
+        .stat-label { font-size: 12px; color: #888; margin-top: 4px; }
+        .stat-warn .stat-value { color: #ffa502; }
+        .stat-error .stat-value { color: #ff4757; }
+        table { width: 100%; border-collapse: collapse; background: #16213e; border-radius: 8px; overflow: hidden; }
+        th, td { padding: 10px 12px; text-align: left; border-bottom: 1px solid #0f3460; font-size: 14px; }
+        th { background: #0f3460; color: #00d4ff; font-weight: 600; }
+        tr:hover { background: #1f4068; }
+        .ok { color: #00ff88; font-weight: bold; }
+        .warn { color: #ffa502; font-weight: bold; }
+        .error { color: #ff4757; font-weight: bold; }
+        .muted { color: #888; }
+        .message { max-width: 500px; overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
+        .grid { display: grid; grid-template-columns: 1fr 1fr; gap: 20px; }
+        @media (max-width: 1000px) { .grid { grid-template-columns: 1fr; } }
+    </style>
+</head>
+<body>
+    <h1>📊 {{i18n:PageDashboardTitle}}</h1>
+
+    <div class="toolbar">
+        <button onclick="refresh()">🔄 {{i18n:PageRefresh}}</button>
+        <span id="generatedAt"></span>
+    </div>
+
+    <h2>{{i18n:PageDocuments}}</h2>
+    <div class="stats" id="documentStats"><p class="muted">{{i18n:PageLoading}}</p></div>
+
+    <h2>{{i18n:PageWebhookQueue}}</h2>
+    <div class="stats" id="queueStats"><p class="muted">{{i18n:PageLoading}}</p></div>
+
+    <div class="grid">
+        <div>
+            <h2>{{i18n:PageQuota}}</h2>
+            <div id="quota"><p class="muted">{{i18n:PageLoading}}</p></div>
+        </div>
+        <div>
+            <h2>{{i18n:PageTokenExpiry}}</h2>
+            <div id="tokens"><p class="muted">{{i18n:PageLoading}}</p></div>
+        </div>
+    </div>
+
+    <h2>{{i18n:PageStuckDocuments}}</h2>
+    <div id="stuck"><p class="muted">{{i18n:PageLoading}}</p></div>
+
+    <h2>{{i18n:PageRecentDocumentErrors}}</h2>
+    <div id="documentErrors"><p class="muted">{{i18n:PageLoading}}</p></div>
+
+    <h2>{{i18n:PageFailedWebhookEvents}}</h2>
+    <div id="webhookFailures"><p class="muted">{{i18n:PageLoading}}</p></div>
+
+    <script>
+        async function login() {
+            localStorage.removeItem('authToken');
+            const username = prompt('{{i18n:PageLoginPrompt}}');
+            if (!username) return false;
+            const password = prompt('{{i18n:PagePassword}}');
+            if (!password) return false;
+            const res = await fetch('/auth/login', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ username: username, password: password })
+            });
+            const data = await res.json();
+            if (!data.success) { alert(data.message); return false; }
+            localStorage.setItem('authToken', data.data.token);
+            return true;
+        }
+
+        async function api(url) {
+            const token = localStorage.getItem('authToken');
+            const res = await fetch(url, { headers: token ? { 'Authorization': 'Bearer ' + token } : {} });
+            if (res.status === 401 || res.status === 403) {
+                if (await login()) { return api(url); }
+                throw new Error('{{i18n:PageNotAuthorized}}');
+            }
+            const data = await res.json();
+            if (!data.success) throw new Error(data.message);
+            return data.data;
+        }
+
+        function escapeHtml(str) {
+            if (str === null || str === undefined) return '';
+            return String(str).replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;').replace(/"/g, '&quot;');
+        }
+
+        function formatTime(value) {
+            return value ? new Date(value).toLocaleString() : '-';
+        }
+
+        function stat(value, label, cls) {
+            return '<div class="stat-item ' + (cls || '') + '"><div class="stat-value">' + value + '</div><div class="stat-label">' + escapeHtml(label) + '</div></div>';
+        }
+
+        function table(headers, rows, empty) {
+            if (!rows.length) return '<p class="muted">' + empty + '</p>';
+            return '<table><thead><tr>' + headers.map(h => '<th>' + h + '</th>').join('') + '</tr></thead><tbody>' +
+                rows.map(r => '<tr>' + r.map(c => '<td>' + c + '</td>').join('') + '</tr>').join('') + '</tbody></table>';
+        }
+
+        function renderSummary(s) {
+            const stuck = s.stuck_documents.length;
+            document.getElementById('generatedAt').textContent = '{{i18n:PageUpdated}} ' + formatTime(s.generated_at);
+            document.getElementById('documentStats').innerHTML =
+                stat(s.documents.ready, '{{i18n:PageReady}}') +
+                stat(s.documents.progress, '{{i18n:PageInProgress}}') +
+                stat(s.documents.finish, '{{i18n:PageFinished}}') +
+                stat(stuck, '{{i18n:PageStuck}}', stuck ? 'stat-warn' : '');
+
+            const q = s.webhook_queue;
+            document.getElementById('queueStats').innerHTML =
+                stat(q.pending, '{{i18n:PagePending}}', q.pending ? 'stat-warn' : '') +
+                stat(q.processing, '{{i18n:PageProcessing}}') +
+                stat(q.processed, '{{i18n:PageProcessed}}') +
+                stat(q.failed, '{{i18n:PageFailed}}', q.failed ? 'stat-error' : '');
+
+            document.getElementById('stuck').innerHTML = table(
+                ['{{i18n:PageFilename}}', '{{i18n:PageInProgressSince}}', '{{i18n:PageAge}}'],
+                s.stuck_documents.map(d => [escapeHtml(d.filename), formatTime(d.since), '<span class="warn">' + d.age_hours + 'h</span>']),
+                '{{i18n:PageNoStuckDocuments}} ' + escapeHtml(s.folders.progress));
+
+            document.getElementById('documentErrors').innerHTML = table(
+                ['{{i18n:PageTime}}', '{{i18n:PageInvoice}}', '{{i18n:PageDocument}}', '{{i18n:PageError}}'],
+                s.recent_failures.document_errors.map(e => [formatTime(e.created_at), escapeHtml(e.invoice_no || '-'),
+                    escapeHtml(e.document_id), '<div class="message error" title="' + escapeHtml(e.description) + '">' + escapeHtml(e.description) + '</div>']),
+                '{{i18n:PageNoRecentDocumentErrors}}');
+
+            document.getElementById('webhookFailures').innerHTML = table(
+                ['ID', '{{i18n:PageReceived}}', '{{i18n:PageDocument}}', '{{i18n:PageAttempts}}', '{{i18n:PageLastError}}'],
+                s.recent_failures.webhook_events.map(e => [e.id, formatTime(e.created_at), escapeHtml(e.document_id), e.attempts,
+                    '<div class="message error" title="' + escapeHtml(e.last_error) + '">' + escapeHtml(e.last_error) + '</div>']),
+                '{{i18n:PageNoFailedWebhookEvents}}');
+        }
+
+        function renderQuota(quotas) {
+            document.getElementById('quota').innerHTML = table(
+                ['{{i18n:PageAccount}}', '{{i18n:PageRemaining}}', '{{i18n:PageUsed}}', '{{i18n:PageGlobalSign}}', '{{i18n:PagePSrESigning}}'],
+                quotas.map(q => q.error
+                    ? [escapeHtml(q.email || 'company'), '<span class="error" title="' + escapeHtml(q.error) + '">{{i18n:PageUnavailable}}</span>', '-', '-', '-']
+                    : [escapeHtml(q.email || 'company'), '<span class="' + (q.low ? 'error' : 'ok') + '">' + q.remaining_emeterai + '</span>',
+                        q.emeterai_usage, q.global_sign_document, q.psre_signing]),
+                '{{i18n:PageNoConnectedAccounts}}');
+        }
+
+        function renderTokens(tokens) {
+            const classes = { valid: 'ok', access_expired: 'warn', expired: 'error', no_token: 'muted' };
+            document.getElementById('tokens').innerHTML = table(
+                ['{{i18n:PageEmail}}', '{{i18n:PageStatus}}', '{{i18n:PageAccessTokenExpires}}', '{{i18n:PageRefreshTokenExpires}}'],
+                tokens.map(t => [escapeHtml(t.email), '<span class="' + classes[t.status] + '">' + t.status + '</span>',
+                    formatTime(t.access_token_expires_at), formatTime(t.refresh_token_expires_at)]),
+                '{{i18n:PageNoOAuthTokens}}');
+        }
+
+        async function load(url, id, render) {
+            try {
+                render(await api(url));
+            } catch (err) {
+                document.getElementById(id).innerHTML = '<p class="error">{{i18n:PageError}}: ' + escapeHtml(err.message) + '</p>';
+            }
+        }
+
+        async function refresh() {
+            await load('/admin/dashboard/summary', 'documentStats', renderSummary);
+            load('/admin/dashboard/quota', 'quota', renderQuota);
+            load('/admin/dashboard/tokens', 'tokens', renderTokens);
+        }
+
+        document.addEventListener('DOMContentLoaded', function () {
+            refresh();
+            setInterval(refresh, 60000);
+        });
+    </script>
+</body>
+</html>`
+	c.Set("Content-Type", "text/html")
+	return c.SendString(h.translator.LocalizePage(middleware.RequestLanguage(c), html))
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/timeutil"
+)
+
+// processStartedAt is used to report the uptime
+var processStartedAt = timeutil.Now()
+
+type DiagnosticsHandler struct{}
+
+func NewDiagnosticsHandler() *DiagnosticsHandler {
+	return &DiagnosticsHandler{}
+}
+
+// Runtime godoc
+// @Summary Runtime diagnostics
+// @Description Goroutine count, heap usage and GC statistics of the service process.
+//
+//	Use with diagnostics.pprof heap profiles to track memory growth.
+//
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Success 200 {object} entity.APIResponse
+// @Failure 401 {object} entity.APIResponse
+// @Failure 403 {object} entity.APIResponse
+// @Router /api/v1/admin/runtime [get]
+func (h *DiagnosticsHandler) Runtime(c *fiber.Ctx) error {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stats := entity.RuntimeStats{
+		GoVersion:  runtime.Version(),
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		Goroutines: runtime.NumGoroutine(),
+		StartedAt:  processStartedAt,
+		Uptime:     time.Since(processStartedAt).Round(time.Second).String(),
+		Memory: entity.RuntimeMemory{
+			HeapAlloc:    mem.HeapAlloc,
+			HeapInuse:    mem.HeapInuse,
+			HeapIdle:     mem.HeapIdle,
+			HeapReleased: mem.HeapReleased,
+			HeapSys:      mem.HeapSys,
+			HeapObjects:  mem.HeapObjects,
+			StackInuse:   mem.StackInuse,
+			Sys:          mem.Sys,
+			TotalAlloc:   mem.TotalAlloc,
+			Mallocs:      mem.Mallocs,
+			Frees:        mem.Frees,
+		},
+		GC: entity.RuntimeGC{
+			NumGC:        mem.NumGC,
+			NumForcedGC:  mem.NumForcedGC,
+			NextGC:       mem.NextGC,
+			PauseTotalMs: float64(mem.PauseTotalNs) / float64(time.Millisecond),
+			CPUFraction:  mem.GCCPUFraction,
+		},
+	}
+	if mem.NumGC > 0 {
+		lastGC := time.Unix(0, int64(mem.LastGC))
+		stats.GC.LastGC = &lastGC
+		// PauseNs is a circular buffer, the most recent pause is at (NumGC+255)%256
+		stats.GC.LastPauseMs = float64(mem.PauseNs[(mem.NumGC+255)%256]) / float64(time.Millisecond)
+	}
+
+	return c.JSON(entity.NewSuccessResponse(stats, "Runtime statistics retrieved successfully"))
+}
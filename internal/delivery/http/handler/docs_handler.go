@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"path"
+
+	"github.com/gofiber/fiber/v2"
+
+	"mekari-esign/docs"
+)
+
+type DocsHandler struct{}
+
+func NewDocsHandler() *DocsHandler {
+	return &DocsHandler{}
+}
+
+// SwaggerUI serves the Swagger UI page for browsing the API spec, with the embedded assets
+func (h *DocsHandler) SwaggerUI(c *fiber.Ctx) error {
+	html := `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Mekari eSign API Docs</title>
+    <link rel="icon" type="image/png" href="/docs/assets/favicon-32x32.png">
+    <link rel="stylesheet" href="/docs/assets/swagger-ui.css">
+</head>
+<body>
+    <div id="swagger-ui"></div>
+    <script src="/docs/assets/swagger-ui-bundle.js"></script>
+    <script>
+        window.onload = function () {
+            window.ui = SwaggerUIBundle({
+                url: '/docs/swagger.json',
+                dom_id: '#swagger-ui',
+                deepLinking: true
+            });
+        };
+    </script>
+</body>
+</html>`
+	c.Set("Content-Type", "text/html")
+	return c.SendString(html)
+}
+
+// SwaggerSpec serves the embedded swagger.json
+func (h *DocsHandler) SwaggerSpec(c *fiber.Ctx) error {
+	c.Set("Content-Type", "application/json")
+	return c.Send(docs.SwaggerJSON)
+}
+
+// SwaggerAsset serves a swagger-ui asset embedded in the binary
+func (h *DocsHandler) SwaggerAsset(c *fiber.Ctx) error {
+	name := c.Params("file")
+	data, err := docs.SwaggerUI.ReadFile(path.Join("swagger-ui", name))
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+	c.Type(path.Ext(name))
+	c.Set(fiber.HeaderCacheControl, "public, max-age=86400")
+	return c.Send(data)
+}
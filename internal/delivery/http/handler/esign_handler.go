@@ -1,24 +1,41 @@
 package handler
 
 import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 
+	"mekari-esign/internal/delivery/http/middleware"
+	"mekari-esign/internal/delivery/http/validation"
 	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/document"
 	"mekari-esign/internal/usecase"
 )
 
+// sseHeartbeat keeps idle event streams open through proxies
+const sseHeartbeat = 15 * time.Second
+
 type EsignHandler struct {
-	usecase usecase.EsignUsecase
-	logger  *zap.Logger
+	usecase  usecase.EsignUsecase
+	eventHub *usecase.DocumentEventHub
+	logger   *zap.Logger
 }
 
-func NewEsignHandler(usecase usecase.EsignUsecase, logger *zap.Logger) *EsignHandler {
+func NewEsignHandler(usecase usecase.EsignUsecase, eventHub *usecase.DocumentEventHub, logger *zap.Logger) *EsignHandler {
 	return &EsignHandler{
-		usecase: usecase,
-		logger:  logger,
+		usecase:  usecase,
+		eventHub: eventHub,
+		logger:   logger.Named("esign"),
 	}
 }
 
@@ -101,6 +118,8 @@ func (h *EsignHandler) GetDocuments(c *fiber.Ctx) error {
 // @Success 201 {object} entity.APIResponse
 // @Success 200 {object} entity.APIResponse "Need authorization - returns redirect URL"
 // @Failure 400 {object} entity.APIResponse
+// @Failure 409 {object} entity.APIResponse "Sign request for the invoice already in progress"
+// @Failure 422 {object} entity.APIResponse "Field validation errors"
 // @Failure 500 {object} entity.APIResponse
 // @Router /api/v1/esign/documents/request-sign [post]
 func (h *EsignHandler) GlobalRequestSign(c *fiber.Ctx) error {
@@ -115,8 +134,19 @@ func (h *EsignHandler) GlobalRequestSign(c *fiber.Ctx) error {
 		)
 	}
 
+	if fields := validation.Validate(&req); fields != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(entity.NewValidationErrorResponse(fields))
+	}
+
 	// Call usecase (which handles OAuth validation)
 	result, err := h.usecase.GlobalRequestSign(ctx, &req)
+	var signerErr *usecase.SignerError
+	if errors.As(err, &signerErr) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(entity.NewValidationErrorResponse(signerErr.Fields))
+	}
+	if errors.Is(err, usecase.ErrSignInProgress) {
+		return c.Status(fiber.StatusConflict).JSON(entity.NewErrorResponse("SIGN_IN_PROGRESS", err.Error()))
+	}
 	if err != nil {
 		h.logger.Error("Failed to request global sign", zap.Error(err))
 		return c.Status(fiber.StatusInternalServerError).JSON(
@@ -135,3 +165,467 @@ func (h *EsignHandler) GlobalRequestSign(c *fiber.Ctx) error {
 		entity.NewSuccessResponse(result, result.Message),
 	)
 }
+
+// ResubmitDocument godoc
+// @Summary Resubmit a document
+// @Description Send an expired, declined or stuck document for signing again as a new document, to the signers
+// @Description stored in its mapping. The original file is taken from the progress, ready, failed or finish folder.
+// @Description A document still out for signing is voided. The NAV log entry gets the new attempt number.
+// @Tags esign
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Param id path string true "Mekari document ID"
+// @Success 201 {object} entity.APIResponse
+// @Success 200 {object} entity.APIResponse "Need authorization - returns redirect URL"
+// @Failure 401 {object} entity.APIResponse
+// @Failure 403 {object} entity.APIResponse
+// @Failure 404 {object} entity.APIResponse
+// @Failure 409 {object} entity.APIResponse "Document completed or already resubmitted"
+// @Failure 422 {object} entity.APIResponse "Document has no stored signers"
+// @Failure 500 {object} entity.APIResponse
+// @Router /api/v1/esign/documents/{id}/resubmit [post]
+func (h *EsignHandler) ResubmitDocument(c *fiber.Ctx) error {
+	result, err := h.usecase.ResubmitDocument(c.UserContext(), c.Params("id"), requestActor(c))
+	switch {
+	case errors.Is(err, usecase.ErrDocumentCompleted), errors.Is(err, usecase.ErrAlreadyResubmitted):
+		return c.Status(fiber.StatusConflict).JSON(
+			entity.NewErrorResponse("CONFLICT", err.Error()),
+		)
+	case errors.Is(err, usecase.ErrNotResubmittable):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(
+			entity.NewErrorResponse("NOT_RESUBMITTABLE", err.Error()),
+		)
+	case err != nil:
+		return h.documentMappingError(c, err)
+	}
+
+	// If authorization is needed, return 200 with redirect URL
+	if result.NeedAuth {
+		return c.Status(fiber.StatusOK).JSON(
+			entity.NewSuccessResponse(result, result.Message),
+		)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(
+		entity.NewSuccessResponse(result, result.Message),
+	)
+}
+
+// VerifyDocument godoc
+// @Summary Verify a signed PDF
+// @Description Check the digital signatures of a PDF: that the signed bytes are unchanged, that each signature matches
+// @Description its certificate and that the certificate chains to a trusted root (system roots and document.trusted_roots).
+// @Description The report also tells whether an e-meterai is present. Upload the PDF as the multipart field "file", or
+// @Description send JSON (entity.VerifyDocumentRequest) with the base64 "content" or the "filename" of a file in the
+// @Description finish folder.
+// @Tags esign
+// @Accept mpfd,json
+// @Produce json
+// @Param file formData file false "PDF to verify"
+// @Success 200 {object} entity.APIResponse{data=entity.VerificationReport}
+// @Failure 400 {object} entity.APIResponse
+// @Failure 404 {object} entity.APIResponse "File not in the finish folder"
+// @Failure 422 {object} entity.APIResponse "Not a PDF"
+// @Failure 500 {object} entity.APIResponse
+// @Router /api/v1/esign/verify [post]
+func (h *EsignHandler) VerifyDocument(c *fiber.Ctx) error {
+	var (
+		filename string
+		content  []byte
+	)
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(
+				entity.NewErrorResponse("BAD_REQUEST", "A PDF file is required in the file field"),
+			)
+		}
+		file, err := fileHeader.Open()
+		if err == nil {
+			content, err = io.ReadAll(file)
+			file.Close()
+		}
+		if err != nil {
+			h.logger.Error("Failed to read uploaded file", zap.Error(err))
+			return c.Status(fiber.StatusBadRequest).JSON(
+				entity.NewErrorResponse("BAD_REQUEST", "Invalid file upload"),
+			)
+		}
+		filename = fileHeader.Filename
+	} else {
+		var req entity.VerifyDocumentRequest
+		if err := c.BodyParser(&req); err != nil {
+			h.logger.Error("Failed to parse request body", zap.Error(err))
+			return c.Status(fiber.StatusBadRequest).JSON(
+				entity.NewErrorResponse("BAD_REQUEST", "Invalid request body"),
+			)
+		}
+		if fields := validation.Validate(&req); fields != nil {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(entity.NewValidationErrorResponse(fields))
+		}
+		filename = req.Filename
+		if req.Content != "" {
+			content, _ = base64.StdEncoding.DecodeString(req.Content)
+		}
+	}
+
+	report, err := h.usecase.VerifyDocument(c.UserContext(), filename, content)
+	switch {
+	case errors.Is(err, document.ErrDocumentNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(
+			entity.NewErrorResponse("NOT_FOUND", err.Error()),
+		)
+	case errors.Is(err, document.ErrNotPDF):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(
+			entity.NewErrorResponse("NOT_PDF", err.Error()),
+		)
+	case err != nil:
+		h.logger.Error("Failed to verify document", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(
+			entity.NewErrorResponse("INTERNAL_ERROR", err.Error()),
+		)
+	}
+
+	return c.JSON(entity.NewSuccessResponse(report, "Document verified"))
+}
+
+// GetDocumentTimeline godoc
+// @Summary Get document timeline
+// @Description Get the ordered lifecycle events of a document (submitted, signed, stamped, saved, errors)
+// @Tags esign
+// @Accept json
+// @Produce json
+// @Param id path string true "Mekari document ID"
+// @Success 200 {object} entity.APIResponse
+// @Failure 400 {object} entity.APIResponse
+// @Failure 404 {object} entity.APIResponse
+// @Failure 500 {object} entity.APIResponse
+// @Router /api/v1/esign/documents/{id}/timeline [get]
+func (h *EsignHandler) GetDocumentTimeline(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	documentID := c.Params("id")
+	if documentID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(
+			entity.NewErrorResponse("BAD_REQUEST", "Document ID is required"),
+		)
+	}
+
+	timeline, err := h.usecase.GetDocumentTimeline(ctx, documentID)
+	if err != nil {
+		h.logger.Error("Failed to get document timeline", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(
+			entity.NewErrorResponse("INTERNAL_ERROR", err.Error()),
+		)
+	}
+
+	if len(timeline.Events) == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(
+			entity.NewErrorResponse("NOT_FOUND", "No events found for this document"),
+		)
+	}
+
+	return c.JSON(entity.NewSuccessResponse(timeline, "Document timeline retrieved successfully"))
+}
+
+// GetInvoiceStatus godoc
+// @Summary Get invoice processing status
+// @Description Get the state of the document last submitted for an invoice: submitted, signing, stamping,
+// @Description finished or failed, with its document ID and the path of its file. Read from the timeline
+// @Description without calling Mekari or NAV, for NAV codeunits that poll instead of receiving callbacks.
+// @Tags esign
+// @Produce json
+// @Param invoiceNo path string true "Invoice number, slashes encoded as %2F"
+// @Success 200 {object} entity.APIResponse{data=entity.InvoiceStatus}
+// @Failure 400 {object} entity.APIResponse
+// @Failure 404 {object} entity.APIResponse
+// @Failure 500 {object} entity.APIResponse
+// @Router /api/v1/esign/status/by-invoice/{invoiceNo} [get]
+func (h *EsignHandler) GetInvoiceStatus(c *fiber.Ctx) error {
+	// Invoice numbers with slashes (e.g. INV/2024/001) are sent encoded as %2F
+	invoiceNo, err := url.PathUnescape(c.Params("invoiceNo"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(entity.NewErrorResponse("BAD_REQUEST", "Invalid invoice number"))
+	}
+
+	status, err := h.usecase.GetInvoiceStatus(c.UserContext(), invoiceNo)
+	switch {
+	case errors.Is(err, usecase.ErrInvoiceNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(entity.NewErrorResponse("NOT_FOUND", err.Error()))
+	case err != nil:
+		h.logger.Error("Failed to get invoice status", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(entity.NewErrorResponse("INTERNAL_ERROR", err.Error()))
+	}
+
+	return c.JSON(entity.NewSuccessResponse(status, "Invoice status retrieved successfully"))
+}
+
+// GetSigningLinks godoc
+// @Summary Get signing links
+// @Description Get the signing link of each signer of a document, to send it again to a signer who lost
+// @Description the email. Links are kept from webhooks; without stored links, or with refresh, they are
+// @Description fetched from Mekari. Signers who have signed may have no link.
+// @Tags esign
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Param id path string true "Mekari document ID"
+// @Param refresh query bool false "Fetch the links from Mekari even when some are stored"
+// @Success 200 {object} entity.APIResponse{data=entity.DocumentSigningLinks}
+// @Failure 401 {object} entity.APIResponse
+// @Failure 403 {object} entity.APIResponse
+// @Failure 404 {object} entity.APIResponse
+// @Failure 500 {object} entity.APIResponse
+// @Router /api/v1/esign/documents/{id}/signing-links [get]
+func (h *EsignHandler) GetSigningLinks(c *fiber.Ctx) error {
+	links, err := h.usecase.GetSigningLinks(c.UserContext(), c.Params("id"), c.QueryBool("refresh"))
+	if err != nil {
+		return h.documentMappingError(c, err)
+	}
+
+	return c.JSON(entity.NewSuccessResponse(links, "Signing links retrieved successfully"))
+}
+
+// StreamDocumentEvents godoc
+// @Summary Stream document events
+// @Description Server-Sent Events stream of a document's lifecycle events. Recorded events are sent first,
+// @Description then new ones as webhooks are processed. Each message has the event ID as `id` and a
+// @Description document event as JSON `data`; reconnects with Last-Event-ID only receive newer events.
+// @Description Browsers may pass a login token as `access_token` because EventSource cannot set headers.
+// @Tags esign
+// @Produce text/event-stream
+// @Param id path string true "Mekari document ID"
+// @Param Last-Event-ID header string false "ID of the last event received"
+// @Success 200 {string} string "text/event-stream"
+// @Failure 400 {object} entity.APIResponse
+// @Failure 500 {object} entity.APIResponse
+// @Router /api/v1/esign/documents/{id}/events [get]
+func (h *EsignHandler) StreamDocumentEvents(c *fiber.Ctx) error {
+	documentID := c.Params("id")
+	if documentID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(
+			entity.NewErrorResponse("BAD_REQUEST", "Document ID is required"),
+		)
+	}
+
+	lastID, _ := strconv.ParseInt(c.Get("Last-Event-ID"), 10, 64)
+
+	// Subscribe before loading the timeline so no event falls in between
+	events, unsubscribe := h.eventHub.Subscribe(documentID)
+
+	timeline, err := h.usecase.GetDocumentTimeline(c.UserContext(), documentID)
+	if err != nil {
+		unsubscribe()
+		h.logger.Error("Failed to get document timeline", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(
+			entity.NewErrorResponse("INTERNAL_ERROR", err.Error()),
+		)
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	h.logger.Info("Document event stream opened",
+		zap.String("document_id", documentID),
+		zap.Int("subscribers", h.eventHub.Subscribers()),
+	)
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+
+		send := func(event *entity.DocumentEvent) error {
+			if event.ID <= lastID {
+				return nil // Already sent, or received before a reconnect
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			lastID = event.ID
+			_, err = fmt.Fprintf(w, "id: %d\ndata: %s\n\n", event.ID, data)
+			return err
+		}
+
+		fmt.Fprintf(w, "retry: %d\n\n", (3 * time.Second).Milliseconds())
+		for i := range timeline.Events {
+			if err := send(&timeline.Events[i]); err != nil {
+				return
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		ticker := time.NewTicker(sseHeartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return // Server shutting down
+				}
+				if err := send(&event); err != nil {
+					return
+				}
+			case <-ticker.C:
+				if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+					return
+				}
+			}
+
+			// Flush fails once the client has disconnected
+			if err := w.Flush(); err != nil {
+				h.logger.Debug("Document event stream closed", zap.String("document_id", documentID))
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+// ListDocumentMappings godoc
+// @Summary List document mappings
+// @Description List the stored document mappings. Pass next_cursor back as cursor for the next page.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Param cursor query string false "Cursor from next_cursor of the previous page"
+// @Param limit query int false "Maximum mappings per page (default and max 500)"
+// @Param invoice query string false "Filter by invoice number (case-insensitive substring)"
+// @Success 200 {object} entity.APIResponse
+// @Failure 400 {object} entity.APIResponse
+// @Failure 500 {object} entity.APIResponse
+// @Router /admin/document-mappings [get]
+func (h *EsignHandler) ListDocumentMappings(c *fiber.Ctx) error {
+	var cursor uint64
+	if raw := c.Query("cursor"); raw != "" {
+		parsed, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(
+				entity.NewErrorResponse("BAD_REQUEST", "Invalid cursor"),
+			)
+		}
+		cursor = parsed
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	page, err := h.usecase.ListDocumentMappings(c.UserContext(), cursor, limit, c.Query("invoice"))
+	if err != nil {
+		h.logger.Error("Failed to list document mappings", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(
+			entity.NewErrorResponse("INTERNAL_ERROR", err.Error()),
+		)
+	}
+
+	return c.JSON(entity.NewSuccessResponse(page, "Document mappings retrieved successfully"))
+}
+
+// GetDocumentMapping godoc
+// @Summary Get document mapping
+// @Description Get the stored mapping (email, invoice number, stamp position, entry number) of a document
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Param id path string true "Document ID"
+// @Success 200 {object} entity.APIResponse
+// @Failure 404 {object} entity.APIResponse
+// @Failure 500 {object} entity.APIResponse
+// @Router /admin/document-mappings/{id} [get]
+func (h *EsignHandler) GetDocumentMapping(c *fiber.Ctx) error {
+	mapping, err := h.usecase.GetDocumentMapping(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return h.documentMappingError(c, err)
+	}
+
+	return c.JSON(entity.NewSuccessResponse(mapping, "Document mapping retrieved successfully"))
+}
+
+// UpdateDocumentMapping godoc
+// @Summary Update document mapping
+// @Description Partially update a document mapping, e.g. to fix a wrong invoice number or stamp position of an in-flight document.
+// @Description Omitted fields are kept. The entry_no key is moved when entry_no changes.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Param id path string true "Document ID"
+// @Param request body entity.UpdateDocumentMappingRequest true "Fields to update"
+// @Success 200 {object} entity.APIResponse
+// @Failure 400 {object} entity.APIResponse
+// @Failure 404 {object} entity.APIResponse
+// @Failure 422 {object} entity.APIResponse
+// @Failure 500 {object} entity.APIResponse
+// @Router /admin/document-mappings/{id} [patch]
+func (h *EsignHandler) UpdateDocumentMapping(c *fiber.Ctx) error {
+	var req entity.UpdateDocumentMappingRequest
+	if err := c.BodyParser(&req); err != nil {
+		h.logger.Error("Failed to parse request body", zap.Error(err))
+		return c.Status(fiber.StatusBadRequest).JSON(
+			entity.NewErrorResponse("BAD_REQUEST", "Invalid request body"),
+		)
+	}
+
+	if fields := validation.Validate(&req); fields != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(entity.NewValidationErrorResponse(fields))
+	}
+
+	mapping, err := h.usecase.UpdateDocumentMapping(c.UserContext(), c.Params("id"), &req, requestActor(c))
+	if err != nil {
+		return h.documentMappingError(c, err)
+	}
+
+	return c.JSON(entity.NewSuccessResponse(mapping, "Document mapping updated"))
+}
+
+// DeleteDocumentMapping godoc
+// @Summary Delete document mapping
+// @Description Delete a document mapping and its entry_no key. Webhooks for the document can no longer be matched afterwards.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Param id path string true "Document ID"
+// @Success 200 {object} entity.APIResponse
+// @Failure 404 {object} entity.APIResponse
+// @Failure 500 {object} entity.APIResponse
+// @Router /admin/document-mappings/{id} [delete]
+func (h *EsignHandler) DeleteDocumentMapping(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.usecase.DeleteDocumentMapping(c.UserContext(), id, requestActor(c)); err != nil {
+		return h.documentMappingError(c, err)
+	}
+
+	return c.JSON(entity.NewSuccessResponse(map[string]string{"document_id": id}, "Document mapping deleted"))
+}
+
+func (h *EsignHandler) documentMappingError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, usecase.ErrDocumentMappingNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(
+			entity.NewErrorResponse("NOT_FOUND", err.Error()),
+		)
+	}
+
+	h.logger.Error("Document mapping operation failed", zap.String("document_id", c.Params("id")), zap.Error(err))
+	return c.Status(fiber.StatusInternalServerError).JSON(
+		entity.NewErrorResponse("INTERNAL_ERROR", err.Error()),
+	)
+}
+
+// requestActor names the user, API key or admin token behind a change for audit records
+func requestActor(c *fiber.Ctx) string {
+	if apiKey, ok := c.Locals(middleware.LocalsAPIKey).(*entity.APIKey); ok && apiKey != nil {
+		return "key:" + apiKey.Name
+	}
+	if user := middleware.CurrentUser(c); user != nil {
+		return user.Username
+	}
+	return "admin-token"
+}
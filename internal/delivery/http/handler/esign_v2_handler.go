@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/delivery/http/validation"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/document"
+	"mekari-esign/internal/infrastructure/httpclient"
+	"mekari-esign/internal/usecase"
+)
+
+// GlobalRequestSignV2 godoc
+// @Summary Request global document signing (v2)
+// @Description Same flow as v1 with multiple e-meterai positions (`stamps`), an optional inline base64
+// @Description document instead of the ready folder, a flat response and typed error codes:
+// @Description AUTHORIZATION_REQUIRED (403, redirect_url in data), DOCUMENT_NOT_FOUND (404),
+// @Description DOCUMENT_NOT_SIGNED (409), QUOTA_EXHAUSTED (402), MEKARI_ERROR (502) and INTERNAL_ERROR (500).
+// @Tags esign-v2
+// @Accept json
+// @Produce json
+// @Param request body entity.GlobalSignRequestV2 true "Global sign request"
+// @Success 201 {object} entity.APIResponse{data=entity.GlobalSignResponseV2}
+// @Failure 400 {object} entity.APIResponse
+// @Failure 402 {object} entity.APIResponse
+// @Failure 403 {object} entity.APIResponse{data=entity.AuthorizationRequiredData}
+// @Failure 404 {object} entity.APIResponse
+// @Failure 409 {object} entity.APIResponse
+// @Failure 422 {object} entity.APIResponse "Field validation errors"
+// @Failure 500 {object} entity.APIResponse
+// @Failure 502 {object} entity.APIResponse
+// @Router /api/v2/esign/documents/request-sign [post]
+func (h *EsignHandler) GlobalRequestSignV2(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	var req entity.GlobalSignRequestV2
+	if err := c.BodyParser(&req); err != nil {
+		h.logger.Error("Failed to parse request body", zap.Error(err))
+		return c.Status(fiber.StatusBadRequest).JSON(
+			entity.NewErrorResponse("BAD_REQUEST", "Invalid request body"),
+		)
+	}
+
+	if fields := validation.Validate(&req); fields != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(entity.NewValidationErrorResponse(fields))
+	}
+
+	// Same usecase as v1, only the request and response shapes differ
+	signReq := req.ToGlobalSignRequest()
+	result, err := h.usecase.GlobalRequestSign(ctx, signReq)
+	var signerErr *usecase.SignerError
+	if errors.As(err, &signerErr) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(entity.NewValidationErrorResponse(signerErr.Fields))
+	}
+	if err != nil {
+		h.logger.Error("Failed to request global sign", zap.Error(err))
+		status, code := signErrorCode(err)
+		return c.Status(status).JSON(entity.NewErrorResponse(code, err.Error()))
+	}
+
+	if result.NeedAuth {
+		return c.Status(fiber.StatusForbidden).JSON(entity.APIResponse{
+			Success: false,
+			Message: result.Message,
+			Data:    entity.AuthorizationRequiredData{RedirectURL: result.RedirectURL},
+			Error:   &entity.APIError{Code: "AUTHORIZATION_REQUIRED", Message: result.Message},
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(
+		entity.NewSuccessResponse(newGlobalSignResponseV2(signReq, result), result.Message),
+	)
+}
+
+// signErrorCode maps sign errors to the v2 HTTP status and error code
+func signErrorCode(err error) (int, string) {
+	var apiErr *httpclient.APIError
+	switch {
+	case errors.Is(err, document.ErrDocumentNotFound):
+		return fiber.StatusNotFound, "DOCUMENT_NOT_FOUND"
+	case errors.Is(err, usecase.ErrNotSigned):
+		return fiber.StatusConflict, "DOCUMENT_NOT_SIGNED"
+	case errors.Is(err, usecase.ErrSignInProgress):
+		return fiber.StatusConflict, "SIGN_IN_PROGRESS"
+	case errors.Is(err, usecase.ErrAttachmentNotMergeable):
+		return fiber.StatusUnprocessableEntity, "ATTACHMENT_NOT_MERGEABLE"
+	case errors.Is(err, usecase.ErrQuotaExhausted):
+		return fiber.StatusPaymentRequired, "QUOTA_EXHAUSTED"
+	case errors.Is(err, usecase.ErrEmailRequired):
+		return fiber.StatusBadRequest, "BAD_REQUEST"
+	case errors.Is(err, httpclient.ErrUnauthorized):
+		return fiber.StatusForbidden, "AUTHORIZATION_REQUIRED"
+	case errors.As(err, &apiErr):
+		return fiber.StatusBadGateway, "MEKARI_ERROR"
+	default:
+		return fiber.StatusInternalServerError, "INTERNAL_ERROR"
+	}
+}
+
+// newGlobalSignResponseV2 flattens the sign result
+func newGlobalSignResponseV2(req *entity.GlobalSignRequest, result *entity.GlobalSignResult) *entity.GlobalSignResponseV2 {
+	resp := &entity.GlobalSignResponseV2{
+		Status:        entity.SignStatusSubmitted,
+		InvoiceNumber: req.InvoiceNumber,
+		StampCount:    len(req.AllStampPositions()),
+	}
+	if req.IsStampingOnly() {
+		resp.Status = entity.SignStatusStampingRequested
+	}
+
+	if result.Data != nil {
+		attrs := result.Data.Attributes
+		resp.DocumentID = result.Data.ID
+		resp.Filename = attrs.Filename
+		resp.MekariStatus = attrs.Status
+		resp.Signers = attrs.Signers
+		resp.ExpiryDate = attrs.ExpiryDate
+	}
+
+	return resp
+}
@@ -0,0 +1,323 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/graphql-go/graphql"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/repository"
+	"mekari-esign/internal/usecase"
+)
+
+// graphQLMaxLogs caps the number of API logs a single field may return
+const graphQLMaxLogs = 200
+
+// GraphQLRequest is the standard GraphQL-over-HTTP request body
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// GraphQLHandler serves a read-only GraphQL schema over documents and API logs
+type GraphQLHandler struct {
+	esignUsecase usecase.EsignUsecase
+	eventRepo    repository.WebhookEventRepository
+	logRepo      repository.APILogRepository
+	logger       *zap.Logger
+	schema       graphql.Schema
+}
+
+// graphQLDocument is the resolved source of the Document type
+type graphQLDocument struct {
+	ID string
+}
+
+func NewGraphQLHandler(
+	esignUsecase usecase.EsignUsecase,
+	eventRepo repository.WebhookEventRepository,
+	logRepo repository.APILogRepository,
+	logger *zap.Logger,
+) (*GraphQLHandler, error) {
+	h := &GraphQLHandler{
+		esignUsecase: esignUsecase,
+		eventRepo:    eventRepo,
+		logRepo:      logRepo,
+		logger:       logger,
+	}
+
+	schema, err := h.buildSchema()
+	if err != nil {
+		return nil, err
+	}
+	h.schema = schema
+
+	return h, nil
+}
+
+// Query godoc
+// @Summary GraphQL query
+// @Description Read-only GraphQL endpoint. Fetch a document with its mapping, timeline, NAV status and API logs in one query.
+// @Description Example: { document(id: "doc-id") { mapping { invoiceNumber } timeline { eventType createdAt } navStatus { signingStatus } logs(limit: 10) { endpoint statusCode } } }
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Param request body GraphQLRequest true "GraphQL request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} entity.APIResponse
+// @Router /api/v1/graphql [post]
+func (h *GraphQLHandler) Query(c *fiber.Ctx) error {
+	var req GraphQLRequest
+	if c.Method() == fiber.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+	} else if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(
+			entity.NewErrorResponse("BAD_REQUEST", "Invalid request body"),
+		)
+	}
+
+	if req.Query == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(
+			entity.NewErrorResponse("BAD_REQUEST", "Query is required"),
+		)
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         h.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        c.UserContext(),
+	})
+
+	if result.HasErrors() {
+		h.logger.Warn("GraphQL query returned errors", zap.Any("errors", result.Errors))
+	}
+
+	return c.JSON(result)
+}
+
+func (h *GraphQLHandler) buildSchema() (graphql.Schema, error) {
+	mappingType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "DocumentMapping",
+		Fields: graphql.Fields{
+			"email":         stringField(func(m *entity.DocumentMapping) string { return m.Email }),
+			"invoiceNumber": stringField(func(m *entity.DocumentMapping) string { return m.InvoiceNumber }),
+			"filename":      stringField(func(m *entity.DocumentMapping) string { return m.Filename }),
+			"entryNo":       intField(func(m *entity.DocumentMapping) int { return m.EntryNo }),
+			"signing":       boolField(func(m *entity.DocumentMapping) bool { return m.Signing }),
+			"stamping":      boolField(func(m *entity.DocumentMapping) bool { return m.Stamping }),
+			"enforceOrder":  boolField(func(m *entity.DocumentMapping) bool { return m.EnforceOrder }),
+		},
+	})
+
+	eventType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "DocumentEvent",
+		Fields: graphql.Fields{
+			"id":          intField(func(e *entity.DocumentEvent) int { return int(e.ID) }),
+			"eventType":   stringField(func(e *entity.DocumentEvent) string { return e.EventType }),
+			"description": stringField(func(e *entity.DocumentEvent) string { return e.Description }),
+			"actor":       stringField(func(e *entity.DocumentEvent) string { return e.Actor }),
+			"invoiceNo":   stringField(func(e *entity.DocumentEvent) string { return e.InvoiceNo }),
+			"entryNo":     intField(func(e *entity.DocumentEvent) int { return e.EntryNo }),
+			"createdAt":   timeField(func(e *entity.DocumentEvent) *time.Time { return &e.CreatedAt }),
+		},
+	})
+
+	navStatusType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "NavStatus",
+		Description: "Status last reported to NAV, taken from the latest webhook event",
+		Fields: graphql.Fields{
+			"signingStatus":    stringField(func(e *entity.WebhookEvent) string { return entity.MapSigningStatus(e.SigningStatus) }),
+			"stampingStatus":   stringField(func(e *entity.WebhookEvent) string { return entity.MapStampingStatus(e.StampingStatus) }),
+			"processingStatus": stringField(func(e *entity.WebhookEvent) string { return e.Status }),
+			"attempts":         intField(func(e *entity.WebhookEvent) int { return e.Attempts }),
+			"lastError":        stringField(func(e *entity.WebhookEvent) string { return e.LastError }),
+			"receivedAt":       timeField(func(e *entity.WebhookEvent) *time.Time { return &e.CreatedAt }),
+			"processedAt":      timeField(func(e *entity.WebhookEvent) *time.Time { return e.ProcessedAt }),
+		},
+	})
+
+	logType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ApiLog",
+		Fields: graphql.Fields{
+			"id":           intField(func(l *entity.APILog) int { return int(l.ID) }),
+			"endpoint":     stringField(func(l *entity.APILog) string { return l.Endpoint }),
+			"invoiceNo":    stringField(func(l *entity.APILog) string { return l.InvoiceNo }),
+			"entryNo":      intField(func(l *entity.APILog) int { return l.EntryNo }),
+			"method":       stringField(func(l *entity.APILog) string { return l.Method }),
+			"statusCode":   intField(func(l *entity.APILog) int { return l.StatusCode }),
+			"durationMs":   intField(func(l *entity.APILog) int { return int(l.Duration) }),
+			"email":        stringField(func(l *entity.APILog) string { return l.Email }),
+			"caller":       stringField(func(l *entity.APILog) string { return l.Caller }),
+			"requestId":    stringField(func(l *entity.APILog) string { return l.RequestID }),
+			"requestBody":  stringField(func(l *entity.APILog) string { return l.RequestBody }),
+			"responseBody": stringField(func(l *entity.APILog) string { return l.ResponseBody }),
+			"createdAt":    timeField(func(l *entity.APILog) *time.Time { return &l.CreatedAt }),
+		},
+	})
+
+	logArgs := graphql.FieldConfigArgument{
+		"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 50},
+	}
+
+	documentType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Document",
+		Fields: graphql.Fields{
+			"id": stringField(func(d *graphQLDocument) string { return d.ID }),
+			"mapping": &graphql.Field{
+				Type: mappingType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return h.documentMapping(p.Context, p.Source.(*graphQLDocument).ID)
+				},
+			},
+			"timeline": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(eventType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					timeline, err := h.esignUsecase.GetDocumentTimeline(p.Context, p.Source.(*graphQLDocument).ID)
+					if err != nil {
+						return nil, err
+					}
+					events := make([]*entity.DocumentEvent, len(timeline.Events))
+					for i := range timeline.Events {
+						events[i] = &timeline.Events[i]
+					}
+					return events, nil
+				},
+			},
+			"navStatus": &graphql.Field{
+				Type: navStatusType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					event, err := h.eventRepo.FindLatestByDocumentID(p.Context, p.Source.(*graphQLDocument).ID)
+					if err != nil || event == nil {
+						return nil, err
+					}
+					return event, nil
+				},
+			},
+			"logs": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(logType))),
+				Description: "API logs related to the document's invoice number, newest first",
+				Args:        logArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					mapping, err := h.documentMapping(p.Context, p.Source.(*graphQLDocument).ID)
+					if err != nil || mapping == nil || mapping.InvoiceNumber == "" {
+						return []*entity.APILog{}, err
+					}
+					return h.findLogs(p.Context, &entity.APILogFilter{Invoice: mapping.InvoiceNumber}, p.Args["limit"].(int))
+				},
+			},
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"document": &graphql.Field{
+				Type: documentType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return &graphQLDocument{ID: p.Args["id"].(string)}, nil
+				},
+			},
+			"logs": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(logType))),
+				Description: "API logs, newest first",
+				Args: graphql.FieldConfigArgument{
+					"invoice": &graphql.ArgumentConfig{Type: graphql.String},
+					"method":  &graphql.ArgumentConfig{Type: graphql.String},
+					"email":   &graphql.ArgumentConfig{Type: graphql.String},
+					"limit":   &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 50},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					filter := &entity.APILogFilter{}
+					filter.Invoice, _ = p.Args["invoice"].(string)
+					filter.Method, _ = p.Args["method"].(string)
+					filter.Email, _ = p.Args["email"].(string)
+					return h.findLogs(p.Context, filter, p.Args["limit"].(int))
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
+
+// documentMapping returns the stored mapping of a document, or nil when it has expired
+func (h *GraphQLHandler) documentMapping(ctx context.Context, documentID string) (*entity.DocumentMapping, error) {
+	mapping, err := h.esignUsecase.GetDocumentMapping(ctx, documentID)
+	if err != nil {
+		return nil, nil // Mapping is gone once the document is finished
+	}
+	return mapping, nil
+}
+
+// findLogs returns the newest logs matching the filter
+func (h *GraphQLHandler) findLogs(ctx context.Context, filter *entity.APILogFilter, limit int) ([]*entity.APILog, error) {
+	if limit <= 0 || limit > graphQLMaxLogs {
+		limit = graphQLMaxLogs
+	}
+	filter.Desc = true
+	filter.Limit = limit
+
+	page, err := h.logRepo.FindAll(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	logs := make([]*entity.APILog, len(page.Logs))
+	for i := range page.Logs {
+		logs[i] = &page.Logs[i]
+	}
+	return logs, nil
+}
+
+// stringField builds a String field resolved from a typed source
+func stringField[T any](get func(*T) string) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.String,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source.(*T)), nil
+		},
+	}
+}
+
+// intField builds an Int field resolved from a typed source
+func intField[T any](get func(*T) int) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.Int,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source.(*T)), nil
+		},
+	}
+}
+
+// boolField builds a Boolean field resolved from a typed source
+func boolField[T any](get func(*T) bool) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.Boolean,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source.(*T)), nil
+		},
+	}
+}
+
+// timeField builds a DateTime field resolved from a typed source (null when nil)
+func timeField[T any](get func(*T) *time.Time) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.DateTime,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if t := get(p.Source.(*T)); t != nil {
+				return *t, nil
+			}
+			return nil, nil
+		},
+	}
+}
@@ -1,37 +1,202 @@
 package handler
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
 
+	"mekari-esign/internal/config"
 	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/database"
+	"mekari-esign/internal/infrastructure/nav"
+	"mekari-esign/internal/infrastructure/redis"
+	"mekari-esign/internal/timeutil"
 )
 
-type HealthHandler struct{}
+// healthCheckTimeout bounds each dependency check so /health always answers quickly
+const healthCheckTimeout = 3 * time.Second
 
-func NewHealthHandler() *HealthHandler {
-	return &HealthHandler{}
+// Health and dependency statuses
+const (
+	HealthStatusHealthy   = "healthy"
+	HealthStatusDegraded  = "degraded" // A non-critical dependency is down
+	HealthStatusUnhealthy = "unhealthy"
+
+	DependencyUp       = "up"
+	DependencyDown     = "down"
+	DependencyDisabled = "disabled"
+)
+
+type HealthHandler struct {
+	config     *config.Config
+	db         *database.Database
+	redis      *redis.RedisClient
+	navClient  *nav.Client
+	httpClient *http.Client
+	logger     *zap.Logger
+}
+
+func NewHealthHandler(
+	cfg *config.Config,
+	db *database.Database,
+	redisClient *redis.RedisClient,
+	navClient *nav.Client,
+	logger *zap.Logger,
+) *HealthHandler {
+	return &HealthHandler{
+		config:     cfg,
+		db:         db,
+		redis:      redisClient,
+		navClient:  navClient,
+		httpClient: &http.Client{Timeout: healthCheckTimeout},
+		logger:     logger,
+	}
 }
 
 type HealthResponse struct {
-	Status    string    `json:"status"`
-	Timestamp time.Time `json:"timestamp"`
-	Version   string    `json:"version"`
+	Status    string                      `json:"status"`
+	Timestamp time.Time                   `json:"timestamp"`
+	Version   string                      `json:"version"`
+	Checks    map[string]*DependencyCheck `json:"checks,omitempty"`
+}
+
+// DependencyCheck is the result of checking a single dependency
+type DependencyCheck struct {
+	Status    string `json:"status"`
+	Critical  bool   `json:"critical"` // A critical dependency being down makes the service unhealthy (503)
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// dependency is a named check run by the health endpoint
+type dependency struct {
+	name     string
+	critical bool
+	enabled  bool
+	check    func(ctx context.Context) error
 }
 
 // Health godoc
 // @Summary Health check
-// @Description Check if the service is healthy
+// @Description Check Postgres, Redis, Mekari and NAV and report per-dependency status and latency.
+// @Description Returns 503 when a critical dependency (Postgres, Redis) is down; Mekari or NAV being down only degrades the status.
 // @Tags health
 // @Accept json
 // @Produce json
 // @Success 200 {object} entity.APIResponse
+// @Failure 503 {object} entity.APIResponse
 // @Router /health [get]
 func (h *HealthHandler) Health(c *fiber.Ctx) error {
+	checks := h.runChecks(c.UserContext())
+
+	status := HealthStatusHealthy
+	for _, check := range checks {
+		if check.Status != DependencyDown {
+			continue
+		}
+		if check.Critical {
+			status = HealthStatusUnhealthy
+			break
+		}
+		status = HealthStatusDegraded
+	}
+
+	health := HealthResponse{
+		Status:    status,
+		Timestamp: timeutil.Now(),
+		Version:   "1.0.0",
+		Checks:    checks,
+	}
+
+	if status == HealthStatusUnhealthy {
+		h.logger.Warn("Health check failed", zap.Any("checks", checks))
+		resp := entity.NewErrorResponse("SERVICE_UNAVAILABLE", "A critical dependency is down")
+		resp.Data = health
+		return c.Status(fiber.StatusServiceUnavailable).JSON(resp)
+	}
+
+	return c.JSON(entity.NewSuccessResponse(health, "Service is "+status))
+}
+
+// Live godoc
+// @Summary Liveness check
+// @Description Static response that only confirms the process is serving requests (no dependency checks)
+// @Tags health
+// @Produce json
+// @Success 200 {object} entity.APIResponse
+// @Router /health/live [get]
+func (h *HealthHandler) Live(c *fiber.Ctx) error {
 	return c.JSON(entity.NewSuccessResponse(HealthResponse{
-		Status:    "healthy",
-		Timestamp: time.Now(),
+		Status:    HealthStatusHealthy,
+		Timestamp: timeutil.Now(),
 		Version:   "1.0.0",
 	}, "Service is healthy"))
 }
+
+// runChecks checks all dependencies concurrently
+func (h *HealthHandler) runChecks(ctx context.Context) map[string]*DependencyCheck {
+	dependencies := []dependency{
+		{name: h.db.Dialect.Name(), critical: true, enabled: true, check: h.db.DB.PingContext},
+		{name: "redis", critical: true, enabled: true, check: func(ctx context.Context) error {
+			return h.redis.Client.Ping(ctx).Err()
+		}},
+		{name: "mekari", enabled: h.config.Mekari.BaseURL != "", check: h.pingMekari},
+		{name: "nav", enabled: h.config.NAVSettings().Enabled, check: h.navClient.Ping},
+	}
+
+	checks := make(map[string]*DependencyCheck, len(dependencies))
+	var wg sync.WaitGroup
+
+	for _, dep := range dependencies {
+		result := &DependencyCheck{Status: DependencyDisabled, Critical: dep.critical}
+		checks[dep.name] = result
+		if !dep.enabled {
+			continue
+		}
+
+		wg.Add(1)
+		go func(dep dependency) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			started := time.Now()
+			err := dep.check(checkCtx)
+
+			result.LatencyMs = time.Since(started).Milliseconds()
+			result.Status = DependencyUp
+			if err != nil {
+				result.Status = DependencyDown
+				result.Error = err.Error()
+			}
+		}(dep)
+	}
+
+	wg.Wait()
+	return checks
+}
+
+// pingMekari sends a HEAD request to the Mekari base URL; any HTTP answer below 500 counts as up
+func (h *HealthHandler) pingMekari(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, h.config.Mekari.BaseURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("mekari returned %s", resp.Status)
+	}
+	return nil
+}
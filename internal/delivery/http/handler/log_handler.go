@@ -1,27 +1,45 @@
 package handler
 
 import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/delivery/http/middleware"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/i18n"
 	"mekari-esign/internal/infrastructure/repository"
+	"mekari-esign/internal/timeutil"
+	"mekari-esign/internal/usecase"
 )
 
 type LogHandler struct {
-	logRepo repository.APILogRepository
+	config     *config.Config
+	logRepo    repository.APILogRepository
+	traffic    usecase.TrafficUsecase
+	translator i18n.Translator
 }
 
-func NewLogHandler(logRepo repository.APILogRepository) *LogHandler {
-	return &LogHandler{logRepo: logRepo}
+func NewLogHandler(cfg *config.Config, logRepo repository.APILogRepository, traffic usecase.TrafficUsecase, translator i18n.Translator) *LogHandler {
+	return &LogHandler{config: cfg, logRepo: logRepo, traffic: traffic, translator: translator}
 }
 
-// LogViewer serves the HTML page for viewing logs
+// LogViewer serves the HTML page for viewing logs in the language of the request
 func (h *LogHandler) LogViewer(c *fiber.Ctx) error {
 	html := `<!DOCTYPE html>
-<html lang="en">
+<html lang="{{LANG}}">
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>API Log Viewer</title>
+    <title>{{i18n:PageLogViewerTitle}}</title>
     <style>
         * { box-sizing: border-box; margin: 0; padding: 0; }
         body { font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif; background: #1a1a2e; color: #eee; padding: 20px; }
@@ -29,6 +47,10 @@ func (h *LogHandler) LogViewer(c *fiber.Ctx) error {
         .search-box { margin-bottom: 20px; display: flex; gap: 10px; flex-wrap: wrap; }
         input[type="text"] { padding: 12px 16px; font-size: 16px; border: 2px solid #00d4ff; border-radius: 8px; background: #16213e; color: #fff; width: 300px; }
         input[type="text"]:focus { outline: none; border-color: #00ff88; }
+        input[type="date"] { padding: 10px 12px; font-size: 16px; border: 2px solid #00d4ff; border-radius: 8px; background: #16213e; color: #fff; margin-left: 6px; }
+        label { display: flex; align-items: center; color: #888; }
+        select { padding: 12px 16px; font-size: 16px; border: 2px solid #00d4ff; border-radius: 8px; background: #16213e; color: #fff; }
+        .load-more { margin-top: 15px; text-align: center; }
         button { padding: 12px 24px; font-size: 16px; background: #00d4ff; color: #000; border: none; border-radius: 8px; cursor: pointer; font-weight: bold; }
         button:hover { background: #00ff88; }
         .btn-secondary { background: #6c5ce7; color: #fff; }
@@ -58,36 +80,63 @@ func (h *LogHandler) LogViewer(c *fiber.Ctx) error {
     </style>
 </head>
 <body>
-    <h1>🔍 API Log Viewer</h1>
+    <h1>🔍 {{i18n:PageLogViewerTitle}}</h1>
     
     <div class="search-box">
-        <input type="text" id="invoiceInput" placeholder="Enter Invoice Number..." onkeypress="if(event.key==='Enter')searchLogs()">
-        <button onclick="searchLogs()">🔎 Search</button>
+        <input type="text" id="invoiceInput" placeholder="{{i18n:PageInvoicePlaceholder}}" onkeypress="if(event.key==='Enter')searchLogs()">
+        <button onclick="searchLogs()">🔎 {{i18n:PageSearch}}</button>
+        <select id="sortSelect" onchange="applyFilters()">
+            <option value="sort=created_at&order=desc">{{i18n:PageNewestFirst}}</option>
+            <option value="sort=created_at&order=asc">{{i18n:PageOldestFirst}}</option>
+            <option value="sort=duration_ms&order=desc">{{i18n:PageSlowestFirst}}</option>
+            <option value="sort=status_code&order=desc">{{i18n:PageStatusCode}}</option>
+        </select>
+        <button class="btn-secondary" onclick="loadAll()">📋 {{i18n:PageLoadAll}}</button>
+        <button class="btn-secondary" onclick="exportLogs()">📥 {{i18n:PageExportCSV}}</button>
+    </div>
+
+    <div class="search-box">
+        <label>{{i18n:PageFrom}} <input type="date" id="fromInput" onchange="applyFilters()"></label>
+        <label>{{i18n:PageTo}} <input type="date" id="toInput" onchange="applyFilters()"></label>
+        <select id="statusSelect" onchange="applyFilters()">
+            <option value="">{{i18n:PageAllStatuses}}</option>
+            <option value="2xx">{{i18n:PageSuccessStatus}}</option>
+            <option value="4xx">{{i18n:PageClientError}}</option>
+            <option value="5xx">{{i18n:PageServerError}}</option>
+        </select>
+        <select id="methodSelect" onchange="applyFilters()">
+            <option value="">{{i18n:PageAllMethods}}</option>
+            <option value="GET">GET</option>
+            <option value="POST">POST</option>
+            <option value="PUT">PUT</option>
+            <option value="PATCH">PATCH</option>
+            <option value="DELETE">DELETE</option>
+        </select>
     </div>
 
     <div id="stats" class="stats" style="display:none;">
         <div class="stat-item">
             <div class="stat-value" id="totalCount">0</div>
-            <div class="stat-label">Total Logs</div>
+            <div class="stat-label">{{i18n:PageTotalLogs}}</div>
         </div>
         <div class="stat-item">
             <div class="stat-value status-success" id="successCount">0</div>
-            <div class="stat-label">Success</div>
+            <div class="stat-label">{{i18n:PageSuccess}}</div>
         </div>
         <div class="stat-item">
             <div class="stat-value status-error" id="errorCount">0</div>
-            <div class="stat-label">Errors</div>
+            <div class="stat-label">{{i18n:PageErrors}}</div>
         </div>
     </div>
 
     <div id="tableContainer">
-        <p class="loading">Enter an invoice number to search logs or click "Load All"...</p>
+        <p class="loading">{{i18n:PageLogsHint}}</p>
     </div>
 
     <div id="modal" class="modal" onclick="closeModal(event)">
         <div class="modal-content" onclick="event.stopPropagation()">
             <div class="modal-header">
-                <h3 id="modalTitle">Details</h3>
+                <h3 id="modalTitle">{{i18n:PageDetails}}</h3>
                 <span class="modal-close" onclick="closeModal()">&times;</span>
             </div>
             <pre id="modalBody"></pre>
@@ -95,33 +144,113 @@ func (h *LogHandler) LogViewer(c *fiber.Ctx) error {
     </div>
 
     <script>
+        const authMode = '{{AUTH_MODE}}';
         let currentLogs = [];
+        let listUrl = '';
+        let nextCursor = '';
+
+        // filterQuery builds the server-side filter parameters from the inputs
+        function filterQuery() {
+            const params = new URLSearchParams();
+            const values = {
+                invoice: document.getElementById('invoiceInput').value.trim(),
+                from: document.getElementById('fromInput').value,
+                to: document.getElementById('toInput').value,
+                status: document.getElementById('statusSelect').value,
+                method: document.getElementById('methodSelect').value
+            };
+            Object.keys(values).forEach(function (key) {
+                if (values[key]) params.set(key, values[key]);
+            });
+            return params.toString();
+        }
 
         async function searchLogs() {
             const invoice = document.getElementById('invoiceInput').value.trim();
-            if (!invoice) { alert('Please enter an invoice number'); return; }
-            await fetchLogs('/api/v1/logs/search?invoice=' + encodeURIComponent(invoice));
+            if (!invoice) { alert('{{i18n:PageEnterInvoice}}'); return; }
+            await applyFilters();
+        }
+
+        async function applyFilters() {
+            listUrl = '/api/v1/logs?limit=50&' + document.getElementById('sortSelect').value;
+            const query = filterQuery();
+            if (query) listUrl += '&' + query;
+            await fetchLogs(listUrl, false);
         }
 
         async function loadAll() {
-            await fetchLogs('/api/v1/logs?limit=50');
+            ['invoiceInput', 'fromInput', 'toInput', 'statusSelect', 'methodSelect'].forEach(function (id) {
+                document.getElementById(id).value = '';
+            });
+            await applyFilters();
+        }
+
+        async function loadMore() {
+            await fetchLogs(listUrl + '&cursor=' + encodeURIComponent(nextCursor), true);
+        }
+
+        async function login() {
+            if (authMode === 'basic') {
+                alert('{{i18n:PageBasicAuthRequired}}');
+                return false;
+            }
+            localStorage.removeItem('authToken');
+            const username = prompt('{{i18n:PageLoginPromptLogs}}');
+            if (!username) return false;
+            const password = prompt('{{i18n:PagePassword}}');
+            if (!password) return false;
+            const res = await fetch('/auth/login', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ username: username, password: password })
+            });
+            const data = await res.json();
+            if (!data.success) { alert(data.message); return false; }
+            localStorage.setItem('authToken', data.data.token);
+            return true;
+        }
+
+        async function exportLogs() {
+            const invoice = document.getElementById('invoiceInput').value.trim();
+            const query = filterQuery();
+            const url = '/api/v1/logs/export' + (query ? '?' + query : '');
+            const token = localStorage.getItem('authToken');
+            const res = await fetch(url, { headers: token ? { 'Authorization': 'Bearer ' + token } : {} });
+            if (res.status === 401) {
+                if (await login()) { return exportLogs(); }
+                return;
+            }
+            if (!res.ok) { alert('{{i18n:PageExportFailed}} (' + res.status + ')'); return; }
+            const blob = await res.blob();
+            const link = document.createElement('a');
+            link.href = URL.createObjectURL(blob);
+            link.download = 'api-logs' + (invoice ? '-' + invoice : '') + '.csv';
+            link.click();
+            URL.revokeObjectURL(link.href);
         }
 
-        async function fetchLogs(url) {
-            document.getElementById('tableContainer').innerHTML = '<p class="loading">Loading...</p>';
-            document.getElementById('stats').style.display = 'none';
+        async function fetchLogs(url, append) {
+            if (!append) {
+                document.getElementById('tableContainer').innerHTML = '<p class="loading">{{i18n:PageLoading}}</p>';
+                document.getElementById('stats').style.display = 'none';
+            }
             try {
-                const res = await fetch(url);
+                const token = localStorage.getItem('authToken');
+                const res = await fetch(url, { headers: token ? { 'Authorization': 'Bearer ' + token } : {} });
+                if (res.status === 401) {
+                    if (await login()) { return fetchLogs(url, append); }
+                }
                 const data = await res.json();
                 if (data.success && data.data) {
-                    currentLogs = data.data;
-                    renderTable(data.data);
-                    updateStats(data.data);
+                    currentLogs = append ? currentLogs.concat(data.data) : data.data;
+                    nextCursor = data.pagination && data.pagination.has_more ? data.pagination.next_cursor : '';
+                    renderTable(currentLogs);
+                    updateStats(currentLogs);
                 } else {
-                    document.getElementById('tableContainer').innerHTML = '<p class="loading">No logs found</p>';
+                    document.getElementById('tableContainer').innerHTML = '<p class="loading">{{i18n:PageNoLogs}}</p>';
                 }
             } catch (err) {
-                document.getElementById('tableContainer').innerHTML = '<p class="loading">Error: ' + err.message + '</p>';
+                document.getElementById('tableContainer').innerHTML = '<p class="loading">{{i18n:PageError}}: ' + err.message + '</p>';
             }
         }
 
@@ -139,10 +268,10 @@ func (h *LogHandler) LogViewer(c *fiber.Ctx) error {
 
         function renderTable(logs) {
             if (!logs || logs.length === 0) {
-                document.getElementById('tableContainer').innerHTML = '<p class="loading">No logs found</p>';
+                document.getElementById('tableContainer').innerHTML = '<p class="loading">{{i18n:PageNoLogs}}</p>';
                 return;
             }
-            let html = '<div class="table-container"><table><thead><tr><th>ID</th><th>Invoice Number</th><th>Time</th><th>Method</th><th>Endpoint</th><th>Status</th><th>Duration</th><th>Email</th><th>Request</th><th>Response</th></tr></thead><tbody>';
+            let html = '<div class="table-container"><table><thead><tr><th>ID</th><th>{{i18n:PageInvoiceNumber}}</th><th>{{i18n:PageTime}}</th><th>{{i18n:PageMethod}}</th><th>{{i18n:PageEndpoint}}</th><th>{{i18n:PageStatus}}</th><th>{{i18n:PageDuration}}</th><th>{{i18n:PageEmail}}</th><th>{{i18n:PageRequest}}</th><th>{{i18n:PageResponse}}</th></tr></thead><tbody>';
             logs.forEach((log, idx) => {
                 const statusClass = log.status_code >= 200 && log.status_code < 300 ? 'status-success' : 'status-error';
                 const time = new Date(log.created_at).toLocaleString();
@@ -155,11 +284,14 @@ func (h *LogHandler) LogViewer(c *fiber.Ctx) error {
                     '<td class="' + statusClass + '">' + log.status_code + '</td>' +
                     '<td>' + log.duration_ms + 'ms</td>' +
                     '<td>' + (log.email || '-') + '</td>' +
-                    '<td class="body-cell"><button class="view-btn" onclick="showBody(' + idx + ', \'request\')">View</button></td>' +
-                    '<td class="body-cell"><button class="view-btn" onclick="showBody(' + idx + ', \'response\')">View</button></td>' +
+                    '<td class="body-cell"><button class="view-btn" onclick="showBody(' + idx + ', \'request\')">{{i18n:PageView}}</button></td>' +
+                    '<td class="body-cell"><button class="view-btn" onclick="showBody(' + idx + ', \'response\')">{{i18n:PageView}}</button></td>' +
                     '</tr>';
             });
             html += '</tbody></table></div>';
+            if (listUrl && nextCursor) {
+                html += '<div class="load-more"><button class="btn-secondary" onclick="loadMore()">⬇ {{i18n:PageLoadMore}}</button></div>';
+            }
             document.getElementById('tableContainer').innerHTML = html;
         }
 
@@ -170,14 +302,14 @@ func (h *LogHandler) LogViewer(c *fiber.Ctx) error {
 
         function showBody(idx, type) {
             const log = currentLogs[idx];
-            const title = type === 'request' ? 'Request Body' : 'Response Body';
+            const title = type === 'request' ? '{{i18n:PageRequestBody}}' : '{{i18n:PageResponseBody}}';
             const body = type === 'request' ? log.request_body : log.response_body;
             
             document.getElementById('modalTitle').textContent = title + ' (ID: ' + log.id + ')';
             try {
                 document.getElementById('modalBody').textContent = JSON.stringify(JSON.parse(body), null, 2);
             } catch {
-                document.getElementById('modalBody').textContent = body || '(empty)';
+                document.getElementById('modalBody').textContent = body || '{{i18n:PageEmpty}}';
             }
             document.getElementById('modal').style.display = 'block';
         }
@@ -198,23 +330,60 @@ func (h *LogHandler) LogViewer(c *fiber.Ctx) error {
     </script>
 </body>
 </html>`
+	html = strings.Replace(html, "{{AUTH_MODE}}", h.config.LogViewer.Auth, 1)
 	c.Set("Content-Type", "text/html")
-	return c.SendString(html)
+	return c.SendString(h.translator.LocalizePage(middleware.RequestLanguage(c), html))
 }
 
-// GetLogs returns all logs with limit
+// GetLogs returns a page of logs
+// Query: limit (max 200), offset or cursor, sort (created_at|status_code|duration_ms), order (asc|desc),
+// plus the filters of parseLogFilter
 func (h *LogHandler) GetLogs(c *fiber.Ctx) error {
 	limit := c.QueryInt("limit", 50)
+	if limit <= 0 {
+		limit = 50
+	}
 	if limit > 200 {
 		limit = 200
 	}
 
-	logs, err := h.logRepo.FindAll(c.Context(), limit)
+	filter, err := parseLogFilter(c, h.config.App.Location())
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "message": err.Error()})
+	}
+	filter.Sort = c.Query("sort", entity.APILogSortCreatedAt)
+	filter.Desc = !strings.EqualFold(c.Query("order"), "asc")
+	filter.Limit = limit
+	filter.Offset = c.QueryInt("offset")
+	filter.Cursor = c.Query("cursor")
+
+	if filter.Offset < 0 {
+		return c.Status(400).JSON(fiber.Map{"success": false, "message": "offset must not be negative"})
+	}
+	switch filter.Sort {
+	case entity.APILogSortCreatedAt, entity.APILogSortStatus, entity.APILogSortDuration:
+	default:
+		return c.Status(400).JSON(fiber.Map{"success": false, "message": "sort must be one of: created_at, status_code, duration_ms"})
+	}
+
+	page, err := h.logRepo.FindAll(c.Context(), filter)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		return c.Status(400).JSON(fiber.Map{"success": false, "message": err.Error()})
+	}
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"success": false, "message": err.Error()})
 	}
 
-	return c.JSON(fiber.Map{"success": true, "data": logs})
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    page.Logs,
+		"pagination": fiber.Map{
+			"limit":       limit,
+			"offset":      filter.Offset,
+			"has_more":    page.HasMore,
+			"next_cursor": page.NextCursor,
+		},
+	})
 }
 
 // SearchLogs searches logs by invoice number
@@ -231,3 +400,140 @@ func (h *LogHandler) SearchLogs(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{"success": true, "data": logs})
 }
+
+// GetDocumentLogs returns the webhook callbacks, Mekari calls and NAV updates of a document, oldest first
+func (h *LogHandler) GetDocumentLogs(c *fiber.Ctx) error {
+	traffic, err := h.traffic.GetDocumentTraffic(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "message": err.Error()})
+	}
+	if len(traffic.Entries) == 0 {
+		return c.Status(404).JSON(fiber.Map{"success": false, "message": "no traffic recorded for document"})
+	}
+
+	return c.JSON(fiber.Map{"success": true, "data": traffic})
+}
+
+// ExportLogs streams logs matching the filters of parseLogFilter as a CSV download
+func (h *LogHandler) ExportLogs(c *fiber.Ctx) error {
+	filter, err := parseLogFilter(c, h.config.App.Location())
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "message": err.Error()})
+	}
+
+	// Times are shown in app.timezone, with the offset
+	loc := h.config.App.Location()
+	filename := "api-logs-" + time.Now().In(loc).Format("20060102-150405") + ".csv"
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		// UTF-8 BOM so Excel detects the encoding
+		w.WriteString("\ufeff")
+
+		csvWriter := csv.NewWriter(w)
+		csvWriter.Write([]string{"id", "created_at", "source", "invoice_no", "entry_no", "document_id", "method", "endpoint", "status_code", "duration_ms", "email", "caller", "request_id", "request_body", "response_body"})
+
+		rows := 0
+		err := h.logRepo.Stream(context.Background(), filter, func(log *entity.APILog) error {
+			csvWriter.Write([]string{
+				strconv.FormatInt(log.ID, 10),
+				log.CreatedAt.In(loc).Format(time.RFC3339),
+				log.Source,
+				log.InvoiceNo,
+				strconv.Itoa(log.EntryNo),
+				log.DocumentID,
+				log.Method,
+				log.Endpoint,
+				strconv.Itoa(log.StatusCode),
+				strconv.FormatInt(log.Duration, 10),
+				log.Email,
+				log.Caller,
+				log.RequestID,
+				log.RequestBody,
+				log.ResponseBody,
+			})
+
+			// Flush regularly so the download progresses and memory stays flat
+			rows++
+			if rows%100 == 0 {
+				csvWriter.Flush()
+				if err := csvWriter.Error(); err != nil {
+					return err
+				}
+				return w.Flush()
+			}
+			return nil
+		})
+		if err != nil {
+			// Headers are already sent; the error can only be noted in the file
+			csvWriter.Write([]string{"ERROR", err.Error()})
+		}
+
+		csvWriter.Flush()
+		w.Flush()
+	})
+
+	return nil
+}
+
+// parseLogFilter reads the log filters shared by listing and export:
+// invoice, method, status_code, status (2xx|3xx|4xx|5xx), email, caller, request_id, document_id,
+// source (mekari|nav|api), from and to (YYYY-MM-DD in app.timezone or RFC3339; a date-only "to" includes
+// that whole day)
+func parseLogFilter(c *fiber.Ctx, loc *time.Location) (*entity.APILogFilter, error) {
+	from, err := parseDateParam(c.Query("from"), false, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from: %w", err)
+	}
+	to, err := parseDateParam(c.Query("to"), true, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to: %w", err)
+	}
+
+	filter := &entity.APILogFilter{
+		Invoice:    c.Query("invoice"),
+		Method:     c.Query("method"),
+		StatusCode: c.QueryInt("status_code"),
+		Email:      c.Query("email"),
+		Caller:     c.Query("caller"),
+		RequestID:  c.Query("request_id"),
+		DocumentID: c.Query("document_id"),
+		Source:     c.Query("source"),
+		From:       from,
+		To:         to,
+	}
+
+	if status := c.Query("status"); status != "" {
+		if len(status) != 3 || status[1:] != "xx" || status[0] < '1' || status[0] > '5' {
+			return nil, fmt.Errorf("status must be one of: 1xx, 2xx, 3xx, 4xx, 5xx")
+		}
+		filter.StatusClass = int(status[0] - '0')
+	}
+
+	return filter, nil
+}
+
+// parseDateParam parses YYYY-MM-DD in loc (app.timezone) or RFC3339, and returns the time in
+// UTC like the stored timestamps. Date-only values mark the start of the day, or the start of
+// the next day when endOfDay is set (for exclusive upper bounds).
+func parseDateParam(value string, endOfDay bool, loc *time.Location) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		t = t.UTC()
+		return &t, nil
+	}
+
+	t, err := time.ParseInLocation(timeutil.DateLayout, value, loc)
+	if err != nil {
+		return nil, fmt.Errorf("expected YYYY-MM-DD or RFC3339")
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	t = t.UTC()
+	return &t, nil
+}
@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"errors"
+
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 
+	"mekari-esign/internal/delivery/http/validation"
 	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/logger"
 	"mekari-esign/internal/infrastructure/oauth2"
 	"mekari-esign/internal/usecase"
 )
@@ -12,13 +16,15 @@ import (
 type OAuthHandler struct {
 	usecase      usecase.OAuthUsecase
 	tokenService oauth2.TokenService
+	redactor     *logger.Redactor
 	logger       *zap.Logger
 }
 
-func NewOAuthHandler(usecase usecase.OAuthUsecase, tokenService oauth2.TokenService, logger *zap.Logger) *OAuthHandler {
+func NewOAuthHandler(usecase usecase.OAuthUsecase, tokenService oauth2.TokenService, redactor *logger.Redactor, logger *zap.Logger) *OAuthHandler {
 	return &OAuthHandler{
 		usecase:      usecase,
 		tokenService: tokenService,
+		redactor:     redactor,
 		logger:       logger,
 	}
 }
@@ -124,7 +130,7 @@ func (h *OAuthHandler) OAuthCallback(c *fiber.Ctx) error {
 	locale := c.Query("locale")
 
 	h.logger.Info("OAuth callback received",
-		zap.String("code", code),
+		h.redactor.String("code", code),
 		zap.String("state", state),
 		zap.String("locale", locale),
 	)
@@ -166,6 +172,7 @@ func (h *OAuthHandler) OAuthCallback(c *fiber.Ctx) error {
 // @Param request body entity.SaveCodeRequest true "Save code request"
 // @Success 200 {object} entity.APIResponse
 // @Failure 400 {object} entity.APIResponse
+// @Failure 422 {object} entity.APIResponse "Field validation errors"
 // @Failure 500 {object} entity.APIResponse
 // @Router /api/v1/oauth/save-code [post]
 func (h *OAuthHandler) SaveCode(c *fiber.Ctx) error {
@@ -179,10 +186,8 @@ func (h *OAuthHandler) SaveCode(c *fiber.Ctx) error {
 		)
 	}
 
-	if req.Email == "" || req.Code == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(
-			entity.NewErrorResponse("BAD_REQUEST", "Email and code are required"),
-		)
+	if fields := validation.Validate(&req); fields != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(entity.NewValidationErrorResponse(fields))
 	}
 
 	if err := h.usecase.SaveCode(ctx, req.Email, req.Code); err != nil {
@@ -236,10 +241,78 @@ func (h *OAuthHandler) GetToken(c *fiber.Ctx) error {
 	return c.JSON(entity.NewSuccessResponse(token, "OAuth token retrieved successfully"))
 }
 
+// GetTokenHistory godoc
+// @Summary Get OAuth token history by email
+// @Description List the token grant and refresh attempts of an email, newest first, with the error of each failed attempt
+// @Tags oauth
+// @Produce json
+// @Param email query string true "Email address"
+// @Param limit query int false "Maximum entries (default and max 500)"
+// @Success 200 {object} entity.APIResponse
+// @Failure 400 {object} entity.APIResponse
+// @Failure 500 {object} entity.APIResponse
+// @Router /api/v1/oauth/history [get]
+func (h *OAuthHandler) GetTokenHistory(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	email := c.Query("email")
+	if email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(
+			entity.NewErrorResponse("BAD_REQUEST", "Email is required"),
+		)
+	}
+
+	history, err := h.usecase.GetTokenHistory(ctx, email, c.QueryInt("limit"))
+	if err != nil {
+		h.logger.Error("Failed to get OAuth token history", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(
+			entity.NewErrorResponse("INTERNAL_ERROR", err.Error()),
+		)
+	}
+
+	return c.JSON(entity.NewSuccessResponse(history, "OAuth token history retrieved successfully"))
+}
+
+// DeleteToken godoc
+// @Summary Delete the OAuth token of an email
+// @Description Clear the code and tokens of an email and mark its record deleted, for data removal requests. The record and its token history are purged after oauth.deleted_retention_days; saving a new code restores it.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Param email query string true "Email address"
+// @Success 200 {object} entity.APIResponse
+// @Failure 400 {object} entity.APIResponse
+// @Failure 404 {object} entity.APIResponse
+// @Failure 500 {object} entity.APIResponse
+// @Router /admin/oauth-tokens [delete]
+func (h *OAuthHandler) DeleteToken(c *fiber.Ctx) error {
+	email := c.Query("email")
+	if email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(
+			entity.NewErrorResponse("BAD_REQUEST", "Email is required"),
+		)
+	}
+
+	if err := h.usecase.DeleteToken(c.UserContext(), email, requestActor(c)); err != nil {
+		if errors.Is(err, usecase.ErrOAuthTokenNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(
+				entity.NewErrorResponse("NOT_FOUND", err.Error()),
+			)
+		}
+		h.logger.Error("Failed to delete OAuth token", zap.String("email", email), zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(
+			entity.NewErrorResponse("INTERNAL_ERROR", err.Error()),
+		)
+	}
+
+	return c.JSON(entity.NewSuccessResponse(map[string]string{"email": email}, "OAuth token deleted successfully"))
+}
+
 // ExchangeCodeRequest represents the request to exchange code for tokens
 type ExchangeCodeRequest struct {
-	Email string `json:"email"`
-	Code  string `json:"code"`
+	Email string `json:"email" validate:"required,email"`
+	Code  string `json:"code" validate:"required"`
 }
 
 // ExchangeCode godoc
@@ -251,6 +324,7 @@ type ExchangeCodeRequest struct {
 // @Param request body ExchangeCodeRequest true "Exchange code request"
 // @Success 200 {object} entity.APIResponse
 // @Failure 400 {object} entity.APIResponse
+// @Failure 422 {object} entity.APIResponse "Field validation errors"
 // @Failure 500 {object} entity.APIResponse
 // @Router /api/v1/oauth/exchange [post]
 func (h *OAuthHandler) ExchangeCode(c *fiber.Ctx) error {
@@ -264,16 +338,8 @@ func (h *OAuthHandler) ExchangeCode(c *fiber.Ctx) error {
 		)
 	}
 
-	if req.Email == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(
-			entity.NewErrorResponse("BAD_REQUEST", "Email is required"),
-		)
-	}
-
-	if req.Code == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(
-			entity.NewErrorResponse("BAD_REQUEST", "Code is required"),
-		)
+	if fields := validation.Validate(&req); fields != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(entity.NewValidationErrorResponse(fields))
 	}
 
 	// Exchange code for tokens
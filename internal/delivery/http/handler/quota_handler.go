@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/usecase"
+)
+
+type QuotaHandler struct {
+	config  *config.Config
+	usecase usecase.QuotaUsecase
+	logger  *zap.Logger
+}
+
+func NewQuotaHandler(cfg *config.Config, usecase usecase.QuotaUsecase, logger *zap.Logger) *QuotaHandler {
+	return &QuotaHandler{
+		config:  cfg,
+		usecase: usecase,
+		logger:  logger,
+	}
+}
+
+// GetQuota godoc
+// @Summary Get e-meterai quota
+// @Description Get the remaining e-meterai balance from the profile balance block (cached for quota.cache_ttl seconds).
+// @Description `low` is true when the balance is below quota.alert_threshold; a notification is sent at most once per quota.alert_interval.
+// @Tags esign
+// @Accept json
+// @Produce json
+// @Param email query string false "User email for OAuth token (required for oauth2 auth)"
+// @Success 200 {object} entity.APIResponse
+// @Failure 400 {object} entity.APIResponse
+// @Failure 500 {object} entity.APIResponse
+// @Router /api/v1/esign/quota [get]
+func (h *QuotaHandler) GetQuota(c *fiber.Ctx) error {
+	email := c.Query("email")
+	if email == "" && h.config.Mekari.IsOAuth2() {
+		return c.Status(fiber.StatusBadRequest).JSON(
+			entity.NewErrorResponse("BAD_REQUEST", "Email is required"),
+		)
+	}
+
+	quota, err := h.usecase.GetQuota(c.UserContext(), email)
+	if err != nil {
+		h.logger.Error("Failed to get e-meterai quota", zap.String("email", email), zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(
+			entity.NewErrorResponse("INTERNAL_ERROR", err.Error()),
+		)
+	}
+
+	return c.JSON(entity.NewSuccessResponse(quota, "E-meterai quota retrieved successfully"))
+}
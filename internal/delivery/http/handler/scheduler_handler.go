@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/scheduler"
+)
+
+type SchedulerHandler struct {
+	scheduler *scheduler.Scheduler
+	logger    *zap.Logger
+}
+
+func NewSchedulerHandler(sched *scheduler.Scheduler, logger *zap.Logger) *SchedulerHandler {
+	return &SchedulerHandler{scheduler: sched, logger: logger}
+}
+
+// ListJobs godoc
+// @Summary List scheduled jobs
+// @Description The recurring jobs of this instance with their schedule (scheduler.jobs), next run and the
+// @Description outcome of their last run. A last run of cluster jobs is skipped when another instance ran it.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Success 200 {object} entity.APIResponse
+// @Failure 401 {object} entity.APIResponse
+// @Failure 403 {object} entity.APIResponse
+// @Router /api/v1/admin/scheduler/jobs [get]
+func (h *SchedulerHandler) ListJobs(c *fiber.Ctx) error {
+	return c.JSON(entity.NewSuccessResponse(h.scheduler.Jobs(), "Scheduled jobs retrieved successfully"))
+}
+
+// RunJob godoc
+// @Summary Run a scheduled job now
+// @Description Start a run of the job on this instance in the background, also when it is disabled. Its
+// @Description outcome is reported as the last run of the job.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Param name path string true "Job name"
+// @Success 202 {object} entity.APIResponse
+// @Failure 401 {object} entity.APIResponse
+// @Failure 403 {object} entity.APIResponse
+// @Failure 404 {object} entity.APIResponse
+// @Failure 409 {object} entity.APIResponse "Job already running"
+// @Router /api/v1/admin/scheduler/jobs/{name}/run [post]
+func (h *SchedulerHandler) RunJob(c *fiber.Ctx) error {
+	name := c.Params("name")
+	err := h.scheduler.RunNow(name)
+	switch {
+	case errors.Is(err, scheduler.ErrJobNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(entity.NewErrorResponse("NOT_FOUND", err.Error()))
+	case errors.Is(err, scheduler.ErrJobRunning):
+		return c.Status(fiber.StatusConflict).JSON(entity.NewErrorResponse("JOB_RUNNING", err.Error()))
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(entity.NewErrorResponse("INTERNAL_ERROR", err.Error()))
+	}
+
+	h.logger.Info("Scheduled job started manually", zap.String("job", name), zap.String("actor", requestActor(c)))
+	return c.Status(fiber.StatusAccepted).JSON(entity.NewSuccessResponse(nil, "Job started"))
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/usecase"
+	"mekari-esign/updater"
+)
+
+type StatusHandler struct {
+	usecase usecase.StatusUsecase
+}
+
+func NewStatusHandler(usecase usecase.StatusUsecase) *StatusHandler {
+	return &StatusHandler{usecase: usecase}
+}
+
+// Status godoc
+// @Summary Service status
+// @Description Public summary for status pages: version, uptime, documents by state and the last successful Mekari call and NAV update
+// @Tags health
+// @Produce json
+// @Success 200 {object} entity.APIResponse
+// @Router /status [get]
+func (h *StatusHandler) Status(c *fiber.Ctx) error {
+	status := h.usecase.GetStatus(c.UserContext())
+	status.Version = updater.Version
+	status.StartedAt = processStartedAt
+	status.Uptime = time.Since(processStartedAt).Round(time.Second).String()
+
+	return c.JSON(entity.NewSuccessResponse(status, "Service status retrieved successfully"))
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/usecase"
+	"mekari-esign/updater"
+)
+
+type UpdateHandler struct {
+	usecase usecase.UpdateUsecase
+	logger  *zap.Logger
+}
+
+func NewUpdateHandler(usecase usecase.UpdateUsecase, logger *zap.Logger) *UpdateHandler {
+	return &UpdateHandler{usecase: usecase, logger: logger}
+}
+
+// GetStatus godoc
+// @Summary Update status
+// @Description The running version and update settings, the newest version, time and error of the last
+// @Description update check, the update the background updater downloaded and when it is installed
+// @Description (pending, null when none), the outcome of the last update or rollback, and the version a
+// @Description rollback restores.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Success 200 {object} entity.APIResponse
+// @Failure 401 {object} entity.APIResponse
+// @Failure 403 {object} entity.APIResponse
+// @Router /api/v1/admin/update/status [get]
+func (h *UpdateHandler) GetStatus(c *fiber.Ctx) error {
+	status := h.usecase.GetStatus(c.UserContext())
+	return c.JSON(entity.NewSuccessResponse(status, "Update status retrieved successfully"))
+}
+
+// GetHistory godoc
+// @Summary Update history
+// @Description The latest updates and rollbacks of every host using the database, newest first. Each is
+// @Description recorded when the service starts after it.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Param limit query int false "Maximum entries (default and max 100)"
+// @Success 200 {object} entity.APIResponse
+// @Failure 401 {object} entity.APIResponse
+// @Failure 403 {object} entity.APIResponse
+// @Router /api/v1/admin/update/history [get]
+func (h *UpdateHandler) GetHistory(c *fiber.Ctx) error {
+	history, err := h.usecase.GetHistory(c.UserContext(), c.QueryInt("limit"))
+	if err != nil {
+		h.logger.Error("Failed to get update history", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(
+			entity.NewErrorResponse("INTERNAL_ERROR", err.Error()),
+		)
+	}
+
+	return c.JSON(entity.NewSuccessResponse(history, "Update history retrieved successfully"))
+}
+
+// Apply godoc
+// @Summary Apply an update
+// @Description Install the update staged by the background updater now, regardless of the maintenance
+// @Description window, or without one check for an update and install it, like -update. The update runs
+// @Description in a separate process after the response and restarts the service; see update/status.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Success 202 {object} entity.APIResponse
+// @Failure 401 {object} entity.APIResponse
+// @Failure 403 {object} entity.APIResponse
+// @Router /api/v1/admin/update/apply [post]
+func (h *UpdateHandler) Apply(c *fiber.Ctx) error {
+	version, err := h.usecase.Apply(c.UserContext(), requestActor(c))
+	if err != nil {
+		h.logger.Error("Failed to start update", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(
+			entity.NewErrorResponse("INTERNAL_ERROR", err.Error()),
+		)
+	}
+
+	message := "Update check started; a new version is installed and the service restarts"
+	if version != "" {
+		message = "Update started; the service restarts shortly"
+	}
+	return c.Status(fiber.StatusAccepted).JSON(entity.NewSuccessResponse(map[string]interface{}{
+		"from": updater.Version,
+		"to":   version,
+	}, message))
+}
+
+// Rollback godoc
+// @Summary Roll back the last update
+// @Description Reinstall the version replaced by the last update, with its config file, and restart
+// @Description the service, like -rollback. The rollback runs in a separate process after the response;
+// @Description its outcome is written to update-status.json next to the executable.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Success 202 {object} entity.APIResponse
+// @Failure 401 {object} entity.APIResponse
+// @Failure 403 {object} entity.APIResponse
+// @Failure 409 {object} entity.APIResponse "No previous version"
+// @Router /api/v1/admin/update/rollback [post]
+func (h *UpdateHandler) Rollback(c *fiber.Ctx) error {
+	version, err := h.usecase.Rollback(c.UserContext(), requestActor(c))
+	if errors.Is(err, updater.ErrNoPrevious) {
+		return c.Status(fiber.StatusConflict).JSON(
+			entity.NewErrorResponse("NO_PREVIOUS_VERSION", err.Error()),
+		)
+	}
+	if err != nil {
+		h.logger.Error("Failed to start rollback", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(
+			entity.NewErrorResponse("INTERNAL_ERROR", err.Error()),
+		)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(entity.NewSuccessResponse(map[string]interface{}{
+		"from": updater.Version,
+		"to":   version,
+	}, "Rollback started; the service restarts shortly"))
+}
@@ -1,48 +1,57 @@
 package handler
 
 import (
+	"strconv"
+
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 
 	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/logger"
 	"mekari-esign/internal/usecase"
 )
 
 type WebhookHandler struct {
-	usecase usecase.WebhookUsecase
-	logger  *zap.Logger
+	usecase  usecase.WebhookUsecase
+	redactor *logger.Redactor
+	logger   *zap.Logger
 }
 
-func NewWebhookHandler(usecase usecase.WebhookUsecase, logger *zap.Logger) *WebhookHandler {
+func NewWebhookHandler(usecase usecase.WebhookUsecase, redactor *logger.Redactor, logger *zap.Logger) *WebhookHandler {
 	return &WebhookHandler{
-		usecase: usecase,
-		logger:  logger,
+		usecase:  usecase,
+		redactor: redactor,
+		logger:   logger.Named("webhook"),
 	}
 }
 
 // MekariCallback godoc
 // @Summary Mekari eSign webhook callback
-// @Description Receives webhook callbacks from Mekari eSign when document status changes
+// @Description Receives webhook callbacks from Mekari eSign when document status changes.
+//
+//	The event is persisted and processed in the background; duplicates are acknowledged but ignored.
+//
 // @Tags webhook
 // @Accept json
 // @Produce json
 // @Param payload body entity.WebhookPayload true "Webhook payload"
-// @Success 200 {object} entity.APIResponse
+// @Success 202 {object} entity.APIResponse
 // @Failure 400 {object} entity.APIResponse
 // @Failure 500 {object} entity.APIResponse
 // @Router /webhook/mekari [post]
 func (h *WebhookHandler) MekariCallback(c *fiber.Ctx) error {
 	ctx := c.UserContext()
+	log := logger.FromContext(ctx, h.logger)
 
 	// Log raw body for debugging
-	h.logger.Info("Received Mekari webhook callback",
-		zap.String("body", string(c.Body())),
+	log.Info("Received Mekari webhook callback",
+		zap.String("body", h.redactor.Body(string(c.Body()))),
 	)
 
 	// Parse webhook payload
 	var payload entity.WebhookPayload
 	if err := c.BodyParser(&payload); err != nil {
-		h.logger.Error("Failed to parse webhook payload", zap.Error(err))
+		log.Error("Failed to parse webhook payload", zap.Error(err))
 		return c.Status(fiber.StatusBadRequest).JSON(
 			entity.NewErrorResponse("BAD_REQUEST", "Invalid webhook payload"),
 		)
@@ -50,26 +59,88 @@ func (h *WebhookHandler) MekariCallback(c *fiber.Ctx) error {
 
 	// Validate payload
 	if payload.Data.ID == "" {
-		h.logger.Error("Missing document ID in webhook payload")
+		log.Error("Missing document ID in webhook payload")
 		return c.Status(fiber.StatusBadRequest).JSON(
 			entity.NewErrorResponse("BAD_REQUEST", "Missing document ID"),
 		)
 	}
 
-	// Process webhook
-	if err := h.usecase.ProcessWebhook(ctx, &payload); err != nil {
-		h.logger.Error("Failed to process webhook",
-			zap.String("document_id", payload.Data.ID),
-			zap.Error(err),
-		)
+	ctx = logger.WithDocument(ctx, payload.Data.ID, "")
+	log = logger.FromContext(ctx, h.logger)
+
+	// Persist event for background processing (body is copied, fiber reuses the buffer)
+	rawBody := append([]byte(nil), c.Body()...)
+	queued, err := h.usecase.EnqueueWebhook(ctx, rawBody, &payload)
+	if err != nil {
+		log.Error("Failed to persist webhook event", zap.Error(err))
 		return c.Status(fiber.StatusInternalServerError).JSON(
 			entity.NewErrorResponse("INTERNAL_ERROR", err.Error()),
 		)
 	}
 
-	return c.JSON(entity.NewSuccessResponse(map[string]interface{}{
+	return c.Status(fiber.StatusAccepted).JSON(entity.NewSuccessResponse(map[string]interface{}{
 		"document_id":    payload.Data.ID,
 		"signing_status": payload.Data.Attributes.SigningStatus,
-		"processed":      true,
-	}, "Webhook processed successfully"))
+		"queued":         queued,
+		"duplicate":      !queued,
+	}, "Webhook accepted for processing"))
+}
+
+// ListWebhookEvents godoc
+// @Summary List webhook events
+// @Description List received webhook events, newest first
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Param status query string false "Filter by status (pending, processing, processed, failed)"
+// @Param limit query int false "Maximum number of events (default 50, max 500)"
+// @Success 200 {object} entity.APIResponse
+// @Failure 500 {object} entity.APIResponse
+// @Router /admin/webhook-events [get]
+func (h *WebhookHandler) ListWebhookEvents(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 50)
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+
+	events, err := h.usecase.ListWebhookEvents(c.UserContext(), c.Query("status"), limit)
+	if err != nil {
+		h.logger.Error("Failed to list webhook events", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(
+			entity.NewErrorResponse("INTERNAL_ERROR", err.Error()),
+		)
+	}
+
+	return c.JSON(entity.NewSuccessResponse(events, "Webhook events retrieved successfully"))
+}
+
+// ReplayWebhookEvent godoc
+// @Summary Replay webhook event
+// @Description Requeue a processed or failed webhook event so it is processed again
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Security AdminToken
+// @Param id path int true "Webhook event ID"
+// @Success 200 {object} entity.APIResponse
+// @Failure 400 {object} entity.APIResponse
+// @Failure 404 {object} entity.APIResponse
+// @Router /admin/webhook-events/{id}/replay [post]
+func (h *WebhookHandler) ReplayWebhookEvent(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(
+			entity.NewErrorResponse("BAD_REQUEST", "Invalid webhook event ID"),
+		)
+	}
+
+	if err := h.usecase.ReplayWebhookEvent(c.UserContext(), id); err != nil {
+		h.logger.Warn("Failed to replay webhook event", zap.Int64("id", id), zap.Error(err))
+		return c.Status(fiber.StatusNotFound).JSON(
+			entity.NewErrorResponse("NOT_FOUND", err.Error()),
+		)
+	}
+
+	return c.JSON(entity.NewSuccessResponse(map[string]int64{"id": id}, "Webhook event requeued"))
 }
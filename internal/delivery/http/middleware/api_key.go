@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/usecase"
+)
+
+const (
+	// APIKeyHeader is the header clients send their API key in
+	APIKeyHeader = "X-API-Key"
+	// AdminTokenHeader is the header used for admin endpoints
+	AdminTokenHeader = "X-Admin-Token"
+	// LocalsAPIKey is the fiber locals key holding the authenticated *entity.APIKey
+	LocalsAPIKey = "api_key"
+)
+
+// APIKeyAuth authenticates requests with hashed API keys and applies per-key rate limits
+type APIKeyAuth struct {
+	config  *config.Config
+	usecase usecase.APIKeyUsecase
+	limiter *RateLimiter
+	logger  *zap.Logger
+}
+
+func NewAPIKeyAuth(cfg *config.Config, usecase usecase.APIKeyUsecase, limiter *RateLimiter, logger *zap.Logger) *APIKeyAuth {
+	return &APIKeyAuth{
+		config:  cfg,
+		usecase: usecase,
+		limiter: limiter,
+		logger:  logger,
+	}
+}
+
+// Handler returns the fiber middleware protecting API routes
+func (m *APIKeyAuth) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !m.config.Security.APIKeyEnabled || m.isExempt(c.Path()) {
+			return c.Next()
+		}
+
+		// Logged-in users are authenticated by their token instead, and the admin token grants admin
+		if CurrentUser(c) != nil || m.hasAdminToken(c) {
+			return c.Next()
+		}
+
+		key := extractAPIKey(c)
+		if key == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(
+				entity.NewErrorResponse("UNAUTHORIZED", "API key is required"),
+			)
+		}
+
+		apiKey, err := m.usecase.Authenticate(c.UserContext(), key)
+		if err != nil {
+			if errors.Is(err, usecase.ErrInvalidAPIKey) {
+				return c.Status(fiber.StatusUnauthorized).JSON(
+					entity.NewErrorResponse("UNAUTHORIZED", err.Error()),
+				)
+			}
+			m.logger.Error("Failed to authenticate API key", zap.Error(err))
+			return c.Status(fiber.StatusInternalServerError).JSON(
+				entity.NewErrorResponse("INTERNAL_ERROR", "Failed to authenticate API key"),
+			)
+		}
+
+		limit := apiKey.RateLimit
+		if limit == 0 {
+			limit = m.config.Security.DefaultRateLimit
+		}
+		if limit > 0 && !m.limiter.check(c, "key:"+strconv.FormatInt(apiKey.ID, 10), limit, time.Minute) {
+			return c.Status(fiber.StatusTooManyRequests).JSON(
+				entity.NewErrorResponse("RATE_LIMITED", "Rate limit exceeded for this API key"),
+			)
+		}
+
+		c.Locals(LocalsAPIKey, apiKey)
+		return c.Next()
+	}
+}
+
+// isExempt checks if a path is excluded from API key authentication
+func (m *APIKeyAuth) isExempt(path string) bool {
+	for _, exempt := range m.config.Security.ExemptPaths {
+		if path == exempt {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAdminToken checks the static admin token from config
+func (m *APIKeyAuth) hasAdminToken(c *fiber.Ctx) bool {
+	expected := m.config.Security.AdminToken
+	return expected != "" && subtle.ConstantTimeCompare([]byte(c.Get(AdminTokenHeader)), []byte(expected)) == 1
+}
+
+// extractAPIKey reads the key from X-API-Key or "Authorization: ApiKey <key>"
+func extractAPIKey(c *fiber.Ctx) string {
+	if key := c.Get(APIKeyHeader); key != "" {
+		return key
+	}
+
+	auth := c.Get(fiber.HeaderAuthorization)
+	if strings.HasPrefix(auth, "ApiKey ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "ApiKey "))
+	}
+
+	return ""
+}
@@ -0,0 +1,197 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/usecase"
+)
+
+const (
+	// LocalsUser is the fiber locals key holding the authenticated *entity.AuthUser
+	LocalsUser = "auth_user"
+	// basicAuthChallenge is sent with 401 responses in log viewer basic auth mode
+	basicAuthChallenge = `Basic realm="Mekari eSign Log Viewer", charset="UTF-8"`
+)
+
+// Auth resolves the logged-in user of a request and enforces role requirements
+type Auth struct {
+	config  *config.Config
+	usecase usecase.AuthUsecase
+	logger  *zap.Logger
+}
+
+func NewAuth(cfg *config.Config, usecase usecase.AuthUsecase, logger *zap.Logger) *Auth {
+	return &Auth{
+		config:  cfg,
+		usecase: usecase,
+		logger:  logger,
+	}
+}
+
+// Handler parses an optional "Authorization: Bearer <token>" header and stores the user in locals.
+// Event stream requests may send the token as the access_token query parameter instead.
+// In log viewer basic auth mode, valid basic credentials authenticate as a read-only user.
+// Invalid credentials are rejected; requests without any continue unauthenticated.
+func (m *Auth) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		auth := c.Get(fiber.HeaderAuthorization)
+		if auth == "" && c.Query("access_token") != "" && strings.Contains(c.Get(fiber.HeaderAccept), "text/event-stream") {
+			// EventSource cannot set headers, so event streams may pass the token in the query
+			auth = "Bearer " + c.Query("access_token")
+		}
+		if strings.HasPrefix(auth, "Basic ") && m.config.LogViewer.Auth == config.LogViewerAuthBasic {
+			return m.basicAuth(c, strings.TrimPrefix(auth, "Basic "))
+		}
+		if !strings.HasPrefix(auth, "Bearer ") {
+			return c.Next()
+		}
+
+		user, err := m.usecase.ParseToken(strings.TrimSpace(strings.TrimPrefix(auth, "Bearer ")))
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(
+				entity.NewErrorResponse("UNAUTHORIZED", err.Error()),
+			)
+		}
+
+		c.Locals(LocalsUser, user)
+		return c.Next()
+	}
+}
+
+// basicAuth authenticates log viewer basic credentials as a read-only user
+func (m *Auth) basicAuth(c *fiber.Ctx, encoded string) error {
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	username, password, ok := strings.Cut(string(decoded), ":")
+	if err != nil || !ok ||
+		subtle.ConstantTimeCompare([]byte(username), []byte(m.config.LogViewer.BasicUsername)) != 1 ||
+		subtle.ConstantTimeCompare([]byte(password), []byte(m.config.LogViewer.BasicPassword)) != 1 {
+		c.Set(fiber.HeaderWWWAuthenticate, basicAuthChallenge)
+		return c.Status(fiber.StatusUnauthorized).JSON(
+			entity.NewErrorResponse("UNAUTHORIZED", "Invalid credentials"),
+		)
+	}
+
+	c.Locals(LocalsUser, &entity.AuthUser{
+		Username: username,
+		Role:     entity.RoleReadOnly,
+	})
+	return c.Next()
+}
+
+// RequireRole allows the request if the logged-in user has at least minRole.
+// A valid X-Admin-Token always grants admin access. When auth is disabled,
+// read-only routes stay open and higher roles require the admin token.
+func (m *Auth) RequireRole(minRole string) fiber.Handler {
+	return m.requireRole(minRole, !m.config.Auth.Enabled && minRole == entity.RoleReadOnly)
+}
+
+// RequireLogViewer protects the log viewer and logs API according to log_viewer.auth
+func (m *Auth) RequireLogViewer() fiber.Handler {
+	switch m.config.LogViewer.Auth {
+	case config.LogViewerAuthBasic:
+		require := m.requireRole(entity.RoleReadOnly, false)
+		return func(c *fiber.Ctx) error {
+			if CurrentUser(c) == nil {
+				// Lets browsers prompt for the log viewer credentials
+				c.Set(fiber.HeaderWWWAuthenticate, basicAuthChallenge)
+			}
+			return require(c)
+		}
+	case config.LogViewerAuthLogin:
+		return m.requireRole(entity.RoleReadOnly, false)
+	default:
+		return m.RequireRole(entity.RoleReadOnly)
+	}
+}
+
+func (m *Auth) requireRole(minRole string, allowAnonymous bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if m.hasAdminToken(c) {
+			return c.Next()
+		}
+
+		user := CurrentUser(c)
+		if user == nil {
+			if allowAnonymous {
+				return c.Next()
+			}
+			return c.Status(fiber.StatusUnauthorized).JSON(
+				entity.NewErrorResponse("UNAUTHORIZED", "Authentication is required"),
+			)
+		}
+
+		if !entity.RoleAtLeast(user.Role, minRole) {
+			return m.deny(c, user, minRole)
+		}
+
+		return c.Next()
+	}
+}
+
+// RequireUserRole requires at least minRole from logged-in users only. Requests without a login
+// are left to the API key check, so NAV codeunits keep calling these routes with their keys.
+func (m *Auth) RequireUserRole(minRole string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := CurrentUser(c)
+		if user == nil || m.hasAdminToken(c) || entity.RoleAtLeast(user.Role, minRole) {
+			return c.Next()
+		}
+		return m.deny(c, user, minRole)
+	}
+}
+
+// deny rejects a logged-in user whose role is below minRole
+func (m *Auth) deny(c *fiber.Ctx, user *entity.AuthUser, minRole string) error {
+	m.logger.Warn("Access denied",
+		zap.String("username", user.Username),
+		zap.String("role", user.Role),
+		zap.String("required_role", minRole),
+		zap.String("path", c.Path()),
+	)
+	return c.Status(fiber.StatusForbidden).JSON(
+		entity.NewErrorResponse("FORBIDDEN", "Role "+minRole+" or higher is required"),
+	)
+}
+
+// RequireMetricsToken protects the metrics route with metrics.token, sent by scrapers as
+// "Authorization: Bearer <token>". A valid X-Admin-Token is accepted too.
+func (m *Auth) RequireMetricsToken() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if m.hasAdminToken(c) {
+			return c.Next()
+		}
+
+		token := strings.TrimSpace(strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "Bearer "))
+		if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(m.config.Metrics.Token)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(
+				entity.NewErrorResponse("UNAUTHORIZED", "A valid metrics token is required"),
+			)
+		}
+
+		return c.Next()
+	}
+}
+
+// hasAdminToken checks the static admin token from config
+func (m *Auth) hasAdminToken(c *fiber.Ctx) bool {
+	expected := m.config.Security.AdminToken
+	if expected == "" {
+		return false
+	}
+
+	token := c.Get(AdminTokenHeader)
+	return subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+// CurrentUser returns the logged-in user of the request, or nil
+func CurrentUser(c *fiber.Ctx) *entity.AuthUser {
+	user, _ := c.Locals(LocalsUser).(*entity.AuthUser)
+	return user
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+
+	"mekari-esign/internal/domain/entity"
+)
+
+// megabyte is the unit of the configured body limits
+const megabyte = 1024 * 1024
+
+// BodyLimit rejects request bodies larger than the limit of the route with 413. routeLimits
+// overrides defaultLimit for exact paths; limits are in MB. The server-wide fiber limit must be
+// at least the largest of them.
+func BodyLimit(defaultLimit int, routeLimits map[string]int) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		limit := defaultLimit
+		if routeLimit, ok := routeLimits[c.Path()]; ok {
+			limit = routeLimit
+		}
+		maxBytes := limit * megabyte
+
+		// Content-Length is known up front; chunked bodies are checked after reading
+		size := c.Request().Header.ContentLength()
+		if size < 0 {
+			size = len(c.Body())
+		}
+
+		if size > maxBytes {
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(
+				entity.NewErrorResponse("PAYLOAD_TOO_LARGE", fmt.Sprintf("Request body exceeds the %d MB limit", limit)),
+			)
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/httpclient"
+	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/infrastructure/repository"
+)
+
+// maxInboundBodyLength caps the request and response bodies saved per request
+const maxInboundBodyLength = 10000
+
+// InboundLog saves the requests received on the API to api_logs (source "api") when
+// logging.inbound is on, so support can see exactly what a caller such as NAV sent
+type InboundLog struct {
+	config     *config.Config
+	apiLogRepo repository.APILogRepository
+	redactor   *logger.Redactor
+	logger     *zap.Logger
+}
+
+func NewInboundLog(
+	cfg *config.Config,
+	apiLogRepo repository.APILogRepository,
+	redactor *logger.Redactor,
+	logger *zap.Logger,
+) *InboundLog {
+	return &InboundLog{
+		config:     cfg,
+		apiLogRepo: apiLogRepo,
+		redactor:   redactor,
+		logger:     logger,
+	}
+}
+
+// Handler returns the middleware. It runs before authentication so rejected requests are
+// saved too; the caller is read once the request has been handled.
+func (m *InboundLog) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !m.config.LoggingSettings().Inbound || !m.logsMethod(c.Method()) {
+			return c.Next()
+		}
+
+		start := time.Now()
+		err := c.Next()
+
+		// Errors are turned into responses by the error handler after this middleware returns
+		status := c.Response().StatusCode()
+		responseBody := "[streamed response]"
+		if !c.Response().IsBodyStream() {
+			responseBody = string(c.Response().Body())
+		}
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+			responseBody = err.Error()
+		}
+
+		apiLog := &entity.APILog{
+			Source:       entity.APILogSourceAPI,
+			Endpoint:     m.endpoint(c),
+			Method:       utils.CopyString(c.Method()),
+			RequestBody:  m.requestSummary(c),
+			ResponseBody: summarizeBody(m.redactor.Body(responseBody)),
+			StatusCode:   status,
+			Duration:     time.Since(start).Milliseconds(),
+			Caller:       requestCaller(c),
+			RequestID:    logger.RequestIDFromContext(c.UserContext()),
+			CreatedAt:    start.UTC(),
+		}
+
+		// Queued for the batch writer, so the response does not wait for the insert
+		if err := m.apiLogRepo.Save(c.UserContext(), apiLog); err != nil {
+			m.logger.Warn("Failed to save inbound API log to database",
+				zap.String("endpoint", apiLog.Endpoint),
+				zap.String("request_id", apiLog.RequestID),
+				zap.Error(err),
+			)
+		}
+
+		return err
+	}
+}
+
+// logsMethod reports whether requests with method are saved (logging.inbound_methods)
+func (m *InboundLog) logsMethod(method string) bool {
+	for _, logged := range m.config.LoggingSettings().InboundMethods {
+		if method == logged {
+			return true
+		}
+	}
+	return false
+}
+
+// endpoint returns the path and query string, with sensitive query values masked
+func (m *InboundLog) endpoint(c *fiber.Ctx) string {
+	endpoint := utils.CopyString(c.Path())
+	query := c.Request().URI().QueryArgs()
+	if query.Len() == 0 {
+		return endpoint
+	}
+
+	var params []string
+	query.VisitAll(func(key, value []byte) {
+		v := string(value)
+		if m.redactor.IsSensitive(string(key)) {
+			v = logger.RedactedValue
+		}
+		params = append(params, string(key)+"="+url.QueryEscape(v))
+	})
+	return endpoint + "?" + strings.Join(params, "&")
+}
+
+// requestSummary returns the request body with embedded documents shortened and sensitive
+// fields masked. Uploads are summarized by their size.
+func (m *InboundLog) requestSummary(c *fiber.Ctx) string {
+	contentType := string(c.Request().Header.ContentType())
+	if strings.HasPrefix(contentType, fiber.MIMEMultipartForm) {
+		return fmt.Sprintf("[multipart form, %d bytes]", c.Request().Header.ContentLength())
+	}
+
+	body := c.Body()
+	if len(body) == 0 {
+		return ""
+	}
+	return summarizeBody(m.redactor.Body(httpclient.TruncateBase64InJSON(string(body), 100)))
+}
+
+// summarizeBody caps a body at maxInboundBodyLength characters
+func summarizeBody(body string) string {
+	if len(body) > maxInboundBodyLength {
+		return body[:maxInboundBodyLength] + "... [truncated]"
+	}
+	return body
+}
+
+// requestCaller names the API key ("key:NAV") or user ("user:alice") that sent the request,
+// or returns "" for unauthenticated requests
+func requestCaller(c *fiber.Ctx) string {
+	if apiKey, ok := c.Locals(LocalsAPIKey).(*entity.APIKey); ok && apiKey != nil {
+		return "key:" + apiKey.Name
+	}
+	if user := CurrentUser(c); user != nil {
+		return "user:" + user.Username
+	}
+	return ""
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/gofiber/fiber/v2"
+
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/i18n"
+)
+
+// LocalsLanguage is the fiber locals key holding the language of the request
+const LocalsLanguage = "language"
+
+// localizedResponse is entity.APIResponse with the data left as sent; the error code stays raw as
+// the fiber error handler writes numeric codes
+type localizedResponse struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Error   *localizedError `json:"error,omitempty"`
+}
+
+type localizedError struct {
+	Code    json.RawMessage     `json:"code"`
+	Message string              `json:"message"`
+	Fields  []entity.FieldError `json:"fields,omitempty"`
+}
+
+// Language picks the language of a request from its Accept-Language header (app.language when
+// none is supported), stores it for the handlers and translates the messages of JSON API
+// responses, including the field errors of validation failures. Response data is not translated.
+func Language(translator i18n.Translator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		lang := translator.Language(c.Get(fiber.HeaderAcceptLanguage))
+		c.Locals(LocalsLanguage, lang)
+		c.Set(fiber.HeaderContentLanguage, lang)
+		c.Vary(fiber.HeaderAcceptLanguage)
+
+		err := c.Next()
+		if lang == i18n.LanguageEnglish {
+			return err
+		}
+		// Write the error response now so its message is translated too
+		if err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				return handlerErr
+			}
+		}
+
+		resp := c.Response()
+		if resp.IsBodyStream() || !bytes.HasPrefix(resp.Header.ContentType(), []byte(fiber.MIMEApplicationJSON)) {
+			return nil
+		}
+		body, ok := localizeBody(translator, lang, resp.Body())
+		if ok {
+			resp.SetBodyRaw(body)
+		}
+		return nil
+	}
+}
+
+// RequestLanguage returns the language of the request picked by Language
+func RequestLanguage(c *fiber.Ctx) string {
+	lang, _ := c.Locals(LocalsLanguage).(string)
+	return lang
+}
+
+// localizeBody translates the messages of an API response body; other JSON bodies are left alone
+func localizeBody(translator i18n.Translator, lang string, body []byte) ([]byte, bool) {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(body, &keys); err != nil {
+		return nil, false
+	}
+	if _, ok := keys["success"]; !ok {
+		return nil, false
+	}
+	for key := range keys {
+		switch key {
+		case "success", "message", "data", "error":
+		default:
+			return nil, false
+		}
+	}
+
+	var payload localizedResponse
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, false
+	}
+	payload.Message = translator.Translate(lang, payload.Message)
+	if payload.Error != nil {
+		payload.Error.Message = translator.Translate(lang, payload.Error.Message)
+		for i := range payload.Error.Fields {
+			payload.Error.Fields[i].Message = translator.Translate(lang, payload.Error.Fields[i].Message)
+		}
+	}
+
+	localized, err := json.Marshal(payload)
+	if err != nil {
+		return nil, false
+	}
+	return localized, true
+}
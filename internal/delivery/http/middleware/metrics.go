@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+
+	"mekari-esign/internal/infrastructure/metrics"
+)
+
+// unmatchedRoute labels requests that matched no route, so scanners cannot inflate the label set
+const unmatchedRoute = "unmatched"
+
+// Metrics records the count, status and latency of every request by route pattern
+// (e.g. /api/v1/esign/documents/:id/timeline) rather than by path
+func Metrics(m *metrics.Metrics) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		m.HTTPInFlight.Inc()
+		defer m.HTTPInFlight.Dec()
+
+		err := c.Next()
+
+		// Errors are turned into responses by the error handler after this middleware returns
+		route := c.Route().Path
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+				if fiberErr.Code == fiber.StatusNotFound {
+					route = unmatchedRoute
+				}
+			}
+		}
+
+		method := utils.CopyString(c.Method())
+		m.HTTPRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+		m.HTTPDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+
+		return err
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+
+	"mekari-esign/internal/infrastructure/logger"
+)
+
+// LocalsRequestID is the fiber locals key holding the request ID
+const LocalsRequestID = "requestid"
+
+// maxRequestIDLength caps incoming IDs so clients cannot bloat logs and api_logs rows
+const maxRequestIDLength = 100
+
+// RequestID assigns every request a correlation ID, reusing an incoming X-Request-ID, echoes it
+// in the response and stores it in the user context so logs, outbound Mekari/NAV calls and
+// api_logs rows of the request all carry the same ID
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestID := c.Get(logger.RequestIDHeader)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = utils.UUIDv4()
+		}
+
+		c.Set(logger.RequestIDHeader, requestID)
+		c.Locals(LocalsRequestID, requestID)
+		c.SetUserContext(logger.WithRequestID(c.UserContext(), requestID))
+
+		return c.Next()
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"go.uber.org/fx"
 
 	"mekari-esign/internal/delivery/http/handler"
+	"mekari-esign/internal/delivery/http/middleware"
 	"mekari-esign/internal/delivery/http/router"
 )
 
@@ -14,6 +15,20 @@ var Module = fx.Module("http",
 		handler.NewOAuthHandler,
 		handler.NewWebhookHandler,
 		handler.NewLogHandler,
+		handler.NewDocsHandler,
+		handler.NewAPIKeyHandler,
+		handler.NewAuthHandler,
+		handler.NewGraphQLHandler,
+		handler.NewDashboardHandler,
+		handler.NewQuotaHandler,
+		handler.NewDiagnosticsHandler,
+		handler.NewStatusHandler,
+		handler.NewUpdateHandler,
+		handler.NewSchedulerHandler,
+		middleware.NewRateLimiter,
+		middleware.NewAPIKeyAuth,
+		middleware.NewAuth,
+		middleware.NewInboundLog,
 		router.NewRouter,
 	),
 )
@@ -1,16 +1,37 @@
 package router
 
 import (
+	"strings"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/compress"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/etag"
 	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/gofiber/fiber/v2/middleware/pprof"
 	"github.com/gofiber/fiber/v2/middleware/recover"
-	"github.com/gofiber/fiber/v2/middleware/requestid"
 
 	"mekari-esign/internal/config"
 	"mekari-esign/internal/delivery/http/handler"
+	"mekari-esign/internal/delivery/http/middleware"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/i18n"
+	applog "mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/infrastructure/metrics"
+)
+
+// Routes with their own body limits
+const (
+	signPath     = "/api/v1/esign/documents/request-sign"
+	signPathV2   = "/api/v2/esign/documents/request-sign"
+	verifyPath   = "/api/v1/esign/verify"
+	verifyPathV2 = "/api/v2/esign/verify"
+	webhookPath  = "/webhook/mekari"
 )
 
+// pprofPrefix is where the pprof middleware serves profiles
+const pprofPrefix = "/debug/pprof"
+
 type Router struct {
 	app            *fiber.App
 	config         *config.Config
@@ -19,6 +40,23 @@ type Router struct {
 	oauthHandler   *handler.OAuthHandler
 	webhookHandler *handler.WebhookHandler
 	logHandler     *handler.LogHandler
+	docsHandler    *handler.DocsHandler
+	apiKeyHandler  *handler.APIKeyHandler
+	authHandler    *handler.AuthHandler
+	graphQLHandler *handler.GraphQLHandler
+	dashHandler    *handler.DashboardHandler
+	quotaHandler   *handler.QuotaHandler
+	diagHandler    *handler.DiagnosticsHandler
+	statusHandler  *handler.StatusHandler
+	updateHandler  *handler.UpdateHandler
+	schedHandler   *handler.SchedulerHandler
+	apiKeyAuth     *middleware.APIKeyAuth
+	auth           *middleware.Auth
+	inboundLog     *middleware.InboundLog
+	rateLimiter    *middleware.RateLimiter
+	metrics        *metrics.Metrics
+	reporter       *applog.Reporter
+	translator     i18n.Translator
 }
 
 func NewRouter(
@@ -28,10 +66,29 @@ func NewRouter(
 	oauthHandler *handler.OAuthHandler,
 	webhookHandler *handler.WebhookHandler,
 	logHandler *handler.LogHandler,
+	docsHandler *handler.DocsHandler,
+	apiKeyHandler *handler.APIKeyHandler,
+	authHandler *handler.AuthHandler,
+	graphQLHandler *handler.GraphQLHandler,
+	dashHandler *handler.DashboardHandler,
+	quotaHandler *handler.QuotaHandler,
+	diagHandler *handler.DiagnosticsHandler,
+	statusHandler *handler.StatusHandler,
+	updateHandler *handler.UpdateHandler,
+	schedHandler *handler.SchedulerHandler,
+	apiKeyAuth *middleware.APIKeyAuth,
+	auth *middleware.Auth,
+	inboundLog *middleware.InboundLog,
+	rateLimiter *middleware.RateLimiter,
+	meter *metrics.Metrics,
+	reporter *applog.Reporter,
+	translator i18n.Translator,
 ) *Router {
 	app := fiber.New(fiber.Config{
-		AppName:      cfg.App.Name,
-		ErrorHandler: customErrorHandler,
+		AppName:           cfg.App.Name,
+		ErrorHandler:      customErrorHandler,
+		BodyLimit:         cfg.App.MaxBodyLimit() * 1024 * 1024, // Per-route limits are enforced by middleware.BodyLimit
+		StreamRequestBody: cfg.App.StreamRequestBody,
 	})
 
 	return &Router{
@@ -42,42 +99,186 @@ func NewRouter(
 		oauthHandler:   oauthHandler,
 		webhookHandler: webhookHandler,
 		logHandler:     logHandler,
+		docsHandler:    docsHandler,
+		apiKeyHandler:  apiKeyHandler,
+		authHandler:    authHandler,
+		graphQLHandler: graphQLHandler,
+		dashHandler:    dashHandler,
+		quotaHandler:   quotaHandler,
+		diagHandler:    diagHandler,
+		statusHandler:  statusHandler,
+		updateHandler:  updateHandler,
+		schedHandler:   schedHandler,
+		apiKeyAuth:     apiKeyAuth,
+		auth:           auth,
+		inboundLog:     inboundLog,
+		rateLimiter:    rateLimiter,
+		metrics:        meter,
+		reporter:       reporter,
+		translator:     translator,
 	}
 }
 
 func (r *Router) Setup() *fiber.App {
 	// Middleware
-	r.app.Use(recover.New())
-	r.app.Use(requestid.New())
+	r.app.Use(recover.New(recover.Config{
+		// Report panics before they become 500 responses
+		EnableStackTrace: r.reporter.Enabled(),
+		StackTraceHandler: func(c *fiber.Ctx, e interface{}) {
+			r.reporter.CapturePanic(c.UserContext(), e, map[string]string{
+				"method": c.Method(),
+				"route":  c.Route().Path,
+			})
+		},
+	}))
+	r.app.Use(middleware.RequestID())
+	if r.config.Metrics.Enabled {
+		r.app.Use(middleware.Metrics(r.metrics))
+	}
+	corsConfig := r.config.App.CORS
 	r.app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowMethods: "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders: "Origin,Content-Type,Accept,Authorization",
+		AllowOrigins:     strings.Join(corsConfig.AllowOrigins, ","),
+		AllowMethods:     strings.Join(corsConfig.AllowMethods, ","),
+		AllowHeaders:     strings.Join(corsConfig.AllowHeaders, ","),
+		ExposeHeaders:    strings.Join(corsConfig.ExposeHeaders, ","),
+		AllowCredentials: corsConfig.AllowCredentials,
+		MaxAge:           corsConfig.MaxAge,
 	}))
 
+	// Response compression (event streams are skipped, they must not be buffered)
+	if r.config.App.Compress {
+		r.app.Use(compress.New(compress.Config{
+			Level: compress.LevelBestSpeed,
+			Next: func(c *fiber.Ctx) bool {
+				return strings.Contains(c.Get(fiber.HeaderAccept), "text/event-stream")
+			},
+		}))
+	}
+
+	// Messages in the language of the request; runs inside compression to see the plain body
+	r.app.Use(middleware.Language(r.translator))
+
+	// ETag/If-None-Match for large JSON reads polled by the dashboard (304 when unchanged).
+	// Not for streamed responses, computing the tag would buffer the whole body.
+	conditional := etag.New(etag.Config{Weak: true})
+
+	// Request body limits (sign requests and webhooks carry documents and get their own)
+	r.app.Use(middleware.BodyLimit(r.config.App.BodyLimit, map[string]int{
+		signPath:     r.config.App.SignBodyLimit,
+		signPathV2:   r.config.App.SignBodyLimit,
+		verifyPath:   r.config.App.SignBodyLimit,
+		verifyPathV2: r.config.App.SignBodyLimit,
+		webhookPath:  r.config.App.WebhookBodyLimit,
+	}))
+
+	// Per-IP rate limiting (Redis-backed, shared across instances)
+	r.app.Use(r.rateLimiter.PerIP())
+
 	if r.config.IsDevelopment() {
 		r.app.Use(logger.New(logger.Config{
-			Format: "[${time}] ${status} - ${latency} ${method} ${path}\n",
+			Format: "[${time}] ${status} - ${latency} ${method} ${path} ${locals:requestid}\n",
 		}))
 	}
 
 	// Health check route
 	r.app.Get("/health", r.healthHandler.Health)
+	r.app.Get("/health/live", r.healthHandler.Live)
 
-	// Log viewer route (HTML page)
-	r.app.Get("/logs", r.logHandler.LogViewer)
+	// Public status summary for status pages
+	r.app.Get("/status", r.statusHandler.Status)
+
+	// Prometheus metrics (metrics.token for scrapers, otherwise an operator login or the admin token)
+	if r.config.Metrics.Enabled {
+		if r.config.Metrics.Token != "" {
+			r.app.Get(r.config.Metrics.Path, r.auth.RequireMetricsToken(), r.metrics.Handler())
+		} else {
+			r.app.Get(r.config.Metrics.Path, r.auth.Handler(), r.auth.RequireRole(entity.RoleOperator), r.metrics.Handler())
+		}
+	}
+
+	// API documentation (Swagger UI)
+	if r.config.Docs.Enabled {
+		r.app.Get("/docs", r.docsHandler.SwaggerUI)
+		r.app.Get("/docs/swagger.json", r.docsHandler.SwaggerSpec)
+		r.app.Get("/docs/assets/:file", r.docsHandler.SwaggerAsset)
+	}
+
+	// Log viewer route (HTML page). In basic auth mode the page itself is protected so the
+	// browser prompts once; in login mode the page handles the login.
+	if r.config.LogViewer.Enabled {
+		if r.config.LogViewer.Auth == config.LogViewerAuthBasic {
+			r.app.Get("/logs", r.auth.Handler(), r.auth.RequireLogViewer(), r.logHandler.LogViewer)
+		} else {
+			r.app.Get("/logs", r.logHandler.LogViewer)
+		}
+	}
+
+	// Operations dashboard page (data comes from /admin/dashboard, which requires a login)
+	if r.config.Dashboard.Enabled {
+		r.app.Get("/dashboard", r.dashHandler.Dashboard)
+	}
 
 	// OAuth callback route (must be at root level for redirect)
 	r.app.Get("/redirect/oauth", r.oauthHandler.OAuthCallback)
 
 	// Webhook routes (at root level for external callbacks)
-	r.app.Post("/webhook/mekari", r.webhookHandler.MekariCallback)
+	r.app.Post(webhookPath, r.webhookHandler.MekariCallback)
+
+	// Auth routes (user login)
+	auth := r.app.Group("/auth")
+	{
+		auth.Post("/login", r.authHandler.Login)
+		auth.Get("/me", r.auth.Handler(), r.authHandler.Me)
+	}
+
+	// Admin routes (role-based, X-Admin-Token grants admin)
+	admin := r.app.Group("/admin", r.auth.Handler())
+	{
+		operator := r.auth.RequireRole(entity.RoleOperator)
+		admin.Get("/webhook-events", operator, r.webhookHandler.ListWebhookEvents)
+		admin.Post("/webhook-events/:id/replay", operator, r.webhookHandler.ReplayWebhookEvent)
+		admin.Get("/document-mappings", operator, conditional, r.esignHandler.ListDocumentMappings)
+		admin.Get("/document-mappings/:id", operator, conditional, r.esignHandler.GetDocumentMapping)
+		admin.Patch("/document-mappings/:id", operator, r.esignHandler.UpdateDocumentMapping)
+		admin.Delete("/document-mappings/:id", operator, r.esignHandler.DeleteDocumentMapping)
+		if r.config.Dashboard.Enabled {
+			admin.Get("/dashboard/summary", operator, conditional, r.dashHandler.GetSummary)
+			admin.Get("/dashboard/quota", operator, r.dashHandler.GetQuota)
+			admin.Get("/dashboard/tokens", operator, r.dashHandler.GetTokenExpiry)
+		}
+
+		adminOnly := r.auth.RequireRole(entity.RoleAdmin)
+		admin.Get("/api-keys", adminOnly, r.apiKeyHandler.ListAPIKeys)
+		admin.Post("/api-keys", adminOnly, r.apiKeyHandler.CreateAPIKey)
+		admin.Delete("/api-keys/:id", adminOnly, r.apiKeyHandler.RevokeAPIKey)
+		admin.Delete("/oauth-tokens", adminOnly, r.oauthHandler.DeleteToken)
+		admin.Get("/users", adminOnly, r.authHandler.ListUsers)
+		admin.Post("/users", adminOnly, r.authHandler.CreateUser)
+		admin.Get("/config", adminOnly, r.authHandler.GetConfig)
+
+		// Registered ahead of the /api/v1 group so the admin token works without an API key
+		r.app.Post("/api/v1/admin/config/reload", r.auth.Handler(), adminOnly, r.authHandler.ReloadConfig)
+		r.app.Get("/api/v1/admin/runtime", r.auth.Handler(), adminOnly, r.diagHandler.Runtime)
+		r.app.Get("/api/v1/admin/update/status", r.auth.Handler(), adminOnly, r.updateHandler.GetStatus)
+		r.app.Get("/api/v1/admin/update/history", r.auth.Handler(), adminOnly, r.updateHandler.GetHistory)
+		r.app.Post("/api/v1/admin/update/apply", r.auth.Handler(), adminOnly, r.updateHandler.Apply)
+		r.app.Post("/api/v1/admin/update/rollback", r.auth.Handler(), adminOnly, r.updateHandler.Rollback)
+		r.app.Get("/api/v1/admin/scheduler/jobs", r.auth.Handler(), adminOnly, r.schedHandler.ListJobs)
+		r.app.Post("/api/v1/admin/scheduler/jobs/:name/run", r.auth.Handler(), adminOnly, r.schedHandler.RunJob)
+
+		// CPU, heap, goroutine and other profiles under /debug/pprof
+		if r.config.Diagnostics.Pprof {
+			r.app.Use(pprofPrefix, r.auth.Handler(), adminOnly)
+			r.app.Use(pprof.New())
+		}
+	}
 
-	// API v1 routes
-	api := r.app.Group("/api/v1")
+	// API v1 routes (protected by API key when enabled, or a user login). Requests are saved to
+	// api_logs when logging.inbound is on, including the ones rejected by authentication.
+	api := r.app.Group("/api/v1", r.inboundLog.Handler(), r.auth.Handler(), r.apiKeyAuth.Handler())
 	{
-		// OAuth routes
-		oauth := api.Group("/oauth")
+		// OAuth routes (they hand out live Mekari tokens, so logged-in users need operator)
+		oauth := api.Group("/oauth", r.auth.RequireUserRole(entity.RoleOperator))
 		{
 			oauth.Get("/check", r.oauthHandler.CheckCode)
 			oauth.Get("/authorize", r.oauthHandler.CheckCodeAndRedirect)
@@ -85,27 +286,60 @@ func (r *Router) Setup() *fiber.App {
 			oauth.Post("/exchange", r.oauthHandler.ExchangeCode)
 			oauth.Post("/refresh", r.oauthHandler.RefreshAccessToken)
 			oauth.Get("/token", r.oauthHandler.GetToken)
+			oauth.Get("/history", r.oauthHandler.GetTokenHistory)
 		}
 
-		// eSign routes
-		esign := api.Group("/esign")
-		{
-			esign.Get("/profile", r.esignHandler.GetProfile)
-			esign.Get("/documents", r.esignHandler.GetDocuments)
-			esign.Post("/documents/request-sign", r.esignHandler.GlobalRequestSign)
-		}
+		// eSign routes (frozen, NAV codeunits depend on these shapes)
+		r.registerEsignRoutes(api.Group("/esign"), r.esignHandler.GlobalRequestSign, conditional)
 
 		// Log routes
-		logs := api.Group("/logs")
+		if r.config.LogViewer.Enabled {
+			logs := api.Group("/logs", r.auth.RequireLogViewer())
+			{
+				logs.Get("", conditional, r.logHandler.GetLogs)
+				logs.Get("/search", conditional, r.logHandler.SearchLogs)
+				logs.Get("/by-document/:id", conditional, r.logHandler.GetDocumentLogs)
+				logs.Get("/export", r.logHandler.ExportLogs)
+			}
+		}
+
+		// GraphQL (read-only documents and logs)
+		graphQL := api.Group("/graphql", r.auth.RequireRole(entity.RoleReadOnly))
 		{
-			logs.Get("", r.logHandler.GetLogs)
-			logs.Get("/search", r.logHandler.SearchLogs)
+			graphQL.Get("", r.graphQLHandler.Query)
+			graphQL.Post("", r.graphQLHandler.Query)
 		}
 	}
 
+	// API v2 routes: same auth and handlers as v1 except for the sign request shape. OAuth,
+	// logs and GraphQL stay on v1.
+	apiV2 := r.app.Group("/api/v2", r.inboundLog.Handler(), r.auth.Handler(), r.apiKeyAuth.Handler())
+	{
+		r.registerEsignRoutes(apiV2.Group("/esign"), r.esignHandler.GlobalRequestSignV2, conditional)
+	}
+
 	return r.app
 }
 
+// registerEsignRoutes registers the eSign routes shared by the API versions. Routes that send
+// documents to Mekari need operator from logged-in users; API keys keep calling them. Resubmits
+// are operator actions like the admin requeues, and signing links let anyone holding them sign,
+// so both need an operator login or the admin token.
+func (r *Router) registerEsignRoutes(esign fiber.Router, requestSign, conditional fiber.Handler) {
+	operatorUser := r.auth.RequireUserRole(entity.RoleOperator)
+	operator := r.auth.RequireRole(entity.RoleOperator)
+	esign.Get("/profile", r.esignHandler.GetProfile)
+	esign.Get("/quota", r.quotaHandler.GetQuota)
+	esign.Get("/documents", conditional, r.esignHandler.GetDocuments)
+	esign.Post("/documents/request-sign", operatorUser, requestSign)
+	esign.Get("/documents/:id/timeline", conditional, r.esignHandler.GetDocumentTimeline)
+	esign.Get("/documents/:id/events", r.esignHandler.StreamDocumentEvents)
+	esign.Get("/documents/:id/signing-links", operator, r.esignHandler.GetSigningLinks)
+	esign.Post("/documents/:id/resubmit", operator, r.esignHandler.ResubmitDocument)
+	esign.Post("/verify", operatorUser, r.esignHandler.VerifyDocument)
+	esign.Get("/status/by-invoice/:invoiceNo", r.esignHandler.GetInvoiceStatus)
+}
+
 func (r *Router) GetApp() *fiber.App {
 	return r.app
 }
@@ -0,0 +1,163 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	"mekari-esign/internal/domain/entity"
+)
+
+// validate is safe for concurrent use and caches struct metadata
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// Report fields by their JSON name so errors match the request body
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	v.RegisterStructValidation(validateGlobalSignRequest, entity.GlobalSignRequest{})
+	v.RegisterStructValidation(validateGlobalSignRequestV2, entity.GlobalSignRequestV2{})
+
+	return v
+}
+
+// validateGlobalSignRequest requires signers unless the request is stamping only, which takes no
+// attachments
+func validateGlobalSignRequest(sl validator.StructLevel) {
+	req := sl.Current().Interface().(entity.GlobalSignRequest)
+	if !req.IsStampingOnly() && len(req.Signers) == 0 {
+		sl.ReportError(req.Signers, "signers", "Signers", "required", "")
+	}
+	if req.IsStampingOnly() && len(req.Attachments) > 0 {
+		sl.ReportError(req.Attachments, "attachments", "Attachments", "excluded", "")
+	}
+	if req.EnforceOrder {
+		validateSignerOrder(sl, req.Signers)
+	}
+}
+
+// validateGlobalSignRequestV2 requires signers unless stamping only; stamping only works on the
+// already signed document, so an inline document or attachments are rejected there
+func validateGlobalSignRequestV2(sl validator.StructLevel) {
+	req := sl.Current().Interface().(entity.GlobalSignRequestV2)
+	if req.IsStampingOnly() {
+		if req.Document != nil {
+			sl.ReportError(req.Document, "document", "Document", "excluded", "")
+		}
+		if len(req.Attachments) > 0 {
+			sl.ReportError(req.Attachments, "attachments", "Attachments", "excluded", "")
+		}
+		return
+	}
+	if len(req.Signers) == 0 {
+		sl.ReportError(req.Signers, "signers", "Signers", "required", "")
+	}
+	if req.Document == nil && req.InvoiceNumber == "" {
+		sl.ReportError(req.InvoiceNumber, "invoice_number", "InvoiceNumber", "required_without", "document")
+	}
+	if req.EnforceOrder {
+		validateSignerOrder(sl, req.Signers)
+	}
+}
+
+// validateSignerOrder requires the orders of an enforce_order request to be unique and to run from
+// 1 to the number of signers, so Mekari has a next signer at every step
+func validateSignerOrder(sl validator.StructLevel, signers []entity.SignerRequest) {
+	seen := make(map[int]bool, len(signers))
+	for i, signer := range signers {
+		name := fmt.Sprintf("signers[%d].order", i)
+		switch {
+		case seen[signer.Order]:
+			sl.ReportError(signer.Order, name, "Order", "unique", "")
+		case signer.Order < 1 || signer.Order > len(signers):
+			sl.ReportError(signer.Order, name, "Order", "signer_order", strconv.Itoa(len(signers)))
+		}
+		seen[signer.Order] = true
+	}
+}
+
+// Validate checks the struct tags of v and returns one error per invalid field, or nil
+func Validate(v interface{}) []entity.FieldError {
+	err := validate.Struct(v)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return []entity.FieldError{{Field: "", Message: err.Error()}}
+	}
+
+	fields := make([]entity.FieldError, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		fields = append(fields, entity.FieldError{
+			Field:   fieldPath(fe.Namespace()),
+			Message: message(fe),
+		})
+	}
+
+	return fields
+}
+
+// fieldPath strips the root struct name from a namespace, e.g. "GlobalSignRequest.signers[0].email"
+func fieldPath(namespace string) string {
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+// message renders a human readable message for a failed tag
+func message(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "numeric":
+		return "must contain digits only"
+	case "base64":
+		return "must be base64 encoded"
+	case "excluded":
+		return "is not allowed for stamping only requests"
+	case "required_without":
+		return fmt.Sprintf("is required without %s", fe.Param())
+	case "min":
+		if fe.Kind() == reflect.String || fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must contain at least %s items/characters", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		if fe.Kind() == reflect.String || fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must contain at most %s items/characters", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fe.Param())
+	case "gte":
+		return fmt.Sprintf("must be greater than or equal to %s", fe.Param())
+	case "unique":
+		return "must be unique"
+	case "signer_order":
+		return fmt.Sprintf("must be between 1 and %s with enforce_order, without gaps", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.ReplaceAll(fe.Param(), " ", ", "))
+	default:
+		return fmt.Sprintf("failed %q validation", fe.Tag())
+	}
+}
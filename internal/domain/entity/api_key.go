@@ -0,0 +1,32 @@
+package entity
+
+import "time"
+
+// APIKey represents a client API key. Only the SHA-256 hash of the key is stored.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	KeyPrefix  string     `json:"key_prefix"` // First characters of the key, for identification
+	KeyHash    string     `json:"-"`
+	RateLimit  int        `json:"rate_limit"` // Requests per minute, 0 = use default
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// IsRevoked returns true if the key has been revoked
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
+
+// CreateAPIKeyRequest represents the request to create an API key
+type CreateAPIKeyRequest struct {
+	Name      string `json:"name" validate:"required,max=255"`
+	RateLimit int    `json:"rate_limit,omitempty" validate:"gte=0"`
+}
+
+// CreateAPIKeyResponse contains the plaintext key, returned only once at creation
+type CreateAPIKeyResponse struct {
+	Key    string  `json:"key"`
+	APIKey *APIKey `json:"api_key"`
+}
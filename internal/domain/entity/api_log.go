@@ -2,21 +2,82 @@ package entity
 
 import "time"
 
-// APILog represents a log entry for API requests to Mekari
+// API log sources
+const (
+	APILogSourceMekari  = "mekari"  // Calls to the Mekari API
+	APILogSourceNAV     = "nav"     // Log entry updates sent to NAV
+	APILogSourceInbound = "inbound" // Mekari webhook callbacks (by-document view only, stored in webhook_events)
+	APILogSourceAPI     = "api"     // Requests received on /api/v1 and /api/v2 (logging.inbound)
+)
+
+// APILog represents a log entry for API requests to Mekari and NAV, or received on the API
 type APILog struct {
 	ID           int64     `json:"id"`
 	Endpoint     string    `json:"endpoint"`
 	InvoiceNo    string    `json:"invoice_no"`
 	EntryNo      int       `json:"entry_no"`
+	DocumentID   string    `json:"document_id,omitempty"`
+	Source       string    `json:"source"` // One of the APILogSource* values
 	Method       string    `json:"method"`
 	RequestBody  string    `json:"request_body"`
 	ResponseBody string    `json:"response_body"`
 	StatusCode   int       `json:"status_code"`
 	Duration     int64     `json:"duration_ms"`
 	Email        string    `json:"email,omitempty"`
+	Caller       string    `json:"caller,omitempty"`     // API key or user that sent an api request, e.g. "key:NAV"
+	RequestID    string    `json:"request_id,omitempty"` // Correlation ID of the inbound request or webhook that made the call
 	CreatedAt    time.Time `json:"created_at"`
 }
 
+// Sortable API log columns
+const (
+	APILogSortCreatedAt = "created_at"
+	APILogSortStatus    = "status_code"
+	APILogSortDuration  = "duration_ms"
+)
+
+// APILogFilter holds filters, sorting and pagination for listing API logs
+type APILogFilter struct {
+	Method      string     // Exact HTTP method (case-insensitive)
+	StatusCode  int        // Exact status code, 0 = any
+	StatusClass int        // Status class (2 = 2xx, 4 = 4xx, ...), 0 = any
+	Email       string     // Exact email (case-insensitive)
+	Caller      string     // Exact caller of api requests, e.g. "key:NAV" (case-insensitive)
+	Invoice     string     // Invoice number, matched against invoice_no, endpoint and request body
+	RequestID   string     // Exact request ID
+	DocumentID  string     // Exact document ID
+	Source      string     // One of the APILogSource* values
+	From        *time.Time // Created at or after
+	To          *time.Time // Created before
+	Sort        string     // One of the APILogSort* columns (default: created_at)
+	Desc        bool       // Sort descending
+	Limit       int
+	Offset      int    // Offset pagination, ignored when Cursor is set
+	Cursor      string // Opaque cursor from a previous page (keyset pagination)
+}
+
+// APILogPage is a page of API logs
+type APILogPage struct {
+	Logs       []APILog `json:"logs"`
+	HasMore    bool     `json:"has_more"`
+	NextCursor string   `json:"next_cursor,omitempty"` // Pass as cursor to fetch the next page
+}
+
+// DocumentTraffic lists the webhook callbacks, Mekari calls and NAV updates of one document
+type DocumentTraffic struct {
+	DocumentID string         `json:"document_id"`
+	InvoiceNo  string         `json:"invoice_no,omitempty"`
+	Entries    []TrafficEntry `json:"entries"` // Oldest first
+}
+
+// TrafficEntry is an api_logs row, or a webhook callback when Source is inbound
+type TrafficEntry struct {
+	APILog
+	WebhookStatus string `json:"webhook_status,omitempty"` // Processing status of an inbound callback
+	Attempts      int    `json:"attempts,omitempty"`
+	LastError     string `json:"last_error,omitempty"`
+}
+
 // NAVAPILog represents the API log entry to send to NAV (MekariApiLogEntries)
 type NAVAPILog struct {
 	StatusDescription string `json:"Status_Description"` // SUCCESS or ERROR
@@ -8,7 +8,14 @@ type APIResponse struct {
 }
 
 type APIError struct {
-	Code    string `json:"code"`
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields,omitempty"` // Field-level errors for VALIDATION_ERROR
+}
+
+// FieldError describes a single invalid request field
+type FieldError struct {
+	Field   string `json:"field"` // JSON path, e.g. signers[0].email
 	Message string `json:"message"`
 }
 
@@ -30,3 +37,10 @@ func NewErrorResponse(code string, message string) *APIResponse {
 		},
 	}
 }
+
+// NewValidationErrorResponse builds a 422 response body listing the invalid fields
+func NewValidationErrorResponse(fields []FieldError) *APIResponse {
+	resp := NewErrorResponse("VALIDATION_ERROR", "Request validation failed")
+	resp.Error.Fields = fields
+	return resp
+}
@@ -0,0 +1,58 @@
+package entity
+
+import "time"
+
+// Token statuses reported on the dashboard
+const (
+	TokenStatusValid         = "valid"
+	TokenStatusAccessExpired = "access_expired" // Renewed with the refresh token on next use
+	TokenStatusExpired       = "expired"        // Re-authorization required
+	TokenStatusNoToken       = "no_token"       // Code saved but never exchanged
+)
+
+// DashboardSummary aggregates document and processing state for the operations dashboard
+type DashboardSummary struct {
+	Documents      DocumentCounts  `json:"documents"`
+	Folders        DocumentFolders `json:"folders"`
+	StuckDocuments []StuckDocument `json:"stuck_documents"`
+	WebhookQueue   map[string]int  `json:"webhook_queue"` // Webhook events per processing status
+	RecentFailures RecentFailures  `json:"recent_failures"`
+	GeneratedAt    time.Time       `json:"generated_at"`
+}
+
+// DocumentCounts is the number of files in each document folder
+type DocumentCounts struct {
+	Ready    int `json:"ready"`
+	Progress int `json:"progress"`
+	Finish   int `json:"finish"`
+}
+
+// DocumentFolders are the folders the counts were taken from (NAV setup or config)
+type DocumentFolders struct {
+	Ready    string `json:"ready"`
+	Progress string `json:"progress"`
+	Finish   string `json:"finish"`
+}
+
+// StuckDocument is a document that stayed in the progress folder too long
+type StuckDocument struct {
+	Filename string    `json:"filename"`
+	Since    time.Time `json:"since"` // When the file was moved to (or last updated in) the progress folder
+	AgeHours int       `json:"age_hours"`
+}
+
+// RecentFailures lists the latest failed webhook events and document errors
+type RecentFailures struct {
+	WebhookEvents  []WebhookEvent  `json:"webhook_events"`
+	DocumentErrors []DocumentEvent `json:"document_errors"`
+}
+
+// TokenExpiry is the OAuth token state of an email, without the secrets
+type TokenExpiry struct {
+	Email                 string     `json:"email"`
+	Status                string     `json:"status"`
+	HasCode               bool       `json:"has_code"`
+	AccessTokenExpiresAt  *time.Time `json:"access_token_expires_at,omitempty"`
+	RefreshTokenExpiresAt *time.Time `json:"refresh_token_expires_at,omitempty"` // Estimated from oauth.refresh_token_age_days
+	UpdatedAt             time.Time  `json:"updated_at"`
+}
@@ -19,6 +19,12 @@ type DocumentListResponse struct {
 	Meta    *Meta      `json:"meta,omitempty"`
 }
 
+// DocumentDetailResponse is the Mekari response for one document, whose attributes are the ones
+// webhooks carry
+type DocumentDetailResponse struct {
+	Data *WebhookData `json:"data"`
+}
+
 type Meta struct {
 	Page       int `json:"page"`
 	PerPage    int `json:"per_page"`
@@ -0,0 +1,65 @@
+package entity
+
+import "time"
+
+// Document event types recorded for the document timeline
+const (
+	DocumentEventSubmitted        = "submitted"
+	DocumentEventSignerSigned     = "signer_signed"
+	DocumentEventSigningCompleted = "signing_completed"
+	DocumentEventStampRequested   = "stamp_requested"
+	DocumentEventStamped          = "stamped"
+	DocumentEventSavedToFinish    = "saved_to_finish"
+	DocumentEventAuditTrailSaved  = "audit_trail_saved"
+	DocumentEventError            = "error"
+	DocumentEventReminderSent     = "reminder_sent"
+	DocumentEventExpired          = "expired"
+	DocumentEventVoided           = "voided"
+	DocumentEventDeclined         = "declined"
+	DocumentEventResubmitted      = "resubmitted"
+	DocumentEventAttachmentSigned = "attachment_signed"
+	DocumentEventMappingUpdated   = "mapping_updated"
+	DocumentEventMappingDeleted   = "mapping_deleted"
+)
+
+// DocumentEvent represents a single step in a document's lifecycle
+type DocumentEvent struct {
+	ID          int64     `json:"id"`
+	DocumentID  string    `json:"document_id"`
+	InvoiceNo   string    `json:"invoice_no,omitempty"`
+	EntryNo     int       `json:"entry_no,omitempty"`
+	EventType   string    `json:"event_type"`
+	Description string    `json:"description"`
+	Actor       string    `json:"actor,omitempty"` // Signer email or system component
+	DedupeKey   string    `json:"-"`               // Prevents the same milestone being recorded twice
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// DocumentTimeline represents the ordered events of a document
+type DocumentTimeline struct {
+	DocumentID string          `json:"document_id"`
+	InvoiceNo  string          `json:"invoice_no,omitempty"`
+	EntryNo    int             `json:"entry_no,omitempty"`
+	Events     []DocumentEvent `json:"events"`
+}
+
+// Invoice processing states, derived from the timeline of the document last submitted for it
+const (
+	InvoiceStateSubmitted = "submitted" // Sent to Mekari, nobody signed yet
+	InvoiceStateSigning   = "signing"   // Signed by some of the signers
+	InvoiceStateStamping  = "stamping"  // Signed by all, waiting for the e-meterai
+	InvoiceStateFinished  = "finished"
+	InvoiceStateFailed    = "failed" // Expired, voided, declined or stopped by an error
+)
+
+// InvoiceStatus is the processing state of an invoice, polled by NAV
+type InvoiceStatus struct {
+	InvoiceNo    string    `json:"invoice_no"`
+	State        string    `json:"state"`
+	DocumentID   string    `json:"document_id"`
+	EntryNo      int       `json:"entry_no,omitempty"`
+	FileLocation string    `json:"file_location"` // Path of the file in this state; the folder when the file name is unknown
+	LastEvent    string    `json:"last_event"`    // Type of the newest timeline event
+	Detail       string    `json:"detail"`        // Description of the newest timeline event
+	UpdatedAt    time.Time `json:"updated_at"`
+}
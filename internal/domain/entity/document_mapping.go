@@ -0,0 +1,204 @@
+package entity
+
+import "time"
+
+// DocumentMapping stores document info for webhook processing
+type DocumentMapping struct {
+	DocumentID       string            `json:"document_id"`
+	Email            string            `json:"email"`
+	InvoiceNumber    string            `json:"invoice_number"`
+	Filename         string            `json:"filename"`
+	StampPositions   *StampPosition    `json:"stamp_positions,omitempty"`
+	Stamps           []StampPosition   `json:"stamps,omitempty"` // Multiple positions (v2), take precedence over StampPositions
+	DocumentDeadline *DocumentDeadline `json:"document_deadline,omitempty"`
+	EntryNo          int               `json:"entry_no"`
+	Signing          bool              `json:"signing"`
+	Stamping         bool              `json:"stamping"`
+	EnforceOrder     bool              `json:"enforce_order,omitempty"`   // Signers sign in order, see WaitingSigners
+	StampPlacement   string            `json:"stamp_placement,omitempty"` // fixed, auto or anchor (default: stamping.placement)
+
+	// Deadline tracking
+	Signers       []SignerRequest `json:"signers,omitempty"`        // Kept to send the document again when it expires
+	SubmittedAt   *time.Time      `json:"submitted_at,omitempty"`   // When the document was sent for signing
+	ExpiresAt     *time.Time      `json:"expires_at,omitempty"`     // Signing deadline, nil when the request set none
+	SigningStatus string          `json:"signing_status,omitempty"` // Last signing status reported by Mekari
+	RemindersSent int             `json:"reminders_sent,omitempty"` // Deadline reminders sent, see deadline.reminders
+	Resubmits     int             `json:"resubmits,omitempty"`      // Resubmits before this document, plus failed automatic attempts
+	ResubmittedAs string          `json:"resubmitted_as,omitempty"` // Document ID of the new request after expiry
+	SigningLinks  []SigningLink   `json:"signing_links,omitempty"`  // Last signing links reported by Mekari, to send them again
+
+	// Attachments sent as linked documents (attachment_mode separate)
+	ParentDocumentID string           `json:"parent_document_id,omitempty"` // Set on the mapping of an attachment
+	Attachments      []LinkedDocument `json:"attachments,omitempty"`        // Set on the mapping of the main document
+}
+
+// SigningLink is the link a signer opens to sign a document
+type SigningLink struct {
+	Name       string `json:"name"`
+	Email      string `json:"email"`
+	Order      int    `json:"order,omitempty"`
+	Status     string `json:"status"`      // pending or completed
+	SigningURL string `json:"signing_url"` // Empty when Mekari sent none, e.g. once signed
+}
+
+// NewSigningLinks returns the signing links of the signers of a webhook or document detail, nil
+// when none of them has a link
+func NewSigningLinks(signers []WebhookSigner) []SigningLink {
+	var links []SigningLink
+	found := false
+	for _, signer := range signers {
+		link := SigningLink{Name: signer.Name, Email: signer.Email, Order: signer.Order, Status: signer.Status}
+		if signer.SigningURL != nil && *signer.SigningURL != "" {
+			link.SigningURL = *signer.SigningURL
+			found = true
+		}
+		links = append(links, link)
+	}
+	if !found {
+		return nil
+	}
+	return links
+}
+
+// DocumentSigningLinks are the signing links of a document, served by
+// GET /api/v1/esign/documents/:id/signing-links
+type DocumentSigningLinks struct {
+	DocumentID    string        `json:"document_id"`
+	InvoiceNumber string        `json:"invoice_number,omitempty"`
+	SigningStatus string        `json:"signing_status,omitempty"`
+	Source        string        `json:"source"` // stored (from webhooks) or mekari (fetched now)
+	Signers       []SigningLink `json:"signers"`
+}
+
+// Sources of DocumentSigningLinks
+const (
+	SigningLinksStored = "stored"
+	SigningLinksMekari = "mekari"
+)
+
+// LinkedDocument is an attachment sent for signing as its own document with the main document
+type LinkedDocument struct {
+	DocumentID    string `json:"document_id"`
+	Filename      string `json:"filename"`
+	SigningStatus string `json:"signing_status,omitempty"` // Last signing status reported by Mekari
+}
+
+// Signing statuses reported by Mekari in webhooks
+const (
+	SigningStatusCompleted = "completed"
+	SigningStatusExpired   = "expired"
+	SigningStatusDeclined  = "declined"
+	SigningStatusVoided    = "voided"
+	SigningStatusCancelled = "cancelled"
+)
+
+// IsVoidedStatus reports whether a signing status means the document was voided in Mekari
+func IsVoidedStatus(status string) bool {
+	return status == SigningStatusVoided || status == SigningStatusCancelled
+}
+
+// AwaitsSignatures reports whether the document is still out for signing
+func (m *DocumentMapping) AwaitsSignatures() bool {
+	switch m.SigningStatus {
+	case "", "pending", "in_progress":
+		return m.Signing
+	}
+	return false
+}
+
+// AggregateSigningStatus returns the signing status of the entry given the status of the main
+// document: a voided, expired or declined attachment ends the entry like the main document would,
+// and the entry is completed once the main document and all attachments are.
+func (m *DocumentMapping) AggregateSigningStatus(status string) string {
+	if len(m.Attachments) == 0 {
+		return status
+	}
+
+	statuses := []string{status}
+	for _, attachment := range m.Attachments {
+		statuses = append(statuses, attachment.SigningStatus)
+	}
+	for _, ended := range []func(string) bool{
+		IsVoidedStatus,
+		func(s string) bool { return s == SigningStatusExpired },
+		func(s string) bool { return s == SigningStatusDeclined },
+	} {
+		for _, s := range statuses {
+			if ended(s) {
+				return s
+			}
+		}
+	}
+
+	for _, s := range statuses {
+		if s != SigningStatusCompleted {
+			if status == SigningStatusCompleted {
+				return "in_progress"
+			}
+			return status
+		}
+	}
+	return SigningStatusCompleted
+}
+
+// Superseded reports whether the document was voided or sent again as a new document; its
+// webhooks no longer change NAV or the files
+func (m *DocumentMapping) Superseded() bool {
+	return m.ResubmittedAs != "" || IsVoidedStatus(m.SigningStatus)
+}
+
+// Attempt returns the signing attempt sent to NAV: 0 for a document sent once, which leaves the
+// NAV field alone, otherwise 2 for the first resubmit and so on
+func (m *DocumentMapping) Attempt() int {
+	if m.Resubmits == 0 {
+		return 0
+	}
+	return m.Resubmits + 1
+}
+
+// AllStampPositions returns the e-meterai positions saved for stamping
+func (m *DocumentMapping) AllStampPositions() []StampPosition {
+	if len(m.Stamps) > 0 {
+		return m.Stamps
+	}
+	if m.StampPositions != nil {
+		return []StampPosition{*m.StampPositions}
+	}
+	return nil
+}
+
+// DocumentMappingItem is a stored mapping together with the document ID of its key
+// (stamp documents are keyed by their own ID but share the original document's mapping)
+type DocumentMappingItem struct {
+	Key string `json:"key"`
+	DocumentMapping
+}
+
+// DocumentMappingPage is one page of document mappings; NextCursor is empty on the last page
+type DocumentMappingPage struct {
+	Mappings   []DocumentMappingItem `json:"mappings"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+// UpdateDocumentMappingRequest is a partial update of a document mapping; omitted fields are kept
+type UpdateDocumentMappingRequest struct {
+	Email            *string           `json:"email,omitempty" validate:"omitempty,email"`
+	InvoiceNumber    *string           `json:"invoice_number,omitempty" validate:"omitempty,min=1,max=255"`
+	Filename         *string           `json:"filename,omitempty" validate:"omitempty,min=1,max=255"`
+	StampPositions   *StampPosition    `json:"stamp_positions,omitempty"`                  // Replaces stamps with a single position
+	Stamps           []StampPosition   `json:"stamps,omitempty" validate:"omitempty,dive"` // Replaces stamp_positions
+	DocumentDeadline *DocumentDeadline `json:"document_deadline,omitempty"`
+	EntryNo          *int              `json:"entry_no,omitempty" validate:"omitempty,gt=0"`
+	Signing          *bool             `json:"signing,omitempty"`
+	Stamping         *bool             `json:"stamping,omitempty"`
+}
+
+// ArchivedDocumentMapping is a Redis mapping key removed by the TTL cleanup, kept with its raw value
+type ArchivedDocumentMapping struct {
+	ID         int64     `json:"id"`
+	Key        string    `json:"key"`
+	DocumentID string    `json:"document_id,omitempty"`
+	InvoiceNo  string    `json:"invoice_no,omitempty"`
+	Data       string    `json:"data"`
+	ArchivedAt time.Time `json:"archived_at"`
+}
@@ -1,58 +1,94 @@
 package entity
 
+import "time"
+
 // GlobalSignRequest represents the incoming request from client
 type GlobalSignRequest struct {
-	EntryNo          int               `json:"entry_no"`                    // Entry number for tracking
-	Email            string            `json:"email"`                       // User email for OAuth token
-	InvoiceNumber    string            `json:"invoice_number,omitempty"`    // Invoice number reference
-	Signing          bool              `json:"signing"`                     // Signing only
-	Stamping         bool              `json:"stamping"`                    // Stamping only
-	Signers          []SignerRequest   `json:"signers"`                     // List of signers
-	StampPositions   *StampPosition    `json:"stamp_positions,omitempty"`   // Stamp position (saved for later stamping)
-	DocumentDeadline *DocumentDeadline `json:"document_deadline,omitempty"` // Optional deadline settings
+	EntryNo          int               `json:"entry_no" validate:"gte=0"`                                              // Entry number for tracking
+	Email            string            `json:"email" validate:"omitempty,email"`                                       // User email for OAuth token
+	InvoiceNumber    string            `json:"invoice_number,omitempty" validate:"omitempty,max=255"`                  // Invoice number reference
+	Signing          bool              `json:"signing"`                                                                // Signing only
+	Stamping         bool              `json:"stamping"`                                                               // Stamping only
+	Signers          []SignerRequest   `json:"signers" validate:"omitempty,dive"`                                      // List of signers (required unless stamping only)
+	StampPositions   *StampPosition    `json:"stamp_positions,omitempty" validate:"omitempty"`                         // Stamp position (saved for later stamping)
+	DocumentDeadline *DocumentDeadline `json:"document_deadline,omitempty" validate:"omitempty"`                       // Optional deadline settings
+	EnforceOrder     bool              `json:"enforce_order,omitempty"`                                                // Sign one after another by signer order
+	StampPlacement   string            `json:"stamp_placement,omitempty" validate:"omitempty,oneof=fixed auto anchor"` // E-meterai placement (default: stamping.placement)
+	Attachments      []Attachment      `json:"attachments,omitempty" validate:"omitempty,max=10,dive"`                 // Supporting PDFs signed together with the document
+	AttachmentMode   string            `json:"attachment_mode,omitempty" validate:"omitempty,oneof=merge separate"`    // merge or separate (default: document.attachment_mode)
+
+	// Only set by /api/v2 requests, the v1 body is unchanged
+	Stamps   []StampPosition `json:"-"` // Multiple e-meterai positions (replaces StampPositions)
+	Document *InlineDocument `json:"-"` // Inline document used instead of the ready folder
+
+	Resubmits int `json:"-"` // Times the document was sent before, set by resubmits
+}
+
+// IsStampingOnly returns true if the request only stamps an already signed document
+func (r *GlobalSignRequest) IsStampingOnly() bool {
+	return !r.Signing && r.Stamping
+}
+
+// AllStampPositions returns the e-meterai positions of the request (v2 stamps or the single v1 position)
+func (r *GlobalSignRequest) AllStampPositions() []StampPosition {
+	if len(r.Stamps) > 0 {
+		return r.Stamps
+	}
+	if r.StampPositions != nil {
+		return []StampPosition{*r.StampPositions}
+	}
+	return nil
+}
+
+// Attachment is a supporting PDF of a sign request, such as the delivery note of an invoice.
+// Without content it is read from the ready folder by filename.
+type Attachment struct {
+	Filename string `json:"filename" validate:"required,max=255"`
+	Content  string `json:"content,omitempty" validate:"omitempty,base64"` // Base64 encoded PDF
 }
 
 // SignerRequest represents a signer in the client request
 type SignerRequest struct {
-	Name               string             `json:"name"`
-	Email              string             `json:"email"`
-	Phone              string             `json:"phone,omitempty"`
-	Order              int                `json:"order,omitempty"`        // Signer order
-	SignPage           int                `json:"sign_page"`              // Page number
-	SignaturePositions *SignaturePosition `json:"signature_positions"`    // Signature placement position
-	RequiresOTP        bool               `json:"requires_otp,omitempty"` // Require OTP verification
+	Name               string             `json:"name" validate:"required,max=255"`
+	Email              string             `json:"email" validate:"required,email"`
+	Phone              string             `json:"phone,omitempty" validate:"omitempty,max=20"`
+	CountryCode        string             `json:"country_code,omitempty" validate:"omitempty,numeric,max=3"` // Country calling code of phone (default: mekari.signers.default_country_code)
+	Order              int                `json:"order,omitempty" validate:"gte=0"`                          // Signer order
+	SignPage           int                `json:"sign_page" validate:"gt=0"`                                 // Page number
+	SignaturePositions *SignaturePosition `json:"signature_positions" validate:"required"`                   // Signature placement position
+	RequiresOTP        bool               `json:"requires_otp,omitempty"`                                    // Require OTP verification
 }
 
 // SignaturePosition represents the position of signature on a document (client request)
 type SignaturePosition struct {
-	X            float64  `json:"x"` // X coordinate
-	Y            float64  `json:"y"` // Y coordinate
-	Width        float64  `json:"width,omitempty"`
-	Height       float64  `json:"height,omitempty"`
-	CanvasWidth  float64  `json:"canvas_width,omitempty"`
-	CanvasHeight float64  `json:"canvas_height,omitempty"`
-	Page         int      `json:"page,omitempty"` // Page number (1-based)
+	X            float64  `json:"x" validate:"gte=0"` // X coordinate
+	Y            float64  `json:"y" validate:"gte=0"` // Y coordinate
+	Width        float64  `json:"width,omitempty" validate:"gte=0"`
+	Height       float64  `json:"height,omitempty" validate:"gte=0"`
+	CanvasWidth  float64  `json:"canvas_width,omitempty" validate:"gte=0"`
+	CanvasHeight float64  `json:"canvas_height,omitempty" validate:"gte=0"`
+	Page         int      `json:"page,omitempty" validate:"gte=0"` // Page number (1-based)
 	AutoFields   []string `json:"auto_fields,omitempty"`
-	TypeOf       string   `json:"type_of,omitempty"`
+	TypeOf       string   `json:"type_of,omitempty" validate:"omitempty,oneof=signature meterai initial stamp"`
 }
 
 // DocumentDeadline represents optional deadline settings
 type DocumentDeadline struct {
-	SigningDeadline          int    `json:"signing_deadline,omitempty"`             // value min 3 - max 31
-	RecurringReminder        string `json:"recurring_reminder,omitempty"`           // none, daily, three_days, weekly, monthly
-	DaysReminderAfterReceive int    `json:"days_reminder_after_received,omitempty"` // value min 1 - max 31
+	SigningDeadline          int    `json:"signing_deadline,omitempty" validate:"omitempty,min=3,max=31"`                                 // value min 3 - max 31
+	RecurringReminder        string `json:"recurring_reminder,omitempty" validate:"omitempty,oneof=none daily three_days weekly monthly"` // none, daily, three_days, weekly, monthly
+	DaysReminderAfterReceive int    `json:"days_reminder_after_received,omitempty" validate:"omitempty,min=1,max=31"`                     // value min 1 - max 31
 }
 
 // StampPosition represents the position of e-meterai stamp on document
 // This is stored temporarily and used later during stamping
 type StampPosition struct {
-	X            float64 `json:"x"` // X coordinate
-	Y            float64 `json:"y"` // Y coordinate
-	Width        float64 `json:"width,omitempty"`
-	Height       float64 `json:"height,omitempty"`
-	CanvasWidth  float64 `json:"canvas_width,omitempty"`
-	CanvasHeight float64 `json:"canvas_height,omitempty"`
-	Page         int     `json:"page,omitempty"` // Page number (1-based)
+	X            float64 `json:"x" validate:"gte=0"` // X coordinate
+	Y            float64 `json:"y" validate:"gte=0"` // Y coordinate
+	Width        float64 `json:"width,omitempty" validate:"gte=0"`
+	Height       float64 `json:"height,omitempty" validate:"gte=0"`
+	CanvasWidth  float64 `json:"canvas_width,omitempty" validate:"gte=0"`
+	CanvasHeight float64 `json:"canvas_height,omitempty" validate:"gte=0"`
+	Page         int     `json:"page,omitempty" validate:"gte=0"` // Page number (1-based)
 }
 
 // ========== Mekari API Request Structures ==========
@@ -66,6 +102,7 @@ type MekariSignRequest struct {
 	QRCodeAuditTrail *QRCodeAuditTrail `json:"qr_code_audit_trail,omitempty"` // QR code audit trail position
 	DocumentDeadline *DocumentDeadline `json:"document_deadline,omitempty"`   // Deadline settings
 	EntryNo          int               `json:"entry_no"`                      // Entry number for tracking
+	IsSequence       bool              `json:"is_sequence,omitempty"`         // Signers sign in the order of their Order
 }
 
 // MekariSigner represents a signer in Mekari API format
@@ -158,6 +195,61 @@ type SignerStatus struct {
 	Sequence int    `json:"sequence,omitempty"`
 }
 
+// SigningExpiry returns the signing deadline of a new document: the expiry date returned by Mekari
+// or, without it, signing_deadline days after createdAt. ok is false when there is none.
+func SigningExpiry(attrs *GlobalSignAttributes, deadline *DocumentDeadline, createdAt time.Time) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, attrs.ExpiryDate, time.Local); err == nil {
+			return t, true
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", attrs.ExpiryDate, time.Local); err == nil {
+		return t.AddDate(0, 0, 1), true // Valid through the expiry date
+	}
+	if created, err := time.Parse(time.RFC3339, attrs.CreatedAt); err == nil {
+		createdAt = created
+	}
+	if deadline != nil && deadline.SigningDeadline > 0 {
+		return createdAt.AddDate(0, 0, deadline.SigningDeadline), true
+	}
+	return time.Time{}, false
+}
+
+// SignerStatusWaiting is the status of a signer of an ordered request whose turn has not come yet
+const SignerStatusWaiting = "waiting_for_previous_signer"
+
+// MarkWaitingSigners sets the status of the signers after the next one to sign to
+// SignerStatusWaiting, for requests with enforce_order
+func MarkWaitingSigners(signers []SignerStatus) {
+	orders := make([]int, len(signers))
+	completed := make([]bool, len(signers))
+	for i, s := range signers {
+		orders[i] = s.Sequence
+		completed[i] = s.Status == "completed"
+	}
+	for i, waiting := range waitingSigners(orders, completed) {
+		if waiting {
+			signers[i].Status = SignerStatusWaiting
+		}
+	}
+}
+
+// waitingSigners returns which signers wait for a previous signer: those not completed whose
+// order is after the lowest order not completed yet
+func waitingSigners(orders []int, completed []bool) []bool {
+	next := 0
+	for i, order := range orders {
+		if !completed[i] && (next == 0 || order < next) {
+			next = order
+		}
+	}
+	waiting := make([]bool, len(orders))
+	for i, order := range orders {
+		waiting[i] = !completed[i] && order > next
+	}
+	return waiting
+}
+
 // Default values for annotations
 const (
 	DefaultElementWidth  = 180.0 // Signature width (increased from 120 for better visibility)
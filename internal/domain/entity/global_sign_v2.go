@@ -0,0 +1,79 @@
+package entity
+
+import "encoding/base64"
+
+// GlobalSignRequestV2 is the /api/v2 sign request. Compared to v1 it takes any number of
+// e-meterai positions and may carry the document inline instead of reading the ready folder.
+type GlobalSignRequestV2 struct {
+	EntryNo          int               `json:"entry_no" validate:"gte=0"`
+	Email            string            `json:"email" validate:"omitempty,email"`
+	InvoiceNumber    string            `json:"invoice_number,omitempty" validate:"omitempty,max=255"`
+	Signing          bool              `json:"signing"`
+	Stamping         bool              `json:"stamping"`
+	Signers          []SignerRequest   `json:"signers" validate:"omitempty,dive"`                 // Required unless stamping only
+	Stamps           []StampPosition   `json:"stamps,omitempty" validate:"omitempty,max=20,dive"` // E-meterai positions, applied after signing
+	Document         *InlineDocument   `json:"document,omitempty" validate:"omitempty"`           // Inline document (default: ready folder by invoice number)
+	DocumentDeadline *DocumentDeadline `json:"document_deadline,omitempty" validate:"omitempty"`
+	EnforceOrder     bool              `json:"enforce_order,omitempty"`                                                // Sign one after another by signer order
+	StampPlacement   string            `json:"stamp_placement,omitempty" validate:"omitempty,oneof=fixed auto anchor"` // E-meterai placement (default: stamping.placement)
+	Attachments      []Attachment      `json:"attachments,omitempty" validate:"omitempty,max=10,dive"`                 // Inline or from the ready folder by filename
+	AttachmentMode   string            `json:"attachment_mode,omitempty" validate:"omitempty,oneof=merge separate"`    // merge or separate (default: document.attachment_mode)
+}
+
+// InlineDocument is a PDF sent in the request body
+type InlineDocument struct {
+	Filename string `json:"filename" validate:"required,max=255"`
+	Content  string `json:"content" validate:"required,base64"` // Base64 encoded PDF
+}
+
+// Decode returns the raw document content
+func (d *InlineDocument) Decode() ([]byte, error) {
+	return base64.StdEncoding.DecodeString(d.Content)
+}
+
+// IsStampingOnly returns true if the request only stamps an already signed document
+func (r *GlobalSignRequestV2) IsStampingOnly() bool {
+	return !r.Signing && r.Stamping
+}
+
+// ToGlobalSignRequest converts the request to the shape shared with v1 by the usecase
+func (r *GlobalSignRequestV2) ToGlobalSignRequest() *GlobalSignRequest {
+	return &GlobalSignRequest{
+		EntryNo:          r.EntryNo,
+		Email:            r.Email,
+		InvoiceNumber:    r.InvoiceNumber,
+		Signing:          r.Signing,
+		Stamping:         r.Stamping,
+		Signers:          r.Signers,
+		DocumentDeadline: r.DocumentDeadline,
+		EnforceOrder:     r.EnforceOrder,
+		StampPlacement:   r.StampPlacement,
+		Attachments:      r.Attachments,
+		AttachmentMode:   r.AttachmentMode,
+		Stamps:           r.Stamps,
+		Document:         r.Document,
+	}
+}
+
+// Sign result statuses of /api/v2
+const (
+	SignStatusSubmitted         = "submitted"          // Sent to Mekari for signing
+	SignStatusStampingRequested = "stamping_requested" // Signed document sent for e-meterai stamping
+)
+
+// GlobalSignResponseV2 is the flattened /api/v2 sign response
+type GlobalSignResponseV2 struct {
+	Status        string         `json:"status"` // submitted or stamping_requested
+	DocumentID    string         `json:"document_id,omitempty"`
+	InvoiceNumber string         `json:"invoice_number,omitempty"`
+	Filename      string         `json:"filename,omitempty"`
+	MekariStatus  string         `json:"mekari_status,omitempty"`
+	Signers       []SignerStatus `json:"signers,omitempty"`
+	StampCount    int            `json:"stamp_count"` // E-meterai positions saved for stamping
+	ExpiryDate    string         `json:"expiry_date,omitempty"`
+}
+
+// AuthorizationRequiredData is returned with AUTHORIZATION_REQUIRED errors
+type AuthorizationRequiredData struct {
+	RedirectURL string `json:"redirect_url"`
+}
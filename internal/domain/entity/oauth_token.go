@@ -15,6 +15,26 @@ type OAuthToken struct {
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// OAuth grant types recorded in the token history
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+)
+
+// OAuthTokenEvent is one token grant or refresh attempt for an email. IssuedAt and ExpiresAt are
+// only set when the attempt succeeded.
+type OAuthTokenEvent struct {
+	ID        int64      `json:"id"`
+	Email     string     `json:"email"`
+	GrantType string     `json:"grant_type"`
+	Success   bool       `json:"success"`
+	Error     string     `json:"error,omitempty"`
+	IssuedAt  *time.Time `json:"issued_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RequestID string     `json:"request_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
 // CheckCodeRequest represents the request to check if code exists
 type CheckCodeRequest struct {
 	Email string `json:"email" validate:"required,email"`
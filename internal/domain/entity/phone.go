@@ -0,0 +1,68 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalidPhone is returned for phone numbers that are not a valid international number
+var ErrInvalidPhone = errors.New("must be a phone number of 8 to 15 digits, e.g. +62812345678 or 0812345678")
+
+// twoDigitCountryCodes are the two digit country calling codes; 1 and 7 are the one digit
+// codes and every other code has three digits (ITU-T E.164 codes are prefix free)
+var twoDigitCountryCodes = map[string]bool{
+	"20": true, "27": true, "30": true, "31": true, "32": true, "33": true, "34": true, "36": true,
+	"39": true, "40": true, "41": true, "43": true, "44": true, "45": true, "46": true, "47": true,
+	"48": true, "49": true, "51": true, "52": true, "53": true, "54": true, "55": true, "56": true,
+	"57": true, "58": true, "60": true, "61": true, "62": true, "63": true, "64": true, "65": true,
+	"66": true, "81": true, "82": true, "84": true, "86": true, "90": true, "91": true, "92": true,
+	"93": true, "94": true, "95": true, "98": true,
+}
+
+// NormalizePhone returns phone as a Mekari phone number in international format. Spaces, dashes,
+// dots and parentheses are dropped; numbers starting with + or 00 carry their country code,
+// numbers starting with 0 or without the country code get countryCode (e.g. "62"), so
+// "0812-3456-789" becomes +628123456789.
+func NormalizePhone(phone, countryCode string) (*PhoneNumber, error) {
+	phone = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, phone)
+
+	international := false
+	switch {
+	case strings.HasPrefix(phone, "+"):
+		phone, international = phone[1:], true
+	case strings.HasPrefix(phone, "00"):
+		phone, international = phone[2:], true
+	case strings.HasPrefix(phone, "0"):
+		phone = countryCode + phone[1:]
+	case !strings.HasPrefix(phone, countryCode):
+		phone = countryCode + phone
+	}
+
+	if len(phone) < 8 || len(phone) > 15 || strings.Trim(phone, "0123456789") != "" || phone[0] == '0' {
+		return nil, ErrInvalidPhone
+	}
+
+	code := countryCode
+	if international {
+		code = splitCountryCode(phone)
+	}
+	return &PhoneNumber{CountryCode: code, Number: "+" + phone}, nil
+}
+
+// splitCountryCode returns the country calling code at the start of an international number
+func splitCountryCode(digits string) string {
+	switch {
+	case digits[0] == '1' || digits[0] == '7':
+		return digits[:1]
+	case twoDigitCountryCodes[digits[:2]]:
+		return digits[:2]
+	default:
+		return digits[:3]
+	}
+}
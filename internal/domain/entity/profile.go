@@ -33,6 +33,19 @@ type Quota struct {
 	EkycQuota         int `json:"ekyc_quota"`
 }
 
+// EmeteraiQuota is the e-meterai balance of an account, derived from the profile balance
+type EmeteraiQuota struct {
+	Email             string    `json:"email,omitempty"`
+	RemainingEmeterai int       `json:"remaining_emeterai"`
+	EmeteraiUsage     int       `json:"emeterai_usage"`
+	GlobalSignDoc     int       `json:"global_sign_document"`
+	PsreSigning       int       `json:"psre_signing"`
+	Threshold         int       `json:"threshold,omitempty"` // quota.alert_threshold
+	Low               bool      `json:"low"`                 // Remaining balance is below the threshold
+	CheckedAt         time.Time `json:"checked_at,omitempty"`
+	Error             string    `json:"error,omitempty"`
+}
+
 type Subscription struct {
 	Plan      string    `json:"plan"`
 	Status    string    `json:"status"`
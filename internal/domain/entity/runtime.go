@@ -0,0 +1,41 @@
+package entity
+
+import "time"
+
+// RuntimeStats reports the Go runtime state of the service process
+type RuntimeStats struct {
+	GoVersion  string        `json:"go_version"`
+	NumCPU     int           `json:"num_cpu"`
+	GOMAXPROCS int           `json:"gomaxprocs"`
+	Goroutines int           `json:"goroutines"`
+	StartedAt  time.Time     `json:"started_at"`
+	Uptime     string        `json:"uptime"`
+	Memory     RuntimeMemory `json:"memory"`
+	GC         RuntimeGC     `json:"gc"`
+}
+
+// RuntimeMemory is the memory usage in bytes (see runtime.MemStats)
+type RuntimeMemory struct {
+	HeapAlloc    uint64 `json:"heap_alloc"`    // Bytes of allocated heap objects
+	HeapInuse    uint64 `json:"heap_inuse"`    // Bytes in in-use heap spans
+	HeapIdle     uint64 `json:"heap_idle"`     // Bytes in idle heap spans
+	HeapReleased uint64 `json:"heap_released"` // Idle bytes returned to the OS
+	HeapSys      uint64 `json:"heap_sys"`      // Heap bytes obtained from the OS
+	HeapObjects  uint64 `json:"heap_objects"`  // Number of allocated heap objects
+	StackInuse   uint64 `json:"stack_inuse"`
+	Sys          uint64 `json:"sys"`         // Total bytes obtained from the OS
+	TotalAlloc   uint64 `json:"total_alloc"` // Cumulative bytes allocated, including freed
+	Mallocs      uint64 `json:"mallocs"`
+	Frees        uint64 `json:"frees"`
+}
+
+// RuntimeGC summarizes garbage collection
+type RuntimeGC struct {
+	NumGC        uint32     `json:"num_gc"`
+	NumForcedGC  uint32     `json:"num_forced_gc"`
+	LastGC       *time.Time `json:"last_gc"` // Null before the first collection
+	NextGC       uint64     `json:"next_gc"` // Heap size in bytes that triggers the next collection
+	PauseTotalMs float64    `json:"pause_total_ms"`
+	LastPauseMs  float64    `json:"last_pause_ms"`
+	CPUFraction  float64    `json:"cpu_fraction"` // Share of CPU time spent in GC since start
+}
@@ -0,0 +1,41 @@
+package entity
+
+import "time"
+
+// ScheduledJob is a recurring job of the scheduler with the outcome of its last run
+type ScheduledJob struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Enabled     bool       `json:"enabled"`
+	Schedule    string     `json:"schedule"` // Cron expression or @every interval, in app.timezone
+	Cluster     bool       `json:"cluster"`  // Run by one of the instances side by side
+	Running     bool       `json:"running"`
+	NextRunAt   *time.Time `json:"next_run_at"`
+	Runs        int        `json:"runs"`     // Since the service started
+	Failures    int        `json:"failures"` // Since the service started
+	LastRun     *JobRun    `json:"last_run"`
+}
+
+// JobRun is a run of a scheduled job
+type JobRun struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Duration   string    `json:"duration"`
+	Result     string    `json:"result"` // JobResult* constant
+	Error      string    `json:"error,omitempty"`
+	Trigger    string    `json:"trigger"` // JobTrigger* constant
+}
+
+// Job run results
+const (
+	JobResultSuccess = "success"
+	JobResultFailed  = "failed"
+	JobResultSkipped = "skipped" // Another instance ran it
+)
+
+// What started a job run
+const (
+	JobTriggerStartup  = "startup"
+	JobTriggerSchedule = "schedule"
+	JobTriggerManual   = "manual"
+)
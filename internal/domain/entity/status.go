@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+// ServiceStatus is the public summary served by GET /status
+type ServiceStatus struct {
+	Version           string          `json:"version"`
+	StartedAt         time.Time       `json:"started_at"`
+	Uptime            string          `json:"uptime"`
+	Documents         StatusDocuments `json:"documents"`
+	LastMekariSuccess *time.Time      `json:"last_mekari_success"` // Null when no successful Mekari call is logged
+	LastNAVUpdate     *time.Time      `json:"last_nav_update"`     // Null when NAV is disabled or no update succeeded
+	GeneratedAt       time.Time       `json:"generated_at"`
+}
+
+// StatusDocuments counts documents by processing state
+type StatusDocuments struct {
+	Ready      int `json:"ready"`       // Waiting to be sent for signing
+	InProgress int `json:"in_progress"` // Out for signing or stamping
+	Completed  int `json:"completed"`
+	Stuck      int `json:"stuck"` // In progress for longer than dashboard.stuck_after, also counted in in_progress
+}
@@ -0,0 +1,66 @@
+package entity
+
+import "time"
+
+// UpdateInfo reports the running version, the last update check, the update staged by the
+// background updater and the outcome of the last update
+type UpdateInfo struct {
+	Version           string         `json:"version"`
+	Channel           string         `json:"channel"`
+	PinVersion        string         `json:"pin_version,omitempty"`
+	Background        bool           `json:"background"`
+	MaintenanceWindow string         `json:"maintenance_window,omitempty"`
+	Latest            string         `json:"latest,omitempty"` // Newest version found by the last check
+	Available         bool           `json:"available"`        // Latest is to be installed
+	LastCheckAt       *time.Time     `json:"last_check_at"`    // By the background updater or -update
+	LastCheckError    string         `json:"last_check_error,omitempty"`
+	Pending           *PendingUpdate `json:"pending"`
+	LastUpdate        *UpdateResult  `json:"last_update"`
+	RollbackVersion   string         `json:"rollback_version,omitempty"` // Version -rollback restores
+}
+
+// PendingUpdate is a downloaded and verified release waiting for the maintenance window
+type PendingUpdate struct {
+	Version      string    `json:"version"`
+	Prerelease   bool      `json:"prerelease"`
+	PublishedAt  time.Time `json:"published_at"`
+	Notes        string    `json:"notes,omitempty"`
+	StagedAt     time.Time `json:"staged_at"`
+	InstallAfter time.Time `json:"install_after"`
+}
+
+// UpdateResult is the outcome of an update or rollback
+type UpdateResult struct {
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	Rollback   bool      `json:"rollback"`
+	Result     string    `json:"result"` // UpdateResult* constant
+	Error      string    `json:"error,omitempty"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// Update results
+const (
+	UpdateResultUpdated    = "updated"     // The new version runs
+	UpdateResultRolledBack = "rolled_back" // The new version did not start; the previous one runs again
+	UpdateResultFailed     = "failed"      // See the error
+)
+
+// Update history kinds
+const (
+	UpdateKindUpdate   = "update"
+	UpdateKindRollback = "rollback"
+)
+
+// UpdateHistory is an update or rollback of a host, recorded when the service next starts
+type UpdateHistory struct {
+	ID          int64     `json:"id"`
+	Host        string    `json:"host"`
+	FromVersion string    `json:"from_version"`
+	ToVersion   string    `json:"to_version"`
+	Kind        string    `json:"kind"`   // UpdateKind* constant
+	Result      string    `json:"result"` // UpdateResult* constant
+	Error       string    `json:"error,omitempty"`
+	FinishedAt  time.Time `json:"finished_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,66 @@
+package entity
+
+import "time"
+
+// User roles, from most to least privileged
+const (
+	RoleAdmin    = "admin"     // Full access including configuration and key management
+	RoleOperator = "operator"  // Can view logs and replay/retry processing
+	RoleReadOnly = "read_only" // Can only view logs and status
+)
+
+// roleRanks orders roles so a higher rank includes the permissions of lower ranks
+var roleRanks = map[string]int{
+	RoleReadOnly: 1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// IsValidRole checks if the role is one of the known roles
+func IsValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// RoleAtLeast returns true if role has at least the permissions of minRole
+func RoleAtLeast(role, minRole string) bool {
+	return roleRanks[role] >= roleRanks[minRole] && roleRanks[role] > 0
+}
+
+// User represents a dashboard/admin user
+type User struct {
+	ID           int64      `json:"id"`
+	Username     string     `json:"username"`
+	PasswordHash string     `json:"-"`
+	Role         string     `json:"role"`
+	Disabled     bool       `json:"disabled"`
+	LastLoginAt  *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// AuthUser represents the authenticated principal of a request
+type AuthUser struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// LoginRequest represents the login request
+type LoginRequest struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// LoginResponse represents a successful login
+type LoginResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	User      *AuthUser `json:"user"`
+}
+
+// CreateUserRequest represents the request to create a user
+type CreateUserRequest struct {
+	Username string `json:"username" validate:"required,max=100"`
+	Password string `json:"password" validate:"required,min=8"`
+	Role     string `json:"role" validate:"required,oneof=admin operator read_only"`
+}
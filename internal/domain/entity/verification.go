@@ -0,0 +1,52 @@
+package entity
+
+import "time"
+
+// VerifyDocumentRequest selects the PDF to verify when it is not uploaded as a file
+type VerifyDocumentRequest struct {
+	Filename string `json:"filename,omitempty" validate:"required_without=Content"` // File in the finish folder
+	Content  string `json:"content,omitempty" validate:"omitempty,base64"`          // Base64 PDF, takes precedence over filename
+}
+
+// VerificationReport is the result of checking the digital signatures of a PDF
+type VerificationReport struct {
+	Filename             string                  `json:"filename,omitempty"`
+	SizeBytes            int                     `json:"size_bytes"`
+	Signed               bool                    `json:"signed"`
+	Valid                bool                    `json:"valid"`                  // Signed, every signature intact and trusted, nothing changed after the last one
+	ModifiedAfterSigning bool                    `json:"modified_after_signing"` // Bytes were appended after the last signature
+	EMeteraiPresent      bool                    `json:"emeterai_present"`
+	Signatures           []SignatureVerification `json:"signatures"` // In the order they were applied
+	VerifiedAt           time.Time               `json:"verified_at"`
+}
+
+// SignatureVerification is the result of checking one digital signature of a PDF
+type SignatureVerification struct {
+	Index               int               `json:"index"`                       // 1 for the first signature applied
+	Name                string            `json:"name,omitempty"`              // Signer name written in the signature dictionary
+	Reason              string            `json:"reason,omitempty"`            // e.g. e-Meterai
+	Location            string            `json:"location,omitempty"`          // Signing location written in the signature dictionary
+	SubFilter           string            `json:"sub_filter,omitempty"`        // e.g. adbe.pkcs7.detached, ETSI.CAdES.detached, ETSI.RFC3161
+	DocumentTimestamp   bool              `json:"document_timestamp"`          // A timestamp of the document rather than a signature
+	EMeterai            bool              `json:"emeterai"`                    // The signature of an e-meterai stamp
+	SignerName          string            `json:"signer_name,omitempty"`       // Common name of the signer certificate
+	SignerEmail         string            `json:"signer_email,omitempty"`      // Email address of the signer certificate
+	SigningTime         *time.Time        `json:"signing_time,omitempty"`      // Claimed by the signer
+	TimestampedAt       *time.Time        `json:"timestamped_at,omitempty"`    // From the timestamp token of the signature or document timestamp, once the token is trusted
+	DigestAlgorithm     string            `json:"digest_algorithm,omitempty"`  // e.g. SHA-256
+	IntegrityValid      bool              `json:"integrity_valid"`             // The signed bytes are unchanged
+	SignatureValid      bool              `json:"signature_valid"`             // The signature matches the signer certificate
+	ChainTrusted        bool              `json:"chain_trusted"`               // The signer certificate chains to a trusted root
+	CoversWholeDocument bool              `json:"covers_whole_document"`       // Nothing was appended after this signature
+	CertificateChain    []CertificateInfo `json:"certificate_chain,omitempty"` // Signer certificate first, up to the root when trusted
+	Errors              []string          `json:"errors,omitempty"`            // Why a check failed
+}
+
+// CertificateInfo describes a certificate of a signature's chain
+type CertificateInfo struct {
+	Subject      string    `json:"subject"`
+	Issuer       string    `json:"issuer"`
+	SerialNumber string    `json:"serial_number"`
+	NotBefore    time.Time `json:"not_before"`
+	NotAfter     time.Time `json:"not_after"`
+}
@@ -19,7 +19,7 @@ type WebhookAttributes struct {
 	Filename         string          `json:"filename"`
 	Category         string          `json:"category"`
 	DocURL           string          `json:"doc_url"`
-	SigningStatus    string          `json:"signing_status"`  // pending, in_progress, completed
+	SigningStatus    string          `json:"signing_status"`  // pending, in_progress, completed, expired, declined, voided
 	StampingStatus   string          `json:"stamping_status"` // pending, success
 	TypeOfMeterai    string          `json:"type_of_meterai"`
 	Signers          []WebhookSigner `json:"signers"`
@@ -65,6 +65,8 @@ type NAVLogEntry struct {
 	FilePathOut     string `json:"File_Path_Out"`
 	SigningStatus   string `json:"Signing_Status"`
 	StampingStatus  string `json:"Stamping_Status"`
+	SigningAttempt  int    `json:"Signing_Attempt,omitempty"`       // Only sent for resubmitted documents, see DocumentMapping.Attempt
+	AuditTrailPath  string `json:"File_Path_Audit_Trail,omitempty"` // Only sent once the audit trail is saved, see document.audit_trail
 	// Signer 1
 	Signer1Name          string `json:"Signer1_Name,omitempty"`
 	Signer1Email         string `json:"Signer1_Email,omitempty"`
@@ -99,10 +101,30 @@ type NAVLogEntry struct {
 
 // MapSigningStatus maps Mekari signing status to NAV status
 func MapSigningStatus(status string) string {
-	if status == "completed" {
+	switch status {
+	case SigningStatusCompleted:
 		return "Completed"
+	case SigningStatusExpired:
+		return "Expired"
+	case SigningStatusVoided, SigningStatusCancelled:
+		return "Voided"
+	default:
+		return "Pending"
+	}
+}
+
+// NAVSigningStatusWaiting is the NAV signing status of a signer waiting for a previous signer
+const NAVSigningStatusWaiting = "Waiting"
+
+// WaitingSigners returns which webhook signers of an ordered request wait for a previous signer
+func WaitingSigners(signers []WebhookSigner) []bool {
+	orders := make([]int, len(signers))
+	completed := make([]bool, len(signers))
+	for i, s := range signers {
+		orders[i] = s.Order
+		completed[i] = s.Status == "completed"
 	}
-	return "Pending"
+	return waitingSigners(orders, completed)
 }
 
 // MapStampingStatus maps Mekari stamping status to NAV status
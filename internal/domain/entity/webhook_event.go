@@ -0,0 +1,29 @@
+package entity
+
+import "time"
+
+// Webhook event processing statuses
+const (
+	WebhookEventPending    = "pending"
+	WebhookEventProcessing = "processing"
+	WebhookEventProcessed  = "processed"
+	WebhookEventFailed     = "failed"
+)
+
+// WebhookEvent represents a raw webhook callback persisted for background processing
+type WebhookEvent struct {
+	ID             int64      `json:"id"`
+	EventKey       string     `json:"event_key"` // SHA-256 of the raw body, used to drop Mekari retries
+	DocumentID     string     `json:"document_id"`
+	SigningStatus  string     `json:"signing_status"`
+	StampingStatus string     `json:"stamping_status"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"` // pending, processing, processed, failed
+	Attempts       int        `json:"attempts"`
+	LastError      string     `json:"last_error,omitempty"`
+	RequestID      string     `json:"request_id,omitempty"`      // Request ID of the callback, reused while processing
+	ClaimedBy      string     `json:"claimed_by,omitempty"`      // app.instance_id of the instance that last claimed the event
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"` // When a failed event is retried, see webhook.retry_backoff
+	CreatedAt      time.Time  `json:"created_at"`
+	ProcessedAt    *time.Time `json:"processed_at,omitempty"`
+}
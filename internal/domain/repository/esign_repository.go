@@ -12,4 +12,8 @@ type EsignRepository interface {
 	// GlobalRequestSign sends sign request to Mekari API
 	// The doc (base64 PDF) will be fetched from invoice service based on invoice_number
 	GlobalRequestSign(ctx context.Context, email string, req *entity.GlobalSignRequest) (*entity.GlobalSignResponse, error)
+	// GetDocument fetches the details of a document, including its signers and their signing links
+	GetDocument(ctx context.Context, email, documentID string) (*entity.WebhookData, error)
+	// VoidDocument cancels a document that is still out for signing
+	VoidDocument(ctx context.Context, email, documentID string) error
 }
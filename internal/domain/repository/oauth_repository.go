@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"mekari-esign/internal/domain/entity"
 )
@@ -13,6 +14,26 @@ type OAuthRepository interface {
 	// SaveCode saves or updates OAuth code for an email
 	SaveCode(ctx context.Context, email, code string) error
 
+	// FindAll returns the tokens of all emails ordered by email
+	FindAll(ctx context.Context) ([]entity.OAuthToken, error)
+
 	// UpdateTokens updates access and refresh tokens
 	UpdateTokens(ctx context.Context, email, accessToken, refreshToken, tokenType string, expiresAt int64) error
+
+	// SoftDelete clears the code and tokens of an email and marks its record deleted, hiding it
+	// until a new code is saved. Returns false when no record exists.
+	SoftDelete(ctx context.Context, email string) (bool, error)
+
+	// PurgeDeleted removes the records deleted before the given time, with their token history,
+	// and returns how many were removed
+	PurgeDeleted(ctx context.Context, before time.Time) (int, error)
+}
+
+// OAuthTokenHistoryRepository records every token grant and refresh attempt
+type OAuthTokenHistoryRepository interface {
+	// Save stores an attempt and sets its ID
+	Save(ctx context.Context, event *entity.OAuthTokenEvent) error
+
+	// FindByEmail returns the newest attempts of an email first
+	FindByEmail(ctx context.Context, email string, limit int) ([]entity.OAuthTokenEvent, error)
 }
@@ -1,39 +1,59 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	_ "github.com/lib/pq"
 	"go.uber.org/zap"
 
 	"mekari-esign/internal/config"
+	"mekari-esign/internal/infrastructure/startup"
 )
 
+// Database is the connection pool with the dialect of database.driver. Its query methods
+// accept $1, $2... placeholders on every driver.
 type Database struct {
-	DB     *sql.DB
-	logger *zap.Logger
+	DB      *sql.DB
+	Dialect Dialect
+	logger  *zap.Logger
+
+	// queryTimeout bounds every query (database.query_timeout); slower ones than slowQuery
+	// are logged (database.slow_query_ms)
+	queryTimeout time.Duration
+	slowQuery    time.Duration
+
+	// replica serves the Read* methods when database.replica_dsn is set; nil otherwise.
+	// replicaDown is set while its health pings fail, sending reads to the primary.
+	replica     *sql.DB
+	replicaDown atomic.Bool
+
+	// down is set while health pings fail; repositories then serve from their fallbacks
+	down        atomic.Bool
+	mu          sync.Mutex
+	onReconnect []func()
 }
 
 func NewDatabase(cfg *config.Config, logger *zap.Logger) (*Database, error) {
-	// Build PostgreSQL connection string
-	dsn := fmt.Sprintf(
-		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Database.Host,
-		cfg.Database.Port,
-		cfg.Database.User,
-		cfg.Database.Password,
-		cfg.Database.DBName,
-		cfg.Database.SSLMode,
-	)
+	dialect, err := NewDialect(cfg.Database.Driver)
+	if err != nil {
+		return nil, err
+	}
 
-	db, err := sql.Open(cfg.Database.Driver, dsn)
+	db, err := sql.Open(dialect.Name(), dialect.DSN(cfg))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	// Test connection
-	if err := db.Ping(); err != nil {
+	db.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(time.Duration(cfg.Database.ConnMaxLifetime) * time.Second)
+
+	// Test connection, waiting for a database that is still starting
+	if err := startup.Wait(cfg, logger, "database", db.PingContext); err != nil {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
@@ -42,83 +62,350 @@ func NewDatabase(cfg *config.Config, logger *zap.Logger) (*Database, error) {
 		zap.String("host", cfg.Database.Host),
 		zap.Int("port", cfg.Database.Port),
 		zap.String("dbname", cfg.Database.DBName),
+		zap.Int("max_open_conns", cfg.Database.MaxOpenConns),
+		zap.Int("max_idle_conns", cfg.Database.MaxIdleConns),
 	)
 
 	database := &Database{
-		DB:     db,
-		logger: logger,
+		DB:           db,
+		Dialect:      dialect,
+		logger:       logger,
+		queryTimeout: time.Duration(cfg.Database.QueryTimeout) * time.Second,
+		slowQuery:    time.Duration(cfg.Database.SlowQueryMs) * time.Millisecond,
+	}
+
+	if cfg.Database.ReplicaDSN != "" {
+		replica, err := sql.Open(dialect.Name(), cfg.Database.ReplicaDSN)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open database replica: %w", err)
+		}
+		replica.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+		replica.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+		replica.SetConnMaxLifetime(time.Duration(cfg.Database.ConnMaxLifetime) * time.Second)
+		database.replica = replica
+
+		// An unreachable replica is not fatal; reads use the primary until it answers
+		if err := replica.Ping(); err != nil {
+			database.replicaDown.Store(true)
+			logger.Warn("Database replica unavailable, reading from the primary", zap.Error(err))
+		} else {
+			logger.Info("Database replica connected successfully")
+		}
 	}
 
 	// Run migrations
-	if err := database.migrate(); err != nil {
+	if err := dialect.migrate(db); err != nil {
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
+	logger.Info("Database migrations completed successfully")
+
+	// Rows written before timestamps were stored in UTC, see database.legacy_offset
+	if err := database.recordUTCSwitch(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to record the switch to UTC timestamps: %w", err)
+	}
+	converted, err := database.convertLegacyTimestamps(context.Background(), cfg.Database.LegacyOffsetSeconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert local timestamps to UTC: %w", err)
+	}
+	if converted {
+		logger.Info("Converted local timestamps to UTC", zap.String("legacy_offset", cfg.Database.LegacyOffset))
+	}
 
 	return database, nil
 }
 
-func (d *Database) migrate() error {
-	// Create oauth_tokens table (PostgreSQL syntax)
-	createTableSQL := `
-	CREATE TABLE IF NOT EXISTS oauth_tokens (
-		id SERIAL PRIMARY KEY,
-		email VARCHAR(255) NOT NULL UNIQUE,
-		code TEXT NOT NULL,
-		access_token TEXT DEFAULT '',
-		refresh_token TEXT DEFAULT '',
-		token_type VARCHAR(50) DEFAULT '',
-		expires_at TIMESTAMP,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-	`
-
-	_, err := d.DB.Exec(createTableSQL)
+// Available reports whether the last health ping succeeded
+func (d *Database) Available() bool {
+	return !d.down.Load()
+}
+
+// OnReconnect registers fn to run after the database becomes available again
+func (d *Database) OnReconnect(fn func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onReconnect = append(d.onReconnect, fn)
+}
+
+// Ping checks the connection and updates Available. The pool replaces broken connections
+// itself, so the first successful ping after an outage is the reconnect; the OnReconnect
+// functions then run before Ping returns.
+func (d *Database) Ping(ctx context.Context) error {
+	d.pingReplica(ctx)
+
+	err := d.DB.PingContext(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to create oauth_tokens table: %w", err)
+		if !d.down.Swap(true) {
+			d.logger.Error("Database unavailable, running in degraded mode", zap.Error(err))
+		}
+		return err
 	}
 
-	// Create index separately (PostgreSQL doesn't support IF NOT EXISTS in same statement)
-	createIndexSQL := `
-	CREATE INDEX IF NOT EXISTS idx_oauth_tokens_email ON oauth_tokens(email);
-	`
-	_, err = d.DB.Exec(createIndexSQL)
+	if d.down.Swap(false) {
+		d.logger.Info("Database connection restored")
+
+		d.mu.Lock()
+		callbacks := append([]func(){}, d.onReconnect...)
+		d.mu.Unlock()
+		for _, fn := range callbacks {
+			fn()
+		}
+	}
+	return nil
+}
+
+// pingReplica updates whether the Read* methods use the replica
+func (d *Database) pingReplica(ctx context.Context) {
+	if d.replica == nil {
+		return
+	}
+
+	if err := d.replica.PingContext(ctx); err != nil {
+		if !d.replicaDown.Swap(true) {
+			d.logger.Warn("Database replica unavailable, reading from the primary", zap.Error(err))
+		}
+		return
+	}
+	if d.replicaDown.Swap(false) {
+		d.logger.Info("Database replica connection restored")
+	}
+}
+
+// reader returns the replica while it is configured and healthy, the primary otherwise
+func (d *Database) reader() *sql.DB {
+	if d.replica == nil || d.replicaDown.Load() {
+		return d.DB
+	}
+	return d.replica
+}
+
+// Replica returns the pool of database.replica_dsn, or nil when none is configured
+func (d *Database) Replica() *sql.DB {
+	return d.replica
+}
+
+// conn returns the transaction of WithTx carried by ctx, or the pool
+func (d *Database) conn(ctx context.Context) querier {
+	if tx, ok := ctx.Value(txKey{}).(*Tx); ok {
+		return tx.Tx
+	}
+	return d.DB
+}
+
+func (d *Database) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	query, args = d.Dialect.Rebind(query, args)
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	start := time.Now()
+	result, err := d.conn(ctx).ExecContext(ctx, query, args...)
+	d.observe(ctx, start, query, args, err)
+	return result, err
+}
+
+func (d *Database) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	query, args = d.Dialect.Rebind(query, args)
+	return d.query(ctx, d.conn(ctx), query, args)
+}
+
+func (d *Database) QueryRowContext(ctx context.Context, query string, args ...interface{}) *Row {
+	query, args = d.Dialect.Rebind(query, args)
+	return d.queryRow(ctx, d.conn(ctx), query, args)
+}
+
+// query runs a rebound query on q; the deadline lasts until the rows are closed
+func (d *Database) query(ctx context.Context, q querier, query string, args []interface{}) (*Rows, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, query, args...)
+	d.observe(ctx, start, query, args, err)
 	if err != nil {
-		return fmt.Errorf("failed to create index: %w", err)
-	}
-
-	// Create api_logs table for logging Mekari API requests
-	createAPILogsSQL := `
-	CREATE TABLE IF NOT EXISTS api_logs (
-		id SERIAL PRIMARY KEY,
-		endpoint VARCHAR(500) NOT NULL,
-		method VARCHAR(10) NOT NULL,
-		request_body TEXT,
-		response_body TEXT,
-		status_code INT NOT NULL,
-		duration_ms BIGINT NOT NULL,
-		email VARCHAR(255),
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-	`
-	_, err = d.DB.Exec(createAPILogsSQL)
+		cancel()
+		return nil, err
+	}
+	return &Rows{Rows: rows, cancel: cancel}, nil
+}
+
+// queryRow runs a rebound single-row query on q; the deadline lasts until the row is scanned
+func (d *Database) queryRow(ctx context.Context, q querier, query string, args []interface{}) *Row {
+	ctx, cancel := d.withTimeout(ctx)
+	start := time.Now()
+	row := q.QueryRowContext(ctx, query, args...)
+	d.observe(ctx, start, query, args, row.Err())
+	return &Row{Row: row, cancel: cancel}
+}
+
+// ReadQueryContext runs a read-only query on the replica, or on the primary when no replica is
+// configured or it fails. Replicas lag behind the primary, so it suits logs and reports, not
+// reads that must see a write made just before.
+func (d *Database) ReadQueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	if InTx(ctx) {
+		return d.QueryContext(ctx, query, args...)
+	}
+	query, args = d.Dialect.Rebind(query, args)
+
+	reader := d.reader()
+	rows, err := d.query(ctx, reader, query, args)
+	if err == nil || reader == d.DB || ctx.Err() != nil {
+		return rows, err
+	}
+
+	d.logger.Warn("Database replica query failed, retrying on the primary", zap.Error(err))
+	return d.query(ctx, d.DB, query, args)
+}
+
+// ReadQueryRowContext is QueryRowContext on the replica, with the lag of ReadQueryContext. Errors
+// surface on Scan, so it falls back to the primary only while the replica is marked down.
+func (d *Database) ReadQueryRowContext(ctx context.Context, query string, args ...interface{}) *Row {
+	if InTx(ctx) {
+		return d.QueryRowContext(ctx, query, args...)
+	}
+	query, args = d.Dialect.Rebind(query, args)
+	return d.queryRow(ctx, d.reader(), query, args)
+}
+
+// Insert runs an "INSERT INTO ... VALUES (...)" query and returns the id of the new row
+func (d *Database) Insert(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	query, args = d.Dialect.Rebind(query, args)
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	start := time.Now()
+	id, err := d.Dialect.insertID(ctx, d.conn(ctx), query, args)
+	d.observe(ctx, start, query, args, err)
+	return id, err
+}
+
+// InsertIgnore runs an "INSERT INTO ... VALUES (...)" query unless a row with the same value
+// of the unique conflict column exists. Returns the id of the new row, or false for a duplicate.
+func (d *Database) InsertIgnore(ctx context.Context, query, conflict string, args ...interface{}) (int64, bool, error) {
+	query, args = d.Dialect.Rebind(query, args)
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	start := time.Now()
+	id, inserted, err := d.Dialect.insertIgnore(ctx, d.conn(ctx), query, conflict, args)
+	d.observe(ctx, start, query, args, err)
+	return id, inserted, err
+}
+
+// BeginTx starts a transaction whose query methods accept $N placeholders like Database
+func (d *Database) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := d.DB.BeginTx(ctx, opts)
 	if err != nil {
-		return fmt.Errorf("failed to create api_logs table: %w", err)
+		return nil, err
+	}
+	return &Tx{Tx: tx, db: d}, nil
+}
+
+// WithTx runs fn in a transaction, committed when fn returns nil and rolled back otherwise.
+// Queries of Database made with the ctx of WithTx join the transaction, so repositories
+// take part without passing tx around; a nested WithTx joins the outer transaction.
+func (d *Database) WithTx(ctx context.Context, fn func(tx *Tx) error) (err error) {
+	if tx, ok := ctx.Value(txKey{}).(*Tx); ok {
+		return fn(tx)
 	}
 
-	// Create index for api_logs
-	createAPILogsIndexSQL := `
-	CREATE INDEX IF NOT EXISTS idx_api_logs_created_at ON api_logs(created_at);
-	`
-	_, err = d.DB.Exec(createAPILogsIndexSQL)
+	tx, err := d.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create api_logs index: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	tx.ctx = context.WithValue(ctx, txKey{}, tx)
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
 
-	d.logger.Info("Database migrations completed successfully")
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return nil
 }
 
+// InTx reports whether ctx carries a transaction of WithTx
+func InTx(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{}).(*Tx)
+	return ok
+}
+
 func (d *Database) Close() error {
+	if d.replica != nil {
+		if err := d.replica.Close(); err != nil {
+			d.logger.Warn("Failed to close database replica", zap.Error(err))
+		}
+	}
 	return d.DB.Close()
 }
+
+// txKey is the context key of the transaction of WithTx
+type txKey struct{}
+
+// Tx is a transaction of Database. Its queries have the timeout and slow query logging of
+// Database.
+type Tx struct {
+	*sql.Tx
+	db  *Database
+	ctx context.Context // Set by WithTx
+}
+
+// Context returns the ctx of WithTx carrying the transaction; repository calls made with it
+// run inside the transaction
+func (t *Tx) Context() context.Context {
+	if t.ctx == nil {
+		return context.Background()
+	}
+	return t.ctx
+}
+
+func (t *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	query, args = t.db.Dialect.Rebind(query, args)
+	ctx, cancel := t.db.withTimeout(ctx)
+	defer cancel()
+
+	start := time.Now()
+	result, err := t.Tx.ExecContext(ctx, query, args...)
+	t.db.observe(ctx, start, query, args, err)
+	return result, err
+}
+
+func (t *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	query, args = t.db.Dialect.Rebind(query, args)
+	return t.db.query(ctx, t.Tx, query, args)
+}
+
+func (t *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *Row {
+	query, args = t.db.Dialect.Rebind(query, args)
+	return t.db.queryRow(ctx, t.Tx, query, args)
+}
+
+// Insert is Database.Insert inside the transaction
+func (t *Tx) Insert(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	query, args = t.db.Dialect.Rebind(query, args)
+	ctx, cancel := t.db.withTimeout(ctx)
+	defer cancel()
+
+	start := time.Now()
+	id, err := t.db.Dialect.insertID(ctx, t.Tx, query, args)
+	t.db.observe(ctx, start, query, args, err)
+	return id, err
+}
+
+// InsertIgnore is Database.InsertIgnore inside the transaction
+func (t *Tx) InsertIgnore(ctx context.Context, query, conflict string, args ...interface{}) (int64, bool, error) {
+	query, args = t.db.Dialect.Rebind(query, args)
+	ctx, cancel := t.db.withTimeout(ctx)
+	defer cancel()
+
+	start := time.Now()
+	id, inserted, err := t.db.Dialect.insertIgnore(ctx, t.Tx, query, conflict, args)
+	t.db.observe(ctx, start, query, args, err)
+	return id, inserted, err
+}
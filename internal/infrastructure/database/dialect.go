@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"mekari-esign/internal/config"
+)
+
+// Drivers selectable with database.driver
+const (
+	DriverPostgres  = "postgres"
+	DriverMySQL     = "mysql"
+	DriverSQLServer = "sqlserver"
+)
+
+// Dialect holds the SQL that differs between the supported databases. Repositories write
+// queries with PostgreSQL-style $1, $2... placeholders, which Rebind converts for the driver,
+// and use the Dialect methods for limits, row locking and upserts.
+type Dialect interface {
+	// Name returns the database/sql driver name
+	Name() string
+	// DSN builds the connection string of the database settings. Sessions use UTC.
+	DSN(cfg *config.Config) string
+	// Rebind rewrites the $N placeholders of query for the driver, reordering args when the
+	// driver binds them by position
+	Rebind(query string, args []interface{}) (string, []interface{})
+	// Limit returns the clause limiting an ordered query to limit rows after skipping offset.
+	// Both are SQL expressions, usually placeholders; offset may be empty.
+	Limit(limit, offset string) string
+	// SkipLocked returns the table reference and the query suffix of a SELECT that locks the
+	// selected rows until the end of the transaction, skipping rows locked by others
+	SkipLocked(table string) (from, suffix string)
+	// Upsert returns an INSERT of columns ($1... in order) into table that updates the update
+	// columns instead when a row with the same key exists
+	Upsert(table, key string, columns, update []string) string
+	// SubtractSeconds returns the expression of the timestamp column moved back by the seconds
+	// of the SQL expression seconds, usually a placeholder
+	SubtractSeconds(column, seconds string) string
+	// IdentityInsert returns the statements run in the same transaction before and after rows
+	// with explicit values of the identity column id are inserted into table (empty when none
+	// are needed)
+	IdentityInsert(table string) (before, after string)
+
+	// insertID runs an "INSERT INTO ... VALUES (...)" query and returns the id of the new row
+	insertID(ctx context.Context, q querier, query string, args []interface{}) (int64, error)
+	// insertIgnore runs an INSERT query unless a row with the same conflict column exists.
+	// Returns false for a duplicate.
+	insertIgnore(ctx context.Context, q querier, query, conflict string, args []interface{}) (int64, bool, error)
+	// migrate creates or updates the schema
+	migrate(db *sql.DB) error
+}
+
+// Table is a table of the service schema
+type Table struct {
+	Name     string
+	Identity bool // Has the identity column id
+}
+
+// Tables lists the tables of the service schema in creation order
+var Tables = []Table{
+	{"oauth_tokens", true},
+	{"api_logs", true},
+	{"webhook_events", true},
+	{"document_events", true},
+	{"api_keys", true},
+	{"users", true},
+	{"document_mapping_archive", true},
+	{"document_mappings", false},
+	{"oauth_token_history", true},
+	{"update_history", true},
+	{"schema_migrations", true},
+	{"utc_cutoffs", true},
+}
+
+// querier is implemented by *sql.DB and *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// NewDialect returns the dialect of database.driver
+func NewDialect(driver string) (Dialect, error) {
+	switch driver {
+	case DriverPostgres:
+		return postgresDialect{}, nil
+	case DriverMySQL:
+		return mysqlDialect{}, nil
+	case DriverSQLServer:
+		return sqlServerDialect{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q (expected postgres, mysql or sqlserver)", driver)
+	}
+}
+
+// Placeholders returns "$from, $from+1, ..." for n values, e.g. for an IN list
+func Placeholders(from, n int) string {
+	placeholders := make([]string, n)
+	for i := range placeholders {
+		placeholders[i] = "$" + strconv.Itoa(from+i)
+	}
+	return strings.Join(placeholders, ", ")
+}
+
+// rebind replaces each $N placeholder outside string literals with placeholder(N). With
+// positional set, the returned args hold the value of each placeholder in order of appearance.
+func rebind(query string, args []interface{}, placeholder func(n int) string, positional bool) (string, []interface{}) {
+	var out strings.Builder
+	var bound []interface{}
+	inString := false
+
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		if ch == '\'' {
+			inString = !inString
+		}
+		if ch != '$' || inString {
+			out.WriteByte(ch)
+			continue
+		}
+
+		j := i + 1
+		for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+			j++
+		}
+		n, err := strconv.Atoi(query[i+1 : j])
+		if err != nil || n < 1 || n > len(args) {
+			out.WriteByte(ch)
+			continue
+		}
+
+		out.WriteString(placeholder(n))
+		if positional {
+			bound = append(bound, args[n-1])
+		}
+		i = j - 1
+	}
+
+	if !positional {
+		return out.String(), args
+	}
+	return out.String(), bound
+}
@@ -0,0 +1,307 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	"mekari-esign/internal/config"
+)
+
+// mysqlDialect is the dialect of MySQL 8.0.13 or later (SKIP LOCKED and expression defaults)
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string {
+	return DriverMySQL
+}
+
+func (mysqlDialect) DSN(cfg *config.Config) string {
+	c := mysql.NewConfig()
+	c.User = cfg.Database.User
+	c.Passwd = cfg.Database.Password
+	c.Net = "tcp"
+	c.Addr = fmt.Sprintf("%s:%d", cfg.Database.Host, cfg.Database.Port)
+	c.DBName = cfg.Database.DBName
+	c.ParseTime = true
+	c.Loc = time.UTC
+	c.Params = map[string]string{"time_zone": "'+00:00'"}
+
+	// sslmode keeps its PostgreSQL meaning
+	switch cfg.Database.SSLMode {
+	case "require":
+		c.TLSConfig = "skip-verify"
+	case "verify-ca", "verify-full":
+		c.TLSConfig = "true"
+	case "prefer":
+		c.TLSConfig = "preferred"
+	}
+
+	return c.FormatDSN()
+}
+
+func (mysqlDialect) Rebind(query string, args []interface{}) (string, []interface{}) {
+	return rebind(query, args, func(int) string { return "?" }, true)
+}
+
+func (mysqlDialect) Limit(limit, offset string) string {
+	if offset == "" {
+		return "LIMIT " + limit
+	}
+	return "LIMIT " + limit + " OFFSET " + offset
+}
+
+func (mysqlDialect) SkipLocked(table string) (string, string) {
+	return table, "FOR UPDATE SKIP LOCKED"
+}
+
+func (mysqlDialect) Upsert(table, key string, columns, update []string) string {
+	set := make([]string, len(update))
+	for i, column := range update {
+		set[i] = column + " = VALUES(" + column + ")"
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON DUPLICATE KEY UPDATE %s",
+		table, strings.Join(columns, ", "), Placeholders(1, len(columns)), strings.Join(set, ", "))
+}
+
+func (mysqlDialect) SubtractSeconds(column, seconds string) string {
+	return "DATE_SUB(" + column + ", INTERVAL " + seconds + " SECOND)"
+}
+
+// IdentityInsert needs no statements; AUTO_INCREMENT follows the largest inserted id
+func (mysqlDialect) IdentityInsert(table string) (before, after string) {
+	return "", ""
+}
+
+func (mysqlDialect) insertID(ctx context.Context, q querier, query string, args []interface{}) (int64, error) {
+	result, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (mysqlDialect) insertIgnore(ctx context.Context, q querier, query, conflict string, args []interface{}) (int64, bool, error) {
+	// The no-op update leaves a duplicate untouched and reports no affected row
+	result, err := q.ExecContext(ctx, query+" ON DUPLICATE KEY UPDATE id = id", args...)
+	if err != nil {
+		return 0, false, err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return 0, false, err
+	}
+	id, err := result.LastInsertId()
+	return id, true, err
+}
+
+// mysqlSchema creates the tables with their current columns. Every statement is idempotent.
+var mysqlSchema = []string{
+	`CREATE TABLE IF NOT EXISTS oauth_tokens (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		email VARCHAR(255) NOT NULL UNIQUE,
+		code TEXT NOT NULL,
+		access_token TEXT DEFAULT (''),
+		refresh_token TEXT DEFAULT (''),
+		token_type VARCHAR(50) DEFAULT '',
+		expires_at DATETIME(6),
+		created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+		updated_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+		deleted_at DATETIME(6) NULL
+	) DEFAULT CHARSET=utf8mb4`,
+
+	`CREATE TABLE IF NOT EXISTS api_logs (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		endpoint VARCHAR(500) NOT NULL,
+		invoice_no VARCHAR(255) NOT NULL DEFAULT '',
+		entry_no INT NOT NULL DEFAULT 0,
+		document_id VARCHAR(255) NOT NULL DEFAULT '',
+		source VARCHAR(20) NOT NULL DEFAULT 'mekari',
+		method VARCHAR(10) NOT NULL,
+		request_body LONGTEXT,
+		response_body LONGTEXT,
+		status_code INT NOT NULL,
+		duration_ms BIGINT NOT NULL,
+		email VARCHAR(255),
+		caller VARCHAR(255) NOT NULL DEFAULT '',
+		request_id VARCHAR(100) NOT NULL DEFAULT '',
+		created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+		INDEX idx_api_logs_created_at (created_at),
+		INDEX idx_api_logs_status_code (status_code, id),
+		INDEX idx_api_logs_duration_ms (duration_ms, id),
+		INDEX idx_api_logs_email (email),
+		INDEX idx_api_logs_request_id (request_id),
+		INDEX idx_api_logs_document_id (document_id),
+		INDEX idx_api_logs_invoice_no (invoice_no)
+	) DEFAULT CHARSET=utf8mb4`,
+
+	`CREATE TABLE IF NOT EXISTS webhook_events (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		event_key VARCHAR(64) NOT NULL UNIQUE,
+		document_id VARCHAR(255) NOT NULL,
+		signing_status VARCHAR(50) DEFAULT '',
+		stamping_status VARCHAR(50) DEFAULT '',
+		payload LONGTEXT NOT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		attempts INT NOT NULL DEFAULT 0,
+		last_error TEXT DEFAULT (''),
+		request_id VARCHAR(100) NOT NULL DEFAULT '',
+		claimed_by VARCHAR(100) NOT NULL DEFAULT '',
+		claimed_at DATETIME(6),
+		next_attempt_at DATETIME(6),
+		created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+		processed_at DATETIME(6),
+		INDEX idx_webhook_events_status (status, id),
+		INDEX idx_webhook_events_document_id (document_id)
+	) DEFAULT CHARSET=utf8mb4`,
+
+	`CREATE TABLE IF NOT EXISTS document_events (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		document_id VARCHAR(255) NOT NULL,
+		invoice_no VARCHAR(255) DEFAULT '',
+		entry_no INT DEFAULT 0,
+		event_type VARCHAR(50) NOT NULL,
+		description TEXT DEFAULT (''),
+		actor VARCHAR(255) DEFAULT '',
+		dedupe_key VARCHAR(500) UNIQUE,
+		created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+		INDEX idx_document_events_document_id (document_id, created_at),
+		INDEX idx_document_events_invoice_no (invoice_no, event_type)
+	) DEFAULT CHARSET=utf8mb4`,
+
+	`CREATE TABLE IF NOT EXISTS api_keys (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		key_prefix VARCHAR(20) NOT NULL,
+		key_hash VARCHAR(64) NOT NULL UNIQUE,
+		rate_limit INT NOT NULL DEFAULT 0,
+		last_used_at DATETIME(6),
+		revoked_at DATETIME(6),
+		created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6)
+	) DEFAULT CHARSET=utf8mb4`,
+
+	`CREATE TABLE IF NOT EXISTS users (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		username VARCHAR(100) NOT NULL UNIQUE,
+		password_hash VARCHAR(255) NOT NULL,
+		role VARCHAR(20) NOT NULL,
+		disabled BOOLEAN NOT NULL DEFAULT FALSE,
+		last_login_at DATETIME(6),
+		created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6)
+	) DEFAULT CHARSET=utf8mb4`,
+
+	`CREATE TABLE IF NOT EXISTS document_mapping_archive (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		redis_key VARCHAR(500) NOT NULL,
+		document_id VARCHAR(255) NOT NULL DEFAULT '',
+		invoice_no VARCHAR(255) NOT NULL DEFAULT '',
+		data LONGTEXT NOT NULL,
+		archived_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+		INDEX idx_document_mapping_archive_document_id (document_id),
+		INDEX idx_document_mapping_archive_invoice_no (invoice_no)
+	) DEFAULT CHARSET=utf8mb4`,
+
+	`CREATE TABLE IF NOT EXISTS document_mappings (
+		mapping_key VARCHAR(255) PRIMARY KEY,
+		document_id VARCHAR(255) NOT NULL DEFAULT '',
+		invoice_no VARCHAR(255) NOT NULL DEFAULT '',
+		data LONGTEXT NOT NULL,
+		updated_at DATETIME(6) NOT NULL,
+		INDEX idx_document_mappings_updated_at (updated_at),
+		INDEX idx_document_mappings_invoice_no (invoice_no)
+	) DEFAULT CHARSET=utf8mb4`,
+
+	`CREATE TABLE IF NOT EXISTS oauth_token_history (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		email VARCHAR(255) NOT NULL,
+		grant_type VARCHAR(50) NOT NULL,
+		success BOOLEAN NOT NULL,
+		error TEXT NOT NULL,
+		issued_at DATETIME(6),
+		expires_at DATETIME(6),
+		request_id VARCHAR(100) NOT NULL DEFAULT '',
+		created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+		INDEX idx_oauth_token_history_email (email, created_at)
+	) DEFAULT CHARSET=utf8mb4`,
+
+	`CREATE TABLE IF NOT EXISTS update_history (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		update_key VARCHAR(300) NOT NULL UNIQUE,
+		host VARCHAR(255) NOT NULL,
+		from_version VARCHAR(100) NOT NULL,
+		to_version VARCHAR(100) NOT NULL,
+		kind VARCHAR(20) NOT NULL,
+		result VARCHAR(20) NOT NULL,
+		error TEXT NOT NULL,
+		finished_at DATETIME(6) NOT NULL,
+		created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+		INDEX idx_update_history_finished_at (finished_at)
+	) DEFAULT CHARSET=utf8mb4`,
+
+	`CREATE TABLE IF NOT EXISTS schema_migrations (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		name VARCHAR(100) NOT NULL UNIQUE,
+		created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6)
+	) DEFAULT CHARSET=utf8mb4`,
+
+	`CREATE TABLE IF NOT EXISTS utc_cutoffs (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		table_name VARCHAR(100) NOT NULL UNIQUE,
+		max_id BIGINT NOT NULL,
+		created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6)
+	) DEFAULT CHARSET=utf8mb4`,
+}
+
+// mysqlColumns are added to tables created by earlier versions; MySQL has no ADD COLUMN IF NOT EXISTS
+var mysqlColumns = []struct{ table, column, definition string }{
+	{"oauth_tokens", "deleted_at", "DATETIME(6) NULL"},
+	{"webhook_events", "claimed_by", "VARCHAR(100) NOT NULL DEFAULT ''"},
+	{"webhook_events", "claimed_at", "DATETIME(6) NULL"},
+	{"webhook_events", "next_attempt_at", "DATETIME(6) NULL"},
+}
+
+// mysqlIndexes are added to tables created by earlier versions; MySQL has no CREATE INDEX IF NOT EXISTS
+var mysqlIndexes = []struct{ table, name, columns string }{
+	{"document_events", "idx_document_events_invoice_no", "invoice_no, event_type"},
+}
+
+func (mysqlDialect) migrate(db *sql.DB) error {
+	for _, statement := range mysqlSchema {
+		if _, err := db.Exec(statement); err != nil {
+			return fmt.Errorf("failed to create schema: %w", err)
+		}
+	}
+
+	for _, c := range mysqlColumns {
+		var count int
+		err := db.QueryRow(`SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?`,
+			c.table, c.column).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to check column %s.%s: %w", c.table, c.column, err)
+		}
+		if count > 0 {
+			continue
+		}
+		if _, err := db.Exec("ALTER TABLE " + c.table + " ADD COLUMN " + c.column + " " + c.definition); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", c.table, c.column, err)
+		}
+	}
+
+	for _, i := range mysqlIndexes {
+		var count int
+		err := db.QueryRow(`SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?`,
+			i.table, i.name).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to check index %s: %w", i.name, err)
+		}
+		if count > 0 {
+			continue
+		}
+		if _, err := db.Exec("CREATE INDEX " + i.name + " ON " + i.table + " (" + i.columns + ")"); err != nil {
+			return fmt.Errorf("failed to create index %s: %w", i.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,398 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "github.com/lib/pq"
+
+	"mekari-esign/internal/config"
+)
+
+// postgresDialect is the dialect of PostgreSQL, the default database. Queries are written in
+// its syntax, so most methods return them unchanged.
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string {
+	return DriverPostgres
+}
+
+func (postgresDialect) DSN(cfg *config.Config) string {
+	// CURRENT_TIMESTAMP defaults match the UTC timestamps written by the service
+	return fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s timezone=UTC",
+		cfg.Database.Host,
+		cfg.Database.Port,
+		cfg.Database.User,
+		cfg.Database.Password,
+		cfg.Database.DBName,
+		cfg.Database.SSLMode,
+	)
+}
+
+func (postgresDialect) Rebind(query string, args []interface{}) (string, []interface{}) {
+	return query, args
+}
+
+func (postgresDialect) Limit(limit, offset string) string {
+	if offset == "" {
+		return "LIMIT " + limit
+	}
+	return "LIMIT " + limit + " OFFSET " + offset
+}
+
+func (postgresDialect) SkipLocked(table string) (string, string) {
+	return table, "FOR UPDATE SKIP LOCKED"
+}
+
+func (postgresDialect) Upsert(table, key string, columns, update []string) string {
+	set := make([]string, len(update))
+	for i, column := range update {
+		set[i] = column + " = EXCLUDED." + column
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO UPDATE SET %s",
+		table, strings.Join(columns, ", "), Placeholders(1, len(columns)), key, strings.Join(set, ", "))
+}
+
+// SubtractSeconds multiplies seconds by a one second interval, which also takes a placeholder
+func (postgresDialect) SubtractSeconds(column, seconds string) string {
+	return column + " - " + seconds + " * INTERVAL '1 second'"
+}
+
+// IdentityInsert moves the sequence of id past the restored rows
+func (postgresDialect) IdentityInsert(table string) (before, after string) {
+	return "", fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), COALESCE((SELECT MAX(id) FROM %[1]s), 0) + 1, false)", table)
+}
+
+func (postgresDialect) insertID(ctx context.Context, q querier, query string, args []interface{}) (int64, error) {
+	var id int64
+	err := q.QueryRowContext(ctx, query+" RETURNING id", args...).Scan(&id)
+	return id, err
+}
+
+func (postgresDialect) insertIgnore(ctx context.Context, q querier, query, conflict string, args []interface{}) (int64, bool, error) {
+	var id int64
+	err := q.QueryRowContext(ctx, query+" ON CONFLICT ("+conflict+") DO NOTHING RETURNING id", args...).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return id, true, nil
+}
+
+func (postgresDialect) migrate(db *sql.DB) error {
+	// Create oauth_tokens table (PostgreSQL syntax)
+	createTableSQL := `
+	CREATE TABLE IF NOT EXISTS oauth_tokens (
+		id SERIAL PRIMARY KEY,
+		email VARCHAR(255) NOT NULL UNIQUE,
+		code TEXT NOT NULL,
+		access_token TEXT DEFAULT '',
+		refresh_token TEXT DEFAULT '',
+		token_type VARCHAR(50) DEFAULT '',
+		expires_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	_, err := db.Exec(createTableSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create oauth_tokens table: %w", err)
+	}
+
+	// Create index separately (PostgreSQL doesn't support IF NOT EXISTS in same statement)
+	createIndexSQL := `
+	CREATE INDEX IF NOT EXISTS idx_oauth_tokens_email ON oauth_tokens(email);
+	ALTER TABLE oauth_tokens ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+	`
+	_, err = db.Exec(createIndexSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create index: %w", err)
+	}
+
+	// Create api_logs table for logging Mekari API requests
+	createAPILogsSQL := `
+	CREATE TABLE IF NOT EXISTS api_logs (
+		id SERIAL PRIMARY KEY,
+		endpoint VARCHAR(500) NOT NULL,
+		method VARCHAR(10) NOT NULL,
+		request_body TEXT,
+		response_body TEXT,
+		status_code INT NOT NULL,
+		duration_ms BIGINT NOT NULL,
+		email VARCHAR(255),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	_, err = db.Exec(createAPILogsSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create api_logs table: %w", err)
+	}
+
+	// Create index for api_logs
+	createAPILogsIndexSQL := `
+	CREATE INDEX IF NOT EXISTS idx_api_logs_created_at ON api_logs(created_at);
+	CREATE INDEX IF NOT EXISTS idx_api_logs_status_code ON api_logs(status_code, id);
+	CREATE INDEX IF NOT EXISTS idx_api_logs_duration_ms ON api_logs(duration_ms, id);
+	CREATE INDEX IF NOT EXISTS idx_api_logs_email ON api_logs(LOWER(email));
+	`
+	_, err = db.Exec(createAPILogsIndexSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create api_logs index: %w", err)
+	}
+
+	// Correlation ID of the request that made the call
+	alterAPILogsRequestIDSQL := `
+	ALTER TABLE api_logs ADD COLUMN IF NOT EXISTS request_id VARCHAR(100) NOT NULL DEFAULT '';
+	CREATE INDEX IF NOT EXISTS idx_api_logs_request_id ON api_logs(request_id);
+	`
+	_, err = db.Exec(alterAPILogsRequestIDSQL)
+	if err != nil {
+		return fmt.Errorf("failed to add api_logs request_id column: %w", err)
+	}
+
+	// Document correlation and the traffic source (mekari, nav or inbound) of each row
+	alterAPILogsDocumentSQL := `
+	ALTER TABLE api_logs ADD COLUMN IF NOT EXISTS invoice_no VARCHAR(255) NOT NULL DEFAULT '';
+	ALTER TABLE api_logs ADD COLUMN IF NOT EXISTS entry_no INT NOT NULL DEFAULT 0;
+	ALTER TABLE api_logs ADD COLUMN IF NOT EXISTS document_id VARCHAR(255) NOT NULL DEFAULT '';
+	ALTER TABLE api_logs ADD COLUMN IF NOT EXISTS source VARCHAR(20) NOT NULL DEFAULT 'mekari';
+	CREATE INDEX IF NOT EXISTS idx_api_logs_document_id ON api_logs(document_id);
+	CREATE INDEX IF NOT EXISTS idx_api_logs_invoice_no ON api_logs(invoice_no);
+	`
+	_, err = db.Exec(alterAPILogsDocumentSQL)
+	if err != nil {
+		return fmt.Errorf("failed to add api_logs document columns: %w", err)
+	}
+
+	// API key or user behind the requests saved with logging.inbound
+	alterAPILogsCallerSQL := `
+	ALTER TABLE api_logs ADD COLUMN IF NOT EXISTS caller VARCHAR(255) NOT NULL DEFAULT '';
+	`
+	_, err = db.Exec(alterAPILogsCallerSQL)
+	if err != nil {
+		return fmt.Errorf("failed to add api_logs caller column: %w", err)
+	}
+
+	// Create webhook_events table for out-of-band webhook processing
+	createWebhookEventsSQL := `
+	CREATE TABLE IF NOT EXISTS webhook_events (
+		id BIGSERIAL PRIMARY KEY,
+		event_key VARCHAR(64) NOT NULL UNIQUE,
+		document_id VARCHAR(255) NOT NULL,
+		signing_status VARCHAR(50) DEFAULT '',
+		stamping_status VARCHAR(50) DEFAULT '',
+		payload TEXT NOT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		attempts INT NOT NULL DEFAULT 0,
+		last_error TEXT DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		processed_at TIMESTAMP
+	);
+	`
+	_, err = db.Exec(createWebhookEventsSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook_events table: %w", err)
+	}
+
+	// Request ID of the callback, restored when the event is processed in the background
+	_, err = db.Exec(`ALTER TABLE webhook_events ADD COLUMN IF NOT EXISTS request_id VARCHAR(100) NOT NULL DEFAULT ''`)
+	if err != nil {
+		return fmt.Errorf("failed to add webhook_events request_id column: %w", err)
+	}
+
+	// Instance processing the event, so instances running side by side only reset their own
+	_, err = db.Exec(`
+	ALTER TABLE webhook_events ADD COLUMN IF NOT EXISTS claimed_by VARCHAR(100) NOT NULL DEFAULT '';
+	ALTER TABLE webhook_events ADD COLUMN IF NOT EXISTS claimed_at TIMESTAMP;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add webhook_events claim columns: %w", err)
+	}
+
+	// When a failed event is retried, so an outage does not use up its attempts at once
+	_, err = db.Exec(`ALTER TABLE webhook_events ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMP`)
+	if err != nil {
+		return fmt.Errorf("failed to add webhook_events next_attempt_at column: %w", err)
+	}
+
+	createWebhookEventsIndexSQL := `
+	CREATE INDEX IF NOT EXISTS idx_webhook_events_status ON webhook_events(status, id);
+	CREATE INDEX IF NOT EXISTS idx_webhook_events_document_id ON webhook_events(document_id);
+	`
+	_, err = db.Exec(createWebhookEventsIndexSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook_events index: %w", err)
+	}
+
+	// Create document_events table for the document timeline
+	createDocumentEventsSQL := `
+	CREATE TABLE IF NOT EXISTS document_events (
+		id BIGSERIAL PRIMARY KEY,
+		document_id VARCHAR(255) NOT NULL,
+		invoice_no VARCHAR(255) DEFAULT '',
+		entry_no INT DEFAULT 0,
+		event_type VARCHAR(50) NOT NULL,
+		description TEXT DEFAULT '',
+		actor VARCHAR(255) DEFAULT '',
+		dedupe_key VARCHAR(500) UNIQUE,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	_, err = db.Exec(createDocumentEventsSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create document_events table: %w", err)
+	}
+
+	createDocumentEventsIndexSQL := `
+	CREATE INDEX IF NOT EXISTS idx_document_events_document_id ON document_events(document_id, created_at);
+	CREATE INDEX IF NOT EXISTS idx_document_events_invoice_no ON document_events(invoice_no, event_type);
+	`
+	_, err = db.Exec(createDocumentEventsIndexSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create document_events index: %w", err)
+	}
+
+	// Create api_keys table for client authentication (keys are stored hashed)
+	createAPIKeysSQL := `
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id BIGSERIAL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		key_prefix VARCHAR(20) NOT NULL,
+		key_hash VARCHAR(64) NOT NULL UNIQUE,
+		rate_limit INT NOT NULL DEFAULT 0,
+		last_used_at TIMESTAMP,
+		revoked_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	_, err = db.Exec(createAPIKeysSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create api_keys table: %w", err)
+	}
+
+	// Create users table for role-based access to admin endpoints
+	createUsersSQL := `
+	CREATE TABLE IF NOT EXISTS users (
+		id BIGSERIAL PRIMARY KEY,
+		username VARCHAR(100) NOT NULL UNIQUE,
+		password_hash VARCHAR(255) NOT NULL,
+		role VARCHAR(20) NOT NULL,
+		disabled BOOLEAN NOT NULL DEFAULT FALSE,
+		last_login_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	_, err = db.Exec(createUsersSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create users table: %w", err)
+	}
+
+	// Create document_mapping_archive table for Redis mappings removed by the TTL cleanup
+	createMappingArchiveSQL := `
+	CREATE TABLE IF NOT EXISTS document_mapping_archive (
+		id BIGSERIAL PRIMARY KEY,
+		redis_key VARCHAR(500) NOT NULL,
+		document_id VARCHAR(255) NOT NULL DEFAULT '',
+		invoice_no VARCHAR(255) NOT NULL DEFAULT '',
+		data TEXT NOT NULL,
+		archived_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_document_mapping_archive_document_id ON document_mapping_archive(document_id);
+	CREATE INDEX IF NOT EXISTS idx_document_mapping_archive_invoice_no ON document_mapping_archive(invoice_no);
+	`
+	_, err = db.Exec(createMappingArchiveSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create document_mapping_archive table: %w", err)
+	}
+
+	// Create document_mappings table for mapping.store database and layered
+	createMappingsSQL := `
+	CREATE TABLE IF NOT EXISTS document_mappings (
+		mapping_key VARCHAR(255) PRIMARY KEY,
+		document_id VARCHAR(255) NOT NULL DEFAULT '',
+		invoice_no VARCHAR(255) NOT NULL DEFAULT '',
+		data TEXT NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_document_mappings_updated_at ON document_mappings(updated_at);
+	CREATE INDEX IF NOT EXISTS idx_document_mappings_invoice_no ON document_mappings(invoice_no);
+	`
+	_, err = db.Exec(createMappingsSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create document_mappings table: %w", err)
+	}
+
+	// Create oauth_token_history table for token grant and refresh attempts
+	createTokenHistorySQL := `
+	CREATE TABLE IF NOT EXISTS oauth_token_history (
+		id BIGSERIAL PRIMARY KEY,
+		email VARCHAR(255) NOT NULL,
+		grant_type VARCHAR(50) NOT NULL,
+		success BOOLEAN NOT NULL,
+		error TEXT NOT NULL DEFAULT '',
+		issued_at TIMESTAMP,
+		expires_at TIMESTAMP,
+		request_id VARCHAR(100) NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_oauth_token_history_email ON oauth_token_history(email, created_at);
+	`
+	_, err = db.Exec(createTokenHistorySQL)
+	if err != nil {
+		return fmt.Errorf("failed to create oauth_token_history table: %w", err)
+	}
+
+	// Create update_history table for the outcome of updates and rollbacks of each host
+	createUpdateHistorySQL := `
+	CREATE TABLE IF NOT EXISTS update_history (
+		id BIGSERIAL PRIMARY KEY,
+		update_key VARCHAR(300) NOT NULL UNIQUE,
+		host VARCHAR(255) NOT NULL,
+		from_version VARCHAR(100) NOT NULL,
+		to_version VARCHAR(100) NOT NULL,
+		kind VARCHAR(20) NOT NULL,
+		result VARCHAR(20) NOT NULL,
+		error TEXT NOT NULL DEFAULT '',
+		finished_at TIMESTAMP NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_update_history_finished_at ON update_history(finished_at);
+	`
+	_, err = db.Exec(createUpdateHistorySQL)
+	if err != nil {
+		return fmt.Errorf("failed to create update_history table: %w", err)
+	}
+
+	// Create schema_migrations table for one-time data migrations, see convertLegacyTimestamps
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		id BIGSERIAL PRIMARY KEY,
+		name VARCHAR(100) NOT NULL UNIQUE,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	// Create utc_cutoffs table for the last rows written in local time, see recordUTCSwitch
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS utc_cutoffs (
+		id BIGSERIAL PRIMARY KEY,
+		table_name VARCHAR(100) NOT NULL UNIQUE,
+		max_id BIGINT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create utc_cutoffs table: %w", err)
+	}
+
+	return nil
+}
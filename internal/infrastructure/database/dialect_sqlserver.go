@@ -0,0 +1,324 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	_ "github.com/microsoft/go-mssqldb"
+
+	"mekari-esign/internal/config"
+)
+
+// SQL Server errors of a duplicate key in a unique constraint or unique index
+const (
+	sqlServerUniqueConstraint = 2627
+	sqlServerUniqueIndex      = 2601
+)
+
+// sqlServerDialect is the dialect of SQL Server 2016 or later. Timestamps are stored in
+// DATETIME2 columns, with SYSUTCDATETIME() defaults.
+type sqlServerDialect struct{}
+
+func (sqlServerDialect) Name() string {
+	return DriverSQLServer
+}
+
+func (sqlServerDialect) DSN(cfg *config.Config) string {
+	query := url.Values{}
+	query.Set("database", cfg.Database.DBName)
+
+	// sslmode keeps its PostgreSQL meaning
+	switch cfg.Database.SSLMode {
+	case "disable":
+		query.Set("encrypt", "disable")
+	case "require":
+		query.Set("encrypt", "true")
+		query.Set("TrustServerCertificate", "true")
+	case "verify-ca", "verify-full":
+		query.Set("encrypt", "true")
+	}
+
+	dsn := &url.URL{
+		Scheme:   "sqlserver",
+		User:     url.UserPassword(cfg.Database.User, cfg.Database.Password),
+		Host:     fmt.Sprintf("%s:%d", cfg.Database.Host, cfg.Database.Port),
+		RawQuery: query.Encode(),
+	}
+	return dsn.String()
+}
+
+func (sqlServerDialect) Rebind(query string, args []interface{}) (string, []interface{}) {
+	return rebind(query, args, func(n int) string { return "@p" + strconv.Itoa(n) }, false)
+}
+
+func (sqlServerDialect) Limit(limit, offset string) string {
+	if offset == "" {
+		offset = "0"
+	}
+	return "OFFSET " + offset + " ROWS FETCH NEXT " + limit + " ROWS ONLY"
+}
+
+func (sqlServerDialect) SkipLocked(table string) (string, string) {
+	return table + " WITH (UPDLOCK, READPAST, ROWLOCK)", ""
+}
+
+func (sqlServerDialect) Upsert(table, key string, columns, update []string) string {
+	source := make([]string, len(columns))
+	values := make([]string, len(columns))
+	for i, column := range columns {
+		source[i] = fmt.Sprintf("$%d AS %s", i+1, column)
+		values[i] = "source." + column
+	}
+	set := make([]string, len(update))
+	for i, column := range update {
+		set[i] = column + " = source." + column
+	}
+
+	return fmt.Sprintf(`MERGE INTO %s WITH (HOLDLOCK) AS target
+		USING (SELECT %s) AS source ON target.%s = source.%s
+		WHEN MATCHED THEN UPDATE SET %s
+		WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s);`,
+		table, strings.Join(source, ", "), key, key,
+		strings.Join(set, ", "),
+		strings.Join(columns, ", "), strings.Join(values, ", "))
+}
+
+// outputID adds an OUTPUT clause returning the new id before the VALUES of an INSERT
+func outputID(query string) string {
+	i := strings.Index(query, "VALUES")
+	if i < 0 {
+		return query
+	}
+	return query[:i] + "OUTPUT INSERTED.id " + query[i:]
+}
+
+func (sqlServerDialect) SubtractSeconds(column, seconds string) string {
+	return "DATEADD(SECOND, -" + seconds + ", " + column + ")"
+}
+
+// IdentityInsert allows explicit ids; the identity seed follows the largest inserted id
+func (sqlServerDialect) IdentityInsert(table string) (before, after string) {
+	return "SET IDENTITY_INSERT " + table + " ON", "SET IDENTITY_INSERT " + table + " OFF"
+}
+
+func (sqlServerDialect) insertID(ctx context.Context, q querier, query string, args []interface{}) (int64, error) {
+	var id int64
+	err := q.QueryRowContext(ctx, outputID(query), args...).Scan(&id)
+	return id, err
+}
+
+func (sqlServerDialect) insertIgnore(ctx context.Context, q querier, query, conflict string, args []interface{}) (int64, bool, error) {
+	var id int64
+	err := q.QueryRowContext(ctx, outputID(query), args...).Scan(&id)
+
+	var sqlErr interface{ SQLErrorNumber() int32 }
+	if errors.As(err, &sqlErr) {
+		if number := sqlErr.SQLErrorNumber(); number == sqlServerUniqueConstraint || number == sqlServerUniqueIndex {
+			return 0, false, nil
+		}
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return id, true, nil
+}
+
+// sqlServerSchema creates the tables with their current columns. Every statement is idempotent.
+var sqlServerSchema = []string{
+	`IF OBJECT_ID(N'oauth_tokens', N'U') IS NULL
+	CREATE TABLE oauth_tokens (
+		id BIGINT IDENTITY(1,1) PRIMARY KEY,
+		email NVARCHAR(255) NOT NULL UNIQUE,
+		code NVARCHAR(MAX) NOT NULL,
+		access_token NVARCHAR(MAX) DEFAULT '',
+		refresh_token NVARCHAR(MAX) DEFAULT '',
+		token_type NVARCHAR(50) DEFAULT '',
+		expires_at DATETIME2,
+		created_at DATETIME2 DEFAULT SYSUTCDATETIME(),
+		updated_at DATETIME2 DEFAULT SYSUTCDATETIME(),
+		deleted_at DATETIME2 NULL
+	)`,
+	`IF COL_LENGTH('oauth_tokens', 'deleted_at') IS NULL
+	ALTER TABLE oauth_tokens ADD deleted_at DATETIME2 NULL`,
+
+	`IF OBJECT_ID(N'api_logs', N'U') IS NULL
+	CREATE TABLE api_logs (
+		id BIGINT IDENTITY(1,1) PRIMARY KEY,
+		endpoint NVARCHAR(500) NOT NULL,
+		invoice_no NVARCHAR(255) NOT NULL DEFAULT '',
+		entry_no INT NOT NULL DEFAULT 0,
+		document_id NVARCHAR(255) NOT NULL DEFAULT '',
+		source NVARCHAR(20) NOT NULL DEFAULT 'mekari',
+		method NVARCHAR(10) NOT NULL,
+		request_body NVARCHAR(MAX),
+		response_body NVARCHAR(MAX),
+		status_code INT NOT NULL,
+		duration_ms BIGINT NOT NULL,
+		email NVARCHAR(255),
+		caller NVARCHAR(255) NOT NULL DEFAULT '',
+		request_id NVARCHAR(100) NOT NULL DEFAULT '',
+		created_at DATETIME2 DEFAULT SYSUTCDATETIME(),
+		INDEX idx_api_logs_created_at (created_at),
+		INDEX idx_api_logs_status_code (status_code, id),
+		INDEX idx_api_logs_duration_ms (duration_ms, id),
+		INDEX idx_api_logs_email (email),
+		INDEX idx_api_logs_request_id (request_id),
+		INDEX idx_api_logs_document_id (document_id),
+		INDEX idx_api_logs_invoice_no (invoice_no)
+	)`,
+
+	`IF OBJECT_ID(N'webhook_events', N'U') IS NULL
+	CREATE TABLE webhook_events (
+		id BIGINT IDENTITY(1,1) PRIMARY KEY,
+		event_key NVARCHAR(64) NOT NULL UNIQUE,
+		document_id NVARCHAR(255) NOT NULL,
+		signing_status NVARCHAR(50) DEFAULT '',
+		stamping_status NVARCHAR(50) DEFAULT '',
+		payload NVARCHAR(MAX) NOT NULL,
+		status NVARCHAR(20) NOT NULL DEFAULT 'pending',
+		attempts INT NOT NULL DEFAULT 0,
+		last_error NVARCHAR(MAX) DEFAULT '',
+		request_id NVARCHAR(100) NOT NULL DEFAULT '',
+		claimed_by NVARCHAR(100) NOT NULL DEFAULT '',
+		claimed_at DATETIME2,
+		next_attempt_at DATETIME2,
+		created_at DATETIME2 DEFAULT SYSUTCDATETIME(),
+		processed_at DATETIME2,
+		INDEX idx_webhook_events_status (status, id),
+		INDEX idx_webhook_events_document_id (document_id)
+	)`,
+
+	`IF COL_LENGTH('webhook_events', 'claimed_by') IS NULL
+	ALTER TABLE webhook_events ADD claimed_by NVARCHAR(100) NOT NULL DEFAULT ''`,
+
+	`IF COL_LENGTH('webhook_events', 'claimed_at') IS NULL
+	ALTER TABLE webhook_events ADD claimed_at DATETIME2 NULL`,
+
+	`IF COL_LENGTH('webhook_events', 'next_attempt_at') IS NULL
+	ALTER TABLE webhook_events ADD next_attempt_at DATETIME2 NULL`,
+
+	`IF OBJECT_ID(N'document_events', N'U') IS NULL
+	CREATE TABLE document_events (
+		id BIGINT IDENTITY(1,1) PRIMARY KEY,
+		document_id NVARCHAR(255) NOT NULL,
+		invoice_no NVARCHAR(255) DEFAULT '',
+		entry_no INT DEFAULT 0,
+		event_type NVARCHAR(50) NOT NULL,
+		description NVARCHAR(MAX) DEFAULT '',
+		actor NVARCHAR(255) DEFAULT '',
+		dedupe_key NVARCHAR(500),
+		created_at DATETIME2 DEFAULT SYSUTCDATETIME(),
+		INDEX idx_document_events_document_id (document_id, created_at)
+	)`,
+
+	// A UNIQUE constraint would allow a single NULL; events without a dedupe key store NULL
+	`IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'ux_document_events_dedupe_key')
+	CREATE UNIQUE INDEX ux_document_events_dedupe_key ON document_events(dedupe_key) WHERE dedupe_key IS NOT NULL`,
+
+	`IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'idx_document_events_invoice_no')
+	CREATE INDEX idx_document_events_invoice_no ON document_events(invoice_no, event_type)`,
+
+	`IF OBJECT_ID(N'api_keys', N'U') IS NULL
+	CREATE TABLE api_keys (
+		id BIGINT IDENTITY(1,1) PRIMARY KEY,
+		name NVARCHAR(255) NOT NULL,
+		key_prefix NVARCHAR(20) NOT NULL,
+		key_hash NVARCHAR(64) NOT NULL UNIQUE,
+		rate_limit INT NOT NULL DEFAULT 0,
+		last_used_at DATETIME2,
+		revoked_at DATETIME2,
+		created_at DATETIME2 DEFAULT SYSUTCDATETIME()
+	)`,
+
+	`IF OBJECT_ID(N'users', N'U') IS NULL
+	CREATE TABLE users (
+		id BIGINT IDENTITY(1,1) PRIMARY KEY,
+		username NVARCHAR(100) NOT NULL UNIQUE,
+		password_hash NVARCHAR(255) NOT NULL,
+		role NVARCHAR(20) NOT NULL,
+		disabled BIT NOT NULL DEFAULT 0,
+		last_login_at DATETIME2,
+		created_at DATETIME2 DEFAULT SYSUTCDATETIME()
+	)`,
+
+	`IF OBJECT_ID(N'document_mapping_archive', N'U') IS NULL
+	CREATE TABLE document_mapping_archive (
+		id BIGINT IDENTITY(1,1) PRIMARY KEY,
+		redis_key NVARCHAR(500) NOT NULL,
+		document_id NVARCHAR(255) NOT NULL DEFAULT '',
+		invoice_no NVARCHAR(255) NOT NULL DEFAULT '',
+		data NVARCHAR(MAX) NOT NULL,
+		archived_at DATETIME2 DEFAULT SYSUTCDATETIME(),
+		INDEX idx_document_mapping_archive_document_id (document_id),
+		INDEX idx_document_mapping_archive_invoice_no (invoice_no)
+	)`,
+
+	`IF OBJECT_ID(N'document_mappings', N'U') IS NULL
+	CREATE TABLE document_mappings (
+		mapping_key NVARCHAR(255) NOT NULL PRIMARY KEY,
+		document_id NVARCHAR(255) NOT NULL DEFAULT '',
+		invoice_no NVARCHAR(255) NOT NULL DEFAULT '',
+		data NVARCHAR(MAX) NOT NULL,
+		updated_at DATETIME2 NOT NULL,
+		INDEX idx_document_mappings_updated_at (updated_at),
+		INDEX idx_document_mappings_invoice_no (invoice_no)
+	)`,
+
+	`IF OBJECT_ID(N'oauth_token_history', N'U') IS NULL
+	CREATE TABLE oauth_token_history (
+		id BIGINT IDENTITY(1,1) PRIMARY KEY,
+		email NVARCHAR(255) NOT NULL,
+		grant_type NVARCHAR(50) NOT NULL,
+		success BIT NOT NULL,
+		error NVARCHAR(MAX) NOT NULL DEFAULT '',
+		issued_at DATETIME2,
+		expires_at DATETIME2,
+		request_id NVARCHAR(100) NOT NULL DEFAULT '',
+		created_at DATETIME2 DEFAULT SYSUTCDATETIME(),
+		INDEX idx_oauth_token_history_email (email, created_at)
+	)`,
+
+	`IF OBJECT_ID(N'update_history', N'U') IS NULL
+	CREATE TABLE update_history (
+		id BIGINT IDENTITY(1,1) PRIMARY KEY,
+		update_key NVARCHAR(300) NOT NULL UNIQUE,
+		host NVARCHAR(255) NOT NULL,
+		from_version NVARCHAR(100) NOT NULL,
+		to_version NVARCHAR(100) NOT NULL,
+		kind NVARCHAR(20) NOT NULL,
+		result NVARCHAR(20) NOT NULL,
+		error NVARCHAR(MAX) NOT NULL DEFAULT '',
+		finished_at DATETIME2 NOT NULL,
+		created_at DATETIME2 DEFAULT SYSUTCDATETIME(),
+		INDEX idx_update_history_finished_at (finished_at)
+	)`,
+
+	`IF OBJECT_ID(N'schema_migrations', N'U') IS NULL
+	CREATE TABLE schema_migrations (
+		id BIGINT IDENTITY(1,1) PRIMARY KEY,
+		name NVARCHAR(100) NOT NULL UNIQUE,
+		created_at DATETIME2 DEFAULT SYSUTCDATETIME()
+	)`,
+
+	`IF OBJECT_ID(N'utc_cutoffs', N'U') IS NULL
+	CREATE TABLE utc_cutoffs (
+		id BIGINT IDENTITY(1,1) PRIMARY KEY,
+		table_name NVARCHAR(100) NOT NULL UNIQUE,
+		max_id BIGINT NOT NULL,
+		created_at DATETIME2 DEFAULT SYSUTCDATETIME()
+	)`,
+}
+
+func (sqlServerDialect) migrate(db *sql.DB) error {
+	for _, statement := range sqlServerSchema {
+		if _, err := db.Exec(statement); err != nil {
+			return fmt.Errorf("failed to create schema: %w", err)
+		}
+	}
+	return nil
+}
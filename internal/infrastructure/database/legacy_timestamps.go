@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"mekari-esign/internal/timeutil"
+)
+
+// legacyTimestampsMigration names the conversion of local timestamps in schema_migrations
+const legacyTimestampsMigration = "utc_timestamps"
+
+// legacyTimestampColumns are the timestamp columns of the tables that existed while timestamps
+// were written in the server's local time. Tables and columns added since only hold UTC. The
+// created columns never change; the updated ones may have been written again since the switch.
+var legacyTimestampColumns = []struct {
+	table   string
+	created []string
+	updated []string
+}{
+	{"oauth_tokens", []string{"created_at"}, []string{"expires_at", "updated_at"}},
+	{"api_logs", []string{"created_at"}, nil},
+	{"webhook_events", []string{"created_at"}, []string{"processed_at"}},
+	{"document_events", []string{"created_at"}, nil},
+	{"api_keys", []string{"created_at"}, []string{"last_used_at", "revoked_at"}},
+	{"users", []string{"created_at"}, []string{"last_login_at"}},
+}
+
+// recordUTCSwitch records in utc_cutoffs, once per database, the last row of each table of
+// legacyTimestampColumns and when this version first started on it. Rows up to it were written in
+// local time. It runs on every start before anything is written, so database.legacy_offset set
+// later still only converts those rows.
+func (d *Database) recordUTCSwitch(ctx context.Context) error {
+	now := timeutil.Now()
+	return d.WithTx(ctx, func(tx *Tx) error {
+		for _, t := range legacyTimestampColumns {
+			var maxID int64
+			if err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM "+t.table).Scan(&maxID); err != nil {
+				return fmt.Errorf("failed to read the last %s row: %w", t.table, err)
+			}
+			_, _, err := tx.InsertIgnore(ctx, `INSERT INTO utc_cutoffs (table_name, max_id, created_at) VALUES ($1, $2, $3)`, "table_name",
+				t.table, maxID, now)
+			if err != nil {
+				return fmt.Errorf("failed to record the last %s row: %w", t.table, err)
+			}
+		}
+		return nil
+	})
+}
+
+// convertLegacyTimestamps converts the timestamps written in local time, offset seconds east of
+// UTC, to UTC. Only rows up to the cutoffs of recordUTCSwitch are converted; of the updated
+// columns only values before the switch, so values written in UTC since are kept. It runs once
+// per database: the conversion is recorded in schema_migrations in the same transaction, so
+// instances starting together or restarts with the setting left in place do not convert twice.
+// Returns whether rows were converted.
+func (d *Database) convertLegacyTimestamps(ctx context.Context, offset int) (bool, error) {
+	if offset == 0 {
+		return false, nil
+	}
+
+	// Large api_logs tables take longer than database.query_timeout
+	ctx = WithoutQueryTimeout(ctx)
+	converted := false
+	err := d.WithTx(ctx, func(tx *Tx) error {
+		_, inserted, err := tx.InsertIgnore(ctx, `INSERT INTO schema_migrations (name, created_at) VALUES ($1, $2)`, "name",
+			legacyTimestampsMigration, timeutil.Now())
+		if err != nil {
+			return fmt.Errorf("failed to record timestamp conversion: %w", err)
+		}
+		if !inserted {
+			return nil // Converted before
+		}
+
+		for _, t := range legacyTimestampColumns {
+			var (
+				maxID      int64
+				switchedAt time.Time
+			)
+			err := tx.QueryRowContext(ctx, `SELECT max_id, created_at FROM utc_cutoffs WHERE table_name = $1`, t.table).Scan(&maxID, &switchedAt)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("failed to read the %s cutoff: %w", t.table, err)
+			}
+			if maxID == 0 {
+				continue // No rows before the switch
+			}
+
+			set := make([]string, 0, len(t.created)+len(t.updated))
+			for _, column := range t.created {
+				set = append(set, column+" = "+d.Dialect.SubtractSeconds(column, "$1"))
+			}
+			for _, column := range t.updated {
+				set = append(set, fmt.Sprintf("%[1]s = CASE WHEN %[1]s < $2 THEN %[2]s ELSE %[1]s END",
+					column, d.Dialect.SubtractSeconds(column, "$1")))
+			}
+			query := "UPDATE " + t.table + " SET " + strings.Join(set, ", ") + " WHERE id <= $3"
+			if _, err := tx.ExecContext(ctx, query, offset, switchedAt, maxID); err != nil {
+				return fmt.Errorf("failed to convert %s timestamps: %w", t.table, err)
+			}
+		}
+		converted = true
+		return nil
+	})
+	return converted, err
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// noTimeoutKey marks a ctx whose queries are not bounded by database.query_timeout
+type noTimeoutKey struct{}
+
+// WithoutQueryTimeout returns ctx for queries that may legitimately run longer than
+// database.query_timeout, such as exports streaming every row of a table. Deadlines of ctx
+// itself still apply.
+func WithoutQueryTimeout(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noTimeoutKey{}, true)
+}
+
+// Rows is *sql.Rows whose query deadline is released by Close
+type Rows struct {
+	*sql.Rows
+	cancel context.CancelFunc
+}
+
+func (r *Rows) Close() error {
+	err := r.Rows.Close()
+	r.cancel()
+	return err
+}
+
+// Row is *sql.Row whose query deadline is released by Scan
+type Row struct {
+	*sql.Row
+	cancel context.CancelFunc
+}
+
+func (r *Row) Scan(dest ...interface{}) error {
+	defer r.cancel()
+	return r.Row.Scan(dest...)
+}
+
+// withTimeout bounds ctx by database.query_timeout, unless it ends sooner or is marked by
+// WithoutQueryTimeout. Without the bound a hung server would block the caller forever.
+func (d *Database) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.queryTimeout <= 0 || ctx.Value(noTimeoutKey{}) != nil {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d.queryTimeout)
+}
+
+// observe logs a query that took longer than database.slow_query_ms, and one cancelled by
+// database.query_timeout. For queries returning rows the time is until the first result.
+func (d *Database) observe(ctx context.Context, start time.Time, query string, args []interface{}, err error) {
+	elapsed := time.Since(start)
+	timedOut := err != nil && ctx.Err() == context.DeadlineExceeded
+	if !timedOut && (d.slowQuery <= 0 || elapsed < d.slowQuery) {
+		return
+	}
+
+	fields := []zap.Field{
+		zap.String("query", strings.Join(strings.Fields(query), " ")),
+		zap.Strings("args", redactArgs(args)),
+		zap.Duration("duration", elapsed),
+	}
+	if timedOut {
+		d.logger.Error("Database query timed out", fields...)
+		return
+	}
+	d.logger.Warn("Slow database query", fields...)
+}
+
+// redactArgs renders query arguments for the log. Text may hold tokens, codes or personal data
+// and only shows its length; numbers, booleans and times are shown as they are.
+func redactArgs(args []interface{}) []string {
+	rendered := make([]string, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case nil:
+			rendered[i] = "NULL"
+		case string:
+			rendered[i] = fmt.Sprintf("[redacted, %d chars]", len(v))
+		case []byte:
+			rendered[i] = fmt.Sprintf("[redacted, %d bytes]", len(v))
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, bool:
+			rendered[i] = fmt.Sprint(v)
+		case time.Time:
+			rendered[i] = v.Format(time.RFC3339Nano)
+		default:
+			rendered[i] = fmt.Sprintf("[%T]", v)
+		}
+	}
+	return rendered
+}
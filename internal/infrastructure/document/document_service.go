@@ -1,17 +1,24 @@
 package document
 
 import (
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"go.uber.org/zap"
 
 	"mekari-esign/internal/config"
+	"mekari-esign/internal/infrastructure/startup"
 )
 
+// ErrDocumentNotFound is returned when no document in the ready folder matches an invoice number
+var ErrDocumentNotFound = errors.New("document not found")
+
 // DocumentService handles document file operations
 type DocumentService interface {
 	// FindDocumentByInvoiceNumber finds a document in the ready folder by invoice number
@@ -59,21 +66,53 @@ type DocumentService interface {
 
 	// GetFinishPath returns the full path to finish folder
 	GetFinishPath() string
+
+	// GetFailedPath returns the full path to the folder of documents that expired unsigned
+	GetFailedPath() string
+
+	// MoveFromProgressWithPath moves a document from the progress folder to targetPath
+	MoveFromProgressWithPath(filename string, progressPath, targetPath string) error
+
+	// FindFile returns the first of dirs holding filename with its content
+	FindFile(filename string, dirs ...string) (dir string, content []byte, err error)
+
+	// DeleteFileWithPath removes a document from dir
+	DeleteFileWithPath(filename string, dir string) error
+
+	// SaveFileWithPath writes content to filename in dir and returns the path of the file
+	SaveFileWithPath(filename string, content []byte, dir string) (string, error)
+
+	// ListFiles lists the document files in a folder
+	ListFiles(dir string) ([]DocumentFile, error)
+
+	// EnsureDirectories creates the configured ready, progress and finish folders if missing
+	EnsureDirectories() error
+}
+
+// DocumentFile is a document file found in a folder
+type DocumentFile struct {
+	Name    string
+	Size    int64
+	ModTime time.Time // Last change; set when the file is moved to progress
 }
 
 type documentService struct {
-	config *config.DocumentConfig
+	config *config.Config // Document settings are read per call, so config reloads apply
 	logger *zap.Logger
 }
 
 func NewDocumentService(cfg *config.Config, logger *zap.Logger) (DocumentService, error) {
+	logger = logger.Named("document")
 	svc := &documentService{
-		config: &cfg.Document,
+		config: cfg,
 		logger: logger,
 	}
 
-	// Ensure all directories exist
-	if err := svc.ensureDirectories(); err != nil {
+	// Ensure all directories exist, waiting for a network share that is not connected yet
+	err := startup.Wait(cfg, logger, "document folders", func(context.Context) error {
+		return svc.EnsureDirectories()
+	})
+	if err != nil {
 		return nil, fmt.Errorf("failed to create document directories: %w", err)
 	}
 
@@ -87,11 +126,12 @@ func NewDocumentService(cfg *config.Config, logger *zap.Logger) (DocumentService
 	return svc, nil
 }
 
-func (s *documentService) ensureDirectories() error {
+func (s *documentService) EnsureDirectories() error {
 	dirs := []string{
 		s.GetReadyPath(),
 		s.GetProgressPath(),
 		s.GetFinishPath(),
+		s.GetFailedPath(),
 	}
 
 	for _, dir := range dirs {
@@ -104,15 +144,23 @@ func (s *documentService) ensureDirectories() error {
 }
 
 func (s *documentService) GetReadyPath() string {
-	return filepath.Join(s.config.BasePath, s.config.ReadyFolder)
+	doc := s.config.DocumentSettings()
+	return filepath.Join(doc.BasePath, doc.ReadyFolder)
 }
 
 func (s *documentService) GetProgressPath() string {
-	return filepath.Join(s.config.BasePath, s.config.ProgressFolder)
+	doc := s.config.DocumentSettings()
+	return filepath.Join(doc.BasePath, doc.ProgressFolder)
 }
 
 func (s *documentService) GetFinishPath() string {
-	return filepath.Join(s.config.BasePath, s.config.FinishFolder)
+	doc := s.config.DocumentSettings()
+	return filepath.Join(doc.BasePath, doc.FinishFolder)
+}
+
+func (s *documentService) GetFailedPath() string {
+	doc := s.config.DocumentSettings()
+	return filepath.Join(doc.BasePath, doc.FailedFolder)
 }
 
 func (s *documentService) FindDocumentByInvoiceNumber(invoiceNumber string) (string, string, error) {
@@ -131,7 +179,7 @@ func (s *documentService) FindDocumentByInvoiceNumber(invoiceNumber string) (str
 
 	// Search for file matching invoice number pattern
 	// Pattern: {prefix}{invoice_number}*.pdf or {invoice_number}*.pdf
-	extension := s.config.FileExtension
+	extension := s.config.DocumentSettings().FileExtension
 	if extension == "" {
 		extension = ".pdf"
 	}
@@ -162,7 +210,7 @@ func (s *documentService) FindDocumentByInvoiceNumber(invoiceNumber string) (str
 	}
 
 	if matchedFile == "" {
-		return "", "", fmt.Errorf("document not found for invoice number: %s", invoiceNumber)
+		return "", "", fmt.Errorf("%w for invoice number: %s", ErrDocumentNotFound, invoiceNumber)
 	}
 
 	// Read file content
@@ -197,6 +245,7 @@ func (s *documentService) MoveToProgress(filename string) error {
 	if err := os.Rename(srcPath, dstPath); err != nil {
 		return fmt.Errorf("failed to move document to progress: %w", err)
 	}
+	touchFile(dstPath, s.logger)
 
 	s.logger.Info("Document moved to progress successfully",
 		zap.String("filename", filename),
@@ -240,7 +289,7 @@ func (s *documentService) FindFilenameInProgress(invoiceNumber string) (string,
 		return "", fmt.Errorf("failed to read progress folder: %w", err)
 	}
 
-	extension := s.config.FileExtension
+	extension := s.config.DocumentSettings().FileExtension
 	if extension == "" {
 		extension = ".pdf"
 	}
@@ -383,7 +432,7 @@ func (s *documentService) FindDocumentByInvoiceNumberWithPath(invoiceNumber stri
 		return "", "", fmt.Errorf("failed to read ready folder: %w", err)
 	}
 
-	extension := s.config.FileExtension
+	extension := s.config.DocumentSettings().FileExtension
 	if extension == "" {
 		extension = ".pdf"
 	}
@@ -411,7 +460,7 @@ func (s *documentService) FindDocumentByInvoiceNumberWithPath(invoiceNumber stri
 	}
 
 	if matchedFile == "" {
-		return "", "", fmt.Errorf("document not found for invoice number: %s", invoiceNumber)
+		return "", "", fmt.Errorf("%w for invoice number: %s", ErrDocumentNotFound, invoiceNumber)
 	}
 
 	// Read file content
@@ -442,7 +491,7 @@ func (s *documentService) FindFilenameInProgressWithPath(invoiceNumber string, p
 		return "", fmt.Errorf("failed to read progress folder: %w", err)
 	}
 
-	extension := s.config.FileExtension
+	extension := s.config.DocumentSettings().FileExtension
 	if extension == "" {
 		extension = ".pdf"
 	}
@@ -484,6 +533,7 @@ func (s *documentService) MoveToProgressWithPath(filename string, readyPath, pro
 	if err := os.Rename(srcPath, dstPath); err != nil {
 		return fmt.Errorf("failed to move document to progress: %w", err)
 	}
+	touchFile(dstPath, s.logger)
 
 	s.logger.Info("Document moved to progress successfully",
 		zap.String("filename", filename),
@@ -492,6 +542,72 @@ func (s *documentService) MoveToProgressWithPath(filename string, readyPath, pro
 	return nil
 }
 
+func (s *documentService) MoveFromProgressWithPath(filename string, progressPath, targetPath string) error {
+	srcPath := filepath.Join(progressPath, filename)
+	dstPath := filepath.Join(targetPath, filename)
+
+	s.logger.Info("Moving document out of progress",
+		zap.String("filename", filename),
+		zap.String("from", srcPath),
+		zap.String("to", dstPath),
+	)
+
+	if err := os.MkdirAll(targetPath, 0755); err != nil {
+		return fmt.Errorf("failed to ensure directory %s: %w", targetPath, err)
+	}
+
+	if err := os.Rename(srcPath, dstPath); err != nil {
+		return fmt.Errorf("failed to move document from progress: %w", err)
+	}
+
+	return nil
+}
+
+func (s *documentService) FindFile(filename string, dirs ...string) (string, []byte, error) {
+	filename = filepath.Base(filename)
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, filename))
+		if err == nil {
+			return dir, content, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", nil, fmt.Errorf("failed to read document %s: %w", filename, err)
+		}
+	}
+	return "", nil, fmt.Errorf("document %s not found: %w", filename, os.ErrNotExist)
+}
+
+func (s *documentService) DeleteFileWithPath(filename string, dir string) error {
+	filePath := filepath.Join(dir, filepath.Base(filename))
+
+	s.logger.Info("Deleting document", zap.String("path", filePath))
+
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+	return nil
+}
+
+func (s *documentService) SaveFileWithPath(filename string, content []byte, dir string) (string, error) {
+	filePath := filepath.Join(dir, filepath.Base(filename))
+
+	s.logger.Info("Saving file",
+		zap.String("path", filePath),
+		zap.Int("size_bytes", len(content)),
+	)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to ensure directory: %w", err)
+	}
+	if err := os.WriteFile(filePath, content, 0644); err != nil {
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+	return filePath, nil
+}
+
 func (s *documentService) ReplaceFileInProgressWithPath(filename string, content []byte, progressPath string) error {
 	filePath := filepath.Join(progressPath, filename)
 
@@ -546,3 +662,37 @@ func (s *documentService) SaveToFinishAndDeleteProgressWithPath(filename string,
 
 	return nil
 }
+
+func (s *documentService) ListFiles(dir string) ([]DocumentFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", dir, err)
+	}
+
+	files := []DocumentFile{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // Removed while listing
+		}
+		files = append(files, DocumentFile{
+			Name:    entry.Name(),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+	}
+
+	return files, nil
+}
+
+// touchFile sets a file's modification time to now. Rename keeps the original time,
+// so without this a moved document would look as old as when it was first created.
+func touchFile(path string, logger *zap.Logger) {
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		logger.Warn("Failed to update document modification time", zap.String("path", path), zap.Error(err))
+	}
+}
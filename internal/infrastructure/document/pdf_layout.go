@@ -0,0 +1,355 @@
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// ErrNoPages is returned for documents whose page tree could not be read
+var ErrNoPages = errors.New("no pages found in PDF")
+
+// Box is an area of a page in points, from the top left corner like the Mekari annotations
+type Box struct {
+	X, Y, Width, Height float64
+}
+
+// Overlaps reports whether b and o share any area
+func (b Box) Overlaps(o Box) bool {
+	return b.X < o.X+o.Width && o.X < b.X+b.Width && b.Y < o.Y+o.Height && o.Y < b.Y+b.Height
+}
+
+// grow returns b with margin added on every side
+func (b Box) grow(margin float64) Box {
+	return Box{b.X - margin, b.Y - margin, b.Width + 2*margin, b.Height + 2*margin}
+}
+
+// PageLayout is the size of a page and the signatures and stamps already on it
+type PageLayout struct {
+	Width, Height float64
+	Signatures    []Box // Visible signature fields, in document order
+	Stamps        []Box // Stamp annotations, e.g. an earlier e-meterai
+}
+
+// PDFLayout is the layout of the pages of a PDF, first page first
+type PDFLayout struct {
+	Pages []PageLayout
+}
+
+// FirstSignature returns the 1-based page and area of the first visible signature
+func (l *PDFLayout) FirstSignature() (int, Box, bool) {
+	for i, page := range l.Pages {
+		if len(page.Signatures) > 0 {
+			return i + 1, page.Signatures[0], true
+		}
+	}
+	return 0, Box{}, false
+}
+
+var (
+	objPattern      = regexp.MustCompile(`(?s)(\d+)\s+\d+\s+obj\b(.*?)\bendobj`)
+	refPattern      = regexp.MustCompile(`(\d+)\s+\d+\s+R`)
+	numberPattern   = regexp.MustCompile(`-?\d*\.?\d+`)
+	rootPattern     = regexp.MustCompile(`/Root\s+(\d+)\s+\d+\s+R`)
+	streamPattern   = regexp.MustCompile(`(?s)stream\r?\n(.*)endstream`)
+	objStmPattern   = regexp.MustCompile(`/Type\s*/ObjStm\b`)
+	pagePattern     = regexp.MustCompile(`/Type\s*/Page\b`)
+	widgetPattern   = regexp.MustCompile(`/Subtype\s*/Widget\b`)
+	stampPattern    = regexp.MustCompile(`/Subtype\s*/Stamp\b`)
+	sigFieldPattern = regexp.MustCompile(`/FT\s*/Sig\b`)
+)
+
+// ReadPDFLayout reads the page sizes and the signature fields of a PDF. It reads the objects of
+// the file and its compressed object streams directly, without rendering; signatures drawn into
+// the page content rather than as a signature field are not found.
+func ReadPDFLayout(content []byte) (*PDFLayout, error) {
+	objects := readObjects(content)
+
+	roots := rootPattern.FindAllSubmatch(content, -1)
+	if len(roots) == 0 {
+		return nil, ErrNoPages
+	}
+	// The last /Root wins, incremental updates (such as a signature) are appended to the file
+	catalog := objects[atoi(roots[len(roots)-1][1])]
+	pagesRef, ok := dictRef(catalog, "Pages")
+	if !ok {
+		return nil, ErrNoPages
+	}
+
+	layout := &PDFLayout{}
+	walkPages(objects, pagesRef, nil, layout, 0)
+	if len(layout.Pages) == 0 {
+		return nil, ErrNoPages
+	}
+	return layout, nil
+}
+
+// walkPages appends the pages below the page tree node ref in order. The media box is inherited
+// from the parent nodes.
+func walkPages(objects map[int][]byte, ref int, mediaBox []float64, layout *PDFLayout, depth int) {
+	node, ok := objects[ref]
+	if !ok || depth > 32 {
+		return
+	}
+	if box := dictNumbers(node, "MediaBox"); len(box) == 4 {
+		mediaBox = box
+	}
+
+	if !pagePattern.Match(node) {
+		for _, kid := range dictRefs(objects, node, "Kids") {
+			walkPages(objects, kid, mediaBox, layout, depth+1)
+		}
+		return
+	}
+
+	page := PageLayout{Width: 595, Height: 842} // A4 when the page has no media box
+	if len(mediaBox) == 4 {
+		page.Width, page.Height = mediaBox[2]-mediaBox[0], mediaBox[3]-mediaBox[1]
+	}
+	for _, annotRef := range dictRefs(objects, node, "Annots") {
+		annot := objects[annotRef]
+		rect := dictNumbers(annot, "Rect")
+		if len(rect) != 4 {
+			continue
+		}
+		box := toBox(rect, mediaBox, page.Height)
+		if box.Width <= 0 || box.Height <= 0 {
+			continue // Invisible signature
+		}
+		switch {
+		case widgetPattern.Match(annot) && isSignatureField(objects, annot):
+			page.Signatures = append(page.Signatures, box)
+		case stampPattern.Match(annot):
+			page.Stamps = append(page.Stamps, box)
+		}
+	}
+	layout.Pages = append(layout.Pages, page)
+}
+
+// isSignatureField reports whether a widget belongs to a signature field, set on the widget or
+// on its parent field
+func isSignatureField(objects map[int][]byte, widget []byte) bool {
+	if sigFieldPattern.Match(widget) {
+		return true
+	}
+	parent, ok := dictRef(widget, "Parent")
+	return ok && sigFieldPattern.Match(objects[parent])
+}
+
+// toBox converts a PDF rectangle (bottom left origin) to a Box from the top left of the page
+func toBox(rect, mediaBox []float64, pageHeight float64) Box {
+	x1, y1, x2, y2 := rect[0], rect[1], rect[2], rect[3]
+	if x1 > x2 {
+		x1, x2 = x2, x1
+	}
+	if y1 > y2 {
+		y1, y2 = y2, y1
+	}
+	if len(mediaBox) == 4 {
+		x1, x2, y1, y2 = x1-mediaBox[0], x2-mediaBox[0], y1-mediaBox[1], y2-mediaBox[1]
+	}
+	return Box{X: x1, Y: pageHeight - y2, Width: x2 - x1, Height: y2 - y1}
+}
+
+// readObjects returns the dictionaries of the objects of a PDF by object number. Objects in
+// compressed object streams are read first so objects written later in the file replace them.
+func readObjects(content []byte) map[int][]byte {
+	objects := make(map[int][]byte)
+	matches := objPattern.FindAllSubmatch(content, -1)
+
+	for _, m := range matches {
+		if objStmPattern.Match(dictOf(m[2])) {
+			readObjectStream(m[2], objects)
+		}
+	}
+	for _, m := range matches {
+		objects[atoi(m[1])] = dictOf(m[2])
+	}
+	return objects
+}
+
+// readObjectStream adds the objects of a FlateDecode object stream
+func readObjectStream(obj []byte, objects map[int][]byte) {
+	stream := streamPattern.FindSubmatch(obj)
+	if stream == nil {
+		return
+	}
+	r, err := zlib.NewReader(bytes.NewReader(stream[1]))
+	if err != nil {
+		return
+	}
+	data, err := io.ReadAll(r)
+	if err != nil && len(data) == 0 {
+		return
+	}
+
+	dict := dictOf(obj)
+	n, first := dictInt(dict, "N"), dictInt(dict, "First")
+	if first <= 0 || first > len(data) {
+		return
+	}
+	header := numberPattern.FindAll(data[:first], -1)
+	for i := 0; i+1 < len(header) && i/2 < n; i += 2 {
+		start := first + atoi(header[i+1])
+		end := len(data)
+		if i+3 < len(header) {
+			end = first + atoi(header[i+3])
+		}
+		if start < end && end <= len(data) {
+			objects[atoi(header[i])] = data[start:end]
+		}
+	}
+}
+
+// dictOf returns the part of an object before its stream
+func dictOf(obj []byte) []byte {
+	if i := bytes.Index(obj, []byte("stream")); i >= 0 {
+		return obj[:i]
+	}
+	return obj
+}
+
+// Value forms of dictionary keys
+const (
+	refValue    = `(\d+)\s+\d+\s+R`
+	arrayValue  = `\[([^\]]*)\]`
+	numberValue = `(\d+)`
+)
+
+// keyPatterns holds the patterns of the keys read, compiled once and only read afterwards
+var keyPatterns = func() map[string]*regexp.Regexp {
+	patterns := make(map[string]*regexp.Regexp)
+	add := func(value string, keys ...string) {
+		for _, key := range keys {
+			patterns[key+value] = regexp.MustCompile(`/` + key + `\s*` + value)
+		}
+	}
+	add(refValue, "Pages", "Parent", "Kids", "Annots")
+	add(arrayValue, "Kids", "Annots", "MediaBox", "Rect")
+	add(numberValue, "N", "First", "Count")
+	return patterns
+}()
+
+func keyValue(key, value string) *regexp.Regexp {
+	return keyPatterns[key+value]
+}
+
+// dictRef returns the object number referenced by key, e.g. /Pages 3 0 R
+func dictRef(dict []byte, key string) (int, bool) {
+	m := keyValue(key, refValue).FindSubmatch(dict)
+	if m == nil {
+		return 0, false
+	}
+	return atoi(m[1]), true
+}
+
+// dictRefs returns the references of the array under key, written inline or as a reference to
+// an array object
+func dictRefs(objects map[int][]byte, dict []byte, key string) []int {
+	array := keyValue(key, arrayValue).FindSubmatch(dict)
+	var body []byte
+	if array != nil {
+		body = array[1]
+	} else if ref, ok := dictRef(dict, key); ok {
+		body = objects[ref]
+	}
+
+	var refs []int
+	for _, m := range refPattern.FindAllSubmatch(body, -1) {
+		refs = append(refs, atoi(m[1]))
+	}
+	return refs
+}
+
+// dictNumbers returns the numbers of the array under key, e.g. /MediaBox [0 0 595 842]
+func dictNumbers(dict []byte, key string) []float64 {
+	m := keyValue(key, arrayValue).FindSubmatch(dict)
+	if m == nil {
+		return nil
+	}
+	var numbers []float64
+	for _, n := range numberPattern.FindAll(m[1], -1) {
+		f, err := strconv.ParseFloat(string(n), 64)
+		if err != nil {
+			return nil
+		}
+		numbers = append(numbers, f)
+	}
+	return numbers
+}
+
+func dictInt(dict []byte, key string) int {
+	m := keyValue(key, numberValue).FindSubmatch(dict)
+	if m == nil {
+		return 0
+	}
+	return atoi(m[1])
+}
+
+func atoi(b []byte) int {
+	n, _ := strconv.Atoi(string(b))
+	return n
+}
+
+// PlaceBeside returns a free area of width by height next to target on page (1-based), trying
+// left of it first, as an e-meterai is placed beside the signature, then right, below and above.
+// The area stays margin away from the page edges, the signatures and the stamps of the page.
+func (l *PDFLayout) PlaceBeside(page int, target Box, width, height, margin float64) (Box, bool) {
+	if page < 1 || page > len(l.Pages) {
+		return Box{}, false
+	}
+	p := l.Pages[page-1]
+
+	candidates := []Box{
+		{target.X - margin - width, target.Y + (target.Height-height)/2, width, height},
+		{target.X + target.Width + margin, target.Y + (target.Height-height)/2, width, height},
+		{target.X + (target.Width-width)/2, target.Y + target.Height + margin, width, height},
+		{target.X + (target.Width-width)/2, target.Y - margin - height, width, height},
+	}
+	for _, c := range candidates {
+		if l.IsFree(page, c, margin) && !c.Overlaps(target.grow(margin)) {
+			return c, true
+		}
+	}
+
+	// Shift the sides along the page when the target is close to an edge
+	for _, c := range candidates {
+		c.X = clamp(c.X, margin, p.Width-margin-width)
+		c.Y = clamp(c.Y, margin, p.Height-margin-height)
+		if l.IsFree(page, c, margin) && !c.Overlaps(target.grow(margin)) {
+			return c, true
+		}
+	}
+	return Box{}, false
+}
+
+// IsFree reports whether b lies on page (1-based) margin away from its edges and clear of its
+// signatures and stamps
+func (l *PDFLayout) IsFree(page int, b Box, margin float64) bool {
+	if page < 1 || page > len(l.Pages) {
+		return false
+	}
+	p := l.Pages[page-1]
+	if b.X < margin || b.Y < margin || b.X+b.Width > p.Width-margin || b.Y+b.Height > p.Height-margin {
+		return false
+	}
+	for _, used := range append(append([]Box{}, p.Signatures...), p.Stamps...) {
+		if b.Overlaps(used.grow(margin)) {
+			return false
+		}
+	}
+	return true
+}
+
+func clamp(v, lo, hi float64) float64 {
+	if v > hi {
+		v = hi
+	}
+	if v < lo {
+		v = lo
+	}
+	return v
+}
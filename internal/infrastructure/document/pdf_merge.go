@@ -0,0 +1,198 @@
+package document
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// ErrEncryptedPDF is returned when merging encrypted documents, whose objects cannot be copied
+var ErrEncryptedPDF = errors.New("encrypted PDFs cannot be merged")
+
+var (
+	objHeaderPattern = regexp.MustCompile(`(\d+)\s+\d+\s+obj\b`)
+	encryptPattern   = regexp.MustCompile(`/Encrypt\s+\d+\s+\d+\s+R`)
+	xrefTypePattern  = regexp.MustCompile(`/Type\s*/XRef\b`)
+	parentPattern    = regexp.MustCompile(`/Parent\s+\d+\s+\d+\s+R`)
+)
+
+// MergePDFs appends the pages of the other documents to the first one and returns the new PDF.
+// The objects of every document are renumbered and copied unchanged; only the page tree gets a
+// new root. Form fields and outlines of the documents after the first are not carried over.
+func MergePDFs(docs ...[]byte) ([]byte, error) {
+	if len(docs) == 0 {
+		return nil, ErrNoPages
+	}
+
+	var (
+		objects = make(map[int][]byte) // Renumbered objects of all documents
+		kids    []int                  // Page tree root of each document
+		count   int
+		next    = 1
+	)
+	for i, content := range docs {
+		if encryptPattern.Match(content) {
+			return nil, ErrEncryptedPDF
+		}
+		raw := readRawObjects(content)
+
+		roots := rootPattern.FindAllSubmatch(content, -1)
+		if len(roots) == 0 {
+			return nil, fmt.Errorf("document %d: %w", i+1, ErrNoPages)
+		}
+		pagesRef, ok := dictRef(dictOf(raw[atoi(roots[len(roots)-1][1])]), "Pages")
+		if !ok || raw[pagesRef] == nil {
+			return nil, fmt.Errorf("document %d: %w", i+1, ErrNoPages)
+		}
+
+		// Objects of this document are numbered after those of the documents before it
+		offset := next - 1
+		maxNum := 0
+		for num, body := range raw {
+			objects[num+offset] = renumberRefs(body, offset)
+			if num > maxNum {
+				maxNum = num
+			}
+		}
+		next += maxNum
+
+		kids = append(kids, pagesRef+offset)
+		count += dictInt(dictOf(raw[pagesRef]), "Count")
+	}
+
+	pagesNum, catalogNum := next, next+1
+	for _, kid := range kids {
+		objects[kid] = setParent(objects[kid], pagesNum)
+	}
+	var kidRefs bytes.Buffer
+	for _, kid := range kids {
+		fmt.Fprintf(&kidRefs, "%d 0 R ", kid)
+	}
+	objects[pagesNum] = []byte(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", bytes.TrimSpace(kidRefs.Bytes()), count))
+	objects[catalogNum] = []byte(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesNum))
+
+	return writePDF(objects, catalogNum), nil
+}
+
+// readRawObjects returns the bodies (between obj and endobj, streams included) of the objects of
+// a PDF by object number. Objects written later replace earlier ones, as incremental updates do;
+// object streams are unpacked and cross-reference streams dropped.
+func readRawObjects(content []byte) map[int][]byte {
+	objects := make(map[int][]byte)
+	direct := make(map[int][]byte)
+
+	for pos := 0; pos < len(content); {
+		loc := objHeaderPattern.FindSubmatchIndex(content[pos:])
+		if loc == nil {
+			break
+		}
+		numStart, numEnd, start := pos+loc[2], pos+loc[3], pos+loc[1]
+		end := bytes.Index(content[start:], []byte("endobj"))
+		if end < 0 {
+			break
+		}
+		end += start
+		// A stream may contain "endobj", so skip to the end of the stream first
+		if s := bytes.Index(content[start:end], []byte("stream")); s >= 0 {
+			es := bytes.Index(content[start+s:], []byte("endstream"))
+			if es < 0 {
+				break
+			}
+			after := start + s + es
+			e := bytes.Index(content[after:], []byte("endobj"))
+			if e < 0 {
+				break
+			}
+			end = after + e
+		}
+		pos = end + len("endobj")
+		body := content[start:end]
+		num := atoi(content[numStart:numEnd])
+
+		dict := dictOf(body)
+		switch {
+		case objStmPattern.Match(dict):
+			readObjectStream(body, objects)
+		case xrefTypePattern.Match(dict):
+		default:
+			direct[num] = body
+		}
+	}
+
+	for num, body := range direct {
+		objects[num] = body
+	}
+	return objects
+}
+
+// renumberRefs adds offset to the object references of an object, outside its stream data
+func renumberRefs(body []byte, offset int) []byte {
+	dict, stream := body, []byte(nil)
+	if i := bytes.Index(body, []byte("stream")); i >= 0 {
+		dict, stream = body[:i], body[i:]
+	}
+	dict = refPattern.ReplaceAllFunc(dict, func(ref []byte) []byte {
+		m := refPattern.FindSubmatch(ref)
+		return []byte(strconv.Itoa(atoi(m[1])+offset) + " 0 R")
+	})
+
+	out := make([]byte, 0, len(dict)+len(stream))
+	return append(append(out, dict...), stream...)
+}
+
+// setParent points the root of a page tree to a new parent
+func setParent(body []byte, parent int) []byte {
+	ref := []byte(fmt.Sprintf("/Parent %d 0 R", parent))
+	if parentPattern.Match(body) {
+		return parentPattern.ReplaceAll(body, ref)
+	}
+	i := bytes.Index(body, []byte("<<"))
+	if i < 0 {
+		return body
+	}
+	out := make([]byte, 0, len(body)+len(ref)+1)
+	out = append(out, body[:i+2]...)
+	out = append(out, ' ')
+	out = append(out, ref...)
+	return append(out, body[i+2:]...)
+}
+
+// writePDF writes objects as a PDF with a classic cross-reference table
+func writePDF(objects map[int][]byte, root int) []byte {
+	size := 0
+	for num := range objects {
+		if num > size {
+			size = num
+		}
+	}
+	size++
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+
+	offsets := make([]int, size)
+	for num := 1; num < size; num++ {
+		body, ok := objects[num]
+		if !ok {
+			continue
+		}
+		offsets[num] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n", num)
+		buf.Write(bytes.TrimSpace(body))
+		buf.WriteString("\nendobj\n")
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", size)
+	for num := 1; num < size; num++ {
+		if offsets[num] == 0 {
+			buf.WriteString("0000000000 65535 f \n")
+			continue
+		}
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offsets[num])
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", size, root, xref)
+	return buf.Bytes()
+}
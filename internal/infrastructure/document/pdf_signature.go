@@ -0,0 +1,686 @@
+package document
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	_ "crypto/sha1" // Registers the digests signatures may use
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"mekari-esign/internal/domain/entity"
+)
+
+// ErrNotPDF is returned when verifying content that is not a PDF
+var ErrNotPDF = errors.New("content is not a PDF")
+
+var (
+	byteRangePattern = regexp.MustCompile(`/ByteRange\s*\[\s*(\d+)\s+(\d+)\s+(\d+)\s+(\d+)\s*\]`)
+	contentsPattern  = regexp.MustCompile(`/Contents\s*<([0-9A-Fa-f\s]*)>`)
+	subFilterPattern = regexp.MustCompile(`/SubFilter\s*/([\w.]+)`)
+
+	// Names of e-meterai signatures, sealed by Peruri
+	emeteraiPattern = regexp.MustCompile(`(?i)meterai|materai|peruri`)
+
+	// Text string keys read from signature dictionaries
+	stringKeyPatterns = map[string]*regexp.Regexp{
+		"Name":     regexp.MustCompile(`/Name\s*[(<]`),
+		"Reason":   regexp.MustCompile(`/Reason\s*[(<]`),
+		"Location": regexp.MustCompile(`/Location\s*[(<]`),
+		"M":        regexp.MustCompile(`/M\s*[(<]`),
+	}
+)
+
+// CMS object identifiers
+var (
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidTSTInfo       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidTimestamp     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 14}
+	oidRSAPSS        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 10}
+)
+
+// digestAlgorithms maps digest and signature algorithm identifiers to their hash; some signers
+// write the signature algorithm where the digest algorithm belongs
+var digestAlgorithms = map[string]crypto.Hash{
+	"1.3.14.3.2.26":          crypto.SHA1,
+	"2.16.840.1.101.3.4.2.1": crypto.SHA256,
+	"2.16.840.1.101.3.4.2.2": crypto.SHA384,
+	"2.16.840.1.101.3.4.2.3": crypto.SHA512,
+	"1.2.840.113549.1.1.5":   crypto.SHA1,
+	"1.2.840.113549.1.1.11":  crypto.SHA256,
+	"1.2.840.113549.1.1.12":  crypto.SHA384,
+	"1.2.840.113549.1.1.13":  crypto.SHA512,
+	"1.2.840.10045.4.1":      crypto.SHA1,
+	"1.2.840.10045.4.3.2":    crypto.SHA256,
+	"1.2.840.10045.4.3.3":    crypto.SHA384,
+	"1.2.840.10045.4.3.4":    crypto.SHA512,
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo contentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type signerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type issuerAndSerial struct {
+	Issuer asn1.RawValue
+	Serial *big.Int
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint struct {
+		HashAlgorithm pkix.AlgorithmIdentifier
+		HashedMessage []byte
+	}
+	SerialNumber *big.Int
+	GenTime      time.Time
+}
+
+// pdfSignature is a signature dictionary found in a PDF
+type pdfSignature struct {
+	byteRange [4]int
+	contents  []byte
+	gap       [2]int // Offsets of the /Contents hex string, delimiters included, which the byte range must leave out
+	dict      []byte
+}
+
+// VerifyPDFSignatures checks the digital signatures of a PDF: that the bytes each one covers are
+// unchanged, that it matches its signer certificate and that the certificate chains to one of
+// roots (the system roots when nil). Certificates are checked at the time of the signature's
+// timestamp token once the token's own signature and chain are verified, and otherwise now.
+func VerifyPDFSignatures(content []byte, roots *x509.CertPool) (*entity.VerificationReport, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(content, "\r\n\t "), []byte("%PDF-")) {
+		return nil, ErrNotPDF
+	}
+
+	report := &entity.VerificationReport{
+		SizeBytes:  len(content),
+		Signatures: []entity.SignatureVerification{},
+	}
+	signatures := findSignatures(content)
+	for i, sig := range signatures {
+		result := verifySignature(content, sig, roots)
+		result.Index = i + 1
+		report.Signatures = append(report.Signatures, result)
+		if result.EMeterai {
+			report.EMeteraiPresent = true
+		}
+	}
+
+	report.Signed = len(signatures) > 0
+	if !report.Signed {
+		return report, nil
+	}
+	report.ModifiedAfterSigning = !report.Signatures[len(report.Signatures)-1].CoversWholeDocument
+	report.Valid = !report.ModifiedAfterSigning
+	for _, result := range report.Signatures {
+		if !result.IntegrityValid || !result.SignatureValid || !result.ChainTrusted {
+			report.Valid = false
+		}
+	}
+	return report, nil
+}
+
+// findSignatures returns the signature dictionaries of a PDF in the order they were applied, each
+// covering the document up to the end of its revision
+func findSignatures(content []byte) []pdfSignature {
+	seen := make(map[[4]int]bool)
+	var signatures []pdfSignature
+	for _, loc := range objPattern.FindAllSubmatchIndex(content, -1) {
+		body := content[loc[4]:loc[5]]
+		br := byteRangePattern.FindSubmatch(body)
+		if br == nil {
+			continue
+		}
+		var byteRange [4]int
+		for i := range byteRange {
+			byteRange[i] = atoi(br[i+1])
+		}
+		contents := contentsPattern.FindSubmatchIndex(body)
+		if contents == nil || seen[byteRange] || byteRange[1] == 0 && byteRange[3] == 0 {
+			continue
+		}
+		raw, err := hex.DecodeString(strings.Join(strings.Fields(string(body[contents[2]:contents[3]])), ""))
+		if err != nil {
+			continue
+		}
+		seen[byteRange] = true
+		// The hex digits are enclosed in < and >
+		gap := [2]int{loc[4] + contents[2] - 1, loc[4] + contents[3] + 1}
+		signatures = append(signatures, pdfSignature{byteRange: byteRange, contents: raw, gap: gap, dict: body})
+	}
+	sort.SliceStable(signatures, func(i, j int) bool {
+		return signatures[i].byteRange[2]+signatures[i].byteRange[3] < signatures[j].byteRange[2]+signatures[j].byteRange[3]
+	})
+	return signatures
+}
+
+// verifySignature checks one signature of content; failed checks are listed in Errors
+func verifySignature(content []byte, sig pdfSignature, roots *x509.CertPool) entity.SignatureVerification {
+	result := entity.SignatureVerification{
+		Name:     dictString(sig.dict, "Name"),
+		Reason:   dictString(sig.dict, "Reason"),
+		Location: dictString(sig.dict, "Location"),
+	}
+	if m := subFilterPattern.FindSubmatch(sig.dict); m != nil {
+		result.SubFilter = string(m[1])
+	}
+	result.DocumentTimestamp = result.SubFilter == "ETSI.RFC3161"
+	if t, ok := parsePDFDate(dictString(sig.dict, "M")); ok {
+		result.SigningTime = &t
+	}
+	fail := func(format string, args ...interface{}) {
+		result.Errors = append(result.Errors, fmt.Sprintf(format, args...))
+	}
+
+	br := sig.byteRange
+	end := br[2] + br[3]
+	if br[0] != 0 || br[1] > br[2] || end > len(content) {
+		fail("byte range %v does not fit the document", br)
+		return result
+	}
+	// Only the signature itself may be left out, or unsigned bytes could be slipped into the gap
+	if br[1] != sig.gap[0] || br[2] != sig.gap[1] {
+		fail("byte range %v does not leave out exactly the signature at %d-%d", br, sig.gap[0], sig.gap[1])
+		return result
+	}
+	signed := append(append([]byte{}, content[:br[1]]...), content[br[2]:end]...)
+	result.CoversWholeDocument = len(bytes.TrimSpace(content[end:])) == 0
+
+	var info contentInfo
+	if _, err := asn1.Unmarshal(sig.contents, &info); err != nil || !info.ContentType.Equal(oidSignedData) {
+		fail("signature is not a CMS signed-data structure")
+		return result
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(info.Content.Bytes, &sd); err != nil {
+		fail("failed to parse signed data: %v", err)
+		return result
+	}
+	if len(sd.SignerInfos) == 0 {
+		fail("signature has no signer")
+		return result
+	}
+	si := sd.SignerInfos[0]
+	certs := parseCertificates(sd.Certificates.Bytes)
+	signer := findSigner(si.SID, certs)
+
+	hash, ok := digestAlgorithms[si.DigestAlgorithm.Algorithm.String()]
+	if !ok || !hash.Available() {
+		fail("unsupported digest algorithm %s", si.DigestAlgorithm.Algorithm)
+		return result
+	}
+	result.DigestAlgorithm = hash.String()
+
+	// The content signed: the document bytes, or for timestamps and adbe.pkcs7.sha1 the
+	// encapsulated content, which holds the digest of the document bytes
+	message := signed
+	encapsulated := len(sd.EncapContentInfo.Content.Bytes) > 0
+	var genTime *time.Time // Of a document timestamp, trusted once its signature and chain are
+	if encapsulated {
+		var eContent []byte
+		if _, err := asn1.Unmarshal(sd.EncapContentInfo.Content.Bytes, &eContent); err != nil {
+			fail("failed to parse encapsulated content: %v", err)
+			return result
+		}
+		message = eContent
+		switch {
+		case sd.EncapContentInfo.ContentType.Equal(oidTSTInfo):
+			t, err := checkTimestamp(eContent, signed)
+			if err != nil {
+				fail("%v", err)
+			} else {
+				genTime = &t
+				result.IntegrityValid = true
+			}
+		case sd.EncapContentInfo.ContentType.Equal(oidData):
+			result.IntegrityValid = bytes.Equal(eContent, digest(crypto.SHA1, signed))
+			if !result.IntegrityValid {
+				fail("document digest does not match the signed digest")
+			}
+		default:
+			fail("unsupported encapsulated content %s", sd.EncapContentInfo.ContentType)
+		}
+	}
+
+	// With signed attributes the signature covers them, and they hold the digest of the content
+	signedBytes := message
+	if len(si.SignedAttrs.Bytes) > 0 {
+		attrs := parseAttributes(si.SignedAttrs.Bytes)
+		var messageDigest []byte
+		if value, ok := attrs[oidMessageDigest.String()]; ok {
+			_, _ = asn1.Unmarshal(value.FullBytes, &messageDigest)
+		}
+		if !bytes.Equal(messageDigest, digest(hash, message)) {
+			result.IntegrityValid = false
+			fail("document digest does not match the signed digest")
+		} else if !encapsulated {
+			result.IntegrityValid = true
+		}
+		if value, ok := attrs[oidSigningTime.String()]; ok {
+			var t time.Time
+			if _, err := asn1.Unmarshal(value.FullBytes, &t); err == nil {
+				result.SigningTime = &t
+			}
+		}
+		// Signed attributes are signed as a SET, not with their implicit tag
+		signedBytes = append([]byte{0x31}, si.SignedAttrs.FullBytes[1:]...)
+	}
+
+	if len(si.UnsignedAttrs.Bytes) > 0 {
+		if token, ok := parseAttributes(si.UnsignedAttrs.Bytes)[oidTimestamp.String()]; ok {
+			if t, err := verifyTimestampToken(token.FullBytes, si.Signature, roots); err == nil {
+				result.TimestampedAt = &t
+			} else {
+				fail("timestamp not trusted: %v", err)
+			}
+		}
+	}
+
+	if signer == nil {
+		fail("signer certificate not found in the signature")
+		return result
+	}
+	result.SignerName = signer.Subject.CommonName
+	if len(signer.EmailAddresses) > 0 {
+		result.SignerEmail = signer.EmailAddresses[0]
+	}
+	result.EMeterai = !result.DocumentTimestamp && (emeteraiPattern.MatchString(result.Reason) ||
+		emeteraiPattern.MatchString(result.Name) || emeteraiPattern.MatchString(signer.Subject.String()))
+
+	if err := checkSignature(signer, si, hash, signedBytes); err != nil {
+		fail("signature does not match the signer certificate: %v", err)
+	} else {
+		result.SignatureValid = true
+	}
+	if !encapsulated && len(si.SignedAttrs.Bytes) == 0 {
+		// Signed over the document bytes directly, so only the signature vouches for them
+		result.IntegrityValid = result.SignatureValid
+	}
+
+	// The signing time is only claimed by the signer, so without a trusted timestamp the
+	// certificate must be valid now. A document timestamp is its own timestamp: its time is
+	// vouched for by the signature just checked, and trusted along with the chain.
+	at := time.Now()
+	if result.TimestampedAt != nil {
+		at = *result.TimestampedAt
+	} else if genTime != nil && result.SignatureValid {
+		at = *genTime
+	}
+	chains, err := verifyChain(signer, certs, roots, at, x509.ExtKeyUsageAny)
+	chain := []*x509.Certificate{signer}
+	if err != nil {
+		fail("certificate chain not trusted: %v", err)
+		chain = append(chain, issuersOf(signer, certs)...)
+	} else {
+		result.ChainTrusted = true
+		chain = chains[0]
+		if genTime != nil && result.SignatureValid {
+			result.TimestampedAt = genTime
+		}
+	}
+	for _, cert := range chain {
+		result.CertificateChain = append(result.CertificateChain, entity.CertificateInfo{
+			Subject:      cert.Subject.String(),
+			Issuer:       cert.Issuer.String(),
+			SerialNumber: cert.SerialNumber.Text(16),
+			NotBefore:    cert.NotBefore,
+			NotAfter:     cert.NotAfter,
+		})
+	}
+	return result
+}
+
+// checkTimestamp returns the time of a timestamp token's TSTInfo after checking it covers signed
+func checkTimestamp(eContent, signed []byte) (time.Time, error) {
+	var info tstInfo
+	if _, err := asn1.Unmarshal(eContent, &info); err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse timestamp: %w", err)
+	}
+	hash, ok := digestAlgorithms[info.MessageImprint.HashAlgorithm.Algorithm.String()]
+	if !ok || !hash.Available() {
+		return time.Time{}, fmt.Errorf("unsupported timestamp digest algorithm %s", info.MessageImprint.HashAlgorithm.Algorithm)
+	}
+	if !bytes.Equal(info.MessageImprint.HashedMessage, digest(hash, signed)) {
+		return time.Time{}, errors.New("timestamp does not match the signed bytes")
+	}
+	return info.GenTime, nil
+}
+
+// verifyTimestampToken returns the time of the timestamp token of a signature value after
+// checking that the token covers signature, is signed by its TSA certificate and that the
+// certificate chains to roots for time stamping at that time
+func verifyTimestampToken(token, signature []byte, roots *x509.CertPool) (time.Time, error) {
+	var info contentInfo
+	if _, err := asn1.Unmarshal(token, &info); err != nil || !info.ContentType.Equal(oidSignedData) {
+		return time.Time{}, errors.New("timestamp token is not a CMS signed-data structure")
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(info.Content.Bytes, &sd); err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse timestamp token: %w", err)
+	}
+	if !sd.EncapContentInfo.ContentType.Equal(oidTSTInfo) {
+		return time.Time{}, fmt.Errorf("unsupported timestamp token content %s", sd.EncapContentInfo.ContentType)
+	}
+	var eContent []byte
+	if _, err := asn1.Unmarshal(sd.EncapContentInfo.Content.Bytes, &eContent); err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse timestamp token: %w", err)
+	}
+	genTime, err := checkTimestamp(eContent, signature)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if len(sd.SignerInfos) == 0 {
+		return time.Time{}, errors.New("timestamp token has no signer")
+	}
+	si := sd.SignerInfos[0]
+	certs := parseCertificates(sd.Certificates.Bytes)
+	tsa := findSigner(si.SID, certs)
+	if tsa == nil {
+		return time.Time{}, errors.New("TSA certificate not found in the timestamp token")
+	}
+	hash, ok := digestAlgorithms[si.DigestAlgorithm.Algorithm.String()]
+	if !ok || !hash.Available() {
+		return time.Time{}, fmt.Errorf("unsupported timestamp token digest algorithm %s", si.DigestAlgorithm.Algorithm)
+	}
+	// RFC 3161 tokens always carry signed attributes, which hold the digest of the TSTInfo
+	if len(si.SignedAttrs.Bytes) == 0 {
+		return time.Time{}, errors.New("timestamp token has no signed attributes")
+	}
+	var messageDigest []byte
+	if value, ok := parseAttributes(si.SignedAttrs.Bytes)[oidMessageDigest.String()]; ok {
+		_, _ = asn1.Unmarshal(value.FullBytes, &messageDigest)
+	}
+	if !bytes.Equal(messageDigest, digest(hash, eContent)) {
+		return time.Time{}, errors.New("timestamp token digest does not match its content")
+	}
+	signedAttrs := append([]byte{0x31}, si.SignedAttrs.FullBytes[1:]...)
+	if err := checkSignature(tsa, si, hash, signedAttrs); err != nil {
+		return time.Time{}, fmt.Errorf("timestamp token does not match the TSA certificate: %w", err)
+	}
+	if _, err := verifyChain(tsa, certs, roots, genTime, x509.ExtKeyUsageTimeStamping); err != nil {
+		return time.Time{}, fmt.Errorf("TSA certificate chain not trusted: %w", err)
+	}
+	return genTime, nil
+}
+
+// verifyChain verifies that cert chains to roots at time at, through the certificates sent with it
+func verifyChain(cert *x509.Certificate, certs []*x509.Certificate, roots *x509.CertPool, at time.Time, usage x509.ExtKeyUsage) ([][]*x509.Certificate, error) {
+	intermediates := x509.NewCertPool()
+	for _, c := range certs {
+		intermediates.AddCert(c)
+	}
+	return cert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   at,
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	})
+}
+
+// checkSignature verifies the signature value of a signer over signed
+func checkSignature(cert *x509.Certificate, si signerInfo, hash crypto.Hash, signed []byte) error {
+	switch pub := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if si.SignatureAlgorithm.Algorithm.Equal(oidRSAPSS) {
+			return rsa.VerifyPSS(pub, hash, digest(hash, signed), si.Signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto})
+		}
+		return rsa.VerifyPKCS1v15(pub, hash, digest(hash, signed), si.Signature)
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(pub, digest(hash, signed), si.Signature) {
+			return errors.New("ECDSA verification failure")
+		}
+		return nil
+	case ed25519.PublicKey:
+		if !ed25519.Verify(pub, signed, si.Signature) {
+			return errors.New("Ed25519 verification failure")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported public key %T", cert.PublicKey)
+	}
+}
+
+func digest(hash crypto.Hash, data []byte) []byte {
+	h := hash.New()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// parseCertificates returns the certificates of a CMS certificate set, skipping other kinds
+func parseCertificates(set []byte) []*x509.Certificate {
+	var certs []*x509.Certificate
+	for len(set) > 0 {
+		var raw asn1.RawValue
+		rest, err := asn1.Unmarshal(set, &raw)
+		if err != nil {
+			break
+		}
+		if cert, err := x509.ParseCertificate(raw.FullBytes); err == nil {
+			certs = append(certs, cert)
+		}
+		set = rest
+	}
+	return certs
+}
+
+// findSigner returns the certificate a signer identifier names
+func findSigner(sid asn1.RawValue, certs []*x509.Certificate) *x509.Certificate {
+	if sid.Class == asn1.ClassContextSpecific && sid.Tag == 0 {
+		for _, cert := range certs {
+			if bytes.Equal(cert.SubjectKeyId, sid.Bytes) {
+				return cert
+			}
+		}
+		return nil
+	}
+	var ias issuerAndSerial
+	if _, err := asn1.Unmarshal(sid.FullBytes, &ias); err != nil || ias.Serial == nil {
+		return nil
+	}
+	for _, cert := range certs {
+		if cert.SerialNumber.Cmp(ias.Serial) == 0 && bytes.Equal(cert.RawIssuer, ias.Issuer.FullBytes) {
+			return cert
+		}
+	}
+	return nil
+}
+
+// issuersOf follows the issuers of cert through certs, for chains that could not be verified
+func issuersOf(cert *x509.Certificate, certs []*x509.Certificate) []*x509.Certificate {
+	var chain []*x509.Certificate
+	for len(chain) < len(certs) {
+		var issuer *x509.Certificate
+		for _, candidate := range certs {
+			if candidate != cert && bytes.Equal(candidate.RawSubject, cert.RawIssuer) {
+				issuer = candidate
+				break
+			}
+		}
+		if issuer == nil {
+			break
+		}
+		chain = append(chain, issuer)
+		cert = issuer
+	}
+	return chain
+}
+
+// parseAttributes returns the first value of each CMS attribute by object identifier
+func parseAttributes(set []byte) map[string]asn1.RawValue {
+	attrs := make(map[string]asn1.RawValue)
+	for len(set) > 0 {
+		var attr attribute
+		rest, err := asn1.Unmarshal(set, &attr)
+		if err != nil {
+			break
+		}
+		if len(attr.Values) > 0 {
+			attrs[attr.Type.String()] = attr.Values[0]
+		}
+		set = rest
+	}
+	return attrs
+}
+
+// dictString returns the text string under key of a dictionary, written as a literal or a hex string
+func dictString(dict []byte, key string) string {
+	loc := stringKeyPatterns[key].FindIndex(dict)
+	if loc == nil {
+		return ""
+	}
+	start := loc[1] - 1
+
+	var raw []byte
+	if dict[start] == '<' {
+		end := bytes.IndexByte(dict[start:], '>')
+		if end < 0 {
+			return ""
+		}
+		decoded, err := hex.DecodeString(strings.Join(strings.Fields(string(dict[start+1:start+end])), ""))
+		if err != nil {
+			return ""
+		}
+		raw = decoded
+	} else {
+		raw = literalString(dict[start+1:])
+	}
+	return decodeText(raw)
+}
+
+// literalString reads a PDF literal string up to its closing parenthesis
+func literalString(b []byte) []byte {
+	var (
+		out   []byte
+		depth = 1
+	)
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c == '\\' && i+1 < len(b):
+			i++
+			switch e := b[i]; e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r', '\n':
+				// Line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					j := i
+					for j < len(b) && j < i+3 && b[j] >= '0' && b[j] <= '7' {
+						j++
+					}
+					n, _ := strconv.ParseUint(string(b[i:j]), 8, 8)
+					out = append(out, byte(n))
+					i = j - 1
+				} else {
+					out = append(out, e)
+				}
+			}
+		case c == '(':
+			depth++
+			out = append(out, c)
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// decodeText decodes a PDF text string, UTF-16BE with a byte order mark or else PDFDocEncoding,
+// read as Latin-1
+func decodeText(raw []byte) string {
+	if len(raw) >= 2 && raw[0] == 0xFE && raw[1] == 0xFF {
+		units := make([]uint16, 0, (len(raw)-2)/2)
+		for i := 2; i+1 < len(raw); i += 2 {
+			units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, len(raw))
+	for i, c := range raw {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// parsePDFDate parses a PDF date such as D:20240131143000+07'00'
+func parsePDFDate(s string) (time.Time, bool) {
+	s = strings.TrimPrefix(s, "D:")
+	if len(s) < 14 {
+		return time.Time{}, false
+	}
+	t, err := time.Parse("20060102150405", s[:14])
+	if err != nil {
+		return time.Time{}, false
+	}
+	zone := strings.ReplaceAll(s[14:], "'", "")
+	if len(zone) >= 5 && (zone[0] == '+' || zone[0] == '-') {
+		hours, _ := strconv.Atoi(zone[1:3])
+		minutes, _ := strconv.Atoi(zone[3:5])
+		offset := hours*3600 + minutes*60
+		if zone[0] == '-' {
+			offset = -offset
+		}
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.FixedZone("", offset))
+	}
+	return t, true
+}
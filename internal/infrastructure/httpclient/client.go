@@ -16,7 +16,10 @@ import (
 
 	"mekari-esign/internal/config"
 	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/infrastructure/metrics"
 	"mekari-esign/internal/infrastructure/oauth2"
+	"mekari-esign/internal/timeutil"
 )
 
 const (
@@ -26,6 +29,16 @@ const (
 // ErrUnauthorized is returned when token is invalid and refresh failed
 var ErrUnauthorized = errors.New("unauthorized: token refresh failed, re-authorization required")
 
+// APIError is returned when Mekari answers with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error: status=%d, body=%s", e.StatusCode, e.Body)
+}
+
 // RequestContext contains context for authenticated requests
 type RequestContext struct {
 	Email     string // Email for token lookup (only used for OAuth2)
@@ -68,19 +81,20 @@ type httpClient struct {
 	hmacSignature   *HMACSignature
 	apiLogSaver     APILogSaver
 	navAPILogSender NAVAPILogSender
+	redactor        *logger.Redactor
 	logger          *zap.Logger
 }
 
-func NewHTTPClient(cfg *config.Config, tokenService oauth2.TokenService, apiLogSaver APILogSaver, navAPILogSender NAVAPILogSender, logger *zap.Logger) HTTPClient {
+func NewHTTPClient(cfg *config.Config, tokenService oauth2.TokenService, apiLogSaver APILogSaver, navAPILogSender NAVAPILogSender, redactor *logger.Redactor, meter *metrics.Metrics, logger *zap.Logger) HTTPClient {
+	logger = logger.Named("httpclient")
 	c := &httpClient{
-		client: &http.Client{
-			Timeout: cfg.Mekari.Timeout,
-		},
+		client:          &http.Client{Transport: meter.Transport(metrics.ServiceMekari, nil)},
 		config:          cfg,
 		baseURL:         cfg.Mekari.BaseURL,
 		tokenService:    tokenService,
 		apiLogSaver:     apiLogSaver,
 		navAPILogSender: navAPILogSender,
+		redactor:        redactor,
 		logger:          logger,
 	}
 
@@ -105,8 +119,8 @@ func truncateString(s string, maxLength int) string {
 	return s[:maxLength] + fmt.Sprintf("... [truncated, total %d chars]", len(s))
 }
 
-// truncateBase64InJSON truncates base64-like values in JSON string
-func truncateBase64InJSON(jsonStr string, maxLength int) string {
+// TruncateBase64InJSON truncates base64-like values (e.g. embedded documents) in a JSON string
+func TruncateBase64InJSON(jsonStr string, maxLength int) string {
 	// Pattern to match base64-like content (long strings of alphanumeric + /+=)
 	base64Pattern := regexp.MustCompile(`"([A-Za-z0-9+/=]{100,})"`)
 
@@ -145,10 +159,11 @@ func extractInvoiceNo(requestBody []byte) string {
 	return ""
 }
 
-// formatHeadersForLog formats HTTP headers for logging in "Header Key=Value" format
-func formatHeadersForLog(headers http.Header) string {
+// formatHeadersForLog formats HTTP headers for logging in "Header Key=Value" format, with
+// sensitive headers masked
+func (c *httpClient) formatHeadersForLog(headers http.Header) string {
 	var sb strings.Builder
-	for key, values := range headers {
+	for key, values := range c.redactor.Headers(headers) {
 		for _, value := range values {
 			// Truncate very long header values
 			if len(value) > 100 {
@@ -161,37 +176,37 @@ func formatHeadersForLog(headers http.Header) string {
 }
 
 // logRequest logs the HTTP request details
-func (c *httpClient) logRequest(method, url string, headers http.Header, body []byte) {
+func (c *httpClient) logRequest(ctx context.Context, method, url string, headers http.Header, body []byte) {
 	var logBuilder strings.Builder
 
 	logBuilder.WriteString("\n>>> [WEBCLIENT-REQ]\n")
 	logBuilder.WriteString(fmt.Sprintf("Method: %s\n", method))
-	logBuilder.WriteString(fmt.Sprintf("URL: %s\n", url))
+	logBuilder.WriteString(fmt.Sprintf("URL: %s\n", c.redactor.URL(url)))
 	logBuilder.WriteString(fmt.Sprintf("Auth-Type: %s\n", c.config.Mekari.AuthType))
-	logBuilder.WriteString(formatHeadersForLog(headers))
+	logBuilder.WriteString(c.formatHeadersForLog(headers))
 
 	if len(body) > 0 {
-		bodyStr := truncateBase64InJSON(string(body), 100)
-		bodyStr = truncateString(bodyStr, maxBodyLogLength)
+		bodyStr := TruncateBase64InJSON(string(body), 100)
+		bodyStr = truncateString(c.redactor.Body(bodyStr), maxBodyLogLength)
 		logBuilder.WriteString(fmt.Sprintf("REQUEST BODY: %s\n", bodyStr))
 	}
 
-	c.logger.Info(logBuilder.String())
+	logger.FromContext(ctx, c.logger).Info(logBuilder.String())
 }
 
 // logResponse logs the HTTP response details
-func (c *httpClient) logResponse(statusCode int, statusText string, duration time.Duration, headers http.Header, body []byte) {
+func (c *httpClient) logResponse(ctx context.Context, statusCode int, statusText string, duration time.Duration, headers http.Header, body []byte) {
 	var logBuilder strings.Builder
 
 	logBuilder.WriteString("\n>>> [WEBCLIENT-RESPONSE]\n")
 	logBuilder.WriteString(fmt.Sprintf("Status: %d %s\n", statusCode, statusText))
 	logBuilder.WriteString(fmt.Sprintf("Duration: %s\n", duration))
-	logBuilder.WriteString(formatHeadersForLog(headers))
+	logBuilder.WriteString(c.formatHeadersForLog(headers))
 
-	bodyStr := truncateString(string(body), maxBodyLogLength)
+	bodyStr := truncateString(c.redactor.Body(string(body)), maxBodyLogLength)
 	logBuilder.WriteString(fmt.Sprintf("Body: %s\n", bodyStr))
 
-	c.logger.Info(logBuilder.String())
+	logger.FromContext(ctx, c.logger).Info(logBuilder.String())
 }
 
 // saveAPILog saves the API request/response log to database
@@ -200,44 +215,52 @@ func (c *httpClient) saveAPILog(ctx context.Context, method, endpoint string, re
 		return
 	}
 
-	// Truncate base64 in request body
+	// Truncate base64 in request body and mask sensitive fields
 	reqBodyStr := ""
 	if len(requestBody) > 0 {
-		reqBodyStr = truncateBase64InJSON(string(requestBody), 100)
+		reqBodyStr = c.redactor.Body(TruncateBase64InJSON(string(requestBody), 100))
 		// Limit total size
 		if len(reqBodyStr) > 10000 {
 			reqBodyStr = reqBodyStr[:10000] + "... [truncated]"
 		}
 	}
 
-	// Truncate response body if too long
-	respBodyStr := string(responseBody)
+	// Mask sensitive fields and truncate response body if too long
+	respBodyStr := c.redactor.Body(string(responseBody))
 	if len(respBodyStr) > 10000 {
 		respBodyStr = respBodyStr[:10000] + "... [truncated]"
 	}
 
+	// The document being processed, when the caller set it on ctx
+	documentID, invoiceNo := logger.DocumentFromContext(ctx)
+	if reqCtx.InvoiceNo != "" {
+		invoiceNo = reqCtx.InvoiceNo
+	}
+
 	apiLog := &entity.APILog{
-		InvoiceNo:    reqCtx.InvoiceNo,
+		InvoiceNo:    invoiceNo,
 		EntryNo:      reqCtx.EntryNo,
-		Endpoint:     endpoint,
+		DocumentID:   documentID,
+		Source:       entity.APILogSourceMekari,
+		Endpoint:     c.redactor.URL(endpoint),
 		Method:       method,
 		RequestBody:  reqBodyStr,
 		ResponseBody: respBodyStr,
 		StatusCode:   statusCode,
 		Duration:     duration.Milliseconds(),
 		Email:        reqCtx.Email,
-		CreatedAt:    time.Now(),
+		RequestID:    logger.RequestIDFromContext(ctx),
+		CreatedAt:    timeutil.Now(),
 	}
 
-	// Save asynchronously to not block the request
-	go func() {
-		if err := c.apiLogSaver.Save(context.Background(), apiLog); err != nil {
-			c.logger.Warn("Failed to save API log to database",
-				zap.String("endpoint", endpoint),
-				zap.Error(err),
-			)
-		}
-	}()
+	// Queued for the batch writer, so the request does not wait for the insert
+	if err := c.apiLogSaver.Save(ctx, apiLog); err != nil {
+		c.logger.Warn("Failed to save API log to database",
+			zap.String("endpoint", apiLog.Endpoint),
+			zap.String("request_id", apiLog.RequestID),
+			zap.Error(err),
+		)
+	}
 }
 
 // setAuthHeaders sets the appropriate authorization headers based on config
@@ -253,7 +276,7 @@ func (c *httpClient) setAuthHeaders(ctx context.Context, req *http.Request, reqC
 		return fmt.Errorf("failed to get access token: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("Date", timeutil.Now().Format(http.TimeFormat))
 	return nil
 }
 
@@ -271,6 +294,13 @@ func (c *httpClient) doRequest(ctx context.Context, reqCtx *RequestContext, meth
 		bodyReader = bytes.NewBuffer(jsonBody)
 	}
 
+	// mekari.timeout is read per request so config reloads apply
+	if timeout := c.config.MekariTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
 	req, err := http.NewRequestWithContext(ctx, method, fullURL, bodyReader)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
@@ -279,6 +309,9 @@ func (c *httpClient) doRequest(ctx context.Context, reqCtx *RequestContext, meth
 	// Set default headers
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
+	if requestID := logger.RequestIDFromContext(ctx); requestID != "" {
+		req.Header.Set(logger.RequestIDHeader, requestID)
+	}
 
 	// Set auth headers based on config
 	if err := c.setAuthHeaders(ctx, req, reqCtx); err != nil {
@@ -286,7 +319,7 @@ func (c *httpClient) doRequest(ctx context.Context, reqCtx *RequestContext, meth
 	}
 
 	// Log request details
-	c.logRequest(method, fullURL, req.Header, jsonBody)
+	c.logRequest(ctx, method, fullURL, req.Header, jsonBody)
 
 	startTime := time.Now()
 	resp, err := c.client.Do(req)
@@ -304,26 +337,27 @@ func (c *httpClient) doRequest(ctx context.Context, reqCtx *RequestContext, meth
 	}
 
 	// Log response details
-	c.logResponse(resp.StatusCode, resp.Status, duration, resp.Header, respBody)
+	c.logResponse(ctx, resp.StatusCode, resp.Status, duration, resp.Header, respBody)
 
 	// Save API log to database
 	c.saveAPILog(ctx, method, fullURL, jsonBody, respBody, resp.StatusCode, duration, reqCtx)
 
 	// Handle 401 Unauthorized - try to refresh token and retry (OAuth2 only)
 	if resp.StatusCode == http.StatusUnauthorized && !isRetry && c.config.Mekari.IsOAuth2() {
-		c.logger.Info("Received 401 Unauthorized, attempting to refresh token",
+		log := logger.FromContext(ctx, c.logger)
+		log.Info("Received 401 Unauthorized, attempting to refresh token",
 			zap.String("email", reqCtx.Email),
 		)
 
 		// Refresh token
 		_, err := c.tokenService.RefreshToken(ctx, reqCtx.Email)
 		if err != nil {
-			c.logger.Error("Failed to refresh token", zap.Error(err))
+			log.Error("Failed to refresh token", zap.Error(err))
 			return ErrUnauthorized
 		}
 
 		// Retry request with new token
-		c.logger.Info("Token refreshed, retrying request",
+		log.Info("Token refreshed, retrying request",
 			zap.String("email", reqCtx.Email),
 		)
 		return c.doRequest(ctx, reqCtx, method, path, body, result, true)
@@ -331,7 +365,7 @@ func (c *httpClient) doRequest(ctx context.Context, reqCtx *RequestContext, meth
 
 	// Check for HTTP errors
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("API error: status=%d, body=%s", resp.StatusCode, string(respBody))
+		return &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	// Parse response
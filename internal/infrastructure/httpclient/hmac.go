@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"net/url"
 	"time"
+
+	"mekari-esign/internal/timeutil"
 )
 
 type HMACSignature struct {
@@ -60,7 +62,7 @@ func (h *HMACSignature) GenerateSignature(method, fullURL string, date time.Time
 
 // SignRequest signs an HTTP request with HMAC-SHA256 signature
 func (h *HMACSignature) SignRequest(req *http.Request) error {
-	authHeader, dateHeader, err := h.GenerateSignature(req.Method, req.URL.String(), time.Now())
+	authHeader, dateHeader, err := h.GenerateSignature(req.Method, req.URL.String(), timeutil.Now())
 	if err != nil {
 		return err
 	}
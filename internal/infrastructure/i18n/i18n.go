@@ -0,0 +1,222 @@
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+
+	goi18n "github.com/nicksnyder/go-i18n/v2/i18n"
+	"go.uber.org/zap"
+	"golang.org/x/text/language"
+
+	"mekari-esign/internal/config"
+)
+
+// Supported languages (app.language and Accept-Language)
+const (
+	LanguageEnglish    = "en"
+	LanguageIndonesian = "id"
+)
+
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// English texts of the catalog are the messages the code writes; other languages translate them
+const sourceFile = "locales/active.en.json"
+
+// pagePrefix starts the IDs of HTML page labels, which are only localized by ID
+const pagePrefix = "Page"
+
+var (
+	placeholderPattern = regexp.MustCompile(`\{\{\s*\.(\w+)\s*\}\}`)
+	pagePattern        = regexp.MustCompile(`\{\{i18n:(\w+)\}\}`)
+)
+
+// Translator localizes user-facing messages: API responses, notifications and the HTML pages
+type Translator interface {
+	// DefaultLanguage returns app.language, used when a request asks for no supported language
+	DefaultLanguage() string
+	// Language returns the supported language best matching an Accept-Language header
+	Language(acceptLanguage string) string
+	// Localize returns the message with id in lang, rendered with data
+	Localize(lang, id string, data map[string]interface{}) string
+	// Translate returns an English text of the catalog in lang, also when it was rendered with
+	// data; texts not in the catalog are returned unchanged
+	Translate(lang, text string) string
+	// LocalizePage replaces the {{i18n:MessageID}} placeholders of an HTML page with the
+	// HTML-escaped messages in lang (app.language when empty), and {{LANG}} with lang
+	LocalizePage(lang, page string) string
+}
+
+// pattern matches a text rendered from an English message with template data
+type pattern struct {
+	id      string
+	re      *regexp.Regexp
+	names   []string
+	literal int // Length of the text outside placeholders, longer patterns are tried first
+}
+
+type translator struct {
+	defaultLanguage string
+	languages       []string
+	matcher         language.Matcher
+	localizers      map[string]*goi18n.Localizer
+	exact           map[string]string // English text without placeholders -> message ID
+	patterns        []pattern
+	logger          *zap.Logger
+}
+
+// NewTranslator loads the embedded message catalogs
+func NewTranslator(cfg *config.Config, logger *zap.Logger) (Translator, error) {
+	bundle := goi18n.NewBundle(language.English)
+	files, err := localeFS.ReadDir("locales")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message catalogs: %w", err)
+	}
+	for _, file := range files {
+		if _, err := bundle.LoadMessageFileFS(localeFS, "locales/"+file.Name()); err != nil {
+			return nil, fmt.Errorf("failed to load message catalog %s: %w", file.Name(), err)
+		}
+	}
+
+	t := &translator{
+		defaultLanguage: cfg.App.Language,
+		localizers:      make(map[string]*goi18n.Localizer),
+		exact:           make(map[string]string),
+		logger:          logger.Named("i18n"),
+	}
+	if t.defaultLanguage == "" {
+		t.defaultLanguage = LanguageEnglish
+	}
+
+	// The default language comes first, the matcher falls back to the first supported language
+	tags := bundle.LanguageTags()
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].String() == t.defaultLanguage && tags[j].String() != t.defaultLanguage
+	})
+	for _, tag := range tags {
+		lang := tag.String()
+		t.languages = append(t.languages, lang)
+		t.localizers[lang] = goi18n.NewLocalizer(bundle, lang)
+	}
+	if t.localizers[t.defaultLanguage] == nil {
+		return nil, fmt.Errorf("no message catalog for app.language %q", t.defaultLanguage)
+	}
+	t.matcher = language.NewMatcher(tags)
+
+	source, err := localeFS.ReadFile(sourceFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message catalog %s: %w", sourceFile, err)
+	}
+	messages, err := goi18n.ParseMessageFileBytes(source, sourceFile, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message catalog %s: %w", sourceFile, err)
+	}
+	for _, message := range messages.Messages {
+		if !strings.HasPrefix(message.ID, pagePrefix) {
+			t.addSource(message.ID, message.Other)
+		}
+	}
+	sort.SliceStable(t.patterns, func(i, j int) bool { return t.patterns[i].literal > t.patterns[j].literal })
+
+	return t, nil
+}
+
+// addSource indexes an English message so Translate can find its ID from the rendered text
+func (t *translator) addSource(id, text string) {
+	matches := placeholderPattern.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		t.exact[text] = id
+		return
+	}
+
+	var (
+		expr  strings.Builder
+		names []string
+		last  int
+	)
+	expr.WriteString("^")
+	for _, m := range matches {
+		expr.WriteString(regexp.QuoteMeta(text[last:m[0]]))
+		expr.WriteString("(.+?)")
+		names = append(names, text[m[2]:m[3]])
+		last = m[1]
+	}
+	expr.WriteString(regexp.QuoteMeta(text[last:]))
+	expr.WriteString("$")
+
+	t.patterns = append(t.patterns, pattern{
+		id:      id,
+		re:      regexp.MustCompile(expr.String()),
+		names:   names,
+		literal: len(placeholderPattern.ReplaceAllString(text, "")),
+	})
+}
+
+func (t *translator) DefaultLanguage() string {
+	return t.defaultLanguage
+}
+
+func (t *translator) Language(acceptLanguage string) string {
+	if acceptLanguage == "" {
+		return t.defaultLanguage
+	}
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return t.defaultLanguage
+	}
+	_, index, confidence := t.matcher.Match(tags...)
+	if confidence == language.No {
+		return t.defaultLanguage
+	}
+	return t.languages[index]
+}
+
+func (t *translator) Localize(lang, id string, data map[string]interface{}) string {
+	localizer, ok := t.localizers[lang]
+	if !ok {
+		localizer = t.localizers[t.defaultLanguage]
+	}
+	message, err := localizer.Localize(&goi18n.LocalizeConfig{MessageID: id, TemplateData: data})
+	if err != nil && message == "" {
+		t.logger.Warn("Message not localized", zap.String("id", id), zap.String("language", lang), zap.Error(err))
+		return id
+	}
+	return message
+}
+
+func (t *translator) Translate(lang, text string) string {
+	if lang == LanguageEnglish || text == "" {
+		return text
+	}
+	if id, ok := t.exact[text]; ok {
+		return t.Localize(lang, id, nil)
+	}
+	for _, p := range t.patterns {
+		m := p.re.FindStringSubmatch(text)
+		if m == nil {
+			continue
+		}
+		// Values may be catalog texts themselves, such as the unit of an alert
+		data := make(map[string]interface{}, len(p.names))
+		for i, name := range p.names {
+			data[name] = t.Translate(lang, m[i+1])
+		}
+		return t.Localize(lang, p.id, data)
+	}
+	return text
+}
+
+func (t *translator) LocalizePage(lang, page string) string {
+	if lang == "" {
+		lang = t.defaultLanguage
+	}
+	page = strings.ReplaceAll(page, "{{LANG}}", lang)
+	return pagePattern.ReplaceAllStringFunc(page, func(placeholder string) string {
+		id := pagePattern.FindStringSubmatch(placeholder)[1]
+		return html.EscapeString(t.Localize(lang, id, nil))
+	})
+}
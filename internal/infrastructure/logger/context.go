@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader is the header carrying the correlation ID on inbound and outbound requests
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	if requestID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx, or an empty string
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+type documentKey struct{}
+
+// documentFields identify the document a request or background job is working on
+type documentFields struct {
+	documentID string
+	invoiceNo  string
+}
+
+// WithDocument returns a copy of ctx carrying the document ID and invoice number, so logs and
+// api_logs rows along the processing path can be correlated. Empty values keep those already set.
+func WithDocument(ctx context.Context, documentID, invoiceNo string) context.Context {
+	current, _ := ctx.Value(documentKey{}).(documentFields)
+	if documentID != "" {
+		current.documentID = documentID
+	}
+	if invoiceNo != "" {
+		current.invoiceNo = invoiceNo
+	}
+	return context.WithValue(ctx, documentKey{}, current)
+}
+
+// DocumentFromContext returns the document ID and invoice number carried by ctx
+func DocumentFromContext(ctx context.Context) (documentID, invoiceNo string) {
+	if ctx == nil {
+		return "", ""
+	}
+	fields, _ := ctx.Value(documentKey{}).(documentFields)
+	return fields.documentID, fields.invoiceNo
+}
+
+// FromContext returns logger with the request ID, document ID and invoice number of ctx
+// attached as fields
+func FromContext(ctx context.Context, logger *zap.Logger) *zap.Logger {
+	var fields []zap.Field
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		fields = append(fields, zap.String("request_id", requestID))
+	}
+	documentID, invoiceNo := DocumentFromContext(ctx)
+	if documentID != "" {
+		fields = append(fields, zap.String("document_id", documentID))
+	}
+	if invoiceNo != "" {
+		fields = append(fields, zap.String("invoice_number", invoiceNo))
+	}
+	if len(fields) == 0 {
+		return logger
+	}
+	return logger.With(fields...)
+}
@@ -0,0 +1,157 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"mekari-esign/updater"
+)
+
+const (
+	// crashLogEntries is the number of recent log entries written to a crash file
+	crashLogEntries = 200
+	// maxCrashFiles is the number of crash files kept; older ones are removed
+	maxCrashFiles = 20
+	// crashStackSize bounds the goroutine dump in a crash file
+	crashStackSize = 1 << 20
+)
+
+// recentLog keeps the latest log entries of the process for crash files. It is package level
+// because the entrypoints record crashes outside the fx application, even before it is built.
+var recentLog = &ringBuffer{lines: make([]string, crashLogEntries)}
+
+// crashed makes only the first panic write a crash file when it passes several handlers
+var crashed atomic.Bool
+
+// RecordCrash writes a crash file and an Event Log entry for a panic of the calling goroutine
+// and panics again, so the process still exits. Use it as: defer logger.RecordCrash()
+func RecordCrash() {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	writeCrash(recovered)
+	panic(recovered)
+}
+
+// writeCrash writes the panic, the stacks of all goroutines and the recent log entries to
+// crashes/crash-<time>.log next to the executable, and reports the file to the Event Log.
+// Panics inside goroutines otherwise end the service with only a stack trace on stderr, which
+// a Windows service does not keep.
+func writeCrash(recovered interface{}) {
+	if !crashed.CompareAndSwap(false, true) {
+		return
+	}
+
+	stack := make([]byte, crashStackSize)
+	stack = stack[:runtime.Stack(stack, true)]
+
+	now := time.Now()
+	var b strings.Builder
+	fmt.Fprintf(&b, "Mekari E-Sign %s crashed at %s\n\n", updater.Version, now.Format(time.RFC3339))
+	fmt.Fprintf(&b, "panic: %v\n\n", recovered)
+	fmt.Fprintf(&b, "%s\n", stack)
+	fmt.Fprintf(&b, "Recent log entries:\n")
+	for _, line := range recentLog.entries() {
+		b.WriteString(line)
+	}
+
+	path, err := saveCrashFile(now, b.String())
+	if err != nil {
+		path = "not written: " + err.Error()
+	}
+	fmt.Fprintf(os.Stderr, "panic: %v (crash file %s)\n", recovered, path)
+
+	// Opened here, as a crash may happen outside the fx application
+	if events, err := OpenEventLog(); err == nil {
+		defer events.Close()
+		events.Report(EventCrash, "Mekari E-Sign crashed", updater.Version, fmt.Sprint(recovered), path)
+	}
+}
+
+func saveCrashFile(now time.Time, content string) (string, error) {
+	dir := "crashes"
+	if exePath, err := os.Executable(); err == nil {
+		dir = filepath.Join(filepath.Dir(exePath), "crashes")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, "crash-"+now.Format("20060102-150405")+".log")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", err
+	}
+
+	// The names sort by time
+	if names, err := filepath.Glob(filepath.Join(dir, "crash-*.log")); err == nil && len(names) > maxCrashFiles {
+		sort.Strings(names)
+		for _, name := range names[:len(names)-maxCrashFiles] {
+			_ = os.Remove(name)
+		}
+	}
+	return path, nil
+}
+
+// ringBuffer holds the latest encoded log entries
+type ringBuffer struct {
+	mu    sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+func (r *ringBuffer) add(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % len(r.lines)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// entries returns the entries from oldest to newest
+func (r *ringBuffer) entries() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]string(nil), r.lines[:r.next]...)
+	}
+	return append(append([]string(nil), r.lines[r.next:]...), r.lines[:r.next]...)
+}
+
+// newRecentCore returns a core keeping the entries enabled by level in recentLog
+func newRecentCore(level zapcore.LevelEnabler) zapcore.Core {
+	encoder := zapcore.NewConsoleEncoder(zapcore.EncoderConfig{
+		TimeKey:        "time",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		MessageKey:     "msg",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeLevel:    zapcore.CapitalLevelEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+		EncodeName:     zapcore.FullNameEncoder,
+	})
+	return zapcore.NewCore(encoder, zapcore.AddSync(recentWriter{}), level)
+}
+
+// recentWriter adds each encoded entry to recentLog
+type recentWriter struct{}
+
+func (recentWriter) Write(p []byte) (int, error) {
+	recentLog.add(string(p))
+	return len(p), nil
+}
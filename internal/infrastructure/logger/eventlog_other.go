@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package logger
+
+import (
+	"go.uber.org/fx"
+	"go.uber.org/zap/zapcore"
+
+	"mekari-esign/internal/config"
+)
+
+// OpenEventLog returns a no-op EventLog outside Windows
+func OpenEventLog() (*EventLog, error) {
+	return &EventLog{}, nil
+}
+
+// NewEventLog returns a no-op EventLog outside Windows
+func NewEventLog(lc fx.Lifecycle, cfg *config.Config) *EventLog {
+	return &EventLog{}
+}
+
+// newEventLogCore is a no-op outside Windows
+func newEventLogCore(level zapcore.LevelEnabler, events *EventLog) zapcore.Core {
+	return nil
+}
@@ -0,0 +1,132 @@
+//go:build windows
+// +build windows
+
+package logger
+
+import (
+	"encoding/json"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+
+	"mekari-esign/internal/config"
+)
+
+// EventLogSource is the event source registered by service.InstallService (the service name)
+const EventLogSource = "MekariEsign"
+
+var eventTypes = map[EventLevel]uint16{
+	EventInformation: windows.EVENTLOG_INFORMATION_TYPE,
+	EventWarning:     windows.EVENTLOG_WARNING_TYPE,
+	EventError:       windows.EVENTLOG_ERROR_TYPE,
+}
+
+// OpenEventLog opens the event source of the service
+func OpenEventLog() (*EventLog, error) {
+	elog, err := eventlog.Open(EventLogSource)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EventLog{
+		write: func(level EventLevel, id EventID, inserts []string) error {
+			ptrs := make([]*uint16, len(inserts))
+			for i, insert := range inserts {
+				ptr, err := windows.UTF16PtrFromString(insert)
+				if err != nil {
+					return err
+				}
+				ptrs[i] = ptr
+			}
+			return windows.ReportEvent(elog.Handle, eventTypes[level], 0, uint32(id), 0, uint16(len(ptrs)), 0, &ptrs[0], nil)
+		},
+		close: elog.Close,
+	}, nil
+}
+
+// NewEventLog returns the Event Log of the service when logging.event_log is on and it runs as
+// a Windows service, and a no-op EventLog otherwise
+func NewEventLog(lc fx.Lifecycle, cfg *config.Config) *EventLog {
+	if !cfg.Logging.EventLog {
+		return &EventLog{}
+	}
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return &EventLog{err: err}
+	}
+
+	events, err := OpenEventLog()
+	if err != nil {
+		return &EventLog{err: err}
+	}
+	lc.Append(fx.StopHook(events.Close))
+	return events
+}
+
+// newEventLogCore returns a core writing Warn and Error entries to events, or nil when it is
+// not used
+func newEventLogCore(level zapcore.LevelEnabler, events *EventLog) zapcore.Core {
+	if !events.Enabled() {
+		return nil
+	}
+	return &eventLogCore{
+		LevelEnabler: zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+			return l >= zapcore.WarnLevel && level.Enabled(l)
+		}),
+		events: events,
+	}
+}
+
+// eventLogCore reports each entry as an EventLogWarning or EventLogError event, with the
+// logger's fields as JSON
+type eventLogCore struct {
+	zapcore.LevelEnabler
+	events *EventLog
+	fields []zapcore.Field
+}
+
+func (c *eventLogCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(append([]zapcore.Field(nil), c.fields...), fields...)
+	return &clone
+}
+
+func (c *eventLogCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *eventLogCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, field := range c.fields {
+		field.AddTo(enc)
+	}
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+	encoded := ""
+	if len(enc.Fields) > 0 {
+		b, err := json.Marshal(enc.Fields)
+		if err != nil {
+			return err
+		}
+		encoded = string(b)
+	}
+
+	id := EventLogWarning
+	if entry.Level > zapcore.WarnLevel {
+		id = EventLogError
+	}
+	c.events.Report(id, entry.Message, entry.LoggerName, encoded, entry.Stack)
+	return nil
+}
+
+func (c *eventLogCore) Sync() error {
+	return nil
+}
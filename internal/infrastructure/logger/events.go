@@ -0,0 +1,145 @@
+package logger
+
+import (
+	"log"
+	"strings"
+)
+
+// EventID identifies a kind of Windows Event Log entry. The IDs are stable so monitoring and
+// SIEM rules can match on them; a new kind of event gets a new ID. They stay at most 1000, the
+// IDs the EventCreate message file registered by -install renders.
+type EventID uint32
+
+// Event IDs; eventCatalogue lists the level and inserts of each
+const (
+	// Warn and Error log entries forwarded by logging.event_log
+	EventLogWarning EventID = 2
+	EventLogError   EventID = 3
+	EventCrash      EventID = 4
+
+	EventServiceStarting   EventID = 100
+	EventServiceStarted    EventID = 101
+	EventServiceStopping   EventID = 102
+	EventServiceStopped    EventID = 103
+	EventServiceFailed     EventID = 104
+	EventStartFailed       EventID = 105
+	EventStopFailed        EventID = 106
+	EventUnexpectedControl EventID = 107
+
+	EventUpdateApplied   EventID = 200
+	EventUpdateFailed    EventID = 201
+	EventRollbackApplied EventID = 202
+	EventRollbackFailed  EventID = 203
+
+	// Alert thresholds (alerts.*) reached, and cleared again
+	EventWebhookFailures      EventID = 300
+	EventNAVUnreachable       EventID = 301
+	EventTokenRefreshFailures EventID = 302
+	EventStuckDocuments       EventID = 303
+	EventAlertResolved        EventID = 310
+)
+
+// EventLevel is the Event Log type of an event
+type EventLevel int
+
+const (
+	EventInformation EventLevel = iota
+	EventWarning
+	EventError
+)
+
+type eventKind struct {
+	level  EventLevel
+	fields []string // Names of the inserts after the message, in order
+}
+
+var eventCatalogue = map[EventID]eventKind{
+	EventLogWarning: {EventWarning, []string{"logger", "fields", "stacktrace"}},
+	EventLogError:   {EventError, []string{"logger", "fields", "stacktrace"}},
+	EventCrash:      {EventError, []string{"version", "panic", "crash_file"}},
+
+	EventServiceStarting:   {EventInformation, []string{"version"}},
+	EventServiceStarted:    {EventInformation, []string{"version"}},
+	EventServiceStopping:   {EventInformation, nil},
+	EventServiceStopped:    {EventInformation, nil},
+	EventServiceFailed:     {EventError, []string{"error"}},
+	EventStartFailed:       {EventWarning, []string{"attempt", "max_attempts", "retry_in", "error"}},
+	EventStopFailed:        {EventWarning, []string{"error"}},
+	EventUnexpectedControl: {EventWarning, []string{"control"}},
+
+	EventUpdateApplied:   {EventInformation, []string{"from", "to"}},
+	EventUpdateFailed:    {EventError, []string{"from", "to", "error"}},
+	EventRollbackApplied: {EventInformation, []string{"from", "to"}},
+	EventRollbackFailed:  {EventError, []string{"from", "to", "error"}},
+
+	EventWebhookFailures:      {EventWarning, []string{"value", "threshold"}},
+	EventNAVUnreachable:       {EventWarning, []string{"value", "threshold"}},
+	EventTokenRefreshFailures: {EventWarning, []string{"value", "threshold"}},
+	EventStuckDocuments:       {EventWarning, []string{"value", "threshold"}},
+	EventAlertResolved:        {EventInformation, []string{"rule", "value", "threshold"}},
+}
+
+// maxInsertLength keeps each insert below the Event Log limit of 31839 characters
+const maxInsertLength = 30000
+
+// EventLog writes catalogued events to the Windows Event Log. Every event has the readable
+// message as its first insert, shown in Event Viewer, followed by the values of its fields in
+// catalogue order, which SIEM tools read as separate event data. It is a no-op when the Event
+// Log is not used.
+type EventLog struct {
+	write func(level EventLevel, id EventID, inserts []string) error
+	close func() error
+	err   error // Why the Event Log could not be opened
+}
+
+// ConsoleEventLog returns an EventLog printing the events, for the console and debug modes
+func ConsoleEventLog() *EventLog {
+	return &EventLog{write: func(level EventLevel, id EventID, inserts []string) error {
+		log.Printf("[event %d] %s", id, inserts[0])
+		return nil
+	}}
+}
+
+// Enabled reports whether events are written
+func (l *EventLog) Enabled() bool {
+	return l != nil && l.write != nil
+}
+
+// Report writes the event id with message and the values of its fields. The fields are also
+// appended to the message as "name: value" lines.
+func (l *EventLog) Report(id EventID, message string, values ...string) {
+	if !l.Enabled() {
+		return
+	}
+
+	kind := eventCatalogue[id]
+	inserts := make([]string, len(kind.fields)+1)
+	var b strings.Builder
+	b.WriteString(message)
+	for i, name := range kind.fields {
+		if i < len(values) {
+			inserts[i+1] = truncateInsert(values[i])
+		}
+		if i == 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("\n" + name + ": " + inserts[i+1])
+	}
+	inserts[0] = truncateInsert(b.String())
+
+	_ = l.write(kind.level, id, inserts)
+}
+
+// Close closes the Event Log
+func (l *EventLog) Close() {
+	if l != nil && l.close != nil {
+		_ = l.close()
+	}
+}
+
+func truncateInsert(s string) string {
+	if len(s) > maxInsertLength {
+		return s[:maxInsertLength] + "... (truncated)"
+	}
+	return s
+}
@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+
+	"mekari-esign/internal/config"
+)
+
+// Levels holds the log level of each module: logging.levels for the modules it names and
+// logging.level for the rest. It is adjustable at runtime on config reload.
+type Levels struct {
+	current atomic.Pointer[levelSet]
+}
+
+type levelSet struct {
+	global  zapcore.Level
+	modules map[string]zapcore.Level
+	lowest  zapcore.Level
+}
+
+func NewLevels(cfg *config.Config) *Levels {
+	l := &Levels{}
+	l.Set(cfg.Logging.Level, cfg.Logging.Levels)
+	return l
+}
+
+// Set replaces the global level and the per-module levels
+func (l *Levels) Set(level string, modules map[string]string) {
+	set := &levelSet{
+		global:  ParseLevel(level),
+		modules: make(map[string]zapcore.Level, len(modules)),
+	}
+	set.lowest = set.global
+	for name, level := range modules {
+		parsed := ParseLevel(level)
+		set.modules[strings.ToLower(name)] = parsed
+		if parsed < set.lowest {
+			set.lowest = parsed
+		}
+	}
+	l.current.Store(set)
+}
+
+// Enabled reports whether lvl is enabled for at least one module
+func (l *Levels) Enabled(lvl zapcore.Level) bool {
+	return lvl >= l.current.Load().lowest
+}
+
+// Level returns the level of a named logger. "webhook.worker" falls back to the level of
+// "webhook", then to logging.level.
+func (l *Levels) Level(name string) zapcore.Level {
+	set := l.current.Load()
+	for name != "" {
+		if level, ok := set.modules[strings.ToLower(name)]; ok {
+			return level
+		}
+		i := strings.LastIndex(name, ".")
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return set.global
+}
+
+// levelCore drops entries below the level of the logger that wrote them. The wrapped cores
+// only need to be enabled for the lowest configured level.
+type levelCore struct {
+	zapcore.Core
+	levels *Levels
+}
+
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return c.levels.Enabled(lvl)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), levels: c.levels}
+}
+
+func (c *levelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if entry.Level < c.levels.Level(entry.LoggerName) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
@@ -2,12 +2,29 @@ package logger
 
 import (
 	"mekari-esign/internal/config"
+	"mekari-esign/updater"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-func NewLogger(cfg *config.Config) (*zap.Logger, error) {
+// ParseLevel converts a logging.level value to a zap level (default: info)
+func ParseLevel(level string) zapcore.Level {
+	switch level {
+	case "debug":
+		return zapcore.DebugLevel
+	case "info":
+		return zapcore.InfoLevel
+	case "warn":
+		return zapcore.WarnLevel
+	case "error":
+		return zapcore.ErrorLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
+
+func NewLogger(cfg *config.Config, levels *Levels, reporter *Reporter, events *EventLog) (*zap.Logger, error) {
 	var zapConfig zap.Config
 
 	if cfg.IsDevelopment() {
@@ -17,24 +34,47 @@ func NewLogger(cfg *config.Config) (*zap.Logger, error) {
 		zapConfig = zap.NewProductionConfig()
 	}
 
-	// Set log level
-	switch cfg.Logging.Level {
-	case "debug":
-		zapConfig.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
-	case "info":
-		zapConfig.Level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
-	case "warn":
-		zapConfig.Level = zap.NewAtomicLevelAt(zapcore.WarnLevel)
-	case "error":
-		zapConfig.Level = zap.NewAtomicLevelAt(zapcore.ErrorLevel)
-	default:
-		zapConfig.Level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
-	}
+	// Entries are filtered by module level (logging.levels) in levelCore
+	zapConfig.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
 
 	logger, err := zapConfig.Build()
 	if err != nil {
 		return nil, err
 	}
 
+	// Recent entries are kept for crash files
+	cores := []zapcore.Core{logger.Core(), newRecentCore(levels)}
+
+	// In service mode, also forward Warn/Error entries to the Windows Event Log
+	if eventCore := newEventLogCore(levels, events); eventCore != nil {
+		cores = append(cores, eventCore)
+	}
+
+	// Report Error entries to Sentry when sentry.dsn is set
+	sentryCore := reporter.newSentryCore(levels)
+	if sentryCore != nil {
+		cores = append(cores, sentryCore)
+	}
+
+	logger = logger.WithOptions(zap.WrapCore(func(zapcore.Core) zapcore.Core {
+		return &levelCore{Core: zapcore.NewTee(cores...), levels: levels}
+	}))
+
+	// Tells apart the entries of instances running side by side in shared log storage
+	logger = logger.With(zap.String("instance", cfg.App.InstanceID))
+
+	if events.err != nil {
+		logger.Warn("Windows Event Log unavailable, logging to files only", zap.Error(events.err))
+	}
+	if sentryCore != nil {
+		logger.Info("Error reporting enabled",
+			zap.String("environment", cfg.Sentry.Environment),
+			zap.String("release", updater.Version),
+		)
+	}
+	if len(cfg.Logging.Levels) > 0 {
+		logger.Info("Module log levels", zap.Any("levels", cfg.Logging.Levels))
+	}
+
 	return logger, nil
 }
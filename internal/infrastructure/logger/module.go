@@ -3,5 +3,5 @@ package logger
 import "go.uber.org/fx"
 
 var Module = fx.Module("logger",
-	fx.Provide(NewLogger),
+	fx.Provide(NewLevels, NewLogger, NewRedactor, NewReporter, NewEventLog),
 )
@@ -0,0 +1,164 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+)
+
+// RedactedValue replaces sensitive values in logs and api_logs
+const RedactedValue = "[REDACTED]"
+
+// Default field patterns (logging.redact_fields) and headers (logging.redact_headers)
+var (
+	defaultRedactFields = []string{
+		"access_token", "refresh_token", "id_token", "doc_token", "code",
+		"client_secret", "*password*", "*secret*", "api_key",
+		"phone", "phone_number",
+	}
+	defaultRedactHeaders = []string{
+		"Authorization", "Cookie", "Set-Cookie", "X-API-Key", "X-Admin-Token", "X-Vault-Token",
+	}
+)
+
+// Redactor masks sensitive values before they are logged or stored in api_logs. Field
+// patterns are matched case-insensitively against JSON keys, form fields and query parameters
+// and support * wildcards.
+type Redactor struct {
+	fields  []string
+	headers map[string]bool
+}
+
+func NewRedactor(cfg *config.Config) *Redactor {
+	fields := cfg.Logging.RedactFields
+	if len(fields) == 0 {
+		fields = defaultRedactFields
+	}
+	headers := cfg.Logging.RedactHeaders
+	if len(headers) == 0 {
+		headers = defaultRedactHeaders
+	}
+
+	r := &Redactor{headers: make(map[string]bool, len(headers))}
+	for _, f := range fields {
+		r.fields = append(r.fields, strings.ToLower(f))
+	}
+	for _, h := range headers {
+		r.headers[http.CanonicalHeaderKey(h)] = true
+	}
+	return r
+}
+
+// IsSensitive reports whether a field name matches a redaction pattern
+func (r *Redactor) IsSensitive(field string) bool {
+	field = strings.ToLower(field)
+	for _, pattern := range r.fields {
+		if ok, _ := path.Match(pattern, field); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns zap.String(key, value), masked when key is sensitive
+func (r *Redactor) String(key, value string) zap.Field {
+	if value != "" && r.IsSensitive(key) {
+		value = RedactedValue
+	}
+	return zap.String(key, value)
+}
+
+// Body masks sensitive fields at any depth of a JSON body, or of an
+// application/x-www-form-urlencoded body such as an OAuth token request. Other bodies are
+// returned unchanged.
+func (r *Redactor) Body(body string) string {
+	if body == "" {
+		return body
+	}
+
+	var data interface{}
+	if err := json.Unmarshal([]byte(body), &data); err != nil {
+		// Form fields are escaped, so a body with whitespace is text rather than a form
+		if strings.Contains(body, "=") && !strings.ContainsAny(body, " \t\r\n") {
+			return r.query(body)
+		}
+		return body
+	}
+	if !r.redactValue(data) {
+		return body
+	}
+
+	redacted, err := json.Marshal(data)
+	if err != nil {
+		return body
+	}
+	return string(redacted)
+}
+
+// URL masks the values of sensitive query parameters of a URL or path
+func (r *Redactor) URL(rawURL string) string {
+	base, query, found := strings.Cut(rawURL, "?")
+	if !found {
+		return rawURL
+	}
+	return base + "?" + r.query(query)
+}
+
+// query masks the values of sensitive fields of a query string or form body, keeping the
+// order and escaping of the others
+func (r *Redactor) query(query string) string {
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		key, value, _ := strings.Cut(param, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil || value == "" || !r.IsSensitive(name) {
+			continue
+		}
+		params[i] = key + "=" + RedactedValue
+	}
+	return strings.Join(params, "&")
+}
+
+// redactValue masks sensitive keys in place and reports whether anything changed
+func (r *Redactor) redactValue(v interface{}) bool {
+	changed := false
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, nested := range value {
+			if nested != nil && r.IsSensitive(key) {
+				value[key] = RedactedValue
+				changed = true
+				continue
+			}
+			if r.redactValue(nested) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, nested := range value {
+			if r.redactValue(nested) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// Headers returns a copy of headers with sensitive values masked
+func (r *Redactor) Headers(headers http.Header) http.Header {
+	redacted := make(http.Header, len(headers))
+	for key, values := range headers {
+		if r.headers[http.CanonicalHeaderKey(key)] {
+			redacted[key] = []string{RedactedValue}
+			continue
+		}
+		redacted[key] = values
+	}
+	return redacted
+}
@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"testing"
+
+	"mekari-esign/internal/config"
+)
+
+func TestRedactorBody(t *testing.T) {
+	r := NewRedactor(&config.Config{})
+
+	tests := []struct {
+		name, body, want string
+	}{
+		{
+			name: "json",
+			body: `{"grant_type":"authorization_code","code":"abc","nested":{"client_secret":"s3cr3t"}}`,
+			want: `{"code":"[REDACTED]","grant_type":"authorization_code","nested":{"client_secret":"[REDACTED]"}}`,
+		},
+		{
+			name: "form",
+			body: "grant_type=refresh_token&refresh_token=r%2Ft0k&client_id=esign&client_secret=s3cr3t",
+			want: "grant_type=refresh_token&refresh_token=[REDACTED]&client_id=esign&client_secret=[REDACTED]",
+		},
+		{
+			name: "form with escaped and empty keys",
+			body: "Code=abc&client%5Fsecret=s3cr3t&refresh_token=&state=x",
+			want: "Code=[REDACTED]&client%5Fsecret=[REDACTED]&refresh_token=&state=x",
+		},
+		{
+			name: "text",
+			body: "invalid request: code=abc is expired",
+			want: "invalid request: code=abc is expired",
+		},
+		{
+			name: "empty",
+			body: "",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		if got := r.Body(tt.body); got != tt.want {
+			t.Errorf("%s: Body(%q) = %q, expected %q", tt.name, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestRedactorURL(t *testing.T) {
+	r := NewRedactor(&config.Config{})
+
+	tests := []struct {
+		url, want string
+	}{
+		{
+			url:  "https://api.mekari.com/auth/oauth2/token?code=abc&client_secret=s3cr3t&state=x",
+			want: "https://api.mekari.com/auth/oauth2/token?code=[REDACTED]&client_secret=[REDACTED]&state=x",
+		},
+		{
+			url:  "/oauth/callback?state=x&code=abc",
+			want: "/oauth/callback?state=x&code=[REDACTED]",
+		},
+		{
+			url:  "https://api.mekari.com/documents/doc-1/download",
+			want: "https://api.mekari.com/documents/doc-1/download",
+		},
+	}
+	for _, tt := range tests {
+		if got := r.URL(tt.url); got != tt.want {
+			t.Errorf("URL(%q) = %q, expected %q", tt.url, got, tt.want)
+		}
+	}
+}
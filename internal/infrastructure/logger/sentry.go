@@ -0,0 +1,187 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/updater"
+)
+
+// sentryFlushTimeout bounds the wait for queued events on shutdown or before a crash
+const sentryFlushTimeout = 2 * time.Second
+
+// sentryTags are log fields promoted to searchable tags; the rest are sent as extra data
+var sentryTags = map[string]bool{
+	"request_id":     true,
+	"document_id":    true,
+	"invoice_number": true,
+	"entry_no":       true,
+	"event_id":       true,
+}
+
+// Reporter sends panics and Error logs to Sentry, or a compatible service, when sentry.dsn is
+// set. Without a DSN every method is a no-op.
+type Reporter struct {
+	hub *sentry.Hub
+}
+
+func NewReporter(lc fx.Lifecycle, cfg *config.Config) (*Reporter, error) {
+	if cfg.Sentry.DSN == "" {
+		return &Reporter{}, nil
+	}
+
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn:              cfg.Sentry.DSN,
+		Environment:      cfg.Sentry.Environment,
+		Release:          "mekari-esign@" + updater.Version,
+		ServerName:       cfg.App.InstanceID,
+		SampleRate:       cfg.Sentry.SampleRate,
+		AttachStacktrace: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid sentry.dsn: %w", err)
+	}
+
+	hub := sentry.NewHub(client, sentry.NewScope())
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			hub.Flush(sentryFlushTimeout)
+			return nil
+		},
+	})
+
+	return &Reporter{hub: hub}, nil
+}
+
+// Enabled reports whether events are sent
+func (r *Reporter) Enabled() bool {
+	return r.hub != nil
+}
+
+// CapturePanic reports a recovered panic, tagged with the request and document of ctx
+func (r *Reporter) CapturePanic(ctx context.Context, recovered interface{}, tags map[string]string) {
+	if r.hub == nil {
+		return
+	}
+
+	hub := r.hub.Clone()
+	hub.ConfigureScope(func(scope *sentry.Scope) {
+		scope.SetTags(contextTags(ctx))
+		scope.SetTags(tags)
+	})
+	hub.RecoverWithContext(ctx, recovered)
+}
+
+// Repanic reports a panic of the calling goroutine, writes a crash file and panics again, so
+// the crash still happens. Use it as: defer reporter.Repanic(ctx)
+func (r *Reporter) Repanic(ctx context.Context) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	// Already reported by an inner handler of the same goroutine
+	if crashed.Load() {
+		panic(recovered)
+	}
+
+	r.CapturePanic(ctx, recovered, nil)
+	if r.hub != nil {
+		r.hub.Flush(sentryFlushTimeout)
+	}
+	writeCrash(recovered)
+	panic(recovered)
+}
+
+// contextTags returns the request ID, document ID and invoice number carried by ctx
+func contextTags(ctx context.Context) map[string]string {
+	tags := make(map[string]string)
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		tags["request_id"] = requestID
+	}
+	documentID, invoiceNo := DocumentFromContext(ctx)
+	if documentID != "" {
+		tags["document_id"] = documentID
+	}
+	if invoiceNo != "" {
+		tags["invoice_number"] = invoiceNo
+	}
+	return tags
+}
+
+// newSentryCore returns a core reporting Error (and higher) entries, or nil when disabled
+func (r *Reporter) newSentryCore(level zapcore.LevelEnabler) zapcore.Core {
+	if r.hub == nil {
+		return nil
+	}
+
+	return &sentryCore{
+		LevelEnabler: zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+			return l >= zapcore.ErrorLevel && level.Enabled(l)
+		}),
+		hub: r.hub,
+	}
+}
+
+// sentryCore turns log entries into Sentry events, with the logger's fields as tags and extra data
+type sentryCore struct {
+	zapcore.LevelEnabler
+	hub    *sentry.Hub
+	fields []zapcore.Field
+}
+
+func (c *sentryCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(append([]zapcore.Field(nil), c.fields...), fields...)
+	return &clone
+}
+
+func (c *sentryCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *sentryCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, field := range c.fields {
+		field.AddTo(enc)
+	}
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+
+	event := sentry.NewEvent()
+	event.Level = sentry.LevelError
+	if entry.Level > zapcore.ErrorLevel {
+		event.Level = sentry.LevelFatal
+	}
+	event.Message = entry.Message
+	event.Logger = entry.LoggerName
+	event.Timestamp = entry.Time
+	for key, value := range enc.Fields {
+		if sentryTags[key] {
+			event.Tags[key] = fmt.Sprint(value)
+			continue
+		}
+		event.Extra[key] = value
+	}
+	if entry.Caller.Defined {
+		event.Extra["caller"] = entry.Caller.TrimmedPath()
+	}
+
+	c.hub.CaptureEvent(event)
+	return nil
+}
+
+func (c *sentryCore) Sync() error {
+	c.hub.Flush(sentryFlushTimeout)
+	return nil
+}
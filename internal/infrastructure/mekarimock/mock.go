@@ -0,0 +1,546 @@
+package mekarimock
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/oauth2"
+)
+
+// mockBalance is the e-meterai balance the fake profile starts with
+const mockBalance = 1000
+
+// Server is a fake Mekari API for mekari.mock. It accepts sign and stamp requests, serves the
+// uploaded documents back as the signed ones and reports progress with delayed webhooks to the
+// callback URL of each request, like Mekari does. Nothing is actually signed or stamped.
+type Server struct {
+	config *config.Config
+	logger *zap.Logger
+	client *http.Client
+	server *http.Server
+
+	mu        sync.Mutex
+	documents map[string]*document
+	balance   int
+	usage     int
+
+	// done stops pending webhooks on shutdown
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// document is a sign or stamp request received by the fake API
+type document struct {
+	id             string
+	filename       string
+	content        []byte
+	callbackURL    string
+	signers        []entity.WebhookSigner
+	signingStatus  string
+	stampingStatus string
+	createdAt      time.Time
+	updatedAt      time.Time
+}
+
+// NewServer starts the fake API when mekari.mock is enabled
+func NewServer(lc fx.Lifecycle, cfg *config.Config, logger *zap.Logger) {
+	if !cfg.Mekari.Mock {
+		return
+	}
+
+	s := &Server{
+		config:    cfg,
+		logger:    logger.Named("mekari-mock"),
+		client:    &http.Client{Timeout: 30 * time.Second},
+		documents: make(map[string]*document),
+		balance:   mockBalance,
+		done:      make(chan struct{}),
+	}
+	s.server = &http.Server{Handler: s.routes(), ReadHeaderTimeout: 10 * time.Second}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(cfg.Mekari.MockPort))
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return fmt.Errorf("cannot listen on %s for mekari.mock: %w", addr, err)
+			}
+
+			s.logger.Warn("Mekari mock API enabled, nothing is sent to Mekari",
+				zap.String("address", ln.Addr().String()),
+				zap.String("callback_base_url", cfg.App.BaseURL),
+				zap.Int("webhook_delay_seconds", cfg.Mekari.MockWebhookDelay),
+			)
+
+			go func() {
+				if err := s.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					s.logger.Error("Mekari mock API stopped", zap.Error(err))
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			close(s.done)
+			err := s.server.Shutdown(ctx)
+			s.wg.Wait()
+			return err
+		},
+	})
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /oauth2/token", s.token)
+	mux.HandleFunc("GET /auth", s.authorize)
+	mux.HandleFunc("GET /profile", s.profile)
+	mux.HandleFunc("GET /documents", s.listDocuments)
+	mux.HandleFunc("POST /documents/request_global_sign", s.globalSign)
+	mux.HandleFunc("POST /documents/stamp", s.stamp)
+	mux.HandleFunc("GET /documents/{id}", s.getDocument)
+	mux.HandleFunc("GET /documents/{id}/download", s.download)
+	mux.HandleFunc("GET /documents/{id}/audit_trail", s.download)
+	mux.HandleFunc("POST /documents/{id}/void", s.void)
+	// Health checks only need the host to answer
+	mux.HandleFunc("/{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return mux
+}
+
+// token issues a token for any code or refresh token
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, oauth2.TokenResponse{
+		AccessToken:  "mock-access-" + newID(),
+		TokenType:    "Bearer",
+		ExpiresIn:    3600,
+		RefreshToken: "mock-refresh-" + newID(),
+	})
+}
+
+// authorize approves the consent screen at once and returns to our OAuth callback
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	target := fmt.Sprintf("%s/redirect/oauth?code=%s&state=%s",
+		s.config.App.BaseURL, "mock-code-"+newID(), r.URL.Query().Get("state"))
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+func (s *Server) profile(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	quota := &entity.Quota{RemainingEmeterai: s.balance, EmeteraiUsage: s.usage, GlobalSignDoc: len(s.documents)}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, entity.ProfileResponse{
+		Data: &entity.Profile{
+			ID:   "mock-profile",
+			Type: "user",
+			Attributes: entity.ProfileAttributes{
+				ID:      "mock-profile",
+				Email:   "mock@example.com",
+				Name:    "Mekari Mock",
+				Company: "Mekari Mock",
+				Status:  "active",
+				Quota:   quota,
+			},
+		},
+		Message: "OK",
+		Status:  http.StatusOK,
+	})
+}
+
+func (s *Server) listDocuments(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	s.mu.Lock()
+	docs := make([]entity.Document, 0, len(s.documents))
+	for _, doc := range s.documents {
+		docs = append(docs, entity.Document{
+			ID:          doc.id,
+			Name:        doc.filename,
+			Type:        "document",
+			Status:      doc.signingStatus,
+			SignerCount: len(doc.signers),
+			CreatedAt:   doc.createdAt,
+			UpdatedAt:   doc.updatedAt,
+		})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(docs, func(i, j int) bool { return docs[i].CreatedAt.After(docs[j].CreatedAt) })
+	total := len(docs)
+	start := min((page-1)*limit, total)
+	end := min(start+limit, total)
+
+	writeJSON(w, http.StatusOK, entity.DocumentListResponse{
+		Data:    docs[start:end],
+		Message: "OK",
+		Status:  http.StatusOK,
+		Meta: &entity.Meta{
+			Page:       page,
+			PerPage:    limit,
+			TotalPages: (total + limit - 1) / limit,
+			TotalCount: total,
+		},
+	})
+}
+
+// globalSign accepts a sign request, then has every signer sign in turn
+func (s *Server) globalSign(w http.ResponseWriter, r *http.Request) {
+	var req entity.MekariSignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	content, err := base64.StdEncoding.DecodeString(req.Doc)
+	if err != nil || len(content) == 0 {
+		writeError(w, http.StatusUnprocessableEntity, "doc must be a base64 encoded PDF")
+		return
+	}
+	if len(req.Signers) == 0 {
+		writeError(w, http.StatusUnprocessableEntity, "signers are required")
+		return
+	}
+
+	doc := s.newDocument(req.Filename, content, req.CallbackURL)
+	doc.signingStatus = "in_progress"
+	doc.stampingStatus = "none"
+	for i, signer := range req.Signers {
+		order := signer.Order
+		if order == 0 {
+			order = i + 1
+		}
+		signingURL := s.config.Mekari.MockURL() + "/sign/" + doc.id
+		doc.signers = append(doc.signers, entity.WebhookSigner{
+			Name:       signer.Name,
+			Email:      signer.Email,
+			Order:      order,
+			Status:     "pending",
+			SigningURL: &signingURL,
+		})
+	}
+	sort.SliceStable(doc.signers, func(i, j int) bool { return doc.signers[i].Order < doc.signers[j].Order })
+	resp := s.globalSignResponse(doc)
+	s.add(doc)
+
+	s.logger.Info("Mock sign request received",
+		zap.String("document_id", doc.id),
+		zap.String("filename", doc.filename),
+		zap.Int("signers", len(doc.signers)),
+	)
+	writeJSON(w, http.StatusCreated, resp)
+
+	s.simulate(doc.id, s.signNext)
+}
+
+// stamp accepts an e-meterai stamp request, then reports it stamped
+func (s *Server) stamp(w http.ResponseWriter, r *http.Request) {
+	var req entity.StampRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	content, err := base64.StdEncoding.DecodeString(req.Doc)
+	if err != nil || len(content) == 0 {
+		writeError(w, http.StatusUnprocessableEntity, "doc must be a base64 encoded PDF")
+		return
+	}
+
+	s.mu.Lock()
+	if s.balance < len(req.Annotations) {
+		s.mu.Unlock()
+		writeError(w, http.StatusUnprocessableEntity, "insufficient e-meterai balance")
+		return
+	}
+	s.balance -= len(req.Annotations)
+	s.usage += len(req.Annotations)
+	s.mu.Unlock()
+
+	doc := s.newDocument(req.Filename, content, req.CallbackURL)
+	doc.signingStatus = "completed"
+	doc.stampingStatus = "in_progress"
+	resp := entity.StampResponse{
+		Data: &entity.StampData{
+			ID:   doc.id,
+			Type: "document",
+			Attributes: entity.StampAttributes{
+				DocID:          doc.id,
+				Filename:       doc.filename,
+				Status:         doc.signingStatus,
+				StampingStatus: doc.stampingStatus,
+				DocURL:         downloadURL(doc.id),
+				CreatedAt:      doc.createdAt.Format(time.RFC3339),
+			},
+		},
+	}
+	s.add(doc)
+
+	s.logger.Info("Mock stamp request received",
+		zap.String("document_id", doc.id),
+		zap.String("filename", doc.filename),
+		zap.Int("annotations", len(req.Annotations)),
+	)
+	writeJSON(w, http.StatusCreated, resp)
+
+	s.simulate(doc.id, func(doc *document) bool {
+		doc.stampingStatus = "success"
+		return false
+	})
+}
+
+// getDocument returns the current state of a document with the attributes of its webhooks
+func (s *Server) getDocument(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	doc, ok := s.documents[r.PathValue("id")]
+	var data entity.WebhookData
+	if ok {
+		data = webhookData(doc)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "document not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, entity.DocumentDetailResponse{Data: &data})
+}
+
+// download serves a document as it was uploaded; the audit trail is the same file
+func (s *Server) download(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	doc, ok := s.documents[r.PathValue("id")]
+	var content []byte
+	if ok {
+		content = doc.content
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "document not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(content)
+}
+
+// void voids a document and reports it with a webhook
+func (s *Server) void(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	s.mu.Lock()
+	doc, ok := s.documents[id]
+	if ok {
+		doc.signingStatus = "voided"
+		doc.updatedAt = time.Now()
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "document not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": "Document voided"})
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.sendWebhook(id)
+	}()
+}
+
+// newDocument returns a received document with a new ID
+func (s *Server) newDocument(filename string, content []byte, callbackURL string) *document {
+	if callbackURL == "" {
+		callbackURL = s.config.App.BaseURL + "/webhook/mekari"
+	}
+	now := time.Now()
+	return &document{
+		id:          newID(),
+		filename:    filename,
+		content:     content,
+		callbackURL: callbackURL,
+		createdAt:   now,
+		updatedAt:   now,
+	}
+}
+
+// add stores a document once it is complete, so that it can be downloaded and listed
+func (s *Server) add(doc *document) {
+	s.mu.Lock()
+	s.documents[doc.id] = doc
+	s.mu.Unlock()
+}
+
+// signNext marks the next pending signer as signed and reports whether any signer is left
+func (s *Server) signNext(doc *document) bool {
+	now := time.Now().Format(time.RFC3339)
+	for i := range doc.signers {
+		if doc.signers[i].Status == "pending" {
+			doc.signers[i].Status = "completed"
+			doc.signers[i].SignedAt = &now
+			if i < len(doc.signers)-1 {
+				return true
+			}
+			doc.signingStatus = "completed"
+			return false
+		}
+	}
+	return false
+}
+
+// simulate applies step to a document every mekari.mock_webhook_delay seconds and sends a webhook
+// after each, until step reports it is done or the document is voided
+func (s *Server) simulate(id string, step func(doc *document) bool) {
+	delay := time.Duration(s.config.Mekari.MockWebhookDelay) * time.Second
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-time.After(delay):
+			}
+
+			s.mu.Lock()
+			doc := s.documents[id]
+			if doc.signingStatus == "voided" {
+				s.mu.Unlock()
+				return
+			}
+			more := step(doc)
+			doc.updatedAt = time.Now()
+			s.mu.Unlock()
+
+			s.sendWebhook(id)
+			if !more {
+				return
+			}
+		}
+	}()
+}
+
+// sendWebhook posts the current state of a document to its callback URL
+func (s *Server) sendWebhook(id string) {
+	s.mu.Lock()
+	doc := s.documents[id]
+	payload := entity.WebhookPayload{Data: webhookData(doc)}
+	callbackURL := doc.callbackURL
+	s.mu.Unlock()
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		s.logger.Error("Failed to encode mock webhook", zap.String("document_id", id), zap.Error(err))
+		return
+	}
+	resp, err := s.client.Post(callbackURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		s.logger.Error("Failed to send mock webhook",
+			zap.String("document_id", id),
+			zap.String("callback_url", callbackURL),
+			zap.Error(err),
+		)
+		return
+	}
+	resp.Body.Close()
+
+	s.logger.Info("Mock webhook sent",
+		zap.String("document_id", id),
+		zap.String("signing_status", payload.Data.Attributes.SigningStatus),
+		zap.String("stamping_status", payload.Data.Attributes.StampingStatus),
+		zap.Int("status_code", resp.StatusCode),
+	)
+}
+
+// webhookData returns the state of a document as webhooks and the document detail report it;
+// s.mu must be held
+func webhookData(doc *document) entity.WebhookData {
+	return entity.WebhookData{
+		ID:   doc.id,
+		Type: "document",
+		Attributes: entity.WebhookAttributes{
+			Filename:       doc.filename,
+			Category:       "global",
+			DocURL:         downloadURL(doc.id),
+			SigningStatus:  doc.signingStatus,
+			StampingStatus: doc.stampingStatus,
+			Signers:        append([]entity.WebhookSigner(nil), doc.signers...),
+			CreatedAt:      doc.createdAt,
+			UpdatedAt:      doc.updatedAt,
+		},
+	}
+}
+
+func (s *Server) globalSignResponse(doc *document) entity.GlobalSignResponse {
+	signers := make([]entity.SignerStatus, 0, len(doc.signers))
+	for _, signer := range doc.signers {
+		signers = append(signers, entity.SignerStatus{
+			Name:     signer.Name,
+			Email:    signer.Email,
+			Status:   signer.Status,
+			Sequence: signer.Order,
+		})
+	}
+
+	return entity.GlobalSignResponse{
+		Data: &entity.GlobalSignData{
+			ID:   doc.id,
+			Type: "document",
+			Attributes: entity.GlobalSignAttributes{
+				DocID:     doc.id,
+				DocToken:  "mock-token-" + doc.id,
+				DocURL:    downloadURL(doc.id),
+				Filename:  doc.filename,
+				Status:    doc.signingStatus,
+				Signers:   signers,
+				CreatedAt: doc.createdAt.Format(time.RFC3339),
+			},
+		},
+	}
+}
+
+// downloadURL is the doc_url of a document, relative to mekari.base_url like Mekari's
+func downloadURL(id string) string {
+	return "/documents/" + id + "/download"
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{"message": message, "status": status})
+}
@@ -0,0 +1,7 @@
+package mekarimock
+
+import "go.uber.org/fx"
+
+var Module = fx.Module("mekarimock",
+	fx.Invoke(NewServer),
+)
@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace prefixes every metric of the service
+const Namespace = "mekari_esign"
+
+// Services labelling outgoing calls
+const (
+	ServiceMekari = "mekari"
+	ServiceNAV    = "nav"
+)
+
+// Webhook event results
+const (
+	WebhookQueued    = "queued"
+	WebhookDuplicate = "duplicate"
+	WebhookProcessed = "processed"
+	WebhookFailed    = "failed"
+)
+
+// OAuth2 token refresh results
+const (
+	RefreshSucceeded = "succeeded"
+	RefreshFailed    = "failed"
+)
+
+// Metrics holds the Prometheus registry and the instruments updated while the service runs.
+// Values sampled on each scrape (queues, folders, pools) are added with Register.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	HTTPRequests     *prometheus.CounterVec
+	HTTPDuration     *prometheus.HistogramVec
+	HTTPInFlight     prometheus.Gauge
+	WebhookReceived  *prometheus.CounterVec
+	WebhookProcessed *prometheus.CounterVec
+	WebhookDuration  prometheus.Histogram
+	ExternalRequests *prometheus.CounterVec
+	ExternalDuration *prometheus.HistogramVec
+	TokenRefreshes   *prometheus.CounterVec
+}
+
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		HTTPRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests handled, by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		HTTPDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency, by method and route pattern.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		HTTPInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "HTTP requests currently being handled, including open event streams.",
+		}),
+		WebhookReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "webhook_events_received_total",
+			Help:      "Mekari webhook callbacks received, by result (queued or duplicate).",
+		}, []string{"result"}),
+		WebhookProcessed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "webhook_events_processed_total",
+			Help:      "Webhook events processed by the worker, by result (processed or failed).",
+		}, []string{"result"}),
+		WebhookDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "webhook_processing_duration_seconds",
+			Help:      "Time to process a webhook event, including document download and NAV updates.",
+			Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		}),
+		ExternalRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "external_requests_total",
+			Help:      "Calls to Mekari and NAV, by service, operation and status code (\"error\" when no response was received).",
+		}, []string{"service", "operation", "status"}),
+		ExternalDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "external_request_duration_seconds",
+			Help:      "Latency of calls to Mekari and NAV, by service and operation.",
+			Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}, []string{"service", "operation"}),
+		TokenRefreshes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "oauth_token_refreshes_total",
+			Help:      "OAuth2 access token refreshes, by result (succeeded or failed).",
+		}, []string{"result"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.HTTPRequests,
+		m.HTTPDuration,
+		m.HTTPInFlight,
+		m.WebhookReceived,
+		m.WebhookProcessed,
+		m.WebhookDuration,
+		m.ExternalRequests,
+		m.ExternalDuration,
+		m.TokenRefreshes,
+	)
+
+	return m
+}
+
+// Register adds collectors sampled on each scrape
+func (m *Metrics) Register(cs ...prometheus.Collector) {
+	m.registry.MustRegister(cs...)
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+}
@@ -0,0 +1,8 @@
+package metrics
+
+import "go.uber.org/fx"
+
+var Module = fx.Module("metrics",
+	fx.Provide(NewMetrics),
+	fx.Invoke(registerPoolCollectors),
+)
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+
+	"mekari-esign/internal/infrastructure/database"
+	"mekari-esign/internal/infrastructure/redis"
+)
+
+// redisPoolCollector samples the Redis connection pool on each scrape
+type redisPoolCollector struct {
+	client *redis.RedisClient
+
+	hits        *prometheus.Desc
+	misses      *prometheus.Desc
+	timeouts    *prometheus.Desc
+	connections *prometheus.Desc
+}
+
+func newRedisPoolCollector(client *redis.RedisClient) *redisPoolCollector {
+	return &redisPoolCollector{
+		client: client,
+		hits: prometheus.NewDesc(Namespace+"_redis_pool_hits_total",
+			"Times a free connection was found in the Redis pool.", nil, nil),
+		misses: prometheus.NewDesc(Namespace+"_redis_pool_misses_total",
+			"Times a free connection was not found in the Redis pool.", nil, nil),
+		timeouts: prometheus.NewDesc(Namespace+"_redis_pool_timeouts_total",
+			"Times waiting for a Redis pool connection timed out.", nil, nil),
+		connections: prometheus.NewDesc(Namespace+"_redis_pool_connections",
+			"Redis pool connections, by state (total, idle or stale).", []string{"state"}, nil),
+	}
+}
+
+func (c *redisPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.timeouts
+	ch <- c.connections
+}
+
+func (c *redisPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.client.Client.PoolStats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(stats.Timeouts))
+	ch <- prometheus.MustNewConstMetric(c.connections, prometheus.GaugeValue, float64(stats.TotalConns), "total")
+	ch <- prometheus.MustNewConstMetric(c.connections, prometheus.GaugeValue, float64(stats.IdleConns), "idle")
+	ch <- prometheus.MustNewConstMetric(c.connections, prometheus.GaugeValue, float64(stats.StaleConns), "stale")
+}
+
+// registerPoolCollectors adds the database (go_sql_*) and Redis pool statistics. The replica
+// pool, when configured, is exported with db_name "<driver>_replica".
+func registerPoolCollectors(m *Metrics, db *database.Database, redisClient *redis.RedisClient) {
+	m.Register(
+		collectors.NewDBStatsCollector(db.DB, db.Dialect.Name()),
+		newRedisPoolCollector(redisClient),
+	)
+	if replica := db.Replica(); replica != nil {
+		m.Register(collectors.NewDBStatsCollector(replica, db.Dialect.Name()+"_replica"))
+	}
+}
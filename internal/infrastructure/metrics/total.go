@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Total returns the sum of the counter series collected from c whose labels match. A nil
+// match sums every series.
+func Total(c prometheus.Collector, match func(labels map[string]string) bool) float64 {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		c.Collect(ch)
+		close(ch)
+	}()
+
+	var total float64
+	for metric := range ch {
+		var m dto.Metric
+		if err := metric.Write(&m); err != nil || m.Counter == nil {
+			continue
+		}
+
+		labels := make(map[string]string, len(m.Label))
+		for _, pair := range m.Label {
+			labels[pair.GetName()] = pair.GetValue()
+		}
+		if match == nil || match(labels) {
+			total += m.GetCounter().GetValue()
+		}
+	}
+	return total
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Transport records the count, status and latency of the requests sent through next
+func (m *Metrics) Transport(service string, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{metrics: m, service: service, next: next}
+}
+
+type transport struct {
+	metrics *Metrics
+	service string
+	next    http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	operation := Operation(req.URL.EscapedPath())
+	start := time.Now()
+
+	resp, err := t.next.RoundTrip(req)
+
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	t.metrics.ExternalRequests.WithLabelValues(t.service, operation, status).Inc()
+	t.metrics.ExternalDuration.WithLabelValues(t.service, operation).Observe(time.Since(start).Seconds())
+
+	return resp, err
+}
+
+// Operation turns a request path into a low-cardinality label: IDs become ":id", OData keys
+// such as "(Entry_No=5)" are dropped and the NAV company name is left out.
+// "/documents/2f1c.../download" becomes "/documents/:id/download".
+func Operation(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	out := segments[:0]
+	for _, segment := range segments {
+		if i := strings.Index(segment, "("); i >= 0 {
+			segment = segment[:i]
+		}
+		if segment == "" || segment == "Company" {
+			continue
+		}
+		if isID(segment) {
+			segment = ":id"
+		}
+		out = append(out, segment)
+	}
+	return "/" + strings.Join(out, "/")
+}
+
+// isID reports whether a path segment looks like an identifier: all digits, or a long token
+// (UUIDs, hashes) containing digits
+func isID(segment string) bool {
+	digits := 0
+	for _, r := range segment {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+	return digits == len(segment) || (digits > 0 && len(segment) >= 16)
+}
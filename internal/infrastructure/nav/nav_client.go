@@ -15,42 +15,65 @@ import (
 
 	"mekari-esign/internal/config"
 	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/infrastructure/metrics"
+	"mekari-esign/internal/timeutil"
 )
 
+// APILogSaver stores NAV log entry updates in api_logs
+type APILogSaver interface {
+	Save(ctx context.Context, log *entity.APILog) error
+}
+
 // Client is the NAV API client for sending log entries
 type Client struct {
-	config     *config.Config
-	httpClient *http.Client
-	logger     *zap.Logger
+	config      *config.Config
+	httpClient  *http.Client
+	apiLogSaver APILogSaver
+	logger      *zap.Logger
 }
 
 // NewClient creates a new NAV client
-func NewClient(cfg *config.Config, logger *zap.Logger) *Client {
-	timeout := time.Duration(cfg.NAV.Timeout) * time.Second
+func NewClient(cfg *config.Config, meter *metrics.Metrics, apiLogSaver APILogSaver, logger *zap.Logger) *Client {
+	return &Client{
+		config:      cfg,
+		httpClient:  &http.Client{Transport: meter.Transport(metrics.ServiceNAV, nil)},
+		apiLogSaver: apiLogSaver,
+		logger:      logger.Named("nav"),
+	}
+}
+
+// withTimeout bounds a NAV call by nav.timeout, read per call so config reloads apply
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := time.Duration(c.config.NAVSettings().Timeout) * time.Second
 	if timeout == 0 {
 		timeout = 30 * time.Second
 	}
+	return context.WithTimeout(ctx, timeout)
+}
 
-	return &Client{
-		config: cfg,
-		httpClient: &http.Client{
-			Timeout: timeout,
-		},
-		logger: logger,
+// setAuthHeaders sets basic auth and forwards the request ID of ctx
+func (c *Client) setAuthHeaders(ctx context.Context, req *http.Request) {
+	nav := c.config.NAVSettings()
+	auth := base64.StdEncoding.EncodeToString([]byte(nav.Username + ":" + nav.Password))
+	req.Header.Set("Authorization", "Basic "+auth)
+	if requestID := logger.RequestIDFromContext(ctx); requestID != "" {
+		req.Header.Set(logger.RequestIDHeader, requestID)
 	}
 }
 
 // UpdateLogEntry updates a log entry in NAV using PATCH
 func (c *Client) UpdateLogEntry(ctx context.Context, entry *entity.NAVLogEntry) error {
-	if !c.config.NAV.Enabled {
+	nav := c.config.NAVSettings()
+	if !nav.Enabled {
 		c.logger.Debug("NAV integration disabled, skipping log entry update")
 		return nil
 	}
 
 	// Build URL with company and Entry_No parameter
 	apiURL := fmt.Sprintf("%s/ODataV4/Company('%s')/Api_MekariInvoiceLogEntries(Entry_No=%d)",
-		c.config.NAV.BaseURL,
-		url.PathEscape(c.config.NAV.Company),
+		nav.BaseURL,
+		url.PathEscape(nav.Company),
 		entry.EntryNo,
 	)
 
@@ -60,7 +83,8 @@ func (c *Client) UpdateLogEntry(ctx context.Context, entry *entity.NAVLogEntry)
 		return fmt.Errorf("failed to marshal NAV log entry: %w", err)
 	}
 
-	c.logger.Info("Updating log entry in NAV (PATCH)",
+	log := logger.FromContext(ctx, c.logger)
+	log.Info("Updating log entry in NAV (PATCH)",
 		zap.String("url", apiURL),
 		zap.Int("entry_no", entry.EntryNo),
 		zap.String("invoice_no", entry.InvoiceNo),
@@ -69,6 +93,9 @@ func (c *Client) UpdateLogEntry(ctx context.Context, entry *entity.NAVLogEntry)
 		zap.String("request_body", string(reqBody)),
 	)
 
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	// Create PATCH request
 	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, apiURL, bytes.NewBuffer(reqBody))
 	if err != nil {
@@ -78,12 +105,13 @@ func (c *Client) UpdateLogEntry(ctx context.Context, entry *entity.NAVLogEntry)
 	// Set headers
 	req.Header.Set("Content-Type", "application/json;EEE754Compatible=true")
 	req.Header.Set("If-Match", "*")
-	auth := base64.StdEncoding.EncodeToString([]byte(c.config.NAV.Username + ":" + c.config.NAV.Password))
-	req.Header.Set("Authorization", "Basic "+auth)
+	c.setAuthHeaders(ctx, req)
 
 	// Execute request
+	startTime := time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		c.saveAPILog(ctx, http.MethodPatch, apiURL, reqBody, []byte(err.Error()), 0, time.Since(startTime), entry)
 		return fmt.Errorf("failed to update NAV log entry: %w", err)
 	}
 	defer resp.Body.Close()
@@ -93,8 +121,9 @@ func (c *Client) UpdateLogEntry(ctx context.Context, entry *entity.NAVLogEntry)
 	if err != nil {
 		return fmt.Errorf("failed to read NAV response: %w", err)
 	}
+	c.saveAPILog(ctx, http.MethodPatch, apiURL, reqBody, respBody, resp.StatusCode, time.Since(startTime), entry)
 
-	c.logger.Info("NAV UpdateLogEntry response",
+	log.Info("NAV UpdateLogEntry response",
 		zap.Int("status_code", resp.StatusCode),
 		zap.String("body", string(respBody)),
 	)
@@ -103,7 +132,7 @@ func (c *Client) UpdateLogEntry(ctx context.Context, entry *entity.NAVLogEntry)
 		return fmt.Errorf("NAV update failed: status=%d, body=%s", resp.StatusCode, string(respBody))
 	}
 
-	c.logger.Info("Successfully updated log entry in NAV",
+	log.Info("Successfully updated log entry in NAV",
 		zap.Int("entry_no", entry.EntryNo),
 		zap.String("invoice_no", entry.InvoiceNo),
 	)
@@ -111,16 +140,48 @@ func (c *Client) UpdateLogEntry(ctx context.Context, entry *entity.NAVLogEntry)
 	return nil
 }
 
+// saveAPILog stores a log entry update in api_logs, correlated with the document of ctx.
+// A status code of 0 means NAV could not be reached.
+func (c *Client) saveAPILog(ctx context.Context, method, endpoint string, requestBody, responseBody []byte, statusCode int, duration time.Duration, entry *entity.NAVLogEntry) {
+	if c.apiLogSaver == nil {
+		return
+	}
+
+	documentID, invoiceNo := logger.DocumentFromContext(ctx)
+	if entry.InvoiceNo != "" {
+		invoiceNo = entry.InvoiceNo
+	}
+
+	apiLog := &entity.APILog{
+		Endpoint:     endpoint,
+		InvoiceNo:    invoiceNo,
+		EntryNo:      entry.EntryNo,
+		DocumentID:   documentID,
+		Source:       entity.APILogSourceNAV,
+		Method:       method,
+		RequestBody:  string(requestBody),
+		ResponseBody: string(responseBody),
+		StatusCode:   statusCode,
+		Duration:     duration.Milliseconds(),
+		RequestID:    logger.RequestIDFromContext(ctx),
+		CreatedAt:    timeutil.Now(),
+	}
+	if err := c.apiLogSaver.Save(context.WithoutCancel(ctx), apiLog); err != nil {
+		logger.FromContext(ctx, c.logger).Warn("Failed to save NAV API log", zap.Error(err))
+	}
+}
+
 // SendAPILog sends an API log entry to NAV (MekariApiLogEntries)
 func (c *Client) SendAPILog(ctx context.Context, log *entity.NAVAPILog) error {
-	if !c.config.NAV.Enabled {
+	nav := c.config.NAVSettings()
+	if !nav.Enabled {
 		return nil
 	}
 
 	// Build URL
 	apiURL := fmt.Sprintf("%s/ODataV4/Company('%s')/MekariApiLogEntries",
-		c.config.NAV.BaseURL,
-		url.PathEscape(c.config.NAV.Company),
+		nav.BaseURL,
+		url.PathEscape(nav.Company),
 	)
 
 	// Marshal request body
@@ -129,6 +190,9 @@ func (c *Client) SendAPILog(ctx context.Context, log *entity.NAVAPILog) error {
 		return fmt.Errorf("failed to marshal NAV API log: %w", err)
 	}
 
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	// Create POST request
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewBuffer(reqBody))
 	if err != nil {
@@ -137,8 +201,7 @@ func (c *Client) SendAPILog(ctx context.Context, log *entity.NAVAPILog) error {
 
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
-	auth := base64.StdEncoding.EncodeToString([]byte(c.config.NAV.Username + ":" + c.config.NAV.Password))
-	req.Header.Set("Authorization", "Basic "+auth)
+	c.setAuthHeaders(ctx, req)
 
 	// Execute request
 	resp, err := c.httpClient.Do(req)
@@ -162,24 +225,27 @@ func (c *Client) SendAPILog(ctx context.Context, log *entity.NAVAPILog) error {
 
 // GetSetup fetches the Mekari setup configuration from NAV
 func (c *Client) GetSetup(ctx context.Context) (*entity.NAVSetup, error) {
-	if !c.config.NAV.Enabled {
+	nav := c.config.NAVSettings()
+	if !nav.Enabled {
 		return nil, nil
 	}
 
 	apiURL := fmt.Sprintf("%s/ODataV4/Company('%s')/Api_MekariSetup",
-		c.config.NAV.BaseURL,
-		url.PathEscape(c.config.NAV.Company),
+		nav.BaseURL,
+		url.PathEscape(nav.Company),
 	)
 
 	c.logger.Info("Fetching Mekari setup from NAV", zap.String("url", apiURL))
 
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create NAV setup request: %w", err)
 	}
 
-	auth := base64.StdEncoding.EncodeToString([]byte(c.config.NAV.Username + ":" + c.config.NAV.Password))
-	req.Header.Set("Authorization", "Basic "+auth)
+	c.setAuthHeaders(ctx, req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -209,3 +275,35 @@ func (c *Client) GetSetup(ctx context.Context) (*entity.NAVSetup, error) {
 
 	return &setupResp.Value[0], nil
 }
+
+// Ping checks that NAV is reachable and accepts the configured credentials
+func (c *Client) Ping(ctx context.Context) error {
+	nav := c.config.NAVSettings()
+	apiURL := fmt.Sprintf("%s/ODataV4/Company('%s')/Api_MekariSetup?$top=1",
+		nav.BaseURL,
+		url.PathEscape(nav.Company),
+	)
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create NAV ping request: %w", err)
+	}
+
+	c.setAuthHeaders(ctx, req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("NAV is unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("NAV ping failed: status=%d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"mekari-esign/internal/config"
+)
+
+const emailTimeout = 30 * time.Second
+
+type emailNotifier struct {
+	config   config.NotifierEmailConfig
+	location *time.Location
+}
+
+// NewEmailNotifier creates a notifier that mails notifications as plain text over SMTP. Port 465
+// connects with TLS; on other ports STARTTLS is used when the server offers it, and a login is
+// only sent over TLS (or to localhost).
+func NewEmailNotifier(cfg config.NotifierEmailConfig, location *time.Location) Notifier {
+	return &emailNotifier{config: cfg, location: location}
+}
+
+func (e *emailNotifier) Notify(ctx context.Context, n *Notification) error {
+	from, err := mail.ParseAddress(e.config.From)
+	if err != nil {
+		return fmt.Errorf("invalid notifier.email.from %q: %w", e.config.From, err)
+	}
+	recipients := make([]string, 0, len(e.config.To))
+	for _, to := range e.config.To {
+		addr, err := mail.ParseAddress(to)
+		if err != nil {
+			return fmt.Errorf("invalid notifier.email.to %q: %w", to, err)
+		}
+		recipients = append(recipients, addr.Address)
+	}
+
+	message, err := e.message(n)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, emailTimeout)
+	defer cancel()
+
+	client, err := e.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if e.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", e.config.Username, e.config.Password, e.config.Host)); err != nil {
+			return fmt.Errorf("smtp login failed: %w", err)
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("smtp sender refused: %w", err)
+	}
+	for _, to := range recipients {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("smtp recipient %s refused: %w", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data refused: %w", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		return fmt.Errorf("failed to write mail: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("mail refused: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// dial connects to the SMTP server, with TLS on port 465 and STARTTLS when offered on other ports
+func (e *emailNotifier) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(e.config.Host, strconv.Itoa(e.config.Port))
+	tlsConfig := &tls.Config{ServerName: e.config.Host, MinVersion: tls.VersionTLS12}
+
+	var conn net.Conn
+	var err error
+	if e.config.Port == 465 {
+		conn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to smtp server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, e.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("smtp handshake failed: %w", err)
+	}
+	if e.config.Port != 465 {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				client.Close()
+				return nil, fmt.Errorf("smtp starttls failed: %w", err)
+			}
+		}
+	}
+	return client, nil
+}
+
+// message builds the mail with the message, the fields and the event as its body
+func (e *emailNotifier) message(n *Notification) ([]byte, error) {
+	var body bytes.Buffer
+	qp := quotedprintable.NewWriter(&body)
+	fmt.Fprintf(qp, "%s\r\n", n.Message)
+	if fields := sortedFields(n); len(fields) > 0 {
+		fmt.Fprint(qp, "\r\n")
+		for _, f := range fields {
+			fmt.Fprintf(qp, "%s: %s\r\n", f.Name, f.Value)
+		}
+	}
+	fmt.Fprintf(qp, "\r\n--\r\n%s (%s), %s\r\n", n.Event, n.Severity, n.Time.In(e.location).Format("2006-01-02 15:04:05 MST"))
+	if err := qp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode mail: %w", err)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.config.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", fmt.Sprintf("[%s] %s", strings.ToUpper(n.Severity), n.Title)))
+	fmt.Fprintf(&msg, "Date: %s\r\n", n.Time.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
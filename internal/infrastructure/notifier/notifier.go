@@ -0,0 +1,149 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/infrastructure/i18n"
+	"mekari-esign/internal/timeutil"
+)
+
+// Notification severities
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Notification is an operational message for the people running the service
+type Notification struct {
+	Event    string                 `json:"event"` // Machine-readable type, e.g. quota_low
+	Severity string                 `json:"severity"`
+	Title    string                 `json:"title"`
+	Message  string                 `json:"message"`
+	Fields   map[string]interface{} `json:"fields,omitempty"`
+	Time     time.Time              `json:"time"`
+}
+
+// Notifier delivers notifications
+type Notifier interface {
+	Notify(ctx context.Context, n *Notification) error
+}
+
+// channel is a configured provider with the events it is sent
+type channel struct {
+	name     string
+	notifier Notifier
+	events   map[string]bool // Empty = all events
+}
+
+func (c *channel) accepts(event string) bool {
+	return len(c.events) == 0 || c.events[event]
+}
+
+type notifier struct {
+	translator i18n.Translator
+	channels   []channel
+	logger     *zap.Logger
+}
+
+// NewNotifier creates a notifier that logs every notification and sends it, with the title and
+// message in app.language, to each channel configured under notifier: the JSON webhook, email,
+// Slack, Microsoft Teams and Telegram
+func NewNotifier(cfg *config.Config, translator i18n.Translator, logger *zap.Logger) Notifier {
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	loc := cfg.App.Location()
+	nc := cfg.Notifier
+
+	n := &notifier{
+		translator: translator,
+		logger:     logger,
+	}
+	if nc.WebhookURL != "" {
+		n.add("webhook", NewWebhookNotifier(nc.WebhookURL, httpClient), nc.Events)
+	}
+	if nc.Email.Host != "" {
+		n.add("email", NewEmailNotifier(nc.Email, loc), nc.Email.Events)
+	}
+	if nc.Slack.WebhookURL != "" {
+		n.add("slack", NewSlackNotifier(nc.Slack.WebhookURL, httpClient), nc.Slack.Events)
+	}
+	if nc.Teams.WebhookURL != "" {
+		n.add("teams", NewTeamsNotifier(nc.Teams.WebhookURL, httpClient, loc), nc.Teams.Events)
+	}
+	if nc.Telegram.BotToken != "" {
+		n.add("telegram", NewTelegramNotifier(nc.Telegram.BotToken, nc.Telegram.ChatID, httpClient, loc), nc.Telegram.Events)
+	}
+	return n
+}
+
+func (n *notifier) add(name string, provider Notifier, events []string) {
+	ch := channel{name: name, notifier: provider, events: make(map[string]bool, len(events))}
+	for _, event := range events {
+		ch.events[event] = true
+	}
+	n.channels = append(n.channels, ch)
+}
+
+// Notify sends the notification to every channel that accepts its event. A failing channel does
+// not keep it from the others; their errors are returned together.
+func (n *notifier) Notify(ctx context.Context, notification *Notification) error {
+	if notification.Time.IsZero() {
+		notification.Time = timeutil.Now()
+	}
+
+	level := n.logger.Warn
+	if notification.Severity == SeverityInfo {
+		level = n.logger.Info
+	}
+	level("Notification",
+		zap.String("event", notification.Event),
+		zap.String("severity", notification.Severity),
+		zap.String("title", notification.Title),
+		zap.String("message", notification.Message),
+		zap.Any("fields", notification.Fields),
+	)
+
+	if len(n.channels) == 0 {
+		return nil
+	}
+
+	localized := *notification
+	lang := n.translator.DefaultLanguage()
+	localized.Title = n.translator.Translate(lang, notification.Title)
+	localized.Message = n.translator.Translate(lang, notification.Message)
+
+	var errs []error
+	for _, ch := range n.channels {
+		if !ch.accepts(notification.Event) {
+			continue
+		}
+		if err := ch.notifier.Notify(ctx, &localized); err != nil {
+			errs = append(errs, fmt.Errorf("%s notification failed: %w", ch.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// field is a notification field formatted for people
+type field struct {
+	Name  string
+	Value string
+}
+
+// sortedFields returns the fields of a notification ordered by name
+func sortedFields(n *Notification) []field {
+	fields := make([]field, 0, len(n.Fields))
+	for name, value := range n.Fields {
+		fields = append(fields, field{Name: name, Value: fmt.Sprint(value)})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+	return fields
+}
@@ -0,0 +1,71 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Attachment bar colours per severity
+var slackColors = map[string]string{
+	SeverityInfo:     "#2eb67d",
+	SeverityWarning:  "#ecb22e",
+	SeverityCritical: "#e01e5a",
+}
+
+// slackEscaper escapes the characters Slack reads as markup
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+type slackMessage struct {
+	Text        string            `json:"text"` // Shown in push notifications
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Title  string       `json:"title"`
+	Text   string       `json:"text"`
+	Fields []slackField `json:"fields,omitempty"`
+	Footer string       `json:"footer"`
+	Ts     int64        `json:"ts"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+type slackNotifier struct {
+	webhookURL string
+	httpClient *http.Client
+}
+
+// NewSlackNotifier creates a notifier that posts to a Slack incoming webhook, as a message with an
+// attachment coloured by severity
+func NewSlackNotifier(webhookURL string, httpClient *http.Client) Notifier {
+	return &slackNotifier{webhookURL: webhookURL, httpClient: httpClient}
+}
+
+func (s *slackNotifier) Notify(ctx context.Context, n *Notification) error {
+	attachment := slackAttachment{
+		Color:  slackColors[n.Severity],
+		Title:  slackEscaper.Replace(n.Title),
+		Text:   slackEscaper.Replace(n.Message),
+		Footer: n.Event,
+		Ts:     n.Time.Unix(),
+	}
+	for _, f := range sortedFields(n) {
+		attachment.Fields = append(attachment.Fields, slackField{
+			Title: f.Name,
+			Value: slackEscaper.Replace(f.Value),
+			Short: len(f.Value) <= 40,
+		})
+	}
+
+	return postJSON(ctx, s.httpClient, s.webhookURL, &slackMessage{
+		Text:        slackEscaper.Replace(fmt.Sprintf("[%s] %s", strings.ToUpper(n.Severity), n.Title)),
+		Attachments: []slackAttachment{attachment},
+	})
+}
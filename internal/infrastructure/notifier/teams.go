@@ -0,0 +1,55 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Adaptive card text colours per severity
+var teamsColors = map[string]string{
+	SeverityInfo:     "Good",
+	SeverityWarning:  "Warning",
+	SeverityCritical: "Attention",
+}
+
+type teamsNotifier struct {
+	webhookURL string
+	httpClient *http.Client
+	location   *time.Location
+}
+
+// NewTeamsNotifier creates a notifier that posts an adaptive card to a Microsoft Teams webhook,
+// either a Workflows "post to a channel when a webhook request is received" URL or a classic
+// incoming webhook
+func NewTeamsNotifier(webhookURL string, httpClient *http.Client, location *time.Location) Notifier {
+	return &teamsNotifier{webhookURL: webhookURL, httpClient: httpClient, location: location}
+}
+
+func (t *teamsNotifier) Notify(ctx context.Context, n *Notification) error {
+	facts := []map[string]string{
+		{"title": "Event", "value": n.Event},
+		{"title": "Time", "value": n.Time.In(t.location).Format("2006-01-02 15:04:05 MST")},
+	}
+	for _, f := range sortedFields(n) {
+		facts = append(facts, map[string]string{"title": f.Name, "value": f.Value})
+	}
+
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body": []map[string]interface{}{
+			{"type": "TextBlock", "text": n.Title, "weight": "Bolder", "size": "Medium", "color": teamsColors[n.Severity], "wrap": true},
+			{"type": "TextBlock", "text": n.Message, "wrap": true},
+			{"type": "FactSet", "facts": facts},
+		},
+	}
+
+	return postJSON(ctx, t.httpClient, t.webhookURL, map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{
+			{"contentType": "application/vnd.microsoft.card.adaptive", "content": card},
+		},
+	})
+}
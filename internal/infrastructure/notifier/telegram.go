@@ -0,0 +1,41 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const telegramAPIURL = "https://api.telegram.org"
+
+type telegramNotifier struct {
+	botToken   string
+	chatID     string
+	httpClient *http.Client
+	location   *time.Location
+}
+
+// NewTelegramNotifier creates a notifier that sends notifications as messages of a Telegram bot to
+// chatID; the bot must be a member of groups and channels it sends to
+func NewTelegramNotifier(botToken, chatID string, httpClient *http.Client, location *time.Location) Notifier {
+	return &telegramNotifier{botToken: botToken, chatID: chatID, httpClient: httpClient, location: location}
+}
+
+func (t *telegramNotifier) Notify(ctx context.Context, n *Notification) error {
+	var text strings.Builder
+	fmt.Fprintf(&text, "<b>[%s] %s</b>\n\n%s\n", strings.ToUpper(n.Severity), html.EscapeString(n.Title), html.EscapeString(n.Message))
+	for _, f := range sortedFields(n) {
+		fmt.Fprintf(&text, "\n%s: <code>%s</code>", html.EscapeString(f.Name), html.EscapeString(f.Value))
+	}
+	fmt.Fprintf(&text, "\n\n<i>%s, %s</i>", n.Event, n.Time.In(t.location).Format("2006-01-02 15:04:05 MST"))
+
+	return postJSON(ctx, t.httpClient, telegramAPIURL+"/bot"+t.botToken+"/sendMessage", map[string]interface{}{
+		"chat_id":                  t.chatID,
+		"text":                     text.String(),
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	})
+}
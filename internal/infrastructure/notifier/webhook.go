@@ -0,0 +1,59 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+type webhookNotifier struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewWebhookNotifier creates a notifier that POSTs notifications to url as JSON, with the fields of
+// Notification
+func NewWebhookNotifier(url string, httpClient *http.Client) Notifier {
+	return &webhookNotifier{url: url, httpClient: httpClient}
+}
+
+func (w *webhookNotifier) Notify(ctx context.Context, n *Notification) error {
+	return postJSON(ctx, w.httpClient, w.url, n)
+}
+
+// postJSON POSTs payload as JSON and fails on a status other than 2xx
+func postJSON(ctx context.Context, httpClient *http.Client, target string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create notification request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		// Leave the URL out of the error, webhook URLs and bot tokens are credentials
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("notification rejected: status=%d, body=%s", resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
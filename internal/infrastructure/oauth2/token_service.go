@@ -7,20 +7,38 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
 
 	"mekari-esign/internal/config"
+	"mekari-esign/internal/domain/entity"
 	"mekari-esign/internal/domain/repository"
+	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/infrastructure/metrics"
 	"mekari-esign/internal/infrastructure/redis"
+	"mekari-esign/internal/timeutil"
 )
 
 const (
-	accessTokenKeyPrefix  = "mekari:access_token:"
-	refreshTokenKeyPrefix = "mekari:refresh_token:"
+	accessTokenKeyPrefix  = "access_token:"
+	refreshTokenKeyPrefix = "refresh_token:"
+
+	// historyErrorLimit caps the error text kept in the token history
+	historyErrorLimit = 1000
 )
 
+// AccessTokenKey returns the Redis key of the access token of an email
+func AccessTokenKey(email string) string {
+	return accessTokenKeyPrefix + email
+}
+
+// RefreshTokenKey returns the Redis key of the refresh token of an email
+func RefreshTokenKey(email string) string {
+	return refreshTokenKeyPrefix + email
+}
+
 // TokenResponse represents the OAuth2 token response from Mekari
 type TokenResponse struct {
 	AccessToken  string `json:"access_token"`
@@ -46,20 +64,27 @@ type TokenService interface {
 
 type tokenService struct {
 	config    *config.Config
-	redis     *redis.RedisClient
+	redis     redis.Cache
 	oauthRepo repository.OAuthRepository
+	history   repository.OAuthTokenHistoryRepository
+	redactor  *logger.Redactor
+	metrics   *metrics.Metrics
 	logger    *zap.Logger
 	client    *http.Client
 }
 
-func NewTokenService(cfg *config.Config, redisClient *redis.RedisClient, oauthRepo repository.OAuthRepository, logger *zap.Logger) TokenService {
+func NewTokenService(cfg *config.Config, cache redis.Cache, oauthRepo repository.OAuthRepository, history repository.OAuthTokenHistoryRepository, redactor *logger.Redactor, meter *metrics.Metrics, logger *zap.Logger) TokenService {
 	return &tokenService{
 		config:    cfg,
-		redis:     redisClient,
+		redis:     cache,
 		oauthRepo: oauthRepo,
-		logger:    logger,
+		history:   history,
+		redactor:  redactor,
+		metrics:   meter,
+		logger:    logger.Named("oauth2"),
 		client: &http.Client{
-			Timeout: cfg.Mekari.Timeout,
+			Timeout:   cfg.Mekari.Timeout,
+			Transport: meter.Transport(metrics.ServiceMekari, nil),
 		},
 	}
 }
@@ -78,6 +103,7 @@ func (s *tokenService) ExchangeCode(ctx context.Context, email, code string) (*T
 	}
 
 	tokenResp, err := s.requestToken(ctx, reqBody)
+	s.recordAttempt(ctx, email, entity.GrantTypeAuthorizationCode, tokenResp, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to exchange code: %w", err)
 	}
@@ -150,7 +176,9 @@ func (s *tokenService) RefreshToken(ctx context.Context, email string) (*TokenRe
 	// Get refresh token from Redis
 	refreshToken, err := s.redis.Get(ctx, refreshTokenKey)
 	if err != nil {
-		return nil, fmt.Errorf("refresh token not found, re-authorization required: %w", err)
+		err = fmt.Errorf("refresh token not found, re-authorization required: %w", err)
+		s.recordAttempt(ctx, email, entity.GrantTypeRefreshToken, nil, err)
+		return nil, err
 	}
 
 	s.logger.Info("Refreshing access token",
@@ -166,11 +194,14 @@ func (s *tokenService) RefreshToken(ctx context.Context, email string) (*TokenRe
 	}
 
 	tokenResp, err := s.requestToken(ctx, reqBody)
+	s.recordAttempt(ctx, email, entity.GrantTypeRefreshToken, tokenResp, err)
 	if err != nil {
+		s.metrics.TokenRefreshes.WithLabelValues(metrics.RefreshFailed).Inc()
 		// If refresh fails, invalidate tokens and require re-auth
 		s.InvalidateTokens(ctx, email)
 		return nil, fmt.Errorf("failed to refresh token: %w", err)
 	}
+	s.metrics.TokenRefreshes.WithLabelValues(metrics.RefreshSucceeded).Inc()
 
 	// Store new tokens in Redis
 	if err := s.storeTokens(ctx, email, tokenResp); err != nil {
@@ -197,6 +228,36 @@ func (s *tokenService) InvalidateTokens(ctx context.Context, email string) error
 	return nil
 }
 
+// recordAttempt adds a grant or refresh attempt to the token history. Failing to record it is
+// only logged, so the token request itself is not affected.
+func (s *tokenService) recordAttempt(ctx context.Context, email, grantType string, tokenResp *TokenResponse, err error) {
+	now := timeutil.Now()
+	event := &entity.OAuthTokenEvent{
+		Email:     email,
+		GrantType: grantType,
+		Success:   err == nil,
+		RequestID: logger.RequestIDFromContext(ctx),
+		CreatedAt: now,
+	}
+	if err != nil {
+		event.Error = err.Error()
+		if len(event.Error) > historyErrorLimit {
+			event.Error = strings.ToValidUTF8(event.Error[:historyErrorLimit], "")
+		}
+	} else {
+		expiresAt := now.Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+		event.IssuedAt, event.ExpiresAt = &now, &expiresAt
+	}
+
+	if err := s.history.Save(ctx, event); err != nil {
+		s.logger.Warn("Failed to record token history",
+			zap.String("email", email),
+			zap.String("grant_type", grantType),
+			zap.Error(err),
+		)
+	}
+}
+
 func (s *tokenService) requestToken(ctx context.Context, reqBody map[string]string) (*TokenResponse, error) {
 	tokenURL := s.config.Mekari.SsoBaseURL + "/oauth2/token"
 
@@ -211,13 +272,17 @@ func (s *tokenService) requestToken(ctx context.Context, reqBody map[string]stri
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	if requestID := logger.RequestIDFromContext(ctx); requestID != "" {
+		req.Header.Set(logger.RequestIDHeader, requestID)
+	}
 
 	// Log request
 	s.logger.Info(">>> [OAUTH2-TOKEN-REQ]",
 		zap.String("url", tokenURL),
-		zap.String("code", reqBody["code"]),
+		s.redactor.String("code", reqBody["code"]),
 		zap.String("client_id", reqBody["client_id"]),
-		zap.String("client_secret", reqBody["client_secret"]),
+		s.redactor.String("client_secret", reqBody["client_secret"]),
+		s.redactor.String("refresh_token", reqBody["refresh_token"]),
 		zap.String("grant_type", reqBody["grant_type"]),
 	)
 
@@ -235,7 +300,7 @@ func (s *tokenService) requestToken(ctx context.Context, reqBody map[string]stri
 	// Log response
 	s.logger.Info(">>> [OAUTH2-TOKEN-RESPONSE]",
 		zap.Int("status", resp.StatusCode),
-		zap.String("body", string(respBody)),
+		zap.String("body", s.redactor.Body(string(respBody))),
 	)
 
 	if resp.StatusCode != http.StatusOK {
@@ -0,0 +1,382 @@
+package redis
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+)
+
+// cacheRecoveryInterval is the time between pings while Redis is down
+const cacheRecoveryInterval = 2 * time.Second
+
+// Cache is the key-value store of OAuth tokens, NAV setups and document mappings. Get returns
+// Nil for a missing key.
+type Cache interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	Del(ctx context.Context, keys ...string) error
+	Exists(ctx context.Context, key string) (bool, error)
+	// Expire extends the time to live of an existing key; missing keys are left alone
+	Expire(ctx context.Context, key string, expiration time.Duration) error
+}
+
+// FallbackCache is a Cache backed by Redis that keeps an in-process LRU copy of the keys it
+// reads and writes. While Redis is unreachable reads are served from the copy and writes are
+// queued, then written to Redis once it is back (last write per key wins). Writes are announced
+// on the invalidation channel so other instances drop their copies of the key.
+type FallbackCache struct {
+	client   *RedisClient
+	logger   *zap.Logger
+	instance string
+
+	mu      sync.Mutex
+	local   *lruCache
+	down    bool
+	pending map[string]cacheWrite
+	stop    context.Context
+}
+
+// cacheWrite is a write queued while Redis is down; a zero expiresAt means no expiry
+type cacheWrite struct {
+	value     string
+	expiresAt time.Time
+	del       bool
+}
+
+func NewCache(lc fx.Lifecycle, cfg *config.Config, client *RedisClient, logger *zap.Logger) Cache {
+	stop, cancel := context.WithCancel(context.Background())
+	c := &FallbackCache{
+		client:   client,
+		logger:   logger,
+		instance: newInstanceID(),
+		local:    newLRUCache(cfg.Redis.FallbackSize),
+		pending:  make(map[string]cacheWrite),
+		stop:     stop,
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			go c.subscribe()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			cancel()
+			return nil
+		},
+	})
+
+	return c
+}
+
+func (c *FallbackCache) Get(ctx context.Context, key string) (string, error) {
+	if !c.isDown() {
+		// PTTL in the same round trip keeps the copy from outliving the Redis key
+		pipe := c.client.Client.Pipeline()
+		get := pipe.Get(ctx, c.client.Key(key))
+		ttl := pipe.PTTL(ctx, c.client.Key(key))
+		_, _ = pipe.Exec(ctx)
+
+		value, err := get.Result()
+		switch {
+		case err == nil:
+			c.mu.Lock()
+			c.local.set(key, value, expiresAt(ttl.Val()))
+			c.mu.Unlock()
+			return value, nil
+		case errors.Is(err, redis.Nil):
+			c.mu.Lock()
+			c.local.del(key)
+			c.mu.Unlock()
+			return "", Nil
+		case !c.unreachable(ctx, err):
+			return "", err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.local.get(key)
+	if !ok {
+		return "", Nil
+	}
+	return value, nil
+}
+
+func (c *FallbackCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	str := cacheString(value)
+	var expires time.Time
+	if expiration > 0 {
+		expires = time.Now().Add(expiration)
+	}
+
+	c.mu.Lock()
+	c.local.set(key, str, expires)
+	down := c.down
+	c.mu.Unlock()
+
+	if !down {
+		err := c.client.Set(ctx, key, str, expiration)
+		if err == nil {
+			c.publish(ctx, key)
+			return nil
+		}
+		if !c.unreachable(ctx, err) {
+			return err
+		}
+	}
+
+	c.queue(key, cacheWrite{value: str, expiresAt: expires})
+	return nil
+}
+
+func (c *FallbackCache) Del(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	for _, key := range keys {
+		c.local.del(key)
+	}
+	down := c.down
+	c.mu.Unlock()
+
+	if !down {
+		err := c.client.Del(ctx, keys...)
+		if err == nil {
+			c.publish(ctx, keys...)
+			return nil
+		}
+		if !c.unreachable(ctx, err) {
+			return err
+		}
+	}
+
+	for _, key := range keys {
+		c.queue(key, cacheWrite{del: true})
+	}
+	return nil
+}
+
+func (c *FallbackCache) Exists(ctx context.Context, key string) (bool, error) {
+	if !c.isDown() {
+		exists, err := c.client.Exists(ctx, key)
+		if err == nil || !c.unreachable(ctx, err) {
+			return exists, err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.local.get(key)
+	return ok, nil
+}
+
+// Expire is best effort while Redis is down: only the local copy gets the new expiry
+func (c *FallbackCache) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	c.mu.Lock()
+	c.local.expire(key, time.Now().Add(expiration))
+	down := c.down
+	c.mu.Unlock()
+
+	if down {
+		return nil
+	}
+	if _, err := c.client.Expire(ctx, key, expiration); err != nil && !c.unreachable(ctx, err) {
+		return err
+	}
+	return nil
+}
+
+func (c *FallbackCache) isDown() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.down
+}
+
+func (c *FallbackCache) queue(key string, write cacheWrite) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending[key] = write
+}
+
+// unreachable reports whether err means Redis cannot be reached, and if so switches to the
+// local copy until a ping succeeds. Errors of a cancelled request do not count.
+func (c *FallbackCache) unreachable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.down {
+		c.down = true
+		c.logger.Error("Redis unavailable, using the in-memory cache", zap.Error(err))
+		go c.recover()
+	}
+	return true
+}
+
+// recover pings Redis until it answers, then writes the queued changes
+func (c *FallbackCache) recover() {
+	ticker := time.NewTicker(cacheRecoveryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(c.stop, cacheRecoveryInterval)
+		err := c.client.Client.Ping(ctx).Err()
+		if err == nil {
+			err = c.replay(ctx)
+		}
+		cancel()
+		if err == nil {
+			return
+		}
+	}
+}
+
+// replay writes the queued changes to Redis and switches back to it once none are left
+func (c *FallbackCache) replay(ctx context.Context) error {
+	written := 0
+	for {
+		c.mu.Lock()
+		if len(c.pending) == 0 {
+			c.down = false
+			c.mu.Unlock()
+			c.logger.Info("Redis connection restored", zap.Int("queued_writes", written))
+			return nil
+		}
+		pending := c.pending
+		c.pending = make(map[string]cacheWrite)
+		c.mu.Unlock()
+
+		for key, write := range pending {
+			if err := c.apply(ctx, key, write); err != nil {
+				// Put back what is left, unless the key was written again meanwhile
+				c.mu.Lock()
+				for key, write := range pending {
+					if _, ok := c.pending[key]; !ok {
+						c.pending[key] = write
+					}
+				}
+				c.mu.Unlock()
+				return err
+			}
+			c.publish(ctx, key)
+			delete(pending, key)
+			written++
+		}
+	}
+}
+
+func (c *FallbackCache) apply(ctx context.Context, key string, write cacheWrite) error {
+	if write.del {
+		return c.client.Del(ctx, key)
+	}
+
+	var expiration time.Duration
+	if !write.expiresAt.IsZero() {
+		expiration = time.Until(write.expiresAt)
+		if expiration <= 0 {
+			return nil // Expired while Redis was down
+		}
+	}
+	return c.client.Set(ctx, key, write.value, expiration)
+}
+
+// expiresAt converts a PTTL result to an expiry time (zero for keys without one)
+func expiresAt(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+// cacheString returns the value as Redis stores it
+func cacheString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// lruCache is a size-bounded map evicting the least recently used key. It is not safe for
+// concurrent use; FallbackCache guards it with its mutex.
+type lruCache struct {
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key       string
+	value     string
+	expiresAt time.Time
+}
+
+func newLRUCache(size int) *lruCache {
+	return &lruCache{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (l *lruCache) get(key string) (string, bool) {
+	elem, ok := l.items[key]
+	if !ok {
+		return "", false
+	}
+	entry := elem.Value.(*lruEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		l.order.Remove(elem)
+		delete(l.items, key)
+		return "", false
+	}
+	l.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+func (l *lruCache) set(key, value string, expiresAt time.Time) {
+	if elem, ok := l.items[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		entry.value, entry.expiresAt = value, expiresAt
+		l.order.MoveToFront(elem)
+		return
+	}
+
+	l.items[key] = l.order.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+	if l.order.Len() > l.size {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+func (l *lruCache) expire(key string, expiresAt time.Time) {
+	if elem, ok := l.items[key]; ok {
+		elem.Value.(*lruEntry).expiresAt = expiresAt
+	}
+}
+
+func (l *lruCache) del(key string) {
+	if elem, ok := l.items[key]; ok {
+		l.order.Remove(elem)
+		delete(l.items, key)
+	}
+}
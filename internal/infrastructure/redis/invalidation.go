@@ -0,0 +1,76 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+
+	"go.uber.org/zap"
+)
+
+// invalidationChannel is the pub/sub channel, under the key prefix, on which instances announce
+// the keys they wrote or deleted, so the others drop their local copies
+const invalidationChannel = "cache:invalidate"
+
+// invalidation is a message of the invalidation channel
+type invalidation struct {
+	Instance string   `json:"instance"`
+	Keys     []string `json:"keys"`
+}
+
+// newInstanceID returns a random ID that tells the messages of this process apart
+func newInstanceID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// publish announces that keys changed. It is best effort: an instance that misses the message
+// keeps its copy until it reads the key from Redis again.
+func (c *FallbackCache) publish(ctx context.Context, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	payload, err := json.Marshal(invalidation{Instance: c.instance, Keys: keys})
+	if err != nil {
+		return
+	}
+	if err := c.client.Client.Publish(ctx, c.client.Key(invalidationChannel), payload).Err(); err != nil {
+		c.logger.Warn("Failed to publish cache invalidation", zap.Strings("keys", keys), zap.Error(err))
+	}
+}
+
+// subscribe drops the local copies of the keys other instances change until the cache stops.
+// The subscription reconnects on its own after Redis outages.
+func (c *FallbackCache) subscribe() {
+	pubsub := c.client.Client.Subscribe(c.stop, c.client.Key(invalidationChannel))
+	defer pubsub.Close()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-c.stop.Done():
+			return
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+
+			var inv invalidation
+			if err := json.Unmarshal([]byte(message.Payload), &inv); err != nil {
+				c.logger.Warn("Ignoring malformed cache invalidation", zap.Error(err))
+				continue
+			}
+			if inv.Instance == c.instance {
+				continue
+			}
+
+			c.mu.Lock()
+			for _, key := range inv.Keys {
+				c.local.del(key)
+			}
+			c.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+)
+
+// ErrLeaseHeld is returned when another instance holds a lease
+var ErrLeaseHeld = errors.New("held by another instance")
+
+// ErrLeaseUnavailable is returned when Redis cannot be reached to take a lease; the work is
+// tried again later
+var ErrLeaseUnavailable = errors.New("lease unavailable while Redis is unreachable")
+
+// leaseKeyPrefix is the key prefix of leases, under the key prefix
+const leaseKeyPrefix = "lease:"
+
+// leaseRetryInterval is how often a held lease is tried again while waiting for it
+const leaseRetryInterval = 200 * time.Millisecond
+
+// Scripts that only touch a lease still holding the caller's token
+var (
+	releaseScript = redis.NewScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end return 0`)
+	renewScript   = redis.NewScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("PEXPIRE", KEYS[1], ARGV[2]) end return 0`)
+)
+
+// Leases are locks in Redis that let instances running side by side (app.instance_id) take turns
+// on shared work: folder moves and stamping of a document, and periodic jobs. While Redis is
+// unreachable leases are refused, unless app.single_instance says no other instance can take them.
+type Leases struct {
+	client         *RedisClient
+	instance       string
+	singleInstance bool
+	logger         *zap.Logger
+}
+
+func NewLeases(cfg *config.Config, client *RedisClient, logger *zap.Logger) *Leases {
+	return &Leases{
+		client:         client,
+		instance:       cfg.App.InstanceID,
+		singleInstance: cfg.App.SingleInstance,
+		logger:         logger.Named("lease"),
+	}
+}
+
+// Lease is a held lease, renewed until it is released
+type Lease struct {
+	leases *Leases
+	key    string // Empty when granted without Redis
+	token  string
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// Acquire takes the lease name for ttl, trying again until wait has passed while another
+// instance holds it, and then returns ErrLeaseHeld. A held lease is renewed every third of ttl
+// until Release, so it only expires when the instance holding it stops. While Redis is
+// unreachable it returns ErrLeaseUnavailable, or a lease held without Redis on a single instance.
+func (l *Leases) Acquire(ctx context.Context, name string, ttl, wait time.Duration) (*Lease, error) {
+	key := leaseKeyPrefix + name
+	token := l.instance + ":" + newInstanceID()
+	deadline := time.Now().Add(wait)
+
+	for {
+		ok, err := l.client.SetNX(ctx, key, token, ttl)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if l.singleInstance {
+				l.logger.Warn("Redis unavailable, continuing without lease", zap.String("lease", name), zap.Error(err))
+				return &Lease{leases: l}, nil
+			}
+			l.logger.Warn("Redis unavailable, lease refused", zap.String("lease", name), zap.Error(err))
+			return nil, fmt.Errorf("%w: %v", ErrLeaseUnavailable, err)
+		}
+		if ok {
+			lease := &Lease{leases: l, key: key, token: token, stop: make(chan struct{}), done: make(chan struct{})}
+			go lease.renew(ttl)
+			return lease, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, ErrLeaseHeld
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(leaseRetryInterval):
+		}
+	}
+}
+
+// Do runs fn holding the lease name, see Acquire
+func (l *Leases) Do(ctx context.Context, name string, ttl, wait time.Duration, fn func(ctx context.Context) error) error {
+	lease, err := l.Acquire(ctx, name, ttl, wait)
+	if err != nil {
+		return err
+	}
+	defer lease.Release(context.WithoutCancel(ctx))
+	return fn(ctx)
+}
+
+// Holder returns the instance holding the lease name, or "" when it is free
+func (l *Leases) Holder(ctx context.Context, name string) (string, error) {
+	token, err := l.client.Get(ctx, leaseKeyPrefix+name)
+	if err == Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if i := strings.LastIndex(token, ":"); i >= 0 {
+		token = token[:i]
+	}
+	return token, nil
+}
+
+// Claim claims a run of the periodic job name and reports whether this instance should run it.
+// It returns false when another instance ran the job less than period ago; every instance keeps
+// its own schedule and the first to reach a run claims it. While Redis is unreachable the run is
+// skipped, unless this is a single instance.
+func (l *Leases) Claim(ctx context.Context, name string, period time.Duration) bool {
+	// A little under the period, so the instance that ran the job can claim its next run
+	ok, err := l.client.SetNX(ctx, leaseKeyPrefix+"job:"+name, l.instance, period*9/10)
+	if err != nil {
+		if l.singleInstance {
+			l.logger.Warn("Redis unavailable, running job without claim", zap.String("job", name), zap.Error(err))
+			return true
+		}
+		l.logger.Warn("Redis unavailable, skipping job run", zap.String("job", name), zap.Error(err))
+		return false
+	}
+	if !ok {
+		l.logger.Debug("Job run claimed by another instance", zap.String("job", name))
+	}
+	return ok
+}
+
+// Release gives the lease up, unless it expired and another instance took it meanwhile
+func (l *Lease) Release(ctx context.Context) {
+	if l.key == "" {
+		return
+	}
+	close(l.stop)
+	<-l.done
+
+	client := l.leases.client
+	if err := releaseScript.Run(ctx, client.Client, []string{client.Key(l.key)}, l.token).Err(); err != nil {
+		l.leases.logger.Warn("Failed to release lease", zap.String("lease", l.key), zap.Error(err))
+	}
+}
+
+// renew extends the lease every third of ttl until it is released
+func (l *Lease) renew(ttl time.Duration) {
+	defer close(l.done)
+
+	client := l.leases.client
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+
+		renewed, err := renewScript.Run(context.Background(), client.Client, []string{client.Key(l.key)}, l.token, ttl.Milliseconds()).Int()
+		if err != nil {
+			l.leases.logger.Warn("Failed to renew lease", zap.String("lease", l.key), zap.Error(err))
+			continue
+		}
+		if renewed == 0 {
+			l.leases.logger.Warn("Lease expired and was taken by another instance", zap.String("lease", l.key))
+			return
+		}
+	}
+}
@@ -4,4 +4,6 @@ import "go.uber.org/fx"
 
 var Module = fx.Module("redis",
 	fx.Provide(NewRedisClient),
+	fx.Provide(NewCache),
+	fx.Provide(NewLeases),
 )
@@ -2,68 +2,230 @@ package redis
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 
 	"mekari-esign/internal/config"
+	"mekari-esign/internal/infrastructure/startup"
 )
 
+// RedisClient prepends redis.key_prefix to every key it is given, so callers use unprefixed keys
 type RedisClient struct {
 	Client *redis.Client
+	prefix string
 	logger *zap.Logger
 }
 
 func NewRedisClient(cfg *config.Config, logger *zap.Logger) (*RedisClient, error) {
-	addr := fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port)
-
-	client := redis.NewClient(&redis.Options{
-		Addr:     addr,
-		Password: cfg.Redis.Password,
-		DB:       cfg.Redis.DB,
-	})
+	options, err := Options(cfg)
+	if err != nil {
+		return nil, err
+	}
+	addr := options.Addr
 
-	// Test connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	client := redis.NewClient(options)
 
-	if err := client.Ping(ctx).Err(); err != nil {
+	// Test connection, waiting for a Redis that is still starting
+	err = startup.Wait(cfg, logger, "redis", func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	})
+	if err != nil {
 		return nil, fmt.Errorf("failed to connect to redis: %w", err)
 	}
 
 	logger.Info("Redis connected successfully",
 		zap.String("addr", addr),
 		zap.Int("db", cfg.Redis.DB),
+		zap.Bool("tls", cfg.Redis.TLS.Enabled),
+		zap.String("key_prefix", cfg.Redis.KeyPrefix),
 	)
 
 	return &RedisClient{
 		Client: client,
+		prefix: cfg.Redis.KeyPrefix,
 		logger: logger,
 	}, nil
 }
 
+// Options returns the client options of the redis settings, including TLS
+func Options(cfg *config.Config) (*redis.Options, error) {
+	options := &redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port),
+		Username: cfg.Redis.Username,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	}
+	if !cfg.Redis.TLS.Enabled {
+		return options, nil
+	}
+
+	tlsCfg := cfg.Redis.TLS
+	options.TLSConfig = &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         tlsCfg.ServerName,
+		InsecureSkipVerify: tlsCfg.InsecureSkipVerify,
+	}
+	if options.TLSConfig.ServerName == "" {
+		options.TLSConfig.ServerName = cfg.Redis.Host
+	}
+
+	if tlsCfg.CAFile != "" {
+		pem, err := os.ReadFile(tlsCfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read redis.tls.ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("redis.tls.ca_file %s contains no PEM certificates", tlsCfg.CAFile)
+		}
+		options.TLSConfig.RootCAs = pool
+	}
+
+	if tlsCfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(tlsCfg.CertFile, tlsCfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load redis.tls client certificate: %w", err)
+		}
+		options.TLSConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return options, nil
+}
+
+// Nil is the error returned when a key does not exist
+const Nil = redis.Nil
+
+// Key returns the key as stored in Redis, with the key prefix
+func (r *RedisClient) Key(key string) string {
+	return r.prefix + key
+}
+
 func (r *RedisClient) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
-	return r.Client.Set(ctx, key, value, expiration).Err()
+	return r.Client.Set(ctx, r.Key(key), value, expiration).Err()
 }
 
 func (r *RedisClient) Get(ctx context.Context, key string) (string, error) {
-	return r.Client.Get(ctx, key).Result()
+	return r.Client.Get(ctx, r.Key(key)).Result()
 }
 
 func (r *RedisClient) Del(ctx context.Context, keys ...string) error {
-	return r.Client.Del(ctx, keys...).Err()
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = r.Key(key)
+	}
+	return r.Client.Del(ctx, prefixed...).Err()
 }
 
 func (r *RedisClient) Exists(ctx context.Context, key string) (bool, error) {
-	result, err := r.Client.Exists(ctx, key).Result()
+	result, err := r.Client.Exists(ctx, r.Key(key)).Result()
 	if err != nil {
 		return false, err
 	}
 	return result > 0, nil
 }
 
+// SetNX sets a key only if it does not exist yet and reports whether it was set
+func (r *RedisClient) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	return r.Client.SetNX(ctx, r.Key(key), value, expiration).Result()
+}
+
+// Scan returns one page of keys matching the pattern and the cursor of the next page (0 when done)
+func (r *RedisClient) Scan(ctx context.Context, cursor uint64, match string, count int64) ([]string, uint64, error) {
+	keys, next, err := r.Client.Scan(ctx, cursor, globEscaper.Replace(r.prefix)+match, count).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, r.prefix)
+	}
+	return keys, next, nil
+}
+
+// globEscaper escapes the pattern characters of SCAN MATCH, so a prefix only matches itself
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// TTL returns the remaining time to live of a key; it is negative for a key without expiry or a missing key
+func (r *RedisClient) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return r.Client.TTL(ctx, r.Key(key)).Result()
+}
+
+// MGet returns the values of the keys in one round trip; missing keys and keys that do not hold
+// a string are left out of the result
+func (r *RedisClient) MGet(ctx context.Context, keys ...string) (map[string]string, error) {
+	values := make(map[string]string, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = r.Key(key)
+	}
+	result, err := r.Client.MGet(ctx, prefixed...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, value := range result {
+		if str, ok := value.(string); ok {
+			values[keys[i]] = str
+		}
+	}
+	return values, nil
+}
+
+// TTLs returns the time to live of each key, in the order of keys, with one pipelined round trip.
+// Like TTL, it is negative for a key without expiry or a missing key.
+func (r *RedisClient) TTLs(ctx context.Context, keys ...string) ([]time.Duration, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	pipe := r.Client.Pipeline()
+	cmds := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.TTL(ctx, r.Key(key))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	ttls := make([]time.Duration, len(keys))
+	for i, cmd := range cmds {
+		ttls[i] = cmd.Val()
+	}
+	return ttls, nil
+}
+
+// Expire sets the time to live of an existing key and reports whether the key exists
+func (r *RedisClient) Expire(ctx context.Context, key string, expiration time.Duration) (bool, error) {
+	return r.Client.Expire(ctx, r.Key(key), expiration).Result()
+}
+
+// incrWithExpireScript increments a counter and sets its expiry on first increment (atomic, works on Redis < 7)
+var incrWithExpireScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return {count, redis.call("PTTL", KEYS[1])}
+`)
+
+// IncrWithExpire increments a fixed-window counter and returns the new count and the time until it resets
+func (r *RedisClient) IncrWithExpire(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	result, err := incrWithExpireScript.Run(ctx, r.Client, []string{r.Key(key)}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, 0, err
+	}
+	return result[0], time.Duration(result[1]) * time.Millisecond, nil
+}
+
 func (r *RedisClient) Close() error {
 	return r.Client.Close()
 }
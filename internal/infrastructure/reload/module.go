@@ -0,0 +1,7 @@
+package reload
+
+import "go.uber.org/fx"
+
+var Module = fx.Module("reload",
+	fx.Provide(NewReloader),
+)
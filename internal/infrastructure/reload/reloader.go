@@ -0,0 +1,208 @@
+package reload
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/infrastructure/document"
+	"mekari-esign/internal/infrastructure/logger"
+)
+
+// debounce collapses the burst of file events editors emit for a single save
+const debounce = time.Second
+
+// ActorFileWatcher is the audit actor of reloads triggered by a config file change
+const ActorFileWatcher = "file-watcher"
+
+// Reloader applies config file changes to the running service. Only reloadable settings
+// (see config.IsReloadable) are applied; other changes are logged as requiring a restart.
+type Reloader struct {
+	config     *config.Config
+	levels     *logger.Levels
+	docService document.DocumentService
+	logger     *zap.Logger
+	audit      *zap.Logger
+
+	mu             sync.Mutex
+	timer          *time.Timer
+	stopped        bool
+	overlayWatcher *fsnotify.Watcher
+}
+
+func NewReloader(
+	lc fx.Lifecycle,
+	cfg *config.Config,
+	levels *logger.Levels,
+	docService document.DocumentService,
+	logger *zap.Logger,
+) *Reloader {
+	r := &Reloader{
+		config:     cfg,
+		levels:     levels,
+		docService: docService,
+		logger:     logger,
+		audit:      logger.Named("config-audit"),
+	}
+
+	if !cfg.App.WatchConfig {
+		return r
+	}
+	if config.FileUsed() == "" {
+		logger.Info("No config file, configuration is read from environment variables only")
+		return r
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			viper.OnConfigChange(r.onFileChange)
+			viper.WatchConfig()
+			logger.Info("Watching config file for changes", zap.String("file", viper.ConfigFileUsed()))
+
+			if overlay := config.OverlayUsed(); overlay != "" {
+				if err := r.watchOverlay(overlay); err != nil {
+					logger.Warn("Cannot watch config overlay, changes to it need a reload or restart",
+						zap.String("file", overlay),
+						zap.Error(err),
+					)
+				} else {
+					logger.Info("Watching config overlay for changes", zap.String("file", overlay))
+				}
+			}
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			// viper cannot stop watching, so later events are ignored instead
+			r.mu.Lock()
+			r.stopped = true
+			if r.timer != nil {
+				r.timer.Stop()
+			}
+			r.mu.Unlock()
+			if r.overlayWatcher != nil {
+				r.overlayWatcher.Close()
+			}
+			return nil
+		},
+	})
+
+	return r
+}
+
+func (r *Reloader) onFileChange(e fsnotify.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.stopped {
+		return
+	}
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	r.timer = time.AfterFunc(debounce, func() {
+		r.logger.Info("Config file changed", zap.String("file", e.Name))
+		_, _ = r.Reload(ActorFileWatcher)
+	})
+}
+
+// watchOverlay reloads when the environment overlay changes; viper only watches the base file
+func (r *Reloader) watchOverlay(path string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	// Watch the directory, as editors often replace the file rather than write to it
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return err
+	}
+	r.overlayWatcher = watcher
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) == filepath.Clean(path) && event.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+					r.onFileChange(event)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				r.logger.Warn("Config overlay watcher error", zap.Error(err))
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Reload re-reads the config file and applies the reloadable settings. actor is recorded in
+// the audit log. An invalid file is rejected and the running configuration is kept.
+func (r *Reloader) Reload(actor string) ([]config.Change, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := config.Reload()
+	if err != nil {
+		r.logger.Error("Config reload failed, keeping the running configuration",
+			zap.String("actor", actor),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	changes := r.config.Apply(next)
+
+	var documentChanged, levelChanged bool
+	var restartRequired []string
+	for _, change := range changes {
+		r.audit.Info("Config setting changed",
+			zap.String("key", change.Key),
+			zap.String("old", change.Old),
+			zap.String("new", change.New),
+			zap.Bool("applied", change.Applied),
+			zap.String("actor", actor),
+		)
+
+		if !change.Applied {
+			restartRequired = append(restartRequired, change.Key)
+			continue
+		}
+		switch {
+		case change.Key == "logging.level" || change.Key == "logging.levels":
+			levelChanged = true
+		case strings.HasPrefix(change.Key, "document."):
+			documentChanged = true
+		}
+	}
+
+	if levelChanged {
+		logging := r.config.LoggingSettings()
+		r.levels.Set(logging.Level, logging.Levels)
+	}
+	if documentChanged {
+		if err := r.docService.EnsureDirectories(); err != nil {
+			r.logger.Warn("Failed to create reloaded document folders", zap.Error(err))
+		}
+	}
+
+	r.audit.Info("Configuration reloaded",
+		zap.String("actor", actor),
+		zap.Int("changed", len(changes)),
+		zap.Strings("restart_required", restartRequired),
+	)
+
+	return changes, nil
+}
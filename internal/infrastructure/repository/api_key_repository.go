@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/database"
+	"mekari-esign/internal/timeutil"
+)
+
+// APIKeyRepository interface for API key operations
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *entity.APIKey) error
+	FindByHash(ctx context.Context, keyHash string) (*entity.APIKey, error)
+	FindAll(ctx context.Context) ([]entity.APIKey, error)
+	Revoke(ctx context.Context, id int64) error
+	TouchLastUsed(ctx context.Context, id int64, usedAt time.Time) error
+}
+
+type apiKeyRepository struct {
+	db     *database.Database
+	logger *zap.Logger
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db *database.Database, logger *zap.Logger) APIKeyRepository {
+	return &apiKeyRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+const apiKeyColumns = `id, name, key_prefix, key_hash, rate_limit, last_used_at, revoked_at, created_at`
+
+func scanAPIKey(scanner interface{ Scan(...interface{}) error }) (*entity.APIKey, error) {
+	var key entity.APIKey
+	var lastUsedAt, revokedAt sql.NullTime
+
+	if err := scanner.Scan(&key.ID, &key.Name, &key.KeyPrefix, &key.KeyHash, &key.RateLimit, &lastUsedAt, &revokedAt, &key.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+
+	return &key, nil
+}
+
+// Create inserts a new API key
+func (r *apiKeyRepository) Create(ctx context.Context, key *entity.APIKey) error {
+	query := `
+		INSERT INTO api_keys (name, key_prefix, key_hash, rate_limit, created_at)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	id, err := r.db.Insert(ctx, query, key.Name, key.KeyPrefix, key.KeyHash, key.RateLimit, key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+	key.ID = id
+
+	return nil
+}
+
+// FindByHash finds an API key by its hash, returns nil if not found
+func (r *apiKeyRepository) FindByHash(ctx context.Context, keyHash string) (*entity.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1`
+
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, query, keyHash))
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find API key: %w", err)
+	}
+
+	return key, nil
+}
+
+// FindAll lists all API keys, newest first
+func (r *apiKeyRepository) FindAll(ctx context.Context) ([]entity.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []entity.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+
+	return keys, nil
+}
+
+// Revoke marks an API key as revoked
+func (r *apiKeyRepository) Revoke(ctx context.Context, id int64) error {
+	query := `UPDATE api_keys SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, timeutil.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return fmt.Errorf("API key %d not found or already revoked", id)
+	}
+
+	return nil
+}
+
+// TouchLastUsed updates the last used timestamp of an API key
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id int64, usedAt time.Time) error {
+	query := `UPDATE api_keys SET last_used_at = $1 WHERE id = $2`
+
+	if _, err := r.db.ExecContext(ctx, query, usedAt, id); err != nil {
+		return fmt.Errorf("failed to update API key last used: %w", err)
+	}
+
+	return nil
+}
@@ -2,54 +2,137 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"go.uber.org/fx"
 	"go.uber.org/zap"
 
+	"mekari-esign/internal/config"
 	"mekari-esign/internal/domain/entity"
 	"mekari-esign/internal/infrastructure/database"
+	"mekari-esign/internal/infrastructure/shutdown"
 )
 
 // APILogRepository interface for API log operations
 type APILogRepository interface {
 	Save(ctx context.Context, log *entity.APILog) error
 	FindByInvoice(ctx context.Context, invoiceNumber string) ([]entity.APILog, error)
-	FindAll(ctx context.Context, limit int) ([]entity.APILog, error)
+	// FindByDocument lists the logs of a document, oldest first. Rows saved before the
+	// document ID was known are matched by invoice number.
+	FindByDocument(ctx context.Context, documentID, invoiceNo string, limit int) ([]entity.APILog, error)
+	FindAll(ctx context.Context, filter *entity.APILogFilter) (*entity.APILogPage, error)
+	// LastSuccess returns when the latest 2xx call of a source was logged, or nil when there is none
+	LastSuccess(ctx context.Context, source string) (*time.Time, error)
+	Stream(ctx context.Context, filter *entity.APILogFilter, fn func(log *entity.APILog) error) error
+	// PurgeBatch deletes up to limit logs created before the cutoff. When archive is set it is
+	// called with the rows first and nothing is deleted if it fails. Returns the number deleted.
+	PurgeBatch(ctx context.Context, before time.Time, limit int, archive func(logs []entity.APILog) error) (int, error)
 }
 
 type apiLogRepository struct {
 	db     *database.Database
 	logger *zap.Logger
+
+	// queue feeds the batch writer. Once it is closed on stop, Save writes directly.
+	queueMu    sync.RWMutex
+	queue      chan entity.APILog
+	closed     bool
+	done       chan struct{}
+	batchSize  int
+	flushEvery time.Duration
+	rejected   int
+
+	// pending holds logs saved while the database was down, written when it is back
+	mu         sync.Mutex
+	pending    []entity.APILog
+	maxPending int
+	dropped    int
 }
 
-// NewAPILogRepository creates a new API log repository
-func NewAPILogRepository(db *database.Database, logger *zap.Logger) APILogRepository {
-	return &apiLogRepository{
-		db:     db,
-		logger: logger,
+const (
+	// apiLogFlushTimeout bounds writing one batch, or one buffered log after a reconnect
+	apiLogFlushTimeout = 5 * time.Second
+	// apiLogColumns is the number of values inserted per log
+	apiLogColumns = 14
+	// apiLogMaxRows keeps an INSERT within the 2100 parameters SQL Server accepts
+	apiLogMaxRows = 2100 / apiLogColumns
+)
+
+// NewAPILogRepository creates a new API log repository. It takes the coordinator so its stop
+// hook, which writes the queued logs, runs before the coordinator closes the database.
+func NewAPILogRepository(lc fx.Lifecycle, cfg *config.Config, db *database.Database, coordinator *shutdown.Coordinator, logger *zap.Logger) APILogRepository {
+	r := &apiLogRepository{
+		db:         db,
+		logger:     logger,
+		queue:      make(chan entity.APILog, cfg.Database.LogQueueSize),
+		done:       make(chan struct{}),
+		batchSize:  cfg.Database.LogBatchSize,
+		flushEvery: time.Duration(cfg.Database.LogFlushMs) * time.Millisecond,
+		maxPending: cfg.Database.LogBuffer,
 	}
+	db.OnReconnect(r.flush)
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			go r.run()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			r.stop(ctx)
+			return nil
+		},
+	})
+
+	return r
 }
 
-// Save saves an API log entry to the database
+// Save queues an API log entry for the batch writer without waiting for the database. When
+// the queue is full the entry is dropped; after shutdown it is written directly.
 func (r *apiLogRepository) Save(ctx context.Context, log *entity.APILog) error {
-	query := `
-		INSERT INTO api_logs (endpoint, invoice_no, entry_no, method, request_body, response_body, status_code, duration_ms, email, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-	`
+	r.queueMu.RLock()
+	if r.closed {
+		r.queueMu.RUnlock()
+		return r.save(ctx, log)
+	}
 
-	_, err := r.db.DB.ExecContext(ctx, query,
-		log.Endpoint,
-		log.InvoiceNo,
-		log.EntryNo,
-		log.Method,
-		log.RequestBody,
-		log.ResponseBody,
-		log.StatusCode,
-		log.Duration,
-		log.Email,
-		log.CreatedAt,
-	)
+	select {
+	case r.queue <- *log:
+		r.queueMu.RUnlock()
+		return nil
+	default:
+	}
+	r.queueMu.RUnlock()
 
+	r.mu.Lock()
+	r.rejected++
+	first := r.rejected == 1
+	r.mu.Unlock()
+	if first {
+		r.logger.Warn("API log queue full, dropping new logs", zap.Int("size", cap(r.queue)))
+	}
+	return fmt.Errorf("failed to save API log: queue full")
+}
+
+// save writes one log, keeping it in memory while the database is down
+func (r *apiLogRepository) save(ctx context.Context, log *entity.APILog) error {
+	if !r.db.Available() {
+		r.buffer(log)
+		return nil
+	}
+
+	err := r.insert(ctx, []entity.APILog{*log})
+	if err != nil && ctx.Err() == nil && r.db.Ping(ctx) != nil {
+		r.buffer(log)
+		return nil
+	}
 	if err != nil {
 		r.logger.Error("Failed to save API log",
 			zap.String("endpoint", log.Endpoint),
@@ -61,18 +144,216 @@ func (r *apiLogRepository) Save(ctx context.Context, log *entity.APILog) error {
 	return nil
 }
 
+// run writes the queued logs in batches of batchSize, or whatever has queued up after
+// flushEvery, until the queue is closed and empty
+func (r *apiLogRepository) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.flushEvery)
+	defer ticker.Stop()
+
+	batch := make([]entity.APILog, 0, r.batchSize)
+	for {
+		select {
+		case log, ok := <-r.queue:
+			if !ok {
+				r.write(batch)
+				return
+			}
+			batch = append(batch, log)
+			if len(batch) < r.batchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+
+		r.write(batch)
+		batch = batch[:0]
+		r.reportRejected()
+	}
+}
+
+// reportRejected logs how many logs were dropped because the queue was full since the last report
+func (r *apiLogRepository) reportRejected() {
+	r.mu.Lock()
+	rejected := r.rejected
+	r.rejected = 0
+	r.mu.Unlock()
+
+	if rejected > 0 {
+		r.logger.Warn("API logs dropped while the queue was full", zap.Int("dropped", rejected))
+	}
+}
+
+// stop closes the queue and waits until the writer has written what was left in it
+func (r *apiLogRepository) stop(ctx context.Context) {
+	r.queueMu.Lock()
+	r.closed = true
+	queued := len(r.queue)
+	close(r.queue)
+	r.queueMu.Unlock()
+
+	if queued > 0 {
+		r.logger.Info("Writing queued API logs", zap.Int("count", queued))
+	}
+
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		r.logger.Warn("Shutdown deadline reached, abandoning queued API logs")
+	}
+}
+
+// write inserts a batch in chunks of at most apiLogMaxRows. While the database is down the
+// rest of the batch is buffered; when a chunk fails otherwise its logs are retried one by one
+// so a single bad row does not lose the others.
+func (r *apiLogRepository) write(batch []entity.APILog) {
+	for start := 0; start < len(batch); start += apiLogMaxRows {
+		chunk := batch[start:min(start+apiLogMaxRows, len(batch))]
+
+		if !r.db.Available() {
+			r.bufferAll(batch[start:])
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), apiLogFlushTimeout)
+		err := r.insert(ctx, chunk)
+		cancel()
+		if err == nil {
+			continue
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), apiLogFlushTimeout)
+		down := r.db.Ping(ctx) != nil
+		cancel()
+		if down {
+			r.bufferAll(batch[start:])
+			return
+		}
+
+		r.logger.Warn("Failed to save API log batch, retrying one by one", zap.Int("count", len(chunk)), zap.Error(err))
+		for i := range chunk {
+			ctx, cancel := context.WithTimeout(context.Background(), apiLogFlushTimeout)
+			_ = r.save(ctx, &chunk[i])
+			cancel()
+		}
+	}
+}
+
+// insert writes logs with one multi-row INSERT
+func (r *apiLogRepository) insert(ctx context.Context, logs []entity.APILog) error {
+	rows := make([]string, len(logs))
+	args := make([]interface{}, 0, len(logs)*apiLogColumns)
+	for i := range logs {
+		log := &logs[i]
+		source := log.Source
+		if source == "" {
+			source = entity.APILogSourceMekari
+		}
+
+		rows[i] = "(" + database.Placeholders(i*apiLogColumns+1, apiLogColumns) + ")"
+		args = append(args,
+			log.Endpoint,
+			log.InvoiceNo,
+			log.EntryNo,
+			log.DocumentID,
+			source,
+			log.Method,
+			log.RequestBody,
+			log.ResponseBody,
+			log.StatusCode,
+			log.Duration,
+			log.Email,
+			log.Caller,
+			log.RequestID,
+			log.CreatedAt,
+		)
+	}
+
+	query := `
+		INSERT INTO api_logs (endpoint, invoice_no, entry_no, document_id, source, method, request_body, response_body, status_code, duration_ms, email, caller, request_id, created_at)
+		VALUES ` + strings.Join(rows, ", ")
+
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// bufferAll keeps the logs of a batch that could not be written while the database is down
+func (r *apiLogRepository) bufferAll(logs []entity.APILog) {
+	for i := range logs {
+		r.buffer(&logs[i])
+	}
+}
+
+// buffer keeps a copy of the log, dropping the oldest one when the buffer is full
+func (r *apiLogRepository) buffer(log *entity.APILog) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.pending) >= r.maxPending {
+		if r.dropped == 0 {
+			r.logger.Warn("API log buffer full, dropping the oldest logs", zap.Int("size", r.maxPending))
+		}
+		r.pending = r.pending[1:]
+		r.dropped++
+	}
+	r.pending = append(r.pending, *log)
+}
+
+// flush writes the buffered logs after a reconnect. When the database fails again the rest
+// stays buffered for the next reconnect.
+func (r *apiLogRepository) flush() {
+	r.mu.Lock()
+	logs, dropped := r.pending, r.dropped
+	r.pending, r.dropped = nil, 0
+	r.mu.Unlock()
+
+	if len(logs) == 0 {
+		return
+	}
+
+	written := 0
+	for i := range logs {
+		ctx, cancel := context.WithTimeout(context.Background(), apiLogFlushTimeout)
+		err := r.insert(ctx, logs[i:i+1])
+		cancel()
+		if err == nil {
+			written++
+			continue
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), apiLogFlushTimeout)
+		down := r.db.Ping(ctx) != nil
+		cancel()
+		if down {
+			r.mu.Lock()
+			r.pending = append(logs[i:], r.pending...)
+			r.mu.Unlock()
+			break
+		}
+		r.logger.Error("Failed to write buffered API log", zap.String("endpoint", logs[i].Endpoint), zap.Error(err))
+	}
+
+	r.logger.Info("Buffered API logs written",
+		zap.Int("written", written),
+		zap.Int("dropped", dropped),
+	)
+}
+
 // FindByInvoice finds API logs by invoice number (searches in endpoint or request_body)
 func (r *apiLogRepository) FindByInvoice(ctx context.Context, invoiceNumber string) ([]entity.APILog, error) {
 	query := `
-		SELECT id, endpoint, invoice_no, entry_no, method, request_body, response_body, status_code, duration_ms, email, created_at
+		SELECT id, endpoint, invoice_no, entry_no, document_id, source, method, request_body, response_body, status_code, duration_ms, email, caller, request_id, created_at
 		FROM api_logs
 		WHERE endpoint LIKE $1 OR request_body LIKE $1
 		ORDER BY created_at DESC
-		LIMIT 100
-	`
+	` + r.db.Dialect.Limit("100", "")
 
 	searchPattern := "%" + invoiceNumber + "%"
-	rows, err := r.db.DB.QueryContext(ctx, query, searchPattern)
+	rows, err := r.db.ReadQueryContext(ctx, query, searchPattern)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query API logs: %w", err)
 	}
@@ -81,7 +362,7 @@ func (r *apiLogRepository) FindByInvoice(ctx context.Context, invoiceNumber stri
 	var logs []entity.APILog
 	for rows.Next() {
 		var log entity.APILog
-		if err := rows.Scan(&log.ID, &log.Endpoint, &log.InvoiceNo, &log.EntryNo, &log.Method, &log.RequestBody, &log.ResponseBody, &log.StatusCode, &log.Duration, &log.Email, &log.CreatedAt); err != nil {
+		if err := rows.Scan(&log.ID, &log.Endpoint, &log.InvoiceNo, &log.EntryNo, &log.DocumentID, &log.Source, &log.Method, &log.RequestBody, &log.ResponseBody, &log.StatusCode, &log.Duration, &log.Email, &log.Caller, &log.RequestID, &log.CreatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan API log: %w", err)
 		}
 		logs = append(logs, log)
@@ -90,29 +371,326 @@ func (r *apiLogRepository) FindByInvoice(ctx context.Context, invoiceNumber stri
 	return logs, nil
 }
 
-// FindAll finds all API logs with limit
-func (r *apiLogRepository) FindAll(ctx context.Context, limit int) ([]entity.APILog, error) {
+func (r *apiLogRepository) LastSuccess(ctx context.Context, source string) (*time.Time, error) {
 	query := `
-		SELECT id, endpoint, invoice_no, entry_no, method, request_body, response_body, status_code, duration_ms, email, created_at
+		SELECT created_at
+		FROM api_logs
+		WHERE source = $1 AND status_code BETWEEN 200 AND 299
+		ORDER BY id DESC
+	` + r.db.Dialect.Limit("1", "")
+
+	var at time.Time
+	err := r.db.ReadQueryRowContext(ctx, query, source).Scan(&at)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find last successful API call: %w", err)
+	}
+
+	return &at, nil
+}
+
+// FindByDocument finds API logs by document ID, falling back to the invoice number for rows
+// without one
+func (r *apiLogRepository) FindByDocument(ctx context.Context, documentID, invoiceNo string, limit int) ([]entity.APILog, error) {
+	query := `
+		SELECT id, endpoint, invoice_no, entry_no, document_id, source, method, request_body, response_body, status_code, duration_ms, email, caller, request_id, created_at
+		FROM api_logs
+		WHERE document_id = $1 OR ($2 <> '' AND document_id = '' AND invoice_no = $2)
+		ORDER BY created_at, id
+	` + r.db.Dialect.Limit("$3", "")
+
+	rows, err := r.db.ReadQueryContext(ctx, query, documentID, invoiceNo, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API logs: %w", err)
+	}
+	defer rows.Close()
+
+	logs := []entity.APILog{}
+	for rows.Next() {
+		var log entity.APILog
+		if err := rows.Scan(&log.ID, &log.Endpoint, &log.InvoiceNo, &log.EntryNo, &log.DocumentID, &log.Source, &log.Method, &log.RequestBody, &log.ResponseBody, &log.StatusCode, &log.Duration, &log.Email, &log.Caller, &log.RequestID, &log.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan API log: %w", err)
+		}
+		logs = append(logs, log)
+	}
+
+	return logs, rows.Err()
+}
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// apiLogSortColumns whitelists sortable columns (values are interpolated into SQL)
+var apiLogSortColumns = map[string]bool{
+	entity.APILogSortCreatedAt: true,
+	entity.APILogSortStatus:    true,
+	entity.APILogSortDuration:  true,
+}
+
+// apiLogCursorTimeLayout keeps microsecond precision, matching the stored timestamps
+const apiLogCursorTimeLayout = "2006-01-02 15:04:05.999999"
+
+// apiLogCursor is the keyset position of the last row of a page
+type apiLogCursor struct {
+	Value string `json:"v"`
+	ID    int64  `json:"id"`
+}
+
+func encodeAPILogCursor(sort string, log *entity.APILog) string {
+	cursor := apiLogCursor{ID: log.ID}
+	switch sort {
+	case entity.APILogSortStatus:
+		cursor.Value = strconv.Itoa(log.StatusCode)
+	case entity.APILogSortDuration:
+		cursor.Value = strconv.FormatInt(log.Duration, 10)
+	default:
+		// Wall-clock time, compared as a timestamp without time zone
+		cursor.Value = log.CreatedAt.Format(apiLogCursorTimeLayout)
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeAPILogCursor returns the typed sort value and id of a cursor (a time.Time for created_at)
+func decodeAPILogCursor(sort, raw string) (interface{}, int64, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+
+	var cursor apiLogCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+
+	switch sort {
+	case entity.APILogSortStatus, entity.APILogSortDuration:
+		value, err := strconv.ParseInt(cursor.Value, 10, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w for sort %s", ErrInvalidCursor, sort)
+		}
+		return value, cursor.ID, nil
+	default:
+		value, err := time.Parse(apiLogCursorTimeLayout, cursor.Value)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w for sort %s", ErrInvalidCursor, sort)
+		}
+		return value, cursor.ID, nil
+	}
+}
+
+// apiLogConditions builds the WHERE conditions and arguments for the filter fields
+func apiLogConditions(filter *entity.APILogFilter) ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.ReplaceAll(condition, "$?", fmt.Sprintf("$%d", len(args))))
+	}
+
+	if filter.Method != "" {
+		addCondition("method = $?", strings.ToUpper(filter.Method))
+	}
+	if filter.StatusCode != 0 {
+		addCondition("status_code = $?", filter.StatusCode)
+	}
+	if filter.StatusClass != 0 {
+		addCondition("status_code BETWEEN $? * 100 AND $? * 100 + 99", filter.StatusClass)
+	}
+	if filter.Email != "" {
+		addCondition("LOWER(email) = LOWER($?)", filter.Email)
+	}
+	if filter.Caller != "" {
+		addCondition("LOWER(caller) = LOWER($?)", filter.Caller)
+	}
+	if filter.Invoice != "" {
+		addCondition("(invoice_no = $? OR endpoint LIKE CONCAT('%', $?, '%') OR request_body LIKE CONCAT('%', $?, '%'))", filter.Invoice)
+	}
+	if filter.RequestID != "" {
+		addCondition("request_id = $?", filter.RequestID)
+	}
+	if filter.DocumentID != "" {
+		addCondition("document_id = $?", filter.DocumentID)
+	}
+	if filter.Source != "" {
+		addCondition("source = $?", filter.Source)
+	}
+	if filter.From != nil {
+		addCondition("created_at >= $?", *filter.From)
+	}
+	if filter.To != nil {
+		addCondition("created_at < $?", *filter.To)
+	}
+
+	return conditions, args
+}
+
+// FindAll finds API logs matching the filter, one page at a time
+func (r *apiLogRepository) FindAll(ctx context.Context, filter *entity.APILogFilter) (*entity.APILogPage, error) {
+	sort := filter.Sort
+	if !apiLogSortColumns[sort] {
+		sort = entity.APILogSortCreatedAt
+	}
+	direction, comparison := "ASC", ">"
+	if filter.Desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	conditions, args := apiLogConditions(filter)
+
+	if filter.Cursor != "" {
+		value, id, err := decodeAPILogCursor(sort, filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value, id)
+		// Expanded row comparison: (sort, id) > (value, id) is not supported by SQL Server
+		conditions = append(conditions, fmt.Sprintf("(%[1]s %[2]s $%[3]d OR (%[1]s = $%[3]d AND id %[2]s $%[4]d))",
+			sort, comparison, len(args)-1, len(args)))
+	}
+
+	query := `
+		SELECT id, endpoint, invoice_no, entry_no, document_id, source, method, request_body, response_body, status_code, duration_ms, email, caller, request_id, created_at
 		FROM api_logs
-		ORDER BY created_at DESC
-		LIMIT $1
 	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// Fetch one extra row to know whether another page exists
+	args = append(args, filter.Limit+1)
+	limit, offset := fmt.Sprintf("$%d", len(args)), ""
+	if filter.Cursor == "" && filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		offset = fmt.Sprintf("$%d", len(args))
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s ", sort, direction, direction) + r.db.Dialect.Limit(limit, offset)
 
-	rows, err := r.db.DB.QueryContext(ctx, query, limit)
+	rows, err := r.db.ReadQueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query API logs: %w", err)
 	}
 	defer rows.Close()
 
-	var logs []entity.APILog
+	logs := []entity.APILog{}
 	for rows.Next() {
 		var log entity.APILog
-		if err := rows.Scan(&log.ID, &log.Endpoint, &log.InvoiceNo, &log.EntryNo, &log.Method, &log.RequestBody, &log.ResponseBody, &log.StatusCode, &log.Duration, &log.Email, &log.CreatedAt); err != nil {
+		if err := rows.Scan(&log.ID, &log.Endpoint, &log.InvoiceNo, &log.EntryNo, &log.DocumentID, &log.Source, &log.Method, &log.RequestBody, &log.ResponseBody, &log.StatusCode, &log.Duration, &log.Email, &log.Caller, &log.RequestID, &log.CreatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan API log: %w", err)
 		}
 		logs = append(logs, log)
 	}
 
-	return logs, nil
+	page := &entity.APILogPage{Logs: logs}
+	if len(logs) > filter.Limit {
+		page.Logs = logs[:filter.Limit]
+		page.HasMore = true
+		page.NextCursor = encodeAPILogCursor(sort, &page.Logs[len(page.Logs)-1])
+	}
+
+	return page, nil
+}
+
+// Stream calls fn for every log matching the filter, oldest first, without loading all rows in memory
+func (r *apiLogRepository) Stream(ctx context.Context, filter *entity.APILogFilter, fn func(log *entity.APILog) error) error {
+	conditions, args := apiLogConditions(filter)
+
+	query := `
+		SELECT id, endpoint, invoice_no, entry_no, document_id, source, method, request_body, response_body, status_code, duration_ms, email, caller, request_id, created_at
+		FROM api_logs
+	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at ASC, id ASC"
+
+	// Exports read rows as fast as the client downloads them, so only the request bounds them
+	rows, err := r.db.ReadQueryContext(database.WithoutQueryTimeout(ctx), query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query API logs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var log entity.APILog
+		if err := rows.Scan(&log.ID, &log.Endpoint, &log.InvoiceNo, &log.EntryNo, &log.DocumentID, &log.Source, &log.Method, &log.RequestBody, &log.ResponseBody, &log.StatusCode, &log.Duration, &log.Email, &log.Caller, &log.RequestID, &log.CreatedAt); err != nil {
+			return fmt.Errorf("failed to scan API log: %w", err)
+		}
+		if err := fn(&log); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// purgeDeleteChunk is the maximum number of ids per DELETE of PurgeBatch
+const purgeDeleteChunk = 1000
+
+// PurgeBatch deletes one batch of old logs inside a transaction (locked rows are skipped
+// so concurrent instances never archive the same rows)
+func (r *apiLogRepository) PurgeBatch(ctx context.Context, before time.Time, limit int, archive func(logs []entity.APILog) error) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin purge transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	from, suffix := r.db.Dialect.SkipLocked("api_logs")
+	query := `
+		SELECT id, endpoint, invoice_no, entry_no, document_id, source, method, request_body, response_body, status_code, duration_ms, email, caller, request_id, created_at
+		FROM ` + from + `
+		WHERE created_at < $1
+		ORDER BY id
+		` + r.db.Dialect.Limit("$2", "") + `
+		` + suffix
+
+	rows, err := tx.QueryContext(ctx, query, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to select API logs to purge: %w", err)
+	}
+
+	var logs []entity.APILog
+	var ids []interface{}
+	for rows.Next() {
+		var log entity.APILog
+		if err := rows.Scan(&log.ID, &log.Endpoint, &log.InvoiceNo, &log.EntryNo, &log.DocumentID, &log.Source, &log.Method, &log.RequestBody, &log.ResponseBody, &log.StatusCode, &log.Duration, &log.Email, &log.Caller, &log.RequestID, &log.CreatedAt); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan API log: %w", err)
+		}
+		logs = append(logs, log)
+		ids = append(ids, log.ID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read API logs to purge: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	if archive != nil {
+		if err := archive(logs); err != nil {
+			return 0, fmt.Errorf("failed to archive API logs: %w", err)
+		}
+	}
+
+	// Chunked to stay below the parameter limit of SQL Server (2100)
+	for start := 0; start < len(ids); start += purgeDeleteChunk {
+		chunk := ids[start:min(start+purgeDeleteChunk, len(ids))]
+		query := `DELETE FROM api_logs WHERE id IN (` + database.Placeholders(1, len(chunk)) + `)`
+		if _, err := tx.ExecContext(ctx, query, chunk...); err != nil {
+			return 0, fmt.Errorf("failed to delete API logs: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit purge: %w", err)
+	}
+
+	return len(ids), nil
 }
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/database"
+	"mekari-esign/internal/timeutil"
+)
+
+// mappingPurgeBatch is the number of expired mappings archived and deleted per transaction
+const mappingPurgeBatch = 500
+
+// dbMappingStore keeps mappings in the document_mappings table. Expiry follows updated_at.
+type dbMappingStore struct {
+	config *config.Config
+	db     *database.Database
+	logger *zap.Logger
+}
+
+func newDBMappingStore(cfg *config.Config, db *database.Database, logger *zap.Logger) *dbMappingStore {
+	return &dbMappingStore{
+		config: cfg,
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (s *dbMappingStore) find(ctx context.Context, key string) (*entity.DocumentMapping, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, `SELECT data FROM document_mappings WHERE mapping_key = $1`, key).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMappingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document mapping: %w", err)
+	}
+	return decodeMapping(data), nil
+}
+
+func (s *dbMappingStore) save(ctx context.Context, key string, mapping *entity.DocumentMapping) error {
+	data, err := encodeMapping(mapping)
+	if err != nil {
+		return err
+	}
+
+	query := s.db.Dialect.Upsert("document_mappings", "mapping_key",
+		[]string{"mapping_key", "document_id", "invoice_no", "data", "updated_at"},
+		[]string{"document_id", "invoice_no", "data", "updated_at"},
+	)
+	if _, err := s.db.ExecContext(ctx, query, key, mapping.DocumentID, mapping.InvoiceNumber, data, timeutil.Now()); err != nil {
+		return fmt.Errorf("failed to save document mapping %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *dbMappingStore) delete(ctx context.Context, keys ...string) error {
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = key
+	}
+
+	query := `DELETE FROM document_mappings WHERE mapping_key IN (` + database.Placeholders(1, len(keys)) + `)`
+	if _, err := s.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to delete document mapping: %w", err)
+	}
+	return nil
+}
+
+func (s *dbMappingStore) touch(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE document_mappings SET updated_at = $1 WHERE mapping_key = $2`, timeutil.Now(), key)
+	if err != nil {
+		return fmt.Errorf("failed to touch document mapping %s: %w", key, err)
+	}
+	return nil
+}
+
+// List pages through the mappings by document ID in key order. The cursor is the row offset.
+func (s *dbMappingStore) List(ctx context.Context, cursor uint64, limit int, invoice string) (*entity.DocumentMappingPage, error) {
+	conditions := []string{"mapping_key LIKE $1"}
+	args := []interface{}{documentMappingPrefix + "%"}
+	if invoice != "" {
+		args = append(args, strings.ToLower(invoice))
+		conditions = append(conditions, fmt.Sprintf("LOWER(invoice_no) LIKE CONCAT('%%', $%d, '%%')", len(args)))
+	}
+
+	// One row more than the limit tells whether there is a next page
+	args = append(args, limit+1, cursor)
+	query := `
+		SELECT mapping_key, data
+		FROM document_mappings
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY mapping_key
+	` + s.db.Dialect.Limit(fmt.Sprintf("$%d", len(args)-1), fmt.Sprintf("$%d", len(args)))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query document mappings: %w", err)
+	}
+	defer rows.Close()
+
+	page := &entity.DocumentMappingPage{Mappings: []entity.DocumentMappingItem{}}
+	for rows.Next() {
+		var key, data string
+		if err := rows.Scan(&key, &data); err != nil {
+			return nil, fmt.Errorf("failed to scan document mapping: %w", err)
+		}
+		page.Mappings = append(page.Mappings, entity.DocumentMappingItem{
+			Key:             strings.TrimPrefix(key, documentMappingPrefix),
+			DocumentMapping: *decodeMapping(data),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read document mappings: %w", err)
+	}
+
+	if len(page.Mappings) > limit {
+		page.Mappings = page.Mappings[:limit]
+		page.NextCursor = strconv.FormatUint(cursor+uint64(limit), 10)
+	}
+
+	return page, nil
+}
+
+// PurgeExpired deletes the mappings not saved or touched for mapping.ttl_days in batches
+func (s *dbMappingStore) PurgeExpired(ctx context.Context, archive func(mapping *entity.ArchivedDocumentMapping) error) (int, error) {
+	cutoff := timeutil.Now().AddDate(0, 0, -s.config.Mapping.TTLDays)
+
+	total := 0
+	for {
+		deleted, err := s.purgeBatch(ctx, cutoff, archive)
+		total += deleted
+		if err != nil {
+			return total, err
+		}
+		if deleted < mappingPurgeBatch {
+			return total, nil
+		}
+	}
+}
+
+func (s *dbMappingStore) purgeBatch(ctx context.Context, cutoff time.Time, archive func(mapping *entity.ArchivedDocumentMapping) error) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin purge transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	from, suffix := s.db.Dialect.SkipLocked("document_mappings")
+	query := `
+		SELECT mapping_key, data
+		FROM ` + from + `
+		WHERE updated_at < $1
+		ORDER BY mapping_key
+		` + s.db.Dialect.Limit("$2", "") + `
+		` + suffix
+
+	rows, err := tx.QueryContext(ctx, query, cutoff, mappingPurgeBatch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to select document mappings to purge: %w", err)
+	}
+
+	now := timeutil.Now()
+	var archived []*entity.ArchivedDocumentMapping
+	var keys []interface{}
+	for rows.Next() {
+		var key, data string
+		if err := rows.Scan(&key, &data); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan document mapping: %w", err)
+		}
+		archived = append(archived, archivedMapping(key, data, now))
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read document mappings to purge: %w", err)
+	}
+
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	if archive != nil {
+		for _, mapping := range archived {
+			if err := archive(mapping); err != nil {
+				return 0, fmt.Errorf("failed to archive document mapping: %w", err)
+			}
+		}
+	}
+
+	query = `DELETE FROM document_mappings WHERE mapping_key IN (` + database.Placeholders(1, len(keys)) + `)`
+	if _, err := tx.ExecContext(ctx, query, keys...); err != nil {
+		return 0, fmt.Errorf("failed to delete document mappings: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit purge: %w", err)
+	}
+
+	return len(keys), nil
+}
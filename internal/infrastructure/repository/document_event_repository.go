@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/database"
+)
+
+// DocumentEventRepository interface for document timeline events
+type DocumentEventRepository interface {
+	// Save stores an event and sets its ID. Events with a dedupe key that was already recorded
+	// are ignored and keep a zero ID.
+	Save(ctx context.Context, event *entity.DocumentEvent) error
+	// FindByDocumentID returns all events of a document ordered by time
+	FindByDocumentID(ctx context.Context, documentID string) ([]entity.DocumentEvent, error)
+	// FindRecentByType returns the newest events of a type across all documents
+	FindRecentByType(ctx context.Context, eventType string, limit int) ([]entity.DocumentEvent, error)
+	// FindLatestByInvoice returns all events of the document last submitted for an invoice ordered
+	// by time, none when no document of the invoice was submitted
+	FindLatestByInvoice(ctx context.Context, invoiceNo string) ([]entity.DocumentEvent, error)
+}
+
+type documentEventRepository struct {
+	db     *database.Database
+	logger *zap.Logger
+}
+
+// NewDocumentEventRepository creates a new document event repository
+func NewDocumentEventRepository(db *database.Database, logger *zap.Logger) DocumentEventRepository {
+	return &documentEventRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Save inserts a document event
+func (r *documentEventRepository) Save(ctx context.Context, event *entity.DocumentEvent) error {
+	query := `
+		INSERT INTO document_events (document_id, invoice_no, entry_no, event_type, description, actor, dedupe_key, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	// Empty dedupe key is stored as NULL so it never conflicts
+	var dedupeKey sql.NullString
+	if event.DedupeKey != "" {
+		dedupeKey = sql.NullString{String: event.DedupeKey, Valid: true}
+	}
+
+	id, inserted, err := r.db.InsertIgnore(ctx, query, "dedupe_key",
+		event.DocumentID,
+		event.InvoiceNo,
+		event.EntryNo,
+		event.EventType,
+		event.Description,
+		event.Actor,
+		dedupeKey,
+		event.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save document event: %w", err)
+	}
+	if !inserted {
+		return nil // Duplicate milestone
+	}
+
+	event.ID = id
+
+	return nil
+}
+
+// FindByDocumentID finds all events for a document
+func (r *documentEventRepository) FindByDocumentID(ctx context.Context, documentID string) ([]entity.DocumentEvent, error) {
+	query := `
+		SELECT id, document_id, invoice_no, entry_no, event_type, description, actor, created_at
+		FROM document_events
+		WHERE document_id = $1
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query document events: %w", err)
+	}
+	return scanDocumentEvents(rows)
+}
+
+// FindRecentByType finds the newest events of a type, newest first
+func (r *documentEventRepository) FindRecentByType(ctx context.Context, eventType string, limit int) ([]entity.DocumentEvent, error) {
+	query := `
+		SELECT id, document_id, invoice_no, entry_no, event_type, description, actor, created_at
+		FROM document_events
+		WHERE event_type = $1
+		ORDER BY created_at DESC, id DESC
+	` + r.db.Dialect.Limit("$2", "")
+
+	rows, err := r.db.QueryContext(ctx, query, eventType, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query document events: %w", err)
+	}
+	return scanDocumentEvents(rows)
+}
+
+// FindLatestByInvoice finds the events of the newest document submitted for an invoice. A
+// resubmitted invoice has a newer submitted document, so late callbacks of the old one are left out.
+func (r *documentEventRepository) FindLatestByInvoice(ctx context.Context, invoiceNo string) ([]entity.DocumentEvent, error) {
+	query := `
+		SELECT document_id
+		FROM document_events
+		WHERE invoice_no = $1 AND event_type = $2
+		ORDER BY created_at DESC, id DESC
+	` + r.db.Dialect.Limit("1", "")
+
+	var documentID string
+	err := r.db.QueryRowContext(ctx, query, invoiceNo, entity.DocumentEventSubmitted).Scan(&documentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return []entity.DocumentEvent{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query latest document of invoice: %w", err)
+	}
+
+	return r.FindByDocumentID(ctx, documentID)
+}
+
+func scanDocumentEvents(rows *database.Rows) ([]entity.DocumentEvent, error) {
+	defer rows.Close()
+
+	events := []entity.DocumentEvent{}
+	for rows.Next() {
+		var event entity.DocumentEvent
+		if err := rows.Scan(&event.ID, &event.DocumentID, &event.InvoiceNo, &event.EntryNo, &event.EventType, &event.Description, &event.Actor, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan document event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
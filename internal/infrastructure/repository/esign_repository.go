@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"path/filepath"
 	"strconv"
 
 	"go.uber.org/zap"
@@ -13,28 +15,29 @@ import (
 	"mekari-esign/internal/domain/repository"
 	"mekari-esign/internal/infrastructure/document"
 	"mekari-esign/internal/infrastructure/httpclient"
+	"mekari-esign/internal/infrastructure/logger"
 	"mekari-esign/internal/infrastructure/redis"
 )
 
 const (
-	navSetupPrefix = "mekari:nav_setup:"
+	navSetupPrefix = "nav_setup:"
 )
 
 type esignRepository struct {
-	config      *config.Config
-	client      httpclient.HTTPClient
-	docService  document.DocumentService
-	redisClient *redis.RedisClient
-	logger      *zap.Logger
+	config     *config.Config
+	client     httpclient.HTTPClient
+	docService document.DocumentService
+	cache      redis.Cache
+	logger     *zap.Logger
 }
 
-func NewEsignRepository(cfg *config.Config, client httpclient.HTTPClient, docService document.DocumentService, redisClient *redis.RedisClient, logger *zap.Logger) repository.EsignRepository {
+func NewEsignRepository(cfg *config.Config, client httpclient.HTTPClient, docService document.DocumentService, cache redis.Cache, logger *zap.Logger) repository.EsignRepository {
 	return &esignRepository{
-		config:      cfg,
-		client:      client,
-		docService:  docService,
-		redisClient: redisClient,
-		logger:      logger,
+		config:     cfg,
+		client:     client,
+		docService: docService,
+		cache:      cache,
+		logger:     logger.Named("esign"),
 	}
 }
 
@@ -42,7 +45,7 @@ func NewEsignRepository(cfg *config.Config, client httpclient.HTTPClient, docSer
 func (r *esignRepository) getNAVSetup(ctx context.Context, entryNo int) *entity.NAVSetup {
 	cacheKey := navSetupPrefix + strconv.Itoa(entryNo)
 
-	cached, err := r.redisClient.Get(ctx, cacheKey)
+	cached, err := r.cache.Get(ctx, cacheKey)
 	if err != nil || cached == "" {
 		return nil
 	}
@@ -81,6 +84,7 @@ func (r *esignRepository) GetDocuments(ctx context.Context, email string, page,
 }
 
 func (r *esignRepository) GlobalRequestSign(ctx context.Context, email string, req *entity.GlobalSignRequest) (*entity.GlobalSignResponse, error) {
+	log := logger.FromContext(ctx, r.logger)
 	var response entity.GlobalSignResponse
 
 	// Get NAV setup for folder paths
@@ -89,15 +93,21 @@ func (r *esignRepository) GlobalRequestSign(ctx context.Context, email string, r
 	var base64Doc, filename string
 	var err error
 
-	// Find and load document from ready folder by invoice number
-	if navSetup != nil && navSetup.FileLocationOut != "" {
-		r.logger.Info("Using NAV Setup paths",
+	// Inline documents (v2) skip the ready folder; otherwise find the document by invoice number
+	if req.Document != nil {
+		base64Doc = req.Document.Content
+		filename = filepath.Base(req.Document.Filename)
+		if filename == "." || filename == string(filepath.Separator) {
+			return nil, fmt.Errorf("invalid document filename: %s", req.Document.Filename)
+		}
+	} else if navSetup != nil && navSetup.FileLocationOut != "" {
+		log.Info("Using NAV Setup paths",
 			zap.String("ready_path", navSetup.FileLocationOut),
 			zap.String("progress_path", navSetup.FileLocationProcess),
 		)
 		base64Doc, filename, err = r.docService.FindDocumentByInvoiceNumberWithPath(req.InvoiceNumber, navSetup.FileLocationOut)
 	} else {
-		r.logger.Info("Using config paths (NAV Setup not available)")
+		log.Info("Using config paths (NAV Setup not available)")
 		base64Doc, filename, err = r.docService.FindDocumentByInvoiceNumber(req.InvoiceNumber)
 	}
 	if err != nil {
@@ -109,7 +119,7 @@ func (r *esignRepository) GlobalRequestSign(ctx context.Context, email string, r
 
 	// Calculate element size based on number of signers
 	elementWidth, elementHeight := calculateSignatureSize(len(req.Signers))
-	r.logger.Info("Signature size calculated",
+	log.Info("Signature size calculated",
 		zap.Int("signer_count", len(req.Signers)),
 		zap.Float64("element_width", elementWidth),
 		zap.Float64("element_height", elementHeight),
@@ -155,13 +165,16 @@ func (r *esignRepository) GlobalRequestSign(ctx context.Context, email string, r
 			annotations = append(annotations, annotation)
 		}
 
-		// Build phone number if provided
+		// Phone numbers are normalized by the usecase, with the country code of the signer
 		var phoneNumber *entity.PhoneNumber
 		if signer.Phone != "" {
 			phoneNumber = &entity.PhoneNumber{
-				CountryCode: "62",
+				CountryCode: signer.CountryCode,
 				Number:      signer.Phone,
 			}
+			if phoneNumber.CountryCode == "" {
+				phoneNumber.CountryCode = r.config.Mekari.Signers.DefaultCountryCode
+			}
 		}
 
 		mekariSigners[i] = entity.MekariSigner{
@@ -187,6 +200,7 @@ func (r *esignRepository) GlobalRequestSign(ctx context.Context, email string, r
 		CallbackURL:      callbackURL,
 		DocumentDeadline: req.DocumentDeadline,
 		EntryNo:          req.EntryNo,
+		IsSequence:       req.EnforceOrder,
 	}
 
 	reqCtx := &httpclient.RequestContext{Email: email, InvoiceNo: req.InvoiceNumber, EntryNo: req.EntryNo}
@@ -196,17 +210,23 @@ func (r *esignRepository) GlobalRequestSign(ctx context.Context, email string, r
 		return nil, fmt.Errorf("failed to request global sign: %w", err)
 	}
 
+	// Keep inline documents in the progress folder so the webhook can replace them when signed
+	if req.Document != nil {
+		r.saveInlineDocument(ctx, navSetup, filename, req.Document)
+		return &response, nil
+	}
+
 	// Move document from ready to progress folder after successful upload
 	if navSetup != nil && navSetup.FileLocationOut != "" && navSetup.FileLocationProcess != "" {
 		if err := r.docService.MoveToProgressWithPath(filename, navSetup.FileLocationOut, navSetup.FileLocationProcess); err != nil {
-			r.logger.Warn("Failed to move document to progress",
+			log.Warn("Failed to move document to progress",
 				zap.String("filename", filename),
 				zap.Error(err),
 			)
 		}
 	} else {
 		if err := r.docService.MoveToProgress(filename); err != nil {
-			r.logger.Warn("Failed to move document to progress",
+			log.Warn("Failed to move document to progress",
 				zap.String("filename", filename),
 				zap.Error(err),
 			)
@@ -216,6 +236,52 @@ func (r *esignRepository) GlobalRequestSign(ctx context.Context, email string, r
 	return &response, nil
 }
 
+func (r *esignRepository) GetDocument(ctx context.Context, email, documentID string) (*entity.WebhookData, error) {
+	var response entity.DocumentDetailResponse
+
+	reqCtx := &httpclient.RequestContext{Email: email}
+	path := fmt.Sprintf("/documents/%s", url.PathEscape(documentID))
+	if err := r.client.Get(ctx, reqCtx, path, &response); err != nil {
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+	if response.Data == nil {
+		return nil, fmt.Errorf("failed to get document: empty response")
+	}
+
+	return response.Data, nil
+}
+
+func (r *esignRepository) VoidDocument(ctx context.Context, email, documentID string) error {
+	reqCtx := &httpclient.RequestContext{Email: email}
+	path := fmt.Sprintf("/documents/%s/void", url.PathEscape(documentID))
+	if err := r.client.Post(ctx, reqCtx, path, struct{}{}, nil); err != nil {
+		return fmt.Errorf("failed to void document: %w", err)
+	}
+	return nil
+}
+
+// saveInlineDocument writes an inline document to the progress folder after a successful upload
+func (r *esignRepository) saveInlineDocument(ctx context.Context, navSetup *entity.NAVSetup, filename string, doc *entity.InlineDocument) {
+	log := logger.FromContext(ctx, r.logger)
+	content, err := doc.Decode()
+	if err != nil {
+		log.Warn("Failed to decode inline document", zap.String("filename", filename), zap.Error(err))
+		return
+	}
+
+	if navSetup != nil && navSetup.FileLocationProcess != "" {
+		err = r.docService.ReplaceFileInProgressWithPath(filename, content, navSetup.FileLocationProcess)
+	} else {
+		err = r.docService.ReplaceFileInProgress(filename, content)
+	}
+	if err != nil {
+		log.Warn("Failed to save inline document to progress",
+			zap.String("filename", filename),
+			zap.Error(err),
+		)
+	}
+}
+
 // calculateSignatureSize returns the appropriate signature element size based on number of signers
 // More signers = smaller signature to fit all on the document
 func calculateSignatureSize(signerCount int) (width, height float64) {
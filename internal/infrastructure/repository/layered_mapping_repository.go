@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/domain/entity"
+)
+
+// layeredMappingStore keeps mappings in the database and caches them in Redis. Reads go to Redis
+// first and fill it from the database on a miss; writes go to the database first.
+type layeredMappingStore struct {
+	cache  *redisMappingStore
+	db     *dbMappingStore
+	logger *zap.Logger
+}
+
+func (s *layeredMappingStore) find(ctx context.Context, key string) (*entity.DocumentMapping, error) {
+	mapping, err := s.cache.find(ctx, key)
+	if err == nil {
+		return mapping, nil
+	}
+	if !errors.Is(err, ErrMappingNotFound) {
+		s.logger.Warn("Failed to read cached document mapping", zap.String("key", key), zap.Error(err))
+	}
+
+	mapping, err = s.db.find(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.cache.save(ctx, key, mapping); err != nil {
+		s.logger.Warn("Failed to cache document mapping", zap.String("key", key), zap.Error(err))
+	}
+	return mapping, nil
+}
+
+func (s *layeredMappingStore) save(ctx context.Context, key string, mapping *entity.DocumentMapping) error {
+	if err := s.db.save(ctx, key, mapping); err != nil {
+		return err
+	}
+	return s.cache.save(ctx, key, mapping)
+}
+
+func (s *layeredMappingStore) delete(ctx context.Context, keys ...string) error {
+	if err := s.db.delete(ctx, keys...); err != nil {
+		return err
+	}
+	return s.cache.delete(ctx, keys...)
+}
+
+func (s *layeredMappingStore) touch(ctx context.Context, key string) error {
+	if err := s.db.touch(ctx, key); err != nil {
+		return err
+	}
+	return s.cache.touch(ctx, key)
+}
+
+// List reads the database, which holds every mapping
+func (s *layeredMappingStore) List(ctx context.Context, cursor uint64, limit int, invoice string) (*entity.DocumentMappingPage, error) {
+	return s.db.List(ctx, cursor, limit, invoice)
+}
+
+// PurgeExpired purges the database and drops the cached copies of the deleted mappings
+func (s *layeredMappingStore) PurgeExpired(ctx context.Context, archive func(mapping *entity.ArchivedDocumentMapping) error) (int, error) {
+	return s.db.PurgeExpired(ctx, func(mapping *entity.ArchivedDocumentMapping) error {
+		if archive != nil {
+			if err := archive(mapping); err != nil {
+				return err
+			}
+		}
+		return s.cache.delete(ctx, mapping.Key)
+	})
+}
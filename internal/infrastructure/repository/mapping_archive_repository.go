@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/database"
+)
+
+// MappingArchiveRepository interface for document mappings archived before their Redis keys expire
+type MappingArchiveRepository interface {
+	// Save stores an archived mapping and sets its ID
+	Save(ctx context.Context, mapping *entity.ArchivedDocumentMapping) error
+	// FindByDocumentID returns the archived keys of a document, newest first
+	FindByDocumentID(ctx context.Context, documentID string) ([]entity.ArchivedDocumentMapping, error)
+}
+
+type mappingArchiveRepository struct {
+	db     *database.Database
+	logger *zap.Logger
+}
+
+// NewMappingArchiveRepository creates a new mapping archive repository
+func NewMappingArchiveRepository(db *database.Database, logger *zap.Logger) MappingArchiveRepository {
+	return &mappingArchiveRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Save inserts an archived mapping
+func (r *mappingArchiveRepository) Save(ctx context.Context, mapping *entity.ArchivedDocumentMapping) error {
+	query := `
+		INSERT INTO document_mapping_archive (redis_key, document_id, invoice_no, data, archived_at)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	id, err := r.db.Insert(ctx, query, mapping.Key, mapping.DocumentID, mapping.InvoiceNo, mapping.Data, mapping.ArchivedAt)
+	if err != nil {
+		return fmt.Errorf("failed to archive document mapping: %w", err)
+	}
+	mapping.ID = id
+
+	return nil
+}
+
+// FindByDocumentID finds the archived mappings of a document
+func (r *mappingArchiveRepository) FindByDocumentID(ctx context.Context, documentID string) ([]entity.ArchivedDocumentMapping, error) {
+	query := `
+		SELECT id, redis_key, document_id, invoice_no, data, archived_at
+		FROM document_mapping_archive
+		WHERE document_id = $1
+		ORDER BY id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query archived document mappings: %w", err)
+	}
+	defer rows.Close()
+
+	mappings := []entity.ArchivedDocumentMapping{}
+	for rows.Next() {
+		var mapping entity.ArchivedDocumentMapping
+		if err := rows.Scan(&mapping.ID, &mapping.Key, &mapping.DocumentID, &mapping.InvoiceNo, &mapping.Data, &mapping.ArchivedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan archived document mapping: %w", err)
+		}
+		mappings = append(mappings, mapping)
+	}
+
+	return mappings, rows.Err()
+}
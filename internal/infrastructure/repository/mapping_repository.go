@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/database"
+	"mekari-esign/internal/infrastructure/redis"
+)
+
+const (
+	// documentMappingPrefix keys mappings by document ID (stamp documents by their own ID)
+	documentMappingPrefix = "document:"
+	// entryNoMappingPrefix keys mappings by NAV entry number
+	entryNoMappingPrefix = "entry_no:"
+)
+
+// ErrMappingNotFound is returned when no mapping is stored for a document ID or entry number
+var ErrMappingNotFound = errors.New("document mapping not found")
+
+// MappingRepository interface for the document mappings used by webhooks and stamping. A mapping is
+// stored under the document ID and under the NAV entry number of the sign request.
+type MappingRepository interface {
+	FindByDocumentID(ctx context.Context, documentID string) (*entity.DocumentMapping, error)
+	FindByEntryNo(ctx context.Context, entryNo int) (*entity.DocumentMapping, error)
+	SaveByDocumentID(ctx context.Context, documentID string, mapping *entity.DocumentMapping) error
+	SaveByEntryNo(ctx context.Context, entryNo int, mapping *entity.DocumentMapping) error
+	DeleteByDocumentID(ctx context.Context, documentIDs ...string) error
+	DeleteByEntryNo(ctx context.Context, entryNo int) error
+	// TouchByDocumentID and TouchByEntryNo restart the expiry of a mapping still in use
+	TouchByDocumentID(ctx context.Context, documentID string) error
+	TouchByEntryNo(ctx context.Context, entryNo int) error
+	// List returns a page of the mappings stored by document ID, optionally filtered by invoice
+	// number. The cursor is opaque; 0 starts at the first page.
+	List(ctx context.Context, cursor uint64, limit int, invoice string) (*entity.DocumentMappingPage, error)
+	// PurgeExpired deletes the mappings without activity for mapping.ttl_days, passing each to
+	// archive first (when not nil), and returns how many were deleted
+	PurgeExpired(ctx context.Context, archive func(mapping *entity.ArchivedDocumentMapping) error) (int, error)
+}
+
+// mappingStore is a MappingRepository backend addressed by key ("document:<id>" or "entry_no:<n>")
+type mappingStore interface {
+	find(ctx context.Context, key string) (*entity.DocumentMapping, error)
+	save(ctx context.Context, key string, mapping *entity.DocumentMapping) error
+	delete(ctx context.Context, keys ...string) error
+	touch(ctx context.Context, key string) error
+	List(ctx context.Context, cursor uint64, limit int, invoice string) (*entity.DocumentMappingPage, error)
+	PurgeExpired(ctx context.Context, archive func(mapping *entity.ArchivedDocumentMapping) error) (int, error)
+}
+
+type mappingRepository struct {
+	mappingStore
+}
+
+// NewMappingRepository creates the mapping repository of mapping.store
+func NewMappingRepository(cfg *config.Config, db *database.Database, cache redis.Cache, client *redis.RedisClient, logger *zap.Logger) MappingRepository {
+	logger = logger.Named("mapping")
+
+	switch cfg.Mapping.Store {
+	case config.MappingStoreDatabase:
+		return &mappingRepository{newDBMappingStore(cfg, db, logger)}
+	case config.MappingStoreLayered:
+		return &mappingRepository{&layeredMappingStore{
+			cache:  newRedisMappingStore(cfg, cache, client, logger),
+			db:     newDBMappingStore(cfg, db, logger),
+			logger: logger,
+		}}
+	default:
+		return &mappingRepository{newRedisMappingStore(cfg, cache, client, logger)}
+	}
+}
+
+func (r *mappingRepository) FindByDocumentID(ctx context.Context, documentID string) (*entity.DocumentMapping, error) {
+	return r.find(ctx, documentMappingPrefix+documentID)
+}
+
+func (r *mappingRepository) FindByEntryNo(ctx context.Context, entryNo int) (*entity.DocumentMapping, error) {
+	return r.find(ctx, entryNoMappingKey(entryNo))
+}
+
+func (r *mappingRepository) SaveByDocumentID(ctx context.Context, documentID string, mapping *entity.DocumentMapping) error {
+	return r.save(ctx, documentMappingPrefix+documentID, mapping)
+}
+
+func (r *mappingRepository) SaveByEntryNo(ctx context.Context, entryNo int, mapping *entity.DocumentMapping) error {
+	return r.save(ctx, entryNoMappingKey(entryNo), mapping)
+}
+
+func (r *mappingRepository) DeleteByDocumentID(ctx context.Context, documentIDs ...string) error {
+	keys := make([]string, len(documentIDs))
+	for i, documentID := range documentIDs {
+		keys[i] = documentMappingPrefix + documentID
+	}
+	return r.delete(ctx, keys...)
+}
+
+func (r *mappingRepository) DeleteByEntryNo(ctx context.Context, entryNo int) error {
+	return r.delete(ctx, entryNoMappingKey(entryNo))
+}
+
+func (r *mappingRepository) TouchByDocumentID(ctx context.Context, documentID string) error {
+	return r.touch(ctx, documentMappingPrefix+documentID)
+}
+
+func (r *mappingRepository) TouchByEntryNo(ctx context.Context, entryNo int) error {
+	return r.touch(ctx, entryNoMappingKey(entryNo))
+}
+
+func entryNoMappingKey(entryNo int) string {
+	return entryNoMappingPrefix + strconv.Itoa(entryNo)
+}
+
+// MappingTTL is the Redis expiry of mapping keys: mapping.ttl_days plus the cleanup grace, so the
+// cleanup job archives a key before Redis drops it
+func MappingTTL(cfg *config.Config) time.Duration {
+	return time.Duration(cfg.Mapping.TTLDays)*24*time.Hour + mappingCleanupGrace(cfg)
+}
+
+// mappingCleanupGrace is the remaining TTL below which the cleanup job removes a Redis key. Two
+// intervals leave room for one failed run.
+func mappingCleanupGrace(cfg *config.Config) time.Duration {
+	return 2 * time.Duration(cfg.Mapping.CleanupInterval) * time.Hour
+}
+
+func encodeMapping(mapping *entity.DocumentMapping) (string, error) {
+	data, err := json.Marshal(mapping)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal document mapping: %w", err)
+	}
+	return string(data), nil
+}
+
+// decodeMapping parses a stored mapping; values of old versions held just the email
+func decodeMapping(data string) *entity.DocumentMapping {
+	var mapping entity.DocumentMapping
+	if err := json.Unmarshal([]byte(data), &mapping); err != nil {
+		return &entity.DocumentMapping{Email: data}
+	}
+	return &mapping
+}
+
+// archivedMapping builds the archive copy of a stored value. Document info and mappings share the
+// document_id and invoice_number fields.
+func archivedMapping(key, data string, archivedAt time.Time) *entity.ArchivedDocumentMapping {
+	archived := &entity.ArchivedDocumentMapping{
+		Key:        key,
+		Data:       data,
+		ArchivedAt: archivedAt,
+	}
+	var info struct {
+		DocumentID    string `json:"document_id"`
+		InvoiceNumber string `json:"invoice_number"`
+	}
+	if json.Unmarshal([]byte(data), &info) == nil {
+		archived.DocumentID, archived.InvoiceNo = info.DocumentID, info.InvoiceNumber
+	}
+	return archived
+}
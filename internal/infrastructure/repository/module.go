@@ -4,16 +4,32 @@ import (
 	"go.uber.org/fx"
 
 	"mekari-esign/internal/infrastructure/httpclient"
+	"mekari-esign/internal/infrastructure/nav"
 )
 
 var Module = fx.Module("repository",
 	fx.Provide(NewEsignRepository),
 	fx.Provide(NewOAuthRepository),
 	fx.Provide(NewAPILogRepository),
+	fx.Provide(NewWebhookEventRepository),
+	fx.Provide(NewDocumentEventRepository),
+	fx.Provide(NewAPIKeyRepository),
+	fx.Provide(NewUserRepository),
+	fx.Provide(NewMappingArchiveRepository),
+	fx.Provide(NewMappingRepository),
+	fx.Provide(NewOAuthTokenHistoryRepository),
+	fx.Provide(NewUpdateHistoryRepository),
+	fx.Provide(NewTransactor),
 	fx.Provide(
 		fx.Annotate(
 			func(repo APILogRepository) httpclient.APILogSaver { return repo },
 			fx.From(new(APILogRepository)),
 		),
 	),
+	fx.Provide(
+		fx.Annotate(
+			func(repo APILogRepository) nav.APILogSaver { return repo },
+			fx.From(new(APILogRepository)),
+		),
+	),
 )
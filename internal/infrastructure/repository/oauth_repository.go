@@ -3,35 +3,237 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/config/secrets"
 	"mekari-esign/internal/domain/entity"
 	"mekari-esign/internal/domain/repository"
 	"mekari-esign/internal/infrastructure/database"
+	"mekari-esign/internal/infrastructure/redis"
+	"mekari-esign/internal/timeutil"
+)
+
+const (
+	// oauthCachePrefix keys the Redis copies of oauth_tokens rows read while the database is
+	// unavailable. The copies hold the columns as stored, so encrypted values stay encrypted.
+	oauthCachePrefix = "oauth_token:"
+	// oauthEncryptTimeout bounds encrypting the rows stored in plain text on start
+	oauthEncryptTimeout = 30 * time.Second
 )
 
 type oauthRepository struct {
-	db *database.Database
+	db     *database.Database
+	cache  redis.Cache
+	cipher *secrets.Cipher // Encrypts code, access_token and refresh_token (nil = plain text)
+	logger *zap.Logger
+}
+
+// NewOAuthRepository creates the OAuth token repository. With oauth.encryption_key set, the rows
+// still stored in plain text are encrypted on start.
+func NewOAuthRepository(lc fx.Lifecycle, cfg *config.Config, db *database.Database, cache redis.Cache, logger *zap.Logger) (repository.OAuthRepository, error) {
+	r := &oauthRepository{
+		db:     db,
+		cache:  cache,
+		logger: logger,
+	}
+
+	key, err := cfg.OAuth.EncryptionKeyBytes()
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return r, nil
+	}
+	if r.cipher, err = secrets.NewCipher(key); err != nil {
+		return nil, fmt.Errorf("invalid oauth.encryption_key: %w", err)
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(context.Background(), oauthEncryptTimeout)
+			defer cancel()
+
+			// Plain rows keep working, so a failure is retried on the next start
+			encrypted, err := r.encryptPlainRows(ctx)
+			if err != nil {
+				logger.Warn("Failed to encrypt stored OAuth tokens", zap.Error(err))
+			} else if encrypted > 0 {
+				logger.Info("Encrypted stored OAuth tokens", zap.Int("count", encrypted))
+			}
+			return nil
+		},
+	})
+
+	return r, nil
+}
+
+// seal encrypts a column value; empty values are stored as they are
+func (r *oauthRepository) seal(value string) (string, error) {
+	if r.cipher == nil || value == "" {
+		return value, nil
+	}
+	return r.cipher.Encrypt(value)
 }
 
-func NewOAuthRepository(db *database.Database) repository.OAuthRepository {
-	return &oauthRepository{
-		db: db,
+// open decrypts a column value. Values stored before encryption was turned on are plain text.
+func (r *oauthRepository) open(value string) (string, error) {
+	if !secrets.IsEncrypted(value) {
+		return value, nil
 	}
+	if r.cipher == nil {
+		return "", fmt.Errorf("value is encrypted but oauth.encryption_key is not set")
+	}
+	return r.cipher.Decrypt(value)
+}
+
+// openToken decrypts the code and tokens of a row as stored
+func (r *oauthRepository) openToken(token *entity.OAuthToken) error {
+	for _, field := range []*string{&token.Code, &token.AccessToken, &token.RefreshToken} {
+		plain, err := r.open(*field)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt oauth token of %s: %w", token.Email, err)
+		}
+		*field = plain
+	}
+	return nil
 }
 
+// encryptPlainRows encrypts the code and tokens of the rows stored in plain text and returns how
+// many rows it changed
+func (r *oauthRepository) encryptPlainRows(ctx context.Context) (int, error) {
+	var plain []entity.OAuthToken
+	err := r.db.WithTx(ctx, func(tx *database.Tx) error {
+		rows, err := tx.QueryContext(ctx, `SELECT email, code, access_token, refresh_token FROM oauth_tokens`)
+		if err != nil {
+			return fmt.Errorf("failed to query oauth tokens: %w", err)
+		}
+
+		for rows.Next() {
+			var token entity.OAuthToken
+			var accessToken, refreshToken sql.NullString
+			if err := rows.Scan(&token.Email, &token.Code, &accessToken, &refreshToken); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan oauth token: %w", err)
+			}
+			token.AccessToken, token.RefreshToken = accessToken.String, refreshToken.String
+			for _, value := range []string{token.Code, token.AccessToken, token.RefreshToken} {
+				if value != "" && !secrets.IsEncrypted(value) {
+					plain = append(plain, token)
+					break
+				}
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to query oauth tokens: %w", err)
+		}
+
+		for i := range plain {
+			token := &plain[i]
+			for _, field := range []*string{&token.Code, &token.AccessToken, &token.RefreshToken} {
+				if secrets.IsEncrypted(*field) {
+					continue
+				}
+				if *field, err = r.seal(*field); err != nil {
+					return err
+				}
+			}
+
+			_, err := tx.ExecContext(ctx, `
+				UPDATE oauth_tokens SET code = $1, access_token = $2, refresh_token = $3 WHERE email = $4
+			`, token.Code, token.AccessToken, token.RefreshToken, token.Email)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt oauth token of %s: %w", token.Email, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// The cached copies of these rows are still in plain text
+	for i := range plain {
+		r.forgetToken(ctx, plain[i].Email)
+	}
+	return len(plain), nil
+}
+
+// FindByEmail reads the token from the database and keeps a copy in Redis, which is returned
+// instead while the database is unavailable
 func (r *oauthRepository) FindByEmail(ctx context.Context, email string) (*entity.OAuthToken, error) {
+	var dbErr error
+	if r.db.Available() {
+		token, err := r.findByEmail(ctx, email)
+		if err == nil {
+			r.cacheToken(ctx, email, token)
+			if token != nil {
+				if err := r.openToken(token); err != nil {
+					return nil, err
+				}
+			}
+			return token, nil
+		}
+		dbErr = err
+	}
+
+	cached, err := r.cache.Get(ctx, oauthCachePrefix+email)
+	if err != nil {
+		if dbErr == nil {
+			dbErr = fmt.Errorf("failed to find oauth token by email: database unavailable")
+		}
+		return nil, dbErr
+	}
+
+	var token entity.OAuthToken
+	if err := json.Unmarshal([]byte(cached), &token); err != nil {
+		return nil, fmt.Errorf("failed to decode cached oauth token: %w", err)
+	}
+	if err := r.openToken(&token); err != nil {
+		return nil, err
+	}
+	r.logger.Warn("Database unavailable, using cached OAuth token", zap.String("email", email), zap.NamedError("db_error", dbErr))
+	return &token, nil
+}
+
+// cacheToken stores the copy read by FindByEmail during outages, as stored; not found removes it
+func (r *oauthRepository) cacheToken(ctx context.Context, email string, token *entity.OAuthToken) {
+	var err error
+	if token == nil {
+		err = r.cache.Del(ctx, oauthCachePrefix+email)
+	} else {
+		data, _ := json.Marshal(token)
+		err = r.cache.Set(ctx, oauthCachePrefix+email, data, 0)
+	}
+	if err != nil {
+		r.logger.Warn("Failed to cache OAuth token", zap.String("email", email), zap.Error(err))
+	}
+}
+
+// forgetToken drops the cached copy after a write; the next lookup caches the new row
+func (r *oauthRepository) forgetToken(ctx context.Context, email string) {
+	if err := r.cache.Del(ctx, oauthCachePrefix+email); err != nil {
+		r.logger.Warn("Failed to clear cached OAuth token", zap.String("email", email), zap.Error(err))
+	}
+}
+
+func (r *oauthRepository) findByEmail(ctx context.Context, email string) (*entity.OAuthToken, error) {
 	query := `
 		SELECT id, email, code, access_token, refresh_token, token_type, expires_at, created_at, updated_at
 		FROM oauth_tokens
-		WHERE email = $1
+		WHERE email = $1 AND deleted_at IS NULL
 	`
 
 	var token entity.OAuthToken
 	var expiresAt sql.NullTime
 
-	err := r.db.DB.QueryRowContext(ctx, query, email).Scan(
+	err := r.db.QueryRowContext(ctx, query, email).Scan(
 		&token.ID,
 		&token.Email,
 		&token.Code,
@@ -57,21 +259,70 @@ func (r *oauthRepository) FindByEmail(ctx context.Context, email string) (*entit
 	return &token, nil
 }
 
-func (r *oauthRepository) SaveCode(ctx context.Context, email, code string) error {
-	// Upsert: Insert or update if exists (PostgreSQL syntax)
+func (r *oauthRepository) FindAll(ctx context.Context) ([]entity.OAuthToken, error) {
 	query := `
-		INSERT INTO oauth_tokens (email, code, updated_at)
-		VALUES ($1, $2, $3)
-		ON CONFLICT(email) DO UPDATE SET
-			code = EXCLUDED.code,
-			updated_at = EXCLUDED.updated_at
+		SELECT id, email, code, access_token, refresh_token, token_type, expires_at, created_at, updated_at
+		FROM oauth_tokens
+		WHERE deleted_at IS NULL
+		ORDER BY email
 	`
 
-	_, err := r.db.DB.ExecContext(ctx, query, email, code, time.Now())
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query oauth tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []entity.OAuthToken{}
+	for rows.Next() {
+		var token entity.OAuthToken
+		var expiresAt sql.NullTime
+
+		err := rows.Scan(
+			&token.ID,
+			&token.Email,
+			&token.Code,
+			&token.AccessToken,
+			&token.RefreshToken,
+			&token.TokenType,
+			&expiresAt,
+			&token.CreatedAt,
+			&token.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan oauth token: %w", err)
+		}
+
+		if expiresAt.Valid {
+			token.ExpiresAt = expiresAt.Time
+		}
+		if err := r.openToken(&token); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+func (r *oauthRepository) SaveCode(ctx context.Context, email, code string) error {
+	// Upsert: Insert or update if exists; a new code restores a deleted record
+	query := r.db.Dialect.Upsert("oauth_tokens", "email",
+		[]string{"email", "code", "updated_at", "deleted_at"},
+		[]string{"code", "updated_at", "deleted_at"},
+	)
+
+	code, err := r.seal(code)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt oauth code: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, query, email, code, timeutil.Now(), sql.NullTime{})
 	if err != nil {
 		return fmt.Errorf("failed to save oauth code: %w", err)
 	}
 
+	r.forgetToken(ctx, email)
 	return nil
 }
 
@@ -79,14 +330,64 @@ func (r *oauthRepository) UpdateTokens(ctx context.Context, email, accessToken,
 	query := `
 		UPDATE oauth_tokens
 		SET access_token = $1, refresh_token = $2, token_type = $3, expires_at = $4, updated_at = $5
-		WHERE email = $6
+		WHERE email = $6 AND deleted_at IS NULL
 	`
 
-	expiresTime := time.Now().Add(time.Duration(expiresAt) * time.Second)
-	_, err := r.db.DB.ExecContext(ctx, query, accessToken, refreshToken, tokenType, expiresTime, time.Now(), email)
+	accessToken, err := r.seal(accessToken)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt oauth tokens: %w", err)
+	}
+	if refreshToken, err = r.seal(refreshToken); err != nil {
+		return fmt.Errorf("failed to encrypt oauth tokens: %w", err)
+	}
+
+	expiresTime := timeutil.Now().Add(time.Duration(expiresAt) * time.Second)
+	_, err = r.db.ExecContext(ctx, query, accessToken, refreshToken, tokenType, expiresTime, timeutil.Now(), email)
 	if err != nil {
 		return fmt.Errorf("failed to update oauth tokens: %w", err)
 	}
 
+	r.forgetToken(ctx, email)
 	return nil
 }
+
+func (r *oauthRepository) SoftDelete(ctx context.Context, email string) (bool, error) {
+	query := `
+		UPDATE oauth_tokens
+		SET code = '', access_token = '', refresh_token = '', token_type = '', expires_at = NULL, updated_at = $1, deleted_at = $1
+		WHERE email = $2 AND deleted_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, timeutil.Now(), email)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete oauth token: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete oauth token: %w", err)
+	}
+
+	r.forgetToken(ctx, email)
+	return affected > 0, nil
+}
+
+func (r *oauthRepository) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	var purged int64
+	err := r.db.WithTx(ctx, func(tx *database.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			DELETE FROM oauth_token_history
+			WHERE email IN (SELECT email FROM oauth_tokens WHERE deleted_at < $1)
+		`, before)
+		if err != nil {
+			return fmt.Errorf("failed to purge oauth token history: %w", err)
+		}
+
+		result, err := tx.ExecContext(ctx, `DELETE FROM oauth_tokens WHERE deleted_at < $1`, before)
+		if err != nil {
+			return fmt.Errorf("failed to purge deleted oauth tokens: %w", err)
+		}
+		purged, err = result.RowsAffected()
+		return err
+	})
+	return int(purged), err
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/domain/repository"
+	"mekari-esign/internal/infrastructure/database"
+)
+
+type oauthTokenHistoryRepository struct {
+	db     *database.Database
+	logger *zap.Logger
+}
+
+// NewOAuthTokenHistoryRepository creates a new OAuth token history repository
+func NewOAuthTokenHistoryRepository(db *database.Database, logger *zap.Logger) repository.OAuthTokenHistoryRepository {
+	return &oauthTokenHistoryRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Save inserts a token attempt
+func (r *oauthTokenHistoryRepository) Save(ctx context.Context, event *entity.OAuthTokenEvent) error {
+	query := `
+		INSERT INTO oauth_token_history (email, grant_type, success, error, issued_at, expires_at, request_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	id, err := r.db.Insert(ctx, query,
+		event.Email,
+		event.GrantType,
+		event.Success,
+		event.Error,
+		event.IssuedAt,
+		event.ExpiresAt,
+		event.RequestID,
+		event.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save oauth token history: %w", err)
+	}
+	event.ID = id
+
+	return nil
+}
+
+// FindByEmail finds the latest token attempts of an email
+func (r *oauthTokenHistoryRepository) FindByEmail(ctx context.Context, email string, limit int) ([]entity.OAuthTokenEvent, error) {
+	query := `
+		SELECT id, email, grant_type, success, error, issued_at, expires_at, request_id, created_at
+		FROM oauth_token_history
+		WHERE email = $1
+		ORDER BY created_at DESC, id DESC
+	` + r.db.Dialect.Limit("$2", "")
+
+	rows, err := r.db.QueryContext(ctx, query, email, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query oauth token history: %w", err)
+	}
+	defer rows.Close()
+
+	events := []entity.OAuthTokenEvent{}
+	for rows.Next() {
+		var event entity.OAuthTokenEvent
+		var issuedAt, expiresAt sql.NullTime
+		if err := rows.Scan(&event.ID, &event.Email, &event.GrantType, &event.Success, &event.Error, &issuedAt, &expiresAt, &event.RequestID, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan oauth token history: %w", err)
+		}
+		if issuedAt.Valid {
+			event.IssuedAt = &issuedAt.Time
+		}
+		if expiresAt.Valid {
+			event.ExpiresAt = &expiresAt.Time
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/redis"
+	"mekari-esign/internal/timeutil"
+)
+
+const (
+	// documentInfoKeyPrefix holds the webhook document info, which shares the document: prefix
+	// and the expiry of the mappings
+	documentInfoKeyPrefix = "document:info:"
+	// mappingScanCount is the SCAN batch size used when listing and purging mappings
+	mappingScanCount = 200
+)
+
+// redisMappingStore keeps mappings as JSON values with an expiry of MappingTTL
+type redisMappingStore struct {
+	config *config.Config
+	cache  redis.Cache
+	client *redis.RedisClient // Key scans and TTLs
+	logger *zap.Logger
+}
+
+func newRedisMappingStore(cfg *config.Config, cache redis.Cache, client *redis.RedisClient, logger *zap.Logger) *redisMappingStore {
+	return &redisMappingStore{
+		config: cfg,
+		cache:  cache,
+		client: client,
+		logger: logger,
+	}
+}
+
+func (s *redisMappingStore) find(ctx context.Context, key string) (*entity.DocumentMapping, error) {
+	data, err := s.cache.Get(ctx, key)
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMappingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document mapping: %w", err)
+	}
+	return decodeMapping(data), nil
+}
+
+func (s *redisMappingStore) save(ctx context.Context, key string, mapping *entity.DocumentMapping) error {
+	data, err := encodeMapping(mapping)
+	if err != nil {
+		return err
+	}
+	if err := s.cache.Set(ctx, key, data, MappingTTL(s.config)); err != nil {
+		return fmt.Errorf("failed to save document mapping %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *redisMappingStore) delete(ctx context.Context, keys ...string) error {
+	if err := s.cache.Del(ctx, keys...); err != nil {
+		return fmt.Errorf("failed to delete document mapping: %w", err)
+	}
+	return nil
+}
+
+func (s *redisMappingStore) touch(ctx context.Context, key string) error {
+	return s.cache.Expire(ctx, key, MappingTTL(s.config))
+}
+
+// List scans the document:* keys. The cursor is the SCAN cursor, so a page may hold slightly
+// more than the limit.
+func (s *redisMappingStore) List(ctx context.Context, cursor uint64, limit int, invoice string) (*entity.DocumentMappingPage, error) {
+	invoice = strings.ToLower(invoice)
+
+	page := &entity.DocumentMappingPage{Mappings: []entity.DocumentMappingItem{}}
+	for {
+		keys, next, err := s.client.Scan(ctx, cursor, documentMappingPrefix+"*", mappingScanCount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan document mappings: %w", err)
+		}
+
+		mappingKeys := keys[:0]
+		for _, key := range keys {
+			if !strings.HasPrefix(key, documentInfoKeyPrefix) {
+				mappingKeys = append(mappingKeys, key)
+			}
+		}
+
+		values, err := s.client.MGet(ctx, mappingKeys...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get document mappings: %w", err)
+		}
+
+		for _, key := range mappingKeys {
+			data, ok := values[key]
+			if !ok {
+				continue // Deleted between SCAN and MGET
+			}
+			mapping := decodeMapping(data)
+			if invoice != "" && !strings.Contains(strings.ToLower(mapping.InvoiceNumber), invoice) {
+				continue
+			}
+			page.Mappings = append(page.Mappings, entity.DocumentMappingItem{
+				Key:             strings.TrimPrefix(key, documentMappingPrefix),
+				DocumentMapping: *mapping,
+			})
+		}
+
+		cursor = next
+		if cursor == 0 || len(page.Mappings) >= limit {
+			break
+		}
+	}
+
+	if cursor != 0 {
+		page.NextCursor = strconv.FormatUint(cursor, 10)
+	}
+	sort.Slice(page.Mappings, func(i, j int) bool {
+		return page.Mappings[i].Key < page.Mappings[j].Key
+	})
+
+	return page, nil
+}
+
+// PurgeExpired removes the keys, including document info, that would expire before the next two
+// cleanup runs. Keys written before expiry was introduced get MappingTTL instead.
+func (s *redisMappingStore) PurgeExpired(ctx context.Context, archive func(mapping *entity.ArchivedDocumentMapping) error) (int, error) {
+	removed := 0
+	for _, prefix := range []string{documentMappingPrefix, entryNoMappingPrefix} {
+		var cursor uint64
+		for {
+			keys, next, err := s.client.Scan(ctx, cursor, prefix+"*", mappingScanCount)
+			if err != nil {
+				return removed, fmt.Errorf("failed to scan document mappings: %w", err)
+			}
+
+			purged, err := s.purgeKeys(ctx, keys, archive)
+			removed += purged
+			if err != nil {
+				return removed, err
+			}
+
+			cursor = next
+			if cursor == 0 {
+				break
+			}
+		}
+	}
+
+	return removed, nil
+}
+
+// purgeKeys archives and deletes the keys of one scan page that are about to expire and returns
+// how many it deleted. TTLs and values are read with one round trip each.
+func (s *redisMappingStore) purgeKeys(ctx context.Context, keys []string, archive func(mapping *entity.ArchivedDocumentMapping) error) (int, error) {
+	ttls, err := s.client.TTLs(ctx, keys...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get ttl of document mappings: %w", err)
+	}
+
+	var expiring []string
+	for i, key := range keys {
+		ttl := ttls[i]
+		if ttl == -1 {
+			// No expiry yet
+			if err := s.cache.Expire(ctx, key, MappingTTL(s.config)); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if ttl >= 0 && ttl <= mappingCleanupGrace(s.config) {
+			expiring = append(expiring, key)
+		}
+	}
+
+	values, err := s.client.MGet(ctx, expiring...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get document mappings: %w", err)
+	}
+
+	removed := 0
+	for _, key := range expiring {
+		data, ok := values[key]
+		if !ok {
+			continue // Expired or deleted meanwhile
+		}
+
+		// The key is kept when archiving fails, so the next run tries again
+		if archive != nil {
+			if err := archive(archivedMapping(key, data, timeutil.Now())); err != nil {
+				return removed, err
+			}
+		}
+
+		if err := s.cache.Del(ctx, key); err != nil {
+			return removed, fmt.Errorf("failed to delete %s: %w", key, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+
+	"mekari-esign/internal/infrastructure/database"
+)
+
+// Transactor makes the database writes of several repositories atomic
+type Transactor interface {
+	// WithinTx runs fn in one database transaction. Repository calls made with the ctx passed to
+	// fn commit together when fn returns nil and roll back otherwise. Redis writes of the
+	// mapping stores are not part of the transaction.
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type transactor struct {
+	db *database.Database
+}
+
+// NewTransactor creates a new transactor
+func NewTransactor(db *database.Database) Transactor {
+	return &transactor{db: db}
+}
+
+func (t *transactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return t.db.WithTx(ctx, func(tx *database.Tx) error {
+		return fn(tx.Context())
+	})
+}
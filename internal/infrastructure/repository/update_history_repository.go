@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/database"
+)
+
+// UpdateHistoryRepository interface for the updates and rollbacks of the hosts sharing the database
+type UpdateHistoryRepository interface {
+	// Save stores an update once per host and finish time and sets its ID. Returns false when it
+	// was already recorded.
+	Save(ctx context.Context, update *entity.UpdateHistory) (bool, error)
+	// List returns the newest updates first
+	List(ctx context.Context, limit int) ([]entity.UpdateHistory, error)
+}
+
+type updateHistoryRepository struct {
+	db     *database.Database
+	logger *zap.Logger
+}
+
+// NewUpdateHistoryRepository creates a new update history repository
+func NewUpdateHistoryRepository(db *database.Database, logger *zap.Logger) UpdateHistoryRepository {
+	return &updateHistoryRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Save inserts an update unless it is recorded already
+func (r *updateHistoryRepository) Save(ctx context.Context, update *entity.UpdateHistory) (bool, error) {
+	query := `
+		INSERT INTO update_history (update_key, host, from_version, to_version, kind, result, error, finished_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	key := update.Host + "/" + update.FinishedAt.UTC().Format(time.RFC3339Nano)
+	id, inserted, err := r.db.InsertIgnore(ctx, query, "update_key",
+		key,
+		update.Host,
+		update.FromVersion,
+		update.ToVersion,
+		update.Kind,
+		update.Result,
+		update.Error,
+		update.FinishedAt,
+		update.CreatedAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to save update history: %w", err)
+	}
+	update.ID = id
+
+	return inserted, nil
+}
+
+// List finds the latest updates of every host
+func (r *updateHistoryRepository) List(ctx context.Context, limit int) ([]entity.UpdateHistory, error) {
+	query := `
+		SELECT id, host, from_version, to_version, kind, result, error, finished_at, created_at
+		FROM update_history
+		ORDER BY finished_at DESC, id DESC
+	` + r.db.Dialect.Limit("$1", "")
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query update history: %w", err)
+	}
+	defer rows.Close()
+
+	updates := []entity.UpdateHistory{}
+	for rows.Next() {
+		var update entity.UpdateHistory
+		if err := rows.Scan(&update.ID, &update.Host, &update.FromVersion, &update.ToVersion, &update.Kind,
+			&update.Result, &update.Error, &update.FinishedAt, &update.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan update history: %w", err)
+		}
+		updates = append(updates, update)
+	}
+
+	return updates, rows.Err()
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/database"
+)
+
+// UserRepository interface for user operations
+type UserRepository interface {
+	Create(ctx context.Context, user *entity.User) error
+	FindByUsername(ctx context.Context, username string) (*entity.User, error)
+	FindAll(ctx context.Context) ([]entity.User, error)
+	Count(ctx context.Context) (int, error)
+	UpdateLastLogin(ctx context.Context, id int64, loginAt time.Time) error
+}
+
+type userRepository struct {
+	db     *database.Database
+	logger *zap.Logger
+}
+
+// NewUserRepository creates a new user repository
+func NewUserRepository(db *database.Database, logger *zap.Logger) UserRepository {
+	return &userRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+const userColumns = `id, username, password_hash, role, disabled, last_login_at, created_at`
+
+func scanUser(scanner interface{ Scan(...interface{}) error }) (*entity.User, error) {
+	var user entity.User
+	var lastLoginAt sql.NullTime
+
+	if err := scanner.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &user.Disabled, &lastLoginAt, &user.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	if lastLoginAt.Valid {
+		user.LastLoginAt = &lastLoginAt.Time
+	}
+
+	return &user, nil
+}
+
+// Create inserts a new user
+func (r *userRepository) Create(ctx context.Context, user *entity.User) error {
+	query := `
+		INSERT INTO users (username, password_hash, role, created_at)
+		VALUES ($1, $2, $3, $4)`
+
+	id, err := r.db.Insert(ctx, query, user.Username, user.PasswordHash, user.Role, user.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	user.ID = id
+
+	return nil
+}
+
+// FindByUsername finds a user by username, returns nil if not found
+func (r *userRepository) FindByUsername(ctx context.Context, username string) (*entity.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE username = $1`
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, username))
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	return user, nil
+}
+
+// FindAll lists all users
+func (r *userRepository) FindAll(ctx context.Context) ([]entity.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users ORDER BY username`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []entity.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, *user)
+	}
+
+	return users, nil
+}
+
+// Count returns the number of users
+func (r *userRepository) Count(ctx context.Context) (int, error) {
+	var count int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count, nil
+}
+
+// UpdateLastLogin updates the last login timestamp
+func (r *userRepository) UpdateLastLogin(ctx context.Context, id int64, loginAt time.Time) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE users SET last_login_at = $1 WHERE id = $2`, loginAt, id); err != nil {
+		return fmt.Errorf("failed to update user last login: %w", err)
+	}
+	return nil
+}
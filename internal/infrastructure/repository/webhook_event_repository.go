@@ -0,0 +1,378 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/database"
+	"mekari-esign/internal/timeutil"
+)
+
+// WebhookEventRepository interface for persisted webhook events
+type WebhookEventRepository interface {
+	// Save stores a new event. Returns false if an event with the same key already exists.
+	Save(ctx context.Context, event *entity.WebhookEvent) (bool, error)
+	// ClaimNext marks the oldest pending (or retryable failed) event as processing by instance and
+	// returns it. Failed events are retried from their next_attempt_at. Events of a document being
+	// processed or waiting for a retry wait for it; events claimed more than claimTimeout ago, by an
+	// instance that stopped, are claimed again. Returns nil when there is nothing to process.
+	ClaimNext(ctx context.Context, instance string, maxAttempts int, claimTimeout time.Duration) (*entity.WebhookEvent, error)
+	// MarkProcessed marks an event as successfully processed
+	MarkProcessed(ctx context.Context, id int64) error
+	// MarkFailed records a failed processing attempt; the event is retried from nextAttemptAt
+	MarkFailed(ctx context.Context, id int64, errMsg string, nextAttemptAt time.Time) error
+	// ResetProcessing returns the events instance left in processing (e.g. after a crash) to
+	// pending, with those claimed before instances were recorded
+	ResetProcessing(ctx context.Context, instance string) (int64, error)
+	// FindAll lists events, newest first, optionally filtered by status
+	FindAll(ctx context.Context, status string, limit int) ([]entity.WebhookEvent, error)
+	// FindLatestByDocumentID returns the most recent event of a document, or nil
+	FindLatestByDocumentID(ctx context.Context, documentID string) (*entity.WebhookEvent, error)
+	// FindByDocument lists the events of a document, or processed under one of requestIDs
+	// (stamped copies are reported with their own document ID), oldest first
+	FindByDocument(ctx context.Context, documentID string, requestIDs []string) ([]entity.WebhookEvent, error)
+	// Requeue resets a processed or failed event to pending so the worker picks it up again
+	Requeue(ctx context.Context, id int64) error
+	// CountByStatus returns the number of events per processing status
+	CountByStatus(ctx context.Context) (map[string]int, error)
+	// LastReceivedAt returns when the newest event was received, or nil when there is none
+	LastReceivedAt(ctx context.Context) (*time.Time, error)
+}
+
+type webhookEventRepository struct {
+	db     *database.Database
+	logger *zap.Logger
+}
+
+// NewWebhookEventRepository creates a new webhook event repository
+func NewWebhookEventRepository(db *database.Database, logger *zap.Logger) WebhookEventRepository {
+	return &webhookEventRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Save inserts the event, ignoring duplicates by event_key
+func (r *webhookEventRepository) Save(ctx context.Context, event *entity.WebhookEvent) (bool, error) {
+	query := `
+		INSERT INTO webhook_events (event_key, document_id, signing_status, stamping_status, payload, status, request_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	id, inserted, err := r.db.InsertIgnore(ctx, query, "event_key",
+		event.EventKey,
+		event.DocumentID,
+		event.SigningStatus,
+		event.StampingStatus,
+		event.Payload,
+		entity.WebhookEventPending,
+		event.RequestID,
+		event.CreatedAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to save webhook event: %w", err)
+	}
+	if !inserted {
+		return false, nil // Duplicate event, already stored
+	}
+
+	event.ID = id
+	event.Status = entity.WebhookEventPending
+	return true, nil
+}
+
+// ClaimNext claims the oldest processable event, skipping events locked by other workers and
+// documents with an event in processing, so each document is processed by one worker at a time.
+// Failed events wait for next_attempt_at, and later events of their document wait for them.
+func (r *webhookEventRepository) ClaimNext(ctx context.Context, instance string, maxAttempts int, claimTimeout time.Duration) (*entity.WebhookEvent, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := timeutil.Now()
+	staleBefore := now.Add(-claimTimeout)
+
+	from, suffix := r.db.Dialect.SkipLocked("webhook_events")
+	query := `
+		SELECT id FROM ` + from + `
+		WHERE (status = $1 OR (status = $2 AND attempts < $3 AND (next_attempt_at IS NULL OR next_attempt_at <= $6)) OR (status = $4 AND claimed_at < $5))
+		AND NOT EXISTS (
+			SELECT 1 FROM webhook_events other
+			WHERE other.document_id = webhook_events.document_id AND other.id <> webhook_events.id
+			AND other.status = $4 AND (other.claimed_at IS NULL OR other.claimed_at >= $5)
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM webhook_events earlier
+			WHERE earlier.document_id = webhook_events.document_id AND earlier.id < webhook_events.id
+			AND earlier.status = $2 AND earlier.attempts < $3
+		)
+		ORDER BY id
+		` + r.db.Dialect.Limit("1", "") + `
+		` + suffix
+
+	var id int64
+	err = tx.QueryRowContext(ctx, query,
+		entity.WebhookEventPending,
+		entity.WebhookEventFailed,
+		maxAttempts,
+		entity.WebhookEventProcessing,
+		staleBefore,
+		now,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil // Nothing to process
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook event: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE webhook_events SET status = $1, attempts = attempts + 1, claimed_by = $2, claimed_at = $3 WHERE id = $4`,
+		entity.WebhookEventProcessing, instance, now, id); err != nil {
+		return nil, fmt.Errorf("failed to claim webhook event: %w", err)
+	}
+
+	event, err := scanWebhookEvent(tx.QueryRowContext(ctx, `
+		SELECT id, event_key, document_id, signing_status, stamping_status, payload, status, attempts, last_error, request_id, claimed_by, next_attempt_at, created_at, processed_at
+		FROM webhook_events
+		WHERE id = $1
+	`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook event: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit webhook event claim: %w", err)
+	}
+
+	return event, nil
+}
+
+func scanWebhookEvent(scanner interface{ Scan(...interface{}) error }) (*entity.WebhookEvent, error) {
+	var event entity.WebhookEvent
+	var nextAttemptAt, processedAt sql.NullTime
+
+	err := scanner.Scan(
+		&event.ID,
+		&event.EventKey,
+		&event.DocumentID,
+		&event.SigningStatus,
+		&event.StampingStatus,
+		&event.Payload,
+		&event.Status,
+		&event.Attempts,
+		&event.LastError,
+		&event.RequestID,
+		&event.ClaimedBy,
+		&nextAttemptAt,
+		&event.CreatedAt,
+		&processedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if nextAttemptAt.Valid {
+		event.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if processedAt.Valid {
+		event.ProcessedAt = &processedAt.Time
+	}
+
+	return &event, nil
+}
+
+// MarkProcessed marks an event as processed
+func (r *webhookEventRepository) MarkProcessed(ctx context.Context, id int64) error {
+	query := `
+		UPDATE webhook_events
+		SET status = $1, last_error = '', processed_at = $2
+		WHERE id = $3
+	`
+
+	_, err := r.db.ExecContext(ctx, query, entity.WebhookEventProcessed, timeutil.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook event processed: %w", err)
+	}
+
+	return nil
+}
+
+// MarkFailed marks an event as failed with the last error message and when to retry it
+func (r *webhookEventRepository) MarkFailed(ctx context.Context, id int64, errMsg string, nextAttemptAt time.Time) error {
+	query := `
+		UPDATE webhook_events
+		SET status = $1, last_error = $2, next_attempt_at = $3
+		WHERE id = $4
+	`
+
+	_, err := r.db.ExecContext(ctx, query, entity.WebhookEventFailed, errMsg, nextAttemptAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook event failed: %w", err)
+	}
+
+	return nil
+}
+
+// ResetProcessing resets the events an instance left in processing state back to pending
+func (r *webhookEventRepository) ResetProcessing(ctx context.Context, instance string) (int64, error) {
+	query := `UPDATE webhook_events SET status = $1 WHERE status = $2 AND (claimed_by = $3 OR claimed_by = '')`
+
+	result, err := r.db.ExecContext(ctx, query, entity.WebhookEventPending, entity.WebhookEventProcessing, instance)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reset processing webhook events: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// FindAll lists webhook events, newest first
+func (r *webhookEventRepository) FindAll(ctx context.Context, status string, limit int) ([]entity.WebhookEvent, error) {
+	query := `
+		SELECT id, event_key, document_id, signing_status, stamping_status, payload, status, attempts, last_error, request_id, claimed_by, next_attempt_at, created_at, processed_at
+		FROM webhook_events
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY id DESC
+	` + r.db.Dialect.Limit("$2", "")
+
+	rows, err := r.db.QueryContext(ctx, query, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []entity.WebhookEvent{}
+	for rows.Next() {
+		event, err := scanWebhookEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook event: %w", err)
+		}
+		events = append(events, *event)
+	}
+
+	return events, nil
+}
+
+// Requeue resets an event to pending with a fresh attempt counter
+func (r *webhookEventRepository) Requeue(ctx context.Context, id int64) error {
+	query := `
+		UPDATE webhook_events
+		SET status = $1, attempts = 0, last_error = '', next_attempt_at = NULL, processed_at = NULL
+		WHERE id = $2 AND status <> $3
+	`
+
+	result, err := r.db.ExecContext(ctx, query, entity.WebhookEventPending, id, entity.WebhookEventProcessing)
+	if err != nil {
+		return fmt.Errorf("failed to requeue webhook event: %w", err)
+	}
+
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return fmt.Errorf("webhook event %d not found or currently processing", id)
+	}
+
+	return nil
+}
+
+// FindLatestByDocumentID finds the newest event of a document
+func (r *webhookEventRepository) FindLatestByDocumentID(ctx context.Context, documentID string) (*entity.WebhookEvent, error) {
+	query := `
+		SELECT id, event_key, document_id, signing_status, stamping_status, payload, status, attempts, last_error, request_id, claimed_by, next_attempt_at, created_at, processed_at
+		FROM webhook_events
+		WHERE document_id = $1
+		ORDER BY id DESC
+	` + r.db.Dialect.Limit("1", "")
+
+	event, err := scanWebhookEvent(r.db.QueryRowContext(ctx, query, documentID))
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find webhook event: %w", err)
+	}
+
+	return event, nil
+}
+
+// FindByDocument finds the events of a document, including those matched by request ID
+func (r *webhookEventRepository) FindByDocument(ctx context.Context, documentID string, requestIDs []string) ([]entity.WebhookEvent, error) {
+	query := `
+		SELECT id, event_key, document_id, signing_status, stamping_status, payload, status, attempts, last_error, request_id, claimed_by, next_attempt_at, created_at, processed_at
+		FROM webhook_events
+		WHERE document_id = $1`
+	args := []interface{}{documentID}
+	if len(requestIDs) > 0 {
+		query += " OR request_id IN (" + database.Placeholders(2, len(requestIDs)) + ")"
+		for _, requestID := range requestIDs {
+			args = append(args, requestID)
+		}
+	}
+	query += " ORDER BY id"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []entity.WebhookEvent{}
+	for rows.Next() {
+		event, err := scanWebhookEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook event: %w", err)
+		}
+		events = append(events, *event)
+	}
+
+	return events, nil
+}
+
+// CountByStatus counts events grouped by status
+func (r *webhookEventRepository) CountByStatus(ctx context.Context) (map[string]int, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT status, COUNT(*) FROM webhook_events GROUP BY status`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count webhook events: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int{
+		entity.WebhookEventPending:    0,
+		entity.WebhookEventProcessing: 0,
+		entity.WebhookEventProcessed:  0,
+		entity.WebhookEventFailed:     0,
+	}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook event count: %w", err)
+		}
+		counts[status] = count
+	}
+
+	return counts, rows.Err()
+}
+
+func (r *webhookEventRepository) LastReceivedAt(ctx context.Context) (*time.Time, error) {
+	query := `
+		SELECT created_at
+		FROM webhook_events
+		ORDER BY id DESC
+	` + r.db.Dialect.Limit("1", "")
+
+	var at time.Time
+	err := r.db.QueryRowContext(ctx, query).Scan(&at)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last webhook event: %w", err)
+	}
+
+	return &at, nil
+}
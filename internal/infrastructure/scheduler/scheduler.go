@@ -0,0 +1,312 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/infrastructure/redis"
+	"mekari-esign/internal/timeutil"
+)
+
+var (
+	// ErrJobNotFound is returned for a job name no module registered
+	ErrJobNotFound = errors.New("scheduled job not found")
+	// ErrJobRunning is returned when a job is started while its previous run is in progress
+	ErrJobRunning = errors.New("job is already running")
+)
+
+// Job is a recurring job hosted by the scheduler
+type Job struct {
+	Name        string
+	Description string
+	// Every is the interval of the job's own setting, e.g. retention.interval. The job runs at
+	// startup and then every interval, unless scheduler.jobs.<name>.schedule sets a cron expression.
+	Every time.Duration
+	// Enabled is the default of scheduler.jobs.<name>.enabled, e.g. retention.enabled
+	Enabled bool
+	// Cluster jobs are run by one of the instances running side by side (see redis.Leases.Claim)
+	Cluster bool
+	Run     func(ctx context.Context) error
+}
+
+// Scheduler runs the recurring jobs registered by other modules on their schedules, in
+// app.timezone, and keeps the outcome of their last run. A job never runs twice at once; a run
+// due while the previous one is in progress is skipped.
+type Scheduler struct {
+	config   *config.Config
+	leases   *redis.Leases
+	reporter *logger.Reporter
+	logger   *zap.Logger
+	cron     *cron.Cron
+
+	// ctx is handed to job runs and cancelled on stop
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu   sync.Mutex
+	jobs map[string]*entry
+}
+
+// entry is a registered job with its state
+type entry struct {
+	job      Job
+	spec     string
+	interval bool // spec is the job's own interval, so it also runs at startup
+	schedule cron.Schedule
+	enabled  bool
+	id       cron.EntryID
+	order    int
+
+	// Guarded by Scheduler.mu
+	running  bool
+	runs     int
+	failures int
+	lastRun  *entity.JobRun
+}
+
+func NewScheduler(
+	lc fx.Lifecycle,
+	cfg *config.Config,
+	leases *redis.Leases,
+	reporter *logger.Reporter,
+	logger *zap.Logger,
+) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		config:   cfg,
+		leases:   leases,
+		reporter: reporter,
+		logger:   logger.Named("scheduler"),
+		cron:     cron.New(cron.WithLocation(cfg.App.Location())),
+		ctx:      ctx,
+		cancel:   cancel,
+		jobs:     make(map[string]*entry),
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			s.start()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			s.stop()
+			return nil
+		},
+	})
+
+	return s
+}
+
+// Register adds a job, applying its scheduler.jobs settings. Modules register their jobs while
+// the service is built, before it starts.
+func (s *Scheduler) Register(job Job) error {
+	e := &entry{
+		job:      job,
+		spec:     "@every " + formatEvery(job.Every),
+		interval: true,
+		enabled:  job.Enabled,
+	}
+	if override, ok := s.config.Scheduler.Jobs[job.Name]; ok {
+		if override.Schedule != "" {
+			e.spec = override.Schedule
+			e.interval = false
+		}
+		if override.Enabled != nil {
+			e.enabled = *override.Enabled
+		}
+	}
+
+	if e.interval && job.Every <= 0 {
+		return fmt.Errorf("job %s has no interval and no schedule", job.Name)
+	}
+	schedule, err := cron.ParseStandard(e.spec)
+	if err != nil {
+		return fmt.Errorf("invalid schedule %q of job %s: %w", e.spec, job.Name, err)
+	}
+	e.schedule = schedule
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[job.Name]; ok {
+		return fmt.Errorf("job %s is already registered", job.Name)
+	}
+	e.order = len(s.jobs)
+	s.jobs[job.Name] = e
+
+	if e.enabled {
+		e.id = s.cron.Schedule(schedule, cron.FuncJob(func() {
+			if s.begin(e) {
+				s.execute(e, entity.JobTriggerSchedule)
+			}
+		}))
+	}
+	return nil
+}
+
+// Jobs returns the registered jobs in registration order
+func (s *Scheduler) Jobs() []entity.ScheduledJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]*entry, 0, len(s.jobs))
+	for _, e := range s.jobs {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].order < entries[j].order })
+
+	jobs := make([]entity.ScheduledJob, len(entries))
+	for i, e := range entries {
+		jobs[i] = entity.ScheduledJob{
+			Name:        e.job.Name,
+			Description: e.job.Description,
+			Enabled:     e.enabled,
+			Schedule:    e.spec,
+			Cluster:     e.job.Cluster,
+			Running:     e.running,
+			Runs:        e.runs,
+			Failures:    e.failures,
+			LastRun:     e.lastRun,
+		}
+		if e.enabled {
+			if next := s.cron.Entry(e.id).Next; !next.IsZero() {
+				next = next.UTC()
+				jobs[i].NextRunAt = &next
+			}
+		}
+	}
+	return jobs
+}
+
+// RunNow starts a run of the job name in the background, also when it is disabled and without
+// claiming the run from other instances
+func (s *Scheduler) RunNow(name string) error {
+	s.mu.Lock()
+	e, ok := s.jobs[name]
+	s.mu.Unlock()
+	if !ok {
+		return ErrJobNotFound
+	}
+
+	if !s.begin(e) {
+		return ErrJobRunning
+	}
+	go s.execute(e, entity.JobTriggerManual)
+	return nil
+}
+
+func (s *Scheduler) start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name := range s.config.Scheduler.Jobs {
+		if _, ok := s.jobs[name]; !ok {
+			s.logger.Warn("Ignoring settings of unknown job", zap.String("job", name))
+		}
+	}
+
+	s.cron.Start()
+
+	enabled := 0
+	for _, e := range s.jobs {
+		if !e.enabled {
+			continue
+		}
+		enabled++
+		if e.interval && !e.running {
+			e.running = true
+			s.wg.Add(1)
+			go s.execute(e, entity.JobTriggerStartup)
+		}
+	}
+
+	s.logger.Info("Scheduler started", zap.Int("jobs", enabled), zap.Int("disabled", len(s.jobs)-enabled))
+}
+
+// stop cancels the running jobs and waits for them to return
+func (s *Scheduler) stop() {
+	s.mu.Lock()
+	s.cancel()
+	s.mu.Unlock()
+
+	s.cron.Stop()
+	s.wg.Wait()
+}
+
+// begin marks e running, returning false when it already runs or the scheduler stopped
+func (s *Scheduler) begin(e *entry) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e.running || s.ctx.Err() != nil {
+		if e.running {
+			s.logger.Debug("Skipping run of job still running", zap.String("job", e.job.Name))
+		}
+		return false
+	}
+	e.running = true
+	s.wg.Add(1)
+	return true
+}
+
+// execute runs e, begun with begin, and records the outcome
+func (s *Scheduler) execute(e *entry, trigger string) {
+	defer s.wg.Done()
+	defer s.reporter.Repanic(s.ctx)
+
+	log := s.logger.With(zap.String("job", e.job.Name), zap.String("trigger", trigger))
+	run := &entity.JobRun{StartedAt: timeutil.Now(), Trigger: trigger}
+
+	started := time.Now()
+	if e.job.Cluster && trigger != entity.JobTriggerManual && !s.leases.Claim(s.ctx, e.job.Name, e.schedule.Next(started).Sub(started)) {
+		run.Result = entity.JobResultSkipped
+	} else if err := e.job.Run(s.ctx); err != nil {
+		run.Result = entity.JobResultFailed
+		run.Error = err.Error()
+		if s.ctx.Err() == nil {
+			log.Error("Scheduled job failed", zap.Error(err))
+		}
+	} else {
+		run.Result = entity.JobResultSuccess
+	}
+	run.FinishedAt = timeutil.Now()
+	run.Duration = time.Since(started).Round(time.Millisecond).String()
+
+	log.Debug("Scheduled job finished", zap.String("result", run.Result), zap.String("duration", run.Duration))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e.running = false
+	e.lastRun = run
+	if run.Result != entity.JobResultSkipped {
+		e.runs++
+	}
+	if run.Result == entity.JobResultFailed {
+		e.failures++
+	}
+}
+
+// formatEvery formats d for an @every schedule, e.g. 24h instead of 24h0m0s
+func formatEvery(d time.Duration) string {
+	switch {
+	case d >= time.Hour && d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d >= time.Minute && d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return d.String()
+	}
+}
@@ -0,0 +1,170 @@
+package shutdown
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/infrastructure/database"
+	"mekari-esign/internal/infrastructure/redis"
+)
+
+// StopTimeout is the upper bound for all fx stop hooks together. Draining is bounded by
+// app.shutdown_timeout, which should stay well below this.
+const StopTimeout = 2 * time.Minute
+
+// Coordinator orders the shutdown: the server stops intake, background work started through Go
+// and the queue workers get until the drain deadline to finish, then DB and Redis are closed
+type Coordinator struct {
+	config *config.Config
+	db     *database.Database
+	redis  *redis.RedisClient
+	logger *zap.Logger
+
+	// workCtx is handed to in-flight work and cancelled when the drain deadline passes
+	workCtx    context.Context
+	cancelWork context.CancelFunc
+
+	mu       sync.Mutex
+	active   int
+	closed   bool
+	draining bool
+	drained  chan struct{}
+	deadline time.Time
+}
+
+func NewCoordinator(
+	lc fx.Lifecycle,
+	cfg *config.Config,
+	db *database.Database,
+	redisClient *redis.RedisClient,
+	logger *zap.Logger,
+) *Coordinator {
+	workCtx, cancelWork := context.WithCancel(context.Background())
+	c := &Coordinator{
+		config:     cfg,
+		db:         db,
+		redis:      redisClient,
+		logger:     logger,
+		workCtx:    workCtx,
+		cancelWork: cancelWork,
+		drained:    make(chan struct{}),
+	}
+
+	// Registered before the workers and the server, so this hook runs after theirs on stop
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			c.drain(ctx)
+
+			if err := redisClient.Close(); err != nil {
+				logger.Warn("Failed to close Redis", zap.Error(err))
+			}
+			if err := db.Close(); err != nil {
+				logger.Warn("Failed to close database", zap.Error(err))
+			}
+			logger.Info("Shutdown complete")
+			return nil
+		},
+	})
+
+	return c
+}
+
+// Begin starts the shutdown and fixes the drain deadline. It is safe to call more than once;
+// only the first call counts.
+func (c *Coordinator) Begin() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.deadline.IsZero() {
+		return
+	}
+
+	timeout := time.Duration(c.config.App.ShutdownTimeout) * time.Second
+	c.deadline = time.Now().Add(timeout)
+	time.AfterFunc(timeout, c.cancelWork)
+
+	c.logger.Info("Shutdown started, draining in-flight work", zap.Duration("timeout", timeout))
+}
+
+// Deadline returns ctx bounded by the drain deadline (starting the shutdown if needed)
+func (c *Coordinator) Deadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	c.Begin()
+
+	c.mu.Lock()
+	deadline := c.deadline
+	c.mu.Unlock()
+
+	return context.WithDeadline(ctx, deadline)
+}
+
+// Context returns the context for in-flight work. It stays valid while draining and is
+// cancelled once the drain deadline passes.
+func (c *Coordinator) Context() context.Context {
+	return c.workCtx
+}
+
+// Go runs fn in the background and waits for it on shutdown. Returns false, without running
+// fn, once draining has finished.
+func (c *Coordinator) Go(name string, fn func(ctx context.Context)) bool {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		c.logger.Warn("Background task rejected, service is shutting down", zap.String("task", name))
+		return false
+	}
+	c.active++
+	c.mu.Unlock()
+
+	go func() {
+		defer c.done()
+		fn(c.workCtx)
+	}()
+
+	return true
+}
+
+func (c *Coordinator) done() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.active--
+	if c.draining && c.active == 0 {
+		close(c.drained)
+	}
+}
+
+// drain waits for tasks started through Go until they finish or the deadline passes
+func (c *Coordinator) drain(ctx context.Context) {
+	ctx, cancel := c.Deadline(ctx)
+	defer cancel()
+
+	c.mu.Lock()
+	c.draining = true
+	active := c.active
+	if active == 0 {
+		close(c.drained)
+	}
+	c.mu.Unlock()
+
+	if active > 0 {
+		c.logger.Info("Waiting for background tasks", zap.Int("count", active))
+	}
+
+	select {
+	case <-c.drained:
+	case <-ctx.Done():
+		c.mu.Lock()
+		c.logger.Warn("Shutdown deadline reached, abandoning background tasks", zap.Int("count", c.active))
+		c.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	c.cancelWork()
+}
@@ -0,0 +1,7 @@
+package shutdown
+
+import "go.uber.org/fx"
+
+var Module = fx.Module("shutdown",
+	fx.Provide(NewCoordinator),
+)
@@ -0,0 +1,58 @@
+// Package startup waits for the dependencies the service needs to start. When the service
+// starts on boot, the database and Redis on the same machine or a network share with the
+// document folders are often not available yet.
+package startup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+)
+
+// attemptTimeout bounds each check, so an unreachable host does not use up the wait
+const attemptTimeout = 10 * time.Second
+
+// NoWait turns off startup.wait for the command line modes, which report an unavailable
+// dependency at once instead of waiting for it
+var NoWait = fx.Decorate(func(cfg *config.Config) *config.Config {
+	cfg.Startup.Wait = false
+	return cfg
+})
+
+// Wait runs check until it succeeds. With startup.wait it retries every startup.retry_interval
+// for up to startup.wait_timeout, logging each failure, and then returns the last error.
+func Wait(cfg *config.Config, logger *zap.Logger, name string, check func(ctx context.Context) error) error {
+	deadline := time.Now().Add(time.Duration(cfg.Startup.WaitTimeout) * time.Second)
+	interval := time.Duration(cfg.Startup.RetryInterval) * time.Second
+
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), attemptTimeout)
+		err := check(ctx)
+		cancel()
+		if err == nil {
+			if attempt > 1 {
+				logger.Info("Startup dependency available", zap.String("dependency", name), zap.Int("attempts", attempt))
+			}
+			return nil
+		}
+		if !cfg.Startup.Wait {
+			return err
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("%w (still failing after waiting %ds for %s)", err, cfg.Startup.WaitTimeout, name)
+		}
+
+		logger.Warn("Startup dependency not available yet, retrying",
+			zap.String("dependency", name),
+			zap.Int("attempt", attempt),
+			zap.Duration("retry_in", interval),
+			zap.Error(err),
+		)
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,179 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const (
+	postgresImage    = "postgres:16-alpine"
+	postgresUser     = "esign"
+	postgresPassword = "esign"
+
+	redisImage = "redis:7-alpine"
+
+	// containerStartup is how long a container may take to accept connections
+	containerStartup = 2 * time.Minute
+)
+
+// container is a Docker container started for the suite
+type container struct {
+	id   string
+	host string
+	port int
+}
+
+// postgresContainer and redisContainer are shared by the tests; every test uses its own database
+// and Redis key prefix
+var (
+	postgresContainer *container
+	redisContainer    *container
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	var err error
+	postgresContainer, err = startContainer(postgresImage, "5432",
+		"-e", "POSTGRES_USER="+postgresUser,
+		"-e", "POSTGRES_PASSWORD="+postgresPassword,
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start PostgreSQL: %v\n", err)
+		return 1
+	}
+	defer postgresContainer.remove()
+
+	redisContainer, err = startContainer(redisImage, "6379")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start Redis: %v\n", err)
+		return 1
+	}
+	defer redisContainer.remove()
+
+	if err := waitFor(postgresContainer.ping); err != nil {
+		fmt.Fprintf(os.Stderr, "PostgreSQL not ready: %v\n", err)
+		return 1
+	}
+	if err := waitFor(redisContainer.pingRedis); err != nil {
+		fmt.Fprintf(os.Stderr, "Redis not ready: %v\n", err)
+		return 1
+	}
+
+	return m.Run()
+}
+
+// startContainer runs image in the background with its port published on a free local port
+func startContainer(image, port string, args ...string) (*container, error) {
+	runArgs := append([]string{"run", "--detach", "--rm", "--publish", "127.0.0.1::" + port}, args...)
+	out, err := docker(append(runArgs, image)...)
+	if err != nil {
+		return nil, err
+	}
+	c := &container{id: out}
+
+	out, err = docker("port", c.id, port+"/tcp")
+	if err != nil {
+		c.remove()
+		return nil, err
+	}
+	// One line per published address, e.g. 127.0.0.1:49153
+	host, hostPort, err := net.SplitHostPort(strings.Split(out, "\n")[0])
+	if err != nil {
+		c.remove()
+		return nil, fmt.Errorf("unexpected published port %q of %s: %w", out, image, err)
+	}
+	c.host = host
+	c.port, err = strconv.Atoi(hostPort)
+	if err != nil {
+		c.remove()
+		return nil, fmt.Errorf("unexpected published port %q of %s: %w", out, image, err)
+	}
+	return c, nil
+}
+
+func (c *container) remove() {
+	docker("rm", "--force", c.id)
+}
+
+// ping connects to the default database of the PostgreSQL container
+func (c *container) ping(ctx context.Context) error {
+	db, err := sql.Open("postgres", c.dsn("postgres"))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.PingContext(ctx)
+}
+
+func (c *container) pingRedis(ctx context.Context) error {
+	client := goredis.NewClient(&goredis.Options{Addr: net.JoinHostPort(c.host, strconv.Itoa(c.port))})
+	defer client.Close()
+	return client.Ping(ctx).Err()
+}
+
+func (c *container) dsn(dbName string) string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+		c.host, c.port, postgresUser, postgresPassword, dbName)
+}
+
+// createDatabase creates an empty database for a test; the service migrates it on start
+func createDatabase(t *testing.T, name string) {
+	t.Helper()
+
+	db, err := sql.Open("postgres", postgresContainer.dsn("postgres"))
+	if err != nil {
+		t.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("CREATE DATABASE " + name); err != nil {
+		t.Fatalf("Failed to create database %s: %v", name, err)
+	}
+}
+
+// waitFor calls ready until it succeeds or containerStartup passes
+func waitFor(ready func(ctx context.Context) error) error {
+	deadline := time.Now().Add(containerStartup)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := ready(ctx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// docker runs the docker CLI and returns its trimmed output
+func docker(args ...string) (string, error) {
+	out, err := exec.Command("docker", args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("docker %s: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("docker %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,13 @@
+// Package integration holds the end-to-end tests of the service, built with the integration tag:
+//
+//	go test -tags integration ./internal/integration/
+//
+// The tests start PostgreSQL and Redis in Docker containers, replace Mekari and NAV with local
+// httptest stubs and run the service in-process. Each test sends a document through the same
+// path as production: POST /api/v1/esign/documents/request-sign, the Mekari callbacks on
+// POST /webhook/mekari, the webhook_events queue and worker, e-meterai stamping, the finish
+// folder and the NAV log entry. Failed callbacks are checked to be retried after
+// webhook.retry_backoff and given up after webhook.max_attempts.
+//
+// Docker must be running; the containers are removed when the tests finish.
+package integration
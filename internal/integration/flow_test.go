@@ -0,0 +1,239 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/repository"
+)
+
+// TestSignWebhookStampNAV sends a document through the whole flow: the sign request moves it to
+// progress, the signing completed callback requests e-meterai stamping, and the stamping success
+// callback saves the final document and audit trail to finish and completes the NAV log entry.
+func TestSignWebhookStampNAV(t *testing.T) {
+	s := startService(t, nil)
+	const invoice, entryNo = "INV-IT-0001", 1001
+	filename := s.putReady(invoice)
+
+	status, result := s.requestSign(invoice, entryNo)
+	if status != http.StatusCreated || result.Data == nil {
+		t.Fatalf("Sign request answered with status %d (%+v), expected %d with the document", status, result, http.StatusCreated)
+	}
+	documentID := result.Data.ID
+
+	signs, _, _ := s.mekari.received()
+	if len(signs) != 1 || signs[0].Filename != filename || signs[0].EntryNo != entryNo {
+		t.Fatalf("Mekari received %d sign request(s), expected one for %s", len(signs), filename)
+	}
+	if signs[0].CallbackURL != s.url+"/webhook/mekari" {
+		t.Errorf("Sign request callback is %q, expected the webhook of the service", signs[0].CallbackURL)
+	}
+	if s.exists(readyFolder, filename) || !s.exists(progressFolder, filename) {
+		t.Fatalf("%s was not moved from ready to progress", filename)
+	}
+	mapping, err := s.deps.Mappings.FindByEntryNo(context.Background(), entryNo)
+	if err != nil || mapping.DocumentID != documentID {
+		t.Fatalf("Entry %d is not mapped to %s: %+v, %v", entryNo, documentID, mapping, err)
+	}
+
+	// Signing completed: the signed document replaces the one in progress and is sent for stamping
+	s.sendWebhook(documentID, filename, "none")
+	event := s.waitForEvent(documentID, entity.WebhookEventProcessed)
+	if event.Attempts != 1 {
+		t.Errorf("Signing callback took %d attempts, expected 1", event.Attempts)
+	}
+
+	_, stamps, _ := s.mekari.received()
+	if len(stamps) != 1 || stamps[0].Filename != filename {
+		t.Fatalf("Mekari received %d stamp request(s), expected one for %s", len(stamps), filename)
+	}
+	if len(stamps[0].Annotations) != 1 || stamps[0].Annotations[0].TypeOf != "meterai" {
+		t.Errorf("Stamp request has annotations %+v, expected the e-meterai of the sign request", stamps[0].Annotations)
+	}
+	entries := s.nav.logEntries(entryNo)
+	if len(entries) == 0 || entries[len(entries)-1].SigningStatus != entity.MapSigningStatus(entity.SigningStatusCompleted) {
+		t.Fatalf("NAV log entry %d was not updated to signed: %+v", entryNo, entries)
+	}
+
+	// Stamping success on the stamp document: the final document goes to finish
+	stampID := s.mekari.stampDocuments()[0]
+	s.sendWebhook(stampID, filename, "success")
+	s.waitForEvent(stampID, entity.WebhookEventProcessed)
+
+	if !s.exists(finishFolder, filename) || s.exists(progressFolder, filename) {
+		t.Errorf("%s was not moved from progress to finish", filename)
+	}
+	auditTrail := strings.TrimSuffix(filename, ".pdf") + "_audit_trail.pdf"
+	if !s.exists(finishFolder, auditTrail) {
+		t.Errorf("Audit trail %s was not saved to finish", auditTrail)
+	}
+
+	entries = s.nav.logEntries(entryNo)
+	last := entries[len(entries)-1]
+	if last.StampingStatus != entity.MapStampingStatus("success") {
+		t.Errorf("Last NAV log entry %d has stamping status %q, expected %q", entryNo, last.StampingStatus, entity.MapStampingStatus("success"))
+	}
+	if last.FilePathIn != s.folder(finishFolder) {
+		t.Errorf("Last NAV log entry %d has File_Path_In %q, expected the finish folder", entryNo, last.FilePathIn)
+	}
+
+	assertEvents(t, s.documentEvents(documentID),
+		entity.DocumentEventSubmitted,
+		entity.DocumentEventStampRequested,
+		entity.DocumentEventStamped,
+		entity.DocumentEventSavedToFinish,
+	)
+	if _, err := s.deps.Mappings.FindByEntryNo(context.Background(), entryNo); !errors.Is(err, repository.ErrMappingNotFound) {
+		t.Errorf("Entry %d is still mapped after the document was finished (%v)", entryNo, err)
+	}
+}
+
+// TestSignRequestFailure checks that a sign request Mekari rejects leaves the document in ready,
+// unmapped and unreported, so NAV can send it again
+func TestSignRequestFailure(t *testing.T) {
+	s := startService(t, nil)
+	const invoice, entryNo = "INV-IT-0002", 1002
+	filename := s.putReady(invoice)
+
+	s.mekari.setFailSign(true)
+	if status, _ := s.requestSign(invoice, entryNo); status != http.StatusInternalServerError {
+		t.Fatalf("Rejected sign request answered with status %d, expected %d", status, http.StatusInternalServerError)
+	}
+	if !s.exists(readyFolder, filename) || s.exists(progressFolder, filename) {
+		t.Fatalf("%s left the ready folder although the sign request failed", filename)
+	}
+	if _, err := s.deps.Mappings.FindByEntryNo(context.Background(), entryNo); !errors.Is(err, repository.ErrMappingNotFound) {
+		t.Fatalf("Entry %d was mapped although the sign request failed (%v)", entryNo, err)
+	}
+	if entries := s.nav.logEntries(entryNo); len(entries) > 0 {
+		t.Fatalf("NAV log entry %d was updated although the sign request failed: %+v", entryNo, entries)
+	}
+
+	// NAV sends the request again once Mekari is back
+	s.mekari.setFailSign(false)
+	status, result := s.requestSign(invoice, entryNo)
+	if status != http.StatusCreated || result.Data == nil {
+		t.Fatalf("Repeated sign request answered with status %d, expected %d", status, http.StatusCreated)
+	}
+	if s.exists(readyFolder, filename) || !s.exists(progressFolder, filename) {
+		t.Fatalf("%s was not moved to progress by the repeated sign request", filename)
+	}
+}
+
+// TestWebhookRetry checks that a callback failing on a Mekari outage waits for
+// webhook.retry_backoff and is then processed by the worker
+func TestWebhookRetry(t *testing.T) {
+	const retryBackoff = 2
+	s := startService(t, func(cfg *config.Config) {
+		cfg.Webhook.RetryBackoff = retryBackoff
+	})
+	const invoice, entryNo = "INV-IT-0003", 1003
+	filename := s.putReady(invoice)
+
+	status, result := s.requestSign(invoice, entryNo)
+	if status != http.StatusCreated || result.Data == nil {
+		t.Fatalf("Sign request answered with status %d, expected %d", status, http.StatusCreated)
+	}
+	documentID := result.Data.ID
+
+	s.mekari.setFailDownloads(1)
+	sentAt := time.Now()
+	s.sendWebhook(documentID, filename, "none")
+
+	failed := s.waitForEvent(documentID, entity.WebhookEventFailed)
+	if failed.Attempts != 1 || !strings.Contains(failed.LastError, "download failed") {
+		t.Fatalf("Failed callback has %d attempt(s) and error %q, expected 1 and the download error", failed.Attempts, failed.LastError)
+	}
+	if failed.NextAttemptAt == nil || failed.NextAttemptAt.Before(sentAt.Add(retryBackoff*time.Second-time.Second)) {
+		t.Fatalf("Failed callback is retried at %v, expected about %ds after it was sent at %v", failed.NextAttemptAt, retryBackoff, sentAt)
+	}
+	if _, stamps, _ := s.mekari.received(); len(stamps) > 0 {
+		t.Fatalf("Stamping was requested although the signed document was not downloaded")
+	}
+
+	processed := s.waitForEvent(documentID, entity.WebhookEventProcessed)
+	if processed.Attempts != 2 {
+		t.Errorf("Retried callback took %d attempts, expected 2", processed.Attempts)
+	}
+	if processed.ProcessedAt == nil || processed.ProcessedAt.Before(*failed.NextAttemptAt) {
+		t.Errorf("Retried callback was processed at %v, before its next attempt at %v", processed.ProcessedAt, failed.NextAttemptAt)
+	}
+	if _, stamps, _ := s.mekari.received(); len(stamps) != 1 {
+		t.Errorf("Mekari received %d stamp request(s) after the retry, expected 1", len(stamps))
+	}
+	assertEvents(t, s.documentEvents(documentID),
+		entity.DocumentEventSubmitted,
+		entity.DocumentEventError,
+		entity.DocumentEventStampRequested,
+	)
+}
+
+// TestWebhookGivesUp checks that a callback failing webhook.max_attempts times stays failed and
+// is not processed again
+func TestWebhookGivesUp(t *testing.T) {
+	const maxAttempts = 2
+	var webhook config.WebhookConfig
+	s := startService(t, func(cfg *config.Config) {
+		cfg.Webhook.MaxAttempts = maxAttempts
+		webhook = cfg.Webhook
+	})
+	const invoice, entryNo = "INV-IT-0004", 1004
+	filename := s.putReady(invoice)
+
+	status, result := s.requestSign(invoice, entryNo)
+	if status != http.StatusCreated || result.Data == nil {
+		t.Fatalf("Sign request answered with status %d, expected %d", status, http.StatusCreated)
+	}
+	documentID := result.Data.ID
+
+	s.mekari.setFailDownloads(1000)
+	s.sendWebhook(documentID, filename, "none")
+
+	eventually(t, func() bool {
+		event := s.webhookEvent(documentID)
+		return event.Status == entity.WebhookEventFailed && event.Attempts == maxAttempts
+	}, "webhook event of %s to fail %d times", documentID, maxAttempts)
+
+	// A further attempt would be due after webhook.retry_backoff doubled per earlier attempt and
+	// picked up by the next poll; until well past that the event must be left alone
+	backoff := time.Duration(webhook.RetryBackoff) * time.Second << (maxAttempts - 1)
+	poll := time.Duration(webhook.PollInterval) * time.Second
+	deadline := time.Now().Add(backoff + 2*poll)
+	for time.Now().Before(deadline) {
+		event := s.webhookEvent(documentID)
+		if event.Status != entity.WebhookEventFailed || event.Attempts != maxAttempts {
+			t.Fatalf("Given up callback is %s after %d attempts, expected failed after %d", event.Status, event.Attempts, maxAttempts)
+		}
+		time.Sleep(poll / 4)
+	}
+	if _, stamps, downloads := s.mekari.received(); downloads != maxAttempts || len(stamps) > 0 {
+		t.Fatalf("Mekari received %d download(s) and %d stamp request(s), expected %d and none", downloads, len(stamps), maxAttempts)
+	}
+	if !s.exists(progressFolder, filename) || s.exists(finishFolder, filename) {
+		t.Errorf("%s left the progress folder although its callback failed", filename)
+	}
+}
+
+// assertEvents checks that the document events include want in order
+func assertEvents(t *testing.T, events []string, want ...string) {
+	t.Helper()
+
+	rest := events
+	for _, eventType := range want {
+		i := slices.Index(rest, eventType)
+		if i < 0 {
+			t.Errorf("Document events %v do not include %v in order", events, want)
+			return
+		}
+		rest = rest[i+1:]
+	}
+}
@@ -0,0 +1,400 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/fx"
+
+	"mekari-esign/internal/config"
+	deliveryhttp "mekari-esign/internal/delivery/http"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/database"
+	"mekari-esign/internal/infrastructure/document"
+	"mekari-esign/internal/infrastructure/httpclient"
+	"mekari-esign/internal/infrastructure/i18n"
+	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/infrastructure/metrics"
+	"mekari-esign/internal/infrastructure/nav"
+	"mekari-esign/internal/infrastructure/notifier"
+	"mekari-esign/internal/infrastructure/oauth2"
+	"mekari-esign/internal/infrastructure/redis"
+	"mekari-esign/internal/infrastructure/reload"
+	"mekari-esign/internal/infrastructure/repository"
+	"mekari-esign/internal/infrastructure/scheduler"
+	"mekari-esign/internal/infrastructure/shutdown"
+	"mekari-esign/internal/server"
+	"mekari-esign/internal/usecase"
+	"mekari-esign/internal/worker"
+)
+
+const (
+	testEmail = "integration@example.com"
+
+	// Folders of a test, also reported by the NAV stub setup
+	readyFolder    = "ready"
+	progressFolder = "progress"
+	finishFolder   = "finish"
+
+	// waitTimeout bounds every wait for the webhook worker
+	waitTimeout = 30 * time.Second
+)
+
+// testPDF is served by the Mekari stub as every signed and stamped document. The fixed stamp
+// placement of the tests never parses it.
+var testPDF = []byte("%PDF-1.4\n1 0 obj<</Type/Catalog/Pages 2 0 R>>endobj\n" +
+	"2 0 obj<</Type/Pages/Kids[3 0 R]/Count 1>>endobj\n" +
+	"3 0 obj<</Type/Page/Parent 2 0 R/MediaBox[0 0 595 842]>>endobj\n" +
+	"trailer<</Root 1 0 R>>\n%%EOF\n")
+
+// deps are the components the tests check the service state with
+type deps struct {
+	fx.In
+
+	Events         repository.WebhookEventRepository
+	DocumentEvents repository.DocumentEventRepository
+	Mappings       repository.MappingRepository
+}
+
+// service is the service of a test, running in-process against the containers and stubs
+type service struct {
+	t      *testing.T
+	url    string
+	dir    string
+	mekari *mekariStub
+	nav    *navStub
+	deps   deps
+	client *http.Client
+}
+
+// startService starts the service with its own database, Redis key prefix, folders and stubs.
+// configure, when set, changes the configuration further. The service is stopped when the test
+// finishes.
+func startService(t *testing.T, configure func(cfg *config.Config)) *service {
+	t.Helper()
+
+	name := testName(t)
+	createDatabase(t, name)
+
+	s := &service{
+		t:      t,
+		dir:    t.TempDir(),
+		client: &http.Client{Timeout: time.Minute},
+	}
+	for _, folder := range []string{readyFolder, progressFolder, finishFolder} {
+		if err := os.MkdirAll(s.folder(folder), 0755); err != nil {
+			t.Fatalf("Failed to create the %s folder: %v", folder, err)
+		}
+	}
+
+	s.mekari = newMekariStub(testPDF)
+	t.Cleanup(s.mekari.server.Close)
+	s.nav = newNAVStub(entity.NAVSetup{
+		FileLocationOut:     s.folder(readyFolder),
+		FileLocationProcess: s.folder(progressFolder),
+		FileLocationIn:      s.folder(finishFolder),
+	})
+	t.Cleanup(s.nav.server.Close)
+
+	port, err := freePort()
+	if err != nil {
+		t.Fatalf("No free port for the service: %v", err)
+	}
+	s.url = fmt.Sprintf("http://127.0.0.1:%d", port)
+
+	decorate := func(cfg *config.Config) *config.Config {
+		cfg.App.Host = "127.0.0.1"
+		cfg.App.Port = port
+		cfg.App.Listen = nil
+		cfg.App.BaseURL = s.url
+		cfg.App.WatchConfig = false
+
+		cfg.Database.Driver = "postgres"
+		cfg.Database.Host = postgresContainer.host
+		cfg.Database.Port = postgresContainer.port
+		cfg.Database.User = postgresUser
+		cfg.Database.Password = postgresPassword
+		cfg.Database.DBName = name
+		cfg.Database.SSLMode = "disable"
+		cfg.Database.ReplicaDSN = ""
+
+		cfg.Redis.Host = redisContainer.host
+		cfg.Redis.Port = redisContainer.port
+		cfg.Redis.Username = ""
+		cfg.Redis.Password = ""
+		cfg.Redis.TLS = config.RedisTLSConfig{}
+		cfg.Redis.KeyPrefix = name + ":"
+
+		cfg.Mekari.Mock = false
+		cfg.Mekari.AuthType = config.AuthTypeHMAC
+		cfg.Mekari.HMAC = config.HMACCredentials{ClientID: "integration", ClientSecret: "integration"}
+		cfg.Mekari.BaseURL = s.mekari.server.URL
+		cfg.Mekari.SsoBaseURL = s.mekari.server.URL
+		cfg.Mekari.AuthURL = s.mekari.server.URL
+
+		cfg.NAV = config.NAVConfig{
+			Enabled:  true,
+			BaseURL:  s.nav.server.URL,
+			Company:  "INTEGRATION",
+			Username: "integration",
+			Password: "integration",
+			Timeout:  10,
+		}
+
+		cfg.Document.BasePath = s.dir
+		cfg.Document.ReadyFolder = readyFolder
+		cfg.Document.ProgressFolder = progressFolder
+		cfg.Document.FinishFolder = finishFolder
+		cfg.Stamping.Placement = config.StampPlacementFixed
+
+		cfg.Webhook.PollInterval = 1
+		cfg.Webhook.MaxAttempts = 3
+		cfg.Webhook.RetryBackoff = 1
+
+		cfg.Auth.Enabled = false
+		cfg.Security.APIKeyEnabled = false
+		cfg.RateLimit.Enabled = false
+		cfg.Logging.Level = "warn"
+		cfg.Logging.Levels = nil
+		cfg.Notifier = config.NotifierConfig{}
+		cfg.Sentry.DSN = ""
+		cfg.Startup.Wait = false
+
+		if configure != nil {
+			configure(cfg)
+		}
+		return cfg
+	}
+
+	app := fx.New(
+		fx.NopLogger,
+		config.Module,
+		fx.Decorate(decorate),
+		logger.Module,
+		metrics.Module,
+		database.Module,
+		redis.Module,
+		shutdown.Module,
+		scheduler.Module,
+		oauth2.Module,
+		document.Module,
+		reload.Module,
+		httpclient.Module,
+		nav.Module,
+		i18n.Module,
+		notifier.Module,
+		repository.Module,
+		usecase.Module,
+		deliveryhttp.Module,
+		fx.Invoke(worker.NewWebhookWorker),
+		server.Module,
+		fx.Populate(&s.deps),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := app.Start(ctx); err != nil {
+		t.Fatalf("Failed to start the service: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := app.Stop(ctx); err != nil {
+			t.Errorf("Failed to stop the service: %v", err)
+		}
+	})
+
+	return s
+}
+
+// putReady puts the document of an invoice in the ready folder, where NAV leaves it
+func (s *service) putReady(invoice string) string {
+	s.t.Helper()
+
+	filename := invoice + ".pdf"
+	if err := os.WriteFile(filepath.Join(s.folder(readyFolder), filename), testPDF, 0644); err != nil {
+		s.t.Fatalf("Failed to put %s in the ready folder: %v", filename, err)
+	}
+	return filename
+}
+
+// requestSign sends the sign request of an invoice like NAV does and returns the status and
+// result
+func (s *service) requestSign(invoice string, entryNo int) (int, *entity.GlobalSignResult) {
+	s.t.Helper()
+
+	req := entity.GlobalSignRequest{
+		EntryNo:       entryNo,
+		Email:         testEmail,
+		InvoiceNumber: invoice,
+		Signing:       true,
+		Stamping:      true,
+		Signers: []entity.SignerRequest{{
+			Name:               "Integration Signer",
+			Email:              testEmail,
+			Order:              1,
+			SignPage:           1,
+			SignaturePositions: &entity.SignaturePosition{X: 100, Y: 650, Page: 1},
+		}},
+		StampPositions: &entity.StampPosition{X: 400, Y: 650, Width: 100, Height: 100, Page: 1},
+		StampPlacement: config.StampPlacementFixed,
+	}
+
+	var response struct {
+		Data entity.GlobalSignResult `json:"data"`
+	}
+	status := s.post("/api/v1/esign/documents/request-sign", req, &response)
+	return status, &response.Data
+}
+
+// sendWebhook posts a completed signing callback of a document like Mekari does
+func (s *service) sendWebhook(documentID, filename, stampingStatus string) {
+	s.t.Helper()
+
+	now := time.Now()
+	signedAt := now.UTC().Format(time.RFC3339)
+	payload := entity.WebhookPayload{
+		Data: entity.WebhookData{
+			ID:   documentID,
+			Type: "document",
+			Attributes: entity.WebhookAttributes{
+				Filename:       filename,
+				Category:       "global",
+				DocURL:         downloadURL(documentID),
+				SigningStatus:  entity.SigningStatusCompleted,
+				StampingStatus: stampingStatus,
+				Signers: []entity.WebhookSigner{{
+					Name:     "Integration Signer",
+					Email:    testEmail,
+					Order:    1,
+					Status:   "completed",
+					SignedAt: &signedAt,
+				}},
+				CreatedAt: now,
+				UpdatedAt: now,
+			},
+		},
+	}
+
+	if status := s.post("/webhook/mekari", payload, nil); status != http.StatusAccepted {
+		s.t.Fatalf("Webhook of %s answered with status %d, expected %d", documentID, status, http.StatusAccepted)
+	}
+}
+
+func (s *service) post(path string, body, result interface{}) int {
+	s.t.Helper()
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		s.t.Fatalf("Failed to encode the body of %s: %v", path, err)
+	}
+	resp, err := s.client.Post(s.url+path, "application/json", bytes.NewReader(data))
+	if err != nil {
+		s.t.Fatalf("POST %s failed: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		s.t.Fatalf("Failed to read the response of %s: %v", path, err)
+	}
+	if result != nil {
+		if err := json.Unmarshal(respBody, result); err != nil {
+			s.t.Fatalf("Unexpected response of %s (%d): %s", path, resp.StatusCode, respBody)
+		}
+	}
+	return resp.StatusCode
+}
+
+// webhookEvent returns the single queued callback of a document
+func (s *service) webhookEvent(documentID string) *entity.WebhookEvent {
+	s.t.Helper()
+
+	events, err := s.deps.Events.FindByDocument(context.Background(), documentID, nil)
+	if err != nil {
+		s.t.Fatalf("Failed to list the webhook events of %s: %v", documentID, err)
+	}
+	if len(events) != 1 {
+		s.t.Fatalf("Document %s has %d webhook event(s), expected 1", documentID, len(events))
+	}
+	return &events[0]
+}
+
+// waitForEvent waits until the callback of a document has status and returns it
+func (s *service) waitForEvent(documentID, status string) *entity.WebhookEvent {
+	s.t.Helper()
+
+	var event *entity.WebhookEvent
+	eventually(s.t, func() bool {
+		event = s.webhookEvent(documentID)
+		return event.Status == status
+	}, "webhook event of %s to be %s", documentID, status)
+	return event
+}
+
+// documentEvents returns the types of the recorded events of a document in order
+func (s *service) documentEvents(documentID string) []string {
+	s.t.Helper()
+
+	events, err := s.deps.DocumentEvents.FindByDocumentID(context.Background(), documentID)
+	if err != nil {
+		s.t.Fatalf("Failed to list the document events of %s: %v", documentID, err)
+	}
+	types := make([]string, len(events))
+	for i, event := range events {
+		types[i] = event.EventType
+	}
+	return types
+}
+
+func (s *service) folder(name string) string {
+	return filepath.Join(s.dir, name)
+}
+
+// exists reports whether filename is in folder
+func (s *service) exists(folder, filename string) bool {
+	_, err := os.Stat(filepath.Join(s.folder(folder), filename))
+	return err == nil
+}
+
+// eventually polls condition until it holds or waitTimeout passes
+func eventually(t *testing.T, condition func() bool, format string, args ...interface{}) {
+	t.Helper()
+
+	deadline := time.Now().Add(waitTimeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for "+format, args...)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+var nonIdentifier = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// testName returns the name of a test usable as database name and key prefix
+func testName(t *testing.T) string {
+	return "it_" + nonIdentifier.ReplaceAllString(strings.ToLower(t.Name()), "_")
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,238 @@
+//go:build integration
+
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"mekari-esign/internal/domain/entity"
+)
+
+// stubBalance is the e-meterai balance of the Mekari stub
+const stubBalance = 100
+
+// mekariStub is the Mekari API of a test. It accepts sign and stamp requests, serves pdf as every
+// signed, stamped and audit trail document and fails the calls a test asks it to.
+type mekariStub struct {
+	server *httptest.Server
+	pdf    []byte
+
+	mu            sync.Mutex
+	signRequests  []entity.MekariSignRequest
+	stampRequests []entity.StampRequest
+	stampIDs      []string // Stamp document of each stamp request
+	documents     int
+
+	failSign      bool // Answer sign requests with an error
+	failDownloads int  // Downloads answered with an error before they succeed again
+	downloads     int  // Download attempts, failed ones included
+}
+
+func newMekariStub(pdf []byte) *mekariStub {
+	s := &mekariStub{pdf: pdf}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /profile", s.profile)
+	mux.HandleFunc("POST /documents/request_global_sign", s.globalSign)
+	mux.HandleFunc("POST /documents/stamp", s.stamp)
+	mux.HandleFunc("GET /documents/{id}/download", s.download)
+	mux.HandleFunc("GET /documents/{id}/audit_trail", s.download)
+	s.server = httptest.NewServer(mux)
+	return s
+}
+
+func (s *mekariStub) profile(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, entity.ProfileResponse{
+		Data: &entity.Profile{
+			ID:   "integration-profile",
+			Type: "user",
+			Attributes: entity.ProfileAttributes{
+				ID:     "integration-profile",
+				Email:  testEmail,
+				Name:   "Mekari Stub",
+				Status: "active",
+				Quota:  &entity.Quota{RemainingEmeterai: stubBalance},
+			},
+		},
+		Message: "OK",
+		Status:  http.StatusOK,
+	})
+}
+
+func (s *mekariStub) globalSign(w http.ResponseWriter, r *http.Request) {
+	var req entity.MekariSignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "invalid request body"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failSign {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"message": "service unavailable"})
+		return
+	}
+	s.signRequests = append(s.signRequests, req)
+	s.documents++
+	id := fmt.Sprintf("doc-%d", s.documents)
+
+	signers := make([]entity.SignerStatus, len(req.Signers))
+	for i, signer := range req.Signers {
+		signers[i] = entity.SignerStatus{Name: signer.Name, Email: signer.Email, Status: "pending"}
+	}
+	writeJSON(w, http.StatusCreated, entity.GlobalSignResponse{
+		Data: &entity.GlobalSignData{
+			ID:   id,
+			Type: "document",
+			Attributes: entity.GlobalSignAttributes{
+				DocID:     id,
+				DocURL:    downloadURL(id),
+				Filename:  req.Filename,
+				Status:    "in_progress",
+				Signers:   signers,
+				CreatedAt: time.Now().Format(time.RFC3339),
+			},
+		},
+	})
+}
+
+// stamp accepts a stamp request and answers with its stamp document
+func (s *mekariStub) stamp(w http.ResponseWriter, r *http.Request) {
+	var req entity.StampRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "invalid request body"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stampRequests = append(s.stampRequests, req)
+	s.documents++
+	id := fmt.Sprintf("stamp-%d", s.documents)
+	s.stampIDs = append(s.stampIDs, id)
+
+	writeJSON(w, http.StatusCreated, entity.StampResponse{
+		Data: &entity.StampData{
+			ID:   id,
+			Type: "document",
+			Attributes: entity.StampAttributes{
+				DocID:          id,
+				Filename:       req.Filename,
+				Status:         entity.SigningStatusCompleted,
+				StampingStatus: "in_progress",
+				DocURL:         downloadURL(id),
+				CreatedAt:      time.Now().Format(time.RFC3339),
+			},
+		},
+	})
+}
+
+func (s *mekariStub) download(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.downloads++
+	fail := s.failDownloads > 0
+	if fail {
+		s.failDownloads--
+	}
+	s.mu.Unlock()
+
+	if fail {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"message": "download failed"})
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Write(s.pdf)
+}
+
+// setFailSign makes sign requests fail or succeed again
+func (s *mekariStub) setFailSign(fail bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failSign = fail
+}
+
+// setFailDownloads fails the next n downloads
+func (s *mekariStub) setFailDownloads(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failDownloads = n
+}
+
+// received returns the sign and stamp requests and the download attempts received
+func (s *mekariStub) received() (signs []entity.MekariSignRequest, stamps []entity.StampRequest, downloads int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append(signs, s.signRequests...), append(stamps, s.stampRequests...), s.downloads
+}
+
+// stampDocuments returns the stamp document IDs answered, in order
+func (s *mekariStub) stampDocuments() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.stampIDs...)
+}
+
+// navStub is the NAV OData API of a test. It reports the folders of the test as its setup and
+// records the log entry updates.
+type navStub struct {
+	server *httptest.Server
+	setup  entity.NAVSetup
+
+	mu      sync.Mutex
+	entries []entity.NAVLogEntry
+}
+
+func newNAVStub(setup entity.NAVSetup) *navStub {
+	s := &navStub{setup: setup}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func (s *navStub) serve(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/Api_MekariSetup"):
+		writeJSON(w, http.StatusOK, entity.NAVSetupResponse{Value: []entity.NAVSetup{s.setup}})
+	case r.Method == http.MethodPatch && strings.Contains(r.URL.Path, "/Api_MekariInvoiceLogEntries"):
+		var entry entity.NAVLogEntry
+		if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": "invalid log entry"})
+			return
+		}
+		s.mu.Lock()
+		s.entries = append(s.entries, entry)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// logEntries returns the log entry updates of entryNo in the order received
+func (s *navStub) logEntries(entryNo int) []entity.NAVLogEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries []entity.NAVLogEntry
+	for _, entry := range s.entries {
+		if entry.EntryNo == entryNo {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func downloadURL(id string) string {
+	return "/documents/" + id + "/download"
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
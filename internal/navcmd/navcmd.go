@@ -0,0 +1,115 @@
+// Package navcmd implements the "nav" command line mode. "nav resend -entry-no N" rebuilds the
+// NAV log entry of a document from its last webhook and sends it to NAV again, for when NAV was
+// down when the webhook arrived.
+package navcmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"go.uber.org/fx"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/database"
+	"mekari-esign/internal/infrastructure/document"
+	"mekari-esign/internal/infrastructure/httpclient"
+	"mekari-esign/internal/infrastructure/i18n"
+	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/infrastructure/metrics"
+	"mekari-esign/internal/infrastructure/nav"
+	"mekari-esign/internal/infrastructure/notifier"
+	"mekari-esign/internal/infrastructure/oauth2"
+	"mekari-esign/internal/infrastructure/redis"
+	"mekari-esign/internal/infrastructure/reload"
+	"mekari-esign/internal/infrastructure/repository"
+	"mekari-esign/internal/infrastructure/shutdown"
+	"mekari-esign/internal/infrastructure/startup"
+	"mekari-esign/internal/usecase"
+)
+
+const usage = "usage: nav resend -entry-no <n>"
+
+// Run runs the nav subcommand in args and reports to out. It returns false on failure.
+func Run(out io.Writer, args []string) bool {
+	if len(args) == 0 || args[0] != "resend" {
+		fmt.Fprintln(out, usage)
+		return false
+	}
+
+	flags := flag.NewFlagSet("nav resend", flag.ContinueOnError)
+	flags.SetOutput(out)
+	entryNo := flags.Int("entry-no", 0, "Entry No. of the NAV log entry to send again")
+	if err := flags.Parse(args[1:]); err != nil {
+		return false
+	}
+	if *entryNo <= 0 {
+		fmt.Fprintln(out, usage)
+		return false
+	}
+
+	return resend(out, *entryNo)
+}
+
+func resend(out io.Writer, entryNo int) bool {
+	cfg, err := config.NewConfig()
+	if err != nil {
+		fmt.Fprintf(out, "Failed to load configuration: %v\n", err)
+		return false
+	}
+	if !cfg.NAV.Enabled {
+		fmt.Fprintln(out, "NAV integration is disabled (nav.enabled is false)")
+		return false
+	}
+
+	// Only what the webhook usecase needs is built; no server or workers are started
+	var webhooks usecase.WebhookUsecase
+	app := fx.New(
+		fx.NopLogger,
+		config.Module,
+		logger.Module,
+		metrics.Module,
+		database.Module,
+		redis.Module,
+		shutdown.Module,
+		startup.NoWait,
+		oauth2.Module,
+		document.Module,
+		reload.Module,
+		httpclient.Module,
+		nav.Module,
+		i18n.Module,
+		notifier.Module,
+		repository.Module,
+		usecase.Module,
+		fx.Populate(&webhooks),
+	)
+
+	ctx := context.Background()
+	if err := app.Start(ctx); err != nil {
+		fmt.Fprintf(out, "Failed to start: %v\n", err)
+		return false
+	}
+	defer app.Stop(ctx)
+
+	navEntry, err := webhooks.ResendNAVLogEntry(ctx, entryNo)
+	if err != nil {
+		fmt.Fprintf(out, "Resend of entry %d FAILED: %v\n", entryNo, err)
+		return false
+	}
+
+	fmt.Fprintf(out, "Sent entry %d to NAV: %s\n", entryNo, describe(navEntry))
+	return true
+}
+
+func describe(e *entity.NAVLogEntry) string {
+	s := fmt.Sprintf("%s, signing %s, stamping %s", e.Filename, e.SigningStatus, e.StampingStatus)
+	for i, status := range []string{e.Signer1SigningStatus, e.Signer2SigningStatus, e.Signer3SigningStatus} {
+		if status != "" {
+			s += fmt.Sprintf(", signer %d %s", i+1, status)
+		}
+	}
+	return s
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+// +build !windows
+
+package server
+
+// portOwner is only implemented on Windows, where the service runs
+func portOwner(addr string) string {
+	return ""
+}
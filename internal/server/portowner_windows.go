@@ -0,0 +1,94 @@
+//go:build windows
+// +build windows
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modiphlpapi             = windows.NewLazySystemDLL("iphlpapi.dll")
+	procGetExtendedTcpTable = modiphlpapi.NewProc("GetExtendedTcpTable")
+)
+
+const tcpTableOwnerPIDListener = 3
+
+// tcpRowOwnerPID is MIB_TCPROW_OWNER_PID
+type tcpRowOwnerPID struct {
+	State      uint32
+	LocalAddr  uint32
+	LocalPort  uint32
+	RemoteAddr uint32
+	RemotePort uint32
+	OwningPID  uint32
+}
+
+// portOwner describes the process listening on the port of addr, for the error when the port is
+// taken, e.g. " (in use by PID 4312, nginx.exe)". It is empty when the owner cannot be found.
+func portOwner(addr string) string {
+	_, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return ""
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return ""
+	}
+
+	pid, ok := listenerPID(uint16(port))
+	if !ok {
+		return ""
+	}
+	if name := processName(pid); name != "" {
+		return fmt.Sprintf(" (in use by PID %d, %s)", pid, name)
+	}
+	return fmt.Sprintf(" (in use by PID %d)", pid)
+}
+
+// listenerPID returns the process with an IPv4 TCP listener on port
+func listenerPID(port uint16) (uint32, bool) {
+	var size uint32
+	procGetExtendedTcpTable.Call(0, uintptr(unsafe.Pointer(&size)), 0, windows.AF_INET, tcpTableOwnerPIDListener, 0)
+	if size == 0 {
+		return 0, false
+	}
+	buf := make([]byte, size)
+	r1, _, _ := procGetExtendedTcpTable.Call(uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&size)), 0,
+		windows.AF_INET, tcpTableOwnerPIDListener, 0)
+	if r1 != 0 {
+		return 0, false
+	}
+
+	// MIB_TCPTABLE_OWNER_PID: the number of entries followed by the rows
+	count := *(*uint32)(unsafe.Pointer(&buf[0]))
+	rows := unsafe.Slice((*tcpRowOwnerPID)(unsafe.Pointer(&buf[4])), count)
+	for _, row := range rows {
+		// The port is in network byte order in the low 16 bits
+		if uint16(row.LocalPort>>8|row.LocalPort<<8) == port {
+			return row.OwningPID, true
+		}
+	}
+	return 0, false
+}
+
+func processName(pid uint32) string {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return ""
+	}
+	defer windows.CloseHandle(h)
+
+	buf := make([]uint16, windows.MAX_PATH)
+	size := uint32(len(buf))
+	if err := windows.QueryFullProcessImageName(h, 0, &buf[0], &size); err != nil {
+		return ""
+	}
+	return filepath.Base(windows.UTF16ToString(buf[:size]))
+}
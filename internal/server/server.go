@@ -3,41 +3,74 @@ package server
 import (
 	"context"
 	"fmt"
+	"net"
 
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 
 	"mekari-esign/internal/config"
 	"mekari-esign/internal/delivery/http/router"
+	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/infrastructure/shutdown"
+	"mekari-esign/internal/usecase"
 )
 
 func NewServer(
 	lc fx.Lifecycle,
 	cfg *config.Config,
 	r *router.Router,
+	eventHub *usecase.DocumentEventHub,
+	coordinator *shutdown.Coordinator,
+	shutdowner fx.Shutdowner,
+	reporter *logger.Reporter,
 	logger *zap.Logger,
 ) error {
 	app := r.Setup()
 
 	lc.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
-			addr := fmt.Sprintf(":%d", cfg.App.Port)
-			logger.Info("Starting HTTP server",
-				zap.String("address", addr),
-				zap.String("env", cfg.App.Env),
-			)
-
-			go func() {
-				if err := app.Listen(addr); err != nil {
-					logger.Error("Failed to start server", zap.Error(err))
+			// Listen before returning so a taken port fails startup instead of only being logged
+			var listeners []net.Listener
+			for _, addr := range cfg.App.ListenAddresses() {
+				ln, err := net.Listen(app.Config().Network, addr)
+				if err != nil {
+					for _, l := range listeners {
+						l.Close()
+					}
+					return fmt.Errorf("cannot listen on %s: %w%s", addr, err, portOwner(addr))
 				}
-			}()
+				listeners = append(listeners, ln)
+			}
+
+			for _, ln := range listeners {
+				logger.Info("Starting HTTP server",
+					zap.String("address", ln.Addr().String()),
+					zap.String("env", cfg.App.Env),
+				)
+
+				go func(ln net.Listener) {
+					defer reporter.Repanic(context.Background())
+					if err := app.Listener(ln); err != nil {
+						logger.Error("HTTP server stopped", zap.String("address", ln.Addr().String()), zap.Error(err))
+						// Without the server the service is useless; stopping lets it be restarted
+						_ = shutdowner.Shutdown(fx.ExitCode(1))
+					}
+				}(ln)
+			}
 
 			return nil
 		},
 		OnStop: func(ctx context.Context) error {
 			logger.Info("Shutting down HTTP server")
-			return app.Shutdown()
+			coordinator.Begin()
+
+			// End live event streams first, Shutdown waits for open connections
+			eventHub.Close()
+
+			// Stop accepting requests and let in-flight ones finish until the drain deadline
+			drainCtx, cancel := coordinator.Deadline(ctx)
+			defer cancel()
+			return app.ShutdownWithContext(drainCtx)
 		},
 	})
 
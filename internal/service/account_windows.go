@@ -0,0 +1,137 @@
+//go:build windows
+// +build windows
+
+package service
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modadvapi32    = windows.NewLazySystemDLL("advapi32.dll")
+	procLogonUserW = modadvapi32.NewProc("LogonUserW")
+)
+
+const (
+	logon32LogonService    = 5
+	logon32ProviderDefault = 0
+)
+
+// accountName returns the account as the service control manager expects it: a name without a
+// domain is a local account, ".\name"
+func accountName(user string) string {
+	if strings.ContainsAny(user, `\@`) {
+		return user
+	}
+	return `.\` + user
+}
+
+// logonUser logs on as the service account, the way the service control manager does
+func logonUser(user, password string) (windows.Token, error) {
+	name, domain := accountName(user), ""
+	if i := strings.Index(name, `\`); i >= 0 {
+		domain, name = name[:i], name[i+1:]
+	}
+
+	namePtr, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return 0, err
+	}
+	passwordPtr, err := windows.UTF16PtrFromString(password)
+	if err != nil {
+		return 0, err
+	}
+	var domainPtr *uint16 // nil for a user@domain name
+	if domain != "" {
+		if domainPtr, err = windows.UTF16PtrFromString(domain); err != nil {
+			return 0, err
+		}
+	}
+
+	var token windows.Token
+	r1, _, e1 := procLogonUserW.Call(
+		uintptr(unsafe.Pointer(namePtr)),
+		uintptr(unsafe.Pointer(domainPtr)),
+		uintptr(unsafe.Pointer(passwordPtr)),
+		logon32LogonService,
+		logon32ProviderDefault,
+		uintptr(unsafe.Pointer(&token)),
+	)
+	if r1 == 0 {
+		if e1 == windows.ERROR_LOGON_TYPE_NOT_GRANTED {
+			return 0, fmt.Errorf("%s lacks the \"Log on as a service\" right; grant it in secpol.msc under User Rights Assignment", user)
+		}
+		return 0, e1
+	}
+	return token, nil
+}
+
+// CheckFolderAccess logs on as the service account and verifies it can list each folder and
+// create, read and delete a file in it, creating a missing folder like the service does. The
+// default LocalSystem account usually cannot reach a network share such as the NAV folders.
+func CheckFolderAccess(user, password string, folders []string) error {
+	token, err := logonUser(user, password)
+	if err != nil {
+		return fmt.Errorf("cannot log on as %s: %w", user, err)
+	}
+	defer token.Close()
+
+	var impersonation windows.Token
+	err = windows.DuplicateTokenEx(token, windows.TOKEN_IMPERSONATE|windows.TOKEN_QUERY, nil,
+		windows.SecurityImpersonation, windows.TokenImpersonation, &impersonation)
+	if err != nil {
+		return err
+	}
+	defer impersonation.Close()
+
+	// Impersonation applies to the thread, so the checks must stay on it
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if err := windows.SetThreadToken(nil, impersonation); err != nil {
+		return err
+	}
+	defer windows.RevertToSelf()
+
+	var failed []string
+	for _, dir := range folders {
+		if err := checkFolder(dir); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%s cannot use the document folders: %s", user, strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+func checkFolder(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if _, err := os.ReadDir(dir); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, ".access-check-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	_, err = f.WriteString("mekari-esign access check")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		_, err = os.ReadFile(name)
+	}
+	if rerr := os.Remove(name); err == nil {
+		err = rerr
+	}
+	return err
+}
@@ -2,9 +2,13 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
+	"sync"
 	"syscall"
+	"time"
 
 	"go.uber.org/fx"
 
@@ -13,21 +17,45 @@ import (
 	"mekari-esign/internal/infrastructure/database"
 	"mekari-esign/internal/infrastructure/document"
 	"mekari-esign/internal/infrastructure/httpclient"
+	"mekari-esign/internal/infrastructure/i18n"
 	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/infrastructure/mekarimock"
+	"mekari-esign/internal/infrastructure/metrics"
 	"mekari-esign/internal/infrastructure/nav"
+	"mekari-esign/internal/infrastructure/notifier"
 	"mekari-esign/internal/infrastructure/oauth2"
 	"mekari-esign/internal/infrastructure/redis"
+	"mekari-esign/internal/infrastructure/reload"
 	"mekari-esign/internal/infrastructure/repository"
+	"mekari-esign/internal/infrastructure/scheduler"
+	"mekari-esign/internal/infrastructure/shutdown"
 	"mekari-esign/internal/server"
 	"mekari-esign/internal/usecase"
+	"mekari-esign/internal/worker"
+)
+
+// A failed startup, or the app shutting itself down with an error, is retried with a growing
+// delay. After startAttempts failures in a row Run gives up, so the service exits with an error
+// and the SCM recovery actions restart the process.
+const (
+	startAttempts   = 5
+	startBackoff    = 5 * time.Second
+	maxStartBackoff = time.Minute
+	// stableAfter is how long the app must have run for its failures to be forgotten
+	stableAfter = 10 * time.Minute
 )
 
 // Application wraps the fx.App for service management
 type Application struct {
-	app      *fx.App
 	ctx      context.Context
 	cancel   context.CancelFunc
 	doneChan chan struct{}
+
+	started     chan struct{}
+	startedOnce sync.Once
+
+	// events reports failed starts, which happen before the app logger exists
+	events *logger.EventLog
 }
 
 // NewApplication creates a new Application instance
@@ -37,12 +65,13 @@ func NewApplication() *Application {
 		ctx:      ctx,
 		cancel:   cancel,
 		doneChan: make(chan struct{}),
+		started:  make(chan struct{}),
+		events:   logger.ConsoleEventLog(),
 	}
 }
 
-// Run starts the application
-func (a *Application) Run() {
-	a.app = fx.New(
+func (a *Application) newApp() *fx.App {
+	return fx.New(
 		// Provide context
 		fx.Provide(func() context.Context { return a.ctx }),
 
@@ -51,12 +80,19 @@ func (a *Application) Run() {
 
 		// Infrastructure
 		logger.Module,
+		metrics.Module,
 		database.Module,
 		redis.Module,
+		shutdown.Module,
+		scheduler.Module,
 		oauth2.Module,
 		document.Module,
+		reload.Module,
 		httpclient.Module,
+		mekarimock.Module,
 		nav.Module,
+		i18n.Module,
+		notifier.Module,
 		repository.Module,
 
 		// Business Logic
@@ -65,37 +101,103 @@ func (a *Application) Run() {
 		// Delivery
 		deliveryhttp.Module,
 
+		// Background workers
+		worker.Module,
+
 		// Server
 		server.Module,
+
+		// Drain deadline is app.shutdown_timeout, enforced by the shutdown coordinator
+		fx.StopTimeout(shutdown.StopTimeout),
 	)
+}
 
-	// Start the application
-	if err := a.app.Start(a.ctx); err != nil {
-		return
-	}
+// Run starts the application and supervises it until Shutdown or a stop signal, restarting it
+// when it fails to start (e.g. Redis briefly unavailable) or stops with an error. It returns an
+// error once it gave up after repeated failures.
+func (a *Application) Run() error {
+	defer close(a.doneChan)
+	// Also runs on the service goroutine of the Windows service handler
+	defer logger.RecordCrash()
 
-	// Wait for shutdown signal
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	failures := 0
+	for {
+		startedAt := time.Now()
+		started, err := a.runOnce(sigChan)
+		if err == nil || a.ctx.Err() != nil {
+			return nil
+		}
+
+		if started && time.Since(startedAt) >= stableAfter {
+			failures = 0
+		}
+		failures++
+		if failures >= startAttempts {
+			return fmt.Errorf("giving up after %d failures: %w", failures, err)
+		}
+
+		wait := startBackoff << (failures - 1)
+		if wait > maxStartBackoff {
+			wait = maxStartBackoff
+		}
+		a.events.Report(logger.EventStartFailed, fmt.Sprintf("Application failed (attempt %d of %d), restarting in %s", failures, startAttempts, wait),
+			strconv.Itoa(failures), strconv.Itoa(startAttempts), wait.String(), err.Error())
+
+		select {
+		case <-time.After(wait):
+		case <-sigChan:
+			a.cancel()
+			return nil
+		case <-a.ctx.Done():
+			return nil
+		}
+	}
+}
+
+// runOnce starts a new app and blocks until it stops. It returns the error that stopped it, nil
+// when it was asked to stop, and whether it had started.
+func (a *Application) runOnce(sigChan <-chan os.Signal) (bool, error) {
+	app := a.newApp()
+	if err := app.Start(a.ctx); err != nil {
+		return false, err
+	}
+	a.startedOnce.Do(func() { close(a.started) })
 
+	var exit error
 	select {
 	case <-sigChan:
-		a.Shutdown()
+		a.cancel()
 	case <-a.ctx.Done():
-		// Context was cancelled
+	case sig := <-app.Wait():
+		// A component shut the app down, e.g. the HTTP server could not listen
+		if sig.ExitCode != 0 {
+			exit = fmt.Errorf("application stopped with exit code %d", sig.ExitCode)
+		} else {
+			a.cancel()
+		}
 	}
 
-	close(a.doneChan)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdown.StopTimeout)
+	defer cancel()
+	if err := app.Stop(ctx); err != nil {
+		a.events.Report(logger.EventStopFailed, "Application did not stop cleanly", err.Error())
+	}
+	return true, exit
+}
+
+// Started is closed once the application has started for the first time
+func (a *Application) Started() <-chan struct{} {
+	return a.started
 }
 
-// Shutdown gracefully shuts down the application
+// Shutdown gracefully shuts down the application and waits until Run has returned
 func (a *Application) Shutdown() {
 	a.cancel()
-	if a.app != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), fx.DefaultTimeout)
-		defer cancel()
-		a.app.Stop(ctx)
-	}
+	a.Wait()
 }
 
 // Wait blocks until the application exits
@@ -3,16 +3,28 @@
 
 package service
 
+import (
+	"log"
+	"time"
+)
+
 // Stub implementations for non-Windows platforms
 
 // RunService is a no-op on non-Windows platforms
 func RunService(isDebug bool, app *Application) {
 	// On non-Windows, just run the app normally
-	app.Run()
+	if err := app.Run(); err != nil {
+		log.Fatalf("Application failed: %v", err)
+	}
 }
 
 // InstallService is a no-op on non-Windows platforms
-func InstallService(exePath string) error {
+func InstallService(exePath, user, password string) error {
+	return nil
+}
+
+// CheckFolderAccess is a no-op on non-Windows platforms
+func CheckFolderAccess(user, password string, folders []string) error {
 	return nil
 }
 
@@ -35,3 +47,18 @@ func StopService() error {
 func IsWindowsService() (bool, error) {
 	return false, nil
 }
+
+// Manager is a no-op on non-Windows platforms; the process must be restarted after -update
+type Manager struct{}
+
+func (Manager) Stop(timeout time.Duration) error {
+	return nil
+}
+
+func (Manager) Start(timeout time.Duration) error {
+	return nil
+}
+
+func (Manager) Running() (bool, error) {
+	return true, nil
+}
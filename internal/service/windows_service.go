@@ -11,13 +11,21 @@ import (
 	"golang.org/x/sys/windows/svc/debug"
 	"golang.org/x/sys/windows/svc/eventlog"
 	"golang.org/x/sys/windows/svc/mgr"
+
+	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/infrastructure/shutdown"
+	"mekari-esign/updater"
 )
 
 const ServiceName = "MekariEsign"
 const ServiceDisplayName = "Mekari E-Sign Service"
 const ServiceDescription = "Mekari E-Sign Integration Service for document signing"
 
-var elog debug.Log
+// startWaitHint is how long the SCM waits for the next start checkpoint, in milliseconds
+const startWaitHint = 30000
+
+// exitFailed is the service-specific exit code when the application gave up restarting
+const exitFailed = 1
 
 // MekariEsignService implements svc.Handler
 type MekariEsignService struct {
@@ -26,27 +34,58 @@ type MekariEsignService struct {
 
 func (s *MekariEsignService) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (ssec bool, errno uint32) {
 	const cmdsAccepted = svc.AcceptStop | svc.AcceptShutdown
-	changes <- svc.Status{State: svc.StartPending}
+	changes <- svc.Status{State: svc.StartPending, WaitHint: startWaitHint}
 
-	// Start your application
-	go s.app.Run()
+	runErr := make(chan error, 1)
+	go func() { runErr <- s.app.Run() }()
+
+	// Startup may be retried for a while; checkpoints tell the SCM it is still making progress
+	ticker := time.NewTicker(startWaitHint / 2)
+	defer ticker.Stop()
+	var checkpoint uint32
+starting:
+	for {
+		select {
+		case <-s.app.Started():
+			break starting
+		case err := <-runErr:
+			s.app.events.Report(logger.EventServiceFailed, ServiceName+" service failed to start", err.Error())
+			return true, exitFailed
+		case <-ticker.C:
+			checkpoint++
+			changes <- svc.Status{State: svc.StartPending, CheckPoint: checkpoint, WaitHint: startWaitHint}
+		case c := <-r:
+			if c.Cmd == svc.Interrogate {
+				changes <- c.CurrentStatus
+			}
+		}
+	}
 
 	changes <- svc.Status{State: svc.Running, Accepts: cmdsAccepted}
-	elog.Info(1, fmt.Sprintf("%s service started", ServiceName))
+	s.app.events.Report(logger.EventServiceStarted, ServiceName+" service started", updater.Version)
 
 loop:
 	for {
 		select {
+		case err := <-runErr:
+			if err != nil {
+				// Exiting with an error lets the SCM recovery actions restart the service
+				s.app.events.Report(logger.EventServiceFailed, ServiceName+" service failed", err.Error())
+				return true, exitFailed
+			}
+			break loop
 		case c := <-r:
 			switch c.Cmd {
 			case svc.Interrogate:
 				changes <- c.CurrentStatus
 			case svc.Stop, svc.Shutdown:
-				elog.Info(1, fmt.Sprintf("%s service stopping", ServiceName))
+				s.app.events.Report(logger.EventServiceStopping, ServiceName+" service stopping")
+				// Tell the SCM that draining in-flight work may take a while
+				changes <- svc.Status{State: svc.StopPending, WaitHint: uint32(shutdown.StopTimeout / time.Millisecond)}
 				s.app.Shutdown()
 				break loop
 			default:
-				elog.Error(1, fmt.Sprintf("unexpected control request #%d", c))
+				s.app.events.Report(logger.EventUnexpectedControl, "Unexpected control request", fmt.Sprint(c.Cmd))
 			}
 		}
 	}
@@ -56,32 +95,32 @@ loop:
 
 // RunService runs the service
 func RunService(isDebug bool, app *Application) {
-	var err error
-	if isDebug {
-		elog = debug.New(ServiceName)
-	} else {
-		elog, err = eventlog.Open(ServiceName)
-		if err != nil {
+	events := logger.ConsoleEventLog()
+	if !isDebug {
+		var err error
+		if events, err = logger.OpenEventLog(); err != nil {
 			return
 		}
 	}
-	defer elog.Close()
+	defer events.Close()
 
-	elog.Info(1, fmt.Sprintf("starting %s service", ServiceName))
+	events.Report(logger.EventServiceStarting, "Starting "+ServiceName+" service", updater.Version)
+	app.events = events
 	run := svc.Run
 	if isDebug {
 		run = debug.Run
 	}
-	err = run(ServiceName, &MekariEsignService{app: app})
+	err := run(ServiceName, &MekariEsignService{app: app})
 	if err != nil {
-		elog.Error(1, fmt.Sprintf("%s service failed: %v", ServiceName, err))
+		events.Report(logger.EventServiceFailed, ServiceName+" service failed", err.Error())
 		return
 	}
-	elog.Info(1, fmt.Sprintf("%s service stopped", ServiceName))
+	events.Report(logger.EventServiceStopped, ServiceName+" service stopped")
 }
 
-// InstallService installs the Windows service
-func InstallService(exePath string) error {
+// InstallService installs the Windows service. It runs as user, e.g. DOMAIN\name or a local
+// name, with password, or as LocalSystem when user is empty.
+func InstallService(exePath, user, password string) error {
 	m, err := mgr.Connect()
 	if err != nil {
 		return err
@@ -94,11 +133,17 @@ func InstallService(exePath string) error {
 		return fmt.Errorf("service %s already exists", ServiceName)
 	}
 
-	s, err = m.CreateService(ServiceName, exePath, mgr.Config{
+	serviceConfig := mgr.Config{
 		DisplayName: ServiceDisplayName,
 		Description: ServiceDescription,
 		StartType:   mgr.StartAutomatic, // Auto-start on boot
-	})
+	}
+	if user != "" {
+		serviceConfig.ServiceStartName = accountName(user)
+		serviceConfig.Password = password
+	}
+
+	s, err = m.CreateService(ServiceName, exePath, serviceConfig)
 	if err != nil {
 		return err
 	}
@@ -121,6 +166,11 @@ func InstallService(exePath string) error {
 	if err != nil {
 		fmt.Printf("Warning: failed to set recovery actions: %v\n", err)
 	}
+	// Also when the service stops with an error, e.g. after giving up on a failing startup
+	err = s.SetRecoveryActionsOnNonCrashFailures(true)
+	if err != nil {
+		fmt.Printf("Warning: failed to enable recovery on service errors: %v\n", err)
+	}
 
 	return nil
 }
@@ -188,3 +238,84 @@ func StopService() error {
 func IsWindowsService() (bool, error) {
 	return svc.IsWindowsService()
 }
+
+// Manager stops and starts the installed service for the updater
+type Manager struct{}
+
+// Stop stops the service and waits until it has stopped; a stopped service is left alone
+func (Manager) Stop(timeout time.Duration) error {
+	return withService(func(s *mgr.Service) error {
+		status, err := s.Query()
+		if err != nil {
+			return err
+		}
+		if status.State == svc.Stopped {
+			return nil
+		}
+		if status.State != svc.StopPending {
+			if _, err := s.Control(svc.Stop); err != nil {
+				return err
+			}
+		}
+		return waitForState(s, svc.Stopped, timeout)
+	})
+}
+
+// Start starts the service and waits until it runs
+func (Manager) Start(timeout time.Duration) error {
+	return withService(func(s *mgr.Service) error {
+		if err := s.Start(); err != nil {
+			return err
+		}
+		return waitForState(s, svc.Running, timeout)
+	})
+}
+
+// Running reports whether the service is running
+func (Manager) Running() (bool, error) {
+	running := false
+	err := withService(func(s *mgr.Service) error {
+		status, err := s.Query()
+		running = status.State == svc.Running
+		return err
+	})
+	return running, err
+}
+
+func withService(fn func(s *mgr.Service) error) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(ServiceName)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed: %w", ServiceName, err)
+	}
+	defer s.Close()
+
+	return fn(s)
+}
+
+// waitForState polls the service until it reaches state. A service that stops while it is
+// expected to start has failed.
+func waitForState(s *mgr.Service, state svc.State, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		status, err := s.Query()
+		if err != nil {
+			return err
+		}
+		if status.State == state {
+			return nil
+		}
+		if state == svc.Running && status.State == svc.Stopped {
+			return fmt.Errorf("service %s stopped while starting", ServiceName)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("service %s did not reach state %d within %s", ServiceName, state, timeout)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
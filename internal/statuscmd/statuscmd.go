@@ -0,0 +1,184 @@
+// Package statuscmd implements the "status" command line mode, for quick triage on the server: the
+// version and uptime of the running service, read from its /status endpoint on localhost, the
+// files in the ready, progress and finish folders, the webhook queue and when the last webhook was
+// received. The counts are read directly, so they are shown while the service is down.
+package statuscmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/fx"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/database"
+	"mekari-esign/internal/infrastructure/document"
+	"mekari-esign/internal/infrastructure/httpclient"
+	"mekari-esign/internal/infrastructure/i18n"
+	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/infrastructure/metrics"
+	"mekari-esign/internal/infrastructure/nav"
+	"mekari-esign/internal/infrastructure/notifier"
+	"mekari-esign/internal/infrastructure/oauth2"
+	"mekari-esign/internal/infrastructure/redis"
+	"mekari-esign/internal/infrastructure/reload"
+	"mekari-esign/internal/infrastructure/repository"
+	"mekari-esign/internal/infrastructure/shutdown"
+	"mekari-esign/internal/infrastructure/startup"
+	"mekari-esign/internal/usecase"
+	"mekari-esign/updater"
+)
+
+// serviceTimeout bounds the request to the running service
+const serviceTimeout = 5 * time.Second
+
+// timeLayout formats times in app.timezone
+const timeLayout = "2006-01-02 15:04:05"
+
+type deps struct {
+	fx.In
+
+	Dashboard usecase.DashboardUsecase
+	Events    repository.WebhookEventRepository
+}
+
+// Run writes the status report to out. It returns false when the service is not reachable or
+// the counts could not be read.
+func Run(out io.Writer) bool {
+	cfg, err := config.NewConfig()
+	if err != nil {
+		fmt.Fprintf(out, "Failed to load configuration: %v\n", err)
+		return false
+	}
+
+	fmt.Fprintln(out, "Mekari E-Sign status")
+	ok := reportService(out, cfg)
+	return reportQueues(out, cfg) && ok
+}
+
+// reportService reports the running service from its /status endpoint
+func reportService(out io.Writer, cfg *config.Config) bool {
+	url := fmt.Sprintf("http://%s/status", localAddress(cfg.App.ListenAddresses()[0]))
+	status, err := fetchStatus(url)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\nService")
+	if err != nil {
+		fmt.Fprintf(w, "  State:\tnot reachable (%v)\n", err)
+		fmt.Fprintf(w, "  Installed:\t%s\n", updater.Version)
+		w.Flush()
+		return false
+	}
+
+	fmt.Fprintf(w, "  State:\trunning (%s)\n", url)
+	fmt.Fprintf(w, "  Version:\t%s\n", status.Version)
+	if status.Version != updater.Version {
+		fmt.Fprintf(w, "  Installed:\t%s (restart the service to run it)\n", updater.Version)
+	}
+	fmt.Fprintf(w, "  Uptime:\t%s (since %s)\n", status.Uptime, status.StartedAt.In(cfg.App.Location()).Format(timeLayout))
+	w.Flush()
+	return true
+}
+
+// localAddress returns the address to reach a listen address from this host, the loopback address
+// when the service listens on all interfaces
+func localAddress(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+func fetchStatus(url string) (*entity.ServiceStatus, error) {
+	client := &http.Client{Timeout: serviceTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data entity.ServiceStatus `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	return &body.Data, nil
+}
+
+// reportQueues reports the document folders and the webhook queue, read directly
+func reportQueues(out io.Writer, cfg *config.Config) bool {
+	var d deps
+	app := fx.New(
+		fx.NopLogger,
+		config.Module,
+		logger.Module,
+		metrics.Module,
+		database.Module,
+		redis.Module,
+		shutdown.Module,
+		startup.NoWait,
+		oauth2.Module,
+		document.Module,
+		reload.Module,
+		httpclient.Module,
+		nav.Module,
+		i18n.Module,
+		notifier.Module,
+		repository.Module,
+		usecase.Module,
+		fx.Populate(&d),
+	)
+
+	ctx := context.Background()
+	if err := app.Start(ctx); err != nil {
+		fmt.Fprintf(out, "\nFailed to read documents and webhooks: %v\n", err)
+		return false
+	}
+	defer app.Stop(ctx)
+
+	summary, err := d.Dashboard.GetSummary(ctx)
+	if err != nil {
+		fmt.Fprintf(out, "\nFailed to read documents and webhooks: %v\n", err)
+		return false
+	}
+	lastWebhook, err := d.Events.LastReceivedAt(ctx)
+	if err != nil {
+		fmt.Fprintf(out, "\nFailed to read the last webhook: %v\n", err)
+		return false
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\nDocuments")
+	fmt.Fprintf(w, "  Ready:\t%d\t%s\n", summary.Documents.Ready, summary.Folders.Ready)
+	fmt.Fprintf(w, "  In progress:\t%d\t%s\n", summary.Documents.Progress, summary.Folders.Progress)
+	fmt.Fprintf(w, "  Finished:\t%d\t%s\n", summary.Documents.Finish, summary.Folders.Finish)
+	fmt.Fprintf(w, "  Stuck:\t%d\tin progress for more than %dh\n", len(summary.StuckDocuments), cfg.Dashboard.StuckAfter)
+
+	queue := summary.WebhookQueue
+	fmt.Fprintln(w, "\nWebhooks")
+	fmt.Fprintf(w, "  Queue:\tpending %d, processing %d, failed %d\n",
+		queue[entity.WebhookEventPending], queue[entity.WebhookEventProcessing], queue[entity.WebhookEventFailed])
+	if lastWebhook == nil {
+		fmt.Fprintf(w, "  Last received:\tnever\n")
+	} else {
+		fmt.Fprintf(w, "  Last received:\t%s (%s ago)\n",
+			lastWebhook.In(cfg.App.Location()).Format(timeLayout), time.Since(*lastWebhook).Round(time.Second))
+	}
+	w.Flush()
+	return true
+}
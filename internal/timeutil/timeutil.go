@@ -0,0 +1,24 @@
+// Package timeutil keeps timestamps in one zone: times stored in the database or sent to
+// Mekari, NAV and API clients are UTC, while dates people type or read (log filters, export
+// file names) are in app.timezone.
+package timeutil
+
+import "time"
+
+// DateLayout is the layout of date-only values such as log filters
+const DateLayout = "2006-01-02"
+
+// Now returns the current time in UTC, for timestamps that are stored or sent
+func Now() time.Time {
+	return time.Now().UTC()
+}
+
+// UTC returns an RFC3339 timestamp converted to UTC, e.g. "2024-05-01T10:00:00+07:00" becomes
+// "2024-05-01T03:00:00Z". Values that do not parse are returned unchanged.
+func UTC(value string) string {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return value
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,243 @@
+// Package tokencmd implements the "token" command line mode, to debug OAuth issues from the
+// server console: "token list" shows the stored token state of every email, "token show -email"
+// that of one with its latest grant and refresh attempts, "token refresh -email" refreshes the
+// access token and "token invalidate -email" removes the tokens from Redis, so the next request
+// exchanges the stored code again. Token values are never printed.
+package tokencmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/fx"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/domain/entity"
+	domainrepo "mekari-esign/internal/domain/repository"
+	"mekari-esign/internal/infrastructure/database"
+	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/infrastructure/metrics"
+	"mekari-esign/internal/infrastructure/oauth2"
+	"mekari-esign/internal/infrastructure/redis"
+	"mekari-esign/internal/infrastructure/repository"
+	"mekari-esign/internal/infrastructure/shutdown"
+	"mekari-esign/internal/infrastructure/startup"
+)
+
+const usage = "usage: token list | token show|refresh|invalidate -email <email>"
+
+// showHistoryLimit is the number of grant and refresh attempts listed by "token show"
+const showHistoryLimit = 5
+
+// deps are the components the token commands use
+type deps struct {
+	fx.In
+
+	Config  *config.Config
+	Tokens  oauth2.TokenService
+	OAuth   domainrepo.OAuthRepository
+	History domainrepo.OAuthTokenHistoryRepository
+	Redis   *redis.RedisClient
+}
+
+// formatTime formats a stored time in app.timezone
+func (d deps) formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.In(d.Config.App.Location()).Format("2006-01-02 15:04:05")
+}
+
+// tokenStates describes the access and refresh token of an email in Redis by their remaining
+// lifetime
+func (d deps) tokenStates(ctx context.Context, email string) (string, string) {
+	ttls, err := d.Redis.TTLs(ctx, oauth2.AccessTokenKey(email), oauth2.RefreshTokenKey(email))
+	if err != nil {
+		return "error: " + err.Error(), "error: " + err.Error()
+	}
+	return ttlState(ttls[0]), ttlState(ttls[1])
+}
+
+// Run runs the token subcommand in args and reports to out. It returns false on failure.
+func Run(out io.Writer, args []string) bool {
+	if len(args) == 0 {
+		fmt.Fprintln(out, usage)
+		return false
+	}
+	command := args[0]
+
+	flags := flag.NewFlagSet("token "+command, flag.ContinueOnError)
+	flags.SetOutput(out)
+	email := flags.String("email", "", "Email of the token")
+	if err := flags.Parse(args[1:]); err != nil {
+		return false
+	}
+
+	var run func(ctx context.Context, out io.Writer, d deps, email string) error
+	switch command {
+	case "list":
+		run = list
+	case "show":
+		run = show
+	case "refresh":
+		run = refresh
+	case "invalidate":
+		run = invalidate
+	default:
+		fmt.Fprintln(out, usage)
+		return false
+	}
+	if command != "list" && *email == "" {
+		fmt.Fprintln(out, usage)
+		return false
+	}
+
+	cfg, err := config.NewConfig()
+	if err != nil {
+		fmt.Fprintf(out, "Failed to load configuration: %v\n", err)
+		return false
+	}
+	if !cfg.Mekari.IsOAuth2() {
+		fmt.Fprintf(out, "mekari.auth_type is %s; OAuth tokens are not used\n", cfg.Mekari.AuthType)
+		return false
+	}
+
+	var d deps
+	app := fx.New(
+		fx.NopLogger,
+		config.Module,
+		logger.Module,
+		metrics.Module,
+		database.Module,
+		redis.Module,
+		shutdown.Module,
+		startup.NoWait,
+		repository.Module,
+		oauth2.Module,
+		fx.Populate(&d),
+	)
+
+	ctx := context.Background()
+	if err := app.Start(ctx); err != nil {
+		fmt.Fprintf(out, "Failed to start: %v\n", err)
+		return false
+	}
+	defer app.Stop(ctx)
+
+	if err := run(ctx, out, d, *email); err != nil {
+		fmt.Fprintf(out, "token %s FAILED: %v\n", command, err)
+		return false
+	}
+	return true
+}
+
+func list(ctx context.Context, out io.Writer, d deps, _ string) error {
+	tokens, err := d.OAuth.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		fmt.Fprintln(out, "No OAuth tokens stored")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "EMAIL\tCODE\tACCESS TOKEN\tREFRESH TOKEN\tUPDATED")
+	for _, token := range tokens {
+		access, refresh := d.tokenStates(ctx, token.Email)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", token.Email, yesNo(token.Code != ""), access, refresh, d.formatTime(token.UpdatedAt))
+	}
+	return w.Flush()
+}
+
+func show(ctx context.Context, out io.Writer, d deps, email string) error {
+	token, err := d.OAuth.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return fmt.Errorf("no OAuth token stored for %s", email)
+	}
+
+	access, refresh := d.tokenStates(ctx, email)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Email:\t%s\n", token.Email)
+	fmt.Fprintf(w, "Code:\t%s\n", yesNo(token.Code != ""))
+	fmt.Fprintf(w, "Access token:\t%s\n", access)
+	fmt.Fprintf(w, "Refresh token:\t%s\n", refresh)
+	fmt.Fprintf(w, "Created:\t%s\n", d.formatTime(token.CreatedAt))
+	fmt.Fprintf(w, "Updated:\t%s\n", d.formatTime(token.UpdatedAt))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	events, err := d.History.FindByEmail(ctx, email, showHistoryLimit)
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	fmt.Fprintln(out, "\nLatest attempts:")
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, event := range events {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", d.formatTime(event.CreatedAt), event.GrantType, d.attemptResult(event))
+	}
+	return w.Flush()
+}
+
+func refresh(ctx context.Context, out io.Writer, d deps, email string) error {
+	resp, err := d.Tokens.RefreshToken(ctx, email)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Refreshed the access token of %s, valid for %s\n", email, time.Duration(resp.ExpiresIn)*time.Second)
+	return nil
+}
+
+func invalidate(ctx context.Context, out io.Writer, d deps, email string) error {
+	if err := d.Tokens.InvalidateTokens(ctx, email); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Removed the tokens of %s from Redis; the next request exchanges the stored code\n", email)
+	return nil
+}
+
+// ttlState describes a token key by its TTL, which is -2 for a missing key and -1 without expiry
+func ttlState(ttl time.Duration) string {
+	switch {
+	case ttl == -2:
+		return "missing"
+	case ttl < 0:
+		return "present, no expiry"
+	}
+	return "expires in " + formatTTL(ttl)
+}
+
+func formatTTL(d time.Duration) string {
+	if d >= 24*time.Hour {
+		return fmt.Sprintf("%dd%dh", d/(24*time.Hour), d%(24*time.Hour)/time.Hour)
+	}
+	return d.Round(time.Second).String()
+}
+
+func (d deps) attemptResult(event entity.OAuthTokenEvent) string {
+	if !event.Success {
+		return "FAILED: " + event.Error
+	}
+	if event.ExpiresAt != nil {
+		return "OK, expires " + d.formatTime(*event.ExpiresAt)
+	}
+	return "OK"
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
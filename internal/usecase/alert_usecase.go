@@ -0,0 +1,237 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	applog "mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/infrastructure/metrics"
+	"mekari-esign/internal/infrastructure/notifier"
+	"mekari-esign/internal/infrastructure/redis"
+)
+
+const (
+	alertKeyPrefix = "alert:"
+
+	// alertWindow is the period failure counts are taken over
+	alertWindow = time.Hour
+)
+
+// Alert rules, also used as the notification event (with an "_resolved" suffix when cleared)
+const (
+	AlertWebhookFailures      = "webhook_failures"
+	AlertNAVFailures          = "nav_failures"
+	AlertTokenRefreshFailures = "token_refresh_failures"
+	AlertStuckDocuments       = "stuck_documents"
+)
+
+type AlertUsecase interface {
+	// Evaluate checks every rule with a threshold, notifies the rules that reach it and the
+	// rules that cleared since they were notified
+	Evaluate(ctx context.Context)
+}
+
+// alertRule compares a value with its threshold. Counter rules compare the increase of the
+// counter over alertWindow rather than its total.
+type alertRule struct {
+	name      string
+	title     string
+	unit      string
+	event     applog.EventID // Reported to the Windows Event Log when the threshold is reached
+	threshold func(cfg *config.AlertsConfig) int
+	counter   bool
+	value     func(ctx context.Context) float64
+}
+
+// alertSample is a counter value read by Evaluate
+type alertSample struct {
+	at    time.Time
+	value float64
+}
+
+type alertUsecase struct {
+	config      *config.Config
+	redisClient *redis.RedisClient
+	notifier    notifier.Notifier
+	events      *applog.EventLog
+	logger      *zap.Logger
+	rules       []alertRule
+
+	mu      sync.Mutex
+	samples map[string][]alertSample
+}
+
+func NewAlertUsecase(
+	cfg *config.Config,
+	meter *metrics.Metrics,
+	dashboard DashboardUsecase,
+	redisClient *redis.RedisClient,
+	notifier notifier.Notifier,
+	events *applog.EventLog,
+	logger *zap.Logger,
+) AlertUsecase {
+	u := &alertUsecase{
+		config:      cfg,
+		redisClient: redisClient,
+		notifier:    notifier,
+		events:      events,
+		logger:      logger,
+		samples:     make(map[string][]alertSample),
+	}
+
+	u.rules = []alertRule{
+		{
+			name:      AlertWebhookFailures,
+			title:     "Webhook processing is failing",
+			unit:      "failed webhook processing attempts in the last hour",
+			event:     applog.EventWebhookFailures,
+			threshold: func(cfg *config.AlertsConfig) int { return cfg.WebhookFailures },
+			counter:   true,
+			value: func(ctx context.Context) float64 {
+				return metrics.Total(meter.WebhookProcessed, func(labels map[string]string) bool {
+					return labels["result"] == metrics.WebhookFailed
+				})
+			},
+		},
+		{
+			name:      AlertNAVFailures,
+			title:     "NAV calls are failing",
+			unit:      "NAV calls without a response or with a 5xx status in the last hour",
+			event:     applog.EventNAVUnreachable,
+			threshold: func(cfg *config.AlertsConfig) int { return cfg.NAVFailures },
+			counter:   true,
+			value: func(ctx context.Context) float64 {
+				return metrics.Total(meter.ExternalRequests, func(labels map[string]string) bool {
+					status := labels["status"]
+					return labels["service"] == metrics.ServiceNAV && (status == "error" || strings.HasPrefix(status, "5"))
+				})
+			},
+		},
+		{
+			name:      AlertTokenRefreshFailures,
+			title:     "OAuth2 token refreshes are failing",
+			unit:      "failed token refreshes in the last hour",
+			event:     applog.EventTokenRefreshFailures,
+			threshold: func(cfg *config.AlertsConfig) int { return cfg.TokenRefreshFailures },
+			counter:   true,
+			value: func(ctx context.Context) float64 {
+				return metrics.Total(meter.TokenRefreshes, func(labels map[string]string) bool {
+					return labels["result"] == metrics.RefreshFailed
+				})
+			},
+		},
+		{
+			name:      AlertStuckDocuments,
+			title:     "Documents are stuck in progress",
+			unit:      fmt.Sprintf("documents in progress for more than %d hours", cfg.Dashboard.StuckAfter),
+			event:     applog.EventStuckDocuments,
+			threshold: func(cfg *config.AlertsConfig) int { return cfg.StuckDocuments },
+			value: func(ctx context.Context) float64 {
+				return float64(len(dashboard.GetStuckDocuments(ctx)))
+			},
+		},
+	}
+
+	return u
+}
+
+func (u *alertUsecase) Evaluate(ctx context.Context) {
+	for _, rule := range u.rules {
+		threshold := rule.threshold(&u.config.Alerts)
+		if threshold <= 0 {
+			continue
+		}
+
+		value := rule.value(ctx)
+		if rule.counter {
+			value = u.increase(rule.name, value)
+		}
+
+		if value >= float64(threshold) {
+			u.fire(ctx, rule, int(value), threshold)
+		} else {
+			u.resolve(ctx, rule, int(value), threshold)
+		}
+	}
+}
+
+// increase records a counter value and returns how much the counter grew over alertWindow.
+// The oldest kept sample is the newest one taken at or before the start of the window.
+func (u *alertUsecase) increase(rule string, value float64) float64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	now := time.Now()
+	samples := append(u.samples[rule], alertSample{at: now, value: value})
+	for len(samples) > 1 && !samples[1].at.After(now.Add(-alertWindow)) {
+		samples = samples[1:]
+	}
+	u.samples[rule] = samples
+
+	return value - samples[0].value
+}
+
+// fire notifies a rule at most once per alerts.repeat_interval
+func (u *alertUsecase) fire(ctx context.Context, rule alertRule, value, threshold int) {
+	interval := time.Duration(u.config.Alerts.RepeatInterval) * time.Hour
+	first, err := u.redisClient.SetNX(ctx, alertKeyPrefix+rule.name, value, interval)
+	if err != nil {
+		u.logger.Warn("Failed to check alert state", zap.String("rule", rule.name), zap.Error(err))
+		return
+	}
+	if !first {
+		return // Already notified within the repeat interval
+	}
+
+	message := fmt.Sprintf("%d %s (threshold %d).", value, rule.unit, threshold)
+	u.events.Report(rule.event, rule.title+": "+message, strconv.Itoa(value), strconv.Itoa(threshold))
+	err = u.notifier.Notify(ctx, &notifier.Notification{
+		Event:    rule.name,
+		Severity: notifier.SeverityCritical,
+		Title:    rule.title,
+		Message:  message,
+		Fields: map[string]interface{}{
+			"value":     value,
+			"threshold": threshold,
+		},
+	})
+	if err != nil {
+		u.logger.Error("Failed to send alert notification", zap.String("rule", rule.name), zap.Error(err))
+	}
+}
+
+// resolve notifies that a rule notified earlier is below its threshold again
+func (u *alertUsecase) resolve(ctx context.Context, rule alertRule, value, threshold int) {
+	key := alertKeyPrefix + rule.name
+	firing, err := u.redisClient.Exists(ctx, key)
+	if err != nil || !firing {
+		return
+	}
+	if err := u.redisClient.Del(ctx, key); err != nil {
+		u.logger.Warn("Failed to clear alert state", zap.String("rule", rule.name), zap.Error(err))
+		return
+	}
+
+	message := fmt.Sprintf("%d %s, below the threshold of %d.", value, rule.unit, threshold)
+	u.events.Report(applog.EventAlertResolved, rule.title+" (resolved): "+message, rule.name, strconv.Itoa(value), strconv.Itoa(threshold))
+	err = u.notifier.Notify(ctx, &notifier.Notification{
+		Event:    rule.name + "_resolved",
+		Severity: notifier.SeverityInfo,
+		Title:    rule.title + " (resolved)",
+		Message:  message,
+		Fields: map[string]interface{}{
+			"value":     value,
+			"threshold": threshold,
+		},
+	})
+	if err != nil {
+		u.logger.Error("Failed to send alert notification", zap.String("rule", rule.name), zap.Error(err))
+	}
+}
@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/repository"
+	"mekari-esign/internal/infrastructure/shutdown"
+	"mekari-esign/internal/timeutil"
+)
+
+const (
+	// apiKeyPrefix marks keys issued by this service
+	apiKeyPrefix = "mk_"
+	// lastUsedUpdateInterval limits how often last_used_at is written per key
+	lastUsedUpdateInterval = time.Minute
+)
+
+// ErrInvalidAPIKey is returned when an API key is unknown or revoked
+var ErrInvalidAPIKey = errors.New("invalid or revoked API key")
+
+type APIKeyUsecase interface {
+	// CreateKey generates a new API key and returns the plaintext key once
+	CreateKey(ctx context.Context, req *entity.CreateAPIKeyRequest) (*entity.CreateAPIKeyResponse, error)
+	// ListKeys lists all API keys (without secrets)
+	ListKeys(ctx context.Context) ([]entity.APIKey, error)
+	// RevokeKey revokes an API key by ID
+	RevokeKey(ctx context.Context, id int64) error
+	// Authenticate validates a plaintext API key and tracks its usage
+	Authenticate(ctx context.Context, key string) (*entity.APIKey, error)
+}
+
+type apiKeyUsecase struct {
+	repo        repository.APIKeyRepository
+	coordinator *shutdown.Coordinator
+	logger      *zap.Logger
+	mu          sync.Mutex
+	lastUsed    map[int64]time.Time
+}
+
+func NewAPIKeyUsecase(repo repository.APIKeyRepository, coordinator *shutdown.Coordinator, logger *zap.Logger) APIKeyUsecase {
+	return &apiKeyUsecase{
+		repo:        repo,
+		coordinator: coordinator,
+		logger:      logger,
+		lastUsed:    make(map[int64]time.Time),
+	}
+}
+
+// hashAPIKey returns the SHA-256 hex digest of a plaintext key
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (u *apiKeyUsecase) CreateKey(ctx context.Context, req *entity.CreateAPIKeyRequest) (*entity.CreateAPIKeyResponse, error) {
+	if req.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if req.RateLimit < 0 {
+		return nil, fmt.Errorf("rate_limit must not be negative")
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	plaintext := apiKeyPrefix + hex.EncodeToString(secret)
+
+	key := &entity.APIKey{
+		Name:      req.Name,
+		KeyPrefix: plaintext[:len(apiKeyPrefix)+8],
+		KeyHash:   hashAPIKey(plaintext),
+		RateLimit: req.RateLimit,
+		CreatedAt: timeutil.Now(),
+	}
+
+	if err := u.repo.Create(ctx, key); err != nil {
+		u.logger.Error("Failed to create API key", zap.Error(err))
+		return nil, err
+	}
+
+	u.logger.Info("API key created",
+		zap.Int64("id", key.ID),
+		zap.String("name", key.Name),
+		zap.String("key_prefix", key.KeyPrefix),
+	)
+
+	return &entity.CreateAPIKeyResponse{
+		Key:    plaintext,
+		APIKey: key,
+	}, nil
+}
+
+func (u *apiKeyUsecase) ListKeys(ctx context.Context) ([]entity.APIKey, error) {
+	return u.repo.FindAll(ctx)
+}
+
+func (u *apiKeyUsecase) RevokeKey(ctx context.Context, id int64) error {
+	if err := u.repo.Revoke(ctx, id); err != nil {
+		return err
+	}
+
+	u.logger.Info("API key revoked", zap.Int64("id", id))
+	return nil
+}
+
+func (u *apiKeyUsecase) Authenticate(ctx context.Context, key string) (*entity.APIKey, error) {
+	if key == "" {
+		return nil, ErrInvalidAPIKey
+	}
+
+	apiKey, err := u.repo.FindByHash(ctx, hashAPIKey(key))
+	if err != nil {
+		return nil, err
+	}
+	if apiKey == nil || apiKey.IsRevoked() {
+		return nil, ErrInvalidAPIKey
+	}
+
+	u.touchLastUsed(apiKey.ID)
+
+	return apiKey, nil
+}
+
+// touchLastUsed updates last_used_at asynchronously, at most once per interval per key
+func (u *apiKeyUsecase) touchLastUsed(id int64) {
+	now := timeutil.Now()
+
+	u.mu.Lock()
+	last, ok := u.lastUsed[id]
+	if ok && now.Sub(last) < lastUsedUpdateInterval {
+		u.mu.Unlock()
+		return
+	}
+	u.lastUsed[id] = now
+	u.mu.Unlock()
+
+	u.coordinator.Go("touch API key", func(ctx context.Context) {
+		if err := u.repo.TouchLastUsed(ctx, id, now); err != nil {
+			u.logger.Warn("Failed to update API key last used", zap.Int64("id", id), zap.Error(err))
+		}
+	})
+}
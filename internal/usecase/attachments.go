@@ -0,0 +1,208 @@
+package usecase
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/document"
+	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/timeutil"
+)
+
+// ErrAttachmentNotMergeable is returned when the attachments of a sign request cannot be merged
+// into its document, e.g. an encrypted or malformed PDF
+var ErrAttachmentNotMergeable = errors.New("attachments cannot be merged into the document")
+
+// attachmentFile is an attachment of a sign request with its content
+type attachmentFile struct {
+	filename string
+	content  []byte
+	dir      string // Ready folder it was read from, empty for inline attachments
+}
+
+// attachmentMode returns how the attachments of a request are sent (config.AttachmentModeMerge or
+// config.AttachmentModeSeparate)
+func (u *esignUsecase) attachmentMode(req *entity.GlobalSignRequest) string {
+	if req.AttachmentMode != "" {
+		return req.AttachmentMode
+	}
+	return u.config.DocumentSettings().AttachmentMode
+}
+
+// readyPathOf returns the ready folder of an entry, from its cached NAV setup when set
+func (u *esignUsecase) readyPathOf(ctx context.Context, entryNo int) string {
+	if navSetup := u.cachedNAVSetup(ctx, entryNo); navSetup != nil && navSetup.FileLocationOut != "" {
+		return navSetup.FileLocationOut
+	}
+	return u.docService.GetReadyPath()
+}
+
+// readAttachments decodes the inline attachments of a request and reads the others from the ready
+// folder by filename
+func (u *esignUsecase) readAttachments(ctx context.Context, req *entity.GlobalSignRequest) ([]attachmentFile, error) {
+	readyPath := u.readyPathOf(ctx, req.EntryNo)
+
+	files := make([]attachmentFile, 0, len(req.Attachments))
+	for _, attachment := range req.Attachments {
+		filename := filepath.Base(attachment.Filename)
+		if filename == "." || filename == string(filepath.Separator) {
+			return nil, fmt.Errorf("invalid attachment filename: %s", attachment.Filename)
+		}
+
+		if attachment.Content != "" {
+			content, err := base64.StdEncoding.DecodeString(attachment.Content)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode attachment %s: %w", filename, err)
+			}
+			files = append(files, attachmentFile{filename: filename, content: content})
+			continue
+		}
+
+		dir, content, err := u.docService.FindFile(filename, readyPath)
+		if err != nil {
+			return nil, fmt.Errorf("%w: attachment %s", document.ErrDocumentNotFound, filename)
+		}
+		files = append(files, attachmentFile{filename: filename, content: content, dir: dir})
+	}
+	return files, nil
+}
+
+// mergeAttachments replaces the document of a request with the document followed by the pages of
+// its attachments, sent inline under the name of the document. The ready folder the document was
+// read from is returned, empty for an inline document.
+func (u *esignUsecase) mergeAttachments(ctx context.Context, req *entity.GlobalSignRequest, files []attachmentFile) (string, error) {
+	var (
+		base64Doc, filename, dir string
+		err                      error
+	)
+	if req.Document != nil {
+		base64Doc, filename = req.Document.Content, req.Document.Filename
+	} else {
+		dir = u.readyPathOf(ctx, req.EntryNo)
+		base64Doc, filename, err = u.docService.FindDocumentByInvoiceNumberWithPath(req.InvoiceNumber, dir)
+		if err != nil {
+			return "", fmt.Errorf("failed to find document: %w", err)
+		}
+	}
+	content, err := base64.StdEncoding.DecodeString(base64Doc)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode document: %w", err)
+	}
+
+	docs := [][]byte{content}
+	for _, file := range files {
+		docs = append(docs, file.content)
+	}
+	merged, err := document.MergePDFs(docs...)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrAttachmentNotMergeable, err)
+	}
+
+	logger.FromContext(ctx, u.logger).Info("Attachments merged into document",
+		zap.String("filename", filename),
+		zap.Int("attachments", len(files)),
+	)
+	req.Document = &entity.InlineDocument{
+		Filename: filename,
+		Content:  base64.StdEncoding.EncodeToString(merged),
+	}
+	return dir, nil
+}
+
+// sendAttachments sends each attachment as its own document to the signers of the main document
+// and saves its mapping, linked to the main document. Attachments that could not be sent are
+// returned by name; the main document is already out for signing, so they do not fail the request.
+func (u *esignUsecase) sendAttachments(ctx context.Context, req *entity.GlobalSignRequest, parentID string, files []attachmentFile) ([]entity.LinkedDocument, []string) {
+	log := logger.FromContext(ctx, u.logger)
+
+	var (
+		linked []entity.LinkedDocument
+		failed []string
+	)
+	for _, file := range files {
+		attachmentReq := &entity.GlobalSignRequest{
+			EntryNo:          req.EntryNo,
+			Email:            req.Email,
+			InvoiceNumber:    req.InvoiceNumber,
+			Signing:          true,
+			Signers:          req.Signers,
+			DocumentDeadline: req.DocumentDeadline,
+			EnforceOrder:     req.EnforceOrder,
+			Document: &entity.InlineDocument{
+				Filename: file.filename,
+				Content:  base64.StdEncoding.EncodeToString(file.content),
+			},
+		}
+		response, err := u.repo.GlobalRequestSign(ctx, req.Email, attachmentReq)
+		if err != nil {
+			log.Error("Failed to send attachment for signing",
+				zap.String("attachment", file.filename),
+				zap.Error(err),
+			)
+			failed = append(failed, file.filename)
+			continue
+		}
+
+		mapping := &entity.DocumentMapping{
+			DocumentID:       response.Data.ID,
+			Email:            req.Email,
+			InvoiceNumber:    req.InvoiceNumber,
+			Filename:         response.Data.Attributes.Filename,
+			DocumentDeadline: req.DocumentDeadline,
+			EntryNo:          req.EntryNo,
+			Signing:          true,
+			EnforceOrder:     req.EnforceOrder,
+			Signers:          req.Signers,
+			ParentDocumentID: parentID,
+		}
+		submittedAt := timeutil.Now()
+		mapping.SubmittedAt = &submittedAt
+		if expiresAt, ok := entity.SigningExpiry(&response.Data.Attributes, req.DocumentDeadline, submittedAt); ok {
+			mapping.ExpiresAt = &expiresAt
+		}
+		// Only by document ID: the entry_no key belongs to the main document
+		if err := u.mappingRepo.SaveByDocumentID(ctx, response.Data.ID, mapping); err != nil {
+			log.Error("Failed to save attachment mapping",
+				zap.String("attachment_id", response.Data.ID),
+				zap.Error(err),
+			)
+		}
+		u.removeSentFile(ctx, file.filename, file.dir)
+
+		log.Info("Attachment sent for signing",
+			zap.String("attachment", file.filename),
+			zap.String("attachment_id", response.Data.ID),
+		)
+		linked = append(linked, entity.LinkedDocument{
+			DocumentID: response.Data.ID,
+			Filename:   mapping.Filename,
+		})
+	}
+	return linked, failed
+}
+
+// removeSentFile deletes a file read from the ready folder once it was sent inline, which wrote
+// it to progress; a copy left in ready would be sent twice
+func (u *esignUsecase) removeSentFile(ctx context.Context, filename, dir string) {
+	if dir == "" {
+		return
+	}
+	if err := u.docService.DeleteFileWithPath(filename, dir); err != nil {
+		logger.FromContext(ctx, u.logger).Warn("Failed to remove sent file from ready",
+			zap.String("filename", filename),
+			zap.Error(err),
+		)
+	}
+}
+
+// usesSeparateAttachments reports whether the attachments of a request are sent as linked documents
+func (u *esignUsecase) usesSeparateAttachments(req *entity.GlobalSignRequest) bool {
+	return len(req.Attachments) > 0 && u.attachmentMode(req) == config.AttachmentModeSeparate
+}
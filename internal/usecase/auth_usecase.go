@@ -0,0 +1,220 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/repository"
+	"mekari-esign/internal/timeutil"
+)
+
+// minPasswordLength is the minimum length accepted for user passwords
+const minPasswordLength = 8
+
+var (
+	// ErrInvalidCredentials is returned when a username/password pair does not match
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrInvalidToken is returned when a login token is malformed, expired or forged
+	ErrInvalidToken = errors.New("invalid or expired token")
+)
+
+// authClaims are the JWT claims issued on login
+type authClaims struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+type AuthUsecase interface {
+	// Login verifies credentials and issues a signed token
+	Login(ctx context.Context, req *entity.LoginRequest) (*entity.LoginResponse, error)
+	// ParseToken validates a token and returns the authenticated user
+	ParseToken(token string) (*entity.AuthUser, error)
+	// CreateUser creates a new user with a hashed password
+	CreateUser(ctx context.Context, req *entity.CreateUserRequest) (*entity.User, error)
+	// ListUsers lists all users
+	ListUsers(ctx context.Context) ([]entity.User, error)
+}
+
+type authUsecase struct {
+	config *config.Config
+	repo   repository.UserRepository
+	logger *zap.Logger
+	secret []byte
+}
+
+func NewAuthUsecase(cfg *config.Config, repo repository.UserRepository, logger *zap.Logger) (AuthUsecase, error) {
+	u := &authUsecase{
+		config: cfg,
+		repo:   repo,
+		logger: logger,
+		secret: []byte(cfg.Auth.JWTSecret),
+	}
+
+	if len(u.secret) == 0 {
+		// Without a configured secret tokens only survive until the next restart
+		u.secret = make([]byte, 32)
+		if _, err := rand.Read(u.secret); err != nil {
+			return nil, fmt.Errorf("failed to generate JWT secret: %w", err)
+		}
+		if cfg.Auth.Enabled {
+			logger.Warn("auth.jwt_secret is not set, using a random secret (logins are lost on restart)")
+		}
+	}
+
+	if err := u.bootstrapAdmin(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// bootstrapAdmin creates the configured admin user when the users table is empty
+func (u *authUsecase) bootstrapAdmin(ctx context.Context) error {
+	if u.config.Auth.AdminUsername == "" || u.config.Auth.AdminPassword == "" {
+		return nil
+	}
+
+	count, err := u.repo.Count(ctx)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	user, err := u.CreateUser(ctx, &entity.CreateUserRequest{
+		Username: u.config.Auth.AdminUsername,
+		Password: u.config.Auth.AdminPassword,
+		Role:     entity.RoleAdmin,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create bootstrap admin: %w", err)
+	}
+
+	u.logger.Info("Bootstrap admin user created", zap.String("username", user.Username))
+	return nil
+}
+
+func (u *authUsecase) Login(ctx context.Context, req *entity.LoginRequest) (*entity.LoginResponse, error) {
+	user, err := u.repo.FindByUsername(ctx, req.Username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.Disabled {
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	now := timeutil.Now()
+	expiresAt := now.Add(time.Duration(u.config.Auth.TokenTTL) * time.Hour)
+
+	claims := authClaims{
+		Username: user.Username,
+		Role:     user.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatInt(user.ID, 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(u.secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	if err := u.repo.UpdateLastLogin(ctx, user.ID, now); err != nil {
+		u.logger.Warn("Failed to update last login", zap.String("username", user.Username), zap.Error(err))
+	}
+
+	u.logger.Info("User logged in", zap.String("username", user.Username), zap.String("role", user.Role))
+
+	return &entity.LoginResponse{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		User: &entity.AuthUser{
+			ID:       user.ID,
+			Username: user.Username,
+			Role:     user.Role,
+		},
+	}, nil
+}
+
+func (u *authUsecase) ParseToken(token string) (*entity.AuthUser, error) {
+	var claims authClaims
+
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		return u.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	id, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil || !entity.IsValidRole(claims.Role) {
+		return nil, ErrInvalidToken
+	}
+
+	return &entity.AuthUser{
+		ID:       id,
+		Username: claims.Username,
+		Role:     claims.Role,
+	}, nil
+}
+
+func (u *authUsecase) CreateUser(ctx context.Context, req *entity.CreateUserRequest) (*entity.User, error) {
+	if req.Username == "" {
+		return nil, fmt.Errorf("username is required")
+	}
+	if len(req.Password) < minPasswordLength {
+		return nil, fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	if !entity.IsValidRole(req.Role) {
+		return nil, fmt.Errorf("invalid role %q (expected %s, %s or %s)", req.Role, entity.RoleAdmin, entity.RoleOperator, entity.RoleReadOnly)
+	}
+
+	existing, err := u.repo.FindByUsername(ctx, req.Username)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("user %q already exists", req.Username)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user := &entity.User{
+		Username:     req.Username,
+		PasswordHash: string(hash),
+		Role:         req.Role,
+		CreatedAt:    timeutil.Now(),
+	}
+
+	if err := u.repo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	u.logger.Info("User created", zap.String("username", user.Username), zap.String("role", user.Role))
+	return user, nil
+}
+
+func (u *authUsecase) ListUsers(ctx context.Context) ([]entity.User, error) {
+	return u.repo.FindAll(ctx)
+}
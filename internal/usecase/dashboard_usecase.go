@@ -0,0 +1,259 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/domain/repository"
+	"mekari-esign/internal/infrastructure/document"
+	"mekari-esign/internal/infrastructure/nav"
+	"mekari-esign/internal/infrastructure/redis"
+	infraRepo "mekari-esign/internal/infrastructure/repository"
+	"mekari-esign/internal/timeutil"
+)
+
+const (
+	dashboardNAVSetupKey = "dashboard:nav_setup"
+
+	// dashboardNAVSetupTTL limits NAV setup lookups when the dashboard auto-refreshes
+	dashboardNAVSetupTTL = 10 * time.Minute
+	// dashboardFailureLimit is the number of recent failures listed per kind
+	dashboardFailureLimit = 20
+)
+
+type DashboardUsecase interface {
+	// GetSummary returns document folder counts, stuck documents, webhook queue and recent failures
+	GetSummary(ctx context.Context) (*entity.DashboardSummary, error)
+	// GetDocumentCounts returns the number of documents per folder
+	GetDocumentCounts(ctx context.Context) entity.DocumentCounts
+	// GetStuckDocuments returns the documents in progress for longer than dashboard.stuck_after, oldest first
+	GetStuckDocuments(ctx context.Context) []entity.StuckDocument
+	// GetQuota returns the e-meterai balance per connected account (cached per account)
+	GetQuota(ctx context.Context) ([]entity.EmeteraiQuota, error)
+	// GetTokenExpiry returns the OAuth token state per email
+	GetTokenExpiry(ctx context.Context) ([]entity.TokenExpiry, error)
+}
+
+type dashboardUsecase struct {
+	config     *config.Config
+	docService document.DocumentService
+	navClient  *nav.Client
+	cache      redis.Cache
+	quota      QuotaUsecase
+	oauthRepo  repository.OAuthRepository
+	eventRepo  infraRepo.WebhookEventRepository
+	docEvents  infraRepo.DocumentEventRepository
+	logger     *zap.Logger
+}
+
+func NewDashboardUsecase(
+	cfg *config.Config,
+	docService document.DocumentService,
+	navClient *nav.Client,
+	cache redis.Cache,
+	quota QuotaUsecase,
+	oauthRepo repository.OAuthRepository,
+	eventRepo infraRepo.WebhookEventRepository,
+	docEvents infraRepo.DocumentEventRepository,
+	logger *zap.Logger,
+) DashboardUsecase {
+	return &dashboardUsecase{
+		config:     cfg,
+		docService: docService,
+		navClient:  navClient,
+		cache:      cache,
+		quota:      quota,
+		oauthRepo:  oauthRepo,
+		eventRepo:  eventRepo,
+		docEvents:  docEvents,
+		logger:     logger,
+	}
+}
+
+func (u *dashboardUsecase) GetSummary(ctx context.Context) (*entity.DashboardSummary, error) {
+	folders := u.documentFolders(ctx)
+	summary := &entity.DashboardSummary{
+		Folders:     folders,
+		GeneratedAt: timeutil.Now(),
+	}
+
+	progress := u.listFolder(folders.Progress)
+	summary.Documents = entity.DocumentCounts{
+		Ready:    len(u.listFolder(folders.Ready)),
+		Progress: len(progress),
+		Finish:   len(u.listFolder(folders.Finish)),
+	}
+	summary.StuckDocuments = u.stuckDocuments(progress)
+
+	queue, err := u.eventRepo.CountByStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	summary.WebhookQueue = queue
+
+	failedEvents, err := u.eventRepo.FindAll(ctx, entity.WebhookEventFailed, dashboardFailureLimit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range failedEvents {
+		failedEvents[i].Payload = "" // Keep the summary small, details are in /admin/webhook-events
+	}
+	summary.RecentFailures.WebhookEvents = failedEvents
+
+	errorEvents, err := u.docEvents.FindRecentByType(ctx, entity.DocumentEventError, dashboardFailureLimit)
+	if err != nil {
+		return nil, err
+	}
+	summary.RecentFailures.DocumentErrors = errorEvents
+
+	return summary, nil
+}
+
+func (u *dashboardUsecase) GetDocumentCounts(ctx context.Context) entity.DocumentCounts {
+	folders := u.documentFolders(ctx)
+	return entity.DocumentCounts{
+		Ready:    len(u.listFolder(folders.Ready)),
+		Progress: len(u.listFolder(folders.Progress)),
+		Finish:   len(u.listFolder(folders.Finish)),
+	}
+}
+
+func (u *dashboardUsecase) GetStuckDocuments(ctx context.Context) []entity.StuckDocument {
+	return u.stuckDocuments(u.listFolder(u.documentFolders(ctx).Progress))
+}
+
+// stuckDocuments returns the progress files older than dashboard.stuck_after, oldest first
+func (u *dashboardUsecase) stuckDocuments(progress []document.DocumentFile) []entity.StuckDocument {
+	stuck := []entity.StuckDocument{}
+	stuckAfter := time.Duration(u.config.Dashboard.StuckAfter) * time.Hour
+	for _, file := range progress {
+		age := time.Since(file.ModTime)
+		if age < stuckAfter {
+			continue
+		}
+		stuck = append(stuck, entity.StuckDocument{
+			Filename: file.Name,
+			Since:    file.ModTime,
+			AgeHours: int(age.Hours()),
+		})
+	}
+	sort.Slice(stuck, func(i, j int) bool {
+		return stuck[i].Since.Before(stuck[j].Since)
+	})
+	return stuck
+}
+
+// listFolder lists a document folder. Missing folders are reported as empty rather than
+// failing the whole summary.
+func (u *dashboardUsecase) listFolder(dir string) []document.DocumentFile {
+	files, err := u.docService.ListFiles(dir)
+	if err != nil {
+		u.logger.Warn("Failed to list document folder", zap.String("dir", dir), zap.Error(err))
+		return nil
+	}
+	return files
+}
+
+// documentFolders returns the folders from the NAV setup when available, otherwise from config
+func (u *dashboardUsecase) documentFolders(ctx context.Context) entity.DocumentFolders {
+	folders := entity.DocumentFolders{
+		Ready:    u.docService.GetReadyPath(),
+		Progress: u.docService.GetProgressPath(),
+		Finish:   u.docService.GetFinishPath(),
+	}
+
+	if !u.config.NAVSettings().Enabled {
+		return folders
+	}
+
+	var setup entity.NAVSetup
+	cached, err := u.cache.Get(ctx, dashboardNAVSetupKey)
+	if err == nil && json.Unmarshal([]byte(cached), &setup) == nil {
+		return applyNAVFolders(folders, &setup)
+	}
+
+	fetched, err := u.navClient.GetSetup(ctx)
+	if err != nil || fetched == nil {
+		u.logger.Warn("Failed to get NAV setup for dashboard, using config folders", zap.Error(err))
+		return folders
+	}
+
+	setupJSON, _ := json.Marshal(fetched)
+	if err := u.cache.Set(ctx, dashboardNAVSetupKey, string(setupJSON), dashboardNAVSetupTTL); err != nil {
+		u.logger.Warn("Failed to cache NAV setup for dashboard", zap.Error(err))
+	}
+
+	return applyNAVFolders(folders, fetched)
+}
+
+// applyNAVFolders maps the NAV setup locations (named from NAV's point of view) onto the folders
+func applyNAVFolders(folders entity.DocumentFolders, setup *entity.NAVSetup) entity.DocumentFolders {
+	if setup.FileLocationOut != "" {
+		folders.Ready = setup.FileLocationOut
+	}
+	if setup.FileLocationProcess != "" {
+		folders.Progress = setup.FileLocationProcess
+	}
+	if setup.FileLocationIn != "" {
+		folders.Finish = setup.FileLocationIn
+	}
+	return folders
+}
+
+func (u *dashboardUsecase) GetQuota(ctx context.Context) ([]entity.EmeteraiQuota, error) {
+	return u.quota.GetAllQuotas(ctx)
+}
+
+func (u *dashboardUsecase) GetTokenExpiry(ctx context.Context) ([]entity.TokenExpiry, error) {
+	tokens, err := u.oauthRepo.FindAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list oauth tokens: %w", err)
+	}
+
+	now := timeutil.Now()
+	refreshAge := time.Duration(u.config.OAuth.RefreshTokenAgeDays) * 24 * time.Hour
+
+	result := make([]entity.TokenExpiry, 0, len(tokens))
+	for _, token := range tokens {
+		expiry := entity.TokenExpiry{
+			Email:     token.Email,
+			HasCode:   token.Code != "",
+			UpdatedAt: token.UpdatedAt,
+		}
+
+		if token.AccessToken == "" {
+			expiry.Status = entity.TokenStatusNoToken
+			result = append(result, expiry)
+			continue
+		}
+
+		if !token.ExpiresAt.IsZero() {
+			accessExpiresAt := token.ExpiresAt
+			expiry.AccessTokenExpiresAt = &accessExpiresAt
+		}
+		if refreshAge > 0 && token.RefreshToken != "" {
+			// Tokens are stored on every exchange or refresh, so updated_at starts the refresh token's life
+			refreshExpiresAt := token.UpdatedAt.Add(refreshAge)
+			expiry.RefreshTokenExpiresAt = &refreshExpiresAt
+		}
+
+		switch {
+		case expiry.AccessTokenExpiresAt == nil || now.Before(*expiry.AccessTokenExpiresAt):
+			expiry.Status = entity.TokenStatusValid
+		case expiry.RefreshTokenExpiresAt == nil || now.Before(*expiry.RefreshTokenExpiresAt):
+			expiry.Status = entity.TokenStatusAccessExpired
+		default:
+			expiry.Status = entity.TokenStatusExpired
+		}
+		result = append(result, expiry)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,218 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/infrastructure/notifier"
+	infraRepo "mekari-esign/internal/infrastructure/repository"
+	"mekari-esign/internal/timeutil"
+)
+
+// deadlinePageSize is the number of mappings read per page by a deadline check
+const deadlinePageSize = 200
+
+type DeadlineUsecase interface {
+	// CheckDeadlines sends the reminders that are due for documents still out for signing and
+	// sends expired documents for signing again (with deadline.auto_resubmit)
+	CheckDeadlines(ctx context.Context) error
+}
+
+type deadlineUsecase struct {
+	config       *config.Config
+	mappingRepo  infraRepo.MappingRepository
+	esign        EsignUsecase
+	notifier     notifier.Notifier
+	docEventRepo infraRepo.DocumentEventRepository
+	docEventHub  *DocumentEventHub
+	logger       *zap.Logger
+}
+
+func NewDeadlineUsecase(
+	cfg *config.Config,
+	mappingRepo infraRepo.MappingRepository,
+	esign EsignUsecase,
+	notifier notifier.Notifier,
+	docEventRepo infraRepo.DocumentEventRepository,
+	docEventHub *DocumentEventHub,
+	logger *zap.Logger,
+) DeadlineUsecase {
+	return &deadlineUsecase{
+		config:       cfg,
+		mappingRepo:  mappingRepo,
+		esign:        esign,
+		notifier:     notifier,
+		docEventRepo: docEventRepo,
+		docEventHub:  docEventHub,
+		logger:       logger,
+	}
+}
+
+func (u *deadlineUsecase) CheckDeadlines(ctx context.Context) error {
+	var cursor uint64
+	for {
+		page, err := u.mappingRepo.List(ctx, cursor, deadlinePageSize, "")
+		if err != nil {
+			return fmt.Errorf("failed to list document mappings: %w", err)
+		}
+
+		for i := range page.Mappings {
+			item := &page.Mappings[i]
+			// Stamp documents share the mapping of their original document, which is checked once
+			if item.Key != item.DocumentID {
+				continue
+			}
+			mapping := &item.DocumentMapping
+			switch {
+			case mapping.AwaitsSignatures():
+				u.remind(ctx, mapping)
+			case mapping.SigningStatus == entity.SigningStatusExpired:
+				u.resubmit(ctx, mapping)
+			}
+		}
+
+		if page.NextCursor == "" || ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if cursor, err = strconv.ParseUint(page.NextCursor, 10, 64); err != nil {
+			return fmt.Errorf("invalid document mapping cursor %q: %w", page.NextCursor, err)
+		}
+	}
+}
+
+// remind notifies once for every deadline.reminders stage the document has reached. A document
+// that passed several stages since the last check gets one notification for the latest stage.
+func (u *deadlineUsecase) remind(ctx context.Context, mapping *entity.DocumentMapping) {
+	if mapping.ExpiresAt == nil {
+		return
+	}
+	left := mapping.ExpiresAt.Sub(timeutil.Now())
+	if left <= 0 {
+		return // Mekari reports the expiry by webhook
+	}
+
+	reminders := u.config.Deadline.Reminders
+	due := 0
+	for _, hours := range reminders {
+		if left <= time.Duration(hours)*time.Hour {
+			due++
+		}
+	}
+	if due <= mapping.RemindersSent {
+		return
+	}
+
+	ctx = logger.WithDocument(ctx, mapping.DocumentID, mapping.InvoiceNumber)
+	log := logger.FromContext(ctx, u.logger)
+
+	severity := notifier.SeverityWarning
+	switch due {
+	case len(reminders):
+		severity = notifier.SeverityCritical
+	case 1:
+		severity = notifier.SeverityInfo
+	}
+
+	hoursLeft := int(left.Hours())
+	err := u.notifier.Notify(ctx, &notifier.Notification{
+		Event:    "deadline_reminder",
+		Severity: severity,
+		Title:    "Document signing deadline approaching",
+		Message: fmt.Sprintf("Document %s (invoice %s, entry %d) expires in %d hours (%s) and is not signed by all signers yet.",
+			mapping.Filename, mapping.InvoiceNumber, mapping.EntryNo, hoursLeft, mapping.ExpiresAt.Format(time.RFC3339)),
+		Fields: map[string]interface{}{
+			"document_id":    mapping.DocumentID,
+			"invoice_number": mapping.InvoiceNumber,
+			"entry_no":       mapping.EntryNo,
+			"expires_at":     mapping.ExpiresAt.Format(time.RFC3339),
+			"hours_left":     hoursLeft,
+		},
+	})
+	if err != nil {
+		// Not saved as sent, the next check tries again
+		log.Error("Failed to send deadline reminder", zap.Error(err))
+		return
+	}
+
+	mapping.RemindersSent = due
+	if err := u.mappingRepo.SaveByDocumentID(ctx, mapping.DocumentID, mapping); err != nil {
+		log.Warn("Failed to save deadline reminder to document mapping", zap.Error(err))
+	}
+
+	recordDocumentEvent(ctx, u.docEventRepo, u.docEventHub, u.logger, &entity.DocumentEvent{
+		DocumentID:  mapping.DocumentID,
+		InvoiceNo:   mapping.InvoiceNumber,
+		EntryNo:     mapping.EntryNo,
+		EventType:   entity.DocumentEventReminderSent,
+		Description: fmt.Sprintf("Deadline reminder sent, %d hours left", hoursLeft),
+		Actor:       "system",
+		DedupeKey:   fmt.Sprintf("%s:%s:%d", mapping.DocumentID, entity.DocumentEventReminderSent, due),
+	})
+}
+
+// resubmit sends an expired document for signing again to the same signers, up to
+// deadline.max_resubmits times. The file was moved back to the ready folder on expiry.
+func (u *deadlineUsecase) resubmit(ctx context.Context, mapping *entity.DocumentMapping) {
+	cfg := u.config.Deadline
+	if !cfg.AutoResubmit || mapping.ResubmittedAs != "" || mapping.Resubmits >= cfg.MaxResubmits ||
+		mapping.InvoiceNumber == "" || len(mapping.Signers) == 0 {
+		return
+	}
+
+	ctx = logger.WithDocument(ctx, mapping.DocumentID, mapping.InvoiceNumber)
+	log := logger.FromContext(ctx, u.logger)
+
+	result, err := u.esign.ResubmitDocument(ctx, mapping.DocumentID, "system")
+	if err == nil && !result.Success {
+		err = fmt.Errorf("document was not sent: %s", resultMessage(result))
+	}
+	if err == nil {
+		log.Info("Expired document sent for signing again", zap.String("new_document_id", result.Data.ID))
+		return
+	}
+
+	// Failed attempts count too so a document that keeps failing is not retried forever
+	mapping.Resubmits++
+	if saveErr := u.mappingRepo.SaveByDocumentID(ctx, mapping.DocumentID, mapping); saveErr != nil {
+		log.Warn("Failed to save resubmit attempt to document mapping", zap.Error(saveErr))
+	}
+
+	log.Error("Failed to resubmit expired document", zap.Int("attempt", mapping.Resubmits), zap.Error(err))
+	notifyErr := u.notifier.Notify(ctx, &notifier.Notification{
+		Event:    "document_resubmit_failed",
+		Severity: notifier.SeverityCritical,
+		Title:    "Expired document could not be sent again",
+		Message: fmt.Sprintf("Document %s (invoice %s, entry %d) expired and sending it for signing again failed: %v",
+			mapping.Filename, mapping.InvoiceNumber, mapping.EntryNo, err),
+		Fields: map[string]interface{}{
+			"document_id":    mapping.DocumentID,
+			"invoice_number": mapping.InvoiceNumber,
+			"entry_no":       mapping.EntryNo,
+			"attempt":        mapping.Resubmits,
+		},
+	})
+	if notifyErr != nil {
+		log.Error("Failed to send resubmit failure notification", zap.Error(notifyErr))
+	}
+}
+
+// resultMessage returns the reason a sign request was not sent, e.g. authorization is needed
+func resultMessage(result *entity.GlobalSignResult) string {
+	switch {
+	case result == nil:
+		return "no result"
+	case result.NeedAuth:
+		return "the account needs to authorize again"
+	case result.Message != "":
+		return result.Message
+	default:
+		return "no document returned"
+	}
+}
@@ -0,0 +1,48 @@
+package usecase
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/repository"
+	"mekari-esign/internal/timeutil"
+)
+
+// recordDocumentEvent saves a timeline event and pushes it to live subscribers;
+// failures are logged and never break the main flow
+func recordDocumentEvent(ctx context.Context, repo repository.DocumentEventRepository, hub *DocumentEventHub, logger *zap.Logger, event *entity.DocumentEvent) {
+	if err := saveDocumentEvent(ctx, repo, event); err != nil {
+		logger.Warn("Failed to record document event",
+			zap.String("document_id", event.DocumentID),
+			zap.String("event_type", event.EventType),
+			zap.Error(err),
+		)
+		return
+	}
+
+	publishDocumentEvent(hub, event)
+}
+
+// saveDocumentEvent saves a timeline event without publishing it, for events written in a
+// transaction that are published once it commits
+func saveDocumentEvent(ctx context.Context, repo repository.DocumentEventRepository, event *entity.DocumentEvent) error {
+	if repo == nil || event.DocumentID == "" {
+		return nil
+	}
+
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = timeutil.Now()
+	}
+
+	return repo.Save(ctx, event)
+}
+
+// publishDocumentEvent pushes a saved event to live subscribers
+func publishDocumentEvent(hub *DocumentEventHub, event *entity.DocumentEvent) {
+	// A zero ID means the event was a duplicate of an already recorded milestone
+	if event.ID != 0 {
+		hub.Publish(*event)
+	}
+}
@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"sync"
+
+	"mekari-esign/internal/domain/entity"
+)
+
+// documentEventBuffer is how many events a slow subscriber may fall behind before events are dropped
+const documentEventBuffer = 16
+
+// DocumentEventHub fans out recorded document events to live subscribers (SSE clients)
+type DocumentEventHub struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[chan entity.DocumentEvent]struct{}
+	closed      bool
+}
+
+func NewDocumentEventHub() *DocumentEventHub {
+	return &DocumentEventHub{
+		subscribers: make(map[string]map[chan entity.DocumentEvent]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving new events of a document and a function that unsubscribes
+func (h *DocumentEventHub) Subscribe(documentID string) (<-chan entity.DocumentEvent, func()) {
+	ch := make(chan entity.DocumentEvent, documentEventBuffer)
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+	if h.subscribers[documentID] == nil {
+		h.subscribers[documentID] = make(map[chan entity.DocumentEvent]struct{})
+	}
+	h.subscribers[documentID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers[documentID], ch)
+			if len(h.subscribers[documentID]) == 0 {
+				delete(h.subscribers, documentID)
+			}
+			h.mu.Unlock()
+		})
+	}
+}
+
+// Publish sends an event to all subscribers of its document without blocking.
+// Subscribers that are full miss the event; they can catch up from the timeline.
+func (h *DocumentEventHub) Publish(event entity.DocumentEvent) {
+	if h == nil {
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for ch := range h.subscribers[event.DocumentID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Close closes every subscriber channel so open streams end (used on shutdown)
+func (h *DocumentEventHub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+	h.closed = true
+
+	for documentID, subs := range h.subscribers {
+		for ch := range subs {
+			close(ch)
+		}
+		delete(h.subscribers, documentID)
+	}
+}
+
+// Subscribers returns the number of live subscribers across all documents
+func (h *DocumentEventHub) Subscribers() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	count := 0
+	for _, subs := range h.subscribers {
+		count += len(subs)
+	}
+	return count
+}
@@ -0,0 +1,200 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/domain/entity"
+	infraRepo "mekari-esign/internal/infrastructure/repository"
+)
+
+// documentMappingMaxLimit caps the number of mappings returned per page
+const documentMappingMaxLimit = 500
+
+// ErrDocumentMappingNotFound is returned when no mapping is stored for a document ID
+var ErrDocumentMappingNotFound = infraRepo.ErrMappingNotFound
+
+// ListDocumentMappings returns the mappings stored by document ID, optionally filtered by invoice number
+func (u *esignUsecase) ListDocumentMappings(ctx context.Context, cursor uint64, limit int, invoice string) (*entity.DocumentMappingPage, error) {
+	if limit <= 0 || limit > documentMappingMaxLimit {
+		limit = documentMappingMaxLimit
+	}
+	return u.mappingRepo.List(ctx, cursor, limit, invoice)
+}
+
+// UpdateDocumentMapping applies a partial update to the mapping of a document and to every key sharing it
+func (u *esignUsecase) UpdateDocumentMapping(ctx context.Context, documentID string, req *entity.UpdateDocumentMappingRequest, actor string) (*entity.DocumentMapping, error) {
+	mapping, err := u.GetDocumentMapping(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	oldEntryNo := mapping.EntryNo
+
+	var changed []string
+	if req.Email != nil {
+		mapping.Email = *req.Email
+		changed = append(changed, "email")
+	}
+	if req.InvoiceNumber != nil {
+		mapping.InvoiceNumber = *req.InvoiceNumber
+		changed = append(changed, "invoice_number")
+	}
+	if req.Filename != nil {
+		mapping.Filename = *req.Filename
+		changed = append(changed, "filename")
+	}
+	if req.StampPositions != nil {
+		mapping.StampPositions = req.StampPositions
+		mapping.Stamps = nil
+		changed = append(changed, "stamp_positions")
+	}
+	if req.Stamps != nil {
+		mapping.Stamps = req.Stamps
+		mapping.StampPositions = nil
+		changed = append(changed, "stamps")
+	}
+	if req.DocumentDeadline != nil {
+		mapping.DocumentDeadline = req.DocumentDeadline
+		changed = append(changed, "document_deadline")
+	}
+	if req.EntryNo != nil {
+		mapping.EntryNo = *req.EntryNo
+		changed = append(changed, "entry_no")
+	}
+	if req.Signing != nil {
+		mapping.Signing = *req.Signing
+		changed = append(changed, "signing")
+	}
+	if req.Stamping != nil {
+		mapping.Stamping = *req.Stamping
+		changed = append(changed, "stamping")
+	}
+	if len(changed) == 0 {
+		return mapping, nil
+	}
+
+	event := &entity.DocumentEvent{
+		DocumentID:  mapping.DocumentID,
+		InvoiceNo:   mapping.InvoiceNumber,
+		EntryNo:     mapping.EntryNo,
+		EventType:   entity.DocumentEventMappingUpdated,
+		Description: "Document mapping updated: " + strings.Join(changed, ", "),
+		Actor:       actor,
+	}
+	err = u.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		for _, id := range u.mappingDocumentIDs(ctx, documentID, mapping.DocumentID) {
+			if err := u.mappingRepo.SaveByDocumentID(ctx, id, mapping); err != nil {
+				return err
+			}
+		}
+
+		// Move the entry_no mapping so stamping finds it under the new entry number
+		oldEntryOwned := oldEntryNo != 0 && u.entryNoBelongsTo(ctx, oldEntryNo, mapping.DocumentID)
+		if mapping.EntryNo != oldEntryNo {
+			if oldEntryOwned {
+				if err := u.mappingRepo.DeleteByEntryNo(ctx, oldEntryNo); err != nil {
+					return fmt.Errorf("failed to delete old entry no mapping: %w", err)
+				}
+			}
+			if err := u.mappingRepo.SaveByEntryNo(ctx, mapping.EntryNo, mapping); err != nil {
+				return fmt.Errorf("failed to save entry no mapping: %w", err)
+			}
+		} else if oldEntryOwned {
+			if err := u.mappingRepo.SaveByEntryNo(ctx, oldEntryNo, mapping); err != nil {
+				return err
+			}
+		}
+
+		return saveDocumentEvent(ctx, u.eventRepo, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	u.logger.Info("Document mapping updated",
+		zap.String("document_id", documentID),
+		zap.Strings("fields", changed),
+		zap.String("actor", actor),
+	)
+	publishDocumentEvent(u.eventHub, event)
+
+	return mapping, nil
+}
+
+// DeleteDocumentMapping removes the mapping of a document and every key sharing it
+func (u *esignUsecase) DeleteDocumentMapping(ctx context.Context, documentID string, actor string) error {
+	mapping, err := u.GetDocumentMapping(ctx, documentID)
+	if err != nil {
+		return err
+	}
+
+	ids := u.mappingDocumentIDs(ctx, documentID, mapping.DocumentID)
+	event := &entity.DocumentEvent{
+		DocumentID:  mapping.DocumentID,
+		InvoiceNo:   mapping.InvoiceNumber,
+		EntryNo:     mapping.EntryNo,
+		EventType:   entity.DocumentEventMappingDeleted,
+		Description: "Document mapping deleted",
+		Actor:       actor,
+	}
+	err = u.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := u.mappingRepo.DeleteByDocumentID(ctx, ids...); err != nil {
+			return err
+		}
+		if mapping.EntryNo != 0 && u.entryNoBelongsTo(ctx, mapping.EntryNo, mapping.DocumentID) {
+			if err := u.mappingRepo.DeleteByEntryNo(ctx, mapping.EntryNo); err != nil {
+				return err
+			}
+		}
+		return saveDocumentEvent(ctx, u.eventRepo, event)
+	})
+	if err != nil {
+		return err
+	}
+
+	u.logger.Info("Document mapping deleted",
+		zap.String("document_id", documentID),
+		zap.Strings("document_ids", ids),
+		zap.Int("entry_no", mapping.EntryNo),
+		zap.String("actor", actor),
+	)
+	publishDocumentEvent(u.eventHub, event)
+
+	return nil
+}
+
+// CleanupDocumentMappings archives (with mapping.archive) and deletes the mappings without activity
+// for mapping.ttl_days
+func (u *esignUsecase) CleanupDocumentMappings(ctx context.Context) (int, error) {
+	var archive func(mapping *entity.ArchivedDocumentMapping) error
+	if u.config.Mapping.Archive {
+		archive = func(mapping *entity.ArchivedDocumentMapping) error {
+			return u.archiveRepo.Save(ctx, mapping)
+		}
+	}
+	return u.mappingRepo.PurgeExpired(ctx, archive)
+}
+
+// mappingDocumentIDs returns the requested document ID plus the original document sharing its mapping
+func (u *esignUsecase) mappingDocumentIDs(ctx context.Context, documentID, originalID string) []string {
+	ids := []string{documentID}
+	if originalID != "" && originalID != documentID {
+		if _, err := u.mappingRepo.FindByDocumentID(ctx, originalID); err == nil {
+			ids = append(ids, originalID)
+		}
+	}
+	return ids
+}
+
+// entryNoBelongsTo reports whether the entry_no mapping holds the mapping of the given document,
+// so a reused entry number of another document is never touched
+func (u *esignUsecase) entryNoBelongsTo(ctx context.Context, entryNo int, documentID string) bool {
+	mapping, err := u.mappingRepo.FindByEntryNo(ctx, entryNo)
+	if err != nil {
+		return false
+	}
+	return mapping.DocumentID == documentID
+}
@@ -3,46 +3,78 @@ package usecase
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"go.uber.org/zap"
 
 	"mekari-esign/internal/config"
 	"mekari-esign/internal/domain/entity"
 	"mekari-esign/internal/domain/repository"
+	"mekari-esign/internal/infrastructure/document"
+	"mekari-esign/internal/infrastructure/logger"
 	"mekari-esign/internal/infrastructure/nav"
 	"mekari-esign/internal/infrastructure/redis"
+	infraRepo "mekari-esign/internal/infrastructure/repository"
+	"mekari-esign/internal/timeutil"
 )
 
 const (
-	// Redis key prefix for document tracking
-	documentKeyPrefix = "mekari:document:"
 	// Redis key prefix for NAV setup cache (by entry_no)
-	navSetupPrefix = "mekari:nav_setup:"
-	// Redis key prefix for entry_no cache (by document_id)
-	entryNoKeyPrefix = "mekari:entry_no:"
+	navSetupPrefix = "nav_setup:"
+	// Lease of an invoice while its sign request is sent, renewed until the request is done
+	signLeaseTTL = 30 * time.Second
 )
 
-// DocumentMapping stores document info for webhook processing
-type DocumentMapping struct {
-	DocumentID       string                   `json:"document_id"`
-	Email            string                   `json:"email"`
-	InvoiceNumber    string                   `json:"invoice_number"`
-	Filename         string                   `json:"filename"`
-	StampPositions   *entity.StampPosition    `json:"stamp_positions,omitempty"`
-	DocumentDeadline *entity.DocumentDeadline `json:"document_deadline,omitempty"`
-	EntryNo          int                      `json:"entry_no"`
-	Signing          bool                     `json:"signing"`
-	Stamping         bool                     `json:"stamping"`
+var (
+	// ErrEmailRequired is returned when a sign request has no email while OAuth2 is used
+	ErrEmailRequired = errors.New("email is required for OAuth2 authentication")
+	// ErrNotSigned is returned for stamping-only requests of documents that were not signed first
+	ErrNotSigned = errors.New("failed to stamping, Please sign first your document")
+	// ErrSignInProgress is returned while another request for the same invoice is being sent
+	ErrSignInProgress = errors.New("a sign request for this invoice is already in progress")
+)
+
+// SignerError is returned when signers cannot be sent to Mekari, e.g. an invalid phone number
+type SignerError struct {
+	Fields []entity.FieldError
+}
+
+func (e *SignerError) Error() string {
+	return fmt.Sprintf("invalid signer: %s %s", e.Fields[0].Field, e.Fields[0].Message)
 }
 
 type EsignUsecase interface {
 	GetProfile(ctx context.Context, email string) (*entity.Profile, error)
 	GetDocuments(ctx context.Context, email string, page, perPage int) (*entity.DocumentListResponse, error)
 	GlobalRequestSign(ctx context.Context, req *entity.GlobalSignRequest) (*entity.GlobalSignResult, error)
-	// GetDocumentMapping retrieves email and invoice number by document ID from Redis
-	GetDocumentMapping(ctx context.Context, documentID string) (*DocumentMapping, error)
+	// GetDocumentMapping retrieves email and invoice number by document ID
+	GetDocumentMapping(ctx context.Context, documentID string) (*entity.DocumentMapping, error)
+	// ListDocumentMappings returns a page of stored document mappings, optionally filtered by invoice number
+	ListDocumentMappings(ctx context.Context, cursor uint64, limit int, invoice string) (*entity.DocumentMappingPage, error)
+	// UpdateDocumentMapping partially updates a document mapping and records who changed it
+	UpdateDocumentMapping(ctx context.Context, documentID string, req *entity.UpdateDocumentMappingRequest, actor string) (*entity.DocumentMapping, error)
+	// DeleteDocumentMapping removes a document mapping and its entry_no key
+	DeleteDocumentMapping(ctx context.Context, documentID string, actor string) error
+	// ResubmitDocument sends an expired, declined or stuck document for signing again to its stored
+	// signers as a new document, voiding the old request when it is still out for signing
+	ResubmitDocument(ctx context.Context, documentID, actor string) (*entity.GlobalSignResult, error)
+	// CleanupDocumentMappings archives and deletes mapping keys close to expiry, returning how many were removed
+	CleanupDocumentMappings(ctx context.Context) (int, error)
+	// GetDocumentTimeline returns the ordered lifecycle events of a document
+	GetDocumentTimeline(ctx context.Context, documentID string) (*entity.DocumentTimeline, error)
+	// GetInvoiceStatus returns the processing state, document and file location of the document
+	// last submitted for an invoice
+	GetInvoiceStatus(ctx context.Context, invoiceNo string) (*entity.InvoiceStatus, error)
+	// GetSigningLinks returns the signing link of each signer of a document, as stored from its
+	// webhooks or, when none is stored or refresh is set, fetched from Mekari
+	GetSigningLinks(ctx context.Context, documentID string, refresh bool) (*entity.DocumentSigningLinks, error)
+	// VerifyDocument checks the digital signatures of a PDF; without content the file is read from
+	// the finish folder by filename
+	VerifyDocument(ctx context.Context, filename string, content []byte) (*entity.VerificationReport, error)
 }
 
 type esignUsecase struct {
@@ -50,20 +82,34 @@ type esignUsecase struct {
 	repo         repository.EsignRepository
 	oauthUsecase OAuthUsecase
 	navClient    *nav.Client
-	redisClient  *redis.RedisClient
+	cache        redis.Cache
+	leases       *redis.Leases
+	mappingRepo  infraRepo.MappingRepository
 	logger       *zap.Logger
 	wbUsecase    WebhookUsecase
+	eventRepo    infraRepo.DocumentEventRepository
+	eventHub     *DocumentEventHub
+	archiveRepo  infraRepo.MappingArchiveRepository
+	transactor   infraRepo.Transactor
+	docService   document.DocumentService
 }
 
-func NewEsignUsecase(cfg *config.Config, repo repository.EsignRepository, oauthUsecase OAuthUsecase, navClient *nav.Client, redisClient *redis.RedisClient, logger *zap.Logger, webhook WebhookUsecase) EsignUsecase {
+func NewEsignUsecase(cfg *config.Config, repo repository.EsignRepository, oauthUsecase OAuthUsecase, navClient *nav.Client, cache redis.Cache, leases *redis.Leases, mappingRepo infraRepo.MappingRepository, logger *zap.Logger, webhook WebhookUsecase, eventRepo infraRepo.DocumentEventRepository, eventHub *DocumentEventHub, archiveRepo infraRepo.MappingArchiveRepository, transactor infraRepo.Transactor, docService document.DocumentService) EsignUsecase {
 	return &esignUsecase{
 		config:       cfg,
 		repo:         repo,
 		oauthUsecase: oauthUsecase,
 		navClient:    navClient,
-		redisClient:  redisClient,
-		logger:       logger,
+		cache:        cache,
+		leases:       leases,
+		mappingRepo:  mappingRepo,
+		logger:       logger.Named("esign"),
 		wbUsecase:    webhook,
+		eventRepo:    eventRepo,
+		eventHub:     eventHub,
+		archiveRepo:  archiveRepo,
+		transactor:   transactor,
+		docService:   docService,
 	}
 }
 
@@ -113,36 +159,38 @@ func (u *esignUsecase) GetDocuments(ctx context.Context, email string, page, per
 }
 
 func (u *esignUsecase) GlobalRequestSign(ctx context.Context, req *entity.GlobalSignRequest) (*entity.GlobalSignResult, error) {
-	u.logger.Info("Requesting global document sign",
+	ctx = logger.WithDocument(ctx, "", req.InvoiceNumber)
+	log := logger.FromContext(ctx, u.logger)
+	log.Info("Requesting global document sign",
 		zap.String("email", req.Email),
-		zap.String("invoice_number", req.InvoiceNumber),
 		zap.Int("signers_count", len(req.Signers)),
+		zap.Bool("enforce_order", req.EnforceOrder),
 	)
 
 	// Fetch and cache NAV setup at the beginning (entry_no = 1 for new requests)
 	entryNo := req.EntryNo
 	if err := u.fetchAndCacheNAVSetup(ctx, entryNo); err != nil {
-		u.logger.Warn("Failed to fetch NAV setup, will use config fallback",
+		log.Warn("Failed to fetch NAV setup, will use config fallback",
 			zap.Error(err),
 		)
 	}
 
 	// Validate email (only required for OAuth2)
 	if u.config.Mekari.IsOAuth2() && req.Email == "" {
-		return nil, fmt.Errorf("email is required for OAuth2 authentication")
+		return nil, ErrEmailRequired
 	}
 
 	// Check if OAuth code exists for this email (only for OAuth2 auth)
 	if u.config.Mekari.IsOAuth2() {
 		codeCheck, err := u.oauthUsecase.CheckCode(ctx, req.Email)
 		if err != nil {
-			u.logger.Error("Failed to check OAuth code", zap.Error(err))
+			log.Error("Failed to check OAuth code", zap.Error(err))
 			return nil, fmt.Errorf("failed to check OAuth code: %w", err)
 		}
 
 		// If no code exists, return redirect URL
 		if !codeCheck.HasCode {
-			u.logger.Info("No OAuth code found, returning redirect URL",
+			log.Info("No OAuth code found, returning redirect URL",
 				zap.String("email", req.Email),
 				zap.String("redirect_url", codeCheck.RedirectURL),
 			)
@@ -155,149 +203,253 @@ func (u *esignUsecase) GlobalRequestSign(ctx context.Context, req *entity.Global
 		}
 	}
 
-	if req.Signing == false && req.Stamping == true {
-		return u.stampingProcess(ctx, req, entryNo)
+	// Requests for the same invoice, e.g. NAV retrying on another instance, would move the same file
+	lease, err := u.leases.Acquire(ctx, "invoice:"+req.InvoiceNumber, signLeaseTTL, 0)
+	if errors.Is(err, redis.ErrLeaseHeld) {
+		return nil, ErrSignInProgress
 	}
+	if err != nil {
+		return nil, err
+	}
+	defer lease.Release(context.WithoutCancel(ctx))
 
-	// Validate request
-	if len(req.Signers) == 0 {
-		return nil, fmt.Errorf("at least one signer is required")
+	// Request fields are validated by the handler (see entity struct tags)
+	if req.IsStampingOnly() {
+		return u.stampingProcess(ctx, req, entryNo)
 	}
 
-	// Validate signers
-	for i, signer := range req.Signers {
-		if signer.Name == "" {
-			return nil, fmt.Errorf("signer %d: name is required", i+1)
-		}
-		if signer.Email == "" {
-			return nil, fmt.Errorf("signer %d: email is required", i+1)
-		}
-		if signer.SignPage <= 0 {
-			return nil, fmt.Errorf("signer %d: sign_page must be greater than 0", i+1)
-		}
-		if signer.SignaturePositions == nil {
-			return nil, fmt.Errorf("signer %d: signature_positions is required", i+1)
-		}
+	if err := u.applySignerPolicy(ctx, req.Signers); err != nil {
+		return nil, err
 	}
 
-	// Validate document deadline if provided
-	if req.DocumentDeadline != nil {
-		if req.DocumentDeadline.SigningDeadline != 0 {
-			if req.DocumentDeadline.SigningDeadline < 3 || req.DocumentDeadline.SigningDeadline > 31 {
-				return nil, fmt.Errorf("signing_deadline must be between 3 and 31")
-			}
+	// Attachments are appended to the document before it is sent, or sent after it as linked documents
+	var (
+		attachments []attachmentFile
+		mergedFrom  string
+	)
+	if len(req.Attachments) > 0 {
+		files, err := u.readAttachments(ctx, req)
+		if err != nil {
+			return nil, err
 		}
-		if req.DocumentDeadline.DaysReminderAfterReceive != 0 {
-			if req.DocumentDeadline.DaysReminderAfterReceive < 1 || req.DocumentDeadline.DaysReminderAfterReceive > 31 {
-				return nil, fmt.Errorf("days_reminder_after_received must be between 1 and 31")
+		attachments = files
+		if !u.usesSeparateAttachments(req) {
+			if mergedFrom, err = u.mergeAttachments(ctx, req, attachments); err != nil {
+				return nil, err
 			}
 		}
-		validReminders := map[string]bool{"": true, "none": true, "daily": true, "three_days": true, "weekly": true, "monthly": true}
-		if !validReminders[req.DocumentDeadline.RecurringReminder] {
-			return nil, fmt.Errorf("recurring_reminder must be one of: none, daily, three_days, weekly, monthly")
-		}
 	}
 
 	// Call repository to make the API request
 	response, err := u.repo.GlobalRequestSign(ctx, req.Email, req)
 	if err != nil {
-		u.logger.Error("Failed to request global sign",
+		log.Error("Failed to request global sign",
 			zap.String("email", req.Email),
 			zap.Error(err),
 		)
 		return nil, err
 	}
 
-	u.logger.Info("Successfully requested global sign",
+	ctx = logger.WithDocument(ctx, response.Data.ID, "")
+	log = logger.FromContext(ctx, u.logger)
+	log.Info("Successfully requested global sign",
 		zap.String("doc_id", response.Data.Attributes.DocID),
 		zap.String("status", response.Data.Attributes.Status),
 	)
 
-	// Save document mapping to Redis for webhook processing
-	u.saveDocumentAndEntryNoToCache(ctx, req, response, entryNo)
+	// Mekari reports every signer of a new document as pending; only the first in order can sign
+	if req.EnforceOrder {
+		signers := response.Data.Attributes.Signers
+		for i := range signers {
+			if signers[i].Sequence == 0 {
+				signers[i].Sequence = signerOrder(req.Signers, signers[i].Email)
+			}
+		}
+		entity.MarkWaitingSigners(signers)
+	}
+
+	// The merged document was written to progress, so the files it was made of leave the ready folder
+	message := "Document sign request created successfully"
+	var linked []entity.LinkedDocument
+	if u.usesSeparateAttachments(req) {
+		var failed []string
+		linked, failed = u.sendAttachments(ctx, req, response.Data.ID, attachments)
+		if len(failed) > 0 {
+			message = fmt.Sprintf("Document sign request created, but sending attachment(s) %s failed", strings.Join(failed, ", "))
+		}
+	} else if len(attachments) > 0 {
+		if mergedFrom != "" {
+			u.removeSentFile(ctx, req.Document.Filename, mergedFrom)
+		}
+		for _, file := range attachments {
+			u.removeSentFile(ctx, file.filename, file.dir)
+		}
+	}
+
+	// Save the document mapping for webhook processing together with the submitted event
+	event := &entity.DocumentEvent{
+		DocumentID:  response.Data.ID,
+		InvoiceNo:   req.InvoiceNumber,
+		EntryNo:     req.EntryNo,
+		EventType:   entity.DocumentEventSubmitted,
+		Description: fmt.Sprintf("Document %s submitted for signing to %d signer(s)", response.Data.Attributes.Filename, len(req.Signers)),
+		Actor:       req.Email,
+		DedupeKey:   response.Data.ID + ":" + entity.DocumentEventSubmitted,
+	}
+	if len(linked) > 0 {
+		event.Description += fmt.Sprintf(" with %d linked attachment(s)", len(linked))
+	}
+	err = u.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := u.saveDocumentMapping(ctx, req, response, entryNo, linked); err != nil {
+			return err
+		}
+		if err := saveDocumentEvent(ctx, u.eventRepo, event); err != nil {
+			return fmt.Errorf("failed to record submitted event: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		// Don't fail the request, the document already exists at Mekari
+		log.Error("Failed to save document mapping",
+			zap.String("email", req.Email),
+			zap.Int("entry_no", entryNo),
+			zap.Error(err),
+		)
+	} else {
+		log.Info("Document mapping saved",
+			zap.String("email", req.Email),
+			zap.Int("stamp_positions", len(req.AllStampPositions())),
+		)
+		publishDocumentEvent(u.eventHub, event)
+	}
 
 	return &entity.GlobalSignResult{
 		Success: true,
 		Data:    response.Data,
-		Message: "Document sign request created successfully",
+		Message: message,
 	}, nil
 }
 
-func (u *esignUsecase) saveDocumentAndEntryNoToCache(ctx context.Context, req *entity.GlobalSignRequest, response *entity.GlobalSignResponse, entryNo int) {
-	// Key: mekari:document:{document_id}, Value: JSON with all necessary info
-	documentKey := documentKeyPrefix + response.Data.ID
-	mapping := DocumentMapping{
+// applySignerPolicy normalizes the phone numbers of the signers and requires OTP from external
+// signers when mekari.signers.require_otp_external is set
+func (u *esignUsecase) applySignerPolicy(ctx context.Context, signers []entity.SignerRequest) error {
+	log := logger.FromContext(ctx, u.logger)
+	policy := &u.config.Mekari.Signers
+
+	var fields []entity.FieldError
+	for i := range signers {
+		signer := &signers[i]
+		if signer.Phone != "" {
+			countryCode := signer.CountryCode
+			if countryCode == "" {
+				countryCode = policy.DefaultCountryCode
+			}
+			phone, err := entity.NormalizePhone(signer.Phone, countryCode)
+			if err != nil {
+				fields = append(fields, entity.FieldError{Field: fmt.Sprintf("signers[%d].phone", i), Message: err.Error()})
+				continue
+			}
+			signer.Phone, signer.CountryCode = phone.Number, phone.CountryCode
+		}
+
+		if policy.RequireOTPExternal && !signer.RequiresOTP && !policy.IsInternal(signer.Email) {
+			log.Info("Requiring OTP from external signer", zap.String("signer", signer.Email))
+			signer.RequiresOTP = true
+		}
+	}
+
+	if len(fields) > 0 {
+		return &SignerError{Fields: fields}
+	}
+	return nil
+}
+
+// signerOrder returns the requested order of the signer with email, 0 when not requested
+func signerOrder(signers []entity.SignerRequest, email string) int {
+	for _, signer := range signers {
+		if strings.EqualFold(signer.Email, email) {
+			return signer.Order
+		}
+	}
+	return 0
+}
+
+// saveDocumentMapping stores the mapping of a new sign request by document ID and entry number,
+// with the attachments sent as linked documents
+func (u *esignUsecase) saveDocumentMapping(ctx context.Context, req *entity.GlobalSignRequest, response *entity.GlobalSignResponse, entryNo int, attachments []entity.LinkedDocument) error {
+	mapping := &entity.DocumentMapping{
 		DocumentID:       response.Data.ID,
 		Email:            req.Email,
 		InvoiceNumber:    req.InvoiceNumber,
 		Filename:         response.Data.Attributes.Filename,
 		StampPositions:   req.StampPositions,
+		Stamps:           req.Stamps,
 		DocumentDeadline: req.DocumentDeadline,
 		EntryNo:          req.EntryNo,
 		Signing:          req.Signing,
 		Stamping:         req.Stamping,
+		EnforceOrder:     req.EnforceOrder,
+		StampPlacement:   req.StampPlacement,
+		Signers:          req.Signers,
+		Resubmits:        req.Resubmits,
+		Attachments:      attachments,
 	}
-	mappingJSON, _ := json.Marshal(mapping)
-	if err := u.redisClient.Set(ctx, documentKey, string(mappingJSON), 0); err != nil {
-		u.logger.Warn("Failed to save document mapping to Redis",
-			zap.String("document_id", response.Data.ID),
-			zap.String("email", req.Email),
-			zap.String("invoice_number", req.InvoiceNumber),
-			zap.Error(err),
-		)
-		// Don't fail the request, just log warning
-	} else {
-		u.logger.Info("Document mapping saved to Redis",
-			zap.String("key", documentKey),
-			zap.String("email", req.Email),
-			zap.String("invoice_number", req.InvoiceNumber),
-			zap.Bool("has_stamp_positions", req.StampPositions != nil),
-		)
+	submittedAt := timeutil.Now()
+	mapping.SubmittedAt = &submittedAt
+	if expiresAt, ok := entity.SigningExpiry(&response.Data.Attributes, req.DocumentDeadline, submittedAt); ok {
+		mapping.ExpiresAt = &expiresAt
 	}
-
-	byEntryNoKey := entryNoKeyPrefix + strconv.Itoa(entryNo)
-	if err := u.redisClient.Set(ctx, byEntryNoKey, string(mappingJSON), 0); err != nil {
-		u.logger.Warn("Failed to save entry no mapping to Redis",
-			zap.String("document_id", response.Data.ID),
-			zap.String("email", req.Email),
-			zap.String("invoice_number", req.InvoiceNumber),
-			zap.Error(err),
-		)
-		// Don't fail the request, just log warning
+	if err := u.mappingRepo.SaveByDocumentID(ctx, response.Data.ID, mapping); err != nil {
+		return err
 	}
+	if err := u.mappingRepo.SaveByEntryNo(ctx, entryNo, mapping); err != nil {
+		return fmt.Errorf("failed to save entry no mapping: %w", err)
+	}
+	return nil
 }
 
 func (u *esignUsecase) stampingProcess(ctx context.Context, req *entity.GlobalSignRequest, entryNo int) (*entity.GlobalSignResult, error) {
-	// Get document mapping from Redis using document ID
-	byEntryNoKey := entryNoKeyPrefix + strconv.Itoa(entryNo)
-	mappingData, err := u.redisClient.Get(ctx, byEntryNoKey)
-	if err != nil || mappingData == "" {
-		u.logger.Error("Failed to get initial entry no mapping from Redis",
+	log := logger.FromContext(ctx, u.logger)
+
+	// Get document mapping saved with the sign request of this entry
+	mapping, err := u.mappingRepo.FindByEntryNo(ctx, entryNo)
+	if err != nil {
+		log.Error("Failed to get initial entry no mapping",
 			zap.Int("entry_no", entryNo),
 			zap.Error(err),
 		)
-		return nil, fmt.Errorf("failed to stamping, Please sign first your document: %s", req.InvoiceNumber)
+		return nil, fmt.Errorf("%w: %s", ErrNotSigned, req.InvoiceNumber)
+	}
+	if mapping.DocumentID == "" {
+		// Values of old versions held just the email
+		return nil, fmt.Errorf("initial entry no mapping of %d has no document ID", entryNo)
 	}
+	if err := u.mappingRepo.TouchByEntryNo(ctx, entryNo); err != nil {
+		log.Warn("Failed to refresh entry no mapping expiry", zap.Error(err))
+	}
+
+	ctx = logger.WithDocument(ctx, mapping.DocumentID, mapping.InvoiceNumber)
+	log = logger.FromContext(ctx, u.logger)
 
-	// Parse document mapping
-	var mapping DocumentMapping
-	if err := json.Unmarshal([]byte(mappingData), &mapping); err != nil {
-		// Fallback: old format might be just email string
-		return nil, fmt.Errorf("failed to parse initial entry no mapping: %w", err)
+	// v2 requests may send the stamp positions with the stamping request itself
+	if len(req.Stamps) > 0 {
+		mapping.Stamps = req.Stamps
+		mapping.StampPositions = nil
+	}
+	if req.StampPlacement != "" {
+		mapping.StampPlacement = req.StampPlacement
 	}
 
 	signedContent, err := u.wbUsecase.DownloadDocument(ctx, req.Email, fmt.Sprintf("/documents/%s/download", mapping.DocumentID))
 	if err != nil {
-		u.logger.Error("Failed to download signed document",
-			zap.String("document_id", mapping.DocumentID),
+		log.Error("Failed to download signed document",
 			zap.Error(err),
 		)
 		return nil, fmt.Errorf("failed to download signed document: %w", err)
 	}
 
-	if err := u.wbUsecase.RequestStamping(ctx, req.Email, signedContent, mapping); err != nil {
-		u.logger.Error("Failed to request stamping",
-			zap.String("document_id", mapping.DocumentID),
+	if err := u.wbUsecase.RequestStamping(ctx, req.Email, signedContent, *mapping); err != nil {
+		log.Error("Failed to request stamping",
 			zap.Error(err),
 		)
 		return nil, fmt.Errorf("failed to request stamping: %w", err)
@@ -309,32 +461,48 @@ func (u *esignUsecase) stampingProcess(ctx context.Context, req *entity.GlobalSi
 	}, nil
 }
 
-// GetDocumentMapping retrieves email and invoice number by document ID from Redis
-func (u *esignUsecase) GetDocumentMapping(ctx context.Context, documentID string) (*DocumentMapping, error) {
-	documentKey := documentKeyPrefix + documentID
+// GetDocumentMapping retrieves email and invoice number by document ID
+func (u *esignUsecase) GetDocumentMapping(ctx context.Context, documentID string) (*entity.DocumentMapping, error) {
+	return u.mappingRepo.FindByDocumentID(ctx, documentID)
+}
 
-	data, err := u.redisClient.Get(ctx, documentKey)
+// GetDocumentTimeline returns the recorded events of a document ordered by time
+func (u *esignUsecase) GetDocumentTimeline(ctx context.Context, documentID string) (*entity.DocumentTimeline, error) {
+	events, err := u.eventRepo.FindByDocumentID(ctx, documentID)
 	if err != nil {
-		return nil, fmt.Errorf("document not found: %w", err)
+		u.logger.Error("Failed to get document events",
+			zap.String("document_id", documentID),
+			zap.Error(err),
+		)
+		return nil, err
 	}
 
-	var mapping DocumentMapping
-	if err := json.Unmarshal([]byte(data), &mapping); err != nil {
-		// Fallback: old format might be just email string
-		return &DocumentMapping{Email: data}, nil
+	timeline := &entity.DocumentTimeline{
+		DocumentID: documentID,
+		Events:     events,
+	}
+
+	// Take invoice and entry number from the first event that carries them
+	for _, event := range events {
+		if event.InvoiceNo != "" {
+			timeline.InvoiceNo = event.InvoiceNo
+			timeline.EntryNo = event.EntryNo
+			break
+		}
 	}
 
-	return &mapping, nil
+	return timeline, nil
 }
 
 // fetchAndCacheNAVSetup fetches NAV setup and caches it to Redis by entry_no
 func (u *esignUsecase) fetchAndCacheNAVSetup(ctx context.Context, entryNo int) error {
+	log := logger.FromContext(ctx, u.logger)
 	cacheKey := navSetupPrefix + strconv.Itoa(entryNo)
 
 	// Check if already cached
-	cached, err := u.redisClient.Get(ctx, cacheKey)
+	cached, err := u.cache.Get(ctx, cacheKey)
 	if err == nil && cached != "" {
-		u.logger.Debug("NAV setup already cached", zap.Int("entry_no", entryNo))
+		log.Debug("NAV setup already cached", zap.Int("entry_no", entryNo))
 		return nil
 	}
 
@@ -349,11 +517,11 @@ func (u *esignUsecase) fetchAndCacheNAVSetup(ctx context.Context, entryNo int) e
 
 	// Cache the setup (no expiration - permanent for this entry_no)
 	setupJSON, _ := json.Marshal(setup)
-	if err := u.redisClient.Set(ctx, cacheKey, string(setupJSON), 0); err != nil {
+	if err := u.cache.Set(ctx, cacheKey, string(setupJSON), 0); err != nil {
 		return fmt.Errorf("failed to cache NAV setup: %w", err)
 	}
 
-	u.logger.Info("NAV setup fetched and cached",
+	log.Info("NAV setup fetched and cached",
 		zap.Int("entry_no", entryNo),
 		zap.String("key", cacheKey),
 		zap.String("file_location_in", setup.FileLocationIn),
@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/logger"
+	infraRepo "mekari-esign/internal/infrastructure/repository"
+)
+
+// ErrInvoiceNotFound is returned for invoices without a document submitted for signing
+var ErrInvoiceNotFound = errors.New("no document was submitted for this invoice")
+
+// GetInvoiceStatus returns the processing state of the document last submitted for an invoice,
+// taken from its timeline and mapping without calling Mekari or NAV
+func (u *esignUsecase) GetInvoiceStatus(ctx context.Context, invoiceNo string) (*entity.InvoiceStatus, error) {
+	events, err := u.eventRepo.FindLatestByInvoice(ctx, invoiceNo)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, ErrInvoiceNotFound
+	}
+
+	documentID := events[0].DocumentID
+	mapping, err := u.mappingRepo.FindByDocumentID(ctx, documentID)
+	if err != nil {
+		if !errors.Is(err, infraRepo.ErrMappingNotFound) {
+			logger.FromContext(ctx, u.logger).Warn("Failed to get document mapping for invoice status",
+				zap.String("document_id", documentID),
+				zap.Error(err),
+			)
+		}
+		mapping = nil
+	}
+
+	status := &entity.InvoiceStatus{
+		InvoiceNo:  invoiceNo,
+		DocumentID: documentID,
+	}
+
+	// The state follows the milestones in order; later milestones override earlier ones, so a
+	// failed webhook that is processed on a retry moves the invoice on again
+	var ended string
+	for _, event := range events {
+		switch event.EventType {
+		case entity.DocumentEventSubmitted:
+			status.State = entity.InvoiceStateSubmitted
+		case entity.DocumentEventSignerSigned:
+			status.State = entity.InvoiceStateSigning
+		case entity.DocumentEventSigningCompleted:
+			status.State = entity.InvoiceStateStamping
+			if mapping != nil && !mapping.Stamping {
+				status.State = entity.InvoiceStateFinished
+			}
+		case entity.DocumentEventStampRequested, entity.DocumentEventStamped:
+			status.State = entity.InvoiceStateStamping
+		case entity.DocumentEventSavedToFinish:
+			status.State = entity.InvoiceStateFinished
+		case entity.DocumentEventExpired, entity.DocumentEventVoided, entity.DocumentEventDeclined, entity.DocumentEventError:
+			status.State = entity.InvoiceStateFailed
+		default:
+			continue
+		}
+		ended = event.EventType
+	}
+
+	last := events[len(events)-1]
+	status.LastEvent = last.EventType
+	status.Detail = last.Description
+	status.UpdatedAt = last.CreatedAt
+	if status.EntryNo = last.EntryNo; status.EntryNo == 0 && mapping != nil {
+		status.EntryNo = mapping.EntryNo
+	}
+
+	status.FileLocation = u.invoiceFolder(ctx, status.EntryNo, ended)
+	if mapping != nil && mapping.Filename != "" {
+		status.FileLocation = filepath.Join(status.FileLocation, filepath.Base(mapping.Filename))
+	}
+
+	return status, nil
+}
+
+// invoiceFolder returns the folder holding the file of a document after its last milestone, with
+// the paths of the cached NAV setup of the entry when set. The file of a document signed without
+// e-meterai, declined or failed stays in the progress folder.
+func (u *esignUsecase) invoiceFolder(ctx context.Context, entryNo int, milestone string) string {
+	navSetup := u.cachedNAVSetup(ctx, entryNo)
+	if navSetup == nil {
+		navSetup = &entity.NAVSetup{}
+	}
+	pick := func(navPath, configured string) string {
+		if navPath != "" {
+			return navPath
+		}
+		return configured
+	}
+
+	moveTo := ""
+	switch milestone {
+	case entity.DocumentEventSavedToFinish:
+		return pick(navSetup.FileLocationIn, u.docService.GetFinishPath())
+	case entity.DocumentEventExpired:
+		moveTo = u.config.Deadline.OnExpiry
+	case entity.DocumentEventVoided:
+		moveTo = u.config.DocumentSettings().OnVoid
+	}
+	switch moveTo {
+	case config.MoveToReady:
+		return pick(navSetup.FileLocationOut, u.docService.GetReadyPath())
+	case config.MoveToFailed:
+		return u.docService.GetFailedPath()
+	}
+	return pick(navSetup.FileLocationProcess, u.docService.GetProgressPath())
+}
@@ -0,0 +1,70 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/metrics"
+	"mekari-esign/internal/infrastructure/repository"
+)
+
+// metricsScrapeTimeout bounds the queries run on each scrape
+const metricsScrapeTimeout = 10 * time.Second
+
+// webhookQueueStatuses are always reported, so an empty queue shows as 0 rather than missing
+var webhookQueueStatuses = []string{
+	entity.WebhookEventPending,
+	entity.WebhookEventProcessing,
+	entity.WebhookEventProcessed,
+	entity.WebhookEventFailed,
+}
+
+// stateCollector samples the webhook queue and the document folders on each scrape
+type stateCollector struct {
+	dashboard DashboardUsecase
+	eventRepo repository.WebhookEventRepository
+	logger    *zap.Logger
+
+	queue     *prometheus.Desc
+	documents *prometheus.Desc
+}
+
+func registerStateCollector(m *metrics.Metrics, dashboard DashboardUsecase, eventRepo repository.WebhookEventRepository, logger *zap.Logger) {
+	m.Register(&stateCollector{
+		dashboard: dashboard,
+		eventRepo: eventRepo,
+		logger:    logger,
+		queue: prometheus.NewDesc(metrics.Namespace+"_webhook_queue_events",
+			"Webhook events in the queue, by status.", []string{"status"}, nil),
+		documents: prometheus.NewDesc(metrics.Namespace+"_documents",
+			"Documents in each folder (ready, progress or finish).", []string{"folder"}, nil),
+	})
+}
+
+func (c *stateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.queue
+	ch <- c.documents
+}
+
+func (c *stateCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), metricsScrapeTimeout)
+	defer cancel()
+
+	queue, err := c.eventRepo.CountByStatus(ctx)
+	if err != nil {
+		c.logger.Warn("Failed to count webhook events for metrics", zap.Error(err))
+	} else {
+		for _, status := range webhookQueueStatuses {
+			ch <- prometheus.MustNewConstMetric(c.queue, prometheus.GaugeValue, float64(queue[status]), status)
+		}
+	}
+
+	counts := c.dashboard.GetDocumentCounts(ctx)
+	ch <- prometheus.MustNewConstMetric(c.documents, prometheus.GaugeValue, float64(counts.Ready), "ready")
+	ch <- prometheus.MustNewConstMetric(c.documents, prometheus.GaugeValue, float64(counts.Progress), "progress")
+	ch <- prometheus.MustNewConstMetric(c.documents, prometheus.GaugeValue, float64(counts.Finish), "finish")
+}
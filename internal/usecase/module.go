@@ -6,4 +6,15 @@ var Module = fx.Module("usecase",
 	fx.Provide(NewEsignUsecase),
 	fx.Provide(NewOAuthUsecase),
 	fx.Provide(NewWebhookUsecase),
+	fx.Provide(NewAPIKeyUsecase),
+	fx.Provide(NewAuthUsecase),
+	fx.Provide(NewDocumentEventHub),
+	fx.Provide(NewQuotaUsecase),
+	fx.Provide(NewDashboardUsecase),
+	fx.Provide(NewTrafficUsecase),
+	fx.Provide(NewAlertUsecase),
+	fx.Provide(NewStatusUsecase),
+	fx.Provide(NewUpdateUsecase),
+	fx.Provide(NewDeadlineUsecase),
+	fx.Invoke(registerStateCollector),
 )
@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
 
@@ -10,6 +11,9 @@ import (
 	"mekari-esign/internal/config"
 	"mekari-esign/internal/domain/entity"
 	"mekari-esign/internal/domain/repository"
+	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/infrastructure/oauth2"
+	"mekari-esign/internal/timeutil"
 )
 
 type OAuthUsecase interface {
@@ -23,21 +27,44 @@ type OAuthUsecase interface {
 	// GetOAuthToken retrieves OAuth token by email
 	GetOAuthToken(ctx context.Context, email string) (*entity.OAuthToken, error)
 
+	// GetTokenHistory returns the latest token grant and refresh attempts of an email, newest first
+	GetTokenHistory(ctx context.Context, email string, limit int) ([]entity.OAuthTokenEvent, error)
+
+	// DeleteToken removes the code and tokens of an email for a data removal request; the record
+	// is purged with its history after oauth.deleted_retention_days
+	DeleteToken(ctx context.Context, email, actor string) error
+
+	// PurgeDeletedTokens removes the records deleted more than oauth.deleted_retention_days ago,
+	// returning how many were removed
+	PurgeDeletedTokens(ctx context.Context) (int, error)
+
 	// BuildAuthURL builds the Mekari OAuth authorization URL
 	BuildAuthURL(email string) string
 }
 
+// tokenHistoryMaxLimit caps the number of token history entries returned at once
+const tokenHistoryMaxLimit = 500
+
+// ErrOAuthTokenNotFound is returned when deleting the token of an email without one
+var ErrOAuthTokenNotFound = errors.New("OAuth token not found for this email")
+
 type oauthUsecase struct {
-	repo   repository.OAuthRepository
-	config *config.Config
-	logger *zap.Logger
+	repo         repository.OAuthRepository
+	history      repository.OAuthTokenHistoryRepository
+	tokenService oauth2.TokenService
+	config       *config.Config
+	redactor     *logger.Redactor
+	logger       *zap.Logger
 }
 
-func NewOAuthUsecase(repo repository.OAuthRepository, cfg *config.Config, logger *zap.Logger) OAuthUsecase {
+func NewOAuthUsecase(repo repository.OAuthRepository, history repository.OAuthTokenHistoryRepository, tokenService oauth2.TokenService, cfg *config.Config, redactor *logger.Redactor, logger *zap.Logger) OAuthUsecase {
 	return &oauthUsecase{
-		repo:   repo,
-		config: cfg,
-		logger: logger,
+		repo:         repo,
+		history:      history,
+		tokenService: tokenService,
+		config:       cfg,
+		redactor:     redactor,
+		logger:       logger.Named("oauth2"),
 	}
 }
 
@@ -78,7 +105,7 @@ func (u *oauthUsecase) CheckCode(ctx context.Context, email string) (*entity.Che
 func (u *oauthUsecase) SaveCode(ctx context.Context, email, code string) error {
 	u.logger.Info("Saving OAuth code",
 		zap.String("email", email),
-		zap.String("code", code),
+		u.redactor.String("code", code),
 	)
 
 	// Validate inputs
@@ -111,6 +138,48 @@ func (u *oauthUsecase) GetOAuthToken(ctx context.Context, email string) (*entity
 	return token, nil
 }
 
+func (u *oauthUsecase) GetTokenHistory(ctx context.Context, email string, limit int) ([]entity.OAuthTokenEvent, error) {
+	if email == "" {
+		return nil, fmt.Errorf("email is required")
+	}
+	if limit <= 0 || limit > tokenHistoryMaxLimit {
+		limit = tokenHistoryMaxLimit
+	}
+
+	return u.history.FindByEmail(ctx, email, limit)
+}
+
+func (u *oauthUsecase) DeleteToken(ctx context.Context, email, actor string) error {
+	if email == "" {
+		return fmt.Errorf("email is required")
+	}
+
+	found, err := u.repo.SoftDelete(ctx, email)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrOAuthTokenNotFound
+	}
+
+	// Cached tokens would otherwise keep working until they expire
+	if err := u.tokenService.InvalidateTokens(ctx, email); err != nil {
+		u.logger.Warn("Failed to invalidate cached tokens of deleted OAuth token", zap.String("email", email), zap.Error(err))
+	}
+
+	u.logger.Info("OAuth token deleted",
+		zap.String("email", email),
+		zap.String("actor", actor),
+		zap.Int("purge_after_days", u.config.OAuth.DeletedRetentionDays),
+	)
+	return nil
+}
+
+func (u *oauthUsecase) PurgeDeletedTokens(ctx context.Context) (int, error) {
+	before := timeutil.Now().AddDate(0, 0, -u.config.OAuth.DeletedRetentionDays)
+	return u.repo.PurgeDeleted(ctx, before)
+}
+
 func (u *oauthUsecase) BuildAuthURL(email string) string {
 	// Build OAuth authorization URL
 	// Format: https://sandbox-account.mekari.com/auth?client_id=xxx&response_type=code&scope=esign&lang=id&state=email
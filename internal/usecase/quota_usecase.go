@@ -0,0 +1,243 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/domain/repository"
+	"mekari-esign/internal/infrastructure/notifier"
+	"mekari-esign/internal/infrastructure/redis"
+	"mekari-esign/internal/timeutil"
+)
+
+const (
+	quotaKeyPrefix          = "quota:"
+	quotaAlertKeyPrefix     = "quota_alert:"
+	quotaExhaustedKeyPrefix = "quota_exhausted:"
+)
+
+// ErrQuotaExhausted is returned when the e-meterai balance cannot cover a stamp request
+var ErrQuotaExhausted = errors.New("e-meterai balance is exhausted")
+
+type QuotaUsecase interface {
+	// GetQuota returns the e-meterai balance of an account (cached) and alerts when it is low
+	GetQuota(ctx context.Context, email string) (*entity.EmeteraiQuota, error)
+	// GetAllQuotas returns the balance of every connected account; failures are reported per account
+	GetAllQuotas(ctx context.Context) ([]entity.EmeteraiQuota, error)
+	// CheckStampQuota returns ErrQuotaExhausted, and notifies, when the balance of an account is
+	// below needed e-meterai. A balance that cannot be read does not block stamping.
+	CheckStampQuota(ctx context.Context, email string, needed int) error
+}
+
+type quotaUsecase struct {
+	config      *config.Config
+	esignRepo   repository.EsignRepository
+	oauthRepo   repository.OAuthRepository
+	redisClient *redis.RedisClient
+	notifier    notifier.Notifier
+	logger      *zap.Logger
+}
+
+func NewQuotaUsecase(
+	cfg *config.Config,
+	esignRepo repository.EsignRepository,
+	oauthRepo repository.OAuthRepository,
+	redisClient *redis.RedisClient,
+	notifier notifier.Notifier,
+	logger *zap.Logger,
+) QuotaUsecase {
+	return &quotaUsecase{
+		config:      cfg,
+		esignRepo:   esignRepo,
+		oauthRepo:   oauthRepo,
+		redisClient: redisClient,
+		notifier:    notifier,
+		logger:      logger,
+	}
+}
+
+func (u *quotaUsecase) GetQuota(ctx context.Context, email string) (*entity.EmeteraiQuota, error) {
+	if quota, ok := u.cachedQuota(ctx, email); ok {
+		return quota, nil
+	}
+	return u.fetchQuota(ctx, email)
+}
+
+// cachedQuota returns the balance cached by an earlier read
+func (u *quotaUsecase) cachedQuota(ctx context.Context, email string) (*entity.EmeteraiQuota, bool) {
+	cached, err := u.redisClient.Get(ctx, quotaKeyPrefix+email)
+	if err != nil || cached == "" {
+		return nil, false
+	}
+	var quota entity.EmeteraiQuota
+	if json.Unmarshal([]byte(cached), &quota) != nil {
+		return nil, false
+	}
+	return &quota, true
+}
+
+// fetchQuota reads the balance from the Mekari profile and caches it
+func (u *quotaUsecase) fetchQuota(ctx context.Context, email string) (*entity.EmeteraiQuota, error) {
+	cacheKey := quotaKeyPrefix + email
+
+	profile, err := u.esignRepo.GetProfile(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil || profile.Attributes.Quota == nil {
+		return nil, fmt.Errorf("profile has no balance information")
+	}
+
+	balance := profile.Attributes.Quota
+	quota := &entity.EmeteraiQuota{
+		Email:             email,
+		RemainingEmeterai: balance.RemainingEmeterai,
+		EmeteraiUsage:     balance.EmeteraiUsage,
+		GlobalSignDoc:     balance.GlobalSignDoc,
+		PsreSigning:       balance.PsreSigning,
+		Threshold:         u.config.Quota.AlertThreshold,
+		CheckedAt:         timeutil.Now(),
+	}
+	quota.Low = quota.Threshold > 0 && quota.RemainingEmeterai < quota.Threshold
+
+	quotaJSON, _ := json.Marshal(quota)
+	if err := u.redisClient.Set(ctx, cacheKey, string(quotaJSON), time.Duration(u.config.Quota.CacheTTL)*time.Second); err != nil {
+		u.logger.Warn("Failed to cache e-meterai quota", zap.String("email", email), zap.Error(err))
+	}
+
+	if quota.Low {
+		u.alertLowQuota(ctx, quota)
+	}
+
+	return quota, nil
+}
+
+// alertLowQuota notifies once per alert interval per account
+func (u *quotaUsecase) alertLowQuota(ctx context.Context, quota *entity.EmeteraiQuota) {
+	interval := time.Duration(u.config.Quota.AlertInterval) * time.Hour
+	first, err := u.redisClient.SetNX(ctx, quotaAlertKeyPrefix+quota.Email, quota.RemainingEmeterai, interval)
+	if err != nil {
+		u.logger.Warn("Failed to check quota alert state", zap.String("email", quota.Email), zap.Error(err))
+		return
+	}
+	if !first {
+		return // Already alerted within the interval
+	}
+
+	account := quota.Email
+	if account == "" {
+		account = "company account"
+	}
+
+	err = u.notifier.Notify(ctx, &notifier.Notification{
+		Event:    "quota_low",
+		Severity: notifier.SeverityWarning,
+		Title:    "E-meterai balance is low",
+		Message: fmt.Sprintf("Remaining e-meterai balance for %s is %d (threshold %d). Stamping will fail once it reaches 0.",
+			account, quota.RemainingEmeterai, quota.Threshold),
+		Fields: map[string]interface{}{
+			"email":              quota.Email,
+			"remaining_emeterai": quota.RemainingEmeterai,
+			"threshold":          quota.Threshold,
+		},
+	})
+	if err != nil {
+		u.logger.Error("Failed to send low quota notification", zap.String("email", quota.Email), zap.Error(err))
+	}
+}
+
+func (u *quotaUsecase) CheckStampQuota(ctx context.Context, email string, needed int) error {
+	if !u.config.Quota.CheckBeforeStamping {
+		return nil
+	}
+
+	// The cached balance is enough to let stamping through; it is read again before refusing,
+	// as it may have been topped up since
+	quota, ok := u.cachedQuota(ctx, email)
+	var err error
+	if !ok || quota.RemainingEmeterai < needed {
+		quota, err = u.fetchQuota(ctx, email)
+	}
+	if err != nil {
+		u.logger.Warn("Failed to check e-meterai balance before stamping, stamping anyway",
+			zap.String("email", email),
+			zap.Error(err),
+		)
+		return nil
+	}
+	if quota.RemainingEmeterai >= needed {
+		return nil
+	}
+
+	u.alertExhaustedQuota(ctx, quota, needed)
+	return fmt.Errorf("%w: %d remaining, %d needed; top up the balance and send the stamping request again",
+		ErrQuotaExhausted, quota.RemainingEmeterai, needed)
+}
+
+// alertExhaustedQuota notifies once per alert interval per account that stamping was refused
+func (u *quotaUsecase) alertExhaustedQuota(ctx context.Context, quota *entity.EmeteraiQuota, needed int) {
+	interval := time.Duration(u.config.Quota.AlertInterval) * time.Hour
+	first, err := u.redisClient.SetNX(ctx, quotaExhaustedKeyPrefix+quota.Email, quota.RemainingEmeterai, interval)
+	if err != nil {
+		u.logger.Warn("Failed to check quota alert state", zap.String("email", quota.Email), zap.Error(err))
+		return
+	}
+	if !first {
+		return
+	}
+
+	account := quota.Email
+	if account == "" {
+		account = "company account"
+	}
+
+	err = u.notifier.Notify(ctx, &notifier.Notification{
+		Event:    "quota_exhausted",
+		Severity: notifier.SeverityCritical,
+		Title:    "E-meterai balance is exhausted",
+		Message: fmt.Sprintf("Stamping was refused: the e-meterai balance for %s is %d, %d needed. Signed documents wait in the progress folder until the balance is topped up and stamping is requested again.",
+			account, quota.RemainingEmeterai, needed),
+		Fields: map[string]interface{}{
+			"email":              quota.Email,
+			"remaining_emeterai": quota.RemainingEmeterai,
+			"needed":             needed,
+		},
+	})
+	if err != nil {
+		u.logger.Error("Failed to send exhausted quota notification", zap.String("email", quota.Email), zap.Error(err))
+	}
+}
+
+func (u *quotaUsecase) GetAllQuotas(ctx context.Context) ([]entity.EmeteraiQuota, error) {
+	var emails []string
+	if u.config.Mekari.IsHMAC() {
+		emails = []string{""} // HMAC credentials belong to the company, not to an email
+	} else {
+		tokens, err := u.oauthRepo.FindAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, token := range tokens {
+			emails = append(emails, token.Email)
+		}
+	}
+
+	quotas := make([]entity.EmeteraiQuota, 0, len(emails))
+	for _, email := range emails {
+		quota, err := u.GetQuota(ctx, email)
+		if err != nil {
+			quotas = append(quotas, entity.EmeteraiQuota{Email: email, Error: err.Error()})
+			continue
+		}
+		quotas = append(quotas, *quota)
+	}
+
+	return quotas, nil
+}
@@ -0,0 +1,179 @@
+package usecase
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/logger"
+)
+
+var (
+	// ErrDocumentCompleted is returned when resubmitting a document that is already signed
+	ErrDocumentCompleted = errors.New("document is already signed")
+	// ErrAlreadyResubmitted is returned when a document was already sent again as a new document
+	ErrAlreadyResubmitted = errors.New("document was already resubmitted")
+	// ErrNotResubmittable is returned for documents without stored signers, such as stamping only
+	// requests or documents sent before signers were kept
+	ErrNotResubmittable = errors.New("document has no stored signers to resubmit to")
+)
+
+func (u *esignUsecase) ResubmitDocument(ctx context.Context, documentID, actor string) (*entity.GlobalSignResult, error) {
+	mapping, err := u.GetDocumentMapping(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	ctx = logger.WithDocument(ctx, documentID, mapping.InvoiceNumber)
+	log := logger.FromContext(ctx, u.logger)
+
+	switch {
+	case mapping.ResubmittedAs != "":
+		return nil, fmt.Errorf("%w as %s", ErrAlreadyResubmitted, mapping.ResubmittedAs)
+	case mapping.SigningStatus == entity.SigningStatusCompleted:
+		return nil, ErrDocumentCompleted
+	case !mapping.Signing || len(mapping.Signers) == 0:
+		return nil, ErrNotResubmittable
+	}
+	stillOpen := mapping.AwaitsSignatures()
+
+	// The original file is wherever the old request left it: still in progress, moved to failed or
+	// ready on expiry, or kept in finish
+	if err := u.fetchAndCacheNAVSetup(ctx, mapping.EntryNo); err != nil {
+		log.Warn("Failed to fetch NAV setup, will use config fallback", zap.Error(err))
+	}
+	progressPath, readyPath := u.docService.GetProgressPath(), u.docService.GetReadyPath()
+	if navSetup := u.cachedNAVSetup(ctx, mapping.EntryNo); navSetup != nil {
+		if navSetup.FileLocationProcess != "" {
+			progressPath = navSetup.FileLocationProcess
+		}
+		if navSetup.FileLocationOut != "" {
+			readyPath = navSetup.FileLocationOut
+		}
+	}
+	failedPath := u.docService.GetFailedPath()
+	dir, content, err := u.docService.FindFile(mapping.Filename, progressPath, readyPath, failedPath, u.docService.GetFinishPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to find original document: %w", err)
+	}
+	log.Info("Resubmitting document",
+		zap.String("actor", actor),
+		zap.String("from", dir),
+		zap.Int("attempt", mapping.Resubmits+2),
+		zap.Bool("void", stillOpen),
+	)
+
+	req := &entity.GlobalSignRequest{
+		EntryNo:          mapping.EntryNo,
+		Email:            mapping.Email,
+		InvoiceNumber:    mapping.InvoiceNumber,
+		Signing:          true,
+		Stamping:         mapping.Stamping,
+		Signers:          mapping.Signers,
+		StampPositions:   mapping.StampPositions,
+		Stamps:           mapping.Stamps,
+		DocumentDeadline: mapping.DocumentDeadline,
+		EnforceOrder:     mapping.EnforceOrder,
+		StampPlacement:   mapping.StampPlacement,
+		Document: &entity.InlineDocument{
+			Filename: mapping.Filename,
+			Content:  base64.StdEncoding.EncodeToString(content),
+		},
+		Resubmits: mapping.Resubmits + 1,
+	}
+	result, err := u.GlobalRequestSign(ctx, req)
+	if err != nil || !result.Success {
+		return result, err
+	}
+	newID := result.Data.ID
+
+	// Mark the old document superseded before voiding it so its void callback is ignored
+	mapping.ResubmittedAs = newID
+	mapping.Resubmits = req.Resubmits
+	if err := u.mappingRepo.SaveByDocumentID(ctx, documentID, mapping); err != nil {
+		log.Error("Failed to mark document mapping resubmitted", zap.Error(err))
+	}
+
+	// Inline documents are written to progress; a copy left in ready or failed would be sent twice
+	if dir == readyPath || dir == failedPath {
+		if err := u.docService.DeleteFileWithPath(mapping.Filename, dir); err != nil {
+			log.Warn("Failed to remove resubmitted document", zap.String("dir", dir), zap.Error(err))
+		}
+	}
+
+	description := fmt.Sprintf("Sent for signing again as %s (attempt %d)", newID, req.Resubmits+1)
+	if stillOpen {
+		if err := u.repo.VoidDocument(ctx, mapping.Email, documentID); err != nil {
+			log.Error("Failed to void resubmitted document", zap.Error(err))
+			result.Message = fmt.Sprintf("Document resubmitted, but voiding the previous request failed: %v", err)
+			description += ", voiding the previous request failed"
+		} else {
+			description += ", previous request voided"
+		}
+	}
+	recordDocumentEvent(ctx, u.eventRepo, u.eventHub, u.logger, &entity.DocumentEvent{
+		DocumentID:  documentID,
+		InvoiceNo:   mapping.InvoiceNumber,
+		EntryNo:     mapping.EntryNo,
+		EventType:   entity.DocumentEventResubmitted,
+		Description: description,
+		Actor:       actor,
+		DedupeKey:   documentID + ":" + entity.DocumentEventResubmitted,
+	})
+
+	u.sendResubmitToNAV(ctx, result.Data, req)
+	return result, nil
+}
+
+// sendResubmitToNAV writes the new document to the NAV log entry, pending with its attempt
+func (u *esignUsecase) sendResubmitToNAV(ctx context.Context, data *entity.GlobalSignData, req *entity.GlobalSignRequest) {
+	mapping, err := u.mappingRepo.FindByDocumentID(ctx, data.ID)
+	if err != nil {
+		logger.FromContext(ctx, u.logger).Warn("Failed to read new document mapping for NAV", zap.Error(err))
+		return
+	}
+
+	payload := &entity.WebhookPayload{Data: entity.WebhookData{
+		ID: data.ID,
+		Attributes: entity.WebhookAttributes{
+			Filename:      data.Attributes.Filename,
+			SigningStatus: "pending",
+		},
+	}}
+	if req.Stamping {
+		payload.Data.Attributes.StampingStatus = "pending"
+	} else {
+		payload.Data.Attributes.StampingStatus = "none"
+	}
+	for _, signer := range data.Attributes.Signers {
+		payload.Data.Attributes.Signers = append(payload.Data.Attributes.Signers, entity.WebhookSigner{
+			Name:   signer.Name,
+			Email:  signer.Email,
+			Order:  signer.Sequence,
+			Status: "pending",
+		})
+	}
+
+	if err := u.wbUsecase.SendNAVLogEntry(ctx, payload, mapping); err != nil {
+		logger.FromContext(ctx, u.logger).Error("Failed to send resubmit to NAV", zap.Error(err))
+	}
+}
+
+// cachedNAVSetup returns the NAV setup cached for an entry, nil when none is cached
+func (u *esignUsecase) cachedNAVSetup(ctx context.Context, entryNo int) *entity.NAVSetup {
+	cached, err := u.cache.Get(ctx, navSetupPrefix+strconv.Itoa(entryNo))
+	if err != nil || cached == "" {
+		return nil
+	}
+
+	var setup entity.NAVSetup
+	if err := json.Unmarshal([]byte(cached), &setup); err != nil {
+		return nil
+	}
+	return &setup
+}
@@ -0,0 +1,60 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/logger"
+)
+
+// GetSigningLinks returns the signing links kept from the webhooks of a document. Without stored
+// links, or with refresh, they are fetched from Mekari and stored on the mapping.
+func (u *esignUsecase) GetSigningLinks(ctx context.Context, documentID string, refresh bool) (*entity.DocumentSigningLinks, error) {
+	mapping, err := u.mappingRepo.FindByDocumentID(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &entity.DocumentSigningLinks{
+		DocumentID:    documentID,
+		InvoiceNumber: mapping.InvoiceNumber,
+		SigningStatus: mapping.SigningStatus,
+		Source:        entity.SigningLinksStored,
+		Signers:       mapping.SigningLinks,
+	}
+	if len(mapping.SigningLinks) > 0 && !refresh {
+		return result, nil
+	}
+
+	document, err := u.repo.GetDocument(ctx, mapping.Email, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch signing links from Mekari: %w", err)
+	}
+
+	result.Source = entity.SigningLinksMekari
+	result.Signers = entity.NewSigningLinks(document.Attributes.Signers)
+	if status := document.Attributes.SigningStatus; status != "" && !entity.IsVoidedStatus(status) {
+		result.SigningStatus = status
+	}
+
+	if result.Signers != nil || result.SigningStatus != mapping.SigningStatus {
+		if result.Signers != nil {
+			mapping.SigningLinks = result.Signers
+		}
+		mapping.SigningStatus = result.SigningStatus
+		if err := u.mappingRepo.SaveByDocumentID(ctx, documentID, mapping); err != nil {
+			logger.FromContext(ctx, u.logger).Warn("Failed to save signing links to document mapping",
+				zap.String("document_id", documentID),
+				zap.Error(err),
+			)
+		}
+	}
+	if result.Signers == nil {
+		result.Signers = []entity.SigningLink{}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/repository"
+	"mekari-esign/internal/timeutil"
+)
+
+// statusCacheTTL limits folder listings and queries when /status is polled
+const statusCacheTTL = 15 * time.Second
+
+type StatusUsecase interface {
+	// GetStatus returns the document counts by state and the last successful Mekari call and
+	// NAV update. Version and uptime are left to the caller.
+	GetStatus(ctx context.Context) entity.ServiceStatus
+}
+
+type statusUsecase struct {
+	config     *config.Config
+	dashboard  DashboardUsecase
+	apiLogRepo repository.APILogRepository
+	logger     *zap.Logger
+
+	mu     sync.Mutex
+	cached entity.ServiceStatus
+}
+
+func NewStatusUsecase(
+	cfg *config.Config,
+	dashboard DashboardUsecase,
+	apiLogRepo repository.APILogRepository,
+	logger *zap.Logger,
+) StatusUsecase {
+	return &statusUsecase{
+		config:     cfg,
+		dashboard:  dashboard,
+		apiLogRepo: apiLogRepo,
+		logger:     logger,
+	}
+}
+
+func (u *statusUsecase) GetStatus(ctx context.Context) entity.ServiceStatus {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if time.Since(u.cached.GeneratedAt) < statusCacheTTL {
+		return u.cached
+	}
+
+	counts := u.dashboard.GetDocumentCounts(ctx)
+	status := entity.ServiceStatus{
+		Documents: entity.StatusDocuments{
+			Ready:      counts.Ready,
+			InProgress: counts.Progress,
+			Completed:  counts.Finish,
+			Stuck:      len(u.dashboard.GetStuckDocuments(ctx)),
+		},
+		GeneratedAt: timeutil.Now(),
+	}
+
+	// A failed lookup is reported as null rather than failing the status page
+	lastMekari, err := u.apiLogRepo.LastSuccess(ctx, entity.APILogSourceMekari)
+	if err != nil {
+		u.logger.Warn("Failed to get last successful Mekari call for status", zap.Error(err))
+	}
+	status.LastMekariSuccess = lastMekari
+
+	if u.config.NAVSettings().Enabled {
+		lastNAV, err := u.apiLogRepo.LastSuccess(ctx, entity.APILogSourceNAV)
+		if err != nil {
+			u.logger.Warn("Failed to get last successful NAV update for status", zap.Error(err))
+		}
+		status.LastNAVUpdate = lastNAV
+	}
+
+	u.cached = status
+	return status
+}
@@ -0,0 +1,107 @@
+package usecase
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/infrastructure/repository"
+)
+
+// documentTrafficLimit caps the api_logs rows returned for one document
+const documentTrafficLimit = 500
+
+type TrafficUsecase interface {
+	// GetDocumentTraffic returns the webhook callbacks, Mekari calls and NAV updates of a
+	// document, oldest first
+	GetDocumentTraffic(ctx context.Context, documentID string) (*entity.DocumentTraffic, error)
+}
+
+type trafficUsecase struct {
+	logRepo   repository.APILogRepository
+	eventRepo repository.WebhookEventRepository
+	docEvents repository.DocumentEventRepository
+	redactor  *logger.Redactor
+}
+
+func NewTrafficUsecase(
+	logRepo repository.APILogRepository,
+	eventRepo repository.WebhookEventRepository,
+	docEvents repository.DocumentEventRepository,
+	redactor *logger.Redactor,
+) TrafficUsecase {
+	return &trafficUsecase{
+		logRepo:   logRepo,
+		eventRepo: eventRepo,
+		docEvents: docEvents,
+		redactor:  redactor,
+	}
+}
+
+func (u *trafficUsecase) GetDocumentTraffic(ctx context.Context, documentID string) (*entity.DocumentTraffic, error) {
+	traffic := &entity.DocumentTraffic{DocumentID: documentID, Entries: []entity.TrafficEntry{}}
+
+	// The invoice number matches calls logged before Mekari assigned the document ID
+	events, err := u.docEvents.FindByDocumentID(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		if event.InvoiceNo != "" {
+			traffic.InvoiceNo = event.InvoiceNo
+			break
+		}
+	}
+
+	logs, err := u.logRepo.FindByDocument(ctx, documentID, traffic.InvoiceNo, documentTrafficLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Callbacks of stamped copies carry another document ID, but their processing
+	// continues the request ID of the callback
+	seen := make(map[string]bool)
+	var requestIDs []string
+	for _, log := range logs {
+		if traffic.InvoiceNo == "" {
+			traffic.InvoiceNo = log.InvoiceNo
+		}
+		if log.RequestID != "" && !seen[log.RequestID] {
+			seen[log.RequestID] = true
+			requestIDs = append(requestIDs, log.RequestID)
+		}
+		traffic.Entries = append(traffic.Entries, entity.TrafficEntry{APILog: log})
+	}
+
+	callbacks, err := u.eventRepo.FindByDocument(ctx, documentID, requestIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range callbacks {
+		traffic.Entries = append(traffic.Entries, entity.TrafficEntry{
+			APILog: entity.APILog{
+				ID:          event.ID,
+				Endpoint:    "/webhook/mekari",
+				InvoiceNo:   traffic.InvoiceNo,
+				DocumentID:  event.DocumentID,
+				Source:      entity.APILogSourceInbound,
+				Method:      http.MethodPost,
+				RequestBody: u.redactor.Body(event.Payload),
+				StatusCode:  http.StatusAccepted,
+				RequestID:   event.RequestID,
+				CreatedAt:   event.CreatedAt,
+			},
+			WebhookStatus: event.Status,
+			Attempts:      event.Attempts,
+			LastError:     event.LastError,
+		})
+	}
+
+	sort.SliceStable(traffic.Entries, func(i, j int) bool {
+		return traffic.Entries[i].CreatedAt.Before(traffic.Entries[j].CreatedAt)
+	})
+
+	return traffic, nil
+}
@@ -0,0 +1,216 @@
+package usecase
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/infrastructure/repository"
+	"mekari-esign/internal/timeutil"
+	"mekari-esign/updater"
+)
+
+// updateHistoryMaxLimit caps the updates returned by GetHistory
+const updateHistoryMaxLimit = 100
+
+type UpdateUsecase interface {
+	// GetStatus returns the running version, the last update check, the staged update with when
+	// it is installed and the outcome of the last update
+	GetStatus(ctx context.Context) entity.UpdateInfo
+	// GetHistory returns the latest updates and rollbacks of every host, newest first
+	GetHistory(ctx context.Context, limit int) ([]entity.UpdateHistory, error)
+	// Apply installs the staged update, or checks for one and installs it, in a separate process
+	// that restarts the service. Returns the version installed, "" when it is checked for first.
+	Apply(ctx context.Context, actor string) (string, error)
+	// Rollback reinstalls the version replaced by the last update in a separate process and
+	// returns it; updater.ErrNoPrevious when there is none
+	Rollback(ctx context.Context, actor string) (string, error)
+	// RecordLastUpdate adds the outcome of the last update of this host to the history. The
+	// service records it when it starts, as every update restarts it.
+	RecordLastUpdate(ctx context.Context) error
+}
+
+type updateUsecase struct {
+	config  *config.Config
+	history repository.UpdateHistoryRepository
+	events  *logger.EventLog
+	logger  *zap.Logger
+}
+
+func NewUpdateUsecase(cfg *config.Config, history repository.UpdateHistoryRepository, events *logger.EventLog, logger *zap.Logger) UpdateUsecase {
+	return &updateUsecase{
+		config:  cfg,
+		history: history,
+		events:  events,
+		logger:  logger.Named("updater"),
+	}
+}
+
+func (u *updateUsecase) GetStatus(ctx context.Context) entity.UpdateInfo {
+	info := entity.UpdateInfo{
+		Version:           updater.Version,
+		Channel:           u.config.Updater.Channel,
+		PinVersion:        u.config.Updater.PinVersion,
+		Background:        u.config.Updater.Background,
+		MaintenanceWindow: u.config.Updater.MaintenanceWindow,
+	}
+
+	if check, err := updater.LoadCheck(); err != nil {
+		u.logger.Warn("Failed to read the last update check", zap.Error(err))
+	} else if check != nil {
+		info.Latest = check.Latest
+		info.Available = check.Available
+		info.LastCheckAt = &check.CheckedAt
+		info.LastCheckError = check.Error
+	}
+
+	if pending, err := updater.LoadPending(); err != nil {
+		u.logger.Warn("Failed to read the staged update", zap.Error(err))
+	} else if pending != nil {
+		installAfter := time.Now().In(u.config.App.Location())
+		if window, ok := u.config.Updater.Window(); ok {
+			installAfter = window.Next(installAfter)
+		}
+		info.Pending = &entity.PendingUpdate{
+			Version:      pending.Version,
+			Prerelease:   pending.Prerelease,
+			PublishedAt:  pending.PublishedAt,
+			Notes:        pending.Notes,
+			StagedAt:     pending.StagedAt,
+			InstallAfter: installAfter.UTC(),
+		}
+	}
+
+	if status, err := updater.LoadStatus(); err != nil {
+		u.logger.Warn("Failed to read the last update status", zap.Error(err))
+	} else if status != nil {
+		info.LastUpdate = &entity.UpdateResult{
+			From:       status.From,
+			To:         status.To,
+			Rollback:   status.Rollback,
+			Result:     status.Result,
+			Error:      status.Error,
+			FinishedAt: status.FinishedAt,
+		}
+	}
+
+	if previous, err := updater.LoadPrevious(); err == nil {
+		info.RollbackVersion = previous.Version
+	}
+
+	return info
+}
+
+func (u *updateUsecase) GetHistory(ctx context.Context, limit int) ([]entity.UpdateHistory, error) {
+	if limit <= 0 || limit > updateHistoryMaxLimit {
+		limit = updateHistoryMaxLimit
+	}
+	return u.history.List(ctx, limit)
+}
+
+func (u *updateUsecase) Apply(ctx context.Context, actor string) (string, error) {
+	pending, err := updater.LoadPending()
+	if err != nil {
+		return "", err
+	}
+
+	if pending != nil {
+		if err := updater.StartInstallPending(); err != nil {
+			return "", err
+		}
+		u.logger.Warn("Installing staged update on request",
+			zap.String("actor", actor),
+			zap.String("from", updater.Version),
+			zap.String("to", pending.Version),
+		)
+		return pending.Version, nil
+	}
+
+	if err := updater.StartUpdate(); err != nil {
+		return "", err
+	}
+	u.logger.Warn("Update started on request", zap.String("actor", actor), zap.String("version", updater.Version))
+	return "", nil
+}
+
+func (u *updateUsecase) Rollback(ctx context.Context, actor string) (string, error) {
+	previous, err := updater.LoadPrevious()
+	if err != nil {
+		return "", err
+	}
+	if err := updater.StartRollback(); err != nil {
+		return "", err
+	}
+
+	u.logger.Warn("Rollback started",
+		zap.String("actor", actor),
+		zap.String("from", updater.Version),
+		zap.String("to", previous.Version),
+	)
+	return previous.Version, nil
+}
+
+func (u *updateUsecase) RecordLastUpdate(ctx context.Context) error {
+	status, err := updater.LoadStatus()
+	if err != nil || status == nil {
+		return err
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	update := &entity.UpdateHistory{
+		Host:        host,
+		FromVersion: status.From,
+		ToVersion:   status.To,
+		Kind:        entity.UpdateKindUpdate,
+		Result:      status.Result,
+		Error:       status.Error,
+		FinishedAt:  status.FinishedAt,
+		CreatedAt:   timeutil.Now(),
+	}
+	if status.Rollback {
+		update.Kind = entity.UpdateKindRollback
+	}
+
+	inserted, err := u.history.Save(ctx, update)
+	if err != nil {
+		return err
+	}
+	if inserted {
+		u.logger.Info("Recorded update",
+			zap.String("from", update.FromVersion),
+			zap.String("to", update.ToVersion),
+			zap.String("kind", update.Kind),
+			zap.String("result", update.Result),
+		)
+		u.reportUpdate(update)
+	}
+	return nil
+}
+
+// reportUpdate writes the outcome of an update or rollback to the Event Log, once, like the history
+func (u *updateUsecase) reportUpdate(update *entity.UpdateHistory) {
+	rollback := update.Kind == entity.UpdateKindRollback
+	if update.Result == updater.ResultUpdated {
+		if rollback {
+			u.events.Report(logger.EventRollbackApplied, "Rolled back to "+update.ToVersion, update.FromVersion, update.ToVersion)
+		} else {
+			u.events.Report(logger.EventUpdateApplied, "Updated to "+update.ToVersion, update.FromVersion, update.ToVersion)
+		}
+		return
+	}
+
+	// Rolled back automatically because the new version did not start, or failed
+	if rollback {
+		u.events.Report(logger.EventRollbackFailed, "Rollback to "+update.ToVersion+" failed", update.FromVersion, update.ToVersion, update.Error)
+	} else {
+		u.events.Report(logger.EventUpdateFailed, "Update to "+update.ToVersion+" failed ("+update.Result+")", update.FromVersion, update.ToVersion, update.Error)
+	}
+}
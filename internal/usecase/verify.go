@@ -0,0 +1,75 @@
+package usecase
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/document"
+	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/timeutil"
+)
+
+func (u *esignUsecase) VerifyDocument(ctx context.Context, filename string, content []byte) (*entity.VerificationReport, error) {
+	log := logger.FromContext(ctx, u.logger)
+
+	filename = filepath.Base(filename)
+	if content == nil {
+		if filename == "." || filename == string(filepath.Separator) {
+			return nil, fmt.Errorf("%w: %s", document.ErrDocumentNotFound, filename)
+		}
+		_, found, err := u.docService.FindFile(filename, u.docService.GetFinishPath())
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", document.ErrDocumentNotFound, filename)
+		}
+		content = found
+	}
+
+	roots, err := u.trustedRoots()
+	if err != nil {
+		return nil, err
+	}
+	report, err := document.VerifyPDFSignatures(content, roots)
+	if err != nil {
+		return nil, err
+	}
+	if filename != "." {
+		report.Filename = filename
+	}
+	report.VerifiedAt = timeutil.Now()
+
+	log.Info("Document signatures verified",
+		zap.String("filename", report.Filename),
+		zap.Int("signatures", len(report.Signatures)),
+		zap.Bool("valid", report.Valid),
+		zap.Bool("emeterai_present", report.EMeteraiPresent),
+	)
+	return report, nil
+}
+
+// trustedRoots returns the system roots with the certificates of document.trusted_roots, read on
+// each verification so a changed file applies without a restart
+func (u *esignUsecase) trustedRoots() (*x509.CertPool, error) {
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+
+	path := u.config.DocumentSettings().TrustedRoots
+	if path == "" {
+		return roots, nil
+	}
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document.trusted_roots: %w", err)
+	}
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in document.trusted_roots %s", path)
+	}
+	return roots, nil
+}
@@ -2,12 +2,18 @@ package usecase
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
@@ -16,28 +22,56 @@ import (
 	"mekari-esign/internal/domain/entity"
 	"mekari-esign/internal/infrastructure/document"
 	"mekari-esign/internal/infrastructure/httpclient"
+	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/infrastructure/metrics"
 	"mekari-esign/internal/infrastructure/nav"
+	"mekari-esign/internal/infrastructure/notifier"
 	"mekari-esign/internal/infrastructure/oauth2"
 	"mekari-esign/internal/infrastructure/redis"
+	"mekari-esign/internal/infrastructure/repository"
+	"mekari-esign/internal/timeutil"
 )
 
 const (
 	// Redis key prefix for document info
-	documentInfoKeyPrefix = "mekari:document:info:"
+	documentInfoKeyPrefix = "document:info:"
 	// Redis key prefix for NAV setup cache (by entry_no)
-	navSetupKeyPrefix = "mekari:nav_setup:"
+	navSetupKeyPrefix = "nav_setup:"
+	// Mekari documents API path of the audit trail certificate of a document
+	auditTrailURLFormat = "/documents/%s/audit_trail"
+	// Appended to the name of the final document for its audit trail in the finish folder
+	auditTrailSuffix = "_audit_trail.pdf"
+	// Lease of a document while a callback moves its files, renewed until the callback is done
+	documentLeaseTTL = 30 * time.Second
+	// How long a callback waits for another instance processing the same document
+	documentLeaseWait = time.Minute
 )
 
 type WebhookUsecase interface {
+	// EnqueueWebhook persists the raw webhook event for background processing.
+	// Returns false if the same event was already received (Mekari retry).
+	EnqueueWebhook(ctx context.Context, rawBody []byte, payload *entity.WebhookPayload) (bool, error)
 	// ProcessWebhook processes the webhook callback from Mekari eSign
 	ProcessWebhook(ctx context.Context, payload *entity.WebhookPayload) error
-	RequestStamping(ctx context.Context, email string, signedPDFContent []byte, mapping DocumentMapping) error
+	RequestStamping(ctx context.Context, email string, signedPDFContent []byte, mapping entity.DocumentMapping) error
 	DownloadDocument(ctx context.Context, email, docURL string) ([]byte, error)
+	// ListWebhookEvents lists persisted webhook events, optionally filtered by status
+	ListWebhookEvents(ctx context.Context, status string, limit int) ([]entity.WebhookEvent, error)
+	// ReplayWebhookEvent requeues a webhook event for processing
+	ReplayWebhookEvent(ctx context.Context, id int64) error
+	// ResendNAVLogEntry rebuilds the NAV log entry of a document from its last webhook, or the
+	// document info when none is stored, and sends it to NAV again. Returns the entry sent.
+	ResendNAVLogEntry(ctx context.Context, entryNo int) (*entity.NAVLogEntry, error)
+	// SendNAVLogEntry sends the NAV log entry of a document in the state of payload, e.g. a
+	// document just sent for signing again
+	SendNAVLogEntry(ctx context.Context, payload *entity.WebhookPayload, mapping *entity.DocumentMapping) error
 }
 
 type webhookUsecase struct {
 	config        *config.Config
-	redisClient   *redis.RedisClient
+	cache         redis.Cache
+	leases        *redis.Leases
+	mappingRepo   repository.MappingRepository
 	docService    document.DocumentService
 	tokenService  oauth2.TokenService
 	hmacSignature *httpclient.HMACSignature
@@ -45,28 +79,52 @@ type webhookUsecase struct {
 	logger        *zap.Logger
 	httpClient    *http.Client
 	localClient   httpclient.HTTPClient
+	eventRepo     repository.WebhookEventRepository
+	docEventRepo  repository.DocumentEventRepository
+	docEventHub   *DocumentEventHub
+	metrics       *metrics.Metrics
+	quota         QuotaUsecase
+	notifier      notifier.Notifier
 }
 
 func NewWebhookUsecase(
 	cfg *config.Config,
-	redisClient *redis.RedisClient,
+	cache redis.Cache,
+	leases *redis.Leases,
+	mappingRepo repository.MappingRepository,
 	docService document.DocumentService,
 	tokenService oauth2.TokenService,
 	navClient *nav.Client,
 	logger *zap.Logger,
 	client httpclient.HTTPClient,
+	eventRepo repository.WebhookEventRepository,
+	docEventRepo repository.DocumentEventRepository,
+	docEventHub *DocumentEventHub,
+	meter *metrics.Metrics,
+	quota QuotaUsecase,
+	notify notifier.Notifier,
 ) WebhookUsecase {
+	logger = logger.Named("webhook")
 	uc := &webhookUsecase{
 		config:       cfg,
-		redisClient:  redisClient,
+		cache:        cache,
+		leases:       leases,
+		mappingRepo:  mappingRepo,
 		docService:   docService,
 		tokenService: tokenService,
 		navClient:    navClient,
 		logger:       logger,
 		httpClient: &http.Client{
-			Timeout: cfg.Mekari.Timeout,
+			Timeout:   cfg.Mekari.Timeout,
+			Transport: meter.Transport(metrics.ServiceMekari, nil),
 		},
-		localClient: client,
+		localClient:  client,
+		eventRepo:    eventRepo,
+		docEventRepo: docEventRepo,
+		docEventHub:  docEventHub,
+		metrics:      meter,
+		quota:        quota,
+		notifier:     notify,
 	}
 
 	// Initialize HMAC signature if using HMAC auth
@@ -80,40 +138,218 @@ func NewWebhookUsecase(
 	return uc
 }
 
+func (u *webhookUsecase) EnqueueWebhook(ctx context.Context, rawBody []byte, payload *entity.WebhookPayload) (bool, error) {
+	log := logger.FromContext(ctx, u.logger)
+
+	// Identical bodies are Mekari retries of the same event
+	sum := sha256.Sum256(rawBody)
+
+	event := &entity.WebhookEvent{
+		EventKey:       hex.EncodeToString(sum[:]),
+		DocumentID:     payload.Data.ID,
+		SigningStatus:  payload.Data.Attributes.SigningStatus,
+		StampingStatus: payload.Data.Attributes.StampingStatus,
+		Payload:        string(rawBody),
+		RequestID:      logger.RequestIDFromContext(ctx),
+		CreatedAt:      timeutil.Now(),
+	}
+
+	queued, err := u.eventRepo.Save(ctx, event)
+	if err != nil {
+		return false, err
+	}
+
+	if !queued {
+		u.metrics.WebhookReceived.WithLabelValues(metrics.WebhookDuplicate).Inc()
+		log.Info("Duplicate webhook event ignored",
+			zap.String("event_key", event.EventKey),
+		)
+		return false, nil
+	}
+
+	u.metrics.WebhookReceived.WithLabelValues(metrics.WebhookQueued).Inc()
+	log.Info("Webhook event queued",
+		zap.Int64("event_id", event.ID),
+		zap.String("signing_status", event.SigningStatus),
+		zap.String("stamping_status", event.StampingStatus),
+	)
+
+	return true, nil
+}
+
+func (u *webhookUsecase) ListWebhookEvents(ctx context.Context, status string, limit int) ([]entity.WebhookEvent, error) {
+	return u.eventRepo.FindAll(ctx, status, limit)
+}
+
+func (u *webhookUsecase) ReplayWebhookEvent(ctx context.Context, id int64) error {
+	if err := u.eventRepo.Requeue(ctx, id); err != nil {
+		return err
+	}
+
+	u.logger.Info("Webhook event requeued", zap.Int64("event_id", id))
+	return nil
+}
+
+func (u *webhookUsecase) ResendNAVLogEntry(ctx context.Context, entryNo int) (*entity.NAVLogEntry, error) {
+	mapping, err := u.mappingRepo.FindByEntryNo(ctx, entryNo)
+	if err != nil {
+		return nil, fmt.Errorf("document mapping not found: %w", err)
+	}
+	if mapping == nil {
+		return nil, fmt.Errorf("no document mapping for entry %d", entryNo)
+	}
+	ctx = logger.WithDocument(ctx, mapping.DocumentID, mapping.InvoiceNumber)
+
+	payload, err := u.lastPayload(ctx, mapping.DocumentID)
+	if err != nil {
+		return nil, err
+	}
+
+	navEntry := u.buildNAVLogEntry(ctx, payload, mapping)
+	if err := u.navClient.UpdateLogEntry(ctx, navEntry); err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx, u.logger).Info("NAV log entry resent",
+		zap.Int("entry_no", entryNo),
+		zap.String("signing_status", navEntry.SigningStatus),
+		zap.String("stamping_status", navEntry.StampingStatus),
+	)
+	return navEntry, nil
+}
+
+// lastPayload returns the last webhook stored for a document, or one built from its document
+// info in Redis, which lacks the signers
+func (u *webhookUsecase) lastPayload(ctx context.Context, documentID string) (*entity.WebhookPayload, error) {
+	event, err := u.eventRepo.FindLatestByDocumentID(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if event != nil {
+		var payload entity.WebhookPayload
+		if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+			return nil, fmt.Errorf("invalid payload of webhook event %d: %w", event.ID, err)
+		}
+		return &payload, nil
+	}
+
+	cached, err := u.cache.Get(ctx, documentInfoKeyPrefix+documentID)
+	if err != nil || cached == "" {
+		return nil, fmt.Errorf("no webhook or document info stored for document %s", documentID)
+	}
+	var docInfo entity.DocumentInfo
+	if err := json.Unmarshal([]byte(cached), &docInfo); err != nil {
+		return nil, fmt.Errorf("invalid document info: %w", err)
+	}
+
+	payload := &entity.WebhookPayload{}
+	payload.Data.ID = documentID
+	payload.Data.Attributes.Filename = docInfo.Filename
+	payload.Data.Attributes.SigningStatus = docInfo.SigningStatus
+	payload.Data.Attributes.StampingStatus = docInfo.StampingStatus
+	return payload, nil
+}
+
 func (u *webhookUsecase) ProcessWebhook(ctx context.Context, payload *entity.WebhookPayload) error {
+	log := logger.FromContext(ctx, u.logger)
 	documentID := payload.Data.ID
 
-	u.logger.Info("Processing webhook callback",
-		zap.String("document_id", documentID),
+	log.Info("Processing webhook callback",
 		zap.String("signing_status", payload.Data.Attributes.SigningStatus),
 		zap.String("stamping_status", payload.Data.Attributes.StampingStatus),
 		zap.String("filename", payload.Data.Attributes.Filename),
 	)
 
-	// Get document mapping from Redis using document ID
-	documentKey := documentKeyPrefix + documentID
-	mappingData, err := u.redisClient.Get(ctx, documentKey)
+	// Get document mapping saved with the sign request
+	mapping, err := u.mappingRepo.FindByDocumentID(ctx, documentID)
 	if err != nil {
-		u.logger.Error("Failed to get document mapping from Redis",
-			zap.String("document_id", documentID),
+		log.Error("Failed to get document mapping",
 			zap.Error(err),
 		)
-		return fmt.Errorf("document not found in Redis: %w", err)
+		return fmt.Errorf("document mapping not found: %w", err)
+	}
+	if err := u.mappingRepo.TouchByDocumentID(ctx, documentID); err != nil {
+		log.Warn("Failed to refresh document mapping expiry", zap.Error(err))
 	}
 
-	// Parse document mapping
-	var mapping DocumentMapping
-	if err := json.Unmarshal([]byte(mappingData), &mapping); err != nil {
-		// Fallback: old format might be just email string
-		mapping = DocumentMapping{Email: mappingData}
+	// Stamp documents carry the original mapping, so events are kept on the original document
+	timelineID := mapping.DocumentID
+	if timelineID == "" {
+		timelineID = documentID
+	}
+	if timelineID != documentID {
+		log.Info("Callback for stamped copy", zap.String("original_document_id", timelineID))
+	}
+	ctx = logger.WithDocument(ctx, timelineID, mapping.InvoiceNumber)
+
+	// The entry and file now belong to the document that replaced this one
+	if mapping.Superseded() {
+		log.Info("Ignoring callback for superseded document",
+			zap.String("signing_status", mapping.SigningStatus),
+			zap.String("resubmitted_as", mapping.ResubmittedAs),
+		)
+		return nil
 	}
 
+	// The deadline checks read the signing status of the original document from its mapping.
+	// Voided documents are marked once handled, see handleVoided. The signing links are kept so
+	// they can be sent again to a signer who lost the email.
+	if timelineID == documentID {
+		changed := false
+		if status := payload.Data.Attributes.SigningStatus; status != "" && status != mapping.SigningStatus && !entity.IsVoidedStatus(status) {
+			mapping.SigningStatus = status
+			changed = true
+		}
+		if links := entity.NewSigningLinks(payload.Data.Attributes.Signers); links != nil && !slices.Equal(links, mapping.SigningLinks) {
+			mapping.SigningLinks = links
+			changed = true
+		}
+		if changed {
+			if err := u.mappingRepo.SaveByDocumentID(ctx, documentID, mapping); err != nil {
+				log.Warn("Failed to save signing status and links to document mapping", zap.Error(err))
+			}
+		}
+	}
+
+	// Attachments sent as linked documents only update their own file and the main document
+	if mapping.ParentDocumentID != "" {
+		timelineID = mapping.ParentDocumentID
+	}
+
+	// Callbacks of a document and its stamped copy move the same files, one instance at a time
+	err = u.leases.Do(ctx, "document:"+timelineID, documentLeaseTTL, documentLeaseWait, func(ctx context.Context) error {
+		if mapping.ParentDocumentID != "" {
+			return u.processAttachmentWebhook(ctx, payload, mapping)
+		}
+		return u.handleWebhook(ctx, payload, mapping, timelineID)
+	})
+	if err != nil {
+		recordDocumentEvent(ctx, u.docEventRepo, u.docEventHub, u.logger, &entity.DocumentEvent{
+			DocumentID:  timelineID,
+			InvoiceNo:   mapping.InvoiceNumber,
+			EntryNo:     mapping.EntryNo,
+			EventType:   entity.DocumentEventError,
+			Description: err.Error(),
+			Actor:       "webhook",
+		})
+		return err
+	}
+
+	return nil
+}
+
+// handleWebhook applies the webhook to local state, documents and NAV
+func (u *webhookUsecase) handleWebhook(ctx context.Context, payload *entity.WebhookPayload, mapping *entity.DocumentMapping, timelineID string) error {
+	log := logger.FromContext(ctx, u.logger)
+	documentID := payload.Data.ID
 	email := mapping.Email
 	invoiceNumber := mapping.InvoiceNumber
 
 	// If invoice number is empty, try to extract from filename
 	if invoiceNumber == "" {
 		invoiceNumber = extractInvoiceNumber(payload.Data.Attributes.Filename)
+		ctx = logger.WithDocument(ctx, "", invoiceNumber)
+		log = logger.FromContext(ctx, u.logger)
 	}
 
 	// Build document info
@@ -125,32 +361,32 @@ func (u *webhookUsecase) ProcessWebhook(ctx context.Context, payload *entity.Web
 		SigningStatus:  payload.Data.Attributes.SigningStatus,
 		StampingStatus: payload.Data.Attributes.StampingStatus,
 		DocURL:         payload.Data.Attributes.DocURL,
-		UpdatedAt:      time.Now(),
+		UpdatedAt:      timeutil.Now(),
 	}
 
 	// Save document info to Redis
 	docInfoKey := documentInfoKeyPrefix + documentID
 	docInfoJSON, err := json.Marshal(docInfo)
 	if err != nil {
-		u.logger.Error("Failed to marshal document info", zap.Error(err))
+		log.Error("Failed to marshal document info", zap.Error(err))
 		return fmt.Errorf("failed to marshal document info: %w", err)
 	}
 
-	if err := u.redisClient.Set(ctx, docInfoKey, string(docInfoJSON), 0); err != nil {
-		u.logger.Error("Failed to save document info to Redis", zap.Error(err))
+	if err := u.cache.Set(ctx, docInfoKey, string(docInfoJSON), repository.MappingTTL(u.config)); err != nil {
+		log.Error("Failed to save document info to Redis", zap.Error(err))
 		return fmt.Errorf("failed to save document info: %w", err)
 	}
 
-	u.logger.Info("Document info saved to Redis",
+	log.Info("Document info saved to Redis",
 		zap.String("key", docInfoKey),
 		zap.String("email", email),
-		zap.String("invoice_number", invoiceNumber),
 	)
 
+	u.recordSignerEvents(ctx, payload, mapping, timelineID)
+
 	// Send log entry to NAV
-	if err := u.sendNAVLogEntry(ctx, payload, &mapping); err != nil {
-		u.logger.Warn("Failed to send log entry to NAV",
-			zap.String("document_id", documentID),
+	if err := u.sendNAVLogEntry(ctx, payload, mapping); err != nil {
+		log.Warn("Failed to send log entry to NAV",
 			zap.Error(err),
 		)
 		// Don't fail the webhook processing, just log warning
@@ -160,59 +396,75 @@ func (u *webhookUsecase) ProcessWebhook(ctx context.Context, payload *entity.Web
 	var progressPath, finishPath string
 	navSetup, err := u.getNAVSetupCached(ctx, mapping.EntryNo)
 	if err != nil {
-		u.logger.Warn("Failed to get NAV setup, using config values", zap.Error(err))
+		log.Warn("Failed to get NAV setup, using config values", zap.Error(err))
 	}
 	if navSetup != nil {
 		progressPath = navSetup.FileLocationProcess
 		finishPath = navSetup.FileLocationIn
-		u.logger.Info("Using NAV setup paths",
+		log.Info("Using NAV setup paths",
 			zap.String("progress_path", progressPath),
 			zap.String("finish_path", finishPath),
 		)
 	}
 
+	if payload.Data.Attributes.SigningStatus == entity.SigningStatusExpired {
+		return u.handleExpiry(ctx, mapping, invoiceNumber, navSetup, timelineID)
+	}
+	if entity.IsVoidedStatus(payload.Data.Attributes.SigningStatus) {
+		return u.handleVoided(ctx, payload, mapping, invoiceNumber, navSetup, timelineID)
+	}
+	if payload.Data.Attributes.SigningStatus == entity.SigningStatusDeclined {
+		u.handleDeclined(ctx, payload, mapping, invoiceNumber, timelineID)
+		return nil
+	}
+
 	// Handle signing completed
 	if payload.Data.Attributes.SigningStatus == "completed" && payload.Data.Attributes.StampingStatus != "success" {
-		u.logger.Info("Signing completed",
-			zap.String("document_id", documentID),
+		log.Info("Signing completed",
 			zap.String("stamping_status", payload.Data.Attributes.StampingStatus),
 		)
 
 		// Download a signed document
 		signedContent, err := u.DownloadDocument(ctx, email, payload.Data.Attributes.DocURL)
 		if err != nil {
-			u.logger.Error("Failed to download signed document",
-				zap.String("document_id", documentID),
+			log.Error("Failed to download signed document",
 				zap.Error(err),
 			)
 			return fmt.Errorf("failed to download signed document: %w", err)
 		}
 
-		// If stamping_status is "none" and we have stamp positions, request stamping
-		if payload.Data.Attributes.StampingStatus == "none" && mapping.StampPositions != nil && mapping.Stamping {
-			u.logger.Info("Stamping required, sending stamp request",
-				zap.String("document_id", documentID),
-			)
+		// If stamping_status is "none" and we have stamp positions (or place them), request stamping
+		hasStamps := len(mapping.AllStampPositions()) > 0 || u.stampPlacement(mapping) != config.StampPlacementFixed
+		if payload.Data.Attributes.StampingStatus == "none" && hasStamps && mapping.Stamping {
+			log.Info("Stamping required, sending stamp request")
 
-			if err := u.replaceDocumentInProgress(invoiceNumber, signedContent, progressPath); err != nil {
-				u.logger.Error("Failed to replace document in progress",
-					zap.String("document_id", documentID),
+			if err := u.replaceDocumentInProgress(ctx, invoiceNumber, mapping.Filename, signedContent, progressPath); err != nil {
+				log.Error("Failed to replace document in progress",
 					zap.Error(err),
 				)
 			}
 
-			if err := u.RequestStamping(ctx, email, signedContent, mapping); err != nil {
-				u.logger.Error("Failed to request stamping",
-					zap.String("document_id", documentID),
+			if err := u.RequestStamping(ctx, email, signedContent, *mapping); errors.Is(err, redis.ErrLeaseUnavailable) {
+				// Nothing was sent; the event is retried once Redis answers again
+				return err
+			} else if err != nil {
+				log.Error("Failed to request stamping",
 					zap.Error(err),
 				)
 				// Don't return error, just log it - stamping can be retried
+				recordDocumentEvent(ctx, u.docEventRepo, u.docEventHub, u.logger, &entity.DocumentEvent{
+					DocumentID:  timelineID,
+					InvoiceNo:   invoiceNumber,
+					EntryNo:     mapping.EntryNo,
+					EventType:   entity.DocumentEventError,
+					Description: fmt.Sprintf("Stamping request failed: %v", err),
+					Actor:       "system",
+				})
 			}
 		} else {
 			// No stamping needed, replace the file in progress folder
-			if err := u.replaceDocumentInProgress(invoiceNumber, signedContent, progressPath); err != nil {
-				u.logger.Error("Failed to replace document in progress",
-					zap.String("document_id", documentID),
+			if err := u.replaceDocumentInProgress(ctx, invoiceNumber, mapping.Filename, signedContent, progressPath); err != nil {
+				log.Error("Failed to replace document in progress",
 					zap.Error(err),
 				)
 			}
@@ -221,9 +473,17 @@ func (u *webhookUsecase) ProcessWebhook(ctx context.Context, payload *entity.Web
 
 	// Handle stamping completed - download a final document and save to finish
 	if payload.Data.Attributes.StampingStatus == "success" {
-		u.logger.Info("Stamping completed, downloading final document",
-			zap.String("document_id", documentID),
-		)
+		log.Info("Stamping completed, downloading final document")
+
+		recordDocumentEvent(ctx, u.docEventRepo, u.docEventHub, u.logger, &entity.DocumentEvent{
+			DocumentID:  timelineID,
+			InvoiceNo:   invoiceNumber,
+			EntryNo:     mapping.EntryNo,
+			EventType:   entity.DocumentEventStamped,
+			Description: "E-meterai stamping completed",
+			Actor:       "mekari",
+			DedupeKey:   timelineID + ":" + entity.DocumentEventStamped,
+		})
 
 		// Use the filename from mapping (original filename)
 		originalFilename := mapping.Filename
@@ -233,8 +493,7 @@ func (u *webhookUsecase) ProcessWebhook(ctx context.Context, payload *entity.Web
 
 		finalContent, err := u.DownloadDocument(ctx, email, payload.Data.Attributes.DocURL)
 		if err != nil {
-			u.logger.Error("Failed to download final document",
-				zap.String("document_id", documentID),
+			log.Error("Failed to download final document",
 				zap.Error(err),
 			)
 			return fmt.Errorf("failed to download final document: %w", err)
@@ -247,8 +506,7 @@ func (u *webhookUsecase) ProcessWebhook(ctx context.Context, payload *entity.Web
 			err = u.docService.SaveToFinishAndDeleteProgress(originalFilename, finalContent)
 		}
 		if err != nil {
-			u.logger.Error("Failed to save final document to finish folder",
-				zap.String("document_id", documentID),
+			log.Error("Failed to save final document to finish folder",
 				zap.String("filename", originalFilename),
 				zap.String("finish_path", finishPath),
 				zap.Error(err),
@@ -256,32 +514,424 @@ func (u *webhookUsecase) ProcessWebhook(ctx context.Context, payload *entity.Web
 			return fmt.Errorf("failed to save final document: %w", err)
 		}
 
-		u.logger.Info("Stamped document saved to finish folder",
-			zap.String("document_id", documentID),
+		log.Info("Stamped document saved to finish folder",
 			zap.String("filename", originalFilename),
 			zap.String("finish_path", finishPath),
 			zap.Int("size_bytes", len(finalContent)),
 		)
 
-		err = u.redisClient.Del(ctx, documentInfoKeyPrefix+documentID)
+		savedEvent := &entity.DocumentEvent{
+			DocumentID:  timelineID,
+			InvoiceNo:   invoiceNumber,
+			EntryNo:     mapping.EntryNo,
+			EventType:   entity.DocumentEventSavedToFinish,
+			Description: fmt.Sprintf("Final document %s saved to finish folder", originalFilename),
+			Actor:       "system",
+			DedupeKey:   timelineID + ":" + entity.DocumentEventSavedToFinish,
+		}
+		recordDocumentEvent(ctx, u.docEventRepo, u.docEventHub, u.logger, savedEvent)
+		if finishPath == "" {
+			finishPath = u.docService.GetFinishPath()
+		}
+		// A zero ID means the document was already saved and notified before
+		if savedEvent.ID != 0 || u.docEventRepo == nil {
+			err = u.notifier.Notify(ctx, &notifier.Notification{
+				Event:    "document_completed",
+				Severity: notifier.SeverityInfo,
+				Title:    "Document completed",
+				Message: fmt.Sprintf("Document %s (invoice %s, entry %d) was signed and stamped. It was saved to %s.",
+					originalFilename, invoiceNumber, mapping.EntryNo, finishPath),
+				Fields: map[string]interface{}{
+					"document_id":    timelineID,
+					"invoice_number": invoiceNumber,
+					"entry_no":       mapping.EntryNo,
+					"saved_to":       finishPath,
+				},
+			})
+			if err != nil {
+				log.Error("Failed to send document completed notification", zap.Error(err))
+			}
+		}
+
+		if u.config.DocumentSettings().AuditTrail {
+			u.saveAuditTrail(ctx, payload, mapping, originalFilename, finishPath, invoiceNumber, timelineID)
+		}
+
+		err = u.cache.Del(ctx, documentInfoKeyPrefix+documentID)
+		if err != nil {
+			log.Error("Failed to delete document info from Redis", zap.Error(err))
+		}
+
+		err = u.mappingRepo.DeleteByEntryNo(ctx, mapping.EntryNo)
+		if err != nil {
+			log.Error("Failed to delete entry number mapping", zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// saveAuditTrail downloads the audit trail certificate of a stamped document, saves it next to
+// the final document as <name>_audit_trail.pdf and reports its path to NAV. The final document is
+// already saved, so a missing or failed audit trail is only logged.
+func (u *webhookUsecase) saveAuditTrail(ctx context.Context, payload *entity.WebhookPayload, mapping *entity.DocumentMapping, filename, finishPath, invoiceNumber, timelineID string) {
+	log := logger.FromContext(ctx, u.logger)
+
+	content, err := u.DownloadDocument(ctx, mapping.Email, fmt.Sprintf(auditTrailURLFormat, payload.Data.ID))
+	if err != nil {
+		log.Warn("Audit trail not available, skipping", zap.Error(err))
+		return
+	}
+
+	auditTrailName := strings.TrimSuffix(filename, filepath.Ext(filename)) + auditTrailSuffix
+	path, err := u.docService.SaveFileWithPath(auditTrailName, content, finishPath)
+	if err != nil {
+		log.Error("Failed to save audit trail to finish folder",
+			zap.String("filename", auditTrailName),
+			zap.Error(err),
+		)
+		return
+	}
+
+	log.Info("Audit trail saved to finish folder",
+		zap.String("filename", auditTrailName),
+		zap.String("finish_path", finishPath),
+		zap.Int("size_bytes", len(content)),
+	)
+
+	recordDocumentEvent(ctx, u.docEventRepo, u.docEventHub, u.logger, &entity.DocumentEvent{
+		DocumentID:  timelineID,
+		InvoiceNo:   invoiceNumber,
+		EntryNo:     mapping.EntryNo,
+		EventType:   entity.DocumentEventAuditTrailSaved,
+		Description: fmt.Sprintf("Audit trail %s saved to finish folder", auditTrailName),
+		Actor:       "system",
+		DedupeKey:   timelineID + ":" + entity.DocumentEventAuditTrailSaved,
+	})
+
+	navEntry := u.buildNAVLogEntry(ctx, payload, mapping)
+	navEntry.AuditTrailPath = path
+	if err := u.navClient.UpdateLogEntry(ctx, navEntry); err != nil {
+		log.Warn("Failed to send audit trail path to NAV", zap.Error(err))
+	}
+}
+
+// handleExpiry moves the file of a document whose signing deadline passed out of the progress
+// folder (to deadline.on_expiry) and notifies. NAV already has the Expired status by then.
+func (u *webhookUsecase) handleExpiry(ctx context.Context, mapping *entity.DocumentMapping, invoiceNumber string, navSetup *entity.NAVSetup, timelineID string) error {
+	log := logger.FromContext(ctx, u.logger)
+
+	targetPath, err := u.moveOutOfProgress(ctx, invoiceNumber, mapping.Filename, navSetup, u.config.Deadline.OnExpiry)
+	if err != nil {
+		return fmt.Errorf("failed to move expired document: %w", err)
+	}
+
+	event := &entity.DocumentEvent{
+		DocumentID:  timelineID,
+		InvoiceNo:   invoiceNumber,
+		EntryNo:     mapping.EntryNo,
+		EventType:   entity.DocumentEventExpired,
+		Description: fmt.Sprintf("Signing deadline passed, document moved to %s", targetPath),
+		Actor:       "mekari",
+		DedupeKey:   timelineID + ":" + entity.DocumentEventExpired,
+	}
+	recordDocumentEvent(ctx, u.docEventRepo, u.docEventHub, u.logger, event)
+	// A zero ID means the expiry was already recorded and notified, e.g. a replayed webhook
+	if event.ID == 0 && u.docEventRepo != nil {
+		return nil
+	}
+
+	message := fmt.Sprintf("Document %s (invoice %s, entry %d) expired before all signers signed. It was moved to %s.",
+		mapping.Filename, invoiceNumber, mapping.EntryNo, targetPath)
+	if u.config.Deadline.Enabled && u.config.Deadline.AutoResubmit {
+		message += " It will be sent for signing again."
+	}
+	err = u.notifier.Notify(ctx, &notifier.Notification{
+		Event:    "document_expired",
+		Severity: notifier.SeverityWarning,
+		Title:    "Document expired unsigned",
+		Message:  message,
+		Fields: map[string]interface{}{
+			"document_id":    timelineID,
+			"invoice_number": invoiceNumber,
+			"entry_no":       mapping.EntryNo,
+			"moved_to":       targetPath,
+		},
+	})
+	if err != nil {
+		log.Error("Failed to send document expired notification", zap.Error(err))
+	}
+	return nil
+}
+
+// handleVoided ends a document voided or cancelled from the Mekari dashboard: the file leaves the
+// progress folder (to document.on_void), the cached keys of the document are removed and the
+// mapping is marked voided so later callbacks are ignored. NAV already has the Voided status.
+func (u *webhookUsecase) handleVoided(ctx context.Context, payload *entity.WebhookPayload, mapping *entity.DocumentMapping, invoiceNumber string, navSetup *entity.NAVSetup, timelineID string) error {
+	log := logger.FromContext(ctx, u.logger)
+	documentID := payload.Data.ID
+
+	targetPath, err := u.moveOutOfProgress(ctx, invoiceNumber, mapping.Filename, navSetup, u.config.DocumentSettings().OnVoid)
+	if err != nil {
+		return fmt.Errorf("failed to move voided document: %w", err)
+	}
+
+	u.clearDocumentKeys(ctx, documentID, mapping.EntryNo)
+
+	// Marked last so a failure above leaves the callback to be retried
+	mapping.SigningStatus = payload.Data.Attributes.SigningStatus
+	if err := u.mappingRepo.SaveByDocumentID(ctx, documentID, mapping); err != nil {
+		return fmt.Errorf("failed to mark document mapping voided: %w", err)
+	}
+
+	recordDocumentEvent(ctx, u.docEventRepo, u.docEventHub, u.logger, &entity.DocumentEvent{
+		DocumentID:  timelineID,
+		InvoiceNo:   invoiceNumber,
+		EntryNo:     mapping.EntryNo,
+		EventType:   entity.DocumentEventVoided,
+		Description: fmt.Sprintf("Document voided in Mekari, moved to %s", targetPath),
+		Actor:       "mekari",
+		DedupeKey:   timelineID + ":" + entity.DocumentEventVoided,
+	})
+
+	err = u.notifier.Notify(ctx, &notifier.Notification{
+		Event:    "document_voided",
+		Severity: notifier.SeverityWarning,
+		Title:    "Document voided",
+		Message: fmt.Sprintf("Document %s (invoice %s, entry %d) was voided in Mekari. It was moved to %s.",
+			mapping.Filename, invoiceNumber, mapping.EntryNo, targetPath),
+		Fields: map[string]interface{}{
+			"document_id":    timelineID,
+			"invoice_number": invoiceNumber,
+			"entry_no":       mapping.EntryNo,
+			"moved_to":       targetPath,
+		},
+	})
+	if err != nil {
+		log.Error("Failed to send document voided notification", zap.Error(err))
+	}
+	return nil
+}
+
+// handleDeclined records that a signer declined the document and notifies it. The file stays in
+// the progress folder, so the document can be resubmitted.
+func (u *webhookUsecase) handleDeclined(ctx context.Context, payload *entity.WebhookPayload, mapping *entity.DocumentMapping, invoiceNumber, timelineID string) {
+	log := logger.FromContext(ctx, u.logger)
+
+	var declinedBy []string
+	for _, signer := range payload.Data.Attributes.Signers {
+		if signer.Status == entity.SigningStatusDeclined {
+			declinedBy = append(declinedBy, signer.Email)
+		}
+	}
+	description := "Document declined in Mekari"
+	if len(declinedBy) > 0 {
+		description = fmt.Sprintf("Document declined by %s", strings.Join(declinedBy, ", "))
+	}
+
+	event := &entity.DocumentEvent{
+		DocumentID:  timelineID,
+		InvoiceNo:   invoiceNumber,
+		EntryNo:     mapping.EntryNo,
+		EventType:   entity.DocumentEventDeclined,
+		Description: description,
+		Actor:       "mekari",
+		DedupeKey:   timelineID + ":" + entity.DocumentEventDeclined,
+	}
+	recordDocumentEvent(ctx, u.docEventRepo, u.docEventHub, u.logger, event)
+	// A zero ID means the rejection was already recorded and notified, e.g. a replayed webhook
+	if event.ID == 0 && u.docEventRepo != nil {
+		return
+	}
+
+	err := u.notifier.Notify(ctx, &notifier.Notification{
+		Event:    "document_declined",
+		Severity: notifier.SeverityWarning,
+		Title:    "Document declined",
+		Message: fmt.Sprintf("Document %s (invoice %s, entry %d) was declined by a signer. It stays in the progress folder and can be resubmitted.",
+			mapping.Filename, invoiceNumber, mapping.EntryNo),
+		Fields: map[string]interface{}{
+			"document_id":    timelineID,
+			"invoice_number": invoiceNumber,
+			"entry_no":       mapping.EntryNo,
+			"declined_by":    strings.Join(declinedBy, ", "),
+		},
+	})
+	if err != nil {
+		log.Error("Failed to send document declined notification", zap.Error(err))
+	}
+}
+
+// processAttachmentWebhook applies a webhook of an attachment sent as a linked document. Only its
+// own file is saved to finish or moved out of progress; its status is kept on the mapping of the
+// main document, whose timeline gets the events, and NAV gets the status of the whole entry.
+func (u *webhookUsecase) processAttachmentWebhook(ctx context.Context, payload *entity.WebhookPayload, mapping *entity.DocumentMapping) error {
+	log := logger.FromContext(ctx, u.logger)
+	documentID := payload.Data.ID
+	status := payload.Data.Attributes.SigningStatus
+
+	parent, err := u.mappingRepo.FindByDocumentID(ctx, mapping.ParentDocumentID)
+	if err != nil {
+		return fmt.Errorf("main document mapping not found: %w", err)
+	}
+
+	navSetup, err := u.getNAVSetupCached(ctx, mapping.EntryNo)
+	if err != nil {
+		log.Warn("Failed to get NAV setup, using config values", zap.Error(err))
+	}
+
+	event := &entity.DocumentEvent{
+		DocumentID: parent.DocumentID,
+		InvoiceNo:  parent.InvoiceNumber,
+		EntryNo:    parent.EntryNo,
+		Actor:      "mekari",
+	}
+	switch {
+	case status == entity.SigningStatusCompleted:
+		content, err := u.DownloadDocument(ctx, mapping.Email, payload.Data.Attributes.DocURL)
 		if err != nil {
-			u.logger.Error("Failed to delete document info from Redis", zap.Error(err))
+			return fmt.Errorf("failed to download signed attachment: %w", err)
 		}
 
-		err = u.redisClient.Del(ctx, entryNoKeyPrefix+strconv.Itoa(mapping.EntryNo))
+		progressPath, finishPath := u.docService.GetProgressPath(), u.docService.GetFinishPath()
+		if navSetup != nil && navSetup.FileLocationProcess != "" && navSetup.FileLocationIn != "" {
+			progressPath, finishPath = navSetup.FileLocationProcess, navSetup.FileLocationIn
+		}
+		if err := u.docService.SaveToFinishAndDeleteProgressWithPath(mapping.Filename, content, finishPath, progressPath); err != nil {
+			return fmt.Errorf("failed to save signed attachment: %w", err)
+		}
+		event.EventType = entity.DocumentEventAttachmentSigned
+		event.Description = fmt.Sprintf("Attachment %s signed and saved to finish folder", mapping.Filename)
+
+	case status == entity.SigningStatusExpired, entity.IsVoidedStatus(status):
+		moveTo, eventType := u.config.Deadline.OnExpiry, entity.DocumentEventExpired
+		if entity.IsVoidedStatus(status) {
+			moveTo, eventType = u.config.DocumentSettings().OnVoid, entity.DocumentEventVoided
+		}
+		// No invoice number: the main document's file has the same one
+		targetPath, err := u.moveOutOfProgress(ctx, "", mapping.Filename, navSetup, moveTo)
 		if err != nil {
-			u.logger.Error("Failed to delete entry number mapping from Redis", zap.Error(err))
+			return fmt.Errorf("failed to move attachment: %w", err)
+		}
+		event.EventType = eventType
+		event.Description = fmt.Sprintf("Attachment %s %s, moved to %s", mapping.Filename, status, targetPath)
+
+		// Voided attachments are marked once handled, like voided documents
+		if entity.IsVoidedStatus(status) {
+			mapping.SigningStatus = status
+			if err := u.mappingRepo.SaveByDocumentID(ctx, documentID, mapping); err != nil {
+				return fmt.Errorf("failed to mark attachment mapping voided: %w", err)
+			}
 		}
 	}
+	if event.EventType != "" {
+		event.DedupeKey = documentID + ":" + event.EventType
+		recordDocumentEvent(ctx, u.docEventRepo, u.docEventHub, u.logger, event)
+	}
+
+	if status == "" {
+		return nil
+	}
+	for i := range parent.Attachments {
+		if parent.Attachments[i].DocumentID == documentID {
+			parent.Attachments[i].SigningStatus = status
+		}
+	}
+	if err := u.mappingRepo.SaveByDocumentID(ctx, parent.DocumentID, parent); err != nil {
+		return fmt.Errorf("failed to save attachment status to main document mapping: %w", err)
+	}
 
+	parentPayload, err := u.lastPayload(ctx, parent.DocumentID)
+	if err != nil {
+		log.Warn("No state of the main document to send to NAV", zap.Error(err))
+		return nil
+	}
+	if err := u.sendNAVLogEntry(ctx, parentPayload, parent); err != nil {
+		log.Warn("Failed to send log entry to NAV", zap.Error(err))
+	}
 	return nil
 }
 
+// moveOutOfProgress moves the file of a document that ended unsigned from the progress folder to
+// the failed or ready folder (config.MoveToFailed or config.MoveToReady) and returns that folder.
+// A file no longer in progress, e.g. on a replayed webhook, is left alone.
+func (u *webhookUsecase) moveOutOfProgress(ctx context.Context, invoiceNumber, filename string, navSetup *entity.NAVSetup, moveTo string) (string, error) {
+	log := logger.FromContext(ctx, u.logger)
+
+	progressPath := u.docService.GetProgressPath()
+	targetPath := u.docService.GetFailedPath()
+	if moveTo == config.MoveToReady {
+		targetPath = u.docService.GetReadyPath()
+	}
+	if navSetup != nil && navSetup.FileLocationProcess != "" {
+		progressPath = navSetup.FileLocationProcess
+		if moveTo == config.MoveToReady && navSetup.FileLocationOut != "" {
+			targetPath = navSetup.FileLocationOut
+		}
+	}
+
+	filename, err := u.findInProgress(invoiceNumber, filename, progressPath)
+	if err != nil {
+		log.Warn("Document not found in progress", zap.String("progress_path", progressPath), zap.Error(err))
+		return targetPath, nil
+	}
+	if err := u.docService.MoveFromProgressWithPath(filename, progressPath, targetPath); err != nil {
+		return "", err
+	}
+
+	log.Info("Document moved out of progress",
+		zap.String("filename", filename),
+		zap.String("to", targetPath),
+	)
+	return targetPath, nil
+}
+
+// findInProgress returns the name of the file of a document in the progress folder (the
+// configured one when progressPath is empty): filename when it is there, otherwise the first file
+// containing invoiceNumber, as documents of older mappings were found. Attachments with the same
+// invoice number share the folder, so only filename is tried when invoiceNumber is empty.
+func (u *webhookUsecase) findInProgress(invoiceNumber, filename, progressPath string) (string, error) {
+	if progressPath == "" {
+		progressPath = u.docService.GetProgressPath()
+	}
+	if filename != "" {
+		if _, _, err := u.docService.FindFile(filename, progressPath); err == nil {
+			return filepath.Base(filename), nil
+		}
+	}
+	if invoiceNumber == "" {
+		return "", fmt.Errorf("document %s not found in progress", filename)
+	}
+	return u.docService.FindFilenameInProgressWithPath(invoiceNumber, progressPath)
+}
+
+// clearDocumentKeys removes the cached document info and NAV setup of a document that ended, and
+// its entry_no key when that still points to it. The mapping by document ID is kept to recognize
+// late callbacks and to resubmit the document.
+func (u *webhookUsecase) clearDocumentKeys(ctx context.Context, documentID string, entryNo int) {
+	log := logger.FromContext(ctx, u.logger)
+
+	keys := []string{documentInfoKeyPrefix + documentID, navSetupKeyPrefix + strconv.Itoa(entryNo)}
+	if err := u.cache.Del(ctx, keys...); err != nil {
+		log.Warn("Failed to delete document keys", zap.Strings("keys", keys), zap.Error(err))
+	}
+
+	entryMapping, err := u.mappingRepo.FindByEntryNo(ctx, entryNo)
+	if err != nil || entryMapping == nil || entryMapping.DocumentID != documentID {
+		return
+	}
+	if err := u.mappingRepo.DeleteByEntryNo(ctx, entryNo); err != nil {
+		log.Warn("Failed to delete entry_no mapping", zap.Int("entry_no", entryNo), zap.Error(err))
+	}
+}
+
 func (u *webhookUsecase) DownloadDocument(ctx context.Context, email, docURL string) ([]byte, error) {
+	log := logger.FromContext(ctx, u.logger)
+
 	// Build full download URL
 	downloadURL := u.config.Mekari.BaseURL + docURL
 
-	u.logger.Info("Downloading document",
+	log.Info("Downloading document",
 		zap.String("url", downloadURL),
 		zap.String("email", email),
 		zap.String("auth_type", u.config.Mekari.AuthType),
@@ -292,6 +942,9 @@ func (u *webhookUsecase) DownloadDocument(ctx context.Context, email, docURL str
 	if err != nil {
 		return nil, fmt.Errorf("failed to create download request: %w", err)
 	}
+	if requestID := logger.RequestIDFromContext(ctx); requestID != "" {
+		req.Header.Set(logger.RequestIDHeader, requestID)
+	}
 
 	// Set auth headers based on config
 	if u.config.Mekari.IsHMAC() {
@@ -299,7 +952,7 @@ func (u *webhookUsecase) DownloadDocument(ctx context.Context, email, docURL str
 		if err := u.hmacSignature.SignRequest(req); err != nil {
 			return nil, fmt.Errorf("failed to sign request with HMAC: %w", err)
 		}
-		u.logger.Debug("Using HMAC authentication for download request")
+		log.Debug("Using HMAC authentication for download request")
 	} else {
 		// Use OAuth2 authentication
 		accessToken, err := u.tokenService.GetAccessToken(ctx, email)
@@ -307,8 +960,8 @@ func (u *webhookUsecase) DownloadDocument(ctx context.Context, email, docURL str
 			return nil, fmt.Errorf("failed to get access token: %w", err)
 		}
 		req.Header.Set("Authorization", "Bearer "+accessToken)
-		req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
-		u.logger.Debug("Using OAuth2 authentication for download request")
+		req.Header.Set("Date", timeutil.Now().Format(http.TimeFormat))
+		log.Debug("Using OAuth2 authentication for download request")
 	}
 
 	// Execute request
@@ -329,23 +982,18 @@ func (u *webhookUsecase) DownloadDocument(ctx context.Context, email, docURL str
 		return nil, fmt.Errorf("failed to read download response: %w", err)
 	}
 
-	u.logger.Info("Document downloaded successfully",
+	log.Info("Document downloaded successfully",
 		zap.Int("size_bytes", len(content)),
 	)
 
 	return content, nil
 }
 
-func (u *webhookUsecase) replaceDocumentInProgress(invoiceNumber string, content []byte, progressPath string) error {
-	var filename string
-	var err error
+func (u *webhookUsecase) replaceDocumentInProgress(ctx context.Context, invoiceNumber, filename string, content []byte, progressPath string) error {
+	log := logger.FromContext(ctx, u.logger)
 
 	// Find the filename in progress folder (use NAV setup path if provided)
-	if progressPath != "" {
-		filename, err = u.docService.FindFilenameInProgressWithPath(invoiceNumber, progressPath)
-	} else {
-		filename, err = u.docService.FindFilenameInProgress(invoiceNumber)
-	}
+	filename, err := u.findInProgress(invoiceNumber, filename, progressPath)
 	if err != nil {
 		return fmt.Errorf("failed to find file in progress: %w", err)
 	}
@@ -360,7 +1008,7 @@ func (u *webhookUsecase) replaceDocumentInProgress(invoiceNumber string, content
 		return fmt.Errorf("failed to replace file: %w", err)
 	}
 
-	u.logger.Info("Document replaced in progress folder",
+	log.Info("Document replaced in progress folder",
 		zap.String("filename", filename),
 		zap.String("progress_path", progressPath),
 		zap.Int("size_bytes", len(content)),
@@ -369,35 +1017,28 @@ func (u *webhookUsecase) replaceDocumentInProgress(invoiceNumber string, content
 	return nil
 }
 
-func (u *webhookUsecase) RequestStamping(ctx context.Context, email string, signedPDFContent []byte, mapping DocumentMapping) error {
-	// Encode PDF to base64
-	base64Doc := base64.StdEncoding.EncodeToString(signedPDFContent)
-	defaultWidth := float64(80)
-	defaultHeight := float64(80)
+func (u *webhookUsecase) RequestStamping(ctx context.Context, email string, signedPDFContent []byte, mapping entity.DocumentMapping) error {
+	ctx = logger.WithDocument(ctx, mapping.DocumentID, mapping.InvoiceNumber)
+	log := logger.FromContext(ctx, u.logger)
 
-	if mapping.StampPositions.Width == 0 {
-		mapping.StampPositions.Width = defaultWidth
-		mapping.StampPositions.Height = defaultHeight
+	// Each stamp request uses e-meterai balance, so a document is never stamped by two instances
+	lease, err := u.leases.Acquire(ctx, "stamp:"+mapping.DocumentID, documentLeaseTTL, 0)
+	if err != nil {
+		return fmt.Errorf("failed to acquire stamping lease: %w", err)
 	}
+	defer lease.Release(context.WithoutCancel(ctx))
+
+	// Encode PDF to base64
+	base64Doc := base64.StdEncoding.EncodeToString(signedPDFContent)
 
-	if mapping.StampPositions.CanvasWidth == 0 {
-		mapping.StampPositions.CanvasWidth = entity.DefaultCanvasWidth
-		mapping.StampPositions.CanvasHeight = entity.DefaultCanvasHeight
+	annotations, err := u.stampAnnotations(ctx, signedPDFContent, mapping)
+	if err != nil {
+		return err
 	}
 
-	// Build stamp annotation from saved stamp positions
-	annotations := []entity.StampAnnotation{}
-	if mapping.StampPositions != nil {
-		annotations = append(annotations, entity.StampAnnotation{
-			Page:          mapping.StampPositions.Page,
-			PositionX:     mapping.StampPositions.X,
-			PositionY:     mapping.StampPositions.Y,
-			ElementWidth:  mapping.StampPositions.Width, // Default e-meterai size
-			ElementHeight: mapping.StampPositions.Height,
-			CanvasWidth:   mapping.StampPositions.CanvasWidth,  // A4 width
-			CanvasHeight:  mapping.StampPositions.CanvasHeight, // A4 height
-			TypeOf:        "meterai",
-		})
+	// Mekari rejects stamping without balance with an unclear error, after the document is signed
+	if err := u.quota.CheckStampQuota(ctx, mapping.Email, len(annotations)); err != nil {
+		return err
 	}
 
 	// Build stamp request
@@ -413,7 +1054,7 @@ func (u *webhookUsecase) RequestStamping(ctx context.Context, email string, sign
 	// Build stamp URL
 	stampURL := u.config.Mekari.BaseURL + "/documents/stamp"
 
-	u.logger.Info("Sending stamp request",
+	log.Info("Sending stamp request",
 		zap.String("url", stampURL),
 		zap.String("email", email),
 		zap.String("filename", mapping.Filename),
@@ -428,30 +1069,37 @@ func (u *webhookUsecase) RequestStamping(ctx context.Context, email string, sign
 	}
 
 	var stampResp entity.StampResponse
-	err := u.localClient.Post(ctx, reqCtx, "/documents/stamp", stampReq, &stampResp)
+	err = u.localClient.Post(ctx, reqCtx, "/documents/stamp", stampReq, &stampResp)
 	if err != nil {
 		return fmt.Errorf("failed to send stamp request: %w", err)
 	}
 
-	u.logger.Info("Stamp request successful",
+	log.Info("Stamp request successful",
 		zap.String("stamp_doc_id", stampResp.Data.ID),
 		zap.String("status", stampResp.Data.Attributes.Status),
 	)
 
-	// Save stamp document ID -> original mapping to Redis
+	recordDocumentEvent(ctx, u.docEventRepo, u.docEventHub, u.logger, &entity.DocumentEvent{
+		DocumentID:  mapping.DocumentID,
+		InvoiceNo:   mapping.InvoiceNumber,
+		EntryNo:     mapping.EntryNo,
+		EventType:   entity.DocumentEventStampRequested,
+		Description: fmt.Sprintf("E-meterai stamping requested (stamp document %s)", stampResp.Data.ID),
+		Actor:       email,
+		DedupeKey:   mapping.DocumentID + ":" + entity.DocumentEventStampRequested + ":" + stampResp.Data.ID,
+	})
+
+	// Save stamp document ID -> original mapping
 	// This is needed to retrieve the original filename when stamping completes
-	stampDocKey := documentKeyPrefix + stampResp.Data.ID
-	mappingJSON, _ := json.Marshal(mapping)
-	if err := u.redisClient.Set(ctx, stampDocKey, string(mappingJSON), 0); err != nil {
-		u.logger.Warn("Failed to save stamp document mapping to Redis",
+	if err := u.mappingRepo.SaveByDocumentID(ctx, stampResp.Data.ID, &mapping); err != nil {
+		log.Warn("Failed to save stamp document mapping",
 			zap.String("stamp_doc_id", stampResp.Data.ID),
 			zap.Error(err),
 		)
 	} else {
-		u.logger.Info("Stamp document mapping saved to Redis",
-			zap.String("key", stampDocKey),
+		log.Info("Stamp document mapping saved",
+			zap.String("stamp_doc_id", stampResp.Data.ID),
 			zap.String("email", email),
-			zap.String("invoice_number", mapping.InvoiceNumber),
 			zap.String("filename", mapping.Filename),
 		)
 	}
@@ -459,16 +1107,163 @@ func (u *webhookUsecase) RequestStamping(ctx context.Context, email string, sign
 	return nil
 }
 
+// stampPlacement returns the e-meterai placement of a document, chosen by its sign request or by
+// stamping.placement
+func (u *webhookUsecase) stampPlacement(mapping *entity.DocumentMapping) string {
+	if mapping.StampPlacement != "" {
+		return mapping.StampPlacement
+	}
+	return u.config.Stamping.Placement
+}
+
+// stampAnnotations returns the e-meterai annotations of a document: the positions saved with the
+// sign request, or with the auto and anchor placements a position found in the signed PDF
+func (u *webhookUsecase) stampAnnotations(ctx context.Context, signedPDF []byte, mapping entity.DocumentMapping) ([]entity.StampAnnotation, error) {
+	log := logger.FromContext(ctx, u.logger)
+	cfg := u.config.Stamping
+
+	placement := u.stampPlacement(&mapping)
+	if placement != config.StampPlacementFixed {
+		annotation, err := u.placeStamp(signedPDF, placement)
+		if err == nil {
+			log.Info("E-meterai placed",
+				zap.String("placement", placement),
+				zap.Int("page", annotation.Page),
+				zap.Float64("x", annotation.PositionX),
+				zap.Float64("y", annotation.PositionY),
+			)
+			return []entity.StampAnnotation{*annotation}, nil
+		}
+		if len(mapping.AllStampPositions()) == 0 {
+			return nil, fmt.Errorf("failed to place e-meterai: %w", err)
+		}
+		log.Warn("Failed to place e-meterai, using the positions of the sign request",
+			zap.String("placement", placement),
+			zap.Error(err),
+		)
+	}
+
+	// Build one stamp annotation per saved stamp position
+	annotations := []entity.StampAnnotation{}
+	for _, position := range mapping.AllStampPositions() {
+		if position.Width == 0 {
+			position.Width = cfg.Width
+			position.Height = cfg.Height
+		}
+
+		if position.CanvasWidth == 0 {
+			position.CanvasWidth = entity.DefaultCanvasWidth
+			position.CanvasHeight = entity.DefaultCanvasHeight
+		}
+
+		annotations = append(annotations, entity.StampAnnotation{
+			Page:          position.Page,
+			PositionX:     position.X,
+			PositionY:     position.Y,
+			ElementWidth:  position.Width,
+			ElementHeight: position.Height,
+			CanvasWidth:   position.CanvasWidth,
+			CanvasHeight:  position.CanvasHeight,
+			TypeOf:        "meterai",
+		})
+	}
+	return annotations, nil
+}
+
+// placeStamp finds the e-meterai position in the signed PDF. E-meterai rules require it on a
+// signed page, whole and readable, so auto puts it beside the first signature without covering
+// any signature, other stamp or the page edge; without room there the anchor is used.
+func (u *webhookUsecase) placeStamp(signedPDF []byte, placement string) (*entity.StampAnnotation, error) {
+	cfg := u.config.Stamping
+	layout, err := document.ReadPDFLayout(signedPDF)
+	if err != nil {
+		return nil, err
+	}
+
+	page, box, found := 0, document.Box{}, false
+	if placement == config.StampPlacementAuto {
+		if sigPage, signature, ok := layout.FirstSignature(); ok {
+			box, found = layout.PlaceBeside(sigPage, signature, cfg.Width, cfg.Height, cfg.Margin)
+			page = sigPage
+		}
+	}
+
+	if !found {
+		page = cfg.Anchor.Page
+		if page <= 0 || page > len(layout.Pages) {
+			page = len(layout.Pages)
+		}
+		p := layout.Pages[page-1]
+		box = document.Box{X: cfg.Anchor.X, Y: cfg.Anchor.Y, Width: cfg.Width, Height: cfg.Height}
+		if cfg.Anchor.X == 0 && cfg.Anchor.Y == 0 {
+			box.X, box.Y = p.Width-cfg.Margin-cfg.Width, p.Height-cfg.Margin-cfg.Height
+		}
+		if !layout.IsFree(page, box, 0) {
+			return nil, fmt.Errorf("anchor on page %d is off the page or covers a signature", page)
+		}
+	}
+
+	p := layout.Pages[page-1]
+	return &entity.StampAnnotation{
+		Page:          page,
+		PositionX:     box.X,
+		PositionY:     box.Y,
+		ElementWidth:  box.Width,
+		ElementHeight: box.Height,
+		CanvasWidth:   p.Width, // Page size in points, so the position needs no scaling
+		CanvasHeight:  p.Height,
+		TypeOf:        "meterai",
+	}, nil
+}
+
+// recordSignerEvents records signer and signing completion milestones found in the webhook
+func (u *webhookUsecase) recordSignerEvents(ctx context.Context, payload *entity.WebhookPayload, mapping *entity.DocumentMapping, timelineID string) {
+	for _, signer := range payload.Data.Attributes.Signers {
+		if signer.Status != "completed" {
+			continue
+		}
+
+		event := &entity.DocumentEvent{
+			DocumentID:  timelineID,
+			InvoiceNo:   mapping.InvoiceNumber,
+			EntryNo:     mapping.EntryNo,
+			EventType:   entity.DocumentEventSignerSigned,
+			Description: fmt.Sprintf("%s signed the document", signer.Name),
+			Actor:       signer.Email,
+			DedupeKey:   timelineID + ":" + entity.DocumentEventSignerSigned + ":" + signer.Email,
+		}
+		if signer.SignedAt != nil {
+			if signedAt, err := time.Parse(time.RFC3339, *signer.SignedAt); err == nil {
+				event.CreatedAt = signedAt.UTC()
+			}
+		}
+		recordDocumentEvent(ctx, u.docEventRepo, u.docEventHub, u.logger, event)
+	}
+
+	if payload.Data.Attributes.SigningStatus == "completed" {
+		recordDocumentEvent(ctx, u.docEventRepo, u.docEventHub, u.logger, &entity.DocumentEvent{
+			DocumentID:  timelineID,
+			InvoiceNo:   mapping.InvoiceNumber,
+			EntryNo:     mapping.EntryNo,
+			EventType:   entity.DocumentEventSigningCompleted,
+			Description: "All signers have signed the document",
+			Actor:       "mekari",
+			DedupeKey:   timelineID + ":" + entity.DocumentEventSigningCompleted,
+		})
+	}
+}
+
 // getNAVSetupCached gets NAV setup from cache or fetches from NAV
 func (u *webhookUsecase) getNAVSetupCached(ctx context.Context, entryNo int) (*entity.NAVSetup, error) {
+	log := logger.FromContext(ctx, u.logger)
 	cacheKey := navSetupKeyPrefix + strconv.Itoa(entryNo)
 
 	// Try to get from cache
-	cached, err := u.redisClient.Get(ctx, cacheKey)
+	cached, err := u.cache.Get(ctx, cacheKey)
 	if err == nil && cached != "" {
 		var setup entity.NAVSetup
 		if err := json.Unmarshal([]byte(cached), &setup); err == nil {
-			u.logger.Debug("Using cached NAV setup", zap.Int("entry_no", entryNo))
+			log.Debug("Using cached NAV setup", zap.Int("entry_no", entryNo))
 			return &setup, nil
 		}
 	}
@@ -484,10 +1279,10 @@ func (u *webhookUsecase) getNAVSetupCached(ctx context.Context, entryNo int) (*e
 
 	// Cache the setup (no expiration - permanent for this entry_no)
 	setupJSON, _ := json.Marshal(setup)
-	if err := u.redisClient.Set(ctx, cacheKey, string(setupJSON), 0); err != nil {
-		u.logger.Warn("Failed to cache NAV setup", zap.Error(err))
+	if err := u.cache.Set(ctx, cacheKey, string(setupJSON), 0); err != nil {
+		log.Warn("Failed to cache NAV setup", zap.Error(err))
 	} else {
-		u.logger.Info("NAV setup cached",
+		log.Info("NAV setup cached",
 			zap.Int("entry_no", entryNo),
 			zap.String("key", cacheKey),
 		)
@@ -496,17 +1291,29 @@ func (u *webhookUsecase) getNAVSetupCached(ctx context.Context, entryNo int) (*e
 	return setup, nil
 }
 
+func (u *webhookUsecase) SendNAVLogEntry(ctx context.Context, payload *entity.WebhookPayload, mapping *entity.DocumentMapping) error {
+	return u.sendNAVLogEntry(ctx, payload, mapping)
+}
+
 // sendNAVLogEntry sends a log entry to NAV using PATCH
-func (u *webhookUsecase) sendNAVLogEntry(ctx context.Context, payload *entity.WebhookPayload, mapping *DocumentMapping) error {
+func (u *webhookUsecase) sendNAVLogEntry(ctx context.Context, payload *entity.WebhookPayload, mapping *entity.DocumentMapping) error {
+	return u.navClient.UpdateLogEntry(ctx, u.buildNAVLogEntry(ctx, payload, mapping))
+}
+
+// buildNAVLogEntry builds the NAV log entry for the state of a document in a webhook
+func (u *webhookUsecase) buildNAVLogEntry(ctx context.Context, payload *entity.WebhookPayload, mapping *entity.DocumentMapping) *entity.NAVLogEntry {
+	log := logger.FromContext(ctx, u.logger)
+
 	// Default locations from config
-	locationIn := u.config.Document.BasePath + "/" + u.config.Document.ReadyFolder
-	locationProcess := u.config.Document.BasePath + "/" + u.config.Document.ProgressFolder
-	locationOut := u.config.Document.BasePath + "/" + u.config.Document.FinishFolder
+	doc := u.config.DocumentSettings()
+	locationIn := doc.BasePath + "/" + doc.ReadyFolder
+	locationProcess := doc.BasePath + "/" + doc.ProgressFolder
+	locationOut := doc.BasePath + "/" + doc.FinishFolder
 
 	// Get NAV setup (cached by entry_no)
 	navSetup, err := u.getNAVSetupCached(ctx, mapping.EntryNo)
 	if err != nil {
-		u.logger.Warn("Failed to get NAV setup, using config values", zap.Error(err))
+		log.Warn("Failed to get NAV setup, using config values", zap.Error(err))
 	} else if navSetup != nil {
 		locationIn = navSetup.FileLocationIn
 		locationProcess = navSetup.FileLocationProcess
@@ -520,21 +1327,33 @@ func (u *webhookUsecase) sendNAVLogEntry(ctx context.Context, payload *entity.We
 		FilePathIn:      locationIn,
 		FilePathProcess: locationProcess,
 		FilePathOut:     locationOut,
-		SigningStatus:   entity.MapSigningStatus(payload.Data.Attributes.SigningStatus),
+		SigningStatus:   entity.MapSigningStatus(u.entrySigningStatus(ctx, payload.Data.Attributes.SigningStatus, mapping)),
 		StampingStatus:  entity.MapStampingStatus(payload.Data.Attributes.StampingStatus),
+		SigningAttempt:  mapping.Attempt(),
 	}
 
-	// Populate signer info (up to 3 signers based on NAV API)
+	// Populate signer info (up to 3 signers based on NAV API). NAV expects signing dates in UTC.
 	signers := payload.Data.Attributes.Signers
+	statuses := make([]string, len(signers))
+	for i, signer := range signers {
+		statuses[i] = entity.MapSigningStatus(signer.Status)
+	}
+	if mapping.EnforceOrder {
+		for i, waiting := range entity.WaitingSigners(signers) {
+			if waiting {
+				statuses[i] = entity.NAVSigningStatusWaiting
+			}
+		}
+	}
 
 	// Signer 1
 	if len(signers) > 0 && navEntry.StampingStatus != "Completed" {
 		//navEntry.Signer1Name = signers[0].Name
 		//navEntry.Signer1Email = signers[0].Email
 		//navEntry.Signer1Order = strconv.Itoa(signers[0].Order)
-		navEntry.Signer1SigningStatus = entity.MapSigningStatus(signers[0].Status)
+		navEntry.Signer1SigningStatus = statuses[0]
 		if signers[0].SignedAt != nil {
-			navEntry.Signer1SigningDate = *signers[0].SignedAt
+			navEntry.Signer1SigningDate = timeutil.UTC(*signers[0].SignedAt)
 		} else {
 			navEntry.Signer1SigningDate = "0001-01-01T00:00:00Z"
 		}
@@ -545,9 +1364,9 @@ func (u *webhookUsecase) sendNAVLogEntry(ctx context.Context, payload *entity.We
 		//navEntry.Signer2Name = signers[1].Name
 		//navEntry.Signer2Email = signers[1].Email
 		//navEntry.Signer2Order = strconv.Itoa(signers[1].Order)
-		navEntry.Signer2SigningStatus = entity.MapSigningStatus(signers[1].Status)
+		navEntry.Signer2SigningStatus = statuses[1]
 		if signers[1].SignedAt != nil {
-			navEntry.Signer2SigningDate = *signers[1].SignedAt
+			navEntry.Signer2SigningDate = timeutil.UTC(*signers[1].SignedAt)
 		} else {
 			navEntry.Signer2SigningDate = "0001-01-01T00:00:00Z"
 		}
@@ -558,15 +1377,28 @@ func (u *webhookUsecase) sendNAVLogEntry(ctx context.Context, payload *entity.We
 		//navEntry.Signer3Name = signers[2].Name
 		//navEntry.Signer3Email = signers[2].Email
 		//navEntry.Signer3Order = strconv.Itoa(signers[2].Order)
-		navEntry.Signer3SigningStatus = entity.MapSigningStatus(signers[2].Status)
+		navEntry.Signer3SigningStatus = statuses[2]
 		if signers[2].SignedAt != nil {
-			navEntry.Signer3SigningDate = *signers[2].SignedAt
+			navEntry.Signer3SigningDate = timeutil.UTC(*signers[2].SignedAt)
 		} else {
 			navEntry.Signer3SigningDate = "0001-01-01T00:00:00Z"
 		}
 	}
 
-	return u.navClient.UpdateLogEntry(ctx, navEntry)
+	return navEntry
+}
+
+// entrySigningStatus returns the signing status of the entry of a document, which includes the
+// attachments sent as linked documents. The mapping of a stamped copy holds the attachment
+// statuses of when stamping was requested, so they are read from the main document again.
+func (u *webhookUsecase) entrySigningStatus(ctx context.Context, status string, mapping *entity.DocumentMapping) string {
+	if len(mapping.Attachments) == 0 {
+		return status
+	}
+	if current, err := u.mappingRepo.FindByDocumentID(ctx, mapping.DocumentID); err == nil && current != nil {
+		mapping = current
+	}
+	return mapping.AggregateSigningStatus(status)
 }
 
 // extractInvoiceNumber extracts invoice number from filename
@@ -0,0 +1,90 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/usecase"
+)
+
+// AlertWorker periodically checks the alert thresholds (alerts.*) and notifies the rules that
+// reach them
+type AlertWorker struct {
+	config   *config.Config
+	usecase  usecase.AlertUsecase
+	reporter *logger.Reporter
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func NewAlertWorker(
+	lc fx.Lifecycle,
+	cfg *config.Config,
+	alertUsecase usecase.AlertUsecase,
+	reporter *logger.Reporter,
+	logger *zap.Logger,
+) *AlertWorker {
+	w := &AlertWorker{
+		config:   cfg,
+		usecase:  alertUsecase,
+		reporter: reporter,
+	}
+
+	if !cfg.Alerts.IsEnabled() {
+		return w
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			runCtx, cancel := context.WithCancel(context.Background())
+			w.cancel = cancel
+
+			w.wg.Add(1)
+			go w.run(runCtx)
+
+			logger.Info("Alert checks started",
+				zap.Int("interval_minutes", cfg.Alerts.CheckInterval),
+				zap.Int("webhook_failures", cfg.Alerts.WebhookFailures),
+				zap.Int("nav_failures", cfg.Alerts.NAVFailures),
+				zap.Int("token_refresh_failures", cfg.Alerts.TokenRefreshFailures),
+				zap.Int("stuck_documents", cfg.Alerts.StuckDocuments),
+			)
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			if w.cancel != nil {
+				w.cancel()
+			}
+			w.wg.Wait()
+			return nil
+		},
+	})
+
+	return w
+}
+
+// run checks once at startup, which also takes the first counter samples, and then on every
+// interval until the context is cancelled
+func (w *AlertWorker) run(ctx context.Context) {
+	defer w.wg.Done()
+	defer w.reporter.Repanic(ctx)
+
+	ticker := time.NewTicker(time.Duration(w.config.Alerts.CheckInterval) * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		w.usecase.Evaluate(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/infrastructure/database"
+	"mekari-esign/internal/infrastructure/logger"
+)
+
+// dbPingTimeout bounds one health ping so a hanging server counts as down
+const dbPingTimeout = 3 * time.Second
+
+// DBMonitorWorker pings the database periodically. Failed pings switch the repositories to
+// their fallbacks; the first successful one after an outage flushes what they buffered.
+type DBMonitorWorker struct {
+	config   *config.Config
+	db       *database.Database
+	logger   *zap.Logger
+	reporter *logger.Reporter
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func NewDBMonitorWorker(
+	lc fx.Lifecycle,
+	cfg *config.Config,
+	db *database.Database,
+	reporter *logger.Reporter,
+	logger *zap.Logger,
+) *DBMonitorWorker {
+	w := &DBMonitorWorker{
+		config:   cfg,
+		db:       db,
+		logger:   logger,
+		reporter: reporter,
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			runCtx, cancel := context.WithCancel(context.Background())
+			w.cancel = cancel
+
+			w.wg.Add(1)
+			go w.run(runCtx)
+
+			logger.Info("Database monitor started",
+				zap.Int("interval_seconds", cfg.Database.HealthInterval),
+			)
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			if w.cancel != nil {
+				w.cancel()
+			}
+			w.wg.Wait()
+			return nil
+		},
+	})
+
+	return w
+}
+
+// run pings on every interval until the context is cancelled
+func (w *DBMonitorWorker) run(ctx context.Context) {
+	defer w.wg.Done()
+	defer w.reporter.Repanic(ctx)
+
+	ticker := time.NewTicker(time.Duration(w.config.Database.HealthInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pingCtx, cancel := context.WithTimeout(ctx, dbPingTimeout)
+			// Failures are logged by Ping on the transition only
+			_ = w.db.Ping(pingCtx)
+			cancel()
+		}
+	}
+}
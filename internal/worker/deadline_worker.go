@@ -0,0 +1,42 @@
+package worker
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/infrastructure/scheduler"
+	"mekari-esign/internal/usecase"
+)
+
+// DeadlineWorker checks the signing deadlines of the documents out for signing on the deadline
+// schedule
+type DeadlineWorker struct {
+	config  *config.Config
+	usecase usecase.DeadlineUsecase
+	logger  *zap.Logger
+}
+
+func NewDeadlineWorker(
+	cfg *config.Config,
+	sched *scheduler.Scheduler,
+	deadlineUsecase usecase.DeadlineUsecase,
+	logger *zap.Logger,
+) (*DeadlineWorker, error) {
+	w := &DeadlineWorker{
+		config:  cfg,
+		usecase: deadlineUsecase,
+		logger:  logger,
+	}
+
+	// Reminders and expiries are handled by one of the instances running side by side
+	return w, sched.Register(scheduler.Job{
+		Name:        "deadline",
+		Description: "Send signing reminders and handle expired documents",
+		Every:       time.Duration(cfg.Deadline.CheckInterval) * time.Minute,
+		Enabled:     cfg.Deadline.Enabled,
+		Cluster:     true,
+		Run:         deadlineUsecase.CheckDeadlines,
+	})
+}
@@ -0,0 +1,156 @@
+package worker
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/repository"
+	"mekari-esign/internal/infrastructure/scheduler"
+	"mekari-esign/internal/timeutil"
+)
+
+// retentionBatchPause gives autovacuum and other queries room between delete batches
+const retentionBatchPause = 200 * time.Millisecond
+
+// LogRetentionWorker purges old API logs on the log_retention schedule, optionally archiving
+// them first
+type LogRetentionWorker struct {
+	config  *config.Config
+	logRepo repository.APILogRepository
+	logger  *zap.Logger
+}
+
+func NewLogRetentionWorker(
+	cfg *config.Config,
+	sched *scheduler.Scheduler,
+	logRepo repository.APILogRepository,
+	logger *zap.Logger,
+) (*LogRetentionWorker, error) {
+	w := &LogRetentionWorker{
+		config:  cfg,
+		logRepo: logRepo,
+		logger:  logger,
+	}
+
+	// Run by one instance, so logs are archived once
+	return w, sched.Register(scheduler.Job{
+		Name:        "log_retention",
+		Description: fmt.Sprintf("Purge API logs older than %d days", cfg.Retention.Days),
+		Every:       time.Duration(cfg.Retention.Interval) * time.Hour,
+		Enabled:     cfg.Retention.Enabled,
+		Cluster:     true,
+		Run:         w.Purge,
+	})
+}
+
+// Purge deletes all API logs older than the retention period in batches
+func (w *LogRetentionWorker) Purge(ctx context.Context) error {
+	cutoff := timeutil.Now().AddDate(0, 0, -w.config.Retention.Days)
+	started := time.Now()
+
+	var archive *logArchive
+	if w.config.Retention.ArchiveDir != "" {
+		archive = &logArchive{dir: w.config.Retention.ArchiveDir, loc: w.config.App.Location()}
+		defer func() {
+			if err := archive.Close(); err != nil {
+				w.logger.Error("Failed to close API log archive", zap.Error(err))
+			}
+		}()
+	}
+
+	total := 0
+	for {
+		var archiveFn func(logs []entity.APILog) error
+		if archive != nil {
+			archiveFn = archive.Write
+		}
+
+		deleted, err := w.logRepo.PurgeBatch(ctx, cutoff, w.config.Retention.BatchSize, archiveFn)
+		if err != nil {
+			return err
+		}
+		total += deleted
+
+		if deleted < w.config.Retention.BatchSize {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retentionBatchPause):
+		}
+	}
+
+	if total > 0 {
+		fields := []zap.Field{
+			zap.Int("deleted", total),
+			zap.Time("cutoff", cutoff),
+			zap.Duration("duration", time.Since(started)),
+		}
+		if archive != nil && archive.path != "" {
+			fields = append(fields, zap.String("archive", archive.path))
+		}
+		w.logger.Info("Purged old API logs", fields...)
+	}
+
+	return nil
+}
+
+// logArchive writes purged logs as gzipped JSON lines, creating the file on first write
+type logArchive struct {
+	dir  string
+	loc  *time.Location // Zone of the file name (app.timezone)
+	path string
+	file *os.File
+	gz   *gzip.Writer
+}
+
+func (a *logArchive) Write(logs []entity.APILog) error {
+	if a.gz == nil {
+		if err := os.MkdirAll(a.dir, 0755); err != nil {
+			return fmt.Errorf("failed to create archive directory: %w", err)
+		}
+
+		a.path = filepath.Join(a.dir, "api_logs_"+time.Now().In(a.loc).Format("20060102_150405")+".jsonl.gz")
+		file, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to create archive file: %w", err)
+		}
+		a.file = file
+		a.gz = gzip.NewWriter(file)
+	}
+
+	encoder := json.NewEncoder(a.gz)
+	for i := range logs {
+		if err := encoder.Encode(&logs[i]); err != nil {
+			return err
+		}
+	}
+
+	// Flush so rows are on disk before the batch is deleted
+	if err := a.gz.Flush(); err != nil {
+		return err
+	}
+	return a.file.Sync()
+}
+
+func (a *logArchive) Close() error {
+	if a.gz == nil {
+		return nil
+	}
+	if err := a.gz.Close(); err != nil {
+		a.file.Close()
+		return err
+	}
+	return a.file.Close()
+}
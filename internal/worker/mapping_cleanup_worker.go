@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/infrastructure/scheduler"
+	"mekari-esign/internal/usecase"
+)
+
+// MappingCleanupWorker archives and deletes document mapping keys close to expiry on the
+// mapping_cleanup schedule
+type MappingCleanupWorker struct {
+	config       *config.Config
+	esignUsecase usecase.EsignUsecase
+	logger       *zap.Logger
+}
+
+func NewMappingCleanupWorker(
+	cfg *config.Config,
+	sched *scheduler.Scheduler,
+	esignUsecase usecase.EsignUsecase,
+	logger *zap.Logger,
+) (*MappingCleanupWorker, error) {
+	w := &MappingCleanupWorker{
+		config:       cfg,
+		esignUsecase: esignUsecase,
+		logger:       logger,
+	}
+
+	description := "Delete document mappings close to expiry"
+	if cfg.Mapping.Archive {
+		description = "Archive and delete document mappings close to expiry"
+	}
+	return w, sched.Register(scheduler.Job{
+		Name:        "mapping_cleanup",
+		Description: description,
+		Every:       time.Duration(cfg.Mapping.CleanupInterval) * time.Hour,
+		Enabled:     true,
+		Cluster:     true,
+		Run:         w.cleanup,
+	})
+}
+
+func (w *MappingCleanupWorker) cleanup(ctx context.Context) error {
+	started := time.Now()
+	removed, err := w.esignUsecase.CleanupDocumentMappings(ctx)
+	if removed > 0 {
+		w.logger.Info("Removed expiring document mappings",
+			zap.Int("removed", removed),
+			zap.Duration("duration", time.Since(started)),
+		)
+	}
+	return err
+}
@@ -0,0 +1,16 @@
+package worker
+
+import "go.uber.org/fx"
+
+var Module = fx.Module("worker",
+	fx.Invoke(NewWebhookWorker),
+	fx.Invoke(NewLogRetentionWorker),
+	fx.Invoke(NewQuotaMonitorWorker),
+	fx.Invoke(NewAlertWorker),
+	fx.Invoke(NewDBMonitorWorker),
+	fx.Invoke(NewMappingCleanupWorker),
+	fx.Invoke(NewOAuthPurgeWorker),
+	fx.Invoke(NewPoolStatsWorker),
+	fx.Invoke(NewUpdateWorker),
+	fx.Invoke(NewDeadlineWorker),
+)
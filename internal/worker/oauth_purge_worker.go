@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/infrastructure/scheduler"
+	"mekari-esign/internal/usecase"
+)
+
+// OAuthPurgeWorker removes OAuth token records deleted more than oauth.deleted_retention_days
+// ago on the oauth_purge schedule
+type OAuthPurgeWorker struct {
+	config       *config.Config
+	oauthUsecase usecase.OAuthUsecase
+	logger       *zap.Logger
+}
+
+func NewOAuthPurgeWorker(
+	cfg *config.Config,
+	sched *scheduler.Scheduler,
+	oauthUsecase usecase.OAuthUsecase,
+	logger *zap.Logger,
+) (*OAuthPurgeWorker, error) {
+	w := &OAuthPurgeWorker{
+		config:       cfg,
+		oauthUsecase: oauthUsecase,
+		logger:       logger,
+	}
+
+	return w, sched.Register(scheduler.Job{
+		Name:        "oauth_purge",
+		Description: fmt.Sprintf("Remove OAuth tokens deleted more than %d days ago", cfg.OAuth.DeletedRetentionDays),
+		Every:       time.Duration(cfg.OAuth.PurgeInterval) * time.Hour,
+		Enabled:     true,
+		Cluster:     true,
+		Run:         w.purge,
+	})
+}
+
+func (w *OAuthPurgeWorker) purge(ctx context.Context) error {
+	purged, err := w.oauthUsecase.PurgeDeletedTokens(ctx)
+	if purged > 0 {
+		w.logger.Info("Purged deleted OAuth tokens", zap.Int("purged", purged))
+	}
+	return err
+}
@@ -0,0 +1,144 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/infrastructure/database"
+	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/infrastructure/redis"
+)
+
+// PoolStatsWorker logs the database and Redis connection pool statistics every
+// metrics.pool_log_interval seconds, so latency spikes can be matched with pool exhaustion in
+// the logs. Intervals in which callers waited for a connection are logged at info level, the
+// others at debug level.
+type PoolStatsWorker struct {
+	config   *config.Config
+	db       *database.Database
+	redis    *redis.RedisClient
+	logger   *zap.Logger
+	reporter *logger.Reporter
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+
+	// Counters of the previous sample; the log lines show the change since then
+	lastDB      sql.DBStats
+	lastReplica sql.DBStats
+	lastRedis   goredis.PoolStats
+}
+
+func NewPoolStatsWorker(
+	lc fx.Lifecycle,
+	cfg *config.Config,
+	db *database.Database,
+	redisClient *redis.RedisClient,
+	reporter *logger.Reporter,
+	logger *zap.Logger,
+) *PoolStatsWorker {
+	w := &PoolStatsWorker{
+		config:   cfg,
+		db:       db,
+		redis:    redisClient,
+		logger:   logger.Named("pool"),
+		reporter: reporter,
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			runCtx, cancel := context.WithCancel(context.Background())
+			w.cancel = cancel
+
+			w.wg.Add(1)
+			go w.run(runCtx)
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			if w.cancel != nil {
+				w.cancel()
+			}
+			w.wg.Wait()
+			return nil
+		},
+	})
+
+	return w
+}
+
+// run samples the pools on every interval until the context is cancelled
+func (w *PoolStatsWorker) run(ctx context.Context) {
+	defer w.wg.Done()
+	defer w.reporter.Repanic(ctx)
+
+	w.lastDB = w.db.DB.Stats()
+	if replica := w.db.Replica(); replica != nil {
+		w.lastReplica = replica.Stats()
+	}
+	w.lastRedis = *w.redis.Client.PoolStats()
+
+	ticker := time.NewTicker(time.Duration(w.config.Metrics.PoolLogInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.sample()
+		}
+	}
+}
+
+func (w *PoolStatsWorker) sample() {
+	busy := false
+
+	stats := w.db.DB.Stats()
+	busy = w.logDB("Database pool", stats, w.lastDB) || busy
+	w.lastDB = stats
+
+	if replica := w.db.Replica(); replica != nil {
+		stats := replica.Stats()
+		busy = w.logDB("Database replica pool", stats, w.lastReplica) || busy
+		w.lastReplica = stats
+	}
+
+	redisStats := *w.redis.Client.PoolStats()
+	timeouts := redisStats.Timeouts - w.lastRedis.Timeouts
+	log := w.logger.Debug
+	if timeouts > 0 || busy {
+		log = w.logger.Info
+	}
+	log("Redis pool",
+		zap.Uint32("total", redisStats.TotalConns),
+		zap.Uint32("idle", redisStats.IdleConns),
+		zap.Uint32("hits", redisStats.Hits-w.lastRedis.Hits),
+		zap.Uint32("misses", redisStats.Misses-w.lastRedis.Misses),
+		zap.Uint32("timeouts", timeouts),
+	)
+	w.lastRedis = redisStats
+}
+
+// logDB logs one database pool and reports whether callers waited for a connection
+func (w *PoolStatsWorker) logDB(msg string, stats, last sql.DBStats) bool {
+	waits := stats.WaitCount - last.WaitCount
+	log := w.logger.Debug
+	if waits > 0 {
+		log = w.logger.Info
+	}
+	log(msg,
+		zap.Int("max_open", stats.MaxOpenConnections),
+		zap.Int("open", stats.OpenConnections),
+		zap.Int("in_use", stats.InUse),
+		zap.Int("idle", stats.Idle),
+		zap.Int64("waits", waits),
+		zap.Duration("wait_duration", stats.WaitDuration-last.WaitDuration),
+	)
+	return waits > 0
+}
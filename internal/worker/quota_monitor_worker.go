@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/infrastructure/scheduler"
+	"mekari-esign/internal/usecase"
+)
+
+// QuotaMonitorWorker checks the e-meterai balance of all accounts on the quota_monitor schedule,
+// so a low balance is alerted even when nobody asks for it
+type QuotaMonitorWorker struct {
+	config  *config.Config
+	usecase usecase.QuotaUsecase
+	logger  *zap.Logger
+}
+
+func NewQuotaMonitorWorker(
+	cfg *config.Config,
+	sched *scheduler.Scheduler,
+	quotaUsecase usecase.QuotaUsecase,
+	logger *zap.Logger,
+) (*QuotaMonitorWorker, error) {
+	w := &QuotaMonitorWorker{
+		config:  cfg,
+		usecase: quotaUsecase,
+		logger:  logger,
+	}
+
+	// quota.check_interval 0 only checks when requested; an hour applies when the job is enabled
+	// in scheduler.jobs without a schedule
+	interval := time.Duration(cfg.Quota.CheckInterval) * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	// Low balance notifications are sent by one instance
+	return w, sched.Register(scheduler.Job{
+		Name:        "quota_monitor",
+		Description: "Check the e-meterai balance of all accounts and alert low balances",
+		Every:       interval,
+		Enabled:     cfg.Quota.AlertThreshold > 0 && cfg.Quota.CheckInterval > 0,
+		Cluster:     true,
+		Run:         w.check,
+	})
+}
+
+// check fetches all balances; GetQuota sends the low balance notifications
+func (w *QuotaMonitorWorker) check(ctx context.Context) error {
+	quotas, err := w.usecase.GetAllQuotas(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, quota := range quotas {
+		if quota.Error != "" {
+			w.logger.Warn("Failed to check e-meterai quota", zap.String("email", quota.Email), zap.String("error", quota.Error))
+		}
+	}
+	return nil
+}
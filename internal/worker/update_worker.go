@@ -0,0 +1,166 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/infrastructure/scheduler"
+	"mekari-esign/internal/usecase"
+	"mekari-esign/updater"
+)
+
+// updateDownloadTimeout bounds a check with the download of the release
+const updateDownloadTimeout = 10 * time.Minute
+
+// UpdateWorker records the outcome of the last update in the update history when the service
+// starts. With updater.background set its update_check job checks for releases every
+// updater.check_interval hours and downloads and verifies a new one ahead of time, and its
+// update_install job installs it in the maintenance window. The install runs in a separate
+// -install-pending process, as it stops this service.
+type UpdateWorker struct {
+	config   *config.Config
+	usecase  usecase.UpdateUsecase
+	logger   *zap.Logger
+	reporter *logger.Reporter
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+
+	mu         sync.Mutex
+	pending    *updater.Pending
+	installing bool
+}
+
+func NewUpdateWorker(
+	lc fx.Lifecycle,
+	cfg *config.Config,
+	sched *scheduler.Scheduler,
+	updateUsecase usecase.UpdateUsecase,
+	reporter *logger.Reporter,
+	logger *zap.Logger,
+) (*UpdateWorker, error) {
+	w := &UpdateWorker{
+		config:   cfg,
+		usecase:  updateUsecase,
+		logger:   logger.Named("updater"),
+		reporter: reporter,
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			runCtx, cancel := context.WithCancel(context.Background())
+			w.cancel = cancel
+
+			w.wg.Add(1)
+			go w.recordLastUpdate(runCtx)
+
+			if cfg.Updater.Background {
+				w.logger.Info("Background updater started",
+					zap.String("channel", cfg.Updater.Channel),
+					zap.Int("interval_hours", cfg.Updater.CheckInterval),
+					zap.String("maintenance_window", cfg.Updater.MaintenanceWindow),
+				)
+			}
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			if w.cancel != nil {
+				w.cancel()
+			}
+			w.wg.Wait()
+			return nil
+		},
+	})
+
+	// Every instance updates its own installation
+	if err := sched.Register(scheduler.Job{
+		Name:        "update_check",
+		Description: "Check for a new release and download it",
+		Every:       time.Duration(cfg.Updater.CheckInterval) * time.Hour,
+		Enabled:     cfg.Updater.Background,
+		Run:         w.check,
+	}); err != nil {
+		return nil, err
+	}
+	return w, sched.Register(scheduler.Job{
+		Name:        "update_install",
+		Description: "Install the downloaded release in the maintenance window",
+		Every:       time.Minute,
+		Enabled:     cfg.Updater.Background,
+		Run:         w.install,
+	})
+}
+
+func (w *UpdateWorker) recordLastUpdate(ctx context.Context) {
+	defer w.wg.Done()
+	defer w.reporter.Repanic(ctx)
+
+	if err := w.usecase.RecordLastUpdate(ctx); err != nil && ctx.Err() == nil {
+		w.logger.Warn("Failed to record the last update", zap.Error(err))
+	}
+}
+
+// install starts the install of the staged update when the maintenance window allows it
+func (w *UpdateWorker) install(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.pending == nil || w.installing || !w.inWindow(time.Now()) {
+		return nil
+	}
+
+	if err := updater.StartInstallPending(); err != nil {
+		version := w.pending.Version
+		w.pending = nil // Retried after the next check
+		return fmt.Errorf("failed to start the update install of %s: %w", version, err)
+	}
+	w.installing = true
+	w.logger.Warn("Installing update, the service restarts",
+		zap.String("from", updater.Version),
+		zap.String("to", w.pending.Version),
+	)
+	return nil
+}
+
+// check stages the newest release for install, or clears the staged one when there is nothing
+// to install
+func (w *UpdateWorker) check(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, updateDownloadTimeout)
+	defer cancel()
+
+	pending, err := updater.NewUpdater(w.config.Updater.GitHub()).Stage(ctx)
+
+	w.mu.Lock()
+	w.pending = pending
+	w.mu.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("update check failed: %w", err)
+	}
+	if pending == nil {
+		w.logger.Debug("No update available", zap.String("version", updater.Version))
+		return nil
+	}
+
+	if window, ok := w.config.Updater.Window(); ok {
+		w.logger.Info("Update staged",
+			zap.String("version", pending.Version),
+			zap.Time("install_after", window.Next(time.Now().In(w.config.App.Location()))),
+		)
+	} else {
+		w.logger.Info("Update staged", zap.String("version", pending.Version))
+	}
+	return nil
+}
+
+// inWindow reports whether updates may be installed at t
+func (w *UpdateWorker) inWindow(t time.Time) bool {
+	window, ok := w.config.Updater.Window()
+	return !ok || window.Contains(t.In(w.config.App.Location()))
+}
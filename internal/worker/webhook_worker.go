@@ -0,0 +1,194 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"mekari-esign/internal/config"
+	"mekari-esign/internal/domain/entity"
+	"mekari-esign/internal/infrastructure/logger"
+	"mekari-esign/internal/infrastructure/metrics"
+	"mekari-esign/internal/infrastructure/repository"
+	"mekari-esign/internal/infrastructure/shutdown"
+	"mekari-esign/internal/timeutil"
+	"mekari-esign/internal/usecase"
+)
+
+// maxRetryBackoff caps the wait between attempts of a failed webhook event
+const maxRetryBackoff = time.Hour
+
+// WebhookWorker processes persisted webhook events in the background
+type WebhookWorker struct {
+	config      *config.Config
+	eventRepo   repository.WebhookEventRepository
+	usecase     usecase.WebhookUsecase
+	coordinator *shutdown.Coordinator
+	metrics     *metrics.Metrics
+	reporter    *logger.Reporter
+	logger      *zap.Logger
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+}
+
+func NewWebhookWorker(
+	lc fx.Lifecycle,
+	cfg *config.Config,
+	eventRepo repository.WebhookEventRepository,
+	webhookUsecase usecase.WebhookUsecase,
+	coordinator *shutdown.Coordinator,
+	meter *metrics.Metrics,
+	reporter *logger.Reporter,
+	logger *zap.Logger,
+) *WebhookWorker {
+	logger = logger.Named("webhook")
+	w := &WebhookWorker{
+		config:      cfg,
+		eventRepo:   eventRepo,
+		usecase:     webhookUsecase,
+		coordinator: coordinator,
+		metrics:     meter,
+		reporter:    reporter,
+		logger:      logger,
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			// Events this instance left in processing were interrupted by its previous shutdown or
+			// crash; those of other instances are claimed again after webhook.claim_timeout
+			reset, err := eventRepo.ResetProcessing(ctx, cfg.App.InstanceID)
+			if err != nil {
+				logger.Warn("Failed to reset interrupted webhook events", zap.Error(err))
+			} else if reset > 0 {
+				logger.Info("Re-queued interrupted webhook events", zap.Int64("count", reset))
+			}
+
+			runCtx, cancel := context.WithCancel(context.Background())
+			w.cancel = cancel
+
+			w.wg.Add(1)
+			go w.run(runCtx)
+
+			logger.Info("Webhook worker started",
+				zap.Int("poll_interval_seconds", cfg.Webhook.PollInterval),
+				zap.Int("max_attempts", cfg.Webhook.MaxAttempts),
+			)
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			// Stop claiming new events; the event in progress runs on until the drain deadline
+			logger.Info("Stopping webhook worker")
+			if w.cancel != nil {
+				w.cancel()
+			}
+			w.wg.Wait()
+			return nil
+		},
+	})
+
+	return w
+}
+
+// run polls the queue until the context is cancelled
+func (w *WebhookWorker) run(ctx context.Context) {
+	defer w.wg.Done()
+	defer w.reporter.Repanic(ctx)
+
+	interval := time.Duration(w.config.Webhook.PollInterval) * time.Second
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Drain everything available before waiting again
+		for w.processNext(ctx) {
+			if ctx.Err() != nil {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processNext claims and processes a single event. Returns true if an event was handled.
+// ctx only governs claiming; processing is not interrupted when the worker stops.
+func (w *WebhookWorker) processNext(ctx context.Context) bool {
+	claimTimeout := time.Duration(w.config.Webhook.ClaimTimeout) * time.Second
+	event, err := w.eventRepo.ClaimNext(ctx, w.config.App.InstanceID, w.config.Webhook.MaxAttempts, claimTimeout)
+	if err != nil {
+		if ctx.Err() == nil {
+			w.logger.Error("Failed to claim webhook event", zap.Error(err))
+		}
+		return false
+	}
+	if event == nil {
+		return false
+	}
+
+	// Continue the trace of the callback that delivered the event
+	workCtx := logger.WithRequestID(w.coordinator.Context(), event.RequestID)
+	workCtx = logger.WithDocument(workCtx, event.DocumentID, "")
+	log := logger.FromContext(workCtx, w.logger)
+
+	log.Info("Processing webhook event",
+		zap.Int64("event_id", event.ID),
+		zap.Int("attempt", event.Attempts),
+	)
+
+	// A panic still crashes the service (and the event is retried after restart), but is reported first
+	defer w.reporter.Repanic(workCtx)
+
+	start := time.Now()
+	err = w.process(workCtx, event)
+	w.metrics.WebhookDuration.Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		w.metrics.WebhookProcessed.WithLabelValues(metrics.WebhookFailed).Inc()
+		retryIn := w.retryBackoff(event.Attempts)
+		log.Error("Failed to process webhook event",
+			zap.Int64("event_id", event.ID),
+			zap.Int("attempt", event.Attempts),
+			zap.Duration("retry_in", retryIn),
+			zap.Error(err),
+		)
+		if markErr := w.eventRepo.MarkFailed(context.Background(), event.ID, err.Error(), timeutil.Now().Add(retryIn)); markErr != nil {
+			log.Error("Failed to mark webhook event failed", zap.Error(markErr))
+		}
+		return true
+	}
+
+	w.metrics.WebhookProcessed.WithLabelValues(metrics.WebhookProcessed).Inc()
+	if err := w.eventRepo.MarkProcessed(context.Background(), event.ID); err != nil {
+		log.Error("Failed to mark webhook event processed", zap.Error(err))
+	}
+
+	return true
+}
+
+// retryBackoff returns how long a failed event waits before its next attempt: webhook.retry_backoff
+// doubled per attempt, up to maxRetryBackoff, so a Mekari or NAV outage does not use up all attempts
+func (w *WebhookWorker) retryBackoff(attempts int) time.Duration {
+	backoff := time.Duration(w.config.Webhook.RetryBackoff) * time.Second
+	for i := 1; i < attempts && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxRetryBackoff)
+}
+
+func (w *WebhookWorker) process(ctx context.Context, event *entity.WebhookEvent) error {
+	var payload entity.WebhookPayload
+	if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+		return fmt.Errorf("failed to parse stored payload: %w", err)
+	}
+
+	return w.usecase.ProcessWebhook(ctx, &payload)
+}
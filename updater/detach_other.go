@@ -0,0 +1,11 @@
+//go:build !windows
+// +build !windows
+
+package updater
+
+import "syscall"
+
+// detached starts a process in its own session, so it is not stopped with the service
+func detached() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,18 @@
+//go:build windows
+// +build windows
+
+package updater
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// detached starts a process without a console that is not stopped with the service
+func detached() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{
+		CreationFlags: windows.DETACHED_PROCESS | windows.CREATE_NEW_PROCESS_GROUP,
+		HideWindow:    true,
+	}
+}
@@ -0,0 +1,342 @@
+package updater
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Update results recorded in update-status.json
+const (
+	ResultUpdated    = "updated"     // The new version is running
+	ResultRolledBack = "rolled_back" // The new version did not start; the previous one runs again
+	ResultFailed     = "failed"      // The update or the rollback failed; see error
+)
+
+const (
+	stopTimeout  = 2 * time.Minute
+	startTimeout = time.Minute
+	// settleTime is how long the new version must keep running to count as started
+	settleTime = 10 * time.Second
+	// statusFile is written next to the executable with the outcome of the last update
+	statusFile = "update-status.json"
+	// backupDir next to the executable holds the executables and configs replaced by updates
+	backupDir = ".backup"
+	// previousFile in backupDir records what -rollback restores
+	previousFile = "previous.json"
+)
+
+// ErrNoPrevious is returned by Rollback when no update has been installed yet
+var ErrNoPrevious = errors.New("no previous version to roll back to")
+
+// ServiceManager stops and starts the installed service around the executable swap
+type ServiceManager interface {
+	Stop(timeout time.Duration) error
+	Start(timeout time.Duration) error
+	Running() (bool, error)
+}
+
+// UpdateStatus is the outcome of the last update, written to update-status.json
+type UpdateStatus struct {
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	Rollback   bool      `json:"rollback,omitempty"` // Installed with -rollback
+	Result     string    `json:"result"`
+	Error      string    `json:"error,omitempty"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// Previous is the version replaced by the last update or rollback, kept in .backup
+type Previous struct {
+	Version    string    `json:"version"`
+	Executable string    `json:"executable"`       // Copy of the executable in .backup
+	Config     string    `json:"config,omitempty"` // Copy of its config file in .backup
+	ConfigFile string    `json:"config_file,omitempty"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+// target is what a swap installs
+type target struct {
+	version    string
+	executable string
+	config     string // Config file copied over the current one, empty to keep it
+}
+
+// LoadPrevious returns the version -rollback restores, or ErrNoPrevious
+func LoadPrevious() (*Previous, error) {
+	exePath, err := executablePath()
+	if err != nil {
+		return nil, err
+	}
+	return loadPrevious(filepath.Dir(exePath))
+}
+
+func loadPrevious(dir string) (*Previous, error) {
+	data, err := os.ReadFile(filepath.Join(dir, backupDir, previousFile))
+	if os.IsNotExist(err) {
+		return nil, ErrNoPrevious
+	}
+	if err != nil {
+		return nil, err
+	}
+	var previous Previous
+	if err := json.Unmarshal(data, &previous); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", previousFile, err)
+	}
+	if _, err := os.Stat(previous.Executable); err != nil {
+		return nil, fmt.Errorf("previous executable is missing: %w", err)
+	}
+	return &previous, nil
+}
+
+// Rollback reinstalls the version replaced by the last update, with the config file it ran
+// with, and restarts the service. The version rolled back from becomes the previous one, so a
+// second rollback returns to it.
+func Rollback(configFile string, manager ServiceManager) error {
+	exePath, err := executablePath()
+	if err != nil {
+		return err
+	}
+	previous, err := loadPrevious(filepath.Dir(exePath))
+	if err != nil {
+		return err
+	}
+	if previous.Version == Version {
+		return fmt.Errorf("version %s is already running", Version)
+	}
+
+	fmt.Printf("Rolling back from %s to %s...\n", Version, previous.Version)
+	t := target{version: previous.Version, executable: previous.Executable}
+	// The saved config belongs to the same file unless the service was since pointed elsewhere
+	if previous.Config != "" && previous.ConfigFile == configFile {
+		t.config = previous.Config
+	}
+	if err := install(exePath, configFile, t, manager, true); err != nil {
+		return err
+	}
+
+	fmt.Printf("Rolled back to %s\n", previous.Version)
+	return nil
+}
+
+func executablePath() (string, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(exePath); err == nil {
+		exePath = resolved
+	}
+	return exePath, nil
+}
+
+// install replaces exePath with t while the service is stopped and starts it again. The current
+// executable and config are kept in .backup and put back when the new version does not keep
+// running.
+func install(exePath, configFile string, t target, manager ServiceManager, rollback bool) error {
+	dir := filepath.Dir(exePath)
+	status := UpdateStatus{From: Version, To: t.version, Rollback: rollback}
+
+	err := swap(exePath, configFile, t, manager)
+	switch e := err.(type) {
+	case nil:
+		status.Result = ResultUpdated
+	case *rolledBackError:
+		status.Result = ResultRolledBack
+		status.Error = e.cause.Error()
+	default:
+		status.Result = ResultFailed
+		status.Error = err.Error()
+	}
+	status.FinishedAt = time.Now().UTC()
+
+	if werr := writeJSON(filepath.Join(dir, statusFile), status); werr != nil {
+		fmt.Printf("Failed to write %s: %v\n", statusFile, werr)
+	}
+	return err
+}
+
+// rolledBackError is returned when the new version failed and the previous one was restored
+type rolledBackError struct {
+	cause error
+}
+
+func (e *rolledBackError) Error() string {
+	return fmt.Sprintf("update rolled back: %v", e.cause)
+}
+
+func (e *rolledBackError) Unwrap() error {
+	return e.cause
+}
+
+func swap(exePath, configFile string, t target, manager ServiceManager) error {
+	dir := filepath.Dir(exePath)
+	name := filepath.Base(exePath)
+
+	// Stage the new executable on the same volume so the swap is a rename
+	staged := filepath.Join(dir, "."+name+".new")
+	if err := copyFile(t.executable, staged); err != nil {
+		return fmt.Errorf("failed to stage update: %w", err)
+	}
+	defer os.Remove(staged)
+
+	backups := filepath.Join(dir, backupDir)
+	if err := os.MkdirAll(backups, 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	previous := Previous{
+		Version:    Version,
+		Executable: filepath.Join(backups, fmt.Sprintf("%s-%s%s.bak", trimExt(name), Version, filepath.Ext(name))),
+		ConfigFile: configFile,
+	}
+	os.Remove(previous.Executable)
+	if configFile != "" {
+		previous.Config = filepath.Join(backups, fmt.Sprintf("%s-%s%s.bak",
+			trimExt(filepath.Base(configFile)), Version, filepath.Ext(configFile)))
+		if err := copyFile(configFile, previous.Config); err != nil {
+			return fmt.Errorf("failed to back up config: %w", err)
+		}
+	}
+
+	fmt.Println("Stopping service...")
+	if err := manager.Stop(stopTimeout); err != nil {
+		return fmt.Errorf("failed to stop service: %w", err)
+	}
+
+	// Windows allows renaming a running executable but the service may still hold it briefly
+	if err := renameRetry(exePath, previous.Executable); err != nil {
+		startQuietly(manager)
+		return fmt.Errorf("failed to back up executable: %w", err)
+	}
+	if err := renameRetry(staged, exePath); err != nil {
+		if rerr := renameRetry(previous.Executable, exePath); rerr != nil {
+			return fmt.Errorf("failed to install executable: %v; restoring %s failed: %w", err, previous.Executable, rerr)
+		}
+		startQuietly(manager)
+		return fmt.Errorf("failed to install executable: %w", err)
+	}
+	if t.config != "" {
+		if err := copyFile(t.config, configFile); err != nil {
+			err = fmt.Errorf("failed to restore config: %w", err)
+			if rerr := restore(exePath, previous, manager); rerr != nil {
+				return fmt.Errorf("%v; rollback failed: %w", err, rerr)
+			}
+			return &rolledBackError{cause: err}
+		}
+	}
+
+	fmt.Printf("Starting service with %s...\n", t.version)
+	err := startAndSettle(manager)
+	if err != nil {
+		fmt.Printf("New version failed to start: %v\nRolling back to %s...\n", err, Version)
+		if rerr := restore(exePath, previous, manager); rerr != nil {
+			return fmt.Errorf("new version failed to start: %v; rollback failed: %w", err, rerr)
+		}
+		return &rolledBackError{cause: err}
+	}
+
+	previous.ReplacedAt = time.Now().UTC()
+	if err := writeJSON(filepath.Join(backups, previousFile), previous); err != nil {
+		fmt.Printf("Failed to record the previous version, -rollback will not find it: %v\n", err)
+	}
+	return nil
+}
+
+// startAndSettle starts the service and checks that it is still running after settleTime
+func startAndSettle(manager ServiceManager) error {
+	if err := manager.Start(startTimeout); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(settleTime)
+	for time.Now().Before(deadline) {
+		time.Sleep(time.Second)
+		running, err := manager.Running()
+		if err != nil {
+			return err
+		}
+		if !running {
+			return fmt.Errorf("service stopped within %s of starting", settleTime)
+		}
+	}
+	return nil
+}
+
+// restore puts the executable and config replaced by a failed swap back and starts them
+func restore(exePath string, previous Previous, manager ServiceManager) error {
+	if err := manager.Stop(stopTimeout); err != nil {
+		return fmt.Errorf("failed to stop service: %w", err)
+	}
+	failed := exePath + ".failed"
+	os.Remove(failed)
+	if err := renameRetry(exePath, failed); err != nil {
+		return err
+	}
+	if err := renameRetry(previous.Executable, exePath); err != nil {
+		return err
+	}
+	os.Remove(failed)
+	if previous.Config != "" {
+		if err := copyFile(previous.Config, previous.ConfigFile); err != nil {
+			return fmt.Errorf("failed to restore config: %w", err)
+		}
+	}
+	return manager.Start(startTimeout)
+}
+
+// startQuietly restarts the unchanged service after an aborted swap
+func startQuietly(manager ServiceManager) {
+	if err := manager.Start(startTimeout); err != nil {
+		fmt.Printf("Failed to restart service: %v\n", err)
+	}
+}
+
+// renameRetry renames, retrying for a few seconds while the file is still in use
+func renameRetry(from, to string) error {
+	var err error
+	for i := 0; i < 10; i++ {
+		if err = os.Rename(from, to); err == nil {
+			return nil
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return err
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm()|0200)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func trimExt(name string) string {
+	return name[:len(name)-len(filepath.Ext(name))]
+}
+
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,263 @@
+package updater
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// stagingDir next to the executable holds an update downloaded by the service, waiting for the
+// maintenance window
+const (
+	stagingDir  = ".updates"
+	pendingFile = "pending.json"
+)
+
+// Pending is a downloaded and verified update waiting to be installed
+type Pending struct {
+	Version     string    `json:"version"`
+	Prerelease  bool      `json:"prerelease"`
+	PublishedAt time.Time `json:"published_at"`
+	Notes       string    `json:"notes,omitempty"`
+	Package     string    `json:"package"`
+	SHA256      string    `json:"sha256"`
+	StagedAt    time.Time `json:"staged_at"`
+}
+
+// LoadPending returns the staged update, or nil when there is none
+func LoadPending() (*Pending, error) {
+	dir, err := installDir()
+	if err != nil {
+		return nil, err
+	}
+	var pending Pending
+	found, err := readJSON(filepath.Join(dir, stagingDir, pendingFile), &pending)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &pending, nil
+}
+
+// LoadStatus returns the outcome of the last update or rollback, or nil when there was none
+func LoadStatus() (*UpdateStatus, error) {
+	dir, err := installDir()
+	if err != nil {
+		return nil, err
+	}
+	var status UpdateStatus
+	found, err := readJSON(filepath.Join(dir, statusFile), &status)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Stage downloads and verifies the release CheckForUpdate offers into .updates, to be installed
+// later by InstallPending. An update already staged is kept, and one no longer offered, e.g.
+// after a pin, is discarded. A version whose last install failed or was rolled back is not
+// staged again; a newer release or pinning it retries. It returns nil when there is nothing to
+// install.
+func (u *Updater) Stage(ctx context.Context) (*Pending, error) {
+	dir, err := installDir()
+	if err != nil {
+		return nil, err
+	}
+	staging := filepath.Join(dir, stagingDir)
+
+	release, err := u.checkAndRecord(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if release == nil {
+		return nil, os.RemoveAll(staging)
+	}
+	if u.config.PinVersion == "" {
+		if last, _ := LoadStatus(); last != nil && rejected(last, release.TagName) {
+			return nil, os.RemoveAll(staging)
+		}
+	}
+
+	if pending, _ := LoadPending(); pending != nil && pending.Version == release.TagName {
+		if _, err := os.Stat(pending.Package); err == nil {
+			return pending, nil
+		}
+	}
+
+	asset := u.GetDownloadAsset(release)
+	if asset == nil {
+		return nil, fmt.Errorf("no compatible download found for %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+	download, err := u.DownloadUpdate(ctx, asset, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(download)
+	if err := u.VerifyRelease(ctx, release, asset, download); err != nil {
+		return nil, fmt.Errorf("update rejected: %w", err)
+	}
+
+	// Replace an older staged update
+	if err := os.RemoveAll(staging); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(staging, 0755); err != nil {
+		return nil, err
+	}
+	pending := &Pending{
+		Version:     release.TagName,
+		Prerelease:  release.Prerelease,
+		PublishedAt: release.PublishedAt,
+		Notes:       release.Body,
+		Package:     filepath.Join(staging, asset.Name),
+		StagedAt:    time.Now().UTC(),
+	}
+	if err := copyFile(download, pending.Package); err != nil {
+		return nil, err
+	}
+	if pending.SHA256, err = fileSHA256(pending.Package); err != nil {
+		return nil, err
+	}
+	if err := writeJSON(filepath.Join(staging, pendingFile), pending); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// rejected reports whether the last update shows version should not be installed again: its
+// install failed, or it was rolled back from
+func rejected(last *UpdateStatus, version string) bool {
+	if last.Rollback {
+		return last.Result == ResultUpdated && last.From == version
+	}
+	return last.To == version && last.Result != ResultUpdated
+}
+
+// InstallPending installs the update staged by Stage like -update does, and discards it
+// whatever the outcome; a failed version is not staged again.
+func InstallPending(config GitHubConfig, manager ServiceManager) error {
+	pending, err := LoadPending()
+	if err != nil {
+		return err
+	}
+	if pending == nil {
+		return fmt.Errorf("no update is staged")
+	}
+	dir, err := installDir()
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(filepath.Join(dir, stagingDir))
+
+	updater := NewUpdater(config)
+	// The package was verified when staged; this catches a file changed since
+	if err := updater.VerifyChecksum(pending.Package, pending.SHA256); err != nil {
+		return fmt.Errorf("staged update rejected: %w", err)
+	}
+
+	fmt.Printf("Installing staged update %s...\n", pending.Version)
+	if err := updater.ApplyUpdate(pending.Package, pending.Version, manager); err != nil {
+		return err
+	}
+	fmt.Printf("Updated to %s\n", pending.Version)
+	return nil
+}
+
+// StartInstallPending launches "<executable> -install-pending" in its own process, which
+// outlives the service it stops. The outcome is written to update-status.json.
+func StartInstallPending() error {
+	return startDetached("-install-pending")
+}
+
+func installDir() (string, error) {
+	exePath, err := executablePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Dir(exePath), nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// readJSON decodes the file at path into v and reports whether it exists
+func readJSON(path string, v interface{}) (bool, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("invalid %s: %w", filepath.Base(path), err)
+	}
+	return true, nil
+}
+
+// checkFile next to the executable records the last update check
+const checkFile = "update-check.json"
+
+// CheckState is the outcome of the last update check, by -update or the service
+type CheckState struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Latest    string    `json:"latest,omitempty"` // Version to install, or the running one when up to date
+	Available bool      `json:"available"`        // Latest is newer, or pinned
+	Error     string    `json:"error,omitempty"`
+}
+
+// LoadCheck returns the outcome of the last update check, or nil when there was none
+func LoadCheck() (*CheckState, error) {
+	dir, err := installDir()
+	if err != nil {
+		return nil, err
+	}
+	var state CheckState
+	found, err := readJSON(filepath.Join(dir, checkFile), &state)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// checkAndRecord is CheckForUpdate, recording its outcome for LoadCheck
+func (u *Updater) checkAndRecord(ctx context.Context) (*GitHubRelease, error) {
+	release, err := u.CheckForUpdate(ctx)
+
+	state := CheckState{CheckedAt: time.Now().UTC()}
+	switch {
+	case err != nil:
+		state.Error = err.Error()
+	case release != nil:
+		state.Latest, state.Available = release.TagName, true
+	default:
+		state.Latest = Version
+	}
+	if dir, derr := installDir(); derr == nil {
+		_ = writeJSON(filepath.Join(dir, checkFile), state)
+	}
+	return release, err
+}
+
+// StartUpdate launches "<executable> -update" in its own process, which outlives the service
+// it stops. The outcome is written to update-status.json.
+func StartUpdate() error {
+	return startDetached("-update")
+}
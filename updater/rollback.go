@@ -0,0 +1,26 @@
+package updater
+
+import "os/exec"
+
+// StartRollback launches "<executable> -rollback" in its own process, which outlives the service
+// it stops. The outcome is written to update-status.json.
+func StartRollback() error {
+	if _, err := LoadPrevious(); err != nil {
+		return err
+	}
+	return startDetached("-rollback")
+}
+
+// startDetached runs the executable with arg in a process of its own
+func startDetached(arg string) error {
+	exePath, err := executablePath()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(exePath, arg)
+	cmd.SysProcAttr = detached()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
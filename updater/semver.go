@@ -0,0 +1,50 @@
+package updater
+
+import (
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// version is a semantic version (https://semver.org) in the canonical form of
+// golang.org/x/mod/semver, e.g. "v1.2.0-rc.1"; build metadata is dropped since it does not take
+// part in ordering
+type version string
+
+// parseVersion parses "1.2.3", "v1.2.3-beta.1" or "1.2.3+build". Missing minor and patch
+// numbers are 0, so "v2" is "2.0.0". It returns false for anything else, such as "dev".
+func parseVersion(s string) (version, bool) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "v") {
+		s = "v" + s
+	}
+	if !semver.IsValid(s) {
+		return "", false
+	}
+	return version(semver.Canonical(s)), true
+}
+
+// isPrerelease reports whether v is a pre-release such as 1.2.0-rc.1
+func (v version) isPrerelease() bool {
+	return semver.Prerelease(string(v)) != ""
+}
+
+// compare returns -1, 0 or 1 as v is older than, equal to or newer than o. A pre-release is
+// older than its release.
+func (v version) compare(o version) int {
+	return semver.Compare(string(v), string(o))
+}
+
+// isNewerVersion reports whether remote is a newer semantic version than current. A current
+// version that is not one, such as "dev", is never updated.
+func isNewerVersion(remote, current string) bool {
+	r, ok := parseVersion(remote)
+	if !ok {
+		return false
+	}
+	c, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	return r.compare(c) > 0
+}
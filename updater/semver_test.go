@@ -0,0 +1,54 @@
+package updater
+
+import "testing"
+
+func TestIsNewerVersion(t *testing.T) {
+	tests := []struct {
+		remote, current string
+		want            bool
+	}{
+		{"v1.10.0", "v1.9.0", true},
+		{"v1.9.0", "v1.10.0", false},
+		{"v2.0.0", "v1.99.99", true},
+		{"1.2.4", "v1.2.3", true}, // Tags with and without the "v" prefix
+		{"v1.2.3", "1.2.3", false},
+		{"v1.2.0", "v1.2.0-rc.1", true}, // A release is newer than its pre-releases
+		{"v1.2.0-rc.1", "v1.2.0", false},
+		{"v1.2.0-rc.2", "v1.2.0-rc.1", true},
+		{"v1.2.0-rc.10", "v1.2.0-rc.9", true},
+		{"v1.2.0-rc.1", "v1.1.9", true},
+		{"v1.2.3+build.7", "v1.2.3", false}, // Build metadata does not take part in ordering
+		{"v2", "v1.9.9", true},
+		{"v1.2.3", "dev", false}, // Builds without a version are never updated
+		{"latest", "v1.2.3", false},
+	}
+	for _, tt := range tests {
+		if got := isNewerVersion(tt.remote, tt.current); got != tt.want {
+			t.Errorf("isNewerVersion(%q, %q) = %v, expected %v", tt.remote, tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in         string
+		want       version
+		ok         bool
+		prerelease bool
+	}{
+		{"1.2.3", "v1.2.3", true, false},
+		{" v1.2 ", "v1.2.0", true, false},
+		{"v1.2.0-beta.1", "v1.2.0-beta.1", true, true},
+		{"v1.2.0+build", "v1.2.0", true, false},
+		{"dev", "", false, false},
+		{"v1.2.3.4", "", false, false},
+		{"v1.2.3-", "", false, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseVersion(tt.in)
+		if got != tt.want || ok != tt.ok || got.isPrerelease() != tt.prerelease {
+			t.Errorf("parseVersion(%q) = %q, %v (pre-release %v), expected %q, %v (pre-release %v)",
+				tt.in, got, ok, got.isPrerelease(), tt.want, tt.ok, tt.prerelease)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package updater
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// PublicKey is the minisign public key (the base64 line of minisign.pub) that release checksums
+// must be signed with. It is set during build via ldflags; builds without one cannot update.
+var PublicKey = ""
+
+// ErrNoPublicKey is returned when updating a build without a release public key
+var ErrNoPublicKey = errors.New("this build has no release public key, so updates cannot be verified; " +
+	"install a release build or build with -X mekari-esign/updater.PublicKey=<key>")
+
+// Release assets listing and signing the checksums of the other assets
+const (
+	checksumsAsset = "checksums.txt"
+	signatureAsset = checksumsAsset + ".minisig"
+	// maxChecksumsSize bounds the checksum and signature downloads
+	maxChecksumsSize = 1 << 20
+)
+
+// minisign algorithms: Ed signs the message, ED its BLAKE2b-512 hash (the default of minisign 0.10+)
+const (
+	algEd        = "Ed"
+	algPrehashed = "ED"
+)
+
+type minisignKey struct {
+	keyID     [8]byte
+	publicKey ed25519.PublicKey
+}
+
+type minisignSignature struct {
+	algorithm       string
+	keyID           [8]byte
+	signature       []byte
+	trustedComment  string
+	globalSignature []byte
+}
+
+// parsePublicKey parses the base64 line of a minisign public key
+func parsePublicKey(s string) (minisignKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(raw) != 2+8+ed25519.PublicKeySize || string(raw[:2]) != algEd {
+		return minisignKey{}, errors.New("invalid minisign public key")
+	}
+	var key minisignKey
+	copy(key.keyID[:], raw[2:10])
+	key.publicKey = ed25519.PublicKey(raw[10:])
+	return key, nil
+}
+
+// parseSignature parses a .minisig file: an untrusted comment, the signature, a trusted comment
+// and the global signature over the signature and trusted comment
+func parseSignature(data []byte) (minisignSignature, error) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[0], "untrusted comment:") ||
+		!strings.HasPrefix(lines[2], "trusted comment: ") {
+		return minisignSignature{}, errors.New("invalid minisign signature file")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(raw) != 2+8+ed25519.SignatureSize {
+		return minisignSignature{}, errors.New("invalid minisign signature")
+	}
+	global, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return minisignSignature{}, errors.New("invalid minisign global signature")
+	}
+
+	sig := minisignSignature{
+		algorithm:       string(raw[:2]),
+		signature:       raw[10:],
+		trustedComment:  strings.TrimPrefix(lines[2], "trusted comment: "),
+		globalSignature: global,
+	}
+	copy(sig.keyID[:], raw[2:10])
+	return sig, nil
+}
+
+// verify checks that sig signs message with key, including its trusted comment
+func (key minisignKey) verify(message []byte, sig minisignSignature) error {
+	if sig.keyID != key.keyID {
+		return fmt.Errorf("signed with key %X, expected key %X", sig.keyID, key.keyID)
+	}
+
+	switch sig.algorithm {
+	case algEd:
+	case algPrehashed:
+		hash := blake2b.Sum512(message)
+		message = hash[:]
+	default:
+		return fmt.Errorf("unsupported signature algorithm %q", sig.algorithm)
+	}
+	if !ed25519.Verify(key.publicKey, message, sig.signature) {
+		return errors.New("signature verification failed")
+	}
+
+	global := append(append([]byte{}, sig.signature...), sig.trustedComment...)
+	if !ed25519.Verify(key.publicKey, global, sig.globalSignature) {
+		return errors.New("trusted comment signature verification failed")
+	}
+	return nil
+}
+
+// VerifyRelease checks the downloaded asset against the SHA-256 listed for it in the release's
+// checksums.txt, which must be signed with PublicKey, before it is staged. Builds without a
+// PublicKey and releases without signed checksums are rejected.
+func (u *Updater) VerifyRelease(ctx context.Context, release *GitHubRelease, asset *GitHubAsset, filePath string) error {
+	checksums, source, err := u.releaseChecksums(ctx, release)
+	if err != nil {
+		return err
+	}
+
+	expected := checksumFor(checksums, asset.Name)
+	if expected == "" {
+		return fmt.Errorf("%s does not list %s", source, asset.Name)
+	}
+	if err := u.VerifyChecksum(filePath, expected); err != nil {
+		return err
+	}
+	fmt.Printf("Checksum verified against %s\n", source)
+	return nil
+}
+
+// releaseChecksums downloads the checksums of release and returns them with the asset name
+func (u *Updater) releaseChecksums(ctx context.Context, release *GitHubRelease) ([]byte, string, error) {
+	if PublicKey == "" {
+		return nil, "", ErrNoPublicKey
+	}
+
+	key, err := parsePublicKey(PublicKey)
+	if err != nil {
+		return nil, "", err
+	}
+	checksums := findAsset(release, checksumsAsset)
+	signature := findAsset(release, signatureAsset)
+	if checksums == nil || signature == nil {
+		return nil, "", fmt.Errorf("release %s is not signed: %s or %s is missing", release.TagName, checksumsAsset, signatureAsset)
+	}
+
+	data, err := u.fetchAsset(ctx, checksums)
+	if err != nil {
+		return nil, "", err
+	}
+	sigData, err := u.fetchAsset(ctx, signature)
+	if err != nil {
+		return nil, "", err
+	}
+	sig, err := parseSignature(sigData)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := key.verify(data, sig); err != nil {
+		return nil, "", fmt.Errorf("invalid signature of %s: %w", checksumsAsset, err)
+	}
+	// The release workflow signs with the trusted comment "mekari-esign <tag>", so the checksums
+	// of an older release cannot be passed off as those of this one
+	if !slices.Contains(strings.Fields(sig.trustedComment), release.TagName) {
+		return nil, "", fmt.Errorf("%s is signed for %q, not release %s", checksumsAsset, sig.trustedComment, release.TagName)
+	}
+	fmt.Printf("Signature verified (%s)\n", sig.trustedComment)
+	return data, checksumsAsset, nil
+}
+
+// checksumFor returns the checksum of name in sha256sum output, "" when it is not listed. Entries
+// match by file name, as sha256sum writes the paths it was given. A byte order mark, added when
+// the file was edited on Windows, is ignored; the signature covers the file as downloaded.
+func checksumFor(checksums []byte, name string) string {
+	text := strings.TrimPrefix(string(checksums), "\ufeff")
+	for _, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		// sha256sum marks binary mode with "*" before the path
+		file := strings.TrimPrefix(fields[1], "*")
+		if path.Base(filepath.ToSlash(file)) == name {
+			return fields[0]
+		}
+	}
+	return ""
+}
+
+func findAsset(release *GitHubRelease, name string) *GitHubAsset {
+	for i := range release.Assets {
+		if release.Assets[i].Name == name {
+			return &release.Assets[i]
+		}
+	}
+	return nil
+}
+
+// fetchAsset downloads a small release asset into memory
+func (u *Updater) fetchAsset(ctx context.Context, asset *GitHubAsset) ([]byte, error) {
+	req, err := u.newAssetRequest(ctx, asset)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := u.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", asset.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download of %s failed with status %d", asset.Name, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxChecksumsSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxChecksumsSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", asset.Name, maxChecksumsSize)
+	}
+	return data, nil
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -19,10 +20,28 @@ import (
 // Version is set during build via ldflags
 var Version = "dev"
 
+// Release channels
+const (
+	ChannelStable = "stable" // Releases only
+	ChannelBeta   = "beta"   // Releases and pre-releases
+)
+
+// DefaultAPIBase is the GitHub API of github.com
+const DefaultAPIBase = "https://api.github.com"
+
+// releasesPerPage is the number of recent releases searched for the newest one of the channel
+const releasesPerPage = 50
+
 // GitHubConfig holds GitHub repository configuration
 type GitHubConfig struct {
-	Owner string // GitHub username or organization
-	Repo  string // Repository name
+	Owner      string // GitHub username or organization
+	Repo       string // Repository name
+	Channel    string // ChannelStable or ChannelBeta (empty = stable)
+	PinVersion string // Install exactly this version, also to roll back (empty = newest of the channel)
+	ConfigFile string // Config file kept with the replaced executable for -rollback (empty = none)
+	Token      string // Token for the releases of a private repository (empty = anonymous)
+	APIBase    string // API URL, e.g. https://github.example.com/api/v3 for GitHub Enterprise (empty = DefaultAPIBase)
+	Proxy      string // HTTP(S) proxy URL (empty = HTTPS_PROXY/HTTP_PROXY environment)
 }
 
 // DefaultConfig - configure this with your GitHub repo
@@ -45,6 +64,7 @@ type GitHubRelease struct {
 // GitHubAsset represents a release asset
 type GitHubAsset struct {
 	Name               string `json:"name"`
+	URL                string `json:"url"` // API URL of the asset, which downloads it with a token
 	Size               int64  `json:"size"`
 	BrowserDownloadURL string `json:"browser_download_url"`
 	ContentType        string `json:"content_type"`
@@ -58,63 +78,148 @@ type Updater struct {
 
 // NewUpdater creates a new Updater instance
 func NewUpdater(config GitHubConfig) *Updater {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.Proxy != "" {
+		if proxy, err := url.Parse(config.Proxy); err == nil {
+			transport.Proxy = http.ProxyURL(proxy)
+		}
+	}
+
 	return &Updater{
 		config: config,
 		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
+			Timeout:   60 * time.Second,
+			Transport: transport,
 		},
 	}
 }
 
+// newRequest creates a GET request to GitHub, authenticated when a token is configured
+func (u *Updater) newRequest(ctx context.Context, endpoint, accept string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("User-Agent", "MekariEsign-Updater")
+	// Not sent along redirects to other hosts, such as the storage serving assets
+	if u.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+u.config.Token)
+	}
+	return req, nil
+}
+
+// newAssetRequest creates the download request of asset. The browser URL of a private
+// repository's asset needs a session, so with a token the asset is fetched through the API.
+func (u *Updater) newAssetRequest(ctx context.Context, asset *GitHubAsset) (*http.Request, error) {
+	if u.config.Token != "" && asset.URL != "" {
+		return u.newRequest(ctx, asset.URL, "application/octet-stream")
+	}
+	return u.newRequest(ctx, asset.BrowserDownloadURL, "application/octet-stream")
+}
+
 // NewDefaultUpdater creates an Updater with default config
 func NewDefaultUpdater() *Updater {
 	return NewUpdater(DefaultConfig)
 }
 
-// CheckForUpdate checks if a new version is available
+// CheckForUpdate returns the newest release of the channel when it is newer than the running
+// version, or the pinned release when a version is pinned and not running. It returns nil when
+// there is nothing to install.
 func (u *Updater) CheckForUpdate(ctx context.Context) (*GitHubRelease, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/latest", u.config.Owner, u.config.Repo)
+	if u.config.PinVersion != "" {
+		return u.checkPinned(ctx)
+	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
+	var releases []GitHubRelease
+	found, err := u.getJSON(ctx, fmt.Sprintf("/releases?per_page=%d", releasesPerPage), &releases)
+	if err != nil || !found {
 		return nil, err
 	}
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
-	req.Header.Set("User-Agent", "MekariEsign-Updater")
 
-	resp, err := u.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check for updates: %w", err)
+	var newest *GitHubRelease
+	var newestVersion version
+	for i := range releases {
+		release := &releases[i]
+		version, ok := parseVersion(release.TagName)
+		if !ok || release.Draft || !u.inChannel(release, version) {
+			continue
+		}
+		if newest == nil || version.compare(newestVersion) > 0 {
+			newest, newestVersion = release, version
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusNotFound {
-		return nil, nil // No releases available
+	if newest == nil || !isNewerVersion(newest.TagName, Version) {
+		return nil, nil // Already up to date
 	}
+	return newest, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GitHub API returned status %d", resp.StatusCode)
+// inChannel reports whether a release is offered on the configured channel. Stable skips
+// releases marked as pre-release on GitHub and pre-release versions such as 1.2.0-rc.1.
+func (u *Updater) inChannel(release *GitHubRelease, v version) bool {
+	if u.config.Channel == ChannelBeta {
+		return true
 	}
+	return !release.Prerelease && !v.isPrerelease()
+}
 
-	var release GitHubRelease
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return nil, fmt.Errorf("failed to parse release info: %w", err)
+// checkPinned returns the release of the pinned version unless it is running already. The tag
+// is looked up with and without the "v" prefix.
+func (u *Updater) checkPinned(ctx context.Context) (*GitHubRelease, error) {
+	pin, ok := parseVersion(u.config.PinVersion)
+	if !ok {
+		return nil, fmt.Errorf("pinned version %q is not a semantic version", u.config.PinVersion)
 	}
-
-	// Skip draft and prerelease
-	if release.Draft || release.Prerelease {
+	if current, ok := parseVersion(Version); ok && current.compare(pin) == 0 {
 		return nil, nil
 	}
 
-	// Compare versions
-	remoteVersion := strings.TrimPrefix(release.TagName, "v")
-	currentVersion := strings.TrimPrefix(Version, "v")
+	tag := strings.TrimPrefix(u.config.PinVersion, "v")
+	for _, candidate := range []string{"v" + tag, tag} {
+		var release GitHubRelease
+		found, err := u.getJSON(ctx, "/releases/tags/"+url.PathEscape(candidate), &release)
+		if err != nil {
+			return nil, err
+		}
+		if found && !release.Draft {
+			return &release, nil
+		}
+	}
+	return nil, fmt.Errorf("no release found for pinned version %s", u.config.PinVersion)
+}
 
-	if !isNewerVersion(remoteVersion, currentVersion) {
-		return nil, nil // Already up to date
+// getJSON decodes a GitHub API response of the repository into v. It returns false for 404.
+func (u *Updater) getJSON(ctx context.Context, path string, v interface{}) (bool, error) {
+	base := strings.TrimRight(u.config.APIBase, "/")
+	if base == "" {
+		base = DefaultAPIBase
+	}
+	endpoint := fmt.Sprintf("%s/repos/%s/%s%s", base, u.config.Owner, u.config.Repo, path)
+
+	req, err := u.newRequest(ctx, endpoint, "application/vnd.github.v3+json")
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := u.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to check for updates: %w", err)
 	}
+	defer resp.Body.Close()
 
-	return &release, nil
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("GitHub API returned status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return false, fmt.Errorf("failed to parse release info: %w", err)
+	}
+	return true, nil
 }
 
 // GetDownloadAsset finds the appropriate asset for current platform
@@ -141,11 +246,10 @@ func (u *Updater) GetDownloadAsset(release *GitHubRelease) *GitHubAsset {
 
 // DownloadUpdate downloads the update to a temp file
 func (u *Updater) DownloadUpdate(ctx context.Context, asset *GitHubAsset, progressFn func(downloaded, total int64)) (string, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", asset.BrowserDownloadURL, nil)
+	req, err := u.newAssetRequest(ctx, asset)
 	if err != nil {
 		return "", err
 	}
-	req.Header.Set("User-Agent", "MekariEsign-Updater")
 
 	resp, err := u.httpClient.Do(req)
 	if err != nil {
@@ -191,10 +295,10 @@ func (u *Updater) DownloadUpdate(ctx context.Context, asset *GitHubAsset, progre
 	return tmpFile.Name(), nil
 }
 
-// VerifyChecksum verifies the downloaded file (optional - if checksum provided in release body)
+// VerifyChecksum verifies the SHA-256 of the downloaded file against the hex expectedChecksum
 func (u *Updater) VerifyChecksum(filePath, expectedChecksum string) error {
 	if expectedChecksum == "" {
-		return nil // No checksum to verify
+		return fmt.Errorf("no checksum to verify %s against", filepath.Base(filePath))
 	}
 
 	f, err := os.Open(filePath)
@@ -216,21 +320,16 @@ func (u *Updater) VerifyChecksum(filePath, expectedChecksum string) error {
 	return nil
 }
 
-// ApplyUpdate extracts and applies the update
-func (u *Updater) ApplyUpdate(zipPath string) error {
-	exePath, err := os.Executable()
+// ApplyUpdate extracts the update and installs it in place of the running executable: the
+// service is stopped, the executable swapped and the service started again. When the new
+// version does not start, the previous one is put back and started. The outcome is written to
+// update-status.json next to the executable; the replaced version is kept for Rollback.
+func (u *Updater) ApplyUpdate(zipPath, version string, manager ServiceManager) error {
+	exePath, err := executablePath()
 	if err != nil {
 		return err
 	}
 
-	installDir := filepath.Dir(exePath)
-	backupDir := filepath.Join(installDir, ".backup")
-
-	// Create backup directory
-	if err := os.MkdirAll(backupDir, 0755); err != nil {
-		return err
-	}
-
 	// Extract update to temp directory
 	extractDir, err := os.MkdirTemp("", "mekari-esign-extract-*")
 	if err != nil {
@@ -242,78 +341,75 @@ func (u *Updater) ApplyUpdate(zipPath string) error {
 		return fmt.Errorf("failed to extract update: %w", err)
 	}
 
-	// On Windows, we can't replace a running executable directly
-	// Create update script that will be run after service restart
-	updateScript := filepath.Join(installDir, "apply-update.bat")
-	newExe := filepath.Join(extractDir, "mekari-esign.exe")
-	backupExe := filepath.Join(backupDir, fmt.Sprintf("mekari-esign-%s.exe.bak", Version))
-
-	// Check if new executable exists
-	if _, err := os.Stat(newExe); os.IsNotExist(err) {
-		// Try looking in subdirectory
-		files, _ := os.ReadDir(extractDir)
-		for _, f := range files {
-			if f.IsDir() {
-				subNewExe := filepath.Join(extractDir, f.Name(), "mekari-esign.exe")
-				if _, err := os.Stat(subNewExe); err == nil {
-					newExe = subNewExe
-					break
-				}
-			}
-		}
+	newExe, err := findExecutable(extractDir, filepath.Base(exePath))
+	if err != nil {
+		return err
 	}
 
-	script := fmt.Sprintf(`@echo off
-echo Applying Mekari E-Sign update...
-timeout /t 3 /nobreak > nul
-
-echo Stopping service...
-net stop MekariEsign 2>nul
-
-echo Backing up current version...
-if exist "%s" move /y "%s" "%s"
-
-echo Installing new version...
-copy /y "%s" "%s"
-
-echo Starting service...
-net start MekariEsign
-
-echo Update complete!
-del "%%~f0"
-`, exePath, exePath, backupExe, newExe, exePath)
+	return install(exePath, u.config.ConfigFile, target{version: version, executable: newExe}, manager, false)
+}
 
-	if err := os.WriteFile(updateScript, []byte(script), 0755); err != nil {
-		return err
+// findExecutable returns the path of the executable named name in the extracted update, at the
+// top level or in a subdirectory
+func findExecutable(dir, name string) (string, error) {
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
 	}
 
-	fmt.Printf("Update script created at: %s\n", updateScript)
-	fmt.Println("The update will be applied when you run the script or restart the service.")
-
-	return nil
+	files, _ := os.ReadDir(dir)
+	for _, f := range files {
+		if f.IsDir() {
+			path := filepath.Join(dir, f.Name(), name)
+			if _, err := os.Stat(path); err == nil {
+				return path, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("update does not contain %s", name)
 }
 
-// CheckAndUpdate performs a full update check and apply cycle
-func CheckAndUpdate() error {
+// CheckAndUpdate performs a full update check and apply cycle with config, restarting the
+// service through manager
+func CheckAndUpdate(config GitHubConfig, manager ServiceManager) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
-	updater := NewDefaultUpdater()
+	updater := NewUpdater(config)
 
 	fmt.Printf("Current version: %s\n", Version)
-	fmt.Println("Checking for updates...")
+	if config.PinVersion != "" {
+		fmt.Printf("Checking for pinned version %s...\n", config.PinVersion)
+	} else {
+		channel := config.Channel
+		if channel == "" {
+			channel = ChannelStable
+		}
+		fmt.Printf("Checking for updates on the %s channel...\n", channel)
+	}
 
-	release, err := updater.CheckForUpdate(ctx)
+	release, err := updater.checkAndRecord(ctx)
 	if err != nil {
 		return err
 	}
 
 	if release == nil {
-		fmt.Println("You are running the latest version.")
+		if config.PinVersion != "" {
+			fmt.Println("You are running the pinned version.")
+		} else {
+			fmt.Println("You are running the latest version.")
+		}
 		return nil
 	}
 
-	fmt.Printf("New version available: %s\n", release.TagName)
+	switch {
+	case config.PinVersion != "":
+		fmt.Printf("Installing pinned version: %s\n", release.TagName)
+	case release.Prerelease:
+		fmt.Printf("New version available: %s (pre-release)\n", release.TagName)
+	default:
+		fmt.Printf("New version available: %s\n", release.TagName)
+	}
 	fmt.Printf("Release notes:\n%s\n\n", release.Body)
 
 	asset := updater.GetDownloadAsset(release)
@@ -333,34 +429,20 @@ func CheckAndUpdate() error {
 	defer os.Remove(zipPath)
 	fmt.Println()
 
+	// Checked before anything is staged, so a tampered release never reaches the service
+	if err := updater.VerifyRelease(ctx, release, asset, zipPath); err != nil {
+		return fmt.Errorf("update rejected: %w", err)
+	}
+
 	fmt.Println("Applying update...")
-	if err := updater.ApplyUpdate(zipPath); err != nil {
+	if err := updater.ApplyUpdate(zipPath, release.TagName, manager); err != nil {
 		return err
 	}
 
-	fmt.Println("Update downloaded and staged successfully!")
+	fmt.Printf("Updated to %s\n", release.TagName)
 	return nil
 }
 
-// isNewerVersion compares semantic versions
-func isNewerVersion(remote, current string) bool {
-	// Simple string comparison - works for semantic versions
-	// For more robust comparison, use a semver library
-	remoteParts := strings.Split(remote, ".")
-	currentParts := strings.Split(current, ".")
-
-	for i := 0; i < len(remoteParts) && i < len(currentParts); i++ {
-		if remoteParts[i] > currentParts[i] {
-			return true
-		}
-		if remoteParts[i] < currentParts[i] {
-			return false
-		}
-	}
-
-	return len(remoteParts) > len(currentParts)
-}
-
 // extractZip extracts a zip file to destination directory
 func extractZip(zipPath, destDir string) error {
 	r, err := zip.OpenReader(zipPath)